@@ -7,6 +7,8 @@ import (
 
 	"github.com/fenilsonani/system-cleanup/internal/config"
 	"github.com/fenilsonani/system-cleanup/internal/daemon"
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 var (
@@ -54,8 +56,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Validate schedules
-	if len(cfg.Daemon.Schedules) == 0 {
+	// Validate schedules; a daemon that only serves the API needs none
+	if len(cfg.Daemon.Schedules) == 0 && !cfg.API.Enabled {
 		fmt.Fprintf(os.Stderr, "No schedules configured. Add at least one schedule.\n")
 		os.Exit(1)
 	}
@@ -66,7 +68,11 @@ func main() {
 		fmt.Printf("Daemon enabled: %v\n", cfg.Daemon.Enabled)
 		fmt.Printf("Schedules: %d\n", len(cfg.Daemon.Schedules))
 		for _, sched := range cfg.Daemon.Schedules {
-			fmt.Printf("  - %s: %s\n", sched.Name, sched.Schedule)
+			if sched.IsReport() {
+				fmt.Printf("  - %s: %s (report only)\n", sched.Name, sched.Triggers())
+			} else {
+				fmt.Printf("  - %s: %s\n", sched.Name, sched.Triggers())
+			}
 		}
 		os.Exit(0)
 	}
@@ -84,6 +90,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	d.SetVersion(Version)
+
 	// Start daemon
 	fmt.Println("Starting CleanupCache Daemon...")
 	if err := d.Start(); err != nil {
@@ -92,49 +100,37 @@ func main() {
 	}
 }
 
-func loadConfig() (*config.Config, error) {
-	if configPath != "" {
-		return config.Load(configPath)
-	}
+// legacySystemConfigPath is where earlier versions read the system config
+const legacySystemConfigPath = "/etc/cleanup-cache/config.yaml"
 
-	// Try system config first
-	if _, err := os.Stat("/etc/cleanup-cache/config.yaml"); err == nil {
-		return config.Load("/etc/cleanup-cache/config.yaml")
+// loadConfig merges the system config and the user config (or --config),
+// then applies TIDYUP_* environment overrides.
+// The daemon has no working directory of interest, so there's no project layer.
+func loadConfig() (*config.Config, error) {
+	cfgPath := configPath
+	if cfgPath == "" {
+		// Move the user config from where earlier versions kept it
+		if _, err := paths.Migrate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't move old tidyup files: %v\n", err)
+		}
+		var err error
+		if cfgPath, err = config.GetConfigPath(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Fall back to user config
-	cfgPath, err := config.GetConfigPath()
+	layers := []string{legacySystemConfigPath, config.SystemConfigPath, cfgPath}
+	cfg, _, err := config.Resolve(layers, config.Overrides{Env: os.Environ()})
 	if err != nil {
 		return nil, err
 	}
-
-	return config.Load(cfgPath)
+	utils.SetSI(cfg.Units == "si")
+	return cfg, nil
 }
 
+// isRunning reports whether a daemon is already running. A PID file left by
+// one that crashed doesn't count, even if its PID now belongs to another process.
 func isRunning(cfg *config.Config) bool {
-	pidFile := cfg.Daemon.PidFile
-	if pidFile == "" {
-		pidFile = "/var/run/cleanup-cache.pid"
-	}
-
-	data, err := os.ReadFile(pidFile)
-	if err != nil {
-		return false
-	}
-
-	var pid int
-	_, err = fmt.Sscanf(string(data), "%d", &pid)
-	if err != nil {
-		return false
-	}
-
-	// Check if process exists
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-
-	// Try to signal process
-	err = process.Signal(nil)
-	return err == nil
+	_, running, _, err := daemon.Status(cfg)
+	return err == nil && running
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var analyzeTop int
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze [dir]",
+	Short: "Explore what takes up space in a directory",
+	Long: `Scans every file below a directory (the current one by default),
+whatever category it would be in, and shows them as a tree with the largest
+folders first. Mark files or whole folders and confirm to delete them; they go
+through the same safety checks, quarantine and undo as any other cleanup.
+
+When not on a terminal, the largest entries of the directory are listed
+instead.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cmd.Flags().Changed("dry-run") {
+			cfg.DryRun = dryRun
+		}
+
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		fmt.Printf(" Analyzing %s...\n", root)
+		hyperScnr := scanner.NewHyperScanner(cfg, platformInfo)
+		result, err := hyperScnr.AnalyzeDir(root)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		if len(result.Errors) > 0 {
+			fmt.Printf("Warning: %d directories couldn't be read\n", len(result.Errors))
+		}
+
+		if result.TotalCount == 0 {
+			fmt.Println("\nNo files found.")
+			return nil
+		}
+
+		if !ui.IsInteractive() {
+			abs, _ := filepath.Abs(root)
+			fmt.Printf("\nTotal: %d files, %s\n\n", result.TotalCount, formatBytes(result.TotalSize))
+			for _, entry := range largestEntries(abs, result.Files, analyzeTop) {
+				fmt.Printf("  %10s  %s\n", formatBytes(entry.size), entry.name)
+			}
+			return nil
+		}
+		return pickAndClean(cfg, result, "selected files", true)
+	},
+}
+
+// dirEntrySize is the total size of the files below one entry of a directory
+type dirEntrySize struct {
+	name string
+	size int64
+}
+
+// largestEntries totals files by the entry of root they're in and returns the
+// n largest. Directories are shown with a trailing slash.
+func largestEntries(root string, files []scanner.FileInfo, n int) []dirEntrySize {
+	sizes := make(map[string]int64)
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file.Path)
+		if err != nil {
+			continue
+		}
+		name, _, nested := strings.Cut(rel, string(filepath.Separator))
+		if nested {
+			name += string(filepath.Separator)
+		}
+		sizes[name] += file.Size
+	}
+
+	entries := make([]dirEntrySize, 0, len(sizes))
+	for name, size := range sizes {
+		entries = append(entries, dirEntrySize{name, size})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size != entries[j].size {
+			return entries[i].size > entries[j].size
+		}
+		return entries[i].name < entries[j].name
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
@@ -13,10 +13,10 @@ import (
 
 // AppData holds information about an installed app and its related data
 type AppData struct {
-	Name       string
-	AppPath    string          // Path to the .app bundle
-	DataPaths  []string        // Paths to app data, caches, settings
-	TotalSize  int64
+	Name        string
+	AppPath     string   // Path to the .app bundle
+	DataPaths   []string // Paths to app data, caches, settings
+	TotalSize   int64
 	IsInstalled bool
 }
 
@@ -185,7 +185,7 @@ func (am *AppManager) ListApps() error {
 		return nil
 	}
 
-	fmt.Println("\nInstalled Apps with Data\n")
+	fmt.Print("\nInstalled Apps with Data\n\n")
 	fmt.Println("-----+----------------------+----------+------------------")
 	fmt.Println("No.  | App Name             | Size     | Data Locations")
 	fmt.Println("-----+----------------------+----------+------------------")
@@ -221,8 +221,8 @@ func (am *AppManager) InteractiveUninstall() error {
 		return nil
 	}
 
-	fmt.Println("\nInstalled Apps with Associated Data\n")
-	fmt.Println("Select apps to uninstall (with all related data):\n")
+	fmt.Print("\nInstalled Apps with Associated Data\n\n")
+	fmt.Print("Select apps to uninstall (with all related data):\n\n")
 
 	// Show list
 	for i, app := range am.apps {
@@ -279,7 +279,7 @@ func (am *AppManager) UninstallApp(targetName string, force bool) error {
 	var selectedApp *AppData
 	for i := range am.apps {
 		if strings.EqualFold(am.apps[i].Name, targetName) ||
-		   strings.Contains(strings.ToLower(am.apps[i].Name), strings.ToLower(targetName)) {
+			strings.Contains(strings.ToLower(am.apps[i].Name), strings.ToLower(targetName)) {
 			selectedApp = &am.apps[i]
 			break
 		}
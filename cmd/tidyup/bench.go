@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/spf13/cobra"
+)
+
+var benchRuns int
+
+// benchEngine is one way of running the scanner that bench measures
+type benchEngine struct {
+	name      string
+	configure func(cfg *config.Config)
+	prepare   func(hs *scanner.HyperScanner)
+}
+
+// benchEngines are measured in this order; the first is what the others are
+// compared against. HyperScanner is the only scanner left, so they're the
+// ways of running it that change what it does: with or without the cache,
+// and with estimated or exact sizes.
+var benchEngines = []benchEngine{
+	{
+		name:    "cold",
+		prepare: func(hs *scanner.HyperScanner) { hs.DisableCache() },
+	},
+	{
+		// The run before the first timed one fills the cache
+		name: "warm cache",
+	},
+	{
+		name:      "exact sizes",
+		configure: func(cfg *config.Config) { cfg.Dev.VerifySizes = true },
+		prepare:   func(hs *scanner.HyperScanner) { hs.DisableCache() },
+	},
+}
+
+// benchResult is the fastest run of one engine
+type benchResult struct {
+	workers int
+	storage platform.StorageKind
+	elapsed time.Duration
+	calls   *vfs.CountingFS
+	files   map[string]int64
+	size    int64
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure how the scanner performs",
+	Long: `Scans the enabled categories (or --category) several ways: without the
+scan cache, with a warm cache, and measuring development artifacts exactly
+instead of with du. For each it reports the fastest of --runs scans, the file
+system calls made, and any files found differently from the cold scan, so
+performance regressions and discrepancies between scan paths show up.
+
+Nothing is deleted. The warm runs update the scan cache as a normal scan does.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if benchRuns < 1 {
+			return fmt.Errorf("--runs must be at least 1")
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		results := make([]*benchResult, len(benchEngines))
+		for i, engine := range benchEngines {
+			fmt.Printf(" Benchmarking %s (%d runs)...\n", engine.name, benchRuns)
+			if engine.prepare == nil {
+				// Untimed run to fill the cache
+				if _, err := benchRun(engine, platformInfo); err != nil {
+					return err
+				}
+			}
+			for run := 0; run < benchRuns; run++ {
+				result, err := benchRun(engine, platformInfo)
+				if err != nil {
+					return err
+				}
+				if results[i] == nil || result.elapsed < results[i].elapsed {
+					results[i] = result
+				}
+			}
+		}
+
+		fmt.Printf("\nStorage: %s, %d workers\n", results[0].storage, results[0].workers)
+		fmt.Printf("\n%-12s %10s %9s %11s %9s %9s %9s %9s\n",
+			"Engine", "Time", "Files", "Size", "stat", "lstat", "readdir", "open")
+		for i, engine := range benchEngines {
+			r := results[i]
+			fmt.Printf("%-12s %10s %9d %11s %9d %9d %9d %9d\n",
+				engine.name, progress.FormatDuration(r.elapsed), len(r.files), formatBytes(r.size),
+				r.calls.Stats.Load(), r.calls.Lstats.Load(), r.calls.ReadDirs.Load(), r.calls.Opens.Load())
+		}
+
+		fmt.Printf("\nCompared with %s:\n", benchEngines[0].name)
+		for i := 1; i < len(benchEngines); i++ {
+			added, missing, resized := benchDiff(results[0].files, results[i].files)
+			if added == 0 && missing == 0 && resized == 0 && results[i].size == results[0].size {
+				fmt.Printf("  %-12s same files and sizes\n", benchEngines[i].name)
+				continue
+			}
+			fmt.Printf("  %-12s %d extra, %d missing, %d sized differently, %s in total\n",
+				benchEngines[i].name, added, missing, resized, formatSizeDelta(results[i].size-results[0].size))
+		}
+		return nil
+	},
+}
+
+// benchRun scans once with engine
+func benchRun(engine benchEngine, platformInfo *platform.Info) (*benchResult, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if engine.configure != nil {
+		engine.configure(cfg)
+	}
+
+	hs := scanner.NewHyperScanner(cfg, platformInfo)
+	calls := vfs.NewCountingFS(vfs.OS)
+	hs.SetFS(calls)
+	if engine.prepare != nil {
+		engine.prepare(hs)
+	}
+
+	start := time.Now()
+	var result *scanner.ScanResult
+	if category != "" {
+		result = hs.ScanCategory(category)
+	} else if result, err = hs.ScanAll(); err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	elapsed := time.Since(start)
+	defer result.Close()
+
+	bench := &benchResult{elapsed: elapsed, calls: calls, files: make(map[string]int64)}
+	bench.workers, bench.storage = hs.Parallelism()
+	err = result.Each(func(file scanner.FileInfo) error {
+		bench.files[file.Path] = file.Size
+		bench.size += file.Size
+		return nil
+	})
+	return bench, err
+}
+
+// benchDiff compares the files two scans found. A cached directory stands
+// for the files below it, so a file counts as found by both if the other scan
+// found it, a directory above it or a file below it. It returns the files
+// only other found, the ones only base found, and the ones both found with
+// different sizes.
+func benchDiff(base, other map[string]int64) (added, missing, resized int) {
+	baseDirs, otherDirs := benchParents(base), benchParents(other)
+	for path, size := range other {
+		if baseSize, ok := base[path]; ok {
+			if baseSize != size {
+				resized++
+			}
+		} else if !benchCovered(path, base) && !baseDirs[path] {
+			added++
+		}
+	}
+	for path := range base {
+		if _, ok := other[path]; !ok && !benchCovered(path, other) && !otherDirs[path] {
+			missing++
+		}
+	}
+	return added, missing, resized
+}
+
+// benchCovered reports whether a directory above path is in files
+func benchCovered(path string, files map[string]int64) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, ok := files[dir]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// benchParents returns every directory above the files
+func benchParents(files map[string]int64) map[string]bool {
+	parents := make(map[string]bool)
+	for path := range files {
+		for dir := filepath.Dir(path); !parents[dir] && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+	return parents
+}
+
+// formatSizeDelta formats a difference in size with its sign
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
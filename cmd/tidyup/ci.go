@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ciMinFree  string
+	ciLockFile string
+)
+
+// ciWorkspaceVars name the job checkout on common CI systems; nothing under
+// them is ever cleaned
+var ciWorkspaceVars = []string{"GITHUB_WORKSPACE", "BUILDKITE_BUILD_CHECKOUT_PATH", "CI_PROJECT_DIR", "WORKSPACE"}
+
+// ciSummary is the JSON summary tidyup ci prints
+type ciSummary struct {
+	RunID           string                      `json:"run_id"`
+	Action          string                      `json:"action"` // "cleaned", "not_needed" or "skipped"
+	Reason          string                      `json:"reason,omitempty"`
+	DryRun          bool                        `json:"dry_run"`
+	Path            string                      `json:"path"`
+	MinFree         uint64                      `json:"min_free"`
+	Total           uint64                      `json:"total"`
+	FreeBefore      uint64                      `json:"free_before"`
+	FreeAfter       uint64                      `json:"free_after"`
+	Files           uint64                      `json:"files"` // Inodes; 0 if the file system has no fixed number
+	FreeFilesBefore uint64                      `json:"free_files_before"`
+	FreeFilesAfter  uint64                      `json:"free_files_after"`
+	DeletedFiles    int                         `json:"deleted_files"`
+	DeletedSize     int64                       `json:"deleted_size"`
+	Reclaimed       int64                       `json:"reclaimed_size"`
+	Filesystems     []cleaner.FilesystemReclaim `json:"filesystems"`
+	ReadOnly        []string                    `json:"read_only,omitempty"` // Read-only file systems whose files were skipped
+	Copied          []string                    `json:"copied,omitempty"`    // Files copied to the quarantine from another file system
+	Categories      map[string]int64            `json:"categories"`
+	Errors          []string                    `json:"errors"`
+	DurationMS      int64                       `json:"duration_ms"`
+}
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Free disk space on a CI build agent",
+	Long: `A preset for self-hosted build agents (GitHub Actions, Buildkite, ...).
+
+When free space on the disk drops below ci.min_free, or fewer than 5% of its
+inodes are free, cleans the categories in ci.categories (by default caches and
+Docker) and empties the toolchain caches in ci.tool_caches. Nothing in the job workspace is touched. It never prompts,
+and does nothing while ci.lock_file exists, so it can run between jobs from a
+hook or a timer. Files are deleted for good, never quarantined. A JSON summary
+is printed to stdout.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		start := time.Now()
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cmd.Flags().Changed("min-free") {
+			cfg.CI.MinFree = ciMinFree
+		}
+		if cmd.Flags().Changed("lock-file") {
+			cfg.CI.LockFile = ciLockFile
+		}
+		if cmd.Flags().Changed("dry-run") {
+			cfg.DryRun = dryRun
+		}
+		// Build agents clean to get the space back now, which a quarantine
+		// on the same disk wouldn't do
+		cfg.Quarantine.Enabled = false
+
+		summary, err := runCI(cfg)
+		if err != nil {
+			return err
+		}
+		summary.DurationMS = time.Since(start).Milliseconds()
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	},
+}
+
+// runCI checks the free space and cleans if it's below the threshold
+func runCI(cfg *config.Config) (*ciSummary, error) {
+	summary := &ciSummary{RunID: runID, DryRun: cfg.DryRun, Path: cfg.CI.Path, Categories: map[string]int64{}, Filesystems: []cleaner.FilesystemReclaim{}, Errors: []string{}}
+	if summary.Path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		summary.Path = home
+	}
+
+	usage, err := platform.GetDiskUsage(summary.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read free space on %s: %w", summary.Path, err)
+	}
+	summary.Total, summary.FreeBefore, summary.FreeAfter = usage.Total, usage.Free, usage.Free
+	summary.Files, summary.FreeFilesBefore, summary.FreeFilesAfter = usage.Files, usage.FreeFiles, usage.FreeFiles
+	if summary.MinFree, err = cfg.CI.MinFreeBytes(usage.Total); err != nil {
+		return nil, fmt.Errorf("invalid ci.min_free: %w", err)
+	}
+
+	if cfg.CI.LockFile != "" {
+		if _, err := os.Stat(cfg.CI.LockFile); err == nil {
+			summary.Action, summary.Reason = "skipped", "agent is busy ("+cfg.CI.LockFile+" exists)"
+			return summary, nil
+		}
+	}
+	if usage.Free >= summary.MinFree && !usage.LowOnFiles() {
+		summary.Action = "not_needed"
+		return summary, nil
+	}
+	if usage.Free >= summary.MinFree {
+		summary.Reason = "nearly out of inodes"
+	}
+
+	if err := cfg.Categories.Only(cfg.CI.Categories); err != nil {
+		return nil, fmt.Errorf("invalid ci.categories: %w", err)
+	}
+	cfg.Docker.Enabled = cfg.Docker.Enabled || cfg.Categories.Docker
+
+	platformInfo, err := platform.GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platform info: %w", err)
+	}
+	result, err := scanner.NewHyperScanner(cfg, platformInfo).ScanAll()
+	if err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	// Everything found is cleaned, so there's nothing to gain from paging
+	if err := result.Load(); err != nil {
+		return nil, err
+	}
+	result = withoutWorkspace(appendToolCaches(result, cfg.CI.ToolCaches))
+
+	clnr := cleaner.New(cfg)
+	clnr.SetRunID(runID)
+	clnr.SetAskSudo(false)
+	cleanResult, err := clnr.Clean(result)
+	if err != nil {
+		return nil, fmt.Errorf("cleanup failed: %w", err)
+	}
+
+	summary.Action = "cleaned"
+	summary.DeletedFiles = len(cleanResult.DeletedFiles)
+	summary.DeletedSize = cleanResult.DeletedSize
+	summary.Reclaimed = cleanResult.ReclaimedSize()
+	summary.Filesystems = cleanResult.Filesystems
+	summary.ReadOnly = cleanResult.ReadOnly
+	summary.Copied = cleanResult.Copied
+	for category, group := range result.GroupByCategory() {
+		summary.Categories[category] = group.TotalSize
+	}
+	for _, e := range cleanResult.Errors {
+		summary.Errors = append(summary.Errors, e.Error())
+	}
+	summary.Errors = append(summary.Errors, cleanResult.HookErrors...)
+	if after, err := platform.GetDiskUsage(summary.Path); err == nil {
+		summary.FreeAfter = after.Free
+		summary.FreeFilesAfter = after.FreeFiles
+	}
+	return summary, nil
+}
+
+// appendToolCaches adds each toolchain in the tool cache directories to
+// result, in the "toolchains" category
+func appendToolCaches(result *scanner.ScanResult, dirs []string) *scanner.ScanResult {
+	for _, dir := range dirs {
+		dir = os.ExpandEnv(dir)
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue // Unset variable
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			size := dirSize(path)
+			result.Files = append(result.Files, scanner.FileInfo{
+				Path:     path,
+				Size:     size,
+				ModTime:  info.ModTime(),
+				Category: "toolchains",
+				Reason:   "Toolchain cache, downloaded again when a job needs it",
+			})
+			result.TotalSize += size
+			result.TotalCount++
+		}
+	}
+	return result
+}
+
+// withoutWorkspace drops files in the job workspace from result
+func withoutWorkspace(result *scanner.ScanResult) *scanner.ScanResult {
+	var workspaces []string
+	for _, name := range ciWorkspaceVars {
+		if dir := os.Getenv(name); dir != "" {
+			workspaces = append(workspaces, filepath.Clean(dir))
+		}
+	}
+	if len(workspaces) == 0 {
+		return result
+	}
+
+	var keep []string
+	for _, file := range result.Files {
+		inside := false
+		for _, ws := range workspaces {
+			if file.Path == ws || strings.HasPrefix(file.Path, ws+string(filepath.Separator)) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			keep = append(keep, file.Path)
+		}
+	}
+	return result.FilterPaths(keep)
+}
+
+// dirSize adds up the size of the files under path
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// pickerRuns reports whether clean --interactive lets the user pick
+// categories, which asks y/N itself. With --category there's nothing to pick.
+func pickerRuns() bool {
+	return interactive && category == ""
+}
+
+// confirmCleanup asks the user before anything is deleted. Cleanups above the
+// configured size or touching system-wide paths need the amount typed out
+// instead of y/N. --force skips the prompt, except for system paths.
+// askYesNo is false when the interactive browser has already asked.
+func confirmCleanup(cfg *config.Config, result *scanner.ScanResult, askYesNo bool) (bool, error) {
+	if cfg.DryRun {
+		return true, nil
+	}
+
+	var reasons []string
+	if cfg.Confirmation.TypedThreshold != "" {
+		threshold, err := utils.ParseSize(cfg.Confirmation.TypedThreshold)
+		if err == nil && threshold > 0 && result.TotalSize >= threshold {
+			reasons = append(reasons, i18n.T("This cleanup removes %s (confirmation threshold is %s).",
+				formatBytes(result.TotalSize), cfg.Confirmation.TypedThreshold))
+		}
+	}
+
+	system := false
+	if cfg.Confirmation.SystemPaths {
+		home, _ := os.UserHomeDir()
+		files, err := result.SystemFiles(home)
+		if err != nil {
+			return false, err
+		}
+		if len(files) > 0 {
+			system = true
+			reasons = append(reasons, i18n.T("%d item(s) are in system-wide locations, e.g. %s", len(files), files[0].Path))
+		}
+	}
+
+	if len(reasons) == 0 || (force && !system) {
+		if force || !askYesNo {
+			return true, nil
+		}
+		fmt.Fprint(promptOut(), i18n.T("\nProceed with cleanup? (y/N): "))
+		var response string
+		fmt.Scanln(&response)
+		return response == "y" || response == "Y", nil
+	}
+
+	token := ui.ConfirmToken(result.TotalSize)
+	if confirmAmount != "" {
+		if !ui.TypedConfirmMatches(confirmAmount, token) {
+			return false, fmt.Errorf("--confirm %s does not match the amount to delete (%s)", confirmAmount, token)
+		}
+		return true, nil
+	}
+	if !ui.IsInteractive() {
+		return false, fmt.Errorf("this cleanup needs a typed confirmation, rerun with --confirm %s", token)
+	}
+
+	return ui.PromptTypedConfirm(os.Stdin, promptOut(), token, reasons), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// answer feeds response to the next prompt on stdin
+func answer(t *testing.T, response string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(response + "\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+func TestCleanInteractiveWithCategoryAsks(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive bool
+		category    string
+		wantPicker  bool
+	}{
+		{"interactive", true, "", true},
+		{"interactive with category", true, "cache", false},
+		{"category", false, "cache", false},
+		{"neither", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interactive, category = tt.interactive, tt.category
+			t.Cleanup(func() { interactive, category = false, "" })
+			if got := pickerRuns(); got != tt.wantPicker {
+				t.Fatalf("pickerRuns() = %v, want %v", got, tt.wantPicker)
+			}
+			if tt.wantPicker {
+				return
+			}
+
+			// Without the picker, nothing is deleted until the user says y
+			answer(t, "n")
+			result := &scanner.ScanResult{Files: []scanner.FileInfo{{Path: "/tmp/x", Size: 1, Category: "cache"}}, TotalSize: 1, TotalCount: 1}
+			ok, err := confirmCleanup(&config.Config{}, result, !pickerRuns())
+			if err != nil {
+				t.Fatalf("confirmCleanup failed: %v", err)
+			}
+			if ok {
+				t.Error("expected the cleanup declined at the y/N prompt")
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/daemon"
+	"github.com/fenilsonani/system-cleanup/internal/runid"
+	"github.com/spf13/cobra"
+)
+
+// daemonRunsLast limits 'daemon runs' to the most recent runs
+var daemonRunsLast int
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Check on the cleanup daemon and its scheduled runs",
+}
+
+// daemonLogTail is how many lines of the daemon's log 'daemon status
+// --verbose' shows
+const daemonLogTail = 20
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running and how each schedule last ran",
+	Long: `Shows whether the daemon is running and how each schedule last ran.
+--verbose also shows the daemon's log files, with their sizes, and the end of
+its log.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		pid, running, staleLock, err := daemon.Status(cfg)
+		switch {
+		case err != nil:
+			fmt.Printf("Daemon: %v\n", err)
+		case running:
+			fmt.Printf("Daemon: running as PID %d\n", pid)
+		case pid != 0:
+			fmt.Printf("Daemon: not running (the PID file names process %d)\n", pid)
+		default:
+			fmt.Println("Daemon: not running")
+		}
+		if staleLock != "" {
+			fmt.Printf("Stale lock file: %s\n", staleLock)
+		}
+
+		if cfg.Daemon == nil || len(cfg.Daemon.Schedules) == 0 {
+			fmt.Println("No schedules configured")
+		} else {
+			last, err := daemon.NewHistory(daemon.StateFile(cfg)).Last()
+			if err != nil {
+				return err
+			}
+			fmt.Println("\nSchedules:")
+			for _, schedule := range cfg.Daemon.Schedules {
+				run, ok := last[schedule.Name]
+				if !ok {
+					fmt.Printf("  %-20s %-15s never run\n", schedule.Name, schedule.Triggers())
+					continue
+				}
+				fmt.Printf("  %-20s %-15s last ran %s: %s\n", schedule.Name, schedule.Triggers(),
+					run.Started.Local().Format("2006-01-02 15:04"), runOutcome(run))
+			}
+		}
+
+		if verbose {
+			printDaemonLog(cfg)
+		}
+		return nil
+	},
+}
+
+// printDaemonLog lists the daemon's log files and prints the end of its log
+func printDaemonLog(cfg *config.Config) {
+	if cfg.Daemon == nil || cfg.Daemon.LogFile == "" {
+		fmt.Println("\nLog: the daemon logs to its standard output (daemon.log_file isn't set)")
+		return
+	}
+	rotation := daemon.LogRotationFor(cfg.Daemon)
+	fmt.Printf("\nLog: rotated at %s, keeping %d files for up to %d days\n",
+		formatBytes(rotation.MaxSize), rotation.MaxFiles, int(rotation.MaxAge.Hours()/24))
+	for _, name := range daemon.LogFiles(cfg.Daemon.LogFile) {
+		if info, err := os.Stat(name); err == nil {
+			fmt.Printf("  %-40s %10s  %s\n", name, formatBytes(info.Size()), info.ModTime().Format("2006-01-02 15:04"))
+		}
+	}
+
+	lines, err := daemon.LogTail(cfg.Daemon.LogFile, daemonLogTail)
+	if err != nil {
+		fmt.Printf("Couldn't read the log: %v\n", err)
+		return
+	}
+	fmt.Printf("\nLast %d lines of %s:\n", len(lines), cfg.Daemon.LogFile)
+	for _, line := range lines {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+var daemonRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the daemon's recent runs",
+	Long: `Lists the runs of scheduled jobs recorded in the daemon state
+(daemon.state_file), most recent first: when each started and how long it
+took, what triggered it, what it reclaimed and any errors. Use it to check that
+your schedules actually fire.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		runs, err := daemon.NewHistory(daemon.StateFile(cfg)).Runs()
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("The daemon hasn't recorded any runs")
+			return nil
+		}
+		if daemonRunsLast > 0 && len(runs) > daemonRunsLast {
+			runs = runs[len(runs)-daemonRunsLast:]
+		}
+
+		for i := len(runs) - 1; i >= 0; i-- {
+			run := runs[i]
+			fmt.Printf("%s  %-20s %-8s %-9s %8s  %s  (run %s)\n",
+				run.Started.Local().Format("2006-01-02 15:04"), run.Job, run.Action, run.TriggeredBy,
+				run.Duration().Round(time.Second), runOutcome(run), runid.Short(run.RunID))
+			for _, msg := range run.Errors {
+				fmt.Printf("    %s\n", msg)
+			}
+		}
+		return nil
+	},
+}
+
+// runOutcome summarizes what a run did
+func runOutcome(run daemon.RunRecord) string {
+	var outcome string
+	switch {
+	case run.Failed != "":
+		outcome = "failed: " + run.Failed
+	case run.DryRun:
+		outcome = fmt.Sprintf("dry run, found %d files", run.FilesFound)
+	case run.Action != "" && run.Action != config.ActionClean:
+		outcome = fmt.Sprintf("reported %d files", run.FilesFound)
+	default:
+		outcome = fmt.Sprintf("deleted %d files (%s), reclaimed %s", run.FilesDeleted, formatBytes(run.BytesDeleted), formatBytes(run.BytesReclaimed))
+	}
+	if run.Failed == "" && len(run.Errors) > 0 {
+		outcome += fmt.Sprintf(", %d errors", len(run.Errors))
+	}
+	return outcome
+}
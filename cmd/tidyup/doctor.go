@@ -0,0 +1,351 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/daemon"
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+// Outcomes of a doctor check
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// doctorCheck is the outcome of one environment check, with a hint on how to
+// fix it if it didn't pass
+type doctorCheck struct {
+	name   string
+	status string
+	detail string
+	hint   string
+}
+
+// conflictingCleaners are other tools that delete files in the same places,
+// with the executables or macOS apps that give them away
+var conflictingCleaners = []struct {
+	name    string
+	targets []string
+}{
+	{"BleachBit", []string{"bleachbit"}},
+	{"tmpreaper", []string{"tmpreaper"}},
+	{"tmpwatch", []string{"tmpwatch"}},
+	{"CleanMyMac", []string{"/Applications/CleanMyMac X.app", "/Applications/CleanMyMac.app"}},
+	{"OnyX", []string{"/Applications/OnyX.app"}},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment tidyup runs in",
+	Long: `Checks that the config is valid, the scan cache and ignore list are
+readable, the tools tidyup relies on are installed, the daemon is healthy, and
+the directories it cleans can be read. Each check passes, warns or fails, with
+a hint on how to fix it. Exits with an error if any check fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checks := []doctorCheck{checkConfig()}
+		cfg, err := loadConfig()
+		if err != nil {
+			// The remaining checks still work from the defaults
+			cfg = config.GetDefault()
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		checks = append(checks,
+			checkScanCache(),
+			checkIgnoreList(),
+			checkSearchIndex(platformInfo),
+			checkDu(),
+			checkSudo(),
+			checkDaemon(cfg),
+			checkOwnDirs(),
+			checkCleanDirs(platformInfo),
+			checkConflicts(),
+		)
+
+		failed := 0
+		for _, check := range checks {
+			fmt.Printf("[%s] %-16s %s\n", check.status, check.name, check.detail)
+			if check.hint != "" && check.status != checkPass {
+				fmt.Printf("       %-16s → %s\n", "", check.hint)
+			}
+			if check.status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
+		return nil
+	},
+}
+
+// checkConfig loads every config layer and validates the result
+func checkConfig() doctorCheck {
+	check := doctorCheck{name: "Config"}
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		check.status, check.detail = checkFail, err.Error()
+		return check
+	}
+	if _, _, err := config.Resolve(config.Layers(cfgPath), configOverrides()); err != nil {
+		check.status, check.detail = checkFail, err.Error()
+		check.hint = "Fix the file named above; 'tidyup config schema' lists every key"
+		return check
+	}
+	check.status = checkPass
+	if _, err := os.Stat(cfgPath); err != nil {
+		check.detail = fmt.Sprintf("no config at %s, using the defaults", cfgPath)
+	} else {
+		check.detail = fmt.Sprintf("%s is valid", cfgPath)
+	}
+	return check
+}
+
+// checkScanCache verifies the scan cache's checksum
+func checkScanCache() doctorCheck {
+	check := doctorCheck{name: "Scan cache"}
+	path, lastScan, err := scanner.CheckCache()
+	switch {
+	case os.IsNotExist(err):
+		check.status, check.detail = checkPass, "not created yet"
+	case err != nil:
+		check.status, check.detail = checkWarn, fmt.Sprintf("%s: %v", path, err)
+		check.hint = fmt.Sprintf("The next scan replaces it, or delete %s now", path)
+	default:
+		check.status = checkPass
+		check.detail = fmt.Sprintf("%s, written %s ago", path, progress.FormatDuration(time.Since(lastScan)))
+	}
+	return check
+}
+
+// checkIgnoreList verifies the ignore list's checksum
+func checkIgnoreList() doctorCheck {
+	check := doctorCheck{name: "Ignore list"}
+	path := scanner.IgnoreListPath()
+	list, err := scanner.LoadIgnoreList(path)
+	if err != nil {
+		check.status, check.detail = checkWarn, err.Error()
+		check.hint = fmt.Sprintf("Scans flag everything in it again; delete %s and dismiss the files again", path)
+		return check
+	}
+	check.status, check.detail = checkPass, fmt.Sprintf("%d files dismissed", list.Len())
+	return check
+}
+
+// checkSearchIndex checks for Spotlight, which finds large and old files on
+// macOS without walking the disk
+func checkSearchIndex(info *platform.Info) doctorCheck {
+	check := doctorCheck{name: "Search index", status: checkPass}
+	if info.OS != platform.MacOS {
+		check.detail = fmt.Sprintf("not used on %s; large and old files are found by walking the file system", info.OS)
+		return check
+	}
+	if !platform.HasSpotlight() {
+		check.status = checkWarn
+		check.detail = "Spotlight isn't available, so large and old files are found by walking the file system"
+		check.hint = "Turn indexing on with 'sudo mdutil -i on /'"
+		return check
+	}
+	out, err := exec.Command("mdutil", "-s", "/").Output()
+	if err == nil && strings.Contains(string(out), "disabled") {
+		check.status = checkWarn
+		check.detail = "Spotlight indexing is disabled for /, so large and old files are found by walking the file system"
+		check.hint = "Turn indexing on with 'sudo mdutil -i on /'"
+		return check
+	}
+	check.detail = "Spotlight is available"
+	return check
+}
+
+// checkDu checks for du, which sizes development artifacts
+func checkDu() doctorCheck {
+	check := doctorCheck{name: "du"}
+	path, err := exec.LookPath("du")
+	if err != nil {
+		check.status, check.detail = checkWarn, "not found, so development artifacts are reported as empty"
+		check.hint = "Install coreutils, or pass --verify-sizes to measure artifacts directly"
+		return check
+	}
+	check.status, check.detail = checkPass, path
+	return check
+}
+
+// checkSudo checks how files that need root can be deleted
+func checkSudo() doctorCheck {
+	check := doctorCheck{name: "Elevation", status: checkPass}
+	if os.Geteuid() == 0 {
+		check.detail = "running as root"
+		return check
+	}
+	sm := cleaner.NewSudoManager()
+	switch {
+	case sm.IsAvailable() && sm.IsPolkitAvailable():
+		check.detail = "sudo and pkexec are available"
+	case sm.IsAvailable():
+		check.detail = "sudo is available"
+	case sm.IsPolkitAvailable():
+		check.detail = "pkexec is available"
+	default:
+		check.status = checkWarn
+		check.detail = "neither sudo nor pkexec was found, so files that need root are skipped"
+		check.hint = "Install sudo, or run tidyup as root"
+	}
+	return check
+}
+
+// checkDaemon checks the daemon's PID and lock files
+func checkDaemon(cfg *config.Config) doctorCheck {
+	check := doctorCheck{name: "Daemon"}
+	pid, running, staleLock, err := daemon.Status(cfg)
+	switch {
+	case staleLock != "":
+		check.status = checkFail
+		check.detail = fmt.Sprintf("%s was left by a daemon that's no longer running, so the daemon can't start", staleLock)
+		check.hint = fmt.Sprintf("Remove it with 'rm %s'", staleLock)
+	case err != nil:
+		check.status, check.detail = checkWarn, err.Error()
+		check.hint = "Remove the PID file if the daemon isn't running"
+	case running:
+		check.status, check.detail = checkPass, fmt.Sprintf("running as PID %d", pid)
+	case pid != 0:
+		check.status = checkWarn
+		check.detail = fmt.Sprintf("the PID file names process %d, which isn't a running daemon", pid)
+		check.hint = "Restart the daemon with 'cleanup-daemon'"
+	case cfg.Daemon != nil && cfg.Daemon.Enabled:
+		check.status, check.detail = checkWarn, "daemon.enabled is set, but the daemon isn't running"
+		check.hint = "Start it with 'cleanup-daemon'"
+	default:
+		check.status, check.detail = checkPass, "not running"
+	}
+	return check
+}
+
+// checkOwnDirs checks that tidyup can write its config, cache, state and data
+// directories, or create them
+func checkOwnDirs() doctorCheck {
+	check := doctorCheck{name: "tidyup dirs"}
+	var problems []string
+	for _, dirFunc := range []func() (string, error){paths.ConfigDir, paths.CacheDir, paths.StateDir, paths.DataDir} {
+		dir, err := dirFunc()
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if err := checkWritable(dir); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", dir, err))
+		}
+	}
+	if len(problems) > 0 {
+		check.status, check.detail = checkFail, strings.Join(problems, "; ")
+		check.hint = "Fix the ownership of these directories, e.g. after running tidyup with sudo"
+		return check
+	}
+	check.status, check.detail = checkPass, "writable"
+	return check
+}
+
+// checkWritable creates and removes a file in dir, or in the nearest parent
+// that exists if dir doesn't yet
+func checkWritable(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no parent directory exists")
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".tidyup-doctor-*")
+	if err != nil {
+		return fmt.Errorf("not writable")
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkCleanDirs checks that the standard cache, temp and log directories
+// can be read
+func checkCleanDirs(info *platform.Info) doctorCheck {
+	check := doctorCheck{name: "Clean dirs"}
+	var dirs, unreadable []string
+	dirs = append(dirs, info.CacheDirs...)
+	dirs = append(dirs, info.TempDirs...)
+	dirs = append(dirs, info.LogDirs...)
+
+	checked := 0
+	for _, dir := range dirs {
+		f, err := os.Open(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		checked++
+		if err == nil {
+			_, err = f.Readdirnames(1)
+			f.Close()
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			unreadable = append(unreadable, dir)
+		}
+	}
+	if len(unreadable) > 0 {
+		check.status = checkWarn
+		check.detail = fmt.Sprintf("%d of %d can't be read: %s", len(unreadable), checked, strings.Join(unreadable, ", "))
+		check.hint = "Files there are skipped unless sudo is allowed for their category"
+		return check
+	}
+	check.status, check.detail = checkPass, fmt.Sprintf("%d directories readable", checked)
+	return check
+}
+
+// checkConflicts looks for other cleaners, which may delete files tidyup has
+// just listed or remove what it quarantined
+func checkConflicts() doctorCheck {
+	check := doctorCheck{name: "Other cleaners"}
+	var found []string
+	for _, cleaner := range conflictingCleaners {
+		for _, target := range cleaner.targets {
+			var err error
+			if filepath.IsAbs(target) {
+				if runtime.GOOS != "darwin" {
+					continue
+				}
+				_, err = os.Stat(target)
+			} else {
+				_, err = exec.LookPath(target)
+			}
+			if err == nil {
+				found = append(found, cleaner.name)
+				break
+			}
+		}
+	}
+	if len(found) > 0 {
+		check.status = checkWarn
+		check.detail = fmt.Sprintf("%s installed", strings.Join(found, ", "))
+		check.hint = "Point them at different folders or schedule them apart, so they don't delete files the other just listed"
+		return check
+	}
+	check.status, check.detail = checkPass, "none found"
+	return check
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ignoreList   bool
+	ignoreRemove bool
+)
+
+var ignoreCmd = &cobra.Command{
+	Use:   "ignore [path...]",
+	Short: "Never flag files again",
+	Long: `Adds files or directories to the ignore list, so scans never flag them
+again. Unlike whitelist_paths, which protects everything below a path, an entry
+dismisses one file and is kept by tidyup rather than in the config. Each entry
+records the file's inode, so a different file later created at the same path is
+flagged as usual. Files can also be dismissed from the file browser.
+
+With --list, prints the ignore list; with --remove, takes the paths off it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := scanner.LoadIgnoreList(scanner.IgnoreListPath())
+		if err != nil {
+			return err
+		}
+
+		if ignoreList {
+			for _, entry := range list.Entries() {
+				fmt.Printf("%s  %s\n", entry.Added.Format("2006-01-02"), entry.Path)
+			}
+			return nil
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("name the files to ignore, or pass --list")
+		}
+
+		for _, path := range args {
+			if ignoreRemove {
+				if !list.Remove(path) {
+					return fmt.Errorf("%s is not in the ignore list", path)
+				}
+				continue
+			}
+			if _, err := list.Add(path); err != nil {
+				return err
+			}
+		}
+		if err := list.Save(); err != nil {
+			return err
+		}
+		if ignoreRemove {
+			fmt.Printf("Removed %d paths from the ignore list\n", len(args))
+		} else {
+			fmt.Printf("Ignoring %d paths; they won't be flagged again\n", len(args))
+		}
+		return nil
+	},
+}
+
+// ignorePaths adds paths to the ignore list, for the file browser
+func ignorePaths(paths []string) error {
+	list, err := scanner.LoadIgnoreList(scanner.IgnoreListPath())
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, err := list.Add(path); err != nil {
+			return err
+		}
+	}
+	return list.Save()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// inodesTop is how many artifacts dev --inodes lists
+const inodesTop = 20
+
+// filesystemUsage is the usage of one file system holding scan results
+type filesystemUsage struct {
+	path  string // Directory it was measured at
+	usage platform.DiskUsage
+}
+
+// filesystemsOf returns the usage of each file system holding files, in the
+// order they're first met
+func filesystemsOf(files []scanner.FileInfo) []filesystemUsage {
+	var filesystems []filesystemUsage
+	seen := make(map[uint64]bool)
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file.Path)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		dev, err := platform.DeviceID(dir)
+		if err != nil || seen[dev] {
+			continue
+		}
+		seen[dev] = true
+		usage, err := platform.GetDiskUsage(dir)
+		if err != nil {
+			continue
+		}
+		filesystems = append(filesystems, filesystemUsage{dir, usage})
+	}
+	return filesystems
+}
+
+// formatFileUsage describes how many of a file system's inodes are free
+func formatFileUsage(usage platform.DiskUsage) string {
+	return fmt.Sprintf("%d of %d inodes free (%.1f%%)", usage.FreeFiles, usage.Files,
+		float64(usage.FreeFiles)*100/float64(usage.Files))
+}
+
+// printInodeUsage prints the bytes and inodes free on each file system
+// holding files
+func printInodeUsage(files []scanner.FileInfo) {
+	fmt.Println("\n=== File Systems ===")
+	for _, fs := range filesystemsOf(files) {
+		fmt.Printf("  %s: %s of %s free", fs.path, formatBytes(int64(fs.usage.Free)), formatBytes(int64(fs.usage.Total)))
+		if fs.usage.Files > 0 {
+			fmt.Printf(", %s", formatFileUsage(fs.usage))
+		}
+		if fs.usage.LowOnFiles() {
+			fmt.Print(" - nearly out of inodes")
+		}
+		fmt.Println()
+	}
+}
+
+// warnLowOnFiles points to dev --inodes when a file system holding files is
+// nearly out of inodes
+func warnLowOnFiles(files []scanner.FileInfo) {
+	for _, fs := range filesystemsOf(files) {
+		if fs.usage.LowOnFiles() {
+			fmt.Printf("\nWarning: the file system holding %s has %s.\n", fs.path, formatFileUsage(fs.usage))
+			fmt.Println("Run 'tidyup dev --inodes' to free the most files first")
+			return
+		}
+	}
+}
+
+// printByFileCount lists the artifacts holding the most files
+func printByFileCount(result *scanner.ScanResult) {
+	fmt.Println("\n=== Most Files ===")
+	for i, file := range result.Files {
+		if i == inodesTop {
+			fmt.Printf("  ... and %d more\n", len(result.Files)-inodesTop)
+			break
+		}
+		fmt.Printf("  %9d files %10s  %s\n", file.FileCount(), formatBytes(file.Size), file.Path)
+	}
+}
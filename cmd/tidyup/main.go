@@ -1,16 +1,28 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/fenilsonani/system-cleanup/internal/cleaner"
 	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/paths"
 	"github.com/fenilsonani/system-cleanup/internal/platform"
 	"github.com/fenilsonani/system-cleanup/internal/reporter"
+	"github.com/fenilsonani/system-cleanup/internal/runid"
 	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/telemetry"
 	"github.com/fenilsonani/system-cleanup/internal/ui"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -20,22 +32,47 @@ var (
 )
 
 var (
-	configPath      string
-	verbose         bool
-	dryRun          bool
-	force           bool
-	category        string
-	outputFmt       string
-	outputFile      string
-	minSize         string
-	minAgeDays      int
-	cleanAction     bool
-	detailed        bool
-	showLive        bool
-	appToUninstall  string
-	listApps        bool
+	configPath     string
+	verbose        bool
+	configSets     []string
+	siUnits        bool
+	dryRun         bool
+	force          bool
+	category       string
+	outputFmt      string
+	outputFile     string
+	minSize        string
+	fileTypes      []string
+	minAgeDays     int
+	cleanAction    bool
+	pickFiles      bool
+	inodesFirst    bool
+	detailed       bool
+	showLive       bool
+	appToUninstall string
+	listApps       bool
+	interactive    bool
+	noUIColor      bool
+	confirmAmount  string
+	emitScript     string
+	resumeClean    bool
+	templateFile   string
+	verifySizes    bool
+	maxRisk        string
+	owners         []string
+	scanRoot       string
+	saveState      string
+	profileScan    bool
+	cleanState     string
+	streamReport   bool
+	saveReport     bool
+	spread         time.Duration
 )
 
+// runID identifies this invocation in the deletion manifest, quarantine,
+// journal, reports and porcelain output
+var runID = runid.New()
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -60,13 +97,22 @@ var scanCmd = &cobra.Command{
 	Long: `Scans the system and reports what can be cleaned without making any changes.
 
 Use --detailed (-d) to see a tree view of all files found.
-Use --live (-l) to see real-time scanning progress.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
+Use --live (-l) to see real-time scanning progress.
+Use --path to scan only one directory tree, such as a project drive or a CI
+workspace: caches, projects and large and old files are looked for in it
+instead of your home directory.
+Use --save-state to save the files found, so 'tidyup clean --state' deletes
+exactly those rather than scanning again.
+Use --profile-scan to see which directories the scan spent longest in.`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Load config
 		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if cmd.Flags().Changed("verify-sizes") {
+			cfg.Dev.VerifySizes = verifySizes
+		}
 
 		// Get platform info
 		platformInfo, err := platform.GetInfo()
@@ -75,19 +121,31 @@ Use --live (-l) to see real-time scanning progress.`,
 		}
 
 		// Use HyperScanner - blazingly fast with caching & Spotlight
-		fmt.Println(" Scanning...")
+		sayln(" Scanning...")
 		hyperScnr := scanner.NewHyperScanner(cfg, platformInfo)
+		if scanRoot != "" {
+			root, err := scanRootDir(scanRoot)
+			if err != nil {
+				return err
+			}
+			hyperScnr.SetRoot(root)
+		}
+		if profileScan {
+			hyperScnr.EnableProfile()
+		}
 
 		// Setup live progress if enabled
 		var liveProgress *ui.LiveProgress
-		if showLive {
+		if showLive && humanOutput() {
 			liveProgress = ui.NewLiveProgress()
 			liveProgress.Start()
-			hyperScnr.SetProgressCallback(func(cat, path string, filesFound int, totalSize int64) {
-				liveProgress.Update(cat, path, filesFound, totalSize)
-			})
+			hyperScnr.SetProgressCallback(liveProgress.Update)
 		}
 
+		scanStart := time.Now()
+		trace := telemetry.New(cfg, Version).Start("tidyup scan", runID, scanStart)
+		defer func() { exportTrace(trace, err) }()
+
 		result, err := hyperScnr.ScanAll()
 
 		if liveProgress != nil {
@@ -97,9 +155,37 @@ Use --live (-l) to see real-time scanning progress.`,
 		if err != nil {
 			return fmt.Errorf("scan failed: %w", err)
 		}
+		trace.Scan(result, scanStart, hyperScnr.CategoryDurations())
+		defer func() { result.Close() }()
+		if profileScan {
+			defer printScanProfile(os.Stderr, hyperScnr.Profile())
+		}
+		warnScanErrors(result)
+		result = filterOwners(result)
+
+		if saveState != "" {
+			snapshot, err := cleaner.NewSnapshot(runID, result)
+			if err != nil {
+				return err
+			}
+			if err := snapshot.Save(saveState); err != nil {
+				return err
+			}
+			say(" Saved scan state of %d files to %s\n", len(snapshot.Files), saveState)
+		}
+
+		if porcelain {
+			return recordScan(result)
+		}
+		if quiet {
+			return nil
+		}
 
 		// Show detailed tree view if requested
 		if detailed {
+			if err := result.Load(); err != nil {
+				return err
+			}
 			files := make([]ui.FileInfo, len(result.Files))
 			for i, f := range result.Files {
 				files[i] = ui.FileInfo{
@@ -107,6 +193,9 @@ Use --live (-l) to see real-time scanning progress.`,
 					Size:     f.Size,
 					Category: f.Category,
 					Reason:   f.Reason,
+					Risk:     f.Risk,
+					Owner:    f.Owner,
+					Access:   f.Access,
 				}
 			}
 			ui.PrintDetailedTree(files, result.TotalSize)
@@ -128,18 +217,45 @@ Use --live (-l) to see real-time scanning progress.`,
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Clean the system based on configuration",
-	Long:  `Cleans the system by removing files identified during scanning.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
+	Long: `Cleans the system by removing files identified during scanning.
+
+With --state, cleans the files saved by 'tidyup scan --save-state' instead of
+scanning again, so only what was reviewed is deleted. Files replaced or
+modified since the scan are left alone, and a scan older than
+clean.state_max_age is refused.
+
+With --spread, deletions are paced over the given time at low disk and CPU
+priority, so a large cleanup doesn't cause latency spikes. Schedules set
+"spread" to have the daemon do the same.
+
+With docker.registry.garbage_collect, a local registry and BuildKit cache
+are garbage collected at the end; see 'tidyup registry'.`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		// Load config
 		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if cmd.Flags().Changed("verify-sizes") {
+			cfg.Dev.VerifySizes = verifySizes
+		}
 
 		// Override config with flags
 		if cmd.Flags().Changed("dry-run") {
 			cfg.DryRun = dryRun
 		}
+		if emitScript != "" {
+			// Writing a script never deletes anything itself
+			cfg.DryRun = true
+		}
+		if maxRisk != "" {
+			if err := scanner.ValidRisk(maxRisk); err != nil {
+				return fmt.Errorf("invalid --max-risk: %w", err)
+			}
+		}
+		if spread < 0 {
+			return fmt.Errorf("--spread must not be negative")
+		}
 
 		// Get platform info
 		platformInfo, err := platform.GetInfo()
@@ -152,21 +268,189 @@ var cleanCmd = &cobra.Command{
 
 		// Setup live progress if enabled
 		var liveProgress *ui.LiveProgress
-		if showLive {
+		if showLive && humanOutput() {
 			liveProgress = ui.NewLiveProgress()
 			liveProgress.Start()
-			hyperScnr.SetProgressCallback(func(cat, path string, filesFound int, totalSize int64) {
-				liveProgress.Update(cat, path, filesFound, totalSize)
-			})
+			hyperScnr.SetProgressCallback(liveProgress.Update)
 		}
 
+		scanStart := time.Now()
 		var scanResult *scanner.ScanResult
+		defer func() {
+			if scanResult != nil {
+				scanResult.Close()
+			}
+		}()
+		journal := cleaner.NewJournal(cfg.Clean.JournalFile)
+
+		if interactive && !humanOutput() {
+			return fmt.Errorf("--interactive can't be combined with --quiet or --porcelain")
+		}
+
+		var keymap *ui.Keymap
+		if cleanState != "" {
+			if resumeClean || interactive || category != "" {
+				return fmt.Errorf("--state can't be combined with --resume, --interactive or --category")
+			}
+			var maxAge time.Duration
+			if cfg.Clean.StateMaxAge != "" {
+				if maxAge, err = time.ParseDuration(cfg.Clean.StateMaxAge); err != nil {
+					return fmt.Errorf("invalid clean.state_max_age: %w", err)
+				}
+			}
+			snapshot, err := cleaner.LoadSnapshot(cleanState, maxAge)
+			if err != nil {
+				return err
+			}
+			var changed map[string]string
+			scanResult, changed = snapshot.Verify()
+			if len(changed) > 0 {
+				say(" Leaving out %d files changed since the scan\n", len(changed))
+				if verbose {
+					paths := make([]string, 0, len(changed))
+					for path := range changed {
+						paths = append(paths, path)
+					}
+					slices.Sort(paths)
+					for _, path := range paths {
+						say("   %s: %s\n", path, changed[path])
+					}
+				}
+			}
+			say(" Cleaning from the scan of %s: %d files\n", snapshot.CreatedAt.Format("2006-01-02 15:04"), scanResult.TotalCount)
+		} else if resumeClean {
+			if interactive || category != "" || maxRisk != "" || len(owners) > 0 {
+				return fmt.Errorf("--resume can't be combined with --interactive, --category, --max-risk or --owner")
+			}
+			if cfg.Clean.JournalFile == "" {
+				return fmt.Errorf("--resume requires clean.journal_file to be set")
+			}
+			scanResult, err = journal.Pending()
+			if errors.Is(err, os.ErrNotExist) {
+				sayln("No interrupted cleanup to resume")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if scanResult.TotalCount == 0 {
+				sayln("The interrupted cleanup has nothing left to do")
+				return journal.Finish()
+			}
+			if id := journal.RunID(); id != "" {
+				runID = id
+			}
+			say(" Resuming interrupted cleanup: %d files left\n", scanResult.TotalCount)
+		} else if pickerRuns() {
+			if !ui.IsInteractive() {
+				return fmt.Errorf("--interactive requires a terminal")
+			}
+
+			if err := ui.SetTheme(cfg.UI.Theme, noUIColor); err != nil {
+				return fmt.Errorf("invalid ui.theme: %w", err)
+			}
+			keymap, err = ui.NewKeymap(cfg.UI.Keybindings.Preset, cfg.UI.Keybindings.Bindings)
+			if err != nil {
+				return fmt.Errorf("invalid ui.keybindings: %w", err)
+			}
+
+			// Restore how the views were left last time
+			prefs := ui.DefaultPrefs()
+			prefsPath, err := ui.PrefsPath(cfg.UI.StateFile)
+			if err == nil && cfg.UI.SaveState {
+				if prefs, err = ui.LoadPrefs(prefsPath); err != nil && verbose {
+					say("Warning: %v\n", err)
+				}
+			}
+			savePrefs := func() {
+				if !cfg.UI.SaveState || prefsPath == "" {
+					return
+				}
+				if err := prefs.Save(prefsPath); err != nil && verbose {
+					say("Warning: %v\n", err)
+				}
+			}
+
+			// Scan in the background while the user picks categories
+			sel := ui.NewCategorySelector(hyperScnr.EnabledCategories())
+			sel.SetKeymap(keymap)
+			if cfg.UI.SaveState {
+				sel.SetPreviousSelection(prefs.SelectedCategories, cfg.UI.RememberSelection)
+			}
+			hyperScnr.SetProgressCallback(sel.SetProgress)
+
+			var scanErr error
+			scanDone := make(chan struct{})
+			go func() {
+				defer close(scanDone)
+				scanResult, scanErr = hyperScnr.ScanAll()
+				sel.Finish()
+			}()
+
+			selected, err := sel.Run()
+			<-scanDone
+			if err != nil {
+				return err
+			}
+			if scanErr != nil {
+				return fmt.Errorf("scan failed: %w", scanErr)
+			}
+			if selected == nil {
+				sayln("Cleanup cancelled")
+				return nil
+			}
+			prefs.SelectedCategories = selected
+			scanResult = replaceResult(scanResult, scanResult.FilterCategories(selected))
+			scanResult = filterRisk(filterOwners(scanResult))
+
+			// Let the user pick which copy of each duplicate to keep
+			if len(scanResult.Duplicates) > 0 {
+				groups := make([]ui.DuplicateGroup, len(scanResult.Duplicates))
+				for i, g := range scanResult.Duplicates {
+					groups[i].Size = g.Size
+					for _, f := range g.Files {
+						groups[i].Files = append(groups[i].Files, ui.FileInfo{
+							Path:     f.Path,
+							Size:     f.Size,
+							Category: scanner.DuplicatesCategory,
+							ModTime:  f.ModTime,
+						})
+					}
+				}
+				dupView := ui.NewDuplicatesView(groups)
+				dupView.SetKeymap(keymap)
+				dupView.SetProjectDirs(cfg.Dev.ProjectDirs)
+				remove, err := dupView.Run()
+				if err != nil {
+					return err
+				}
+				if remove == nil {
+					sayln("Cleanup cancelled")
+					return nil
+				}
+				paths := make([]string, len(remove))
+				for i, f := range remove {
+					paths[i] = f.Path
+				}
+				scanResult = replaceResult(scanResult, scanResult.ChooseDuplicates(paths))
+			}
 
-		if category != "" {
-			fmt.Printf(" Scanning category: %s...\n", category)
+			// Let the user review individual files before anything is deleted
+			paths, err := reviewFiles(scanResult, cfg.Scan.MaxResults, keymap, prefs, cfg.UI.SaveState)
+			if err != nil {
+				return err
+			}
+			savePrefs()
+			if paths == nil {
+				sayln("Cleanup cancelled")
+				return nil
+			}
+			scanResult = replaceResult(scanResult, scanResult.FilterPaths(paths))
+		} else if category != "" {
+			say(" Scanning category: %s...\n", category)
 			scanResult = hyperScnr.ScanCategory(category)
 		} else {
-			fmt.Println(" Scanning...")
+			sayln(" Scanning...")
 			var scanErr error
 			scanResult, scanErr = hyperScnr.ScanAll()
 			if scanErr != nil {
@@ -180,32 +464,54 @@ var cleanCmd = &cobra.Command{
 		if liveProgress != nil {
 			liveProgress.Finish()
 		}
+		warnScanErrors(scanResult)
+		scanResult = filterRisk(filterOwners(scanResult))
+
+		// Started here, once --resume has picked the run ID
+		trace := telemetry.New(cfg, Version).Start("tidyup clean", runID, scanStart)
+		defer func() { exportTrace(trace, err) }()
+		if cleanState == "" && !resumeClean {
+			trace.Scan(scanResult, scanStart, hyperScnr.CategoryDurations())
+		}
 
 		// Check if any files found
 		if scanResult.TotalCount == 0 {
-			fmt.Println("\n No files found for cleanup. Your system is already clean!")
+			sayln("\n No files found for cleanup. Your system is already clean!")
+			collectGarbage(cmd.Context(), cfg)
+			recordClean(&cleaner.CleanResult{DryRun: cfg.DryRun})
 			return nil
 		}
 
 		// Show summary
-		rptr := reporter.New(os.Stdout, reporter.FormatSummary)
-		if err := rptr.Report(scanResult); err != nil {
-			return fmt.Errorf("failed to generate report: %w", err)
+		if humanOutput() {
+			rptr := reporter.New(os.Stdout, reporter.FormatSummary)
+			if err := rptr.Report(scanResult); err != nil {
+				return fmt.Errorf("failed to generate report: %w", err)
+			}
 		}
 
-		// Confirm if not force mode
-		if !force && !cfg.DryRun {
-			fmt.Print("\nProceed with cleanup? (y/N): ")
-			var response string
-			fmt.Scanln(&response)
-			if response != "y" && response != "Y" {
-				fmt.Println("Cleanup cancelled")
-				return nil
-			}
+		// Confirm unless --force (the interactive browser already asked y/N)
+		ok, err := confirmCleanup(cfg, scanResult, !pickerRuns())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			sayln("Cleanup cancelled")
+			return nil
+		}
+
+		// The cleaner needs every file at once
+		if err := scanResult.Load(); err != nil {
+			return err
 		}
 
 		// Create cleaner
 		clnr := cleaner.New(cfg)
+		clnr.SetRunID(runID)
+		clnr.SetSpread(spread)
+		if !cfg.DryRun && cfg.Clean.JournalFile != "" {
+			clnr.SetJournal(journal)
+		}
 
 		// Don't prompt for sudo if --force is used
 		if force {
@@ -213,50 +519,108 @@ var cleanCmd = &cobra.Command{
 		}
 
 		if cfg.DryRun {
-			fmt.Println("\n[DRY RUN MODE] No files will be deleted.")
+			sayln("\n[DRY RUN MODE] No files will be deleted.")
 
 			// Show permission analysis
 			permReport := clnr.GetPermissionReport(scanResult)
 			if len(permReport.RequiresSudo) > 0 {
-				fmt.Printf("\n📋 Permission Analysis:\n")
-				fmt.Printf("    Normal files: %d (%s)\n",
+				say("\n📋 Permission Analysis:\n")
+				say("    Normal files: %d (%s)\n",
 					len(permReport.NormalFiles),
 					formatBytes(permReport.TotalNormalSize))
-				fmt.Printf("    Requires sudo: %d (%s)\n",
+				say("    Requires sudo: %d (%s)\n",
 					len(permReport.RequiresSudo),
 					formatBytes(permReport.TotalSudoSize))
 				if len(permReport.InaccessibleFiles) > 0 {
-					fmt.Printf("     Inaccessible: %d files\n", len(permReport.InaccessibleFiles))
+					say("     Inaccessible: %d files\n", len(permReport.InaccessibleFiles))
+				}
+				suggestFixOwnership(permReport.RequiresSudo)
+			}
+
+			if emitScript != "" {
+				if err := writeDeletionScript(clnr, scanResult, emitScript); err != nil {
+					return err
 				}
+				say("\n📝 Deletion script written to %s (review it before running)\n", emitScript)
 			}
 		} else {
-			fmt.Println("\nCleaning...")
+			sayln("\nCleaning...")
+			if spread > 0 {
+				say(" Spreading the deletions over %s at low priority\n", spread)
+			}
 		}
 
 		// Clean
-		cleanResult, err := clnr.Clean(scanResult)
+		cleanStart := time.Now()
+		var cleanResult *cleaner.CleanResult
+		if interactive && !cfg.DryRun {
+			// Sudo can't prompt in raw mode; the results screen offers escalation instead
+			clnr.SetAskSudo(false)
+			cleanResult, err = ui.RunCleanView(clnr, scanResult, keymap)
+		} else {
+			cleanResult, err = clnr.Clean(scanResult)
+			if err == nil && !porcelain && !quiet {
+				err = offerAppRetry(clnr, scanResult, cleanResult)
+			}
+		}
+		if cleanResult != nil {
+			// An interrupted cleanup still reports what it deleted
+			trace.Clean(cleanResult, cleanStart)
+		}
 		if err != nil {
 			return fmt.Errorf("clean failed: %w", err)
 		}
+		collectGarbage(cmd.Context(), cfg)
+		for _, hookErr := range cleanResult.HookErrors {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", hookErr)
+		}
+
+		if porcelain {
+			recordClean(cleanResult)
+			return nil
+		}
+		if quiet {
+			if len(cleanResult.Errors) > 0 {
+				fmt.Fprint(os.Stderr, cleaner.FormatErrorSummary(cleanResult.Errors))
+			}
+			return nil
+		}
 
 		// Show results
-		fmt.Printf("\n Cleanup Complete!\n")
-		fmt.Printf(" Successfully deleted: %d files (%s)\n",
+		say("\n Cleanup Complete!\n")
+		say(" Successfully deleted: %d files (%s)\n",
 			len(cleanResult.DeletedFiles),
 			formatBytes(cleanResult.DeletedSize))
+		sayReclaimed(cleanResult)
+		sayCopied(cleanResult)
+		if verbose {
+			say(" Run ID: %s\n", cleanResult.RunID)
+		}
+		if home, err := os.UserHomeDir(); err == nil && !cleanResult.DryRun {
+			if usage, err := platform.GetDiskUsage(home); err == nil {
+				say(" Free space: %s of %s\n", formatBytes(int64(usage.Free)), formatBytes(int64(usage.Total)))
+				if usage.Files > 0 {
+					say(" Free inodes: %d of %d\n", usage.FreeFiles, usage.Files)
+				}
+			}
+		}
 
 		if cleanResult.UsedSudo {
-			fmt.Printf(" Used elevated permissions: %d succeeded, %d failed\n",
+			say(" Used elevated permissions: %d succeeded, %d failed\n",
 				cleanResult.SudoSucceeded,
 				cleanResult.SudoFailed)
 		}
+		suggestFixOwnership(cleanResult.NeedsSudo)
 
 		if len(cleanResult.SkippedFiles) > 0 {
-			fmt.Printf("\n  Skipped: %d files\n", len(cleanResult.SkippedFiles))
+			say("\n  Skipped: %d files\n", len(cleanResult.SkippedFiles))
+		}
+		for _, dir := range cleanResult.ReadOnly {
+			say("   %s is on a read-only file system; its files were skipped\n", dir)
 		}
 
 		if len(cleanResult.Errors) > 0 {
-			fmt.Printf("\n%s", cleaner.FormatErrorSummary(cleanResult.Errors))
+			say("\n%s", cleaner.FormatErrorSummary(cleanResult.Errors))
 		}
 
 		return nil
@@ -273,6 +637,9 @@ var reportCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if cmd.Flags().Changed("verify-sizes") {
+			cfg.Dev.VerifySizes = verifySizes
+		}
 
 		// Get platform info
 		platformInfo, err := platform.GetInfo()
@@ -281,13 +648,35 @@ var reportCmd = &cobra.Command{
 		}
 
 		// Use HyperScanner
-		fmt.Println(" Scanning...")
+		sayln(" Scanning...")
 		hyperScnr := scanner.NewHyperScanner(cfg, platformInfo)
 
+		if streamReport {
+			return reportStreamed(hyperScnr, reporter.NewMetadata(platformInfo, cfg, 0, hyperScnr.Engine(), Version))
+		}
+
+		scanStart := time.Now()
 		result, err := hyperScnr.ScanAll()
 		if err != nil {
 			return fmt.Errorf("scan failed: %w", err)
 		}
+		defer func() { result.Close() }()
+		warnScanErrors(result)
+		result = filterOwners(result)
+		meta := reporter.NewMetadata(platformInfo, cfg, time.Since(scanStart), hyperScnr.Engine(), Version)
+		meta.RunID = runID
+
+		// Keep a copy for report list, whatever is shown
+		if saveReport {
+			path, err := reporter.SaveReport(cfg, result, meta, time.Now())
+			if path == "" {
+				return err
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			say("Report saved to: %s\n", path)
+		}
 
 		// Parse format
 		var format reporter.OutputFormat
@@ -298,18 +687,51 @@ var reportCmd = &cobra.Command{
 			format = reporter.FormatYAML
 		case "table":
 			format = reporter.FormatTable
+		case "html":
+			format = reporter.FormatHTML
+		case "markdown", "md":
+			format = reporter.FormatMarkdown
+		case "csv":
+			format = reporter.FormatCSV
 		default:
 			format = reporter.FormatSummary
 		}
 
+		if porcelain && outputFile == "" && templateFile == "" {
+			return recordScan(result)
+		}
+
+		// Render a custom template
+		if templateFile != "" {
+			out := os.Stdout
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					return fmt.Errorf("failed to save report: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+			rptr := reporter.New(out, format)
+			rptr.SetMetadata(meta)
+			if err := rptr.ReportTemplate(result, templateFile); err != nil {
+				return err
+			}
+			if outputFile != "" {
+				say("Report saved to: %s\n", outputFile)
+			}
+			return nil
+		}
+
 		// Generate report
 		if outputFile != "" {
-			if err := reporter.SaveToFile(result, outputFile, format); err != nil {
+			if err := reporter.SaveToFile(result, outputFile, format, meta); err != nil {
 				return fmt.Errorf("failed to save report: %w", err)
 			}
-			fmt.Printf("Report saved to: %s\n", outputFile)
+			say("Report saved to: %s\n", outputFile)
 		} else {
 			rptr := reporter.New(os.Stdout, format)
+			rptr.SetMetadata(meta)
 			if err := rptr.Report(result); err != nil {
 				return fmt.Errorf("failed to generate report: %w", err)
 			}
@@ -319,12 +741,93 @@ var reportCmd = &cobra.Command{
 	},
 }
 
+// reportBatchSize is how many files a streamed report holds at a time
+const reportBatchSize = 1000
+
+// reportStreamed writes report --stream's JSON or CSV report as the scan
+// finds files, to --file or stdout
+func reportStreamed(hyperScnr *scanner.HyperScanner, meta *reporter.Metadata) error {
+	var format reporter.OutputFormat
+	switch outputFmt {
+	case "json":
+		format = reporter.FormatJSON
+	case "csv":
+		format = reporter.FormatCSV
+	default:
+		return fmt.Errorf("--stream needs --output json or csv")
+	}
+	if templateFile != "" || porcelain || len(owners) > 0 || saveReport {
+		return fmt.Errorf("--stream can't be used with --template, --porcelain, --owner or --save")
+	}
+
+	out := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to save report: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	meta.RunID = runID
+	rptr := reporter.New(out, format)
+	rptr.SetMetadata(meta)
+	result, err := rptr.ReportStream(hyperScnr.Stream(reportBatchSize))
+	if err != nil {
+		return fmt.Errorf("failed to generate report: %w", err)
+	}
+	warnScanErrors(result)
+	if outputFile != "" {
+		say("Report saved to: %s\n", outputFile)
+	}
+	return nil
+}
+
+var reportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved reports",
+	Long: `Lists the reports kept by 'tidyup report --save' in reports.dir, newest
+first: when each was made, what it found, its size and where it is. They're
+gzipped JSON reports; read one with zcat.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		reports, err := reporter.ListReports(reporter.ReportsDir(cfg))
+		if err != nil {
+			return err
+		}
+		if len(reports) == 0 {
+			fmt.Println("No saved reports; save one with 'tidyup report --save'")
+			return nil
+		}
+		for _, report := range reports {
+			found := "unreadable"
+			if report.TotalFiles >= 0 {
+				found = fmt.Sprintf("%d files, %s", report.TotalFiles, formatBytes(report.TotalSize))
+			}
+			run := ""
+			if report.RunID != "" {
+				run = fmt.Sprintf("  (run %s)", runid.Short(report.RunID))
+			}
+			fmt.Printf("%s  %-24s %9s  %s%s\n",
+				report.Created.Format("2006-01-02 15:04:05"), found, formatBytes(report.Size), report.Path, run)
+		}
+		return nil
+	},
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Display current configuration",
-	Long:  `Shows the current configuration being used.`,
+	Long: `Shows the config files being used. They are merged in this order, later
+ones overriding earlier ones: ` + config.SystemConfigPath + `, the user config and
+` + config.ProjectConfigFile + ` in the current directory.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfgPath, err := config.GetConfigPath()
+		cfgPath, err := userConfigPath()
 		if err != nil {
 			return err
 		}
@@ -335,10 +838,78 @@ var configCmd = &cobra.Command{
 		if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
 			fmt.Println("Config file does not exist. Using default configuration.")
 			fmt.Println("\nTo create a config file:")
-			fmt.Printf("  mkdir -p %s\n", cfgPath[:len(cfgPath)-len("/config.yaml")])
+			fmt.Printf("  mkdir -p %s\n", filepath.Dir(cfgPath))
 			fmt.Printf("  cp configs/cleanup.example.yaml %s\n", cfgPath)
 		}
 
+		fmt.Println("\nConfig layers (later ones win):")
+		for _, layer := range config.Layers(cfgPath) {
+			status := "not found"
+			if _, err := os.Stat(layer); err == nil {
+				status = "loaded"
+			}
+			fmt.Printf("  %-40s %s\n", layer, status)
+		}
+
+		return nil
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the config file",
+	Long: `Prints a JSON Schema describing the config file, for autocompletion and
+validation in editors. With the YAML language server, for example:
+
+  tidyup config schema > ~/.config/tidyup/config.schema.json
+
+and add this first line to config.yaml:
+
+  # yaml-language-server: $schema=config.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := json.MarshalIndent(config.Schema(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+var configShowEffective bool
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Prints the configuration tidyup would use: the defaults, merged with the
+system, user and project config files, then TIDYUP_* environment variables
+(e.g. TIDYUP_RETRY_MAX_ATTEMPTS=2) and --set flags. Each value is annotated
+with where it came from, so you can see why something is or isn't cleaned.
+Use --effective=false for plain YAML.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, err := userConfigPath()
+		if err != nil {
+			return err
+		}
+		cfg, sources, err := config.Resolve(config.Layers(cfgPath), configOverrides())
+		if err != nil {
+			return err
+		}
+
+		if !configShowEffective {
+			data, err := yaml.Marshal(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to marshal config: %w", err)
+			}
+			fmt.Print(string(data))
+			return nil
+		}
+
+		data, err := config.Effective(cfg, sources)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
 		return nil
 	},
 }
@@ -347,7 +918,11 @@ var devCmd = &cobra.Command{
 	Use:   "dev",
 	Short: "Scan for development artifacts",
 	Long: `Scans for development artifacts like node_modules, virtual environments,
-and build directories (.next, dist, target, __pycache__, etc.)`,
+and build directories (.next, dist, target, __pycache__, etc.)
+
+With --inodes, for a disk that's out of inodes rather than space, the files in
+each artifact are counted exactly and the artifacts holding the most files are
+listed, and cleaned, first.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := loadConfig()
 		if err != nil {
@@ -372,6 +947,13 @@ and build directories (.next, dist, target, __pycache__, etc.)`,
 		if cmd.Flags().Changed("dry-run") {
 			cfg.DryRun = dryRun
 		}
+		if cmd.Flags().Changed("verify-sizes") {
+			cfg.Dev.VerifySizes = verifySizes
+		}
+		if inodesFirst {
+			// Estimated counts follow the size, so they can't rank by count
+			cfg.Dev.VerifySizes = true
+		}
 
 		platformInfo, err := platform.GetInfo()
 		if err != nil {
@@ -385,6 +967,7 @@ and build directories (.next, dist, target, __pycache__, etc.)`,
 		if err != nil {
 			return fmt.Errorf("scan failed: %w", err)
 		}
+		defer result.Close()
 
 		if result.TotalCount == 0 {
 			fmt.Println("\nNo development artifacts found in configured project directories.")
@@ -404,11 +987,25 @@ and build directories (.next, dist, target, __pycache__, etc.)`,
 
 		fmt.Printf("\nTotal reclaimable: %s\n", formatBytes(result.TotalSize))
 
+		if inodesFirst {
+			if err := result.SortByFiles(); err != nil {
+				return err
+			}
+			printInodeUsage(result.Files)
+			printByFileCount(result)
+		} else if err := result.Load(); err == nil {
+			warnLowOnFiles(result.Files)
+		}
+
 		// If --clean flag is set, proceed with cleanup
 		if cleanAction {
 			return cleanDevArtifacts(cfg, result)
 		}
 
+		if inodesFirst {
+			fmt.Println("\nRun 'tidyup dev --inodes --clean' to remove these artifacts, most files first")
+			return nil
+		}
 		fmt.Println("\nRun 'tidyup dev --clean' to remove these artifacts")
 		return nil
 	},
@@ -440,11 +1037,19 @@ var largeCmd = &cobra.Command{
 
 		// Override config with flags
 		if cmd.Flags().Changed("min") {
+			if _, err := utils.ParseSize(minSize); err != nil {
+				return fmt.Errorf("invalid --min: %w", err)
+			}
 			cfg.LargeFiles.MinSize = minSize
 		}
 		if cmd.Flags().Changed("dry-run") {
 			cfg.DryRun = dryRun
 		}
+		for _, t := range fileTypes {
+			if !slices.Contains(scanner.FileTypes, t) {
+				return fmt.Errorf("unknown file type %q (valid types: %s)", t, strings.Join(scanner.FileTypes, ", "))
+			}
+		}
 
 		platformInfo, err := platform.GetInfo()
 		if err != nil {
@@ -458,26 +1063,52 @@ var largeCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("scan failed: %w", err)
 		}
+		defer func() { result.Close() }()
+
+		if len(fileTypes) > 0 {
+			result = replaceResult(result, result.FilterTypes(fileTypes))
+		}
 
 		if result.TotalCount == 0 {
 			fmt.Printf("\nNo files larger than %s found.\n", cfg.LargeFiles.MinSize)
 			return nil
 		}
 
+		// Let the user choose the files to remove instead of listing them
+		if pickFiles {
+			return pickAndClean(cfg, result, "large files", false)
+		}
+
 		// Show results
 		fmt.Println("\n=== Large Files ===")
-		for _, file := range result.Files {
-			fmt.Printf("  %s - %s\n", formatBytes(file.Size), file.Path)
+		err = result.Each(func(file scanner.FileInfo) error {
+			fmt.Printf("  %s - %-10s - %s\n", formatBytes(file.Size), file.Type, file.Path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		types, err := result.TypeTotals()
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nBy type:")
+		for _, t := range scanner.FileTypes {
+			if total, ok := types[t]; ok {
+				fmt.Printf("  %-10s %d files, %s\n", t, total.Count, formatBytes(total.Size))
+			}
 		}
 
 		fmt.Printf("\nTotal: %d files, %s\n", result.TotalCount, formatBytes(result.TotalSize))
 
 		// If --clean flag is set, proceed with cleanup
 		if cleanAction {
-			return cleanFiles(cfg, result, "large files")
+			_, err := cleanFiles(cfg, result, "large files", true)
+			return err
 		}
 
-		fmt.Println("\nRun 'tidyup large --clean' to remove these files")
+		fmt.Println("\nRun 'tidyup large --clean' to remove these files, or 'tidyup large --pick' to choose which")
 		return nil
 	},
 }
@@ -527,48 +1158,78 @@ Scans Downloads, Documents, and Desktop by default.`,
 		if err != nil {
 			return fmt.Errorf("scan failed: %w", err)
 		}
+		defer result.Close()
 
 		if result.TotalCount == 0 {
 			fmt.Printf("\nNo files found that haven't been accessed in %d days.\n", cfg.OldFiles.MinAgeDays)
 			return nil
 		}
 
+		// Let the user choose the files to remove instead of listing them
+		if pickFiles {
+			return pickAndClean(cfg, result, "old files", false)
+		}
+
 		// Show results
 		fmt.Println("\n=== Old/Unused Files ===")
-		for _, file := range result.Files {
+		err = result.Each(func(file scanner.FileInfo) error {
 			fmt.Printf("  %s - %s\n    %s\n", formatBytes(file.Size), file.Path, file.Reason)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		fmt.Printf("\nTotal: %d files, %s\n", result.TotalCount, formatBytes(result.TotalSize))
 
 		// If --clean flag is set, proceed with cleanup
 		if cleanAction {
-			return cleanFiles(cfg, result, "old files")
+			_, err := cleanFiles(cfg, result, "old files", true)
+			return err
 		}
 
-		fmt.Println("\nRun 'tidyup old --clean' to remove these files")
+		fmt.Println("\nRun 'tidyup old --clean' to remove these files, or 'tidyup old --pick' to choose which")
 		return nil
 	},
 }
 
+// scanRootDir resolves the --path directory to an absolute path
+func scanRootDir(path string) (string, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid --path %s: %w", path, err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid --path: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid --path: %s is not a directory", root)
+	}
+	return root, nil
+}
+
 // cleanDevArtifacts handles cleanup of development artifacts
 func cleanDevArtifacts(cfg *config.Config, scanResult *scanner.ScanResult) error {
-	return cleanFiles(cfg, scanResult, "development artifacts")
+	_, err := cleanFiles(cfg, scanResult, "development artifacts", true)
+	return err
 }
 
-// cleanFiles is a generic function to clean files from any category
-func cleanFiles(cfg *config.Config, scanResult *scanner.ScanResult, description string) error {
-	if !force && !cfg.DryRun {
-		fmt.Print("\nProceed with cleanup? (y/N): ")
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			fmt.Println("Cleanup cancelled")
-			return nil
-		}
+// cleanFiles is a generic function to clean files from any category.
+// askYesNo is false when the user already confirmed, e.g. in the browser.
+// The result is nil if the user declined.
+func cleanFiles(cfg *config.Config, scanResult *scanner.ScanResult, description string, askYesNo bool) (*cleaner.CleanResult, error) {
+	ok, err := confirmCleanup(cfg, scanResult, askYesNo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		fmt.Println("Cleanup cancelled")
+		return nil, nil
 	}
 
 	clnr := cleaner.New(cfg)
+	clnr.SetRunID(runID)
 
 	// Don't prompt for sudo if --force is used
 	if force {
@@ -583,19 +1244,30 @@ func cleanFiles(cfg *config.Config, scanResult *scanner.ScanResult, description
 
 	cleanResult, err := clnr.Clean(scanResult)
 	if err != nil {
-		return fmt.Errorf("clean failed: %w", err)
+		return nil, fmt.Errorf("clean failed: %w", err)
+	}
+	if err := offerAppRetry(clnr, scanResult, cleanResult); err != nil {
+		return nil, fmt.Errorf("clean failed: %w", err)
 	}
 
 	fmt.Printf("\nCleanup Complete!\n")
 	fmt.Printf("Successfully removed: %d items (%s)\n",
 		len(cleanResult.DeletedFiles),
 		formatBytes(cleanResult.DeletedSize))
+	sayReclaimed(cleanResult)
+	sayCopied(cleanResult)
+	for _, dir := range cleanResult.ReadOnly {
+		fmt.Printf("%s is on a read-only file system; its files were skipped\n", dir)
+	}
+	for _, hookErr := range cleanResult.HookErrors {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", hookErr)
+	}
 
 	if len(cleanResult.Errors) > 0 {
 		fmt.Printf("\n%s", cleaner.FormatErrorSummary(cleanResult.Errors))
 	}
 
-	return nil
+	return cleanResult, nil
 }
 
 var uninstallCmd = &cobra.Command{
@@ -641,48 +1313,142 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file path")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "verbose output")
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, "override a config key, e.g. --set retry.max_attempts=2 (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&siUnits, "si", false, "show sizes in decimal units (GB, powers of 1000) instead of binary ones (GiB)")
 
 	// Scan command flags
 	scanCmd.Flags().StringVar(&outputFmt, "output", "summary", "output format (summary, table, json, yaml)")
 	scanCmd.Flags().BoolVarP(&detailed, "detailed", "d", false, "show detailed tree view of all files")
 	scanCmd.Flags().BoolVarP(&showLive, "live", "l", false, "show live scanning progress")
+	scanCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "only print errors")
+	scanCmd.Flags().BoolVar(&porcelain, "porcelain", false, "print stable tab-separated output for scripts")
+	scanCmd.Flags().BoolVar(&verifySizes, "verify-sizes", false, "count dev artifact files and sizes exactly instead of estimating them")
+	scanCmd.Flags().StringSliceVar(&owners, "owner", nil, "only show files owned by these users, by name or ID (comma-separated)")
+	scanCmd.Flags().StringVar(&scanRoot, "path", "", "only scan this directory tree, in place of your home directory")
+	scanCmd.Flags().StringVar(&saveState, "save-state", "", "save the files found to this file for clean --state")
+	scanCmd.Flags().BoolVar(&profileScan, "profile-scan", false, "list the 20 directories the scan spent longest in")
 
 	// Clean command flags
 	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
 	cleanCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	cleanCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
 	cleanCmd.Flags().StringVar(&category, "category", "", "clean only specific category (uses turbo scanner)")
 	cleanCmd.Flags().BoolVarP(&showLive, "live", "l", false, "show live scanning progress")
+	cleanCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "choose categories interactively while scanning")
+	cleanCmd.Flags().BoolVar(&noUIColor, "no-ui-color", false, "disable colors in the interactive view")
+	cleanCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "only print errors")
+	cleanCmd.Flags().BoolVar(&porcelain, "porcelain", false, "print stable tab-separated output for scripts")
+	cleanCmd.Flags().BoolVar(&verifySizes, "verify-sizes", false, "count dev artifact files and sizes exactly instead of estimating them")
+	cleanCmd.Flags().BoolVar(&resumeClean, "resume", false, "finish an interrupted cleanup without rescanning")
+	cleanCmd.Flags().StringVar(&cleanState, "state", "", "clean exactly the files saved by scan --save-state instead of scanning")
+	cleanCmd.Flags().StringVar(&maxRisk, "max-risk", "", "only clean files at or below this risk level: safe, low or review")
+	cleanCmd.Flags().StringSliceVar(&owners, "owner", nil, "only clean files owned by these users, by name or ID (comma-separated)")
+	cleanCmd.Flags().DurationVar(&spread, "spread", 0, "spread the deletions over this long at low I/O priority (e.g., 2h)")
+	cleanCmd.Flags().StringVar(&emitScript, "emit-script", "", "write a shell script with the delete commands instead of deleting (implies --dry-run)")
 
 	// Report command flags
-	reportCmd.Flags().StringVar(&outputFmt, "output", "summary", "output format (summary, table, json, yaml)")
+	reportCmd.Flags().StringVar(&outputFmt, "output", "summary", "output format (summary, table, json, yaml, csv, html, markdown)")
 	reportCmd.Flags().StringVar(&outputFile, "file", "", "save report to file")
+	reportCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "only print the report and errors")
+	reportCmd.Flags().BoolVar(&porcelain, "porcelain", false, "print stable tab-separated output for scripts")
+	reportCmd.Flags().StringVar(&templateFile, "template", "", "render the report with a Go text/template file")
+	reportCmd.Flags().BoolVar(&verifySizes, "verify-sizes", false, "count dev artifact files and sizes exactly instead of estimating them")
+	reportCmd.Flags().BoolVar(&saveReport, "save", false, "also keep a gzipped JSON copy in reports.dir (see 'report list')")
+	reportCmd.Flags().BoolVar(&streamReport, "stream", false, "write json or csv reports as files are found, in bounded memory")
+	reportCmd.Flags().StringSliceVar(&owners, "owner", nil, "only report files owned by these users, by name or ID (comma-separated)")
 
 	// Dev command flags
 	devCmd.Flags().BoolVar(&cleanAction, "clean", false, "clean the found artifacts")
 	devCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
 	devCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	devCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
+	devCmd.Flags().BoolVar(&verifySizes, "verify-sizes", false, "count artifact files and sizes exactly instead of estimating them")
+	devCmd.Flags().BoolVar(&inodesFirst, "inodes", false, "rank artifacts by the files they hold, to free inodes rather than space")
 
 	// Large command flags
 	largeCmd.Flags().StringVar(&minSize, "min", "500MB", "minimum file size (e.g., 500MB, 1GB)")
+	largeCmd.Flags().StringSliceVar(&fileTypes, "type", nil, "only show files of these types (video, disk_image, archive, database, vm_image, other)")
 	largeCmd.Flags().BoolVar(&cleanAction, "clean", false, "clean the found files")
+	largeCmd.Flags().BoolVar(&pickFiles, "pick", false, "choose which of the found files to clean")
 	largeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
 	largeCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	largeCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
 
 	// Old command flags
 	oldCmd.Flags().IntVar(&minAgeDays, "days", 180, "minimum age in days (default 180)")
 	oldCmd.Flags().BoolVar(&cleanAction, "clean", false, "clean the found files")
+	oldCmd.Flags().BoolVar(&pickFiles, "pick", false, "choose which of the found files to clean")
 	oldCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
 	oldCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	oldCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
 
 	// Add commands
 	rootCmd.AddCommand(scanCmd)
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportListCmd)
 	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configShowCmd.Flags().BoolVar(&configShowEffective, "effective", true, "annotate each value with where it came from")
 	rootCmd.AddCommand(devCmd)
 	rootCmd.AddCommand(largeCmd)
 	rootCmd.AddCommand(oldCmd)
 	rootCmd.AddCommand(uninstallCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(purgeCmd)
+	purgeCmd.Flags().BoolVar(&purgeAll, "all", false, "purge everything in the quarantine, not only expired files")
+	purgeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "list what would be purged without deleting it")
+
+	rootCmd.AddCommand(onboardCmd)
+	onboardCmd.Flags().IntVar(&onboardSample, "sample", 5, "how many of the largest items of each category to show")
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(ciCmd)
+	ciCmd.Flags().StringVar(&ciMinFree, "min-free", "", "clean when free space is below this, e.g. 20GB or 10% (default ci.min_free)")
+	ciCmd.Flags().StringVar(&ciLockFile, "lock-file", "", "skip the cleanup while this file exists (default ci.lock_file)")
+	ciCmd.Flags().BoolVar(&dryRun, "dry-run", false, "report what would be deleted without deleting it")
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "address to listen on (default api.listen)")
+	rootCmd.AddCommand(simulateCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(ignoreCmd)
+	rootCmd.AddCommand(vmDisksCmd)
+	rootCmd.AddCommand(registryCmd)
+	rootCmd.AddCommand(fixOwnershipCmd)
+	rootCmd.AddCommand(projectCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(menubarCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonRunsCmd)
+	daemonRunsCmd.Flags().IntVar(&daemonRunsLast, "last", 0, "show only the N most recent runs")
+	vmDisksCmd.Flags().BoolVar(&compactDisks, "compact", false, "prune Docker and hand the space it frees back to the host")
+	vmDisksCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the commands --compact would run without running them")
+	vmDisksCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	registryCmd.Flags().BoolVar(&collectRegistry, "gc", false, "garbage collect the registry and BuildKit cache")
+	registryCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the commands --gc would run without running them")
+	registryCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	fixOwnershipCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the chown commands without running them")
+	fixOwnershipCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	ignoreCmd.Flags().BoolVar(&ignoreList, "list", false, "print the ignore list")
+	ignoreCmd.Flags().BoolVar(&ignoreRemove, "remove", false, "take the paths off the ignore list")
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 3, "scans per engine; the fastest is reported")
+	benchCmd.Flags().StringVarP(&category, "category", "c", "", "benchmark a single category")
+	analyzeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
+	analyzeCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	analyzeCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
+	analyzeCmd.Flags().IntVar(&analyzeTop, "top", 20, "entries to list when not on a terminal")
+	projectCmd.Flags().BoolVar(&cleanAction, "clean", false, "clean what was found")
+	projectCmd.Flags().BoolVar(&pickFiles, "pick", false, "choose which of the found files to clean")
+	projectCmd.Flags().StringSliceVar(&projectOnly, "only", nil, "only these categories (node_modules, virtual_envs, build_artifacts, project_caches, vcs_ignored)")
+	projectCmd.Flags().StringVar(&projectArchive, "archive", "", "retire the project: clean it, then archive the rest here (.tar.zst, .tar.gz or .tar)")
+	projectCmd.Flags().BoolVar(&projectDeleteOriginal, "delete-original", false, "with --archive, delete the project once the archive is checked")
+	projectCmd.Flags().BoolVar(&projectAllowDirty, "allow-dirty", false, "with --archive, archive even with uncommitted, unpushed or stashed work")
+	projectCmd.Flags().IntVar(&projectTop, "top", 10, "entries to list per category (0 for all)")
+	projectCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
+	projectCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
+	projectCmd.Flags().StringVar(&confirmAmount, "confirm", "", "amount to delete when a typed confirmation is required (e.g., 52GB)")
+	simulateCmd.Flags().StringVar(&simulateFixture, "fixture", "", "YAML file describing the file tree to simulate")
 
 	// Uninstall command flags
 	uninstallCmd.Flags().StringVar(&appToUninstall, "app", "", "specific app to uninstall")
@@ -690,28 +1456,71 @@ func init() {
 	uninstallCmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompts")
 }
 
+// writeDeletionScript writes the delete commands for scanResult to an executable script at path
+func writeDeletionScript(clnr *cleaner.Cleaner, scanResult *scanner.ScanResult, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create script: %w", err)
+	}
+	if err := clnr.WriteScript(f, scanResult); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	return nil
+}
+
 func loadConfig() (*config.Config, error) {
-	if configPath != "" {
-		return config.Load(configPath)
+	cfgPath, err := userConfigPath()
+	if err != nil {
+		return nil, err
 	}
 
-	cfgPath, err := config.GetConfigPath()
+	cfg, _, err := config.Resolve(config.Layers(cfgPath), configOverrides())
 	if err != nil {
 		return nil, err
 	}
 
-	return config.Load(cfgPath)
+	utils.SetSI(siUnits || cfg.Units == "si")
+
+	// Porcelain output stays in English so scripts can parse it
+	if !porcelain {
+		i18n.SetLanguage(i18n.Detect(cfg.Language))
+	}
+	return cfg, nil
+}
+
+// configOverrides returns the TIDYUP_* environment variables and --set flags
+func configOverrides() config.Overrides {
+	return config.Overrides{Env: os.Environ(), Set: configSets}
 }
 
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// userConfigPath returns the user config layer: the --config file, or the
+// default location after moving files left by earlier versions
+func userConfigPath() (string, error) {
+	migrateLegacyFiles()
+	if configPath != "" {
+		return configPath, nil
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	return config.GetConfigPath()
+}
+
+// migrateLegacyFiles moves files from the hardcoded locations of earlier
+// versions to the XDG directories. A failed move only costs the old state.
+func migrateLegacyFiles() {
+	moves, err := paths.Migrate()
+	for _, move := range moves {
+		if humanOutput() {
+			fmt.Fprintf(os.Stderr, "Moved %s to %s\n", move.From, move.To)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't move old tidyup files: %v\n", err)
 	}
-	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGT"[exp])
+}
+
+func formatBytes(bytes int64) string {
+	return utils.FormatBytes(bytes)
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/daemon"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var menubarCmd = &cobra.Command{
+	Use:   "menubar",
+	Short: "Print a menu bar item for SwiftBar or xbar",
+	Long: `Prints the reclaimable space, the last cleanup and a "Clean now (safe
+categories)" action in the plugin format of SwiftBar and xbar, the macOS menu
+bar apps. The scan uses the scan cache, so refreshing is cheap.
+
+To install it, save a plugin script in the app's plugin folder, named for how
+often it refreshes, and make it executable:
+
+  #!/bin/sh
+  exec /usr/local/bin/tidyup menubar
+
+e.g. as tidyup.30m.sh to refresh every 30 minutes. Clean now runs
+'tidyup clean --max-risk safe --force' in the background and refreshes the
+item when it's done.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		result, err := scanner.NewHyperScanner(cfg, platformInfo).ScanAll()
+		if err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		defer result.Close()
+
+		var totals menubarTotals
+		if err := result.Each(func(file scanner.FileInfo) error {
+			totals.add(file)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "tidyup"
+		}
+		writeMenubar(os.Stdout, totals, lastCleanup(cfg), exe)
+		return nil
+	},
+}
+
+// menubarTotals is the reclaimable space by risk level
+type menubarTotals struct {
+	count  int
+	size   int64
+	byRisk map[string]int64
+}
+
+// add counts a scan result
+func (t *menubarTotals) add(file scanner.FileInfo) {
+	if t.byRisk == nil {
+		t.byRisk = make(map[string]int64)
+	}
+	t.count++
+	t.size += file.Size
+	t.byRisk[file.Risk] += file.Size
+}
+
+// cleanupSummary is the most recent cleanup, by the daemon or by hand
+type cleanupSummary struct {
+	at   time.Time
+	what string
+}
+
+// lastCleanup returns the most recent of the daemon's last run and the last
+// quarantined cleanup. The zero value means there hasn't been one.
+func lastCleanup(cfg *config.Config) cleanupSummary {
+	var last cleanupSummary
+	if runs, err := daemon.NewHistory(daemon.StateFile(cfg)).Runs(); err == nil && len(runs) > 0 {
+		run := runs[len(runs)-1]
+		last = cleanupSummary{at: run.Started, what: fmt.Sprintf("%s: %s", run.Job, runOutcome(run))}
+	}
+	if cfg.Quarantine.Dir != "" {
+		if run, err := cleaner.NewQuarantine(cfg.Quarantine.Dir).Latest(); err == nil && run.CreatedAt.After(last.at) {
+			last = cleanupSummary{at: run.CreatedAt, what: fmt.Sprintf("%d items (%s), can be undone", len(run.Entries), formatBytes(run.TotalSize()))}
+		}
+	}
+	return last
+}
+
+// writeMenubar writes the menu in the SwiftBar and xbar plugin format: the
+// title, then "---" and one line per menu item, with parameters after "|"
+func writeMenubar(w io.Writer, totals menubarTotals, last cleanupSummary, exe string) {
+	fmt.Fprintf(w, "🧹 %s\n", formatBytes(totals.size))
+	fmt.Fprintln(w, "---")
+	fmt.Fprintf(w, "Reclaimable: %s (%d items)\n", formatBytes(totals.size), totals.count)
+	for _, level := range scanner.RiskLevels {
+		fmt.Fprintf(w, "--%s: %s\n", level, formatBytes(totals.byRisk[level]))
+	}
+	if last.at.IsZero() {
+		fmt.Fprintln(w, "Last cleanup: never")
+	} else {
+		fmt.Fprintf(w, "Last cleanup: %s\n", last.at.Local().Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "--%s\n", menubarEscape(last.what))
+	}
+	fmt.Fprintln(w, "---")
+
+	safe := totals.byRisk[scanner.RiskSafe]
+	if safe > 0 {
+		fmt.Fprintf(w, "Clean now (safe categories, %s) | %s terminal=false refresh=true\n",
+			formatBytes(safe), menubarCommand(exe, "clean", "--max-risk", scanner.RiskSafe, "--force", "--quiet"))
+	} else {
+		fmt.Fprintln(w, "Clean now (safe categories): nothing to clean")
+	}
+	fmt.Fprintf(w, "Choose what to clean… | %s terminal=true\n", menubarCommand(exe, "clean", "--interactive"))
+	fmt.Fprintln(w, "Refresh | refresh=true")
+}
+
+// menubarCommand returns the parameters that run exe with args
+func menubarCommand(exe string, args ...string) string {
+	params := []string{"bash=" + strconv.Quote(exe)}
+	for i, arg := range args {
+		params = append(params, fmt.Sprintf("param%d=%s", i+1, arg))
+	}
+	return strings.Join(params, " ")
+}
+
+// menubarEscape keeps text from being read as menu parameters or nesting
+func menubarEscape(text string) string {
+	text = strings.ReplaceAll(text, "|", "¦")
+	return strings.TrimLeft(text, "-")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// onboardSample is how many of the largest items of each category are shown
+var onboardSample int
+
+var onboardCmd = &cobra.Command{
+	Use:   "onboard",
+	Short: "Choose which categories the daemon may clean",
+	Long: `Scans each enabled category, shows a sample of what it would remove and
+asks whether it may be cleaned automatically from now on. Nothing is deleted.
+
+The answers are saved to onboarding in your config file. Once onboarding is
+completed, the daemon's scheduled cleanups only clean the approved
+categories; scans and cleanups you run yourself aren't affected. Run it again
+to change your answers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if onboardSample < 1 {
+			return fmt.Errorf("--sample must be at least 1")
+		}
+		if !ui.IsInteractive() {
+			return fmt.Errorf("onboard asks for each category; run it in a terminal")
+		}
+		cfgPath, err := userConfigPath()
+		if err != nil {
+			return err
+		}
+
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+		hyperScnr := scanner.NewHyperScanner(cfg, platformInfo)
+
+		// Approvals of categories that aren't enabled right now are kept
+		categories := hyperScnr.EnabledCategories()
+		var approved []string
+		for _, category := range cfg.Onboarding.Approved {
+			if !slices.Contains(categories, category) {
+				approved = append(approved, category)
+			}
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+		for i, category := range categories {
+			say("\n[%d/%d] %s\n", i+1, len(categories), category)
+			result := hyperScnr.ScanCategory(category)
+			files, err := largestFiles(result, onboardSample)
+			result.Close()
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				sayln("  Nothing to clean right now")
+			} else {
+				say("  %d items, %s. The largest:\n", result.TotalCount, formatBytes(result.TotalSize))
+				for _, f := range files {
+					fmt.Printf("    %10s  %s\n", formatBytes(f.Size), f.Path)
+					if f.Reason != "" {
+						fmt.Printf("                %s\n", f.Reason)
+					}
+				}
+			}
+
+			def := "y/N"
+			if cfg.Onboarding.Completed && cfg.Onboarding.Allows(category) {
+				def = "Y/n"
+			}
+			fmt.Fprint(promptOut(), i18n.T("Clean %s automatically? (%s, q to stop without saving): ", category, def))
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				approved = append(approved, category)
+			case "":
+				if def == "Y/n" {
+					approved = append(approved, category)
+				}
+			case "q":
+				sayln("Stopped, nothing was saved")
+				return nil
+			}
+		}
+
+		slices.Sort(approved)
+		onboarding := config.OnboardingConfig{Completed: true, Approved: approved}
+		if err := config.SaveOnboarding(cfgPath, onboarding); err != nil {
+			return err
+		}
+		if len(approved) == 0 {
+			say("\nSaved to %s: the daemon won't clean any category\n", cfgPath)
+		} else {
+			say("\nSaved to %s: the daemon cleans %s\n", cfgPath, strings.Join(approved, ", "))
+		}
+		return nil
+	},
+}
+
+// largestFiles returns the n largest files of result, largest first
+func largestFiles(result *scanner.ScanResult, n int) ([]scanner.FileInfo, error) {
+	var files []scanner.FileInfo
+	err := result.Each(func(file scanner.FileInfo) error {
+		files = append(files, file)
+		slices.SortFunc(files, func(a, b scanner.FileInfo) int {
+			return cmp.Compare(b.Size, a.Size)
+		})
+		if len(files) > n {
+			files = files[:n]
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/telemetry"
+)
+
+// Output modes for scan, clean and report. Human-facing output goes through
+// say; --quiet drops it and prints errors only, --porcelain replaces it with
+// stable tab-separated records for scripts.
+var (
+	quiet     bool
+	porcelain bool
+)
+
+// humanOutput reports whether human-facing output is shown
+func humanOutput() bool {
+	return !quiet && !porcelain
+}
+
+// say prints human-facing output, translated into the user's language
+func say(format string, args ...interface{}) {
+	if humanOutput() {
+		fmt.Print(i18n.T(format, args...))
+	}
+}
+
+// sayln prints a line of human-facing output, translated into the user's language
+func sayln(msg string) {
+	if humanOutput() {
+		fmt.Println(i18n.T(msg))
+	}
+}
+
+// promptOut is where prompts are written, keeping stdout clean for porcelain output
+func promptOut() io.Writer {
+	if porcelain {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// porcelainEscaper keeps every record on one line
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// record prints one porcelain line. Fields are tab-separated; backslashes,
+// tabs and newlines in them are escaped. Numbers are plain integers (sizes in
+// bytes, times in Unix seconds) so output doesn't depend on the locale.
+func record(kind string, fields ...string) {
+	if !porcelain {
+		return
+	}
+	escaped := make([]string, 0, len(fields)+1)
+	escaped = append(escaped, kind)
+	for _, field := range fields {
+		escaped = append(escaped, porcelainEscaper.Replace(field))
+	}
+	fmt.Println(strings.Join(escaped, "\t"))
+}
+
+// recordScan prints a scan result as porcelain records:
+//
+//	file <category> <size> <mtime> <path>
+//	reason <path> <reason>
+//	total <count> <size>
+func recordScan(result *scanner.ScanResult) error {
+	err := result.Each(func(file scanner.FileInfo) error {
+		record("file", file.Category, strconv.FormatInt(file.Size, 10), strconv.FormatInt(file.ModTime.Unix(), 10), file.Path)
+		if file.Reason != "" {
+			record("reason", file.Path, file.Reason)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	record("total", strconv.Itoa(result.TotalCount), strconv.FormatInt(result.TotalSize, 10))
+	return nil
+}
+
+// recordClean prints a clean result as porcelain records:
+//
+//	deleted <path>
+//	skipped <path> <reason>
+//	error <path> <reason>
+//	filesystem <path> <deleted size> <free before> <free after>
+//	summary <deleted> <deleted size> <skipped> <errors> <dry run 0|1>
+func recordClean(result *cleaner.CleanResult) {
+	if result.RunID != "" {
+		record("run", result.RunID)
+	}
+	for _, path := range result.DeletedFiles {
+		record("deleted", path)
+	}
+	for _, path := range result.SkippedFiles {
+		record("skipped", path, result.SkippedReason[path])
+	}
+	for _, err := range result.Errors {
+		record("error", err.Path, err.Reason.String())
+	}
+	for _, fs := range result.Filesystems {
+		record("filesystem", fs.Path,
+			strconv.FormatInt(fs.DeletedSize, 10),
+			strconv.FormatUint(fs.FreeBefore, 10),
+			strconv.FormatUint(fs.FreeAfter, 10))
+	}
+	dryRun := "0"
+	if result.DryRun {
+		dryRun = "1"
+	}
+	record("summary",
+		strconv.Itoa(len(result.DeletedFiles)),
+		strconv.FormatInt(result.DeletedSize, 10),
+		strconv.Itoa(len(result.SkippedFiles)),
+		strconv.Itoa(len(result.Errors)),
+		dryRun)
+}
+
+// sayReclaimed prints how much free space a clean actually gained, and why it
+// differs from what was deleted on file systems where it differs a lot
+func sayReclaimed(result *cleaner.CleanResult) {
+	if len(result.Filesystems) == 0 {
+		return
+	}
+	say(" Space actually freed: %s\n", formatSignedBytes(result.ReclaimedSize()))
+	if files := result.ReclaimedFiles(); files != 0 {
+		say(" Inodes actually freed: %d\n", files)
+	}
+	for _, fs := range result.Filesystems {
+		if fs.Note == "" {
+			continue
+		}
+		say("   %s: %s deleted, %s freed\n", fs.Path, formatBytes(fs.DeletedSize), formatSignedBytes(fs.Reclaimed()))
+		say("   %s\n", i18n.T(fs.Note))
+	}
+}
+
+// copiedListed is how many of the files copied to the quarantine are listed
+const copiedListed = 10
+
+// sayCopied lists the files that had to be copied to the quarantine, rather
+// than renamed, because they were on another file system
+func sayCopied(result *cleaner.CleanResult) {
+	if len(result.Copied) == 0 {
+		return
+	}
+	say(" Copied to the quarantine from another disk (slower): %d files\n", len(result.Copied))
+	for i, path := range result.Copied {
+		if i == copiedListed {
+			say("   ... and %d more\n", len(result.Copied)-copiedListed)
+			break
+		}
+		say("   %s\n", path)
+	}
+}
+
+// warnScanErrors prints the problems a scan ran into without failing, such as
+// a corrupt scan cache. They go to stderr so they never mix with records.
+func warnScanErrors(result *scanner.ScanResult) {
+	for _, err := range result.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// exportTrace exports trace, for a command that ended with err, warning if
+// the collector couldn't be reached
+func exportTrace(trace *telemetry.Run, err error) {
+	if exportErr := trace.End(err); exportErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", exportErr)
+	}
+}
+
+// formatSignedBytes is formatBytes for sizes that may be negative
+func formatSignedBytes(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatBytes(-bytes)
+	}
+	return formatBytes(bytes)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var fixOwnershipCmd = &cobra.Command{
+	Use:   "fix-ownership [path...]",
+	Short: "Hand caches in your home that another user owns back to you",
+	Long: `Finds files and directories in your caches that another user owns, such
+as what npm or Docker created while running as root, and hands them back to
+you with 'sudo chown -R'. Cleaning them otherwise asks for sudo on every run.
+
+Without paths, looks through the cache directories in your home; with them,
+looks through those. Only paths below your home directory are changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+		hs := scanner.NewHyperScanner(cfg, platformInfo)
+
+		roots := hs.CacheRoots()
+		if len(args) > 0 {
+			roots = nil
+			for _, arg := range args {
+				path, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				if !strings.HasPrefix(path, platformInfo.HomeDir+string(filepath.Separator)) {
+					return fmt.Errorf("%s isn't below your home directory, %s", arg, platformInfo.HomeDir)
+				}
+				roots = append(roots, path)
+			}
+		}
+		found := hs.FindForeignOwned(roots)
+		if len(found) == 0 {
+			fmt.Println("Everything in your caches is owned by you")
+			return nil
+		}
+
+		var total int64
+		for _, tree := range found {
+			fmt.Printf("  %s\n    owned by %s, %d files, %s\n", tree.Path, tree.Owner, tree.Files, formatBytes(tree.Size))
+			total += tree.Size
+		}
+		fmt.Printf("\n%d paths (%s) are owned by another user, so cleaning them needs sudo\n", len(found), formatBytes(total))
+
+		if dryRun {
+			fmt.Println("\nWould run:")
+			for _, tree := range found {
+				args, err := cleaner.ChownCommand(tree.Path, platformInfo.HomeDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					continue
+				}
+				fmt.Printf("  sudo %s\n", strings.Join(args, " "))
+			}
+			return nil
+		}
+		if !force {
+			fmt.Print("\nHand them back to you with sudo chown? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled")
+				return nil
+			}
+		}
+
+		sm := cleaner.NewSudoManager()
+		if err := sm.PromptForPassword(); err != nil {
+			return err
+		}
+		defer sm.Clear()
+
+		failed := 0
+		for _, tree := range found {
+			if err := sm.ChownTree(tree.Path, platformInfo.HomeDir); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed++
+				continue
+			}
+			fmt.Printf("Fixed %s\n", tree.Path)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d paths couldn't be handed back", failed, len(found))
+		}
+		return nil
+	},
+}
+
+// suggestFixOwnership points to fix-ownership when some of the files that
+// needed sudo are in the user's home, where they're usually a cache a tool
+// run as root left behind
+func suggestFixOwnership(needSudo []string) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	for _, path := range needSudo {
+		if strings.HasPrefix(path, home+string(filepath.Separator)) {
+			say("   Files in your home need sudo to clean; 'tidyup fix-ownership' hands them back to you so they won't\n")
+			return
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// profileScanTop is how many directories --profile-scan lists
+const profileScanTop = 20
+
+// printScanProfile lists the directories a scan spent longest in, so slow
+// ones that don't need scanning can be excluded
+func printScanProfile(w io.Writer, dirs []scanner.DirProfile) {
+	if len(dirs) == 0 {
+		fmt.Fprintln(w, "\nNo directories were profiled.")
+		return
+	}
+
+	fmt.Fprintf(w, "\nSlowest directories scanned (%d of %d):\n", min(len(dirs), profileScanTop), len(dirs))
+	fmt.Fprintf(w, "%10s %10s  %-24s %s\n", "Time", "Entries", "Categories", "Directory")
+	for _, dir := range dirs[:min(len(dirs), profileScanTop)] {
+		entries := "-"
+		if dir.Entries > 0 {
+			entries = fmt.Sprint(dir.Entries)
+		}
+		fmt.Fprintf(w, "%10s %10s  %-24s %s\n",
+			dir.Elapsed.Round(time.Millisecond), entries, strings.Join(dir.Categories, ","), dir.Path)
+	}
+	fmt.Fprintln(w, "\nTime is added up over the categories that scanned a directory. Entries")
+	fmt.Fprintln(w, "listed by find, du or mdfind aren't counted. To skip a slow directory, leave")
+	fmt.Fprintln(w, "it out of scan_paths or dev.project_dirs, or add it to exclude_paths.")
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+// projectCategories are the categories a project scan reports, in the order
+// they're shown
+var projectCategories = []string{
+	"node_modules", "virtual_envs", "build_artifacts",
+	scanner.ProjectCachesCategory, scanner.VCSIgnoredCategory,
+}
+
+var (
+	projectOnly           []string // Categories to clean
+	projectTop            int      // Entries to list per category
+	projectArchive        string   // Archive to retire the project to
+	projectDeleteOriginal bool     // Delete the project once it's archived
+	projectAllowDirty     bool     // Archive a project git doesn't have all of
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project [dir]",
+	Short: "Clean up a single project before archiving or sharing it",
+	Long: `Scans one project directory (the current one by default) and nothing
+else: its dev artifacts (node_modules, virtual environments and build
+output), the caches its test runners, linters and bundlers keep in it, and,
+in a git work tree, the other files git ignores. Nothing goes by age.
+
+Files like .env, keys and editor settings are often ignored because they
+hold secrets or local settings; they're never listed, and neither is an
+ignored folder holding one.
+
+The findings are broken down by category. --clean removes them, --only
+limits that to some categories and --pick lets you choose the files.
+
+--archive retires the project: once git is found to have all its work, with
+nothing uncommitted, unpushed or stashed, what was found is cleaned and the
+rest written to a .tar.zst, .tar.gz or .tar archive, which is read back to
+check it. --delete-original then deletes the project, through quarantine
+and undo like any other cleanup.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if projectArchive != "" {
+			if err := cleaner.CheckArchiveName(projectArchive); err != nil {
+				return err
+			}
+		} else if projectDeleteOriginal {
+			return fmt.Errorf("--delete-original needs --archive")
+		}
+		for _, cat := range projectOnly {
+			if !slices.Contains(projectCategories, cat) {
+				return fmt.Errorf("unknown category %q (valid categories: %s)", cat, strings.Join(projectCategories, ", "))
+			}
+		}
+		cfg.Categories.NodeModules = true
+		cfg.Categories.VirtualEnvs = true
+		cfg.Categories.BuildArtifacts = true
+		if cmd.Flags().Changed("dry-run") {
+			cfg.DryRun = dryRun
+		}
+
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		fmt.Printf(" Scanning project %s...\n", abs)
+		hyperScnr := scanner.NewHyperScanner(cfg, platformInfo)
+		result, err := hyperScnr.ScanProject(abs)
+		if err != nil {
+			return err
+		}
+		defer func() { result.Close() }()
+		for _, err := range result.Errors {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
+		if len(projectOnly) > 0 {
+			result = replaceResult(result, result.FilterCategories(projectOnly))
+		}
+		if result.TotalCount == 0 && projectArchive == "" {
+			fmt.Println("\nNothing to clean up in this project.")
+			return nil
+		}
+
+		if pickFiles && projectArchive == "" {
+			return pickAndClean(cfg, result, "project files", true)
+		}
+
+		if err := result.Load(); err != nil {
+			return err
+		}
+		printProjectBreakdown(abs, result)
+		fmt.Printf("\nTotal reclaimable: %s\n", formatBytes(result.TotalSize))
+
+		if projectArchive != "" {
+			return archiveProject(cmd.Context(), cfg, abs, result)
+		}
+		if cleanAction {
+			_, err := cleanFiles(cfg, result, "project files", true)
+			return err
+		}
+		fmt.Println("\nRun 'tidyup project --clean' to remove these, or --pick to choose them")
+		return nil
+	},
+}
+
+// archiveProject retires the project at dir to projectArchive: it checks
+// that git has all of its work, cleans what the scan found, archives the rest
+// and reads the archive back, then deletes the project if asked to
+func archiveProject(ctx context.Context, cfg *config.Config, dir string, result *scanner.ScanResult) error {
+	dest, err := filepath.Abs(projectArchive)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", projectArchive, err)
+	}
+	if rel, err := filepath.Rel(dir, dest); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+		return fmt.Errorf("the archive can't be inside %s", dir)
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+	if !projectAllowDirty {
+		problems, err := scanner.RepoProblems(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%s has work git doesn't have elsewhere: %s (use --allow-dirty to archive it anyway)",
+				dir, strings.Join(problems, "; "))
+		}
+	}
+
+	// Only what was cleaned stays out of the archive: files the user kept, or
+	// that couldn't be deleted, are archived with the rest
+	var cleaned []string
+	if result.TotalCount > 0 {
+		cleanResult, err := cleanFiles(cfg, result, "project files", true)
+		if err != nil {
+			return err
+		}
+		if cleanResult == nil {
+			fmt.Println("Archive cancelled")
+			return nil
+		}
+		cleaned = cleanResult.DeletedFiles
+	}
+
+	if cfg.DryRun {
+		fmt.Printf("\n[DRY RUN MODE] Would archive %s to %s\n", dir, dest)
+		if projectDeleteOriginal {
+			fmt.Printf("[DRY RUN MODE] Would then delete %s\n", dir)
+		}
+		return nil
+	}
+	fmt.Printf("\nArchiving %s to %s...\n", dir, dest)
+	stats, err := cleaner.ArchiveDir(ctx, dir, dest, cleaned)
+	if err != nil {
+		return err
+	}
+	if err := cleaner.VerifyArchive(ctx, dest, stats); err != nil {
+		return err
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Archived %d files (%s) to %s (%s)\n", stats.Files, formatBytes(stats.Size), dest, formatBytes(info.Size()))
+
+	if !projectDeleteOriginal {
+		return nil
+	}
+	// Cleaning the project just touched it, and it's archived: min_file_age
+	// has nothing to protect
+	deleteCfg := *cfg
+	deleteCfg.MinFileAge = 0
+	original := &scanner.ScanResult{
+		Files: []scanner.FileInfo{{
+			Path:     dir,
+			Size:     stats.Size,
+			ModTime:  time.Now(),
+			Category: "archived_project",
+			Reason:   "Archived to " + dest,
+			Files:    stats.Files,
+		}},
+		TotalSize:  stats.Size,
+		TotalCount: 1,
+	}
+	_, err = cleanFiles(&deleteCfg, original, "the archived project", true)
+	return err
+}
+
+// printProjectBreakdown lists what was found in the project at root by
+// category, the largest entries of each first, with paths relative to root
+func printProjectBreakdown(root string, result *scanner.ScanResult) {
+	grouped := result.GroupByCategory()
+	for _, cat := range projectCategories {
+		catResult, ok := grouped[cat]
+		if !ok || catResult.TotalCount == 0 {
+			continue
+		}
+		fmt.Printf("\n=== %s: %d items, %s ===\n", cat, catResult.TotalCount, formatBytes(catResult.TotalSize))
+
+		files := slices.Clone(catResult.Files)
+		slices.SortFunc(files, func(a, b scanner.FileInfo) int {
+			return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Path, b.Path))
+		})
+		for i, file := range files {
+			if projectTop > 0 && i == projectTop {
+				fmt.Printf("  ... and %d more\n", len(files)-i)
+				break
+			}
+			rel, err := filepath.Rel(root, file.Path)
+			if err != nil {
+				rel = file.Path
+			}
+			fmt.Printf("  %10s  %s\n", formatBytes(file.Size), rel)
+		}
+	}
+}
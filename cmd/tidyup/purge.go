@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/spf13/cobra"
+)
+
+// purgeAll purges every quarantined file, not only the expired ones
+var purgeAll bool
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete quarantined files past their retention",
+	Long: `Permanently deletes the files in the quarantine older than
+quarantine.retention_days, or the days set for their category in
+quarantine.retention. They can't be restored with 'tidyup undo' afterwards.
+
+The daemon does this on quarantine.purge_schedule. --all purges everything in
+the quarantine, expired or not.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cfg.Quarantine.Dir == "" {
+			return fmt.Errorf("no quarantine directory configured")
+		}
+
+		q := cleaner.NewQuarantine(cfg.Quarantine.Dir)
+		now := time.Now()
+		expired := func(entry cleaner.QuarantineEntry) bool {
+			return purgeAll || cfg.Quarantine.Expired(entry.Category, entry.MovedAt, now)
+		}
+
+		if dryRun {
+			runs, err := q.Runs()
+			if err != nil {
+				return err
+			}
+			var count int
+			var size int64
+			for _, run := range runs {
+				for _, entry := range run.Entries {
+					if expired(entry) {
+						fmt.Printf("  %s (%s, quarantined %s)\n", entry.OriginalPath, formatBytes(entry.Size), entry.MovedAt.Format("2006-01-02"))
+						count++
+						size += entry.Size
+					}
+				}
+			}
+			fmt.Printf("Would purge %d items (%s)\n", count, formatBytes(size))
+			return nil
+		}
+
+		purged, size, errs := q.Purge(expired)
+		fmt.Printf("Purged: %d items (%s)\n", purged, formatBytes(size))
+		if len(errs) > 0 {
+			fmt.Printf("Not purged: %d items\n", len(errs))
+			for _, err := range errs {
+				fmt.Printf("  %v\n", err)
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+// collectRegistry garbage collects the local registry and BuildKit cache
+var collectRegistry bool
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Show what garbage collection would reclaim from a local registry and BuildKit cache",
+	Long: `Reads the storage of the local registries in docker.registry.paths and
+shows their blobs by whether a tag reaches them and by age. Blobs no manifest
+reaches are removed by the registry's garbage collection; blobs only untagged
+manifests reach are removed too with docker.registry.delete_untagged. With
+docker.registry.buildkit, the cache of a BuildKit daemon is shown the same
+way, by whether a running build holds it and by when it was last used.
+
+The registry's storage belongs to it, so scans never list it. --gc runs
+'registry garbage-collect' and 'buildctl prune' instead, as 'tidyup clean'
+does at its end with docker.registry.garbage_collect. Stop the registry or
+put it in read-only mode first, or a push racing the collection can lose
+layers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+		reg := &cfg.Docker.Registry
+
+		hs := scanner.NewHyperScanner(cfg, platformInfo)
+		found := false
+		for _, root := range reg.Paths {
+			registry, err := hs.AnalyzeRegistry(root)
+			if err != nil {
+				if _, statErr := os.Stat(root); statErr == nil {
+					// There, but unreadable; the storage is usually root's
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				continue
+			}
+			found = true
+			printRegistry(registry, reg)
+		}
+		if reg.BuildKit {
+			records, err := cleaner.BuildKitCache(cmd.Context(), reg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "BuildKit cache unknown: %v\n", err)
+			} else {
+				found = true
+				printBuildKitCache(records, reg)
+			}
+		}
+		if !found {
+			fmt.Println("No local registry found in docker.registry.paths")
+			return nil
+		}
+		if !collectRegistry {
+			fmt.Println("\nRun 'tidyup registry --gc' to garbage collect them")
+			return nil
+		}
+
+		steps := cleaner.GarbageCollectSteps(reg, false)
+		if len(steps) == 0 {
+			return fmt.Errorf("nothing to garbage collect: set docker.registry.config_file or docker.registry.buildkit")
+		}
+		if dryRun {
+			fmt.Println("\nWould run:")
+			for _, step := range steps {
+				fmt.Printf("  %s\n", strings.Join(step, " "))
+			}
+			return nil
+		}
+		if !force {
+			fmt.Print("\nGarbage collect the registry and build cache? The registry must be stopped or read-only. (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled")
+				return nil
+			}
+		}
+		fmt.Println()
+		return cleaner.GarbageCollect(cmd.Context(), reg, false, os.Stdout)
+	},
+}
+
+// collectGarbage garbage collects the local registry and BuildKit cache at the
+// end of a cleanup, when docker.registry.garbage_collect asks for it. Their
+// blobs aren't files a scan lists, so only a full or docker cleanup does it,
+// and not one of a saved scan. Its failures are only warnings, as the
+// cleanup itself is done.
+func collectGarbage(ctx context.Context, cfg *config.Config) {
+	reg := &cfg.Docker.Registry
+	if !cfg.Docker.Enabled || !reg.GarbageCollect || cleanState != "" || (category != "" && category != "docker") {
+		return
+	}
+	if len(cleaner.GarbageCollectSteps(reg, cfg.DryRun)) == 0 {
+		return
+	}
+	say("\nGarbage collecting the local registry and build cache...\n")
+	var out io.Writer = io.Discard
+	if humanOutput() {
+		out = os.Stdout
+	}
+	if err := cleaner.GarbageCollect(ctx, reg, cfg.DryRun, out); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// ageBuckets are the ages registry blobs and build cache are grouped by
+var ageBuckets = []struct {
+	name string
+	max  time.Duration // 0 for no limit
+}{
+	{"under a week", 7 * 24 * time.Hour},
+	{"1-4 weeks", 28 * 24 * time.Hour},
+	{"1-3 months", 90 * 24 * time.Hour},
+	{"older", 0},
+}
+
+// ageTotals adds up sizes by age bucket
+type ageTotals [4]struct {
+	count int
+	size  int64
+}
+
+// add counts something of size last touched at t
+func (a *ageTotals) add(t time.Time, size int64) {
+	age := time.Since(t)
+	for i, bucket := range ageBuckets {
+		if bucket.max == 0 || age < bucket.max {
+			a[i].count++
+			a[i].size += size
+			return
+		}
+	}
+}
+
+// print lists the buckets that have anything in them
+func (a *ageTotals) print(indent string) {
+	for i, bucket := range ageBuckets {
+		if a[i].count > 0 {
+			fmt.Printf("%s%-13s %6d  %s\n", indent, bucket.name, a[i].count, formatBytes(a[i].size))
+		}
+	}
+}
+
+// printRegistry shows a registry's blobs by what reaches them, and the ones
+// garbage collection would remove by age
+func printRegistry(registry *scanner.Registry, reg *config.RegistryConfig) {
+	totals := make(map[string]int64)
+	counts := make(map[string]int)
+	var reclaimable ageTotals
+	for _, blob := range registry.Blobs {
+		totals[blob.Reach] += blob.Size
+		counts[blob.Reach]++
+		if blob.Reach == scanner.BlobUnreferenced || (blob.Reach == scanner.BlobUntagged && reg.DeleteUntagged) {
+			reclaimable.add(blob.Modified, blob.Size)
+		}
+	}
+
+	fmt.Printf("Registry %s\n  %d repositories, %d tags, %d blobs\n",
+		registry.Root, registry.Repositories, registry.Tags, len(registry.Blobs))
+	for _, reach := range []struct{ name, note string }{
+		{scanner.BlobTagged, "kept"},
+		{scanner.BlobUntagged, "collected with delete_untagged"},
+		{scanner.BlobUnreferenced, "collected"},
+	} {
+		if counts[reach.name] > 0 {
+			fmt.Printf("  %-13s %6d  %s (%s)\n", reach.name, counts[reach.name], formatBytes(totals[reach.name]), reach.note)
+		}
+	}
+	if reclaimable != (ageTotals{}) {
+		fmt.Println("  Reclaimable by age:")
+		reclaimable.print("    ")
+	}
+}
+
+// printBuildKitCache shows a BuildKit cache, and what a prune would remove
+// by when it was last used
+func printBuildKitCache(records []cleaner.BuildKitRecord, reg *config.RegistryConfig) {
+	var keep time.Duration
+	if reg.BuildKitKeep != "" {
+		keep, _ = time.ParseDuration(reg.BuildKitKeep)
+	}
+	var size, inUse int64
+	var reclaimable ageTotals
+	for _, record := range records {
+		size += record.Size
+		switch {
+		case record.InUse:
+			inUse += record.Size
+		case time.Since(record.LastUsed) >= keep:
+			reclaimable.add(record.LastUsed, record.Size)
+		}
+	}
+
+	fmt.Printf("BuildKit cache\n  %d records, %s, %s of it in use\n", len(records), formatBytes(size), formatBytes(inUse))
+	if reclaimable != (ageTotals{}) {
+		if keep > 0 {
+			fmt.Printf("  Reclaimable, unused for %s or more, by age:\n", reg.BuildKitKeep)
+		} else {
+			fmt.Println("  Reclaimable by age:")
+		}
+		reclaimable.print("    ")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+)
+
+// reviewFiles lets the user pick which files in result to clean. A scan that
+// was spilled to disk is reviewed a page of pageSize files at a time, so the
+// browser never holds more than that. It returns nil if the user cancelled.
+func reviewFiles(result *scanner.ScanResult, pageSize int, keymap *ui.Keymap, prefs *ui.Prefs, applyPrefs bool) ([]string, error) {
+	total := result.Len()
+	if !result.Spilled() || pageSize <= 0 {
+		pageSize = max(total, 1)
+	}
+	pages := max((total+pageSize-1)/pageSize, 1)
+
+	paths := []string{}
+	for page := 0; page < pages; page++ {
+		batch, err := result.Page(page*pageSize, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		files := make([]ui.FileInfo, len(batch))
+		for i, f := range batch {
+			files[i] = ui.FileInfo{
+				Path:     f.Path,
+				Size:     f.Size,
+				Category: f.Category,
+				Reason:   f.Reason,
+				ModTime:  f.ModTime,
+				Risk:     f.Risk,
+				Owner:    f.Owner,
+				Access:   f.Access,
+			}
+		}
+
+		browser := ui.NewBrowserViewModel(files)
+		browser.SetKeymap(keymap)
+		browser.SetPage(page+1, pages)
+		browser.SetIgnore(ignorePaths)
+		if applyPrefs {
+			browser.ApplyPrefs(prefs)
+		}
+		reviewed, err := browser.Run()
+		if err != nil {
+			return nil, err
+		}
+		browser.UpdatePrefs(prefs)
+		if reviewed == nil {
+			return nil, nil
+		}
+		for _, f := range reviewed {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths, nil
+}
+
+// replaceResult returns next, removing the spilled files of prev it was
+// filtered from
+func replaceResult(prev, next *scanner.ScanResult) *scanner.ScanResult {
+	prev.Close()
+	return next
+}
+
+// filterOwners keeps only the files owned by the --owner users
+func filterOwners(result *scanner.ScanResult) *scanner.ScanResult {
+	if len(owners) == 0 {
+		return result
+	}
+	return replaceResult(result, result.FilterOwners(owners))
+}
+
+// filterRisk leaves out the files riskier than --max-risk, saying how many
+func filterRisk(result *scanner.ScanResult) *scanner.ScanResult {
+	if maxRisk == "" {
+		return result
+	}
+	filtered := result.FilterRisk(maxRisk)
+	if left := result.TotalCount - filtered.TotalCount; left > 0 {
+		say(" Leaving out %d files (%s) riskier than %s\n", left, formatBytes(result.TotalSize-filtered.TotalSize), maxRisk)
+	}
+	return replaceResult(result, filtered)
+}
+
+// pickAndClean lets the user choose which of the found files to clean, for
+// the --pick flag of the large and old commands and for analyze, then cleans
+// them. tree starts the browser in its directory tree, largest first.
+func pickAndClean(cfg *config.Config, result *scanner.ScanResult, description string, tree bool) error {
+	if !ui.IsInteractive() {
+		return fmt.Errorf("--pick requires a terminal")
+	}
+	if err := ui.SetTheme(cfg.UI.Theme, false); err != nil {
+		return fmt.Errorf("invalid ui.theme: %w", err)
+	}
+	keymap, err := ui.NewKeymap(cfg.UI.Keybindings.Preset, cfg.UI.Keybindings.Bindings)
+	if err != nil {
+		return fmt.Errorf("invalid ui.keybindings: %w", err)
+	}
+
+	prefs := ui.DefaultPrefs()
+	prefs.TreeView = tree
+	paths, err := reviewFiles(result, cfg.Scan.MaxResults, keymap, prefs, true)
+	if err != nil {
+		return err
+	}
+	if paths == nil {
+		fmt.Println("Cleanup cancelled")
+		return nil
+	}
+	if len(paths) == 0 {
+		fmt.Println("No files selected")
+		return nil
+	}
+
+	picked := result.FilterPaths(paths)
+	defer picked.Close()
+	// The browser already asked, with its confirm key
+	_, err = cleanFiles(cfg, picked, description, false)
+	return err
+}
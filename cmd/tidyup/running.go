@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+)
+
+// offerAppRetry asks the user to quit the applications whose files were
+// skipped because they were running, and retries those files until the apps
+// are gone or the user gives up. --force and non-terminals only skip them.
+func offerAppRetry(clnr *cleaner.Cleaner, scanResult *scanner.ScanResult, result *cleaner.CleanResult) error {
+	if force || result.DryRun || !ui.IsInteractive() {
+		return nil
+	}
+
+	files := make(map[string]scanner.FileInfo, len(scanResult.Files))
+	for _, file := range scanResult.Files {
+		files[file.Path] = file
+	}
+
+	for apps := result.RunningApps(); len(apps) > 0; apps = result.RunningApps() {
+		fmt.Fprint(promptOut(), i18n.T("\n%s is running, so its files were skipped.\nQuit it and press Enter to retry, or n to skip: ",
+			strings.Join(apps, ", ")))
+		var response string
+		fmt.Scanln(&response)
+		if response == "n" || response == "N" {
+			return nil
+		}
+
+		paths := result.RunningAppFiles()
+		retry := &scanner.ScanResult{}
+		for _, path := range paths {
+			file, ok := files[path]
+			if !ok {
+				file = scanner.FileInfo{Path: path}
+			}
+			retry.Files = append(retry.Files, file)
+			retry.TotalSize += file.Size
+		}
+		retry.TotalCount = len(retry.Files)
+
+		retried, err := clnr.Clean(retry)
+		if err != nil {
+			return err
+		}
+		result.Merge(retried, paths)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fenilsonani/system-cleanup/internal/api"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+var serveListen string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the remote management API",
+	Long: `Serves scan, report and clean over HTTP so a fleet dashboard can see how
+much space each machine could reclaim and start cleanups.
+
+Clients authenticate with the bearer token in api.token_file, a client
+certificate signed by api.client_ca, or both. Remote cleanups are refused
+unless api.allow_clean is set. The daemon serves the same API when
+api.enabled is set, with the status of its schedules.
+
+  GET  /v1/status      host, version and the last scan
+  GET  /v1/schedules   scheduled cleanups (daemon only)
+  POST /v1/scan        scan, optionally {"categories": ["cache"]}
+  GET  /v1/report      the last scan as a JSON report
+  POST /v1/clean       scan and clean, {"categories", "dry_run", "confirm"}`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cmd.Flags().Changed("listen") {
+			cfg.API.Listen = serveListen
+		}
+
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		server, err := api.New(cfg, platformInfo, Version)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		say("Serving the API on %s\n", cfg.API.Listen)
+		return server.ListenAndServe(ctx)
+	},
+}
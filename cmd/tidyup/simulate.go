@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/spf13/cobra"
+)
+
+var simulateFixture string
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Run a cleanup against a synthetic file tree",
+	Long: `Builds the file tree described in a fixture in memory, then scans and
+cleans it with the current configuration and rules. Nothing on disk is read
+or deleted, so it's safe to try new exclusions or categories before using
+them for real.
+
+A fixture is a YAML file:
+
+  os: linux              # whose default paths to use (default: this system)
+  home: /home/alice      # ~ below expands to this
+  files:
+    - path: ~/.cache/pip/wheel.whl
+      size: 120MB
+      age: 30d
+    - path: ~/Projects/app/node_modules/lodash/index.js
+      size: 20KB
+    - path: /tmp/locked
+      dir: true
+      mode: "0555"       # read-only, so its files need sudo
+    - path: ~/.cache/passwd
+      symlink: /etc/passwd`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if simulateFixture == "" {
+			return fmt.Errorf("--fixture is required")
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		// Deleting is the point, and it only touches the fixture
+		cfg.DryRun = false
+
+		fixture, err := vfs.LoadFixture(simulateFixture)
+		if err != nil {
+			return err
+		}
+		mem, err := fixture.Build()
+		if err != nil {
+			return fmt.Errorf("failed to build fixture: %w", err)
+		}
+		osName := platform.Detect()
+		if fixture.OS != "" {
+			osName = platform.Platform(fixture.OS)
+		}
+		info, err := platform.InfoFor(osName, fixture.Home, fixture.User)
+		if err != nil {
+			return fmt.Errorf("fixture os %q: %w", osName, err)
+		}
+
+		hs := scanner.NewHyperScanner(cfg, info)
+		hs.SetFS(mem)
+		scanResult, err := hs.ScanAll()
+		if err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := scanResult.Load(); err != nil {
+			return err
+		}
+
+		clnr := cleaner.New(cfg)
+		clnr.SetRunID(runID)
+		clnr.SetFS(mem)
+		clnr.SetAskSudo(false)
+		cleanResult, err := clnr.Clean(scanResult)
+		if err != nil {
+			return fmt.Errorf("clean failed: %w", err)
+		}
+
+		printSimulation(fixture, mem, scanResult, cleanResult)
+		return nil
+	},
+}
+
+// printSimulation lists what a simulated cleanup deleted, skipped and kept
+func printSimulation(fixture *vfs.Fixture, mem *vfs.MemFS, scanResult *scanner.ScanResult, cleanResult *cleaner.CleanResult) {
+	short := func(path string) string {
+		if rel, ok := strings.CutPrefix(path, fixture.Home+"/"); ok {
+			return "~/" + rel
+		}
+		return path
+	}
+	found := make(map[string]scanner.FileInfo, len(scanResult.Files))
+	for _, file := range scanResult.Files {
+		found[file.Path] = file
+	}
+
+	deleted := append([]string(nil), cleanResult.DeletedFiles...)
+	sort.Strings(deleted)
+	fmt.Printf("Deleted: %d items (%s)\n", len(deleted), formatBytes(cleanResult.DeletedSize))
+	for _, path := range deleted {
+		file := found[path]
+		fmt.Printf("  %-16s %10s  %s\n", file.Category, formatBytes(file.Size), short(path))
+	}
+
+	skipped := append([]string(nil), cleanResult.SkippedFiles...)
+	sort.Strings(skipped)
+	fmt.Printf("\nSkipped: %d items\n", len(skipped))
+	for _, path := range skipped {
+		fmt.Printf("  %s: %s\n", short(path), cleanResult.SkippedReason[path])
+	}
+
+	var kept []string
+	vfs.WalkDir(mem, "/", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			kept = append(kept, path)
+		}
+		return nil
+	})
+	fmt.Printf("\nKept: %d files\n", len(kept))
+	for _, path := range kept {
+		fmt.Printf("  %s\n", short(path))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restore files from the most recent quarantined cleanup",
+	Long: `Moves the files from the most recent cleanup back to where they were.
+
+Only available when quarantine mode is enabled (quarantine.enabled in the
+config, on by default); otherwise files are deleted permanently and cannot be
+restored. Files are purged for good once they're older than
+quarantine.retention_days.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if cfg.Quarantine.Dir == "" {
+			return fmt.Errorf("no quarantine directory configured")
+		}
+
+		q := cleaner.NewQuarantine(cfg.Quarantine.Dir)
+		run, err := q.Latest()
+		if err != nil {
+			if !cfg.Quarantine.Enabled {
+				return fmt.Errorf("%w (quarantine mode is disabled, so cleanups can't be undone)", err)
+			}
+			return err
+		}
+
+		fmt.Printf("Restoring %d items (%s) from cleanup %s...\n",
+			len(run.Entries), formatBytes(run.TotalSize()), run.ID)
+		if run.RunID != "" {
+			fmt.Printf("Run ID: %s\n", run.RunID)
+		}
+
+		restored, errs := run.Restore()
+
+		fmt.Printf("\nRestored: %d items\n", len(restored))
+		if len(errs) > 0 {
+			fmt.Printf("Not restored: %d items\n", len(errs))
+			for _, err := range errs {
+				fmt.Printf("  %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+// compactDisks runs the runtimes' prune and compaction on their VM disks
+var compactDisks bool
+
+var vmDisksCmd = &cobra.Command{
+	Use:   "vm-disks",
+	Short: "Show and compact the VM disks of Docker Desktop, Colima and Lima",
+	Long: `Lists the disk images of the VMs Docker Desktop, Colima and Lima run
+Docker in, with their size, the space they take on disk and, when the VM is
+running, what Docker uses inside them.
+
+These disks grow as images are pulled but don't shrink when they're removed,
+and deleting them loses every image, container and volume, so scans never
+list them. --compact runs the runtime's own prune, then fstrim inside the VM
+to hand the freed space back to the host. The VMs must be running.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+
+		hs := scanner.NewHyperScanner(cfg, platformInfo)
+		disks := hs.FindVMDisks()
+		if len(disks) == 0 {
+			fmt.Println("No Docker Desktop, Colima or Lima VM disks found")
+			return nil
+		}
+
+		ctx := cmd.Context()
+		for _, disk := range disks {
+			printVMDisk(ctx, disk)
+		}
+		if !compactDisks {
+			fmt.Println("\nRun 'tidyup vm-disks --compact' to prune and compact them")
+			return nil
+		}
+
+		if dryRun {
+			fmt.Println("\nWould run:")
+			for _, disk := range disks {
+				for _, step := range cleaner.CompactSteps(disk, &cfg.Docker) {
+					fmt.Printf("  %s\n", strings.Join(step, " "))
+				}
+			}
+			return nil
+		}
+		if !force {
+			fmt.Print("\nPrune Docker and compact these disks? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled")
+				return nil
+			}
+		}
+
+		failed := 0
+		for _, disk := range disks {
+			fmt.Printf("\n=== %s ===\n", vmDiskName(disk))
+			if err := cleaner.CompactVMDisk(ctx, disk, &cfg.Docker, os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed++
+				continue
+			}
+			for _, after := range hs.FindVMDisks() {
+				if after.Path == disk.Path {
+					fmt.Printf("Compacted %s: %s → %s on disk\n", disk.Path,
+						formatBytes(disk.Allocated), formatBytes(after.Allocated))
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d disks couldn't be compacted", failed, len(disks))
+		}
+		return nil
+	},
+}
+
+// printVMDisk describes a VM disk and, if its VM is running, the space
+// Docker uses in it
+func printVMDisk(ctx context.Context, disk scanner.VMDisk) {
+	fmt.Printf("%s\n  %s\n  %s on disk, up to %s\n", vmDiskName(disk), disk.Path,
+		formatBytes(disk.Allocated), formatBytes(disk.Size))
+	if disk.Docker == nil {
+		return
+	}
+	usage, err := cleaner.VMDiskUsage(ctx, disk)
+	if err != nil {
+		fmt.Println("  Docker usage unknown; is the VM running?")
+		return
+	}
+	fmt.Printf("  Docker uses %s, %s of it reclaimable\n", formatBytes(usage.Size), formatBytes(usage.Reclaimable))
+}
+
+// vmDiskName names the runtime and profile or instance a disk belongs to
+func vmDiskName(disk scanner.VMDisk) string {
+	switch disk.Runtime {
+	case scanner.RuntimeDockerDesktop:
+		return "Docker Desktop"
+	case scanner.RuntimeColima:
+		return fmt.Sprintf("Colima (profile %s)", disk.Name)
+	default:
+		return fmt.Sprintf("Lima (instance %s)", disk.Name)
+	}
+}
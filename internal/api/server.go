@@ -0,0 +1,447 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/reporter"
+	"github.com/fenilsonani/system-cleanup/internal/runid"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// Server serves the remote management API: scan, report and clean this
+// machine, and the status of its scheduled cleanups
+type Server struct {
+	config    *config.Config
+	info      *platform.Info
+	version   string
+	token     string
+	schedules func() []ScheduleStatus
+	runs      func() ([]RunSummary, error)
+
+	// Scans and cleans run one at a time
+	busy sync.Mutex
+
+	mu       sync.Mutex
+	lastScan *scanRecord
+}
+
+// scanRecord is a finished scan, kept for the status and report endpoints
+type scanRecord struct {
+	result *scanner.ScanResult
+	meta   *reporter.Metadata
+	at     time.Time
+}
+
+// ScheduleStatus is the state of one scheduled cleanup
+type ScheduleStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	DryRun   bool       `json:"dry_run"`
+	NextRun  time.Time  `json:"next_run"`
+	LastRun  *time.Time `json:"last_run,omitempty"` // nil until the first run
+}
+
+// ScanSummary is the reclaimable space found by a scan
+type ScanSummary struct {
+	Hostname   string                     `json:"hostname"`
+	Job        string                     `json:"job,omitempty"` // The scheduled cleanup that scanned, if one did
+	ScannedAt  time.Time                  `json:"scanned_at"`
+	TotalFiles int                        `json:"total_files"`
+	TotalSize  int64                      `json:"total_size"`
+	Categories map[string]CategorySummary `json:"categories"`
+}
+
+// CategorySummary is one category of a ScanSummary
+type CategorySummary struct {
+	Files int   `json:"files"`
+	Size  int64 `json:"size"`
+}
+
+// CleanSummary is the outcome of a remotely started cleanup
+type CleanSummary struct {
+	RunID         string                      `json:"run_id"`
+	DryRun        bool                        `json:"dry_run"`
+	DeletedFiles  int                         `json:"deleted_files"`
+	DeletedSize   int64                       `json:"deleted_size"`
+	ReclaimedSize int64                       `json:"reclaimed_size"` // Growth in free space, 0 for a dry run
+	Filesystems   []cleaner.FilesystemReclaim `json:"filesystems"`
+	Skipped       int                         `json:"skipped"`
+	Errors        []string                    `json:"errors"`
+}
+
+// request is the body of POST /v1/scan and /v1/clean
+type request struct {
+	Categories []string `json:"categories"` // Empty for every enabled category
+	DryRun     bool     `json:"dry_run"`
+	Confirm    string   `json:"confirm"` // The amount to delete, when a typed confirmation is required
+}
+
+// New creates a server from the api section of cfg. It refuses to serve
+// without a token or client certificates, and won't accept a token over
+// plain HTTP except on a loopback address.
+func New(cfg *config.Config, info *platform.Info, version string) (*Server, error) {
+	api := cfg.API
+	if api.TokenFile == "" && api.ClientCA == "" {
+		return nil, fmt.Errorf("the API needs api.token_file or api.client_ca, refusing to serve without authentication")
+	}
+
+	s := &Server{config: cfg, info: info, version: version}
+	if api.TokenFile != "" {
+		data, err := os.ReadFile(api.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API token: %w", err)
+		}
+		s.token = strings.TrimSpace(string(data))
+		if s.token == "" {
+			return nil, fmt.Errorf("API token file %s is empty", api.TokenFile)
+		}
+		if api.TLSCert == "" && !isLoopback(api.Listen) {
+			return nil, fmt.Errorf("refusing to accept the API token over plain HTTP on %s: set api.tls_cert and api.tls_key, or listen on a loopback address", api.Listen)
+		}
+	}
+	return s, nil
+}
+
+// SetSchedules sets where the status of scheduled cleanups comes from
+func (s *Server) SetSchedules(fn func() []ScheduleStatus) {
+	s.schedules = fn
+}
+
+// Handler returns the API's HTTP handler, with authentication
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/schedules", s.handleSchedules)
+	mux.HandleFunc("GET /v1/summary", s.handleSummary)
+	mux.HandleFunc("POST /v1/scan", s.handleScan)
+	mux.HandleFunc("GET /v1/report", s.handleReport)
+	mux.HandleFunc("POST /v1/clean", s.handleClean)
+	return s.authenticate(mux)
+}
+
+// ListenAndServe serves the API until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.config.API.Listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if s.config.API.ClientCA != "" {
+		pem, err := os.ReadFile(s.config.API.ClientCA)
+		if err != nil {
+			return fmt.Errorf("failed to read API client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", s.config.API.ClientCA)
+		}
+		srv.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	var err error
+	if s.config.API.TLSCert != "" {
+		err = srv.ListenAndServeTLS(s.config.API.TLSCert, s.config.API.TLSKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// authenticate checks the bearer token. Client certificates are already
+// verified by the TLS handshake.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tidyup"`)
+				writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStatus describes the machine and the last scan
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	hostname, _ := os.Hostname()
+	status := struct {
+		Hostname string         `json:"hostname"`
+		Version  string         `json:"version"`
+		Platform *platform.Info `json:"platform"`
+		Busy     bool           `json:"busy"`
+		LastScan *ScanSummary   `json:"last_scan,omitempty"`
+	}{Hostname: hostname, Version: s.version, Platform: s.info}
+
+	if s.busy.TryLock() {
+		s.busy.Unlock()
+	} else {
+		status.Busy = true
+	}
+
+	s.mu.Lock()
+	if s.lastScan != nil {
+		summary := s.lastScan.summary()
+		status.LastScan = &summary
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleSchedules lists the scheduled cleanups, empty outside the daemon
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules := []ScheduleStatus{}
+	if s.schedules != nil {
+		schedules = append(schedules, s.schedules()...)
+	}
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+// handleScan scans the machine and returns what could be reclaimed. The full
+// result is kept for GET /v1/report.
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	if !s.busy.TryLock() {
+		writeError(w, http.StatusConflict, "a scan or cleanup is already running")
+		return
+	}
+	defer s.busy.Unlock()
+
+	record, err := s.scan(s.config, req.Categories)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, record.summary())
+}
+
+// handleReport returns the last scan as a JSON report, like tidyup report
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	record := s.lastScan
+	s.mu.Unlock()
+
+	if record == nil {
+		writeError(w, http.StatusNotFound, "no scan yet, POST /v1/scan first")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	rptr := reporter.New(w, reporter.FormatJSON)
+	rptr.SetMetadata(record.meta)
+	rptr.Report(record.result)
+}
+
+// handleClean scans and cleans the machine. Cleanups that need a typed
+// confirmation on the command line need the amount in "confirm" here.
+// Nothing is deleted with sudo, since no one can answer the prompt.
+func (s *Server) handleClean(w http.ResponseWriter, r *http.Request) {
+	if !s.config.API.AllowClean {
+		writeError(w, http.StatusForbidden, "remote cleanups are disabled, set api.allow_clean to enable them")
+		return
+	}
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	if !s.busy.TryLock() {
+		writeError(w, http.StatusConflict, "a scan or cleanup is already running")
+		return
+	}
+	defer s.busy.Unlock()
+
+	cfg := *s.config
+	cfg.DryRun = cfg.DryRun || req.DryRun
+
+	record, err := s.scan(&cfg, req.Categories)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := record.result
+	if !cfg.DryRun && needsTypedConfirm(&cfg, result) {
+		token := ui.ConfirmToken(result.TotalSize)
+		if !ui.TypedConfirmMatches(req.Confirm, token) {
+			writeError(w, http.StatusPreconditionFailed, fmt.Sprintf("this cleanup needs a typed confirmation, resend with \"confirm\": %q", token))
+			return
+		}
+	}
+
+	// The cleanup carries on the run of the scan it cleans
+	clnr := cleaner.New(&cfg)
+	clnr.SetRunID(record.meta.RunID)
+	clnr.SetAskSudo(false)
+	cleanResult, err := clnr.Clean(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	summary := CleanSummary{
+		RunID:         cleanResult.RunID,
+		DryRun:        cleanResult.DryRun,
+		DeletedFiles:  len(cleanResult.DeletedFiles),
+		DeletedSize:   cleanResult.DeletedSize,
+		ReclaimedSize: cleanResult.ReclaimedSize(),
+		Filesystems:   cleanResult.Filesystems,
+		Skipped:       len(cleanResult.SkippedFiles),
+		Errors:        []string{},
+	}
+	if summary.Filesystems == nil {
+		summary.Filesystems = []cleaner.FilesystemReclaim{}
+	}
+	for _, e := range cleanResult.Errors {
+		summary.Errors = append(summary.Errors, e.Error())
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// scan scans with cfg, keeping only categories if any are given, and records
+// the result as the last scan
+func (s *Server) scan(cfg *config.Config, categories []string) (*scanRecord, error) {
+	hs := scanner.NewHyperScanner(cfg, s.info)
+	enabled := make(map[string]bool)
+	for _, category := range hs.EnabledCategories() {
+		enabled[category] = true
+	}
+	for _, category := range categories {
+		if !enabled[category] {
+			return nil, fmt.Errorf("category %q is not enabled in the configuration", category)
+		}
+	}
+
+	start := time.Now()
+	result, err := hs.ScanAll()
+	if err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	// Scans are reported on and cleaned more than once, so keep every
+	// result in memory
+	if err := result.Load(); err != nil {
+		return nil, err
+	}
+	if len(categories) > 0 {
+		result = result.FilterCategories(categories)
+	}
+	record := &scanRecord{
+		result: result,
+		meta:   reporter.NewMetadata(s.info, cfg, time.Since(start), hs.Engine(), s.version),
+		at:     start,
+	}
+	record.meta.RunID = runid.New()
+
+	s.mu.Lock()
+	s.lastScan = record
+	s.mu.Unlock()
+	return record, nil
+}
+
+// needsTypedConfirm reports whether the command line would ask for the amount
+// to be typed before deleting result
+func needsTypedConfirm(cfg *config.Config, result *scanner.ScanResult) bool {
+	if cfg.Confirmation.TypedThreshold != "" {
+		threshold, err := utils.ParseSize(cfg.Confirmation.TypedThreshold)
+		if err == nil && threshold > 0 && result.TotalSize >= threshold {
+			return true
+		}
+	}
+	if cfg.Confirmation.SystemPaths {
+		home, _ := os.UserHomeDir()
+		files, err := result.SystemFiles(home)
+		return err != nil || len(files) > 0
+	}
+	return false
+}
+
+// summary totals the scan by category
+func (rec *scanRecord) summary() ScanSummary {
+	summary := ScanSummary{
+		Hostname:   rec.meta.Hostname,
+		ScannedAt:  rec.at,
+		TotalFiles: rec.result.TotalCount,
+		TotalSize:  rec.result.TotalSize,
+		Categories: make(map[string]CategorySummary),
+	}
+	for category, group := range rec.result.GroupByCategory() {
+		summary.Categories[category] = CategorySummary{Files: group.TotalCount, Size: group.TotalSize}
+	}
+	return summary
+}
+
+// readRequest decodes an optional JSON request body
+func readRequest(w http.ResponseWriter, r *http.Request) (request, bool) {
+	var req request
+	if r.ContentLength == 0 {
+		return req, true
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return req, false
+	}
+	return req, true
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes an {"error": msg} response
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/ui"
+)
+
+const testToken = "s3cret"
+
+// newTestServer serves a temp directory holding one old file, with the token
+// in a token file
+func newTestServer(t *testing.T, configure func(*config.Config)) (*httptest.Server, string) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // Keep the scan cache out of the user's
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "old.tmp")
+	if err := os.WriteFile(file, make([]byte, 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(file, old, old)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(testToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Categories: config.Categories{Temp: true},
+		API:        config.APIConfig{Listen: "127.0.0.1:0", TokenFile: tokenFile},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	server, err := New(cfg, &platform.Info{TempDirs: []string{dir}}, "test")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts, file
+}
+
+// call makes an authenticated request and decodes the JSON response into out
+func call(t *testing.T, ts *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: invalid JSON response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// ============================================================================
+// Authentication Tests
+// ============================================================================
+
+func TestNewRequiresAuthentication(t *testing.T) {
+	if _, err := New(&config.Config{API: config.APIConfig{Listen: "127.0.0.1:7780"}}, &platform.Info{}, ""); err == nil {
+		t.Error("expected an error without a token or client CA")
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte(testToken), 0600)
+	cfg := &config.Config{API: config.APIConfig{Listen: "0.0.0.0:7780", TokenFile: tokenFile}}
+	if _, err := New(cfg, &platform.Info{}, ""); err == nil {
+		t.Error("expected an error for a token over plain HTTP on a public address")
+	}
+
+	cfg.API.Listen = "localhost:7780"
+	if _, err := New(cfg, &platform.Info{}, ""); err != nil {
+		t.Errorf("a token on a loopback address should be accepted: %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	ts, _ := newTestServer(t, nil)
+
+	for _, header := range []string{"", "Bearer wrong", testToken} {
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: got status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	if status := call(t, ts, "GET", "/v1/status", "", nil); status != http.StatusOK {
+		t.Errorf("valid token: got status %d, want 200", status)
+	}
+}
+
+// ============================================================================
+// Scan, Report and Clean Tests
+// ============================================================================
+
+func TestScanAndReport(t *testing.T) {
+	ts, file := newTestServer(t, nil)
+
+	var errResp map[string]string
+	if status := call(t, ts, "GET", "/v1/report", "", &errResp); status != http.StatusNotFound {
+		t.Errorf("report before a scan: got status %d, want 404", status)
+	}
+	if status := call(t, ts, "POST", "/v1/scan", `{"categories": ["docker"]}`, &errResp); status != http.StatusBadRequest {
+		t.Errorf("scan of a disabled category: got status %d, want 400", status)
+	}
+
+	var summary ScanSummary
+	if status := call(t, ts, "POST", "/v1/scan", `{"categories": ["temp"]}`, &summary); status != http.StatusOK {
+		t.Fatalf("scan: got status %d", status)
+	}
+	if summary.TotalFiles != 1 || summary.Categories["temp"].Size != 2048 {
+		t.Errorf("unexpected scan summary %+v", summary)
+	}
+
+	var report struct {
+		SchemaVersion int `json:"schema_version"`
+		Report        struct {
+			Files []struct{ Path string } `json:"files"`
+		} `json:"report"`
+	}
+	if status := call(t, ts, "GET", "/v1/report", "", &report); status != http.StatusOK {
+		t.Fatalf("report: got status %d", status)
+	}
+	if report.SchemaVersion == 0 || len(report.Report.Files) != 1 || report.Report.Files[0].Path != file {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	var schedules []ScheduleStatus
+	if status := call(t, ts, "GET", "/v1/schedules", "", &schedules); status != http.StatusOK || len(schedules) != 0 {
+		t.Errorf("schedules outside the daemon: got %d %v", status, schedules)
+	}
+}
+
+func TestCleanNeedsPermission(t *testing.T) {
+	ts, file := newTestServer(t, nil)
+
+	var errResp map[string]string
+	if status := call(t, ts, "POST", "/v1/clean", "", &errResp); status != http.StatusForbidden {
+		t.Errorf("clean without api.allow_clean: got status %d, want 403", status)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("file should not have been deleted: %v", err)
+	}
+}
+
+func TestCleanTypedConfirmation(t *testing.T) {
+	ts, file := newTestServer(t, func(cfg *config.Config) {
+		cfg.API.AllowClean = true
+		cfg.Confirmation.TypedThreshold = "1KB"
+	})
+
+	var summary CleanSummary
+	if status := call(t, ts, "POST", "/v1/clean", `{"dry_run": true}`, &summary); status != http.StatusOK || !summary.DryRun {
+		t.Fatalf("dry run: got status %d, %+v", status, summary)
+	}
+
+	var errResp map[string]string
+	if status := call(t, ts, "POST", "/v1/clean", "", &errResp); status != http.StatusPreconditionFailed {
+		t.Errorf("clean above the threshold without confirm: got status %d, want 412", status)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("file should not have been deleted yet: %v", err)
+	}
+
+	body := `{"confirm": "` + ui.ConfirmToken(2048) + `"}`
+	if status := call(t, ts, "POST", "/v1/clean", body, &summary); status != http.StatusOK {
+		t.Fatalf("confirmed clean: got status %d", status)
+	}
+	if summary.DeletedFiles != 1 || summary.DeletedSize != 2048 {
+		t.Errorf("unexpected clean summary %+v", summary)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("file should have been deleted, stat: %v", err)
+	}
+}
+
+// ============================================================================
+// Summary Tests
+// ============================================================================
+
+func TestSummary(t *testing.T) {
+	ts, _ := newTestServer(t, nil)
+
+	var summary Summary
+	if status := call(t, ts, "GET", "/v1/summary", "", &summary); status != http.StatusOK {
+		t.Fatalf("summary: got status %d", status)
+	}
+	if summary.LastScan != nil || summary.History.Runs != 0 || summary.History.Days == nil {
+		t.Errorf("summary before a scan: got %+v", summary)
+	}
+
+	call(t, ts, "POST", "/v1/scan", "", nil)
+	if status := call(t, ts, "GET", "/v1/summary", "", &summary); status != http.StatusOK {
+		t.Fatalf("summary: got status %d", status)
+	}
+	if summary.LastScan == nil || summary.LastScan.Categories["temp"].Size != 2048 {
+		t.Errorf("summary after a scan: got %+v", summary.LastScan)
+	}
+}
+
+func TestSummaryRuns(t *testing.T) {
+	day := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+	runs := []RunSummary{
+		{Job: "nightly", Started: day, Finished: day.Add(10 * time.Second), FilesDeleted: 3, BytesDeleted: 300, BytesReclaimed: 250},
+		{Job: "nightly", Started: day.Add(time.Hour), Finished: day.Add(time.Hour + 30*time.Second), Failed: true},
+		{
+			Job: "weekly", Started: day.Add(24 * time.Hour), Finished: day.Add(24*time.Hour + 20*time.Second),
+			FilesFound: 5, FilesDeleted: 5, BytesDeleted: 500, BytesReclaimed: 500,
+			Categories: map[string]CategorySummary{"cache": {Files: 4, Size: 400}, "logs": {Files: 1, Size: 100}},
+		},
+	}
+	server := &Server{config: &config.Config{}}
+	server.SetRuns(func() ([]RunSummary, error) { return runs, nil })
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	var summary Summary
+	if status := call(t, ts, "GET", "/v1/summary", "", &summary); status != http.StatusOK {
+		t.Fatalf("summary: got status %d", status)
+	}
+
+	history := summary.History
+	if history.Runs != 3 || history.Failed != 1 || history.BytesDeleted != 800 || history.BytesReclaimed != 750 {
+		t.Errorf("unexpected history totals %+v", history.RunTotals)
+	}
+	if history.AverageSeconds != 20 {
+		t.Errorf("average run took %vs, want 20s", history.AverageSeconds)
+	}
+	if len(history.Days) != 2 || history.Days[0].Date != "2026-10-14" || history.Days[0].Runs != 2 || history.Days[1].FilesDeleted != 5 {
+		t.Errorf("unexpected days %+v", history.Days)
+	}
+
+	last := summary.LastScan
+	if last == nil || last.Job != "weekly" || last.TotalFiles != 5 || last.TotalSize != 500 || last.Categories["cache"].Size != 400 {
+		t.Errorf("expected the last scheduled scan, got %+v", last)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+// Summary is the GET /v1/summary response: what the last scan found and
+// totals of the scheduled cleanups so far, flat enough for a dashboard to
+// chart without scraping
+type Summary struct {
+	Hostname    string         `json:"hostname"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	LastScan    *ScanSummary   `json:"last_scan"` // The newer of the last API scan and the last scheduled one, null before either
+	History     HistorySummary `json:"history"`
+}
+
+// RunTotals adds up runs of scheduled cleanups
+type RunTotals struct {
+	Runs           int   `json:"runs"`
+	Failed         int   `json:"failed"`
+	FilesDeleted   int   `json:"files_deleted"`
+	BytesDeleted   int64 `json:"bytes_deleted"`
+	BytesReclaimed int64 `json:"bytes_reclaimed"`
+}
+
+// HistorySummary totals the runs of scheduled cleanups, overall and by day
+type HistorySummary struct {
+	RunTotals
+	AverageSeconds float64      `json:"average_seconds"` // How long a run takes
+	FirstRun       *time.Time   `json:"first_run,omitempty"`
+	LastRun        *time.Time   `json:"last_run,omitempty"`
+	Days           []DaySummary `json:"days"` // Each day with runs, oldest first
+}
+
+// DaySummary totals the runs started on one day
+type DaySummary struct {
+	Date string `json:"date"` // 2006-01-02, local time
+	RunTotals
+}
+
+// RunSummary is one run of a scheduled cleanup, as the summary needs it
+type RunSummary struct {
+	Job            string
+	Started        time.Time
+	Finished       time.Time
+	Failed         bool
+	FilesFound     int
+	FilesDeleted   int
+	BytesDeleted   int64
+	BytesReclaimed int64
+	Categories     map[string]CategorySummary // What its scan found; nil if it wasn't recorded
+}
+
+// SetRuns sets where the runs of scheduled cleanups come from, oldest first
+func (s *Server) SetRuns(fn func() ([]RunSummary, error)) {
+	s.runs = fn
+}
+
+// handleSummary returns the last scan by category and totals of the
+// scheduled cleanups
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	hostname, _ := os.Hostname()
+	summary := Summary{Hostname: hostname, GeneratedAt: time.Now()}
+
+	var runs []RunSummary
+	if s.runs != nil {
+		var err error
+		if runs, err = s.runs(); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	if s.lastScan != nil {
+		last := s.lastScan.summary()
+		summary.LastScan = &last
+	}
+	s.mu.Unlock()
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Categories == nil {
+			continue
+		}
+		if summary.LastScan == nil || runs[i].Started.After(summary.LastScan.ScannedAt) {
+			last := runs[i].scanSummary(hostname)
+			summary.LastScan = &last
+		}
+		break
+	}
+
+	summary.History = summarizeRuns(runs)
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// summarizeRuns totals runs, which are oldest first
+func summarizeRuns(runs []RunSummary) HistorySummary {
+	history := HistorySummary{Days: []DaySummary{}}
+	if len(runs) == 0 {
+		return history
+	}
+
+	var took time.Duration
+	days := make(map[string]int) // Date -> index in history.Days
+	for _, run := range runs {
+		history.add(run)
+		took += run.Finished.Sub(run.Started)
+
+		date := run.Started.Local().Format(time.DateOnly)
+		i, ok := days[date]
+		if !ok {
+			i = len(history.Days)
+			days[date] = i
+			history.Days = append(history.Days, DaySummary{Date: date})
+		}
+		history.Days[i].add(run)
+	}
+	history.AverageSeconds = took.Seconds() / float64(len(runs))
+	first, last := runs[0].Started, runs[len(runs)-1].Started
+	history.FirstRun, history.LastRun = &first, &last
+	return history
+}
+
+// add counts run in the totals
+func (t *RunTotals) add(run RunSummary) {
+	t.Runs++
+	if run.Failed {
+		t.Failed++
+	}
+	t.FilesDeleted += run.FilesDeleted
+	t.BytesDeleted += run.BytesDeleted
+	t.BytesReclaimed += run.BytesReclaimed
+}
+
+// scanSummary is what the run's scan found
+func (run RunSummary) scanSummary(hostname string) ScanSummary {
+	summary := ScanSummary{
+		Hostname:   hostname,
+		Job:        run.Job,
+		ScannedAt:  run.Started,
+		TotalFiles: run.FilesFound,
+		Categories: run.Categories,
+	}
+	for _, category := range run.Categories {
+		summary.TotalSize += category.Size
+	}
+	return summary
+}
@@ -0,0 +1,200 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// commLen is how much of a process name Linux keeps; longer names are cut off
+const commLen = 15
+
+// appOwner is an application whose profile and caches get corrupted if
+// they're deleted while it runs
+type appOwner struct {
+	name      string
+	processes []string // Executable names, matched case-insensitively
+	paths     []string // Directories it owns, below the home directory
+}
+
+// appOwners maps browser, IDE and Electron app data to the processes that
+// use it
+var appOwners = []appOwner{
+	// Browsers
+	{name: "Google Chrome", processes: []string{"Google Chrome", "chrome", "google-chrome"},
+		paths: []string{"Library/Caches/Google/Chrome", "Library/Application Support/Google/Chrome",
+			".cache/google-chrome", ".config/google-chrome"}},
+	{name: "Chromium", processes: []string{"Chromium", "chromium", "chromium-browser"},
+		paths: []string{"Library/Caches/Chromium", "Library/Application Support/Chromium",
+			".cache/chromium", ".config/chromium"}},
+	{name: "Firefox", processes: []string{"firefox", "firefox-bin", "firefox-esr"},
+		paths: []string{"Library/Caches/Firefox", "Library/Application Support/Firefox",
+			".cache/mozilla/firefox", ".mozilla/firefox"}},
+	{name: "Brave", processes: []string{"Brave Browser", "brave", "brave-browser"},
+		paths: []string{"Library/Caches/BraveSoftware", "Library/Application Support/BraveSoftware",
+			".cache/BraveSoftware", ".config/BraveSoftware"}},
+	{name: "Microsoft Edge", processes: []string{"Microsoft Edge", "msedge", "microsoft-edge"},
+		paths: []string{"Library/Caches/Microsoft Edge", "Library/Application Support/Microsoft Edge",
+			".cache/microsoft-edge", ".config/microsoft-edge"}},
+	{name: "Safari", processes: []string{"Safari"},
+		paths: []string{"Library/Caches/com.apple.Safari", "Library/Safari"}},
+
+	// Editors and IDEs
+	{name: "Visual Studio Code", processes: []string{"Code", "Code Helper", "code"},
+		paths: []string{"Library/Caches/com.microsoft.VSCode", "Library/Application Support/Code", ".config/Code"}},
+	{name: "Cursor", processes: []string{"Cursor", "cursor"},
+		paths: []string{"Library/Application Support/Cursor", ".config/Cursor"}},
+	{name: "JetBrains IDE", processes: []string{"idea", "goland", "pycharm", "webstorm", "phpstorm",
+		"clion", "rider", "rubymine", "datagrip", "studio"},
+		paths: []string{"Library/Caches/JetBrains", "Library/Application Support/JetBrains",
+			".cache/JetBrains", ".local/share/JetBrains"}},
+	{name: "Xcode", processes: []string{"Xcode"},
+		paths: []string{"Library/Caches/com.apple.dt.Xcode"}},
+
+	// Electron apps
+	{name: "Slack", processes: []string{"Slack", "slack"},
+		paths: []string{"Library/Caches/com.tinyspeck.slackmacgap", "Library/Application Support/Slack", ".config/Slack"}},
+	{name: "Discord", processes: []string{"Discord", "discord"},
+		paths: []string{"Library/Caches/com.hnc.Discord", "Library/Application Support/discord", ".config/discord"}},
+	{name: "Microsoft Teams", processes: []string{"Microsoft Teams", "MSTeams", "teams", "teams-for-linux"},
+		paths: []string{"Library/Application Support/Microsoft/Teams", ".config/Microsoft/Microsoft Teams",
+			".config/teams-for-linux"}},
+	{name: "Spotify", processes: []string{"Spotify", "spotify"},
+		paths: []string{"Library/Caches/com.spotify.client", "Library/Application Support/Spotify", ".cache/spotify"}},
+	{name: "Obsidian", processes: []string{"Obsidian", "obsidian"},
+		paths: []string{"Library/Application Support/obsidian", ".config/obsidian"}},
+}
+
+// appOwning returns the application that owns path, if any
+func appOwning(home, path string) (appOwner, bool) {
+	if home == "" {
+		return appOwner{}, false
+	}
+	for _, app := range appOwners {
+		for _, dir := range app.paths {
+			dir = filepath.Join(home, dir)
+			if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+				return app, true
+			}
+		}
+	}
+	return appOwner{}, false
+}
+
+// runningIn reports whether any of app's processes are in running, a set of
+// lowercase process names
+func (app appOwner) runningIn(running map[string]bool) bool {
+	for _, process := range app.processes {
+		process = strings.ToLower(process)
+		if running[process] || (len(process) > commLen && running[process[:commLen]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// runningProcesses lists the names of the running processes in lowercase,
+// using ps. It returns nil if they can't be listed.
+func runningProcesses() map[string]bool {
+	if _, err := exec.LookPath("ps"); err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// comm is the full executable path on macOS and the name elsewhere
+	out, err := exec.CommandContext(ctx, "ps", "-axo", "comm=").Output()
+	if err != nil {
+		return nil
+	}
+	return parseProcessNames(string(out))
+}
+
+// parseProcessNames parses ps -o comm= output, one process per line
+func parseProcessNames(out string) map[string]bool {
+	names := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		names[strings.ToLower(filepath.Base(line))] = true
+	}
+	return names
+}
+
+// skipRunningApps leaves out the files owned by an application that's
+// running, recording each as a retryable failure so it can be cleaned once
+// the app is quit. It returns the files left to clean.
+func (c *Cleaner) skipRunningApps(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	if !c.config.Clean.SkipRunningApps || !c.native() {
+		return files
+	}
+	home, _ := os.UserHomeDir()
+
+	var running map[string]bool
+	kept := files[:0:0]
+	for _, file := range files {
+		app, ok := appOwning(home, file.Path)
+		if ok && running == nil {
+			// Only list processes once something belongs to an app
+			if running = c.processes(); running == nil {
+				running = map[string]bool{}
+			}
+		}
+		if !ok || !app.runningIn(running) {
+			kept = append(kept, file)
+			continue
+		}
+
+		delErr := &DeletionError{
+			Path:      file.Path,
+			Reason:    ErrorAppRunning,
+			Original:  fmt.Errorf("%s is running", app.name),
+			App:       app.name,
+			Retryable: true,
+		}
+		result.Errors = append(result.Errors, delErr)
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = delErr.UserMessage()
+	}
+	return kept
+}
+
+// RunningApps returns the applications whose files were skipped because they
+// were running, in the order first met
+func (r *CleanResult) RunningApps() []string {
+	return runningApps(r.Errors)
+}
+
+// runningApps returns the applications named by errs, in the order first met
+func runningApps(errs []*DeletionError) []string {
+	var apps []string
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		if err.Reason == ErrorAppRunning && !seen[err.App] {
+			seen[err.App] = true
+			apps = append(apps, err.App)
+		}
+	}
+	return apps
+}
+
+// RunningAppFiles returns the files skipped because their application was
+// running
+func (r *CleanResult) RunningAppFiles() []string {
+	var paths []string
+	for _, err := range r.Errors {
+		if err.Reason == ErrorAppRunning {
+			paths = append(paths, err.Path)
+		}
+	}
+	return paths
+}
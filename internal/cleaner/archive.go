@@ -0,0 +1,267 @@
+package cleaner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveStats is what an archive written by ArchiveDir holds
+type ArchiveStats struct {
+	Files int   // Regular files
+	Size  int64 // Their total size, uncompressed
+}
+
+// ArchiveDir writes the tree at dir, other than the paths in exclude, to a
+// tar file at dest compressed as its name says: .tar.zst with the zstd
+// command, .tar.gz or .tgz, or .tar. Paths in it start with dir's name.
+// Symlinks are kept as links and special files are left out. dest is
+// written beside itself and renamed into place once it's complete; an
+// existing file isn't replaced.
+func ArchiveDir(ctx context.Context, dir, dest string, exclude []string) (ArchiveStats, error) {
+	var stats ArchiveStats
+	if anyUnder([]string{dest}, dir) {
+		return stats, fmt.Errorf("the archive can't be inside %s", dir)
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return stats, fmt.Errorf("%s already exists", dest)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return stats, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := CheckArchiveName(dest); err != nil {
+		return stats, err
+	}
+	zw, err := compressor(ctx, dest, tmp)
+	if err != nil {
+		return stats, fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	tw := tar.NewWriter(zw)
+	base := filepath.Dir(dir)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, skip := range exclude {
+			if path == skip {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := io.Copy(tw, f)
+		if err != nil {
+			return err
+		}
+		stats.Files++
+		stats.Size += n
+		return nil
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return stats, fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	return stats, nil
+}
+
+// VerifyArchive reads the archive at path back, checking that it's intact
+// and holds what ArchiveDir said it wrote
+func VerifyArchive(ctx context.Context, path string, want ArchiveStats) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	defer f.Close()
+	zr, err := decompressor(ctx, path, f)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	defer zr.Close()
+
+	var got ArchiveStats
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		n, err := io.Copy(io.Discard, tr)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", path, err)
+		}
+		got.Files++
+		got.Size += n
+	}
+	if err := zr.Close(); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	if got != want {
+		return fmt.Errorf("failed to verify %s: it holds %d files, %d bytes, expected %d files, %d bytes",
+			path, got.Files, got.Size, want.Files, want.Size)
+	}
+	return nil
+}
+
+// CheckArchiveName checks that ArchiveDir can write an archive named name
+func CheckArchiveName(name string) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"):
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd isn't installed; use .tar.gz instead")
+		}
+		return nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"), strings.HasSuffix(name, ".tar"):
+		return nil
+	}
+	return fmt.Errorf("unknown archive type %s (use .tar.zst, .tar.gz, .tgz or .tar)", filepath.Base(name))
+}
+
+// compressor returns a writer compressing into f as the archive name, one
+// CheckArchiveName accepts, says
+func compressor(ctx context.Context, name string, f *os.File) (io.WriteCloser, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"):
+		cmd := exec.CommandContext(ctx, "zstd", "-q", "-c", "-T0")
+		cmd.Stdout = f
+		return startPiped(cmd)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return gzip.NewWriter(f), nil
+	}
+	return nopWriteCloser{f}, nil
+}
+
+// decompressor returns a reader decompressing f as the archive name says
+func decompressor(ctx context.Context, name string, f *os.File) (io.ReadCloser, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"):
+		cmd := exec.CommandContext(ctx, "zstd", "-q", "-d", "-c")
+		cmd.Stdin = f
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &pipedReader{out, cmd}, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return gzip.NewReader(f)
+	}
+	return io.NopCloser(f), nil
+}
+
+// startPiped starts cmd with a pipe to its input
+func startPiped(cmd *exec.Cmd) (io.WriteCloser, error) {
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pipedWriter{in, cmd}, nil
+}
+
+// pipedWriter writes to a command's input. Closing it waits for the command.
+type pipedWriter struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (w *pipedWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return w.cmd.Wait()
+}
+
+// pipedReader reads a command's output. Closing it waits for the command;
+// only the first close reports its error.
+type pipedReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *pipedReader) Close() error {
+	if r.cmd == nil {
+		return nil
+	}
+	cmd := r.cmd
+	r.cmd = nil
+	io.Copy(io.Discard, r.ReadCloser)
+	return cmd.Wait()
+}
+
+// nopWriteCloser leaves closing the file to its owner
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
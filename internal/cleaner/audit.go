@@ -0,0 +1,187 @@
+package cleaner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// auditRecord is a line of the audit log, one deleted file
+type auditRecord struct {
+	RunID     string    `json:"run_id"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Category  string    `json:"category"`
+	DeletedAt time.Time `json:"deleted_at"`
+	SHA256    string    `json:"sha256,omitempty"`
+}
+
+// fileChecksum is the SHA-256 of a file as it was when it was hashed
+type fileChecksum struct {
+	sum     string
+	size    int64
+	modTime time.Time
+}
+
+// checksumMinSize returns clean.checksum_min_size in bytes, 0 when files
+// aren't checksummed
+func (c *Cleaner) checksumMinSize() int64 {
+	if c.config.Clean.ChecksumMinSize == "" {
+		return 0
+	}
+	size, err := utils.ParseSize(c.config.Clean.ChecksumMinSize)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// checksumFiles hashes the regular files of at least clean.checksum_min_size
+// before anything is deleted, reporting progress as it goes. A file that
+// can't be read is skipped rather than deleted without its checksum.
+func (c *Cleaner) checksumFiles(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	minSize := c.checksumMinSize()
+	if minSize <= 0 {
+		return files
+	}
+
+	var todo int
+	var todoSize int64
+	for _, file := range files {
+		if file.Size >= minSize {
+			todo++
+			todoSize += file.Size
+		}
+	}
+	if todo == 0 {
+		return files
+	}
+
+	startTime := time.Now()
+	kept := files[:0]
+	done := 0
+	var doneSize int64
+	for _, file := range files {
+		if file.Size < minSize {
+			kept = append(kept, file)
+			continue
+		}
+		c.reportCleanProgress(progress.PhaseChecksumming, file.Path, done, todo, doneSize, todoSize, false, startTime)
+		if err := c.checksum(file.Path); err != nil {
+			result.SkippedFiles = append(result.SkippedFiles, file.Path)
+			result.SkippedReason[file.Path] = fmt.Sprintf("Couldn't checksum for the audit log: %v", err)
+			continue
+		}
+		done++
+		doneSize += file.Size
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// checksum records the SHA-256 of path if it's a regular file. Directories
+// are deleted without one.
+func (c *Cleaner) checksum(path string) error {
+	info, err := c.fs.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := c.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	c.checksums[path] = fileChecksum{
+		sum:     hex.EncodeToString(hash.Sum(nil)),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+	return nil
+}
+
+// verifyChecksum hashes a file again if it has changed since it was hashed,
+// just before it's deleted, so the audit log has the version that was deleted
+func (c *Cleaner) verifyChecksum(path string, info os.FileInfo) error {
+	sum, ok := c.checksums[path]
+	if !ok || (sum.size == info.Size() && sum.modTime.Equal(info.ModTime())) {
+		return nil
+	}
+	return c.checksum(path)
+}
+
+// writeAudit appends the files deleted in result, from the manifest entries
+// from on, to clean.audit_log as JSON lines
+func (c *Cleaner) writeAudit(result *CleanResult, from int) error {
+	path := c.config.Clean.AuditLog
+	if path == "" || from >= len(c.manifest.Files) {
+		return nil
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+
+	deleted := make(map[string]bool, len(result.DeletedFiles))
+	for _, file := range result.DeletedFiles {
+		deleted[file] = true
+	}
+	var lines []byte
+	for _, file := range c.manifest.Files[from:] {
+		if !deleted[file.Path] {
+			continue
+		}
+		line, err := json.Marshal(auditRecord{
+			RunID:     c.runID,
+			Path:      file.Path,
+			Size:      file.Size,
+			Category:  file.Category,
+			DeletedAt: file.DeletedAt,
+			SHA256:    file.SHA256,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if _, err := f.Write(lines); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
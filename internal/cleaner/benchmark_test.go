@@ -0,0 +1,63 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// =============================================================================
+// Deletion Benchmarks
+// =============================================================================
+
+// createSmallFileTree builds a node_modules-like tree of small files
+func createSmallFileTree(b *testing.B, root string) {
+	b.Helper()
+	for pkg := 0; pkg < 50; pkg++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%d", pkg), "lib")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for file := 0; file < 100; file++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.js", file)), []byte("x"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkDelete times remove over a fresh tree on each iteration
+func benchmarkDelete(b *testing.B, remove func(path string) error) {
+	base := b.TempDir()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		root := filepath.Join(base, fmt.Sprintf("node_modules%d", i))
+		createSmallFileTree(b, root)
+		b.StartTimer()
+
+		if err := remove(root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRemoveTree(b *testing.B) {
+	benchmarkDelete(b, func(path string) error { return removeTree(path, 0) })
+}
+
+func BenchmarkRemoveTreeSingleWorker(b *testing.B) {
+	benchmarkDelete(b, func(path string) error { return removeTree(path, 1) })
+}
+
+func BenchmarkRemoveAll(b *testing.B) {
+	benchmarkDelete(b, os.RemoveAll)
+}
+
+func BenchmarkRmRf(b *testing.B) {
+	if _, err := exec.LookPath("rm"); err != nil {
+		b.Skip("rm not available")
+	}
+	benchmarkDelete(b, func(path string) error { return exec.Command("rm", "-rf", "--", path).Run() })
+}
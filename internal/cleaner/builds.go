@@ -0,0 +1,228 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// runningBuild is a compiler or package manager at work in dir
+type runningBuild struct {
+	tool string
+	dir  string
+}
+
+// buildTools are the compilers and package managers that write build output
+// and package caches while they run, by executable or script name
+var buildTools = []string{
+	"npm", "npx", "yarn", "pnpm", "bun",
+	"pip", "pip3", "poetry", "uv", "pipenv",
+	"cargo", "rustc", "go", "gradle", "gradlew", "mvn", "mvnw",
+	"xcodebuild", "swift-build", "dotnet", "msbuild", "flutter", "dart",
+}
+
+// buildMainClasses name the Gradle and Maven clients, which run as java.
+// Gradle's daemon is left out: it outlives builds by hours.
+var buildMainClasses = map[string]string{
+	"org.gradle.wrapper.GradleWrapperMain":              "gradle",
+	"org.gradle.launcher.GradleMain":                    "gradle",
+	"org.codehaus.plexus.classworlds.launcher.Launcher": "mvn",
+	"org.apache.maven.wrapper.MavenWrapperMain":         "mvn",
+}
+
+// buildCategories are the categories a running build reads or writes
+var buildCategories = []string{
+	"cache", "build_artifacts", "node_modules", "virtual_envs",
+	scanner.CompilerCachesCategory, scanner.ProjectCachesCategory, scanner.VCSIgnoredCategory,
+	scanner.RPackagesCategory, scanner.JuliaCategory,
+}
+
+// buildTool returns the build tool a process with the command line args is,
+// or "". Scripts count by their name, not the interpreter running them, as
+// with node .../npm-cli.js or python -m pip.
+func buildTool(args []string) string {
+	for _, arg := range args {
+		if tool, ok := buildMainClasses[arg]; ok {
+			return tool
+		}
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.ToLower(filepath.Base(arg))
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		name = strings.TrimSuffix(name, "-cli")
+		if slices.Contains(buildTools, name) {
+			return name
+		}
+		if !interpreter(name) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// interpreter reports whether name runs the script it's given
+func interpreter(name string) bool {
+	switch name {
+	case "node", "java", "ruby", "sh", "bash", "zsh", "env":
+		return true
+	}
+	return strings.HasPrefix(name, "python")
+}
+
+// runningBuilds lists the build tools running and the directory each is in,
+// from /proc on Linux and with ps and lsof elsewhere. Processes whose
+// directory can't be read, other users' mostly, are left out.
+func runningBuilds() []runningBuild {
+	if runtime.GOOS == "linux" {
+		return procBuilds()
+	}
+	if _, err := exec.LookPath("ps"); err != nil {
+		return nil
+	}
+	if _, err := exec.LookPath("lsof"); err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "ps", "-axo", "pid=,args=").Output()
+	if err != nil {
+		return nil
+	}
+	tools := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		// Arguments with spaces are split apart, which only matters past
+		// the tool's name
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if tool := buildTool(fields[1:]); tool != "" {
+			tools[fields[0]] = tool
+		}
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+
+	pids := make([]string, 0, len(tools))
+	for pid := range tools {
+		pids = append(pids, pid)
+	}
+	// lsof exits non-zero when some processes are gone, so ignore the error
+	// and use whatever it printed
+	out, _ = exec.CommandContext(ctx, "lsof", "-a", "-d", "cwd", "-Fn", "-p", strings.Join(pids, ",")).Output()
+	return parseLsofCwds(string(out), tools)
+}
+
+// parseLsofCwds parses lsof -d cwd -Fn output, a p line with the PID followed
+// by an n line with the directory, into the builds of the processes in tools
+func parseLsofCwds(out string, tools map[string]string) []runningBuild {
+	var builds []runningBuild
+	pid := ""
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "p"):
+			pid = line[1:]
+		case strings.HasPrefix(line, "n") && tools[pid] != "":
+			builds = append(builds, runningBuild{tool: tools[pid], dir: line[1:]})
+		}
+	}
+	return builds
+}
+
+// procBuilds lists the running build tools from /proc
+func procBuilds() []runningBuild {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var builds []runningBuild
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		proc := filepath.Join("/proc", entry.Name())
+		cmdline, err := os.ReadFile(filepath.Join(proc, "cmdline"))
+		if err != nil {
+			continue
+		}
+		tool := buildTool(strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"))
+		if tool == "" {
+			continue
+		}
+		if dir, err := os.Readlink(filepath.Join(proc, "cwd")); err == nil {
+			builds = append(builds, runningBuild{tool: tool, dir: dir})
+		}
+	}
+	return builds
+}
+
+// buildIn returns the build running in path or below it, or in the tree
+// path is in, if any
+func buildIn(builds []runningBuild, path string) (runningBuild, bool) {
+	for _, build := range builds {
+		// A process started from / isn't working in any one tree
+		if build.dir == string(filepath.Separator) {
+			continue
+		}
+		if path == build.dir || anyUnder([]string{path}, build.dir) || anyUnder([]string{build.dir}, path) {
+			return build, true
+		}
+	}
+	return runningBuild{}, false
+}
+
+// skipRunningBuilds leaves out the build output and package caches a running
+// compiler or package manager is working in, so a build isn't broken halfway
+// through. They're skipped for this run only. It returns the files left to
+// clean.
+func (c *Cleaner) skipRunningBuilds(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	if !c.config.Clean.SkipRunningBuilds || !c.native() {
+		return files
+	}
+
+	var builds []runningBuild
+	listed := false
+	kept := files[:0:0]
+	for _, file := range files {
+		if !slices.Contains(buildCategories, file.Category) {
+			kept = append(kept, file)
+			continue
+		}
+		if !listed {
+			// Only list processes once something could be in use by a build
+			builds = c.builds()
+			listed = true
+		}
+		build, ok := buildIn(builds, file.Path)
+		if !ok {
+			kept = append(kept, file)
+			continue
+		}
+
+		delErr := &DeletionError{
+			Path:     file.Path,
+			Reason:   ErrorBuildRunning,
+			Original: fmt.Errorf("%s is running in %s", build.tool, build.dir),
+			App:      build.tool,
+		}
+		result.Errors = append(result.Errors, delErr)
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = delErr.UserMessage()
+	}
+	return kept
+}
@@ -1,17 +1,28 @@
 package cleaner
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fenilsonani/system-cleanup/internal/config"
 	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/runid"
 	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 // CleanResult represents the result of a clean operation
 type CleanResult struct {
+	RunID         string // Identifies this cleanup in the manifest, quarantine, journal and logs
 	DeletedFiles  []string
 	DeletedSize   int64
 	SkippedFiles  []string
@@ -21,8 +32,83 @@ type CleanResult struct {
 	UsedSudo      bool
 	SudoSucceeded int
 	SudoFailed    int
+	Filesystems   []FilesystemReclaim // Free space before and after, per file system; empty for a dry run
+	ReadOnly      []string            // A directory on each read-only file system whose files were skipped
+	Copied        []string            // Files copied to the quarantine from another file system, the slow path
+	NeedsSudo     []string            // Files skipped because they need sudo and it wasn't used
+	HookErrors    []string            // Pre and post hooks from clean.hooks that failed
 }
 
+// Merge folds the result of a retry into r. Paths in attempted are dropped from
+// r's skipped files and errors first, so only the latest outcome is kept.
+func (r *CleanResult) Merge(other *CleanResult, attempted []string) {
+	retried := make(map[string]bool, len(attempted))
+	for _, path := range attempted {
+		retried[path] = true
+	}
+
+	skipped := r.SkippedFiles[:0]
+	for _, path := range r.SkippedFiles {
+		if retried[path] {
+			delete(r.SkippedReason, path)
+			continue
+		}
+		skipped = append(skipped, path)
+	}
+	r.SkippedFiles = skipped
+
+	errs := r.Errors[:0]
+	for _, err := range r.Errors {
+		if !retried[err.Path] {
+			errs = append(errs, err)
+		}
+	}
+	r.Errors = errs
+
+	needsSudo := r.NeedsSudo[:0]
+	for _, path := range r.NeedsSudo {
+		if !retried[path] {
+			needsSudo = append(needsSudo, path)
+		}
+	}
+	r.NeedsSudo = needsSudo
+
+	r.DeletedFiles = append(r.DeletedFiles, other.DeletedFiles...)
+	r.DeletedSize += other.DeletedSize
+	r.SkippedFiles = append(r.SkippedFiles, other.SkippedFiles...)
+	for path, reason := range other.SkippedReason {
+		r.SkippedReason[path] = reason
+	}
+	r.Errors = append(r.Errors, other.Errors...)
+	r.HookErrors = append(r.HookErrors, other.HookErrors...)
+	r.UsedSudo = r.UsedSudo || other.UsedSudo
+	r.SudoSucceeded += other.SudoSucceeded
+	r.SudoFailed += other.SudoFailed
+	r.mergeFilesystems(other.Filesystems)
+	r.Copied = append(r.Copied, other.Copied...)
+	r.NeedsSudo = append(r.NeedsSudo, other.NeedsSudo...)
+	for _, dir := range other.ReadOnly {
+		if !slices.Contains(r.ReadOnly, dir) {
+			r.ReadOnly = append(r.ReadOnly, dir)
+		}
+	}
+}
+
+// unskip drops path from the skipped files
+func (r *CleanResult) unskip(path string) {
+	for i := len(r.SkippedFiles) - 1; i >= 0; i-- {
+		if r.SkippedFiles[i] == path {
+			r.SkippedFiles = append(r.SkippedFiles[:i], r.SkippedFiles[i+1:]...)
+			break
+		}
+	}
+	delete(r.SkippedReason, path)
+}
+
+// sudoKeepAliveInterval is how often the sudo timestamp is refreshed during a
+// cleanup, well inside sudo's default 5 minute timeout
+const sudoKeepAliveInterval = time.Minute
+
 // Cleaner handles file deletion with safeguards
 type Cleaner struct {
 	config            *config.Config
@@ -30,19 +116,43 @@ type Cleaner struct {
 	sudoManager       *SudoManager
 	manifest          *DeletionManifest
 	askSudo           bool // Whether to prompt for sudo if needed
+	sessionSudo       bool // Whether to use sudo when it needs no password
 	progressReporter  *progress.ProgressReporter
+	deleteTree        func(path string, workers int) error
+	quarantine        *Quarantine             // nil unless quarantine mode is enabled
+	quarantineRun     *QuarantineRun          // run for this cleaner, created on first clean
+	journal           *Journal                // nil unless progress is journaled for --resume
+	fs                vfs.FS                  // What files are deleted from
+	processes         func() map[string]bool  // Lists running process names, to spare running apps' data
+	builds            func() []runningBuild   // Lists running build tools, to spare what they're working on
+	checksums         map[string]fileChecksum // SHA-256 of large files, for the audit log
+	ctx               context.Context         // Stops the cleanup between files when cancelled
+	spread            time.Duration           // Deletions are spread over this long at low priority; 0 for as fast as possible
+	runID             string
+	mu                sync.Mutex // One cleanup at a time, as they share the manifest
 }
 
 // New creates a new Cleaner
 func New(cfg *config.Config) *Cleaner {
-	return &Cleaner{
+	c := &Cleaner{
 		config:            cfg,
 		permissionManager: NewPermissionManager(),
 		sudoManager:       NewSudoManager(),
 		manifest:          NewDeletionManifest(),
 		askSudo:           true, // Default to asking for sudo
 		progressReporter:  progress.NewProgressReporter(),
+		fs:                vfs.OS,
+		processes:         runningProcesses,
+		builds:            runningBuilds,
+		deleteTree:        removeTree,
+		checksums:         make(map[string]fileChecksum),
+		ctx:               context.Background(),
+		runID:             runid.New(),
+	}
+	if cfg.Quarantine.Enabled && cfg.Quarantine.Dir != "" {
+		c.quarantine = NewQuarantine(cfg.Quarantine.Dir)
 	}
+	return c
 }
 
 // SetAskSudo sets whether to prompt for sudo
@@ -50,25 +160,98 @@ func (c *Cleaner) SetAskSudo(ask bool) {
 	c.askSudo = ask
 }
 
+// SetSessionSudo sets whether to use sudo without prompting when an existing
+// session or a rule that needs no password allows it, for unattended runs
+func (c *Cleaner) SetSessionSudo(use bool) {
+	c.sessionSudo = use
+}
+
+// SetRunID sets the ID recorded with everything this cleaner does. Each
+// cleaner gets a new one otherwise; a resumed cleanup keeps the original's.
+func (c *Cleaner) SetRunID(id string) {
+	c.runID = id
+}
+
+// SetContext stops cleanups when ctx is cancelled. The file being deleted is
+// finished first; the rest are left, and the journal keeps them for --resume.
+func (c *Cleaner) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// RunID returns the ID recorded with everything this cleaner does
+func (c *Cleaner) RunID() string {
+	return c.runID
+}
+
+// SetFS deletes from fsys instead of the real file system. Sudo, quarantine
+// and free space measurement only work on the real one, so they're off.
+func (c *Cleaner) SetFS(fsys vfs.FS) {
+	c.fs = fsys
+	c.permissionManager.fs = fsys
+	if !vfs.IsOS(fsys) {
+		c.quarantine = nil
+	}
+}
+
+// native reports whether files are deleted from the real file system
+func (c *Cleaner) native() bool {
+	return vfs.IsOS(c.fs)
+}
+
 // SetProgressReporter sets a custom progress reporter
 func (c *Cleaner) SetProgressReporter(pr *progress.ProgressReporter) {
 	c.progressReporter = pr
 }
 
+// SetJournal records the progress of each clean in j, so it can be resumed
+func (c *Cleaner) SetJournal(j *Journal) {
+	c.journal = j
+}
+
+// markDone records processed paths in the journal, if there is one
+func (c *Cleaner) markDone(paths ...string) {
+	if c.journal != nil {
+		c.journal.Done(paths...)
+	}
+}
+
+// QuarantineRun returns the quarantine run holding files moved by this
+// cleaner, or nil if quarantine mode is off or nothing has been cleaned yet
+func (c *Cleaner) QuarantineRun() *QuarantineRun {
+	return c.quarantineRun
+}
+
 // GetProgressReporter returns the cleaner's progress reporter
 func (c *Cleaner) GetProgressReporter() *progress.ProgressReporter {
 	return c.progressReporter
 }
 
-// Clean performs the cleanup operation with smart sudo handling
-func (c *Cleaner) Clean(scanResult *scanner.ScanResult) (cleanResult *CleanResult, cleanErr error) {
+// Clean performs the cleanup operation with smart sudo handling. Spilled scan
+// results are read back into memory first.
+func (c *Cleaner) Clean(scanResult *scanner.ScanResult) (*CleanResult, error) {
+	if c.spread > 0 && !c.config.DryRun {
+		return c.cleanSpread(func() (*CleanResult, error) { return c.clean(scanResult) })
+	}
+	return c.clean(scanResult)
+}
+
+// clean is Clean, at whatever priority it's called with
+func (c *Cleaner) clean(scanResult *scanner.ScanResult) (cleanResult *CleanResult, cleanErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := scanResult.Load(); err != nil {
+		return nil, err
+	}
 	result := &CleanResult{
+		RunID:         c.runID,
 		DeletedFiles:  []string{},
 		SkippedFiles:  []string{},
 		SkippedReason: make(map[string]string),
 		Errors:        []*DeletionError{},
 		DryRun:        c.config.DryRun,
 	}
+	c.manifest.RunID = c.runID
 
 	// SECURITY: Ensure sudo password is ALWAYS cleared, even on panic
 	sudoWasUsed := false
@@ -91,14 +274,88 @@ func (c *Cleaner) Clean(scanResult *scanner.ScanResult) (cleanResult *CleanResul
 		return result, nil
 	}
 
+	// Clean categories in the configured order
+	files := orderByCategory(scanResult.Files, c.config.Clean.Order)
+
+	// Deleting a browser or IDE cache while the app runs corrupts its profile
+	files = c.skipRunningApps(files, result)
+
+	// Deleting build output or packages mid-build breaks the build
+	files = c.skipRunningBuilds(files, result)
+
+	// Keys and documents are never deleted, whatever rule matched them
+	files = c.skipNeverDelete(files, result)
+
+	// Pre hooks can stop what uses a category's files, such as containers;
+	// post hooks, which start them again, run however the cleanup ends
+	hooks := &hookRun{c: c}
+	files = hooks.before(files, result)
+	defer func() { hooks.after(result) }()
+
+	// Measure free space up front to compare with what was deleted at the
+	// end. Nothing can be deleted from a read-only file system, so its files
+	// are skipped rather than each failing the same way.
+	space := &spaceMeter{}
+	if c.native() {
+		space = measureSpace(files)
+		files = space.skipReadOnly(files, result)
+	}
+
+	// Large files are hashed before anything is deleted, for the audit log
+	files = c.checksumFiles(files, result)
+	auditFrom := len(c.manifest.Files)
+	defer func() {
+		if err := c.writeAudit(result, auditFrom); err != nil && cleanErr == nil {
+			cleanErr = err
+		}
+	}()
+
+	// Files are moved aside instead of deleted when quarantine mode is on.
+	// Retries reuse the same run so undo covers everything from this cleaner.
+	if c.quarantine != nil && c.native() {
+		if err := c.quarantine.preflight(files); err != nil {
+			return nil, err
+		}
+	}
+	if c.quarantine != nil && c.quarantineRun == nil {
+		run, err := c.quarantine.Begin(c.runID)
+		if err != nil {
+			return nil, err
+		}
+		c.quarantineRun = run
+	}
+	if c.quarantineRun != nil {
+		defer func() {
+			if err := c.quarantineRun.Save(); err != nil && cleanErr == nil {
+				cleanErr = err
+			}
+		}()
+	}
+
+	// Journal the plan so an interrupted run can be resumed. The journal is
+	// only removed once the run gets to the end.
+	completed := false
+	if c.journal != nil {
+		if err := c.journal.Begin(c.runID, files); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if completed {
+				c.journal.Finish()
+			} else {
+				c.journal.Close()
+			}
+		}()
+	}
+
 	// Pre-flight: Analyze permissions
 	startTime := time.Now()
-	totalFiles := len(scanResult.Files)
+	totalFiles := len(files)
 	totalSize := scanResult.TotalSize
 
-	filePaths := make([]string, len(scanResult.Files))
+	filePaths := make([]string, len(files))
 	fileMap := make(map[string]scanner.FileInfo)
-	for i, file := range scanResult.Files {
+	for i, file := range files {
 		filePaths[i] = file.Path
 		fileMap[file.Path] = file
 	}
@@ -110,84 +367,41 @@ func (c *Cleaner) Clean(scanResult *scanner.ScanResult) (cleanResult *CleanResul
 		return 0
 	})
 
-	// Report start of cleanup
-	c.reportCleanProgress(progress.PhaseCleaning, "", 0, totalFiles, 0, totalSize, false, startTime)
-
-	// First, delete files that don't need sudo
-	for _, path := range permReport.NormalFiles {
-		file := fileMap[path]
+	// Only categories the user pre-approved may use sudo
+	permReport.RequiresSudo = c.filterSudoAllowed(permReport.RequiresSudo, fileMap, result)
 
-		// Report current file
-		c.reportCleanProgress(progress.PhaseCleaning, file.Path, len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, false, startTime)
-
-		if err := c.deleteFileNormalWithRetry(file, result); err != nil {
-			result.Errors = append(result.Errors, err)
-		}
-	}
-
-	// Handle files requiring sudo
+	// Ask for the password once, before anything is deleted, so an unattended
+	// run is never held up by a prompt halfway through
+	elevated := false
 	if len(permReport.RequiresSudo) > 0 {
-		if c.askSudo && c.sudoManager.IsAvailable() {
-			// Ask user for sudo password
-			if err := c.sudoManager.PromptForPassword(); err != nil {
+		if (c.askSudo || c.sessionSudo) && c.native() && c.sudoManager.IsAvailable() {
+			var err error
+			if c.askSudo {
+				printSudoBatch(permReport.RequiresSudo, fileMap)
+				err = c.sudoManager.PromptForPassword()
+			} else {
+				err = c.sudoManager.UseSession()
+			}
+			if err != nil {
 				// User declined or password wrong, skip sudo files
+				reason := "Requires elevated permissions (sudo declined)"
+				if !c.askSudo {
+					reason = "Requires elevated permissions (sudo needs a password)"
+				}
 				for _, path := range permReport.RequiresSudo {
 					result.SkippedFiles = append(result.SkippedFiles, path)
-					result.SkippedReason[path] = "Requires elevated permissions (sudo declined)"
+					result.SkippedReason[path] = reason
 				}
+				result.NeedsSudo = append(result.NeedsSudo, permReport.RequiresSudo...)
 			} else {
+				elevated = true
+
 				// Mark that sudo is being used (for cleanup in defer)
 				sudoWasUsed = true
 
-				// Start keep-alive goroutine to maintain sudo session
-				stopKeepAlive := make(chan struct{})
-				defer close(stopKeepAlive)
-
-				go func() {
-					ticker := time.NewTicker(4 * time.Minute)
-					defer ticker.Stop()
-					for {
-						select {
-						case <-ticker.C:
-							// Refresh sudo session
-							if err := c.sudoManager.KeepAlive(); err != nil {
-								// Session expired, can't continue
-								return
-							}
-						case <-stopKeepAlive:
-							return
-						}
-					}
-				}()
-
-				// Delete files with sudo using batch operations for better performance
-				result.UsedSudo = true
-
-				// Use batch deletion (100 files per sudo command)
-				succeeded, failed := c.sudoManager.DeleteFiles(permReport.RequiresSudo)
-
-				// Update results and manifest
-				for _, path := range succeeded {
-					file := fileMap[path]
-
-					// Add to manifest
-					c.manifest.Add(file.Path, file.Size, file.Category)
-
-					result.DeletedFiles = append(result.DeletedFiles, file.Path)
-					result.DeletedSize += file.Size
-					result.SudoSucceeded++
-
-					// Report progress
-					c.reportCleanProgress(progress.PhaseCleaning, file.Path, len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, true, startTime)
-				}
-
-				for path, err := range failed {
-					delErr := CategorizeError(path, err)
-					result.Errors = append(result.Errors, delErr)
-					result.SkippedFiles = append(result.SkippedFiles, path)
-					result.SkippedReason[path] = delErr.UserMessage()
-					result.SudoFailed++
-				}
+				// Keep the sudo timestamp fresh while files are deleted
+				stopKeepAlive := c.sudoManager.StartKeepAlive(sudoKeepAliveInterval)
+				defer stopKeepAlive()
 			}
 		} else {
 			// Sudo not available or not asking, skip these files
@@ -195,63 +409,170 @@ func (c *Cleaner) Clean(scanResult *scanner.ScanResult) (cleanResult *CleanResul
 				result.SkippedFiles = append(result.SkippedFiles, path)
 				result.SkippedReason[path] = "Requires elevated permissions"
 			}
+			result.NeedsSudo = append(result.NeedsSudo, permReport.RequiresSudo...)
 		}
 	}
 
-	// Handle inaccessible files
-	for path, err := range permReport.InaccessibleFiles {
-		result.SkippedFiles = append(result.SkippedFiles, path)
-		result.SkippedReason[path] = fmt.Sprintf("Inaccessible: %v", err)
+	// Report start of cleanup
+	c.reportCleanProgress(progress.PhaseCleaning, "", 0, totalFiles, 0, totalSize, false, startTime)
+
+	// First, delete files that don't need sudo. Busy files are queued and
+	// retried at the end so they don't hold up everything else. Files the
+	// analysis missed that turn out to need sudo join the sudo batch when
+	// there is one, rather than asking again.
+	var escalate []string
+	if elevated {
+		escalate = slices.Clone(permReport.RequiresSudo)
 	}
 
-	// Report completion
-	c.reportCleanProgress(progress.PhaseComplete, "", len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, result.UsedSudo, startTime)
+	// Pace what's deleted, if the cleanup is spread out
+	pace := newPacer(c.spread, append(slices.Clone(permReport.NormalFiles), escalate...), fileMap)
 
-	return result, nil
-}
+	// Escalated files were added to the manifest when they were first tried
+	retried := make(map[string]bool)
+	busy := c.newRetryQueue()
+	for _, path := range permReport.NormalFiles {
+		if err := c.ctx.Err(); err != nil {
+			result.Filesystems = space.finish(result, fileMap, c.quarantineRun != nil)
+			return result, fmt.Errorf("cleanup interrupted: %w", err)
+		}
+		file := fileMap[path]
+
+		// Report current file
+		c.reportCleanProgress(progress.PhaseCleaning, file.Path, len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, false, startTime)
 
-// deleteFileNormalWithRetry attempts to delete a file with retries for transient errors
-func (c *Cleaner) deleteFileNormalWithRetry(file scanner.FileInfo, result *CleanResult) *DeletionError {
-	const maxRetries = 3
-	retryDelays := []time.Duration{
-		100 * time.Millisecond,
-		500 * time.Millisecond,
-		2 * time.Second,
+		if err := c.deleteFileNormalQueued(file, result, busy); err != nil {
+			if err.NeedsSudo && c.sudoAllowed(file.Category) {
+				if elevated {
+					result.unskip(path)
+					escalate = append(escalate, path)
+					retried[path] = true
+					continue
+				}
+				result.NeedsSudo = append(result.NeedsSudo, path)
+			}
+			result.Errors = append(result.Errors, err)
+		}
+		if !busy.queued(path) {
+			c.markDone(path)
+		}
+		pace.wait(c.ctx, 1, file.Size)
 	}
 
-	var lastErr *DeletionError
+	// Then delete everything that needs sudo in batches (100 files per sudo
+	// command)
+	if err := c.ctx.Err(); err != nil {
+		result.Filesystems = space.finish(result, fileMap, c.quarantineRun != nil)
+		return result, fmt.Errorf("cleanup interrupted: %w", err)
+	}
+	if len(escalate) > 0 {
+		result.UsedSudo = true
+		batchSize := len(escalate)
+		if pace != nil {
+			batchSize = sudoPacedBatch
+		}
+		var succeeded []string
+		failed := make(map[string]error)
+		for batch := range slices.Chunk(escalate, batchSize) {
+			ok, errs := c.sudoManager.DeleteFiles(batch)
+			succeeded = append(succeeded, ok...)
+			maps.Copy(failed, errs)
+
+			var size int64
+			for _, path := range batch {
+				size += fileMap[path].Size
+			}
+			if pace.wait(c.ctx, len(batch), size) != nil {
+				break
+			}
+		}
+		c.markDone(succeeded...)
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Try to delete (result is only modified on success)
-		lastErr = c.deleteFileNormal(file, result)
+		// Update results and manifest
+		for _, path := range succeeded {
+			file := fileMap[path]
 
-		if lastErr == nil {
-			// Success
-			return nil
+			// Add to manifest
+			if !retried[path] {
+				c.manifest.addChecksummed(file, c.checksums)
+			}
+
+			result.DeletedFiles = append(result.DeletedFiles, file.Path)
+			result.DeletedSize += file.Size
+			result.SudoSucceeded++
+
+			// Report progress
+			c.reportCleanProgress(progress.PhaseCleaning, file.Path, len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, true, startTime)
 		}
 
-		if !lastErr.Retryable {
-			// Not retryable (permission denied, path invalid, etc.), give up
-			return lastErr
+		for path, err := range failed {
+			c.markDone(path)
+			delErr := CategorizeError(path, err)
+			result.Errors = append(result.Errors, delErr)
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			result.SkippedReason[path] = delErr.UserMessage()
+			result.SudoFailed++
 		}
+	}
+
+	// Handle inaccessible files
+	for path, err := range permReport.InaccessibleFiles {
+		result.SkippedFiles = append(result.SkippedFiles, path)
+		result.SkippedReason[path] = fmt.Sprintf("Inaccessible: %v", err)
+	}
+
+	// A spread cleanup can be stopped while it waits
+	if err := c.ctx.Err(); err != nil {
+		result.Filesystems = space.finish(result, fileMap, c.quarantineRun != nil)
+		return result, fmt.Errorf("cleanup interrupted: %w", err)
+	}
 
-		// File is in use or temporarily unavailable
-		// On last attempt, don't sleep
-		if attempt < maxRetries-1 {
-			time.Sleep(retryDelays[attempt])
-			// Clear any partial state from failed attempt
-			// (deleteFileNormal only adds to result.SkippedFiles on error, which is ok)
+	// Retry busy files now that everything else is done
+	for _, err := range busy.process(func(file scanner.FileInfo) *DeletionError {
+		err := c.deleteFileNormalQueued(file, result, nil)
+		if err == nil || !err.Retryable {
+			c.markDone(file.Path)
+		}
+		return err
+	}) {
+		if err.Retryable {
+			result.SkippedFiles = append(result.SkippedFiles, err.Path)
+			result.SkippedReason[err.Path] = err.UserMessage()
 		}
+		result.Errors = append(result.Errors, err)
 	}
 
-	// All retries exhausted - return last error
-	return lastErr
+	result.Filesystems = space.finish(result, fileMap, c.quarantineRun != nil)
+	c.recountCCache(result.DeletedFiles, fileMap)
+
+	// Report completion
+	c.reportCleanProgress(progress.PhaseComplete, "", len(result.DeletedFiles), totalFiles, result.DeletedSize, totalSize, result.UsedSudo, startTime)
+
+	completed = true
+	return result, nil
+}
+
+// deleteFileNormalQueued deletes a file, leaving it out of the skipped files
+// if it failed because it was busy so it can be retried. The file is added to
+// queue if one is given.
+func (c *Cleaner) deleteFileNormalQueued(file scanner.FileInfo, result *CleanResult, queue *retryQueue) *DeletionError {
+	err := c.deleteFileNormal(file, result)
+	if err == nil || !err.Retryable {
+		return err
+	}
+
+	result.unskip(file.Path)
+	if queue != nil {
+		queue.add(file, err)
+		return nil
+	}
+	return err
 }
 
 // deleteFileNormal deletes a file with normal permissions
 func (c *Cleaner) deleteFileNormal(file scanner.FileInfo, result *CleanResult) *DeletionError {
 	// Safety check: verify it's safe to delete (not a special file)
-	if err := IsSafeToDelete(file.Path); err != nil {
+	if err := isSafeToDelete(c.fs, file.Path); err != nil {
 		result.SkippedFiles = append(result.SkippedFiles, file.Path)
 		result.SkippedReason[file.Path] = fmt.Sprintf("Safety check failed: %v", err)
 		return &DeletionError{
@@ -263,7 +584,7 @@ func (c *Cleaner) deleteFileNormal(file scanner.FileInfo, result *CleanResult) *
 
 	// Safety check: verify file age again
 	// Use Lstat to not follow symlinks (prevents TOCTOU attacks)
-	info, err := os.Lstat(file.Path)
+	info, err := c.fs.Lstat(file.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// File already deleted, that's fine
@@ -290,15 +611,40 @@ func (c *Cleaner) deleteFileNormal(file scanner.FileInfo, result *CleanResult) *
 		return nil
 	}
 
+	if err := c.verifyChecksum(file.Path, info); err != nil {
+		delErr := CategorizeError(file.Path, err)
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = fmt.Sprintf("Couldn't checksum for the audit log: %v", err)
+		return delErr
+	}
+
 	// Add to manifest before deleting
-	c.manifest.Add(file.Path, file.Size, file.Category)
+	c.manifest.addChecksummed(file, c.checksums)
 
-	// Attempt deletion - use RemoveAll for directories (e.g., node_modules, venv)
+	// Attempt deletion - directories (e.g., node_modules, venv) are removed
+	// with parallel unlinkat workers, with RemoveAll mopping up anything left.
+	// A spread cleanup uses just its own low priority thread: other workers
+	// would run at normal priority.
 	var deleteErr error
-	if info.IsDir() {
-		deleteErr = os.RemoveAll(file.Path)
+	if c.quarantineRun != nil {
+		var copied bool
+		if copied, deleteErr = c.quarantineRun.Move(file); copied && deleteErr == nil {
+			result.Copied = append(result.Copied, file.Path)
+		}
+	} else if info.IsDir() && file.Category == scanner.EmptyDirsCategory {
+		deleteErr = removeEmptyDirs(c.fs, file.Path)
+	} else if info.IsDir() && c.native() {
+		workers := 0
+		if c.spread > 0 {
+			workers = 1
+		}
+		if deleteErr = c.deleteTree(file.Path, workers); deleteErr != nil {
+			deleteErr = os.RemoveAll(file.Path)
+		}
+	} else if info.IsDir() {
+		deleteErr = c.fs.RemoveAll(file.Path)
 	} else {
-		deleteErr = os.Remove(file.Path)
+		deleteErr = c.fs.Remove(file.Path)
 	}
 	if deleteErr != nil {
 		delErr := CategorizeError(file.Path, deleteErr)
@@ -313,6 +659,24 @@ func (c *Cleaner) deleteFileNormal(file scanner.FileInfo, result *CleanResult) *
 	return nil
 }
 
+// removeEmptyDirs removes a tree of empty directories one rmdir at a time, so
+// a file that has appeared in it since the scan stops it instead of being
+// deleted
+func removeEmptyDirs(fsys vfs.FS, path string) error {
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := removeEmptyDirs(fsys, filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return fsys.Remove(path)
+}
+
 // deleteFileSudo deletes a file using sudo
 func (c *Cleaner) deleteFileSudo(file scanner.FileInfo, result *CleanResult) *DeletionError {
 	// Safety check: verify it's safe to delete (not a special file)
@@ -356,7 +720,7 @@ func (c *Cleaner) deleteFileSudo(file scanner.FileInfo, result *CleanResult) *De
 	}
 
 	// Add to manifest
-	c.manifest.Add(file.Path, file.Size, file.Category)
+	c.manifest.addChecksummed(file, c.checksums)
 
 	// Delete with sudo
 	if err := c.sudoManager.DeleteFile(file.Path); err != nil {
@@ -374,6 +738,10 @@ func (c *Cleaner) deleteFileSudo(file scanner.FileInfo, result *CleanResult) *De
 
 // CleanCategory cleans files from a specific category
 func (c *Cleaner) CleanCategory(scanResult *scanner.ScanResult, category string) (*CleanResult, error) {
+	if err := scanResult.Load(); err != nil {
+		return nil, err
+	}
+
 	// Filter files by category
 	filteredResult := &scanner.ScanResult{
 		Files:  []scanner.FileInfo{},
@@ -391,6 +759,196 @@ func (c *Cleaner) CleanCategory(scanResult *scanner.ScanResult, category string)
 	return c.Clean(filteredResult)
 }
 
+// EscalateFiles deletes files with sudo, prompting for the password. It is used
+// to retry files that were skipped or failed with permission errors.
+func (c *Cleaner) EscalateFiles(files []scanner.FileInfo) (escalated *CleanResult, escalateErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &CleanResult{
+		RunID:         c.runID,
+		DeletedFiles:  []string{},
+		SkippedFiles:  []string{},
+		SkippedReason: make(map[string]string),
+		Errors:        []*DeletionError{},
+		DryRun:        c.config.DryRun,
+	}
+
+	files = c.skipNeverDelete(files, result)
+	fileMap := make(map[string]scanner.FileInfo, len(files))
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		fileMap[file.Path] = file
+		paths = append(paths, file.Path)
+	}
+	paths = c.filterSudoAllowed(paths, fileMap, result)
+
+	if len(paths) == 0 || c.config.DryRun {
+		return result, nil
+	}
+
+	if !c.native() || !c.sudoManager.IsAvailable() {
+		return nil, fmt.Errorf("sudo is not available on this system")
+	}
+
+	if err := c.sudoManager.PromptForPassword(); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	// SECURITY: Always clear the password once the batch is done
+	defer c.sudoManager.Clear()
+	stopKeepAlive := c.sudoManager.StartKeepAlive(sudoKeepAliveInterval)
+	defer stopKeepAlive()
+
+	auditFrom := len(c.manifest.Files)
+	defer func() {
+		if escalated == nil {
+			return
+		}
+		if err := c.writeAudit(escalated, auditFrom); err != nil && escalateErr == nil {
+			escalateErr = err
+		}
+	}()
+
+	safe := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if err := IsSafeToDelete(path); err != nil {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			result.SkippedReason[path] = fmt.Sprintf("Safety check failed: %v", err)
+			continue
+		}
+		safe = append(safe, path)
+	}
+
+	result.UsedSudo = true
+	succeeded, failed := c.sudoManager.DeleteFiles(safe)
+
+	for _, path := range succeeded {
+		file := fileMap[path]
+		c.manifest.addChecksummed(file, c.checksums)
+		result.DeletedFiles = append(result.DeletedFiles, file.Path)
+		result.DeletedSize += file.Size
+		result.SudoSucceeded++
+	}
+
+	for path, err := range failed {
+		delErr := CategorizeError(path, err)
+		result.Errors = append(result.Errors, delErr)
+		result.SkippedFiles = append(result.SkippedFiles, path)
+		result.SkippedReason[path] = delErr.UserMessage()
+		result.SudoFailed++
+	}
+
+	return result, nil
+}
+
+// filterSudoAllowed returns the paths whose category may be deleted with
+// sudo, recording the rest in result as skipped
+func (c *Cleaner) filterSudoAllowed(paths []string, files map[string]scanner.FileInfo, result *CleanResult) []string {
+	allowed := make([]string, 0, len(paths))
+	for _, path := range paths {
+		category := files[path].Category
+		if c.sudoAllowed(category) {
+			allowed = append(allowed, path)
+			continue
+		}
+		result.SkippedFiles = append(result.SkippedFiles, path)
+		result.SkippedReason[path] = fmt.Sprintf("Elevated permissions not allowed for category %q", category)
+	}
+	return allowed
+}
+
+// skipNeverDelete leaves out the files whose extension is listed in
+// never_delete_extensions, and the directories of user data holding any,
+// recording them as skipped. It returns the files left to clean.
+func (c *Cleaner) skipNeverDelete(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	if len(c.config.NeverDeleteExtensions) == 0 {
+		return files
+	}
+	kept := files[:0:0]
+	for _, file := range files {
+		reason, ok := c.neverDeleteReason(file.Path, file.Category)
+		if !ok {
+			kept = append(kept, file)
+			continue
+		}
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = "Never deleted: " + reason
+	}
+	return kept
+}
+
+// userDataCategories are the categories whose directories can hold files
+// the user made. Caches and dev artifacts are left out: they're rebuilt, and
+// ship .pem and .key files of their own (certifi's cacert.pem, test keys).
+var userDataCategories = map[string]bool{
+	"old_files":                true,
+	"large_files":              true,
+	scanner.DownloadsCategory:  true,
+	scanner.StaleReposCategory: true,
+	"archived_project":         true,
+}
+
+// neverDeleteReason says why path, found in category, is never deleted: its
+// name matches never_delete_extensions or, as directories are deleted whole,
+// something inside a directory of user data does
+func (c *Cleaner) neverDeleteReason(path, category string) (string, bool) {
+	if ext, ok := c.neverDeletes(path); ok {
+		return fmt.Sprintf("matches never_delete_extensions %q", ext), true
+	}
+	if !userDataCategories[category] {
+		return "", false
+	}
+	info, err := c.fs.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return "", false
+	}
+
+	var reason string
+	vfs.WalkDir(c.fs, path, func(inner string, d fs.DirEntry, err error) error {
+		if err != nil || inner == path {
+			return nil
+		}
+		if ext, ok := c.neverDeletes(inner); ok {
+			reason = fmt.Sprintf("holds %s, which matches never_delete_extensions %q", inner, ext)
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return reason, reason != ""
+}
+
+// neverDeletes returns the never_delete_extensions pattern path's name
+// matches, ignoring case
+func (c *Cleaner) neverDeletes(path string) (string, bool) {
+	name := strings.ToLower(filepath.Base(path))
+	for _, ext := range c.config.NeverDeleteExtensions {
+		if ok, _ := filepath.Match("*"+strings.ToLower(ext), name); ok {
+			return ext, true
+		}
+	}
+	return "", false
+}
+
+// sudoAllowed reports whether files in category may be deleted with sudo
+func (c *Cleaner) sudoAllowed(category string) bool {
+	// sudo deletes whole trees, which could take files that turned up in an
+	// empty directory since the scan
+	return c.config.Sudo.AllowsCategory(category) && category != scanner.EmptyDirsCategory
+}
+
+// printSudoBatch tells the user how much is about to be deleted with sudo,
+// before the one password prompt for all of it
+func printSudoBatch(paths []string, files map[string]scanner.FileInfo) {
+	var size int64
+	categories := make(map[string]bool)
+	for _, path := range paths {
+		size += files[path].Size
+		categories[files[path].Category] = true
+	}
+	fmt.Fprintf(os.Stderr, "\n%d files (%s) in %d categories need elevated permissions. They are deleted together after the rest.\n",
+		len(paths), utils.FormatBytes(size), len(categories))
+}
+
 // GetManifest returns the deletion manifest
 func (c *Cleaner) GetManifest() *DeletionManifest {
 	return c.manifest
@@ -401,7 +959,8 @@ func (c *Cleaner) SaveManifest(path string) error {
 	return c.manifest.Save(path)
 }
 
-// GetPermissionReport analyzes files and returns a permission report
+// GetPermissionReport analyzes files and returns a permission report. Spilled
+// scan results must be loaded first.
 func (c *Cleaner) GetPermissionReport(scanResult *scanner.ScanResult) *PermissionReport {
 	filePaths := make([]string, len(scanResult.Files))
 	fileMap := make(map[string]scanner.FileInfo)
@@ -424,7 +983,8 @@ func (c *Cleaner) reportCleanProgress(phase progress.Phase, currentFile string,
 		return
 	}
 
-	c.progressReporter.UpdateCleanProgress(&progress.CleanProgress{
+	update := &progress.CleanProgress{
+		RunID:        c.runID,
 		Phase:        phase,
 		CurrentFile:  currentFile,
 		DeletedFiles: deletedFiles,
@@ -433,11 +993,22 @@ func (c *Cleaner) reportCleanProgress(phase progress.Phase, currentFile string,
 		TotalSize:    totalSize,
 		UsingSudo:    usingSudo,
 		StartTime:    startTime,
-	})
+	}
+	// Deleting costs about the same per file whatever its size, so the
+	// time left goes by files
+	if seconds := time.Since(startTime).Seconds(); seconds > 0 {
+		update.FilesPerSecond = float64(deletedFiles) / seconds
+		update.BytesPerSecond = float64(deletedSize) / seconds
+	}
+	if update.FilesPerSecond > 0 && totalFiles > deletedFiles {
+		update.ETA = time.Duration(float64(totalFiles-deletedFiles) / update.FilesPerSecond * float64(time.Second))
+	}
+	c.progressReporter.UpdateCleanProgress(update)
 }
 
 // DeletionManifest keeps track of deleted files
 type DeletionManifest struct {
+	RunID     string
 	Files     []DeletedFileInfo
 	Timestamp time.Time
 	TotalSize int64
@@ -449,6 +1020,7 @@ type DeletedFileInfo struct {
 	Size      int64
 	Category  string
 	DeletedAt time.Time
+	SHA256    string // Checksum taken before deletion, empty if it wasn't
 }
 
 // NewDeletionManifest creates a new DeletionManifest
@@ -470,6 +1042,13 @@ func (m *DeletionManifest) Add(path string, size int64, category string) {
 	m.TotalSize += size
 }
 
+// addChecksummed adds a file to the manifest with its checksum from sums,
+// if it was hashed
+func (m *DeletionManifest) addChecksummed(file scanner.FileInfo, sums map[string]fileChecksum) {
+	m.Add(file.Path, file.Size, file.Category)
+	m.Files[len(m.Files)-1].SHA256 = sums[file.Path].sum
+}
+
 // Save saves the manifest to a file
 func (m *DeletionManifest) Save(path string) error {
 	file, err := os.Create(path)
@@ -479,13 +1058,20 @@ func (m *DeletionManifest) Save(path string) error {
 	defer file.Close()
 
 	fmt.Fprintf(file, "Deletion Manifest\n")
+	if m.RunID != "" {
+		fmt.Fprintf(file, "Run: %s\n", m.RunID)
+	}
 	fmt.Fprintf(file, "Created: %s\n", m.Timestamp.Format(time.RFC3339))
 	fmt.Fprintf(file, "Total Size: %d bytes\n", m.TotalSize)
 	fmt.Fprintf(file, "Total Files: %d\n\n", len(m.Files))
 
 	for _, f := range m.Files {
-		fmt.Fprintf(file, "%s | %d bytes | %s | %s\n",
+		fmt.Fprintf(file, "%s | %d bytes | %s | %s",
 			f.Path, f.Size, f.Category, f.DeletedAt.Format(time.RFC3339))
+		if f.SHA256 != "" {
+			fmt.Fprintf(file, " | sha256:%s", f.SHA256)
+		}
+		fmt.Fprintln(file)
 	}
 
 	return nil
@@ -1,18 +1,32 @@
 package cleaner
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
 	"github.com/fenilsonani/system-cleanup/internal/scanner"
 	"github.com/fenilsonani/system-cleanup/internal/testutil"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
 )
 
 // =============================================================================
@@ -97,6 +111,9 @@ func TestErrorReasonString(t *testing.T) {
 		{ErrorIsDirectory, "Is a directory"},
 		{ErrorInvalidPath, "Invalid path"},
 		{ErrorUnknown, "Unknown error"},
+		{ErrorAppRunning, "Application is running"},
+		{ErrorImmutable, "Immutable file"},
+		{ErrorPolicyDenied, "Blocked by security policy"},
 		{ErrorReason(999), "Unspecified error"}, // Invalid enum value
 		{ErrorReason(-1), "Unspecified error"},  // Negative value
 	}
@@ -259,6 +276,62 @@ func TestFormatErrorSummary(t *testing.T) {
 			t.Error("summary should mention file in use")
 		}
 	})
+
+	t.Run("flags and policy", func(t *testing.T) {
+		errs := []*DeletionError{
+			{Path: "/a", Reason: ErrorImmutable},
+			{Path: "/b", Reason: ErrorPolicyDenied},
+		}
+		summary := FormatErrorSummary(errs)
+		if !strings.Contains(summary, "Immutable or append-only: 1 files") {
+			t.Errorf("summary should count immutable files: %s", summary)
+		}
+		if !strings.Contains(summary, "Elevated permissions won't help") {
+			t.Errorf("summary should say sudo won't help a policy denial: %s", summary)
+		}
+		if strings.Contains(summary, "Run with sudo") {
+			t.Errorf("summary should not suggest sudo: %s", summary)
+		}
+	})
+}
+
+func TestCategorizeErrorImmutable(t *testing.T) {
+	if _, err := exec.LookPath("chattr"); err != nil {
+		t.Skip("chattr not available")
+	}
+	f := testutil.NewFixture(t)
+	path := f.CreateFile("locked/file", []byte("x"))
+	if err := exec.Command("chattr", "+i", path).Run(); err != nil {
+		t.Skip("can't set the immutable flag here (needs root and a supporting file system)")
+	}
+	t.Cleanup(func() { exec.Command("chattr", "-i", path).Run() })
+
+	err := os.Remove(path)
+	if err == nil {
+		t.Fatal("expected removing an immutable file to fail")
+	}
+	delErr := CategorizeError(path, err)
+	if delErr.Reason != ErrorImmutable || delErr.NeedsSudo {
+		t.Errorf("got reason %v, NeedsSudo %v; want immutable without sudo", delErr.Reason, delErr.NeedsSudo)
+	}
+
+	// Through sudo there's only rm's message
+	delErr = CategorizeError(path, fmt.Errorf("rm: cannot remove '%s': Operation not permitted", path))
+	if delErr.Reason != ErrorImmutable {
+		t.Errorf("got reason %v from rm's message, want immutable", delErr.Reason)
+	}
+
+	// A file in an append-only directory can't be removed either
+	dir := filepath.Dir(path)
+	other := f.CreateFile("locked/other", []byte("x"))
+	exec.Command("chattr", "-i", path).Run()
+	if err := exec.Command("chattr", "+a", dir).Run(); err != nil {
+		t.Skip("can't set the append-only flag here")
+	}
+	t.Cleanup(func() { exec.Command("chattr", "-a", dir).Run() })
+	if reason := deniedReason(other); reason != ErrorImmutable {
+		t.Errorf("deniedReason in an append-only directory = %v, want immutable", reason)
+	}
 }
 
 // =============================================================================
@@ -707,6 +780,20 @@ func TestSetAskSudo(t *testing.T) {
 	}
 }
 
+func TestSudoAllowed(t *testing.T) {
+	c := New(&config.Config{Sudo: config.SudoConfig{Never: []string{"screenshots"}}})
+
+	for category, want := range map[string]bool{
+		"cache":                   true,
+		"screenshots":             false,
+		scanner.EmptyDirsCategory: false,
+	} {
+		if got := c.sudoAllowed(category); got != want {
+			t.Errorf("sudoAllowed(%q) = %v, want %v", category, got, want)
+		}
+	}
+}
+
 func TestCleanDryRun(t *testing.T) {
 	f := testutil.NewFixture(t)
 
@@ -978,6 +1065,55 @@ func TestGetPermissionReport(t *testing.T) {
 	}
 }
 
+func TestCleanResultMerge(t *testing.T) {
+	base := &CleanResult{
+		DeletedFiles:  []string{"/a"},
+		DeletedSize:   10,
+		SkippedFiles:  []string{"/b", "/c"},
+		SkippedReason: map[string]string{"/b": "Requires elevated permissions", "/c": "File too new (safety check)"},
+		Errors: []*DeletionError{
+			{Path: "/d", Reason: ErrorFileInUse, Retryable: true},
+		},
+	}
+	retry := &CleanResult{
+		DeletedFiles:  []string{"/b", "/d"},
+		DeletedSize:   30,
+		SkippedReason: map[string]string{},
+		UsedSudo:      true,
+		SudoSucceeded: 2,
+	}
+
+	base.Merge(retry, []string{"/b", "/d"})
+
+	if len(base.DeletedFiles) != 3 || base.DeletedSize != 40 {
+		t.Errorf("deleted = %v (%d), want 3 files, 40 bytes", base.DeletedFiles, base.DeletedSize)
+	}
+	if len(base.SkippedFiles) != 1 || base.SkippedFiles[0] != "/c" {
+		t.Errorf("SkippedFiles = %v, want [/c]", base.SkippedFiles)
+	}
+	if _, ok := base.SkippedReason["/b"]; ok {
+		t.Error("retried path should be removed from SkippedReason")
+	}
+	if len(base.Errors) != 0 {
+		t.Errorf("Errors = %d, want 0", len(base.Errors))
+	}
+	if !base.UsedSudo || base.SudoSucceeded != 2 {
+		t.Error("sudo stats should be merged")
+	}
+}
+
+func TestEscalateFilesEmpty(t *testing.T) {
+	c := New(&config.Config{})
+
+	result, err := c.EscalateFiles(nil)
+	if err != nil {
+		t.Fatalf("EscalateFiles failed: %v", err)
+	}
+	if len(result.DeletedFiles) != 0 || result.UsedSudo {
+		t.Error("escalating no files should do nothing")
+	}
+}
+
 func TestCleanerGetManifest(t *testing.T) {
 	c := New(&config.Config{})
 	m := c.GetManifest()
@@ -1092,6 +1228,136 @@ func TestCleanerSaveManifest(t *testing.T) {
 	f.AssertFileExists(manifestPath)
 }
 
+func TestCleanNeverDeleteExtensions(t *testing.T) {
+	f := testutil.NewFixture(t)
+	keyFile := f.CreateFileWithAge("cache/server.PEM", []byte("key"), 48*time.Hour)
+	docFile := f.CreateFileWithAge("cache/notes.docx", []byte("doc"), 48*time.Hour)
+	cacheFile := f.CreateFileWithAge("cache/data.bin", []byte("cache"), 48*time.Hour)
+
+	c := New(&config.Config{NeverDeleteExtensions: []string{".pem", ".doc*"}})
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{
+		Files: []scanner.FileInfo{
+			{Path: keyFile, Size: 3, Category: "cache"},
+			{Path: docFile, Size: 3, Category: "cache"},
+			{Path: cacheFile, Size: 5, Category: "cache"},
+		},
+		TotalSize:  11,
+		TotalCount: 3,
+	})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if len(result.DeletedFiles) != 1 || result.DeletedFiles[0] != cacheFile {
+		t.Errorf("expected only %s deleted, got %v", cacheFile, result.DeletedFiles)
+	}
+	if !strings.Contains(result.SkippedReason[docFile], `".doc*"`) {
+		t.Errorf("unexpected skip reason %q", result.SkippedReason[docFile])
+	}
+	f.AssertFileExists(keyFile)
+	f.AssertFileExists(docFile)
+	f.AssertFileNotExists(cacheFile)
+}
+
+func TestCleanNeverDeleteInsideDirectory(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/old-project/build/out.o", 100, old)
+	mem.AddFile("/home/user/old-project/certs/deploy.key", 10, old)
+	mem.AddFile("/home/user/app/node_modules/lib/index.js", 20, old)
+
+	c := New(&config.Config{MinFileAge: 24, NeverDeleteExtensions: []string{".key"}})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/home/user/old-project", Size: 110, Category: "old_files"},
+		{Path: "/home/user/app/node_modules", Size: 20, Category: "node_modules"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if !mem.Exists("/home/user/old-project/certs/deploy.key") || !mem.Exists("/home/user/old-project/build/out.o") {
+		t.Error("a directory holding a key should be kept whole")
+	}
+	if reason := result.SkippedReason["/home/user/old-project"]; !strings.Contains(reason, "deploy.key") {
+		t.Errorf("expected the key named in the skip reason, got %q", reason)
+	}
+	if len(result.DeletedFiles) != 1 || mem.Exists("/home/user/app/node_modules/lib/index.js") {
+		t.Errorf("expected only node_modules deleted, got %v", result.DeletedFiles)
+	}
+}
+
+func TestCleanNeverDeleteIgnoresDevArtifactContents(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	cacert := "/home/user/app/.venv/lib/python3.12/site-packages/pip/_vendor/certifi/cacert.pem"
+	mem.AddFile(cacert, 300, old)
+	mem.AddFile("/home/user/web/node_modules/ssh2/test/fixtures/id_rsa.key", 10, old)
+
+	c := New(&config.Config{MinFileAge: 24, NeverDeleteExtensions: config.GetDefault().NeverDeleteExtensions})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/home/user/app/.venv", Size: 300, Category: "virtual_envs"},
+		{Path: "/home/user/web/node_modules", Size: 10, Category: "node_modules"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if len(result.DeletedFiles) != 2 || mem.Exists(cacert) {
+		t.Errorf("expected the venv and node_modules cleaned despite their .pem and .key files, deleted %v, skipped %v",
+			result.DeletedFiles, result.SkippedReason)
+	}
+}
+
+func TestCleanAuditLogChecksums(t *testing.T) {
+	f := testutil.NewFixture(t)
+	big := bytes.Repeat([]byte("x"), 2048)
+	bigFile := f.CreateFileWithAge("cache/big.bin", big, 48*time.Hour)
+	smallFile := f.CreateFileWithAge("cache/small.txt", []byte("small"), 48*time.Hour)
+	auditLog := filepath.Join(f.RootDir, "state", "audit.jsonl")
+
+	c := New(&config.Config{Clean: config.CleanConfig{AuditLog: auditLog, ChecksumMinSize: "1KB"}})
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{
+		Files: []scanner.FileInfo{
+			{Path: bigFile, Size: 2048, Category: "cache"},
+			{Path: smallFile, Size: 5, Category: "cache"},
+		},
+		TotalSize:  2053,
+		TotalCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.DeletedFiles) != 2 {
+		t.Fatalf("expected both files deleted, got %v (skipped %v)", result.DeletedFiles, result.SkippedReason)
+	}
+
+	data, err := os.ReadFile(auditLog)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	sums := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record auditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		if record.RunID != c.RunID() {
+			t.Errorf("expected run %s, got %s", c.RunID(), record.RunID)
+		}
+		sums[record.Path] = record.SHA256
+	}
+	want := sha256.Sum256(big)
+	if len(sums) != 2 || sums[bigFile] != hex.EncodeToString(want[:]) || sums[smallFile] != "" {
+		t.Errorf("unexpected audit checksums %v", sums)
+	}
+}
+
 // =============================================================================
 // Edge Cases and Security Tests
 // =============================================================================
@@ -1200,6 +1466,9 @@ func TestCleanWithReadOnlyDirectory(t *testing.T) {
 	if len(result.DeletedFiles) != 0 {
 		t.Error("should not be able to delete file in read-only directory")
 	}
+	if len(result.NeedsSudo) != 1 || result.NeedsSudo[0] != trappedFile {
+		t.Errorf("NeedsSudo = %v, want [%s]", result.NeedsSudo, trappedFile)
+	}
 }
 
 func TestCleanSpecialCharactersInPath(t *testing.T) {
@@ -1276,3 +1545,1813 @@ func TestCleanConcurrentSafety(t *testing.T) {
 		<-done
 	}
 }
+
+// =============================================================================
+// Quarantine Tests
+// =============================================================================
+
+func TestCleanWithQuarantineAndRestore(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	file := f.CreateFileWithAge("cache/old.txt", []byte("content"), 48*time.Hour)
+	f.CreateFile("project/node_modules/pkg/index.js", []byte("x"))
+	dir := f.CreateDirWithAge("project/node_modules", 48*time.Hour)
+
+	cfg := &config.Config{
+		MinFileAge: 24,
+		Quarantine: config.QuarantineConfig{Enabled: true, Dir: f.Path("quarantine")},
+	}
+	c := New(cfg)
+	c.SetAskSudo(false)
+
+	scanResult := &scanner.ScanResult{
+		Files: []scanner.FileInfo{
+			{Path: file, Size: 7, Category: "cache"},
+			{Path: dir, Size: 1, Category: "node_modules"},
+		},
+		TotalSize:  8,
+		TotalCount: 2,
+	}
+
+	result, err := c.Clean(scanResult)
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.DeletedFiles) != 2 {
+		t.Fatalf("DeletedFiles = %d, want 2", len(result.DeletedFiles))
+	}
+	f.AssertFileNotExists(file)
+	f.AssertFileNotExists(dir)
+
+	// The run must be discoverable from disk, as `tidyup undo` does it
+	run, err := NewQuarantine(f.Path("quarantine")).Latest()
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if len(run.Entries) != 2 || run.TotalSize() != 8 {
+		t.Errorf("run has %d entries (%d bytes), want 2 (8 bytes)", len(run.Entries), run.TotalSize())
+	}
+
+	restored, errs := run.Restore()
+	if len(errs) != 0 {
+		t.Fatalf("Restore errors: %v", errs)
+	}
+	if len(restored) != 2 {
+		t.Errorf("restored = %d, want 2", len(restored))
+	}
+	f.AssertFileExists(file)
+	f.AssertFileExists(filepath.Join(dir, "pkg", "index.js"))
+
+	if _, err := NewQuarantine(f.Path("quarantine")).Latest(); err == nil {
+		t.Error("expected nothing left to undo")
+	}
+}
+
+func TestQuarantinePreflight(t *testing.T) {
+	f := testutil.NewFixture(t)
+	q := NewQuarantine(f.Path("quarantine/not/created/yet"))
+
+	// Files renamed within the file system need no space
+	local := scanner.FileInfo{Path: f.CreateFile("local", []byte("x")), Size: 1 << 60}
+	if err := q.preflight([]scanner.FileInfo{local}); err != nil {
+		t.Errorf("expected room for a file on the same file system, got %v", err)
+	}
+
+	base, _ := platform.DeviceID(f.RootDir)
+	other := ""
+	for _, dir := range []string{"/dev/shm", os.TempDir()} {
+		if dev, err := platform.DeviceID(dir); err == nil && dev != base {
+			other = dir
+			break
+		}
+	}
+	if other == "" {
+		t.Skip("no second file system to copy from")
+	}
+	remote := scanner.FileInfo{Path: filepath.Join(other, "remote"), Size: 1 << 60}
+	err := q.preflight([]scanner.FileInfo{remote})
+	if err == nil || !strings.Contains(err.Error(), "free but needs") {
+		t.Errorf("expected a shortage of space, got %v", err)
+	}
+}
+
+func TestSkipReadOnly(t *testing.T) {
+	m := &spaceMeter{
+		devices:  map[string]uint64{"/ro/a": 1, "/ro/b": 1, "/rw/c": 2},
+		readOnly: map[uint64]string{1: "/ro"},
+	}
+	result := &CleanResult{SkippedReason: make(map[string]string)}
+	files := m.skipReadOnly([]scanner.FileInfo{{Path: "/ro/a"}, {Path: "/rw/c"}, {Path: "/ro/b"}, {Path: "/unmeasured"}}, result)
+
+	if len(files) != 2 || files[0].Path != "/rw/c" || files[1].Path != "/unmeasured" {
+		t.Errorf("kept %v", files)
+	}
+	if len(result.SkippedFiles) != 2 || len(result.Errors) != 0 {
+		t.Errorf("expected two skipped files and no errors, got %v, %v", result.SkippedFiles, result.Errors)
+	}
+	if !strings.HasPrefix(result.SkippedReason["/ro/a"], SkippedReadOnly) {
+		t.Errorf("unexpected reason %q", result.SkippedReason["/ro/a"])
+	}
+	if len(result.ReadOnly) != 1 || result.ReadOnly[0] != "/ro" {
+		t.Errorf("ReadOnly = %v", result.ReadOnly)
+	}
+}
+
+func TestCopyAndRemove(t *testing.T) {
+	f := testutil.NewFixture(t)
+	src := f.CreateDir("src")
+	f.CreateFile("src/a.txt", []byte("alpha"))
+	f.CreateFile("src/nested/b.txt", []byte("beta"))
+	f.CreateSymlink("a.txt", "src/link")
+	old := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	os.Chtimes(f.Path("src/a.txt"), old, old)
+	os.Chmod(f.Path("src/nested"), 0750)
+
+	dst := f.Path("dst")
+	if err := copyAndRemove(src, dst); err != nil {
+		t.Fatalf("copyAndRemove failed: %v", err)
+	}
+
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("expected the source to be removed, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "nested", "b.txt")); string(data) != "beta" {
+		t.Errorf("nested file = %q", data)
+	}
+	if target, _ := os.Readlink(filepath.Join(dst, "link")); target != "a.txt" {
+		t.Errorf("symlink target = %q", target)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "a.txt")); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("expected the modification time to be kept, got %v", info.ModTime())
+	}
+	if info, err := os.Stat(filepath.Join(dst, "nested")); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("expected the directory mode to be kept, got %v", info.Mode())
+	}
+}
+
+func TestCopyAndRemoveFailureLeavesNothing(t *testing.T) {
+	f := testutil.NewFixture(t)
+	src := f.CreateDir("src")
+	f.CreateFile("src/a.txt", []byte("alpha"))
+	if err := syscall.Mkfifo(f.Path("src/pipe"), 0600); err != nil {
+		t.Skipf("can't create a FIFO: %v", err)
+	}
+
+	dst := f.Path("dst")
+	if err := copyAndRemove(src, dst); err == nil {
+		t.Fatal("expected copying a FIFO to fail")
+	}
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected the partial copy to be removed, got %v", err)
+	}
+	if _, err := os.Stat(f.Path("src/a.txt")); err != nil {
+		t.Errorf("expected the source to be untouched: %v", err)
+	}
+}
+
+func TestQuarantineAcrossFilesystems(t *testing.T) {
+	f := testutil.NewFixture(t)
+	base, _ := platform.DeviceID(f.RootDir)
+	other := ""
+	for _, dir := range []string{"/dev/shm", os.TempDir()} {
+		if dev, err := platform.DeviceID(dir); err == nil && dev != base {
+			other = dir
+			break
+		}
+	}
+	if other == "" {
+		t.Skip("no second file system to quarantine to")
+	}
+	qdir, err := os.MkdirTemp(other, "tidyup-quarantine-")
+	if err != nil {
+		t.Skipf("can't write to %s: %v", other, err)
+	}
+	t.Cleanup(func() { os.RemoveAll(qdir) })
+
+	file := f.CreateFileWithAge("cache/old.txt", []byte("content"), 48*time.Hour)
+	c := New(&config.Config{MinFileAge: 24, Quarantine: config.QuarantineConfig{Enabled: true, Dir: qdir}})
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{{Path: file, Size: 7, Category: "cache"}}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.DeletedFiles) != 1 || len(result.Copied) != 1 || result.Copied[0] != file {
+		t.Fatalf("expected the file to be copied, got deleted %v, copied %v, errors %v", result.DeletedFiles, result.Copied, result.Errors)
+	}
+	if entries := c.QuarantineRun().Entries; len(entries) != 1 || !entries[0].Copied {
+		t.Errorf("expected the entry to record the copy, got %+v", entries)
+	}
+
+	restored, errs := c.QuarantineRun().Restore()
+	if len(restored) != 1 || len(errs) != 0 {
+		t.Fatalf("restore across file systems: restored %v, errors %v", restored, errs)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "content" {
+		t.Errorf("restored content = %q", data)
+	}
+}
+
+func TestCleanRecordsRunID(t *testing.T) {
+	f := testutil.NewFixture(t)
+	file := f.CreateFileWithAge("old.txt", []byte("content"), 48*time.Hour)
+
+	cfg := &config.Config{
+		MinFileAge: 24,
+		Quarantine: config.QuarantineConfig{Enabled: true, Dir: f.Path("quarantine")},
+	}
+	c := New(cfg)
+	if c.RunID() == "" || c.RunID() == New(cfg).RunID() {
+		t.Error("each cleaner should get its own run ID")
+	}
+	c.SetRunID("7f3a9c12")
+	c.SetAskSudo(false)
+
+	updates := c.GetProgressReporter().Subscribe()
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{{Path: file, Size: 7, Category: "cache"}}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if result.RunID != "7f3a9c12" || c.GetManifest().RunID != "7f3a9c12" {
+		t.Errorf("run ID missing from result %q or manifest %q", result.RunID, c.GetManifest().RunID)
+	}
+	if update, ok := (<-updates).(*progress.CleanProgress); !ok || update.RunID != "7f3a9c12" {
+		t.Errorf("progress events should carry the run ID, got %+v", update)
+	}
+	run, err := NewQuarantine(f.Path("quarantine")).Latest()
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if run.RunID != "7f3a9c12" {
+		t.Errorf("quarantine run should record the run ID, got %q", run.RunID)
+	}
+
+	manifestPath := f.Path("manifest.txt")
+	c.SaveManifest(manifestPath)
+	if content, _ := os.ReadFile(manifestPath); !strings.Contains(string(content), "Run: 7f3a9c12") {
+		t.Errorf("manifest should name the run:\n%s", content)
+	}
+}
+
+func TestQuarantineRestoreConflict(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	file := f.CreateFile("data.txt", []byte("original"))
+
+	run, err := NewQuarantine(f.Path("quarantine")).Begin("run-1")
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if _, err := run.Move(scanner.FileInfo{Path: file, Size: 8}); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	// Something new took the original path
+	f.CreateFile("data.txt", []byte("new"))
+
+	restored, errs := run.Restore()
+	if len(restored) != 0 || len(errs) != 1 {
+		t.Errorf("restored=%d errs=%d, want 0 and 1", len(restored), len(errs))
+	}
+	if len(run.Entries) != 1 {
+		t.Error("conflicting entry should stay in the quarantine")
+	}
+
+	content, _ := os.ReadFile(file)
+	if string(content) != "new" {
+		t.Error("restore must not overwrite existing files")
+	}
+}
+
+func TestQuarantineRunsInSameSecond(t *testing.T) {
+	f := testutil.NewFixture(t)
+	q := NewQuarantine(f.Path("quarantine"))
+
+	// Two cleanups of one run, e.g. project --archive then --delete-original,
+	// each quarantining a file with the same name, at the start of a second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	var runs []*QuarantineRun
+	for i, content := range []string{"first", "second"} {
+		file := f.CreateFile(fmt.Sprintf("dir%d/notes.txt", i), []byte(content))
+		run, err := q.Begin("run-1")
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if _, err := run.Move(scanner.FileInfo{Path: file, Size: int64(len(content))}); err != nil {
+			t.Fatalf("Move failed: %v", err)
+		}
+		if err := run.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		runs = append(runs, run)
+	}
+	if runs[1].ID != runs[0].ID+"-2" {
+		t.Fatalf("expected the second run in %s-2, got %s", runs[0].ID, runs[1].ID)
+	}
+	if runs[1].RunID != "run-1" {
+		t.Errorf("expected the run ID recorded, got %q", runs[1].RunID)
+	}
+
+	loaded, err := q.Runs()
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("expected both runs listed, got %d: %v", len(loaded), err)
+	}
+	for _, run := range runs {
+		restored, errs := run.Restore()
+		if len(restored) != 1 || len(errs) != 0 {
+			t.Errorf("run %s: restored %v, errors %v", run.ID, restored, errs)
+		}
+	}
+	for i, want := range []string{"first", "second"} {
+		if content, _ := os.ReadFile(f.Path(fmt.Sprintf("dir%d/notes.txt", i))); string(content) != want {
+			t.Errorf("expected dir%d/notes.txt restored as %q, got %q", i, want, content)
+		}
+	}
+}
+
+func TestSudoRestrictedToAllowedCategories(t *testing.T) {
+	cfg := &config.Config{
+		Sudo: config.SudoConfig{AllowCategories: []string{"logs"}, Never: []string{"large_files"}},
+	}
+	c := New(cfg)
+
+	files := map[string]scanner.FileInfo{
+		"/var/log/app.log":     {Path: "/var/log/app.log", Category: "logs"},
+		"/var/cache/x":         {Path: "/var/cache/x", Category: "cache"},
+		"/home/user/movie.mkv": {Path: "/home/user/movie.mkv", Category: "large_files"},
+	}
+	result := &CleanResult{SkippedReason: make(map[string]string)}
+
+	allowed := c.filterSudoAllowed([]string{"/var/log/app.log", "/var/cache/x", "/home/user/movie.mkv"}, files, result)
+	if len(allowed) != 1 || allowed[0] != "/var/log/app.log" {
+		t.Errorf("expected only logs to be allowed sudo, got %v", allowed)
+	}
+	if len(result.SkippedFiles) != 2 || !strings.Contains(result.SkippedReason["/var/cache/x"], "not allowed") {
+		t.Errorf("expected disallowed files to be skipped with a reason, got %v", result.SkippedReason)
+	}
+
+	// Escalating only disallowed files never asks for a password
+	escalated, err := c.EscalateFiles([]scanner.FileInfo{files["/home/user/movie.mkv"]})
+	if err != nil {
+		t.Fatalf("EscalateFiles failed: %v", err)
+	}
+	if escalated.UsedSudo || len(escalated.SkippedFiles) != 1 {
+		t.Errorf("expected disallowed escalation to be skipped, got %+v", escalated)
+	}
+}
+
+func TestSudoCommandTokenMode(t *testing.T) {
+	sm := NewSudoManager()
+	ctx := context.Background()
+
+	// Token mode runs non-interactively and never feeds a password
+	sm.tokenMode = true
+	cmd := sm.sudoCommand(ctx, "rm", "-f", "--", "/tmp/x")
+	if got := strings.Join(cmd.Args, " "); got != "sudo -n rm -f -- /tmp/x" {
+		t.Errorf("token mode args = %q", got)
+	}
+	if cmd.Stdin != nil {
+		t.Error("token mode should not pass a password on stdin")
+	}
+
+	// Password fallback (timestamp_timeout=0) feeds the password on stdin
+	sm.tokenMode = false
+	sm.password = []byte("secret")
+	cmd = sm.sudoCommand(ctx, "-v")
+	if got := strings.Join(cmd.Args, " "); got != "sudo -S -v" {
+		t.Errorf("password mode args = %q", got)
+	}
+	if cmd.Stdin == nil {
+		t.Error("password mode should pass the password on stdin")
+	}
+
+	sm.Clear()
+	if sm.TokenMode() || sm.password != nil {
+		t.Error("Clear should reset token mode and drop the password")
+	}
+}
+
+// =============================================================================
+// Native Tree Removal Tests
+// =============================================================================
+
+func TestRemoveTree(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	// A node_modules-like tree: many small files across nested packages
+	for pkg := 0; pkg < 20; pkg++ {
+		for file := 0; file < 30; file++ {
+			f.CreateFile(fmt.Sprintf("node_modules/pkg%d/lib/sub/file%d.js", pkg, file), []byte("x"))
+		}
+	}
+	outside := f.CreateFile("outside/keep.txt", []byte("keep"))
+	if err := os.Symlink(filepath.Dir(outside), f.Path("node_modules/link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			if err := removeTree(f.Path("node_modules"), workers); err != nil {
+				t.Fatalf("removeTree failed: %v", err)
+			}
+			if _, err := os.Lstat(f.Path("node_modules")); !os.IsNotExist(err) {
+				t.Error("tree should be removed")
+			}
+			if _, err := os.Stat(outside); err != nil {
+				t.Error("symlink target outside the tree should not be touched")
+			}
+		})
+	}
+
+	// A missing path is not an error, like os.RemoveAll
+	if err := removeTree(f.Path("missing"), 2); err != nil {
+		t.Errorf("removeTree on missing path: %v", err)
+	}
+}
+
+// =============================================================================
+// Busy File Retry Queue Tests
+// =============================================================================
+
+func TestRetryQueueProcess(t *testing.T) {
+	busyErr := func(path string) *DeletionError {
+		return CategorizeError(path, &os.PathError{Op: "remove", Path: path, Err: syscall.EBUSY})
+	}
+
+	var delays []time.Duration
+	var waited [][]string
+	q := &retryQueue{
+		errs:     make(map[string]*DeletionError),
+		attempts: 4,
+		backoff:  100 * time.Millisecond,
+		sleep:    func(d time.Duration) { delays = append(delays, d) },
+		wait:     func(paths []string) { waited = append(waited, paths) },
+	}
+	q.add(scanner.FileInfo{Path: "/tmp/unlocks"}, busyErr("/tmp/unlocks"))
+	q.add(scanner.FileInfo{Path: "/tmp/locked"}, busyErr("/tmp/locked"))
+	q.add(scanner.FileInfo{Path: "/tmp/denied"}, busyErr("/tmp/denied"))
+
+	calls := map[string]int{}
+	failed := q.process(func(file scanner.FileInfo) *DeletionError {
+		calls[file.Path]++
+		switch file.Path {
+		case "/tmp/unlocks":
+			if calls[file.Path] < 2 {
+				return busyErr(file.Path)
+			}
+			return nil
+		case "/tmp/denied":
+			return CategorizeError(file.Path, &os.PathError{Op: "remove", Path: file.Path, Err: syscall.EACCES})
+		}
+		return busyErr(file.Path)
+	})
+
+	if calls["/tmp/unlocks"] != 2 || calls["/tmp/denied"] != 1 || calls["/tmp/locked"] != 4 {
+		t.Errorf("unexpected attempts: %v", calls)
+	}
+	wantDelays := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}
+	if fmt.Sprint(delays) != fmt.Sprint(wantDelays) {
+		t.Errorf("delays = %v, want %v", delays, wantDelays)
+	}
+	if len(waited) != 4 || len(waited[0]) != 3 || len(waited[3]) != 1 {
+		t.Errorf("should wait for holders of the remaining files each round, got %v", waited)
+	}
+
+	if len(failed) != 2 {
+		t.Fatalf("expected 2 failures, got %d", len(failed))
+	}
+	reasons := map[string]ErrorReason{}
+	for _, err := range failed {
+		reasons[err.Path] = err.Reason
+	}
+	if reasons["/tmp/denied"] != ErrorPermissionDenied || reasons["/tmp/locked"] != ErrorFileInUse {
+		t.Errorf("unexpected failures: %v", reasons)
+	}
+}
+
+func TestRetryQueueNoAttempts(t *testing.T) {
+	q := &retryQueue{errs: make(map[string]*DeletionError), sleep: func(time.Duration) {}}
+	err := CategorizeError("/tmp/x", syscall.ETXTBSY)
+	q.add(scanner.FileInfo{Path: "/tmp/x"}, err)
+
+	failed := q.process(func(scanner.FileInfo) *DeletionError {
+		t.Error("should not retry with zero attempts")
+		return nil
+	})
+	if len(failed) != 1 || failed[0] != err {
+		t.Errorf("expected the original error back, got %v", failed)
+	}
+}
+
+func TestCleanResultUnskip(t *testing.T) {
+	result := &CleanResult{
+		SkippedFiles:  []string{"/a", "/b"},
+		SkippedReason: map[string]string{"/a": "busy", "/b": "busy"},
+	}
+	result.unskip("/a")
+	if len(result.SkippedFiles) != 1 || result.SkippedFiles[0] != "/b" {
+		t.Errorf("SkippedFiles = %v", result.SkippedFiles)
+	}
+	if _, ok := result.SkippedReason["/a"]; ok {
+		t.Error("reason should be dropped")
+	}
+}
+
+func TestParsePIDs(t *testing.T) {
+	got := parsePIDs("123\n456\n123\n\nnot-a-pid\n0\n")
+	if fmt.Sprint(got) != "[123 456]" {
+		t.Errorf("parsePIDs = %v", got)
+	}
+}
+
+// =============================================================================
+// Deletion Script Tests
+// =============================================================================
+
+func TestWriteScript(t *testing.T) {
+	f := testutil.NewFixture(t)
+	plain := f.CreateFile("cache/plain.cache", []byte("data"))
+	quoted := f.CreateFile("cache/it's $HOME `x`.log", []byte("data"))
+	dir := f.Path("proj/node_modules")
+	f.CreateFile("proj/node_modules/pkg/index.js", []byte("data"))
+
+	result := &scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: plain, Size: 4, Category: "cache"},
+		{Path: quoted, Size: 4, Category: "logs"},
+		{Path: dir, Size: 4, Category: "node_modules"},
+		{Path: f.Path("cache/missing"), Size: 1, Category: "cache"},
+	}}
+
+	c := New(config.GetDefault())
+	var buf strings.Builder
+	if err := c.WriteScript(&buf, result); err != nil {
+		t.Fatalf("WriteScript failed: %v", err)
+	}
+	script := buf.String()
+
+	if !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Error("script should start with a shebang")
+	}
+	if !strings.Contains(script, "remove "+shellQuote(quoted)) {
+		t.Errorf("expected quoted remove command for %q in:\n%s", quoted, script)
+	}
+	if strings.Contains(script, "remove "+shellQuote(f.Path("cache/missing"))) {
+		t.Error("files that no longer exist should not get a command")
+	}
+	if strings.Index(script, "\n# cache\n") > strings.Index(script, "\n# logs\n") {
+		t.Error("categories should be sorted")
+	}
+
+	// Running the script deletes exactly the listed files
+	path := f.Path("clean.sh")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("sh", path).CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	for _, p := range []string{plain, quoted, dir} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted by the script", p)
+		}
+	}
+}
+
+func TestWriteScriptNewlineNames(t *testing.T) {
+	f := testutil.NewFixture(t)
+	evil := f.CreateFile("cache/x\ntouch injected\n.cache", []byte("data"))
+	kept := f.CreateFile("cache/id\ntouch injected\n.pem", []byte("key"))
+
+	cfg := config.GetDefault()
+	cfg.NeverDeleteExtensions = []string{".pem"}
+	var buf strings.Builder
+	err := New(cfg).WriteScript(&buf, &scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: evil, Size: 4, Category: "cache"},
+		{Path: kept, Size: 3, Category: "cache"},
+	}})
+	if err != nil {
+		t.Fatalf("WriteScript failed: %v", err)
+	}
+
+	// Only the listed file is deleted, and nothing else runs
+	dir := t.TempDir()
+	cmd := exec.Command("sh", "-c", buf.String())
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "injected")); err == nil {
+		t.Errorf("a file name injected a command into:\n%s", buf.String())
+	}
+	f.AssertFileNotExists(evil)
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("skipped file should be kept: %v", err)
+	}
+}
+
+func TestWriteScriptProtectedLocations(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses Linux's protected locations")
+	}
+	f := testutil.NewFixture(t)
+	plain := f.CreateFile("cache/plain.cache", []byte("data"))
+
+	var buf strings.Builder
+	err := New(config.GetDefault()).WriteScript(&buf, &scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: plain, Size: 4, Category: "cache"},
+		{Path: "/etc/passwd", Size: 1, Category: "logs"},
+		{Path: "/usr/bin", Size: 1, Category: "large_files"},
+	}})
+	if err != nil {
+		t.Fatalf("WriteScript failed: %v", err)
+	}
+	script := buf.String()
+
+	if !strings.Contains(script, "remove "+shellQuote(plain)) {
+		t.Errorf("expected a remove command for %s in:\n%s", plain, script)
+	}
+	for _, path := range []string{"/etc/passwd", "/usr/bin"} {
+		if strings.Contains(script, "rm -rf -- "+shellQuote(path)) || strings.Contains(script, "remove "+shellQuote(path)) {
+			t.Errorf("protected %s should get no command in:\n%s", path, script)
+		}
+		if !strings.Contains(script, "# "+strconv.Quote(path)+": \"safety check failed: ") {
+			t.Errorf("expected %s listed as skipped with the reason in:\n%s", path, script)
+		}
+	}
+}
+
+func TestWriteSkipped(t *testing.T) {
+	var buf strings.Builder
+	writeSkipped(&buf, map[string]string{
+		"/cache/x\nrm -rf ~": "inaccessible: lstat /cache/x\nrm -rf ~: input/output error",
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			t.Errorf("expected only comments, got %q", line)
+		}
+	}
+	if len(lines) != 2 {
+		t.Errorf("expected a heading and one line, got %q", lines)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"/tmp/a b":  `'/tmp/a b'`,
+		"/tmp/it's": `'/tmp/it'\''s'`,
+		"$(rm -rf)": `'$(rm -rf)'`,
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// =============================================================================
+// Clean Order and Journal Tests
+// =============================================================================
+
+func TestOrderByCategory(t *testing.T) {
+	files := []scanner.FileInfo{
+		{Path: "/a", Category: "large_files"},
+		{Path: "/b", Category: "cache"},
+		{Path: "/c", Category: "unlisted"},
+		{Path: "/d", Category: "temp"},
+		{Path: "/e", Category: "cache"},
+	}
+	ordered := orderByCategory(files, []string{"temp", "cache", "large_files"})
+
+	var got []string
+	for _, file := range ordered {
+		got = append(got, file.Path)
+	}
+	if strings.Join(got, " ") != "/d /b /e /a /c" {
+		t.Errorf("order = %v", got)
+	}
+	if files[0].Path != "/a" {
+		t.Error("input should not be modified")
+	}
+}
+
+func TestJournalPending(t *testing.T) {
+	f := testutil.NewFixture(t)
+	j := NewJournal(f.Path("state/journal.json"))
+
+	if _, err := j.Pending(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist without a journal, got %v", err)
+	}
+
+	files := []scanner.FileInfo{
+		{Path: "/one", Size: 1, Category: "temp"},
+		{Path: "/two", Size: 2, Category: "cache"},
+		{Path: "/three", Size: 3, Category: "cache"},
+	}
+	if err := j.Begin("run-1", files); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	j.Done("/two")
+	j.Close()
+
+	// A torn write from an interruption is ignored
+	fh, err := os.OpenFile(j.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh.WriteString(`{"done":"/thr`)
+	fh.Close()
+
+	resumed := NewJournal(j.Path())
+	pending, err := resumed.Pending()
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if resumed.RunID() != "run-1" {
+		t.Errorf("expected the interrupted run's ID, got %q", resumed.RunID())
+	}
+	if pending.TotalCount != 2 || pending.TotalSize != 4 {
+		t.Errorf("pending = %d files, %d bytes, want 2 files, 4 bytes", pending.TotalCount, pending.TotalSize)
+	}
+	if pending.Files[0].Path != "/one" || pending.Files[1].Path != "/three" || pending.Files[1].Category != "cache" {
+		t.Errorf("unexpected pending files: %+v", pending.Files)
+	}
+
+	if err := j.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if _, err := os.Stat(j.Path()); !os.IsNotExist(err) {
+		t.Error("Finish should remove the journal")
+	}
+}
+
+func TestCleanJournalsInOrder(t *testing.T) {
+	f := testutil.NewFixture(t)
+	age := 48 * time.Hour
+	large := f.CreateFileWithAge("big.iso", []byte("large"), age)
+	cache := f.CreateFileWithAge("cache/a.cache", []byte("cache"), age)
+	temp := f.CreateFileWithAge("tmp/a.tmp", []byte("temp"), age)
+
+	cfg := &config.Config{MinFileAge: 24, Clean: config.CleanConfig{Order: []string{"temp", "cache", "large_files"}}}
+	c := New(cfg)
+	c.SetAskSudo(false)
+	journal := NewJournal(f.Path("journal.json"))
+	c.SetJournal(journal)
+
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: large, Size: 5, Category: "large_files"},
+		{Path: cache, Size: 5, Category: "cache"},
+		{Path: temp, Size: 4, Category: "temp"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if strings.Join(result.DeletedFiles, " ") != strings.Join([]string{temp, cache, large}, " ") {
+		t.Errorf("files should be deleted in category order, got %v", result.DeletedFiles)
+	}
+	if _, err := os.Stat(journal.Path()); !os.IsNotExist(err) {
+		t.Error("journal should be removed after a completed clean")
+	}
+}
+
+func TestCleanStopsWhenCancelled(t *testing.T) {
+	f := testutil.NewFixture(t)
+	age := 48 * time.Hour
+	cache := f.CreateFileWithAge("cache/a.cache", []byte("cache"), age)
+	temp := f.CreateFileWithAge("tmp/a.tmp", []byte("temp"), age)
+
+	c := New(&config.Config{MinFileAge: 24})
+	c.SetAskSudo(false)
+	journal := NewJournal(f.Path("journal.json"))
+	c.SetJournal(journal)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.SetContext(ctx)
+
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: cache, Size: 5, Category: "cache"},
+		{Path: temp, Size: 4, Category: "temp"},
+	}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Clean error = %v, want context.Canceled", err)
+	}
+	if result == nil || len(result.DeletedFiles) != 0 {
+		t.Fatalf("a cancelled clean shouldn't delete anything, got %+v", result)
+	}
+	f.AssertFileExists(cache)
+	f.AssertFileExists(temp)
+
+	// The journal is kept so the cleanup can be resumed
+	pending, err := journal.Pending()
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if pending.TotalCount != 2 {
+		t.Errorf("pending files = %d, want 2", pending.TotalCount)
+	}
+}
+
+// =============================================================================
+// Reclaimed Space Tests
+// =============================================================================
+
+func TestFilesystemReclaimExplain(t *testing.T) {
+	const mb = 1 << 20
+	tests := []struct {
+		name        string
+		deleted     int64
+		freed       uint64
+		quarantined bool
+		want        string
+	}{
+		{"matches", 100 * mb, 100 * mb, false, ""},
+		{"small difference", 100 * mb, 90 * mb, false, ""},
+		{"below the minimum", 512 * 1024, 0, false, ""},
+		{"hard links", 100 * mb, 10 * mb, false, NoteLessFreed},
+		{"quarantined", 100 * mb, 0, true, NoteQuarantined},
+		{"other activity", 10 * mb, 50 * mb, false, NoteMoreFreed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := FilesystemReclaim{DeletedSize: tt.deleted, FreeBefore: 1000 * mb, FreeAfter: 1000*mb + tt.freed, quarantined: tt.quarantined}
+			fs.explain()
+			if fs.Note != tt.want {
+				t.Errorf("Note = %q, want %q", fs.Note, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanResultMergeFilesystems(t *testing.T) {
+	const mb = 1 << 20
+	r := &CleanResult{SkippedReason: map[string]string{}, Filesystems: []FilesystemReclaim{
+		{Device: 1, Path: "/a", DeletedSize: 10 * mb, FreeBefore: 100 * mb, FreeAfter: 101 * mb, Note: NoteLessFreed},
+	}}
+	r.Merge(&CleanResult{SkippedReason: map[string]string{}, Filesystems: []FilesystemReclaim{
+		{Device: 1, Path: "/a", DeletedSize: 0, FreeBefore: 101 * mb, FreeAfter: 110 * mb},
+		{Device: 2, Path: "/b", DeletedSize: 2 * mb, FreeBefore: 50 * mb, FreeAfter: 52 * mb},
+	}}, nil)
+
+	if len(r.Filesystems) != 2 {
+		t.Fatalf("expected 2 file systems, got %d", len(r.Filesystems))
+	}
+	a := r.Filesystems[0]
+	if a.FreeBefore != 100*mb || a.FreeAfter != 110*mb || a.Note != "" {
+		t.Errorf("retry should extend the first measurement, got %+v", a)
+	}
+	if got := r.ReclaimedSize(); got != 12*mb {
+		t.Errorf("ReclaimedSize() = %d, want %d", got, 12*mb)
+	}
+}
+
+func TestCleanResultReclaimedFiles(t *testing.T) {
+	r := &CleanResult{Filesystems: []FilesystemReclaim{
+		{Path: "/a", Files: 1000, FreeFilesBefore: 10, FreeFilesAfter: 510},
+		{Path: "/b", Files: 1000, FreeFilesBefore: 200, FreeFilesAfter: 190},
+		// File systems without a fixed number of inodes don't count
+		{Path: "/c", FreeFilesBefore: 0, FreeFilesAfter: 7},
+	}}
+	if got := r.ReclaimedFiles(); got != 490 {
+		t.Errorf("ReclaimedFiles() = %d, want 490", got)
+	}
+}
+
+func TestCleanMeasuresFreeSpace(t *testing.T) {
+	f := testutil.NewFixture(t)
+	a := f.CreateFileWithAge("a.tmp", []byte("aaaa"), 48*time.Hour)
+	b := f.CreateFileWithAge("sub/b.tmp", []byte("bb"), 48*time.Hour)
+
+	c := New(&config.Config{MinFileAge: 24})
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: a, Size: 4, Category: "temp"},
+		{Path: b, Size: 2, Category: "temp"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if len(result.Filesystems) != 1 {
+		t.Fatalf("expected one file system, got %+v", result.Filesystems)
+	}
+	if fs := result.Filesystems[0]; fs.DeletedSize != 6 || fs.FreeBefore == 0 {
+		t.Errorf("unexpected measurement %+v", fs)
+	}
+
+	dry := New(&config.Config{DryRun: true})
+	result, err = dry.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{{Path: a, Size: 4}}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.Filesystems) != 0 || result.ReclaimedSize() != 0 {
+		t.Error("a dry run should measure nothing")
+	}
+}
+
+// =============================================================================
+// Running Application Tests
+// =============================================================================
+
+func TestAppOwning(t *testing.T) {
+	tests := []struct {
+		path string
+		app  string
+	}{
+		{"/home/user/.cache/google-chrome/Default/Cache/data_1", "Google Chrome"},
+		{"/home/user/.mozilla/firefox/abc.default/cache2", "Firefox"},
+		{"/home/user/Library/Application Support/Code/CachedData", "Visual Studio Code"},
+		{"/home/user/.config/Slack/Cache", "Slack"},
+		{"/home/user/.cache/JetBrains/GoLand2026.2/caches", "JetBrains IDE"},
+		{"/home/user/.cache/google-chrome-beta/x", ""},
+		{"/home/user/.cache/pip/wheels", ""},
+		{"/other/.cache/google-chrome/x", ""},
+	}
+	for _, tt := range tests {
+		app, ok := appOwning("/home/user", tt.path)
+		if ok != (tt.app != "") || app.name != tt.app {
+			t.Errorf("appOwning(%s) = %q, %v; want %q", tt.path, app.name, ok, tt.app)
+		}
+	}
+	if _, ok := appOwning("", "/.cache/google-chrome/x"); ok {
+		t.Error("expected no owner without a home directory")
+	}
+}
+
+func TestParseProcessNames(t *testing.T) {
+	running := parseProcessNames("  launchd\n/Applications/Google Chrome.app/Contents/MacOS/Google Chrome\nchromium-browse\n\n")
+	for _, name := range []string{"launchd", "google chrome", "chromium-browse"} {
+		if !running[name] {
+			t.Errorf("expected %q in %v", name, running)
+		}
+	}
+
+	chrome, _ := appOwning("/h", "/h/.cache/google-chrome")
+	chromium, _ := appOwning("/h", "/h/.config/chromium")
+	slack, _ := appOwning("/h", "/h/.config/Slack")
+	if !chrome.runningIn(running) {
+		t.Error("expected Chrome to be running")
+	}
+	// Linux cuts process names off at 15 characters
+	if !chromium.runningIn(running) {
+		t.Error("expected a truncated process name to match")
+	}
+	if slack.runningIn(running) {
+		t.Error("expected Slack not to be running")
+	}
+}
+
+func TestCleanSkipsRunningApps(t *testing.T) {
+	f := testutil.NewFixture(t)
+	t.Setenv("HOME", f.RootDir)
+	cache := f.CreateFileWithAge(".cache/google-chrome/Default/Cache/data_1", []byte("chrome"), 48*time.Hour)
+	other := f.CreateFileWithAge(".cache/other/data", []byte("other"), 48*time.Hour)
+
+	cfg := &config.Config{MinFileAge: 24, Clean: config.CleanConfig{SkipRunningApps: true}}
+	c := New(cfg)
+	c.SetAskSudo(false)
+	listed := 0
+	running := map[string]bool{"chrome": true}
+	c.processes = func() map[string]bool {
+		listed++
+		return running
+	}
+	files := &scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: cache, Size: 6, Category: "cache"},
+		{Path: other, Size: 5, Category: "cache"},
+	}}
+
+	result, err := c.Clean(files)
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.DeletedFiles) != 1 || result.DeletedFiles[0] != other {
+		t.Errorf("expected only the other cache to go, got %v", result.DeletedFiles)
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Errorf("expected Chrome's cache to be kept: %v", err)
+	}
+	if !strings.Contains(result.SkippedReason[cache], "Google Chrome is running") {
+		t.Errorf("unexpected reason %q", result.SkippedReason[cache])
+	}
+	if apps := result.RunningApps(); len(apps) != 1 || apps[0] != "Google Chrome" {
+		t.Errorf("RunningApps() = %v", apps)
+	}
+	if len(result.Errors) != 1 || !result.Errors[0].Retryable {
+		t.Errorf("expected one retryable error, got %v", result.Errors)
+	}
+	if listed != 1 {
+		t.Errorf("processes listed %d times, want once", listed)
+	}
+
+	// Retrying once the app has quit cleans its cache
+	delete(running, "chrome")
+	paths := result.RunningAppFiles()
+	retry, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{{Path: cache, Size: 6, Category: "cache"}}})
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	result.Merge(retry, paths)
+	if len(result.DeletedFiles) != 2 || len(result.Errors) != 0 || len(result.RunningApps()) != 0 {
+		t.Errorf("expected the retry to clean Chrome's cache, got %+v", result)
+	}
+
+	// Turning the check off doesn't list processes at all
+	cfg.Clean.SkipRunningApps = false
+	listed = 0
+	c.Clean(&scanner.ScanResult{})
+	if listed != 0 {
+		t.Error("expected no process listing with skip_running_apps off")
+	}
+}
+
+func TestBuildTool(t *testing.T) {
+	tests := []struct {
+		args []string
+		tool string
+	}{
+		{[]string{"npm", "install"}, "npm"},
+		{[]string{"node", "/usr/lib/node_modules/npm/bin/npm-cli.js", "run", "build"}, "npm"},
+		{[]string{"/usr/bin/python3", "-m", "pip", "install", "-r", "requirements.txt"}, "pip"},
+		{[]string{"/home/user/.cargo/bin/cargo", "build", "--release"}, "cargo"},
+		{[]string{"bash", "./gradlew", "assemble"}, "gradlew"},
+		{[]string{"java", "-Xmx64m", "-classpath", "gradle-wrapper.jar", "org.gradle.wrapper.GradleWrapperMain", "build"}, "gradle"},
+		{[]string{"/usr/bin/xcodebuild", "-scheme", "App"}, "xcodebuild"},
+		{[]string{"vim", "go"}, ""},
+		{[]string{"node", "server.js"}, ""},
+		{[]string{"java", "-jar", "app.jar"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := buildTool(tt.args); got != tt.tool {
+			t.Errorf("buildTool(%q) = %q, want %q", tt.args, got, tt.tool)
+		}
+	}
+}
+
+func TestParseLsofCwds(t *testing.T) {
+	builds := parseLsofCwds("p12\nfcwd\nn/home/user/app\np34\nfcwd\nn/tmp\n", map[string]string{"12": "npm"})
+	if len(builds) != 1 || builds[0] != (runningBuild{tool: "npm", dir: "/home/user/app"}) {
+		t.Errorf("parseLsofCwds = %v", builds)
+	}
+}
+
+func TestCleanSkipsRunningBuilds(t *testing.T) {
+	f := testutil.NewFixture(t)
+	modules := f.CreateFileWithAge("app/node_modules/lodash/index.js", []byte("lodash"), 48*time.Hour)
+	target := f.CreateFileWithAge("tool/target/debug/tool", []byte("tool"), 48*time.Hour)
+	idle := f.CreateFileWithAge("idle/build/out.o", []byte("out"), 48*time.Hour)
+	f.CreateDirWithAge("idle/build", 48*time.Hour)
+	log := f.CreateFileWithAge("app/debug.log", []byte("log"), 48*time.Hour)
+
+	cfg := &config.Config{MinFileAge: 24, Clean: config.CleanConfig{SkipRunningBuilds: true}}
+	c := New(cfg)
+	c.SetAskSudo(false)
+	listed := 0
+	c.builds = func() []runningBuild {
+		listed++
+		return []runningBuild{
+			{tool: "npm", dir: f.Path("app")},
+			{tool: "cargo", dir: f.Path("tool/target/debug")},
+			{tool: "go", dir: "/"},
+		}
+	}
+	files := &scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: f.Path("app/node_modules"), Size: 6, Category: "node_modules"},
+		{Path: f.Path("tool/target"), Size: 4, Category: "build_artifacts"},
+		{Path: f.Path("idle/build"), Size: 3, Category: "build_artifacts"},
+		{Path: log, Size: 3, Category: "logs"},
+	}}
+
+	result, err := c.Clean(files)
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	// Logs aren't a build's to use, and a process in / isn't in any tree
+	if len(result.DeletedFiles) != 2 {
+		t.Errorf("expected the idle build and the log to go, got %v", result.DeletedFiles)
+	}
+	f.AssertFileExists(modules)
+	f.AssertFileExists(target)
+	f.AssertFileNotExists(idle)
+	f.AssertFileNotExists(log)
+	if reason := result.SkippedReason[f.Path("app/node_modules")]; !strings.Contains(reason, "npm is building here") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	for _, err := range result.Errors {
+		if err.Reason != ErrorBuildRunning || err.Retryable {
+			t.Errorf("expected builds to be skipped for the run, got %+v", err)
+		}
+	}
+	if len(result.RunningApps()) != 0 {
+		t.Errorf("builds aren't apps to quit, got %v", result.RunningApps())
+	}
+	if listed != 1 {
+		t.Errorf("builds listed %d times, want once", listed)
+	}
+
+	// Turning the check off doesn't list processes at all
+	cfg.Clean.SkipRunningBuilds = false
+	listed = 0
+	c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{{Path: f.Path("app/node_modules"), Category: "node_modules"}}})
+	if listed != 0 {
+		t.Error("expected no process listing with skip_running_builds off")
+	}
+}
+
+// =============================================================================
+// Virtual File System Tests
+// =============================================================================
+
+func TestCleanVirtualFS(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.cache/old.bin", 100, old)
+	mem.AddFile("/home/user/.cache/new.bin", 10, time.Now())
+	mem.AddFile("/home/user/app/node_modules/lib/index.js", 20, old)
+	mem.AddFile("/home/user/.cache/suid", 30, old)
+	mem.Chmod("/home/user/.cache/suid", 0755|fs.ModeSetuid)
+	mem.AddFile("/tmp/locked/x.tmp", 40, old)
+	mem.Chmod("/tmp/locked", 0555)
+
+	c := New(&config.Config{MinFileAge: 24})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/home/user/.cache/old.bin", Size: 100, Category: "cache"},
+		{Path: "/home/user/.cache/new.bin", Size: 10, Category: "cache"},
+		{Path: "/home/user/app/node_modules", Size: 20, Category: "node_modules"},
+		{Path: "/home/user/.cache/suid", Size: 30, Category: "cache"},
+		{Path: "/tmp/locked/x.tmp", Size: 40, Category: "temp"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if result.DeletedSize != 120 || len(result.DeletedFiles) != 2 {
+		t.Errorf("expected old.bin and node_modules deleted, got %v", result.DeletedFiles)
+	}
+	for _, path := range []string{"/home/user/.cache/old.bin", "/home/user/app/node_modules/lib/index.js"} {
+		if mem.Exists(path) {
+			t.Errorf("%s should have been deleted", path)
+		}
+	}
+
+	// The setuid file is left out of the permission report altogether
+	if !mem.Exists("/home/user/.cache/suid") {
+		t.Error("setuid file should never be deleted")
+	}
+	skipped := map[string]string{
+		"/home/user/.cache/new.bin": "too new",
+		"/tmp/locked/x.tmp":         "elevated permissions",
+	}
+	for path, reason := range skipped {
+		if !mem.Exists(path) {
+			t.Errorf("%s should have been kept", path)
+		}
+		if !strings.Contains(result.SkippedReason[path], reason) {
+			t.Errorf("expected %s skipped for %q, got %q", path, reason, result.SkippedReason[path])
+		}
+	}
+	if result.UsedSudo || len(result.Filesystems) != 0 {
+		t.Error("a virtual clean should neither use sudo nor measure the real disk")
+	}
+}
+
+func TestCleanEmptyDirs(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.MkdirAll("/home/user/.cache/gone/a/b", old)
+	mem.MkdirAll("/home/user/.cache/refilled/a", old)
+	mem.AddFile("/home/user/.cache/refilled/a/new.txt", 10, old)
+
+	c := New(&config.Config{MinFileAge: 24})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/home/user/.cache/gone", Category: scanner.EmptyDirsCategory},
+		{Path: "/home/user/.cache/refilled", Category: scanner.EmptyDirsCategory},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if mem.Exists("/home/user/.cache/gone") {
+		t.Error("the empty tree should have been removed")
+	}
+	// A file that turned up since the scan stops the removal
+	if !mem.Exists("/home/user/.cache/refilled/a/new.txt") {
+		t.Error("a file in a supposedly empty directory must never be deleted")
+	}
+	if len(result.DeletedFiles) != 1 || len(result.SkippedFiles) != 1 {
+		t.Errorf("expected one deleted and one skipped, got %v and %v", result.DeletedFiles, result.SkippedFiles)
+	}
+}
+
+// =============================================================================
+// VM Disk Compaction Tests
+// =============================================================================
+
+func TestCompactSteps(t *testing.T) {
+	tests := []struct {
+		disk scanner.VMDisk
+		cfg  config.DockerConfig
+		want []string
+	}{
+		{
+			scanner.VMDisk{Runtime: scanner.RuntimeDockerDesktop, Docker: []string{"--context", "desktop-linux"}},
+			config.DockerConfig{CleanImages: true, OnlyDanglingImages: true},
+			[]string{
+				"docker --context desktop-linux system prune --force",
+				"docker --context desktop-linux run --rm --privileged --pid=host alpine nsenter -t 1 -m -- fstrim -av",
+			},
+		},
+		{
+			scanner.VMDisk{Runtime: scanner.RuntimeColima, Name: "work", Docker: []string{"--context", "colima-work"}},
+			config.DockerConfig{CleanImages: true, CleanVolumes: true},
+			[]string{
+				"docker --context colima-work system prune --force --all --volumes",
+				"colima ssh --profile work -- sudo fstrim -av",
+				"colima prune --force",
+			},
+		},
+		{
+			scanner.VMDisk{Runtime: scanner.RuntimeLima, Name: "default"},
+			config.DockerConfig{CleanVolumes: true},
+			[]string{"limactl shell default sudo fstrim -av"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, step := range CompactSteps(tt.disk, &tt.cfg) {
+			got = append(got, strings.Join(step, " "))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.disk.Runtime, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestParseDockerDF(t *testing.T) {
+	out := []byte(`{"Active":"2","Reclaimable":"1.5GB (60%)","Size":"2.5GB","TotalCount":"5","Type":"Images"}
+{"Active":"1","Reclaimable":"0B (0%)","Size":"12.3kB","TotalCount":"1","Type":"Containers"}
+{"Active":"0","Reclaimable":"500MB","Size":"500MB","TotalCount":"2","Type":"Local Volumes"}
+
+`)
+	usage, err := parseDockerDF(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Size != 2_500_000_000+12_300+500_000_000 || usage.Reclaimable != 2_000_000_000 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	if _, err := parseDockerDF([]byte(`{"Size":"lots"}`)); err == nil {
+		t.Error("expected an unparseable size to be an error")
+	}
+}
+
+// =============================================================================
+// Registry Garbage Collection Tests
+// =============================================================================
+
+func TestGarbageCollectSteps(t *testing.T) {
+	tests := []struct {
+		cfg    config.RegistryConfig
+		dryRun bool
+		want   []string
+	}{
+		{
+			config.RegistryConfig{ConfigFile: "/etc/docker/registry/config.yml", BuildKit: true, BuildKitKeep: "168h"},
+			false,
+			[]string{
+				"registry garbage-collect /etc/docker/registry/config.yml",
+				"buildctl prune --keep-duration 168h",
+			},
+		},
+		{
+			config.RegistryConfig{ConfigFile: "/etc/docker/registry/config.yml", Container: "registry", DeleteUntagged: true, BuildKit: true, BuildKitAddr: "tcp://buildkitd:1234"},
+			true,
+			[]string{"docker exec registry registry garbage-collect --delete-untagged --dry-run /etc/docker/registry/config.yml"},
+		},
+		{
+			config.RegistryConfig{BuildKit: true, BuildKitAddr: "tcp://buildkitd:1234"},
+			false,
+			[]string{"buildctl --addr tcp://buildkitd:1234 prune"},
+		},
+	}
+	for i, tt := range tests {
+		var got []string
+		for _, step := range GarbageCollectSteps(&tt.cfg, tt.dryRun) {
+			got = append(got, strings.Join(step, " "))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("case %d: expected\n%s\ngot\n%s", i, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestParseBuildKitDU(t *testing.T) {
+	out := []byte(`[{"ID":"abc","Mutable":false,"InUse":false,"Size":1000,"Parents":null,"CreatedAt":"2026-01-01T00:00:00Z","Description":"pulled from docker.io/library/alpine","LastUsedAt":"2026-02-01T00:00:00Z","UsageCount":3,"RecordType":"regular","Shared":true},
+{"ID":"def","Mutable":true,"InUse":true,"Size":500,"Parents":["abc"],"CreatedAt":"2026-03-01T00:00:00Z","Description":"","LastUsedAt":null,"UsageCount":0,"RecordType":"exec.cachemount","Shared":false}]`)
+	records, err := parseBuildKitDU(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if r := records[0]; r.Size != 1000 || !r.Shared || r.InUse || r.LastUsed.Month() != time.February {
+		t.Errorf("unexpected first record %+v", r)
+	}
+	if r := records[1]; !r.InUse || r.Type != "exec.cachemount" || r.LastUsed.Month() != time.March {
+		t.Errorf("expected a record never used since to count from its creation, got %+v", r)
+	}
+
+	if _, err := parseBuildKitDU([]byte("ID  RECLAIMABLE")); err == nil {
+		t.Error("expected table output to be an error")
+	}
+}
+
+func TestChownCommand(t *testing.T) {
+	home := t.TempDir()
+	if os.Geteuid() == 0 {
+		// Root's own home is refused
+		if err := os.Chown(home, 4242, 4343); err != nil {
+			t.Skipf("can't change owner: %v", err)
+		}
+	}
+	info, err := os.Stat(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, gid, ok := platform.FileOwner(info)
+	if !ok {
+		t.Skip("file owners aren't supported here")
+	}
+
+	cache := filepath.Join(home, ".npm")
+	if err := os.Mkdir(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	args, err := ChownCommand(cache, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(args, " "), fmt.Sprintf("chown -R -P -- %d:%d %s", uid, gid, cache); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	outside := t.TempDir()
+	os.Mkdir(filepath.Join(outside, "etc"), 0755)
+	link := filepath.Join(home, ".cache")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{link, filepath.Join(link, "etc"), home, outside, ".npm"} {
+		if _, err := ChownCommand(path, home); err == nil {
+			t.Errorf("expected %s to be refused", path)
+		}
+	}
+}
+
+func TestCleanHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh isn't available")
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.cache/a.bin", 100, old)
+	mem.AddFile("/home/user/.cache/b.bin", 50, old)
+	mem.AddFile("/var/lib/docker/layer", 70, old)
+
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	log := `echo "$TIDYUP_HOOK $TIDYUP_CATEGORY $TIDYUP_FILES $TIDYUP_SIZE $TIDYUP_DELETED_FILES $TIDYUP_DELETED_SIZE $(wc -l < "$TIDYUP_FILE_LIST")" >> ` + out
+	c := New(&config.Config{MinFileAge: 24, Clean: config.CleanConfig{Hooks: []config.HookConfig{
+		{When: "pre", Command: log},
+		{When: "pre", Categories: []string{"docker"}, Command: "echo containers still running; exit 3"},
+		{When: "post", Command: log},
+	}}})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/home/user/.cache/a.bin", Size: 100, Category: "cache"},
+		{Path: "/var/lib/docker/layer", Size: 70, Category: "docker"},
+		{Path: "/home/user/.cache/b.bin", Size: 50, Category: "cache"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	// The failed pre hook keeps docker from being cleaned, and its post hooks
+	// from running
+	if !mem.Exists("/var/lib/docker/layer") || mem.Exists("/home/user/.cache/a.bin") {
+		t.Errorf("expected only the cache cleaned, got %v", result.DeletedFiles)
+	}
+	if reason := result.SkippedReason["/var/lib/docker/layer"]; !strings.Contains(reason, "containers still running") {
+		t.Errorf("expected the hook's output in the skip reason, got %q", reason)
+	}
+	if len(result.HookErrors) != 1 || !strings.Contains(result.HookErrors[0], "pre hook for docker") {
+		t.Errorf("expected the docker pre hook's error, got %v", result.HookErrors)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pre cache 2 150 2\npre docker 1 70 1\npost cache 2 150 2 150 2\n"
+	// wc pads its count on some systems
+	if strings.Join(strings.Fields(string(data)), " ") != strings.Join(strings.Fields(want), " ") {
+		t.Errorf("expected hooks to run with\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestCleanHooksOnlyFromUserConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	user := filepath.Join(dir, "user.yaml")
+	marker := filepath.Join(dir, "hook-ran")
+	hooks := fmt.Sprintf("clean:\n  hooks:\n    - when: pre\n      command: %q\n", "touch "+marker)
+
+	// clean runs whatever hooks the config it loaded has
+	clean := func(cfg *config.Config) {
+		mem := vfs.NewMemFS()
+		mem.AddFile("/home/user/.cache/a.bin", 100, time.Now().Add(-48*time.Hour))
+		c := New(cfg)
+		c.SetFS(mem)
+		c.SetAskSudo(false)
+		if _, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+			{Path: "/home/user/.cache/a.bin", Size: 100, Category: "cache"},
+		}}); err != nil {
+			t.Fatalf("Clean failed: %v", err)
+		}
+	}
+
+	// A checkout's .tidyup.yaml can't add hooks
+	os.WriteFile(filepath.Join(dir, config.ProjectConfigFile), []byte(hooks), 0644)
+	cfg, _, err := config.LoadLayered(config.Layers(user))
+	if err == nil {
+		clean(cfg)
+	} else if !strings.Contains(err.Error(), "clean can only be set in the system or user config") {
+		t.Errorf("expected the project config's hooks to be refused, got %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("a hook from the project config ran")
+	}
+
+	// The same hook in the user config runs
+	os.Remove(filepath.Join(dir, config.ProjectConfigFile))
+	os.WriteFile(user, []byte(hooks), 0644)
+	cfg, _, err = config.LoadLayered(config.Layers(user))
+	if err != nil {
+		t.Fatalf("LoadLayered failed: %v", err)
+	}
+	clean(cfg)
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the user config's hook to run: %v", err)
+	}
+}
+
+func TestCleanHookWebhook(t *testing.T) {
+	var events []HookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event HookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/tmp/old.tmp", 40, old)
+	c := New(&config.Config{MinFileAge: 24, Clean: config.CleanConfig{Hooks: []config.HookConfig{
+		{When: "post", Categories: []string{"temp"}, URL: server.URL, Headers: map[string]string{"X-Token": "secret"}},
+		{When: "post", Categories: []string{"cache"}, URL: server.URL},
+	}}})
+	c.SetFS(mem)
+	c.SetAskSudo(false)
+	c.SetRunID("run-1")
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: "/tmp/old.tmp", Size: 40, Category: "temp"},
+	}})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(result.HookErrors) != 0 {
+		t.Errorf("unexpected hook errors: %v", result.HookErrors)
+	}
+
+	want := HookEvent{Hook: "post", Category: "temp", RunID: "run-1", Files: 1, Size: 40,
+		Paths: []string{"/tmp/old.tmp"}, DeletedFiles: 1, DeletedSize: 40}
+	if len(events) != 1 || fmt.Sprint(events[0]) != fmt.Sprint(want) {
+		t.Errorf("expected the webhook to get %+v, got %+v", want, events)
+	}
+}
+
+func TestQuarantinePurge(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	cache := f.CreateFileWithAge("cache/old.txt", []byte("content"), 48*time.Hour)
+	large := f.CreateFileWithAge("videos/big.mkv", []byte("video"), 48*time.Hour)
+
+	c := New(&config.Config{
+		MinFileAge: 24,
+		Quarantine: config.QuarantineConfig{Enabled: true, Dir: f.Path("quarantine")},
+	})
+	c.SetAskSudo(false)
+	if _, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: cache, Size: 7, Category: "cache"},
+		{Path: large, Size: 5, Category: "large_files"},
+	}}); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	q := NewQuarantine(f.Path("quarantine"))
+	purged, size, errs := q.Purge(func(entry QuarantineEntry) bool {
+		return entry.Category == "cache"
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Purge errors: %v", errs)
+	}
+	if purged != 1 || size != 7 {
+		t.Errorf("purged %d files (%d bytes), want 1 (7 bytes)", purged, size)
+	}
+
+	run, err := q.Latest()
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if len(run.Entries) != 1 || run.Entries[0].OriginalPath != large {
+		t.Fatalf("run entries = %+v, want only %s", run.Entries, large)
+	}
+	if restored, errs := run.Restore(); len(errs) != 0 || len(restored) != 1 {
+		t.Fatalf("Restore = %v, %v", restored, errs)
+	}
+	f.AssertFileExists(large)
+	f.AssertFileNotExists(cache)
+
+	// Purging the rest removes the emptied run
+	f.CreateFileWithAge("cache/again.txt", []byte("x"), 48*time.Hour)
+	c = New(&config.Config{
+		MinFileAge: 24,
+		Quarantine: config.QuarantineConfig{Enabled: true, Dir: f.Path("quarantine")},
+	})
+	c.SetAskSudo(false)
+	if _, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: f.Path("cache/again.txt"), Size: 1, Category: "cache"},
+	}}); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if purged, _, _ := q.Purge(func(QuarantineEntry) bool { return true }); purged != 1 {
+		t.Errorf("purged %d files, want 1", purged)
+	}
+	if runs, _ := q.Runs(); len(runs) != 0 {
+		t.Errorf("%d runs left after purging everything, want 0", len(runs))
+	}
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	kept := f.CreateFileWithAge("cache/kept.txt", []byte("kept"), 48*time.Hour)
+	modified := f.CreateFileWithAge("cache/modified.txt", []byte("old"), 48*time.Hour)
+	replaced := f.CreateFileWithAge("cache/replaced.txt", []byte("old"), 48*time.Hour)
+	gone := f.CreateFileWithAge("cache/gone.txt", []byte("gone"), 48*time.Hour)
+
+	result := &scanner.ScanResult{}
+	for _, path := range []string{kept, modified, replaced, gone} {
+		result.Files = append(result.Files, scanner.FileInfo{Path: path, Size: 4, Category: "cache"})
+	}
+	snapshot, err := NewSnapshot("run", result)
+	if err != nil {
+		t.Fatalf("NewSnapshot failed: %v", err)
+	}
+	statePath := f.Path("state/scan.json")
+	if err := snapshot.Save(statePath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	os.Chtimes(modified, time.Now(), time.Now())
+	os.Remove(replaced)
+	f.CreateFile("cache/replaced.txt", []byte("new"))
+	os.Remove(gone)
+
+	loaded, err := LoadSnapshot(statePath, time.Hour)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	verified, changed := loaded.Verify()
+	if verified.TotalCount != 1 || verified.Files[0].Path != kept {
+		t.Errorf("verified files = %+v, want only %s", verified.Files, kept)
+	}
+	if len(changed) != 2 || changed[modified] == "" || changed[replaced] == "" {
+		t.Errorf("changed = %v, want %s and %s", changed, modified, replaced)
+	}
+
+	loaded.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := loaded.Save(statePath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := LoadSnapshot(statePath, time.Hour); err == nil {
+		t.Error("expected a scan state older than the max age to be refused")
+	}
+	if _, err := LoadSnapshot(statePath, 0); err != nil {
+		t.Errorf("expected no max age to accept any scan state, got %v", err)
+	}
+}
+
+func TestAnyUnder(t *testing.T) {
+	tests := []struct {
+		paths []string
+		dir   string
+		want  bool
+	}{
+		{[]string{"/home/user/.ccache/a/b/entry"}, "/home/user/.ccache", true},
+		{[]string{"/home/user/.ccache"}, "/home/user/.ccache", false},
+		{[]string{"/home/user/.ccache2/entry"}, "/home/user/.ccache", false},
+		{[]string{"/home/user/..ccache/entry", "/tmp/x"}, "/home/user", true},
+		{[]string{"/home/user/.ccache/entry"}, "", false},
+	}
+	for _, tt := range tests {
+		if got := anyUnder(tt.paths, tt.dir); got != tt.want {
+			t.Errorf("anyUnder(%v, %q) = %v, want %v", tt.paths, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveDir(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "app")
+	for path, data := range map[string]string{
+		"main.go":                 "package main",
+		"docs/README":             "read me",
+		"node_modules/x/index.js": "junk",
+		"debug.log":               "junk",
+	} {
+		path = filepath.Join(project, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("main.go", filepath.Join(project, "link")); err != nil {
+		t.Fatal(err)
+	}
+	exclude := []string{filepath.Join(project, "node_modules"), filepath.Join(project, "debug.log")}
+
+	names := []string{"app.tar", "app.tar.gz", "app.tgz"}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		names = append(names, "app.tar.zst")
+	}
+	ctx := context.Background()
+	for _, name := range names {
+		dest := filepath.Join(root, name)
+		stats, err := ArchiveDir(ctx, project, dest, exclude)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := ArchiveStats{Files: 2, Size: int64(len("package main") + len("read me"))}
+		if stats != want {
+			t.Errorf("%s: expected %+v, got %+v", name, want, stats)
+		}
+		if err := VerifyArchive(ctx, dest, stats); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := VerifyArchive(ctx, dest, ArchiveStats{Files: 3}); err == nil {
+			t.Errorf("%s: expected verifying with the wrong contents to fail", name)
+		}
+		if _, err := ArchiveDir(ctx, project, dest, nil); err == nil {
+			t.Errorf("%s: expected an existing archive not to be replaced", name)
+		}
+	}
+
+	if _, err := ArchiveDir(ctx, project, filepath.Join(project, "self.tar"), nil); err == nil {
+		t.Error("expected an archive inside the project to be refused")
+	}
+	if err := CheckArchiveName("app.zip"); err == nil {
+		t.Error("expected .zip to be refused")
+	}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestPacer(t *testing.T) {
+	files := map[string]scanner.FileInfo{
+		"/a": {Path: "/a", Size: 900},
+		"/b": {Path: "/b", Size: 50},
+		"/c": {Path: "/c", Size: 50},
+		"/d": {Path: "/d", Size: 0},
+	}
+	p := newPacer(time.Hour, []string{"/a", "/b", "/c", "/d"}, files)
+	now := p.start
+	var slept []time.Duration
+	p.now = func() time.Time { return now }
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	// 90% of the size is due 54 minutes in
+	p.wait(context.Background(), 1, 900)
+	if len(slept) != 1 || slept[0] != 54*time.Minute {
+		t.Fatalf("expected to wait 54m after the big file, got %v", slept)
+	}
+	// Size still sets the pace: 95% is due 57 minutes in
+	p.wait(context.Background(), 1, 50)
+	if len(slept) != 2 || slept[1] != 3*time.Minute {
+		t.Errorf("expected to wait until 57m, got %v", slept)
+	}
+	now = now.Add(time.Hour)
+	p.wait(context.Background(), 1, 50)
+	if len(slept) != 2 {
+		t.Errorf("expected no wait once behind schedule, got %v", slept)
+	}
+	// Nothing is held back after the last file
+	now = p.start
+	p.wait(context.Background(), 1, 0)
+	if len(slept) != 2 {
+		t.Errorf("expected no wait after the last file, got %v", slept)
+	}
+
+	if newPacer(0, []string{"/a"}, files) != nil {
+		t.Error("expected no pacer without a window")
+	}
+	var none *pacer
+	if err := none.wait(context.Background(), 1, 900); err != nil {
+		t.Errorf("nil pacer: %v", err)
+	}
+}
+
+func TestCleanSpreadStopsWhenCancelled(t *testing.T) {
+	f := testutil.NewFixture(t)
+	first := f.CreateFileWithAge("a.tmp", []byte("aaaa"), 48*time.Hour)
+	second := f.CreateFileWithAge("b.tmp", []byte("bbbb"), 48*time.Hour)
+
+	c := New(&config.Config{MinFileAge: 24})
+	c.SetAskSudo(false)
+	c.SetSpread(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+		{Path: first, Size: 4, Category: "temp"},
+		{Path: second, Size: 4, Category: "temp"},
+	}})
+	if err == nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the spread cleanup to be interrupted while it waited, got %v", err)
+	}
+	// Half the files are done, so it waits half an hour for the second
+	if len(result.DeletedFiles) != 1 {
+		t.Errorf("expected one file deleted before the wait, got %v", result.DeletedFiles)
+	}
+	f.AssertFileExists(second)
+}
+
+func TestCleanSpreadDeletesTreesWithOneWorker(t *testing.T) {
+	for _, tt := range []struct {
+		spread  time.Duration
+		workers int
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+	} {
+		f := testutil.NewFixture(t)
+		dir := f.Path("app/node_modules")
+		f.CreateFileWithAge("app/node_modules/lib/index.js", []byte("x"), 48*time.Hour)
+		os.Chtimes(dir, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+
+		c := New(&config.Config{MinFileAge: 24})
+		c.SetAskSudo(false)
+		c.SetSpread(tt.spread)
+		workers := -1
+		c.deleteTree = func(path string, n int) error {
+			workers = n
+			return removeTree(path, n)
+		}
+		result, err := c.Clean(&scanner.ScanResult{Files: []scanner.FileInfo{
+			{Path: dir, Size: 1, Category: "node_modules"},
+		}})
+		if err != nil {
+			t.Fatalf("Clean failed: %v", err)
+		}
+		if len(result.DeletedFiles) != 1 {
+			t.Fatalf("expected %s deleted, got %v", dir, result.SkippedReason)
+		}
+		// Workers beyond the low priority thread would run at normal priority
+		if workers != tt.workers {
+			t.Errorf("spread %s: deleted the tree with %d workers, want %d", tt.spread, workers, tt.workers)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cleaner
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// ccacheTimeout bounds "ccache -c", which reads every stats file in the cache
+const ccacheTimeout = 2 * time.Minute
+
+// recountCCache runs "ccache -c" when entries were deleted from ccache's
+// cache, so its statistics and size limit see the space that was freed.
+// ccache corrects them by itself at its next cleanup otherwise, so a
+// failure isn't reported.
+func (c *Cleaner) recountCCache(deleted []string, files map[string]scanner.FileInfo) {
+	if !c.native() {
+		return
+	}
+	var entries []string
+	for _, path := range deleted {
+		if files[path].Category == scanner.CompilerCachesCategory {
+			entries = append(entries, path)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if _, err := exec.LookPath("ccache"); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, ccacheTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ccache", "-k", "cache_dir").Output()
+	if err != nil || !anyUnder(entries, strings.TrimSpace(string(out))) {
+		return
+	}
+	exec.CommandContext(ctx, "ccache", "-c").Run()
+}
+
+// anyUnder reports whether any of paths is inside dir
+func anyUnder(paths []string, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, path := range paths {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+	return false
+}
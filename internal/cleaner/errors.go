@@ -4,7 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"syscall"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
 )
 
 // ErrorReason categorizes why a deletion failed
@@ -17,25 +24,37 @@ const (
 	ErrorIsDirectory
 	ErrorInvalidPath
 	ErrorUnknown
+	ErrorAppRunning
+	ErrorImmutable
+	ErrorPolicyDenied
+	ErrorBuildRunning
 )
 
 // String returns a human-readable error reason
 func (e ErrorReason) String() string {
 	switch e {
 	case ErrorPermissionDenied:
-		return "Permission denied"
+		return i18n.T("Permission denied")
 	case ErrorFileInUse:
-		return "File is in use"
+		return i18n.T("File is in use")
 	case ErrorFileNotFound:
-		return "File not found"
+		return i18n.T("File not found")
 	case ErrorIsDirectory:
-		return "Is a directory"
+		return i18n.T("Is a directory")
 	case ErrorInvalidPath:
-		return "Invalid path"
+		return i18n.T("Invalid path")
 	case ErrorUnknown:
-		return "Unknown error"
+		return i18n.T("Unknown error")
+	case ErrorAppRunning:
+		return i18n.T("Application is running")
+	case ErrorImmutable:
+		return i18n.T("Immutable file")
+	case ErrorPolicyDenied:
+		return i18n.T("Blocked by security policy")
+	case ErrorBuildRunning:
+		return i18n.T("Build is running")
 	default:
-		return "Unspecified error"
+		return i18n.T("Unspecified error")
 	}
 }
 
@@ -46,6 +65,7 @@ type DeletionError struct {
 	Original  error
 	Retryable bool
 	NeedsSudo bool
+	App       string // Application that was running, for ErrorAppRunning, or build tool, for ErrorBuildRunning
 }
 
 // Error implements the error interface
@@ -58,19 +78,27 @@ func (e *DeletionError) UserMessage() string {
 	switch e.Reason {
 	case ErrorPermissionDenied:
 		if e.NeedsSudo {
-			return fmt.Sprintf("  Need elevated permissions to delete: %s", e.Path)
+			return i18n.T("  Need elevated permissions to delete: %s", e.Path)
 		}
-		return fmt.Sprintf("  Permission denied: %s", e.Path)
+		return i18n.T("  Permission denied: %s", e.Path)
 	case ErrorFileInUse:
-		return fmt.Sprintf("  File is being used: %s (close the application and try again)", e.Path)
+		return i18n.T("  File is being used: %s (close the application and try again)", e.Path)
 	case ErrorFileNotFound:
-		return fmt.Sprintf("ℹ️  Already deleted: %s", e.Path)
+		return i18n.T("ℹ️  Already deleted: %s", e.Path)
 	case ErrorIsDirectory:
-		return fmt.Sprintf("  Cannot delete directory: %s (use recursive delete)", e.Path)
+		return i18n.T("  Cannot delete directory: %s (use recursive delete)", e.Path)
 	case ErrorInvalidPath:
-		return fmt.Sprintf(" Invalid or unsafe path: %s", e.Path)
+		return i18n.T(" Invalid or unsafe path: %s", e.Path)
+	case ErrorAppRunning:
+		return i18n.T("  %s is running: %s (quit it and try again)", e.App, e.Path)
+	case ErrorImmutable:
+		return i18n.T("  Immutable or append-only: %s (the flag must be removed first)", e.Path)
+	case ErrorPolicyDenied:
+		return i18n.T("  Blocked by %s: %s", policyName(), e.Path)
+	case ErrorBuildRunning:
+		return i18n.T("  %s is building here: %s (skipped for this run)", e.App, e.Path)
 	default:
-		return fmt.Sprintf(" Error deleting %s: %v", e.Path, e.Original)
+		return i18n.T(" Error deleting %s: %v", e.Path, e.Original)
 	}
 }
 
@@ -96,9 +124,9 @@ func CategorizeError(path string, err error) *DeletionError {
 
 	// Check if permission error
 	if os.IsPermission(err) {
-		delErr.Reason = ErrorPermissionDenied
+		delErr.Reason = deniedReason(deniedPath(path, err))
 		delErr.Retryable = false
-		delErr.NeedsSudo = true
+		delErr.NeedsSudo = delErr.Reason == ErrorPermissionDenied
 		return delErr
 	}
 
@@ -107,9 +135,9 @@ func CategorizeError(path string, err error) *DeletionError {
 	if errors.As(err, &errno) {
 		switch errno {
 		case syscall.EACCES, syscall.EPERM:
-			delErr.Reason = ErrorPermissionDenied
+			delErr.Reason = deniedReason(deniedPath(path, err))
 			delErr.Retryable = false
-			delErr.NeedsSudo = true
+			delErr.NeedsSudo = delErr.Reason == ErrorPermissionDenied
 		case syscall.EBUSY, syscall.ETXTBSY:
 			delErr.Reason = ErrorFileInUse
 			delErr.Retryable = true
@@ -129,6 +157,15 @@ func CategorizeError(path string, err error) *DeletionError {
 		return delErr
 	}
 
+	// Deletions run through sudo only have rm's message to go on. Root is
+	// only refused by a flag or a policy, never by plain permissions.
+	msg := err.Error()
+	if strings.Contains(msg, "Operation not permitted") || strings.Contains(msg, "Permission denied") {
+		if reason := deniedReason(path); reason != ErrorPermissionDenied {
+			delErr.Reason = reason
+		}
+	}
+
 	// Default to unknown
 	delErr.Retryable = false
 	return delErr
@@ -150,35 +187,126 @@ func FormatErrorSummary(errors []*DeletionError) string {
 	}
 
 	grouped := GroupErrors(errors)
-	summary := fmt.Sprintf("\n  Issues encountered:\n")
+	summary := i18n.T("\n  Issues encountered:\n")
 
 	// Permission denied
 	if perms, ok := grouped[ErrorPermissionDenied]; ok {
-		summary += fmt.Sprintf("   ├─ Permission denied: %d files\n", len(perms))
-		summary += "   │  └─ Tip: Run with sudo or elevate permissions\n"
+		summary += i18n.T("   ├─ Permission denied: %d files\n", len(perms))
+		summary += i18n.T("   │  └─ Tip: Run with sudo or elevate permissions\n")
 	}
 
 	// File in use
 	if busy, ok := grouped[ErrorFileInUse]; ok {
-		summary += fmt.Sprintf("   ├─ File in use: %d files\n", len(busy))
-		summary += "   │  └─ Tip: Close applications and retry\n"
+		summary += i18n.T("   ├─ File in use: %d files\n", len(busy))
+		summary += i18n.T("   │  └─ Tip: Close applications and retry\n")
 	}
 
 	// File not found
 	if notFound, ok := grouped[ErrorFileNotFound]; ok {
-		summary += fmt.Sprintf("   ├─ Already deleted: %d files\n", len(notFound))
+		summary += i18n.T("   ├─ Already deleted: %d files\n", len(notFound))
 	}
 
 	// Directories
 	if dirs, ok := grouped[ErrorIsDirectory]; ok {
-		summary += fmt.Sprintf("   ├─ Directories: %d items\n", len(dirs))
-		summary += "   │  └─ Tip: Use recursive delete option\n"
+		summary += i18n.T("   ├─ Directories: %d items\n", len(dirs))
+		summary += i18n.T("   │  └─ Tip: Use recursive delete option\n")
+	}
+
+	// Owned by a running application
+	if running, ok := grouped[ErrorAppRunning]; ok {
+		summary += i18n.T("   ├─ Application running: %d files\n", len(running))
+		summary += i18n.T("   │  └─ Tip: Quit %s and retry\n", strings.Join(runningApps(running), ", "))
+	}
+
+	// In use by a running build
+	if building, ok := grouped[ErrorBuildRunning]; ok {
+		summary += i18n.T("   ├─ Build running: %d files\n", len(building))
+		summary += i18n.T("   │  └─ Tip: Run the cleanup again once the build is done\n")
+	}
+
+	// Immutable flags, which stop even root
+	if immutable, ok := grouped[ErrorImmutable]; ok {
+		summary += i18n.T("   ├─ Immutable or append-only: %d files\n", len(immutable))
+		summary += i18n.T("   │  └─ Tip: %s\n", immutableHint())
+	}
+
+	// Mandatory access control, which sudo doesn't get around
+	if denied, ok := grouped[ErrorPolicyDenied]; ok {
+		summary += i18n.T("   ├─ Blocked by %s: %d files\n", policyName(), len(denied))
+		summary += i18n.T("   │  └─ Tip: %s\n", policyHint(platform.ActiveAccessControl()))
 	}
 
 	// Unknown errors
 	if unknown, ok := grouped[ErrorUnknown]; ok {
-		summary += fmt.Sprintf("   └─ Other errors: %d files\n", len(unknown))
+		summary += i18n.T("   └─ Other errors: %d files\n", len(unknown))
 	}
 
 	return summary
 }
+
+// deniedPath returns the file a permission error is about, which for a tree
+// removed in one go can be below path
+func deniedPath(path string, err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) && pathErr.Path != "" {
+		return pathErr.Path
+	}
+	return path
+}
+
+// deniedOnce is the current user's permissions, for telling denials apart
+var deniedOnce = sync.OnceValue(NewPermissionManager)
+
+// deniedReason tells apart why deleting path was refused: an immutable or
+// append-only flag on it or its directory, a mandatory access control policy
+// refusing what its owner and mode allow, or plain permissions
+func deniedReason(path string) ErrorReason {
+	if _, err := os.Lstat(path); err != nil {
+		return ErrorPermissionDenied
+	}
+	for _, p := range []string{path, filepath.Dir(path)} {
+		if immutable, err := platform.IsImmutable(p); err == nil && immutable {
+			return ErrorImmutable
+		}
+	}
+
+	if platform.ActiveAccessControl() == "" {
+		return ErrorPermissionDenied
+	}
+	pm := deniedOnce()
+	if writable, _ := pm.checkDirectoryWritable(filepath.Dir(path)); pm.isRoot || writable {
+		return ErrorPolicyDenied
+	}
+	return ErrorPermissionDenied
+}
+
+// policyName names the mandatory access control system refusing deletions
+func policyName() string {
+	if mac := platform.ActiveAccessControl(); mac != "" {
+		return string(mac)
+	}
+	return i18n.T("security policy")
+}
+
+// immutableHint says how to remove the flags that make files immutable
+func immutableHint() string {
+	if runtime.GOOS == "linux" {
+		return i18n.T("Check with 'lsattr', and remove the flag with 'sudo chattr -i' (or -a) if the files should go")
+	}
+	return i18n.T("Check with 'ls -lO', and remove the flag with 'chflags nouchg' (or 'sudo chflags noschg') if the files should go")
+}
+
+// policyHint says where to look when a mandatory access control policy
+// refused a deletion; sudo won't help
+func policyHint(mac platform.AccessControl) string {
+	switch mac {
+	case platform.AccessControlSELinux:
+		return i18n.T("Elevated permissions won't help. See 'ausearch -m avc -ts recent' and relabel the files with 'restorecon'")
+	case platform.AccessControlAppArmor:
+		return i18n.T("Elevated permissions won't help. Look for apparmor=\"DENIED\" in 'journalctl -k' and adjust the profile")
+	case platform.AccessControlMacOSTCC:
+		return i18n.T("Elevated permissions won't help. Give your terminal Full Disk Access in System Settings > Privacy & Security")
+	default:
+		return i18n.T("Elevated permissions won't help; the security policy has to allow it")
+	}
+}
@@ -0,0 +1,256 @@
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// hookTimeout is how long a hook may take when it doesn't set a timeout
+const hookTimeout = time.Minute
+
+// HookEvent describes the cleanup of a category to its hooks. Commands get it
+// as TIDYUP_* environment variables, webhooks as JSON.
+type HookEvent struct {
+	Hook         string   `json:"hook"` // "pre" or "post"
+	Category     string   `json:"category"`
+	RunID        string   `json:"run_id"`
+	Files        int      `json:"files"` // Files of the category being cleaned
+	Size         int64    `json:"size"`
+	Paths        []string `json:"paths"`
+	DeletedFiles int      `json:"deleted_files,omitempty"` // Post hooks only
+	DeletedSize  int64    `json:"deleted_size,omitempty"`
+	FailedFiles  int      `json:"failed_files,omitempty"`
+}
+
+// hookRun runs the clean.hooks of one cleanup: the pre hooks of each
+// category before anything is deleted, and the post hooks of the categories
+// whose pre hooks passed once the cleanup ends
+type hookRun struct {
+	c          *Cleaner
+	categories []string                      // Categories being cleaned, in order
+	files      map[string][]scanner.FileInfo // Files of each category
+}
+
+// before runs the pre hooks of each category in files, in the order they're
+// cleaned. A category whose pre hook fails isn't cleaned, and its post hooks
+// don't run: its files are skipped.
+func (h *hookRun) before(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	hooks := h.c.config.Clean.Hooks
+	if len(hooks) == 0 {
+		return files
+	}
+	h.files = make(map[string][]scanner.FileInfo)
+	for _, file := range files {
+		if _, ok := h.files[file.Category]; !ok {
+			h.categories = append(h.categories, file.Category)
+		}
+		h.files[file.Category] = append(h.files[file.Category], file)
+	}
+
+	failed := make(map[string]string)
+	for _, category := range h.categories {
+		event := h.event("pre", category)
+		for _, hook := range hooks {
+			if hook.When != "pre" || !hookFor(hook, category) {
+				continue
+			}
+			if err := h.c.runHook(hook, event); err != nil {
+				result.HookErrors = append(result.HookErrors, fmt.Sprintf("pre hook for %s: %v", category, err))
+				failed[category] = err.Error()
+				break
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return files
+	}
+
+	h.categories = slices.DeleteFunc(h.categories, func(category string) bool {
+		_, ok := failed[category]
+		return ok
+	})
+	kept := files[:0:0]
+	for _, file := range files {
+		reason, ok := failed[file.Category]
+		if !ok {
+			kept = append(kept, file)
+			continue
+		}
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = fmt.Sprintf("Pre-clean hook failed: %s", reason)
+	}
+	return kept
+}
+
+// after runs the post hooks of the categories before let through, with what
+// was deleted from each
+func (h *hookRun) after(result *CleanResult) {
+	if len(h.categories) == 0 || result == nil {
+		return
+	}
+	deleted := make(map[string]bool, len(result.DeletedFiles))
+	for _, path := range result.DeletedFiles {
+		deleted[path] = true
+	}
+	failed := make(map[string]bool, len(result.Errors))
+	for _, err := range result.Errors {
+		failed[err.Path] = true
+	}
+
+	for _, category := range h.categories {
+		event := h.event("post", category)
+		for _, file := range h.files[category] {
+			switch {
+			case deleted[file.Path]:
+				event.DeletedFiles++
+				event.DeletedSize += file.Size
+			case failed[file.Path]:
+				event.FailedFiles++
+			}
+		}
+		for _, hook := range h.c.config.Clean.Hooks {
+			if hook.When != "post" || !hookFor(hook, category) {
+				continue
+			}
+			if err := h.c.runHook(hook, event); err != nil {
+				result.HookErrors = append(result.HookErrors, fmt.Sprintf("post hook for %s: %v", category, err))
+			}
+		}
+	}
+}
+
+// event describes the cleanup of category to a hook
+func (h *hookRun) event(hook, category string) HookEvent {
+	event := HookEvent{Hook: hook, Category: category, RunID: h.c.runID}
+	for _, file := range h.files[category] {
+		event.Files++
+		event.Size += file.Size
+		event.Paths = append(event.Paths, file.Path)
+	}
+	return event
+}
+
+// hookFor reports whether hook runs for category
+func hookFor(hook config.HookConfig, category string) bool {
+	return len(hook.Categories) == 0 || slices.Contains(hook.Categories, category)
+}
+
+// runHook runs hook's command or calls its webhook with event. It gets the
+// hook's timeout even if the cleanup was interrupted, so post hooks can put
+// back what pre hooks stopped.
+func (c *Cleaner) runHook(hook config.HookConfig, event HookEvent) error {
+	timeout := hookTimeout
+	if hook.Timeout != "" {
+		if d, err := time.ParseDuration(hook.Timeout); err == nil {
+			timeout = d
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.ctx), timeout)
+	defer cancel()
+
+	var err error
+	if hook.URL != "" {
+		err = postHook(ctx, hook, event)
+	} else {
+		err = runHookCommand(ctx, hook.Command, event)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// runHookCommand runs command in the shell with event in TIDYUP_* variables.
+// The paths are listed one per line in the file TIDYUP_FILE_LIST names, as
+// there can be too many for the environment.
+func runHookCommand(ctx context.Context, command string, event HookEvent) error {
+	list, err := os.CreateTemp("", "tidyup-hook-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create file list: %w", err)
+	}
+	defer os.Remove(list.Name())
+	_, err = list.WriteString(strings.Join(append(event.Paths, ""), "\n"))
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file list: %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"TIDYUP_HOOK="+event.Hook,
+		"TIDYUP_CATEGORY="+event.Category,
+		"TIDYUP_RUN_ID="+event.RunID,
+		"TIDYUP_FILES="+strconv.Itoa(event.Files),
+		"TIDYUP_SIZE="+strconv.FormatInt(event.Size, 10),
+		"TIDYUP_FILE_LIST="+list.Name(),
+	)
+	if event.Hook == "post" {
+		cmd.Env = append(cmd.Env,
+			"TIDYUP_DELETED_FILES="+strconv.Itoa(event.DeletedFiles),
+			"TIDYUP_DELETED_SIZE="+strconv.FormatInt(event.DeletedSize, 10),
+			"TIDYUP_FAILED_FILES="+strconv.Itoa(event.FailedFiles),
+		)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if tail := lastLine(out); tail != "" {
+			return fmt.Errorf("%s: %w: %s", command, err, tail)
+		}
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	return nil
+}
+
+// postHook POSTs event to hook's URL as JSON
+func postHook(ctx context.Context, hook config.HookConfig, event HookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hook event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", hook.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", hook.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of a command's output, which is
+// usually why it failed
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
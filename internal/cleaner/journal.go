@@ -0,0 +1,195 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// Journal records the progress of a cleanup so an interrupted one can be
+// resumed without rescanning. The file holds the plan on its first line,
+// followed by one line per processed path. It's removed once the cleanup
+// finishes.
+type Journal struct {
+	path  string
+	runID string // Of the interrupted cleanup, once Pending has read it
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// journalPlan is the first line of the journal
+type journalPlan struct {
+	Started time.Time          `json:"started"`
+	RunID   string             `json:"run_id,omitempty"`
+	Files   []scanner.FileInfo `json:"files"`
+}
+
+// journalEntry records one processed path
+type journalEntry struct {
+	Done string `json:"done"`
+}
+
+// NewJournal creates a journal at path (~ is expanded)
+func NewJournal(path string) *Journal {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return &Journal{path: path}
+}
+
+// Path returns the journal file location
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// RunID returns the run ID of the interrupted cleanup read by Pending, so the
+// resumed one can carry on under it
+func (j *Journal) RunID() string {
+	return j.runID
+}
+
+// Begin writes the plan for a cleanup, replacing any earlier journal
+func (j *Journal) Begin(runID string, files []scanner.FileInfo) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	data, err := json.Marshal(journalPlan{Started: time.Now(), RunID: runID, Files: files})
+	if err == nil {
+		_, err = f.Write(append(data, '\n'))
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	j.f = f
+	return nil
+}
+
+// Done records that path has been processed. Journaling is best effort: a
+// failed write only means the path is tried again on resume.
+func (j *Journal) Done(paths ...string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return
+	}
+	var buf []byte
+	for _, path := range paths {
+		data, err := json.Marshal(journalEntry{Done: path})
+		if err != nil {
+			continue
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+	j.f.Write(buf)
+}
+
+// Close stops journaling, keeping the file so the cleanup can be resumed
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// Finish removes the journal after a cleanup that ran to completion
+func (j *Journal) Finish() error {
+	j.Close()
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the files of an interrupted cleanup that weren't processed,
+// in their original order. It returns an error wrapping os.ErrNotExist if
+// there's nothing to resume.
+func (j *Journal) Pending() (*scanner.ScanResult, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	var plan journalPlan
+	if err := json.Unmarshal(line, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+	j.runID = plan.RunID
+
+	done := make(map[string]bool)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var entry journalEntry
+		// A torn last line from an interruption is ignored
+		if json.Unmarshal(line, &entry) == nil && entry.Done != "" {
+			done[entry.Done] = true
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	result := &scanner.ScanResult{Files: []scanner.FileInfo{}}
+	for _, file := range plan.Files {
+		if done[file.Path] {
+			continue
+		}
+		result.Files = append(result.Files, file)
+		result.TotalSize += file.Size
+		result.TotalCount++
+	}
+	return result, nil
+}
+
+// orderByCategory sorts files so categories are cleaned in the given order.
+// Categories that aren't listed go last, and files keep their relative order.
+func orderByCategory(files []scanner.FileInfo, order []string) []scanner.FileInfo {
+	rank := make(map[string]int, len(order))
+	for i, category := range order {
+		rank[category] = i
+	}
+	rankOf := func(category string) int {
+		if r, ok := rank[category]; ok {
+			return r
+		}
+		return len(order)
+	}
+
+	ordered := append([]scanner.FileInfo(nil), files...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rankOf(ordered[i].Category) < rankOf(ordered[j].Category)
+	})
+	return ordered
+}
@@ -0,0 +1,157 @@
+package cleaner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// moveFile renames src to dst. When they're on different file systems, which
+// a rename can't cross, src is copied, the copy verified and synced to disk,
+// and only then src removed. It reports whether it had to copy.
+func moveFile(src, dst string) (copied bool, err error) {
+	err = os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return false, err
+	}
+	return true, copyAndRemove(src, dst)
+}
+
+// copyAndRemove copies the file or tree at src to dst and removes src once
+// the copy is complete. Nothing is left at dst if the copy fails.
+func copyAndRemove(src, dst string) error {
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s to another file system: %w", src, err)
+	}
+	if err := syncDir(filepath.Dir(dst)); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s to another file system: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("copied %s but failed to remove it: %w", src, err)
+	}
+	return nil
+}
+
+// copyTree copies a file, symlink or directory tree, keeping modes and
+// modification times
+func copyTree(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+
+	case info.IsDir():
+		// Writable until its contents are in, whatever the original mode
+		if err := os.Mkdir(dst, 0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		if err := syncDir(dst); err != nil {
+			return err
+		}
+		if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return os.Chtimes(dst, info.ModTime(), info.ModTime())
+
+	case info.Mode().IsRegular():
+		return copyFile(src, dst, info)
+
+	default:
+		return fmt.Errorf("%s is a special file and can't be copied", src)
+	}
+}
+
+// copyFile copies a regular file, syncs the copy and checks it reads back
+// the same as what was read from src
+func copyFile(src, dst string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	want := sha256.New()
+	n, err := io.Copy(out, io.TeeReader(in, want))
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n != info.Size() {
+		return fmt.Errorf("%s changed size while it was copied", src)
+	}
+
+	got, err := hashFile(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want.Sum(nil)) {
+		return fmt.Errorf("copy of %s doesn't match the original", src)
+	}
+
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// hashFile returns the SHA-256 of a file's contents
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// syncDir flushes a directory's entries to disk, so the files created in it
+// survive a crash
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// ChownTree hands path, and everything under it, back to the owner of home
+// with ChownCommand. It's for caches a tool run as root left in the user's
+// home, which otherwise take sudo to clean on every run.
+func (sm *SudoManager) ChownTree(path, home string) error {
+	if err := sm.ensureAuthenticated(); err != nil {
+		return err
+	}
+	args, err := ChownCommand(path, home)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cmd := sm.sudoCommand(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("sudo chown timed out for %s", path)
+		}
+		return fmt.Errorf("sudo chown failed: %w (stderr: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ChownCommand returns the chown command, to run with sudo, that hands path
+// and everything under it to the owner of home. path must be below home once
+// symlinks are resolved. -P never follows symlinks, so none inside the tree
+// can lead chown out of home.
+func ChownCommand(path, home string) ([]string, error) {
+	uid, gid, err := checkOwnershipFix(path, home)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"chown", "-R", "-P", "--", fmt.Sprintf("%d:%d", uid, gid), path}, nil
+}
+
+// checkOwnershipFix refuses to hand over path unless it's below home, after
+// resolving symlinks, and isn't a symlink itself. It returns the owner of
+// home, whom path is handed to.
+func checkOwnershipFix(path, home string) (uid, gid uint32, err error) {
+	if !filepath.IsAbs(path) {
+		return 0, 0, fmt.Errorf("refusing to change the owner of %s: path must be absolute", path)
+	}
+	realHome, err := filepath.EvalSymlinks(home)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return 0, 0, fmt.Errorf("refusing to change the owner of %s: it's a symlink", path)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !strings.HasPrefix(realPath, realHome+string(filepath.Separator)) {
+		return 0, 0, fmt.Errorf("refusing to change the owner of %s: it isn't below %s", path, home)
+	}
+
+	homeInfo, err := os.Stat(realHome)
+	if err != nil {
+		return 0, 0, err
+	}
+	uid, gid, ok := platform.FileOwner(homeInfo)
+	if !ok {
+		return 0, 0, fmt.Errorf("changing owners isn't supported on this platform")
+	}
+	if uid == 0 {
+		return 0, 0, fmt.Errorf("refusing to change the owner of %s: %s belongs to root", path, home)
+	}
+	return uid, gid, nil
+}
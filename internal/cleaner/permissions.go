@@ -8,6 +8,9 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+
+	"github.com/fenilsonani/system-cleanup/internal/security"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
 )
 
 // PermissionManager handles permission checking with enhanced capabilities
@@ -17,11 +20,12 @@ type PermissionManager struct {
 	currentGID   uint32
 	userGroups   []uint32
 	userName     string
+	fs           vfs.FS
 }
 
 // NewPermissionManager creates a new PermissionManager with comprehensive user info
 func NewPermissionManager() *PermissionManager {
-	pm := &PermissionManager{}
+	pm := &PermissionManager{fs: vfs.OS}
 
 	currentUser, err := user.Current()
 	if err == nil {
@@ -99,7 +103,7 @@ func (pm *PermissionManager) AnalyzeFilePermissions(path string) *FilePermission
 	}
 
 	// Use Lstat to not follow symlinks
-	fileInfo, err := os.Lstat(path)
+	fileInfo, err := pm.fs.Lstat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			info.Exists = false
@@ -121,7 +125,7 @@ func (pm *PermissionManager) AnalyzeFilePermissions(path string) *FilePermission
 	// Check for symlink
 	if fileInfo.Mode()&os.ModeSymlink != 0 {
 		info.IsSymlink = true
-		if target, err := os.Readlink(path); err == nil {
+		if target, err := pm.fs.Readlink(path); err == nil {
 			info.SymlinkTarget = target
 		}
 	}
@@ -131,11 +135,8 @@ func (pm *PermissionManager) AnalyzeFilePermissions(path string) *FilePermission
 	info.IsSpecialFile = special
 	info.SpecialFileType = specialType
 
-	// Get syscall stat for UID/GID
-	if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
-		info.FileUID = stat.Uid
-		info.FileGID = stat.Gid
-	}
+	// Get UID/GID
+	info.FileUID, info.FileGID = pm.owner(fileInfo)
 
 	// Check parent directory write permissions
 	parentDir := filepath.Dir(path)
@@ -185,7 +186,7 @@ func (pm *PermissionManager) checkSpecialFile(mode os.FileMode) (bool, string) {
 
 // checkDirectoryWritable checks if the current user can write to a directory
 func (pm *PermissionManager) checkDirectoryWritable(dirPath string) (bool, string) {
-	dirInfo, err := os.Stat(dirPath)
+	dirInfo, err := pm.fs.Stat(dirPath)
 	if err != nil {
 		return false, fmt.Sprintf("cannot access parent directory: %v", err)
 	}
@@ -194,18 +195,14 @@ func (pm *PermissionManager) checkDirectoryWritable(dirPath string) (bool, strin
 		return false, "parent path is not a directory"
 	}
 
-	stat, ok := dirInfo.Sys().(*syscall.Stat_t)
-	if !ok {
-		return false, "cannot get directory ownership info"
-	}
-
+	uid, gid := pm.owner(dirInfo)
 	mode := dirInfo.Mode()
 
 	// Check sticky bit - if set, only owner can delete files
 	stickyBit := mode&os.ModeSticky != 0
 
 	// Owner check
-	if stat.Uid == pm.currentUID {
+	if uid == pm.currentUID {
 		if mode&0200 != 0 { // Owner write permission
 			return true, ""
 		}
@@ -213,8 +210,8 @@ func (pm *PermissionManager) checkDirectoryWritable(dirPath string) (bool, strin
 	}
 
 	// Group check
-	for _, gid := range pm.userGroups {
-		if stat.Gid == gid {
+	for _, userGID := range pm.userGroups {
+		if gid == userGID {
 			if mode&0020 != 0 { // Group write permission
 				if stickyBit {
 					return false, "directory has sticky bit set, only owner can delete"
@@ -237,21 +234,17 @@ func (pm *PermissionManager) checkDirectoryWritable(dirPath string) (bool, strin
 
 // checkFileWritable checks if the current user can write to a file
 func (pm *PermissionManager) checkFileWritable(info os.FileInfo, path string) bool {
-	stat, ok := info.Sys().(*syscall.Stat_t)
-	if !ok {
-		return false
-	}
-
+	uid, gid := pm.owner(info)
 	mode := info.Mode()
 
 	// Owner check
-	if stat.Uid == pm.currentUID {
+	if uid == pm.currentUID {
 		return mode&0200 != 0
 	}
 
 	// Group check
-	for _, gid := range pm.userGroups {
-		if stat.Gid == gid {
+	for _, userGID := range pm.userGroups {
+		if gid == userGID {
 			return mode&0020 != 0
 		}
 	}
@@ -260,6 +253,15 @@ func (pm *PermissionManager) checkFileWritable(info os.FileInfo, path string) bo
 	return mode&0002 != 0
 }
 
+// owner returns the UID and GID owning a file. Files without ownership info,
+// such as those on a simulated file system, belong to the current user.
+func (pm *PermissionManager) owner(info os.FileInfo) (uint32, uint32) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Uid, stat.Gid
+	}
+	return pm.currentUID, pm.currentGID
+}
+
 // CanDelete checks if we have permission to delete a file
 func (pm *PermissionManager) CanDelete(path string) (bool, error) {
 	info := pm.AnalyzeFilePermissions(path)
@@ -287,7 +289,12 @@ func (pm *PermissionManager) RequiresElevation(path string) bool {
 
 // IsSpecialFile checks if a path is a special file (device, socket, pipe, setuid/setgid)
 func IsSpecialFile(path string) (bool, error) {
-	info, err := os.Lstat(path)
+	return isSpecialFile(vfs.OS, path)
+}
+
+// isSpecialFile is IsSpecialFile on fsys
+func isSpecialFile(fsys vfs.FS, path string) (bool, error) {
+	info, err := fsys.Lstat(path)
 	if err != nil {
 		return false, err
 	}
@@ -310,7 +317,7 @@ func IsSpecialFile(path string) (bool, error) {
 		return true, fmt.Errorf("is a setgid file - potentially dangerous")
 	case mode&os.ModeSymlink != 0:
 		// For symlinks, check what they point to
-		target, err := os.Readlink(path)
+		target, err := fsys.Readlink(path)
 		if err != nil {
 			return false, err
 		}
@@ -319,15 +326,15 @@ func IsSpecialFile(path string) (bool, error) {
 			target = filepath.Join(filepath.Dir(path), target)
 		}
 		// Check if target is special (but limit recursion)
-		return isSpecialFileNonRecursive(target)
+		return isSpecialFileNonRecursive(fsys, target)
 	}
 
 	return false, nil
 }
 
 // isSpecialFileNonRecursive checks for special file without following further symlinks
-func isSpecialFileNonRecursive(path string) (bool, error) {
-	info, err := os.Lstat(path)
+func isSpecialFileNonRecursive(fsys vfs.FS, path string) (bool, error) {
+	info, err := fsys.Lstat(path)
 	if err != nil {
 		// If we can't stat the target, it's probably a broken symlink - safe to delete the link
 		return false, nil
@@ -351,8 +358,13 @@ func isSpecialFileNonRecursive(path string) (bool, error) {
 
 // IsSafeToDelete performs comprehensive safety checks on a file
 func IsSafeToDelete(path string) error {
+	return isSafeToDelete(vfs.OS, path)
+}
+
+// isSafeToDelete is IsSafeToDelete on fsys
+func isSafeToDelete(fsys vfs.FS, path string) error {
 	// Check if it's a special file
-	if isSpecial, err := IsSpecialFile(path); isSpecial {
+	if isSpecial, err := isSpecialFile(fsys, path); isSpecial {
 		return fmt.Errorf("refusing to delete special file: %w", err)
 	}
 
@@ -362,8 +374,13 @@ func IsSafeToDelete(path string) error {
 		return fmt.Errorf("path contains directory traversal")
 	}
 
+	// Never delete in locations the OS or a container runtime owns, even as root
+	if err := security.CheckProtectedLocation(cleanPath); err != nil {
+		return err
+	}
+
 	// Verify file exists and get info
-	_, err := os.Lstat(path)
+	_, err := fsys.Lstat(path)
 	if err != nil {
 		return err
 	}
@@ -409,7 +426,7 @@ func (pm *PermissionManager) AnalyzePermissions(files []string, getSizeFunc func
 		var size int64
 		if getSizeFunc != nil {
 			size = getSizeFunc(path)
-		} else if fileInfo, err := os.Stat(path); err == nil {
+		} else if fileInfo, err := pm.fs.Stat(path); err == nil {
 			size = fileInfo.Size()
 		}
 
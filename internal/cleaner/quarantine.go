@@ -0,0 +1,367 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// quarantineManifestFile is the name of the manifest inside each run directory
+const quarantineManifestFile = "manifest.json"
+
+// quarantineHeadroom is the space kept free for run manifests on top of the
+// files copied into the quarantine
+const quarantineHeadroom = 1 << 20
+
+// Quarantine moves files aside instead of deleting them so a run can be undone
+type Quarantine struct {
+	dir string
+}
+
+// QuarantineEntry records where a quarantined file came from
+type QuarantineEntry struct {
+	OriginalPath string    `json:"original_path"`
+	StoredName   string    `json:"stored_name"`
+	Size         int64     `json:"size"`
+	Category     string    `json:"category"`
+	MovedAt      time.Time `json:"moved_at"`
+	Copied       bool      `json:"copied,omitempty"` // Copied from another file system rather than renamed
+}
+
+// QuarantineRun is the set of files quarantined by one cleanup
+type QuarantineRun struct {
+	ID        string            `json:"id"`
+	RunID     string            `json:"run_id,omitempty"` // The cleanup that quarantined the files
+	CreatedAt time.Time         `json:"created_at"`
+	Entries   []QuarantineEntry `json:"entries"`
+
+	dir string
+	mu  sync.Mutex
+}
+
+// NewQuarantine creates a quarantine rooted at dir (~ is expanded)
+func NewQuarantine(dir string) *Quarantine {
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	return &Quarantine{dir: dir}
+}
+
+// Dir returns the quarantine root directory
+func (q *Quarantine) Dir() string {
+	return q.dir
+}
+
+// Begin creates a new, empty run for the cleanup runID. Its directory is
+// named for the time and runID, with -2, -3, ... after it if another run of
+// the same cleanup began that second; an existing run is never reused.
+func (q *Quarantine) Begin(runID string) (*QuarantineRun, error) {
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	now := time.Now()
+	name := now.Format("20060102-150405")
+	if runID != "" {
+		name += "-" + runID
+	}
+	id := name
+	for n := 2; ; n++ {
+		err := os.Mkdir(filepath.Join(q.dir, id), 0700)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		id = fmt.Sprintf("%s-%d", name, n)
+	}
+
+	dir := filepath.Join(q.dir, id)
+	if err := os.Mkdir(filepath.Join(dir, "files"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	run := &QuarantineRun{ID: id, RunID: runID, CreatedAt: now, dir: dir}
+	if err := run.Save(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// preflight checks the quarantine can take files before any are moved: its
+// file system has to be writable, with room for the files that are copied
+// rather than renamed into it because they're on another file system
+func (q *Quarantine) preflight(files []scanner.FileInfo) error {
+	dir := q.dir
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	usage, err := platform.GetDiskUsage(dir)
+	if err != nil {
+		// Begin reports it if the directory really can't be used
+		return nil
+	}
+	if usage.ReadOnly {
+		return fmt.Errorf("quarantine directory %s is on a read-only file system; set quarantine.dir to a writable location", q.dir)
+	}
+	dev, err := platform.DeviceID(dir)
+	if err != nil {
+		return nil
+	}
+
+	var need int64 = quarantineHeadroom
+	devices := make(map[string]uint64)
+	for _, file := range files {
+		parent := filepath.Dir(file.Path)
+		fileDev, ok := devices[parent]
+		if !ok {
+			if fileDev, err = platform.DeviceID(parent); err != nil {
+				continue
+			}
+			devices[parent] = fileDev
+		}
+		if fileDev != dev {
+			need += file.Size
+		}
+	}
+	if uint64(need) > usage.Free {
+		return fmt.Errorf("quarantine directory %s has %s free but needs %s for the files from other disks; free some space or set quarantine.dir elsewhere",
+			q.dir, utils.FormatBytes(int64(usage.Free)), utils.FormatBytes(need))
+	}
+	return nil
+}
+
+// Runs returns all runs, newest first
+func (q *Quarantine) Runs() ([]*QuarantineRun, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read quarantine directory: %w", err)
+	}
+
+	var runs []*QuarantineRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := q.loadRun(entry.Name())
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+// Latest returns the most recent run that still has files to restore
+func (q *Quarantine) Latest() (*QuarantineRun, error) {
+	runs, err := q.Runs()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if len(run.Entries) > 0 {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("nothing to undo")
+}
+
+// loadRun reads a run's manifest
+func (q *Quarantine) loadRun(id string) (*QuarantineRun, error) {
+	dir := filepath.Join(q.dir, id)
+	data, err := os.ReadFile(filepath.Join(dir, quarantineManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine manifest: %w", err)
+	}
+
+	run := &QuarantineRun{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine manifest: %w", err)
+	}
+	run.dir = dir
+	return run, nil
+}
+
+// Move moves a file or directory into the run, reporting whether it had to
+// be copied because it's on another file system
+func (r *QuarantineRun) Move(file scanner.FileInfo) (copied bool, err error) {
+	r.mu.Lock()
+	stored := fmt.Sprintf("%06d-%s", len(r.Entries)+1, filepath.Base(file.Path))
+	r.mu.Unlock()
+
+	dst := filepath.Join(r.dir, "files", stored)
+	copied, err = moveFile(file.Path, dst)
+	if err != nil {
+		// A complete copy is kept even if the original couldn't all be
+		// removed, so whatever was removed can still be restored
+		if _, statErr := os.Lstat(dst); !copied || statErr != nil {
+			return copied, err
+		}
+	}
+
+	r.mu.Lock()
+	r.Entries = append(r.Entries, QuarantineEntry{
+		OriginalPath: file.Path,
+		StoredName:   stored,
+		Size:         file.Size,
+		Category:     file.Category,
+		MovedAt:      time.Now(),
+		Copied:       copied,
+	})
+	r.mu.Unlock()
+
+	return copied, err
+}
+
+// Save writes the run manifest to disk
+func (r *QuarantineRun) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(r.dir, quarantineManifestFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write quarantine manifest: %w", err)
+	}
+	return nil
+}
+
+// TotalSize returns the combined size of the quarantined files
+func (r *QuarantineRun) TotalSize() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var total int64
+	for _, entry := range r.Entries {
+		total += entry.Size
+	}
+	return total
+}
+
+// Purge permanently deletes the quarantined files expired reports true for,
+// across every run. Runs left with no files are removed.
+func (q *Quarantine) Purge(expired func(QuarantineEntry) bool) (purged int, size int64, errs []error) {
+	runs, err := q.Runs()
+	if err != nil {
+		return 0, 0, []error{err}
+	}
+
+	for _, run := range runs {
+		run.mu.Lock()
+		var remaining []QuarantineEntry
+		for _, entry := range run.Entries {
+			if !expired(entry) {
+				remaining = append(remaining, entry)
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(run.dir, "files", entry.StoredName)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to purge %s: %w", entry.OriginalPath, err))
+				remaining = append(remaining, entry)
+				continue
+			}
+			purged++
+			size += entry.Size
+		}
+		changed := len(remaining) != len(run.Entries)
+		run.Entries = remaining
+		run.mu.Unlock()
+
+		// A run with nothing expired is left alone, as a cleanup may be
+		// moving files into it
+		switch {
+		case !changed:
+		case len(remaining) == 0:
+			if err := os.RemoveAll(run.dir); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove quarantine run: %w", err))
+			}
+		default:
+			if err := run.Save(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return purged, size, errs
+}
+
+// Restore moves every file in the run back to its original location. Files
+// whose original path is occupied again are left in the quarantine. The run
+// directory is removed once it is empty.
+func (r *QuarantineRun) Restore() (restored []string, errs []error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var remaining []QuarantineEntry
+	// Restore in reverse so nested paths come back in the order they left
+	for i := len(r.Entries) - 1; i >= 0; i-- {
+		entry := r.Entries[i]
+
+		if _, err := os.Lstat(entry.OriginalPath); err == nil {
+			errs = append(errs, fmt.Errorf("%s already exists, left in quarantine", entry.OriginalPath))
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0755); err != nil {
+			errs = append(errs, fmt.Errorf("failed to recreate %s: %w", filepath.Dir(entry.OriginalPath), err))
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		if _, err := moveFile(filepath.Join(r.dir, "files", entry.StoredName), entry.OriginalPath); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", entry.OriginalPath, err))
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		restored = append(restored, entry.OriginalPath)
+	}
+
+	// Keep the original order for whatever is left
+	for i, j := 0, len(remaining)-1; i < j; i, j = i+1, j-1 {
+		remaining[i], remaining[j] = remaining[j], remaining[i]
+	}
+	r.Entries = remaining
+
+	if len(r.Entries) == 0 {
+		if err := os.RemoveAll(r.dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove quarantine run: %w", err))
+		}
+		return restored, errs
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(r.dir, quarantineManifestFile), data, 0600)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to update quarantine manifest: %w", err))
+	}
+
+	return restored, errs
+}
@@ -0,0 +1,215 @@
+package cleaner
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// Explanations for a file system whose free space didn't grow by what was
+// deleted from it
+const (
+	NoteLessFreed   = "Less space was freed than was deleted. Some files may have other hard links, share their blocks with clones or snapshots, or still be held open by a running process."
+	NoteMoreFreed   = "More space was freed than was deleted. Other programs probably freed space during the cleanup."
+	NoteQuarantined = "Files were moved to the quarantine on the same disk. The space is freed when the quarantine is purged."
+)
+
+// minDiscrepancy is the smallest difference between deleted and freed space
+// that gets flagged; other programs writing logs and caches cause less
+const minDiscrepancy = 1 << 20
+
+// FilesystemReclaim compares the sizes of the files deleted from one file
+// system with how much its free space actually grew
+type FilesystemReclaim struct {
+	Device          uint64 `json:"-"`
+	Path            string `json:"path"`         // Directory the free space was measured at
+	DeletedSize     int64  `json:"deleted_size"` // Sum of the sizes of the files deleted
+	FreeBefore      uint64 `json:"free_before"`
+	FreeAfter       uint64 `json:"free_after"`
+	Files           uint64 `json:"files"` // Inodes on the file system; 0 if it has no fixed number
+	FreeFilesBefore uint64 `json:"free_files_before"`
+	FreeFilesAfter  uint64 `json:"free_files_after"`
+	Note            string `json:"note,omitempty"` // Why the two differ, if they differ a lot
+
+	quarantined bool
+}
+
+// Reclaimed returns how much the free space grew, negative if it shrank
+func (f *FilesystemReclaim) Reclaimed() int64 {
+	return int64(f.FreeAfter) - int64(f.FreeBefore)
+}
+
+// ReclaimedFiles returns how many inodes were freed, negative if fewer are
+// free than before
+func (f *FilesystemReclaim) ReclaimedFiles() int64 {
+	return int64(f.FreeFilesAfter) - int64(f.FreeFilesBefore)
+}
+
+// explain sets Note if the free space grew by much more or much less than
+// was deleted
+func (f *FilesystemReclaim) explain() {
+	diff := f.DeletedSize - f.Reclaimed()
+	threshold := max(f.DeletedSize/4, minDiscrepancy)
+	switch {
+	case diff > threshold && f.quarantined:
+		f.Note = NoteQuarantined
+	case diff > threshold:
+		f.Note = NoteLessFreed
+	case -diff > threshold:
+		f.Note = NoteMoreFreed
+	default:
+		f.Note = ""
+	}
+}
+
+// ReclaimedSize returns how much the free space grew across the file systems
+// cleaned. It's 0 for a dry run.
+func (r *CleanResult) ReclaimedSize() int64 {
+	var total int64
+	for i := range r.Filesystems {
+		total += r.Filesystems[i].Reclaimed()
+	}
+	return total
+}
+
+// ReclaimedFiles returns how many inodes were freed across the file systems
+// cleaned that have a fixed number of them
+func (r *CleanResult) ReclaimedFiles() int64 {
+	var total int64
+	for i := range r.Filesystems {
+		if r.Filesystems[i].Files > 0 {
+			total += r.Filesystems[i].ReclaimedFiles()
+		}
+	}
+	return total
+}
+
+// mergeFilesystems folds the file systems measured by a retry into r. The
+// free space before is kept from the first clean and the free space after is
+// taken from the retry.
+func (r *CleanResult) mergeFilesystems(other []FilesystemReclaim) {
+	for _, fs := range other {
+		merged := false
+		for i := range r.Filesystems {
+			if r.Filesystems[i].Device == fs.Device {
+				r.Filesystems[i].DeletedSize += fs.DeletedSize
+				r.Filesystems[i].FreeAfter = fs.FreeAfter
+				r.Filesystems[i].FreeFilesAfter = fs.FreeFilesAfter
+				r.Filesystems[i].quarantined = r.Filesystems[i].quarantined || fs.quarantined
+				r.Filesystems[i].explain()
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			r.Filesystems = append(r.Filesystems, fs)
+		}
+	}
+}
+
+// SkippedReadOnly starts the reason given for files skipped because their file
+// system is mounted read-only
+const SkippedReadOnly = "On a read-only file system"
+
+// spaceMeter measures the free space on the file systems holding a set of
+// files, before and after they're deleted
+type spaceMeter struct {
+	devices     map[string]uint64 // File path to device
+	filesystems []FilesystemReclaim
+	readOnly    map[uint64]string // Read-only file systems, to the directory they were measured at
+}
+
+// measureSpace records the free space and inodes on each file system holding
+// files
+func measureSpace(files []scanner.FileInfo) *spaceMeter {
+	m := &spaceMeter{devices: make(map[string]uint64, len(files)), readOnly: make(map[uint64]string)}
+	dirs := make(map[string]uint64)
+	seen := make(map[uint64]bool)
+
+	for _, file := range files {
+		// The parent is on the same file system and still exists afterwards
+		dir := filepath.Dir(file.Path)
+		dev, ok := dirs[dir]
+		if !ok {
+			id, err := platform.DeviceID(dir)
+			if err != nil {
+				continue
+			}
+			dev, dirs[dir] = id, id
+		}
+		m.devices[file.Path] = dev
+
+		if seen[dev] {
+			continue
+		}
+		seen[dev] = true
+		usage, err := platform.GetDiskUsage(dir)
+		if err != nil {
+			continue
+		}
+		if usage.ReadOnly {
+			m.readOnly[dev] = dir
+			continue
+		}
+		m.filesystems = append(m.filesystems, FilesystemReclaim{
+			Device:          dev,
+			Path:            dir,
+			FreeBefore:      usage.Free,
+			Files:           usage.Files,
+			FreeFilesBefore: usage.FreeFiles,
+		})
+	}
+	return m
+}
+
+// skipReadOnly leaves out the files on read-only file systems, recording them
+// as skipped. It returns the files left to clean.
+func (m *spaceMeter) skipReadOnly(files []scanner.FileInfo, result *CleanResult) []scanner.FileInfo {
+	if len(m.readOnly) == 0 {
+		return files
+	}
+	kept := files[:0:0]
+	for _, file := range files {
+		dev, measured := m.devices[file.Path]
+		dir, ok := m.readOnly[dev]
+		if !measured || !ok {
+			kept = append(kept, file)
+			continue
+		}
+		if !slices.Contains(result.ReadOnly, dir) {
+			result.ReadOnly = append(result.ReadOnly, dir)
+		}
+		result.SkippedFiles = append(result.SkippedFiles, file.Path)
+		result.SkippedReason[file.Path] = fmt.Sprintf("%s (%s)", SkippedReadOnly, dir)
+	}
+	return kept
+}
+
+// finish measures the free space again and compares it with the sizes of the
+// files result deleted
+func (m *spaceMeter) finish(result *CleanResult, sizes map[string]scanner.FileInfo, quarantined bool) []FilesystemReclaim {
+	deleted := make(map[uint64]int64)
+	for _, path := range result.DeletedFiles {
+		if dev, ok := m.devices[path]; ok {
+			deleted[dev] += sizes[path].Size
+		}
+	}
+
+	var filesystems []FilesystemReclaim
+	for _, fs := range m.filesystems {
+		usage, err := platform.GetDiskUsage(fs.Path)
+		if err != nil {
+			continue
+		}
+		fs.FreeAfter = usage.Free
+		fs.FreeFilesAfter = usage.FreeFiles
+		fs.DeletedSize = deleted[fs.Device]
+		fs.quarantined = quarantined
+		fs.explain()
+		filesystems = append(filesystems, fs)
+	}
+	return filesystems
+}
@@ -0,0 +1,138 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+)
+
+// GarbageCollectSteps returns the commands that garbage collect the local
+// registry and BuildKit cache: the registry's own garbage-collect, which
+// removes the blobs no manifest reaches, and buildctl prune, which removes
+// the build cache unused for buildkit_keep_duration. With dryRun the
+// registry only reports what it would remove; buildctl has no such mode, so
+// its prune is left out.
+func GarbageCollectSteps(cfg *config.RegistryConfig, dryRun bool) [][]string {
+	var steps [][]string
+	if cfg.ConfigFile != "" {
+		var gc []string
+		if cfg.Container != "" {
+			gc = []string{"docker", "exec", cfg.Container}
+		}
+		gc = append(gc, "registry", "garbage-collect")
+		if cfg.DeleteUntagged {
+			gc = append(gc, "--delete-untagged")
+		}
+		if dryRun {
+			gc = append(gc, "--dry-run")
+		}
+		steps = append(steps, append(gc, cfg.ConfigFile))
+	}
+
+	if cfg.BuildKit && !dryRun {
+		prune := append(buildctl(cfg), "prune")
+		if cfg.BuildKitKeep != "" {
+			prune = append(prune, "--keep-duration", cfg.BuildKitKeep)
+		}
+		steps = append(steps, prune)
+	}
+	return steps
+}
+
+// GarbageCollect runs GarbageCollectSteps, writing their output to out. The
+// registry should be stopped or in read-only mode while it's collected, or
+// a push racing the collection can lose layers. A step that fails doesn't
+// stop the others.
+func GarbageCollect(ctx context.Context, cfg *config.RegistryConfig, dryRun bool, out io.Writer) error {
+	var errs []error
+	for _, step := range GarbageCollectSteps(cfg, dryRun) {
+		if _, err := exec.LookPath(step[0]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to garbage collect: %s isn't installed", step[0]))
+			continue
+		}
+		fmt.Fprintf(out, "$ %s\n", strings.Join(step, " "))
+		cmd := exec.CommandContext(ctx, step[0], step[1:]...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to garbage collect: %s: %w", strings.Join(step, " "), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BuildKitRecord is a record in a BuildKit daemon's cache
+type BuildKitRecord struct {
+	ID          string
+	Type        string // regular, source.local, exec.cachemount, ...
+	Description string
+	Size        int64
+	InUse       bool // Held by a running build; a prune skips it
+	Shared      bool // Also used by other records
+	LastUsed    time.Time
+}
+
+// BuildKitCache lists the cache records of the BuildKit daemon cfg points
+// to. It fails if buildctl isn't installed or the daemon isn't running.
+func BuildKitCache(ctx context.Context, cfg *config.RegistryConfig) ([]BuildKitRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	args := append(buildctl(cfg)[1:], "du", "--format", "{{json .}}")
+	out, err := exec.CommandContext(ctx, "buildctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query buildkit: %w", err)
+	}
+	return parseBuildKitDU(out)
+}
+
+// parseBuildKitDU parses "buildctl du --format '{{json .}}'", a JSON array
+// of buildkit's usage records
+func parseBuildKitDU(out []byte) ([]BuildKitRecord, error) {
+	var rows []struct {
+		ID          string
+		InUse       bool
+		Size        int64
+		CreatedAt   time.Time
+		LastUsedAt  *time.Time
+		Description string
+		RecordType  string
+		Shared      bool
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse buildctl du: %w", err)
+	}
+
+	records := make([]BuildKitRecord, 0, len(rows))
+	for _, row := range rows {
+		record := BuildKitRecord{
+			ID:          row.ID,
+			Type:        row.RecordType,
+			Description: row.Description,
+			Size:        row.Size,
+			InUse:       row.InUse,
+			Shared:      row.Shared,
+			LastUsed:    row.CreatedAt,
+		}
+		if row.LastUsedAt != nil {
+			record.LastUsed = *row.LastUsedAt
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// buildctl is the buildctl command reaching cfg's BuildKit daemon
+func buildctl(cfg *config.RegistryConfig) []string {
+	if cfg.BuildKitAddr != "" {
+		return []string{"buildctl", "--addr", cfg.BuildKitAddr}
+	}
+	return []string{"buildctl"}
+}
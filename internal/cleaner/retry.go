@@ -0,0 +1,149 @@
+package cleaner
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// retryBackoff is the delay before the first retry round; it doubles each round
+const retryBackoff = 500 * time.Millisecond
+
+// retryQueue collects deletions that failed because the file was busy, so
+// they can be retried once the rest of the cleanup is done
+type retryQueue struct {
+	files []scanner.FileInfo
+	errs  map[string]*DeletionError // latest error per path
+
+	attempts int
+	backoff  time.Duration
+	sleep    func(time.Duration)
+	wait     func(paths []string) // waits for the processes holding paths, nil to skip
+}
+
+// newRetryQueue creates a queue configured from the cleaner's retry settings
+func (c *Cleaner) newRetryQueue() *retryQueue {
+	q := &retryQueue{
+		errs:     make(map[string]*DeletionError),
+		attempts: c.config.Retry.MaxAttempts,
+		backoff:  retryBackoff,
+		sleep:    time.Sleep,
+	}
+	if c.config.Retry.WaitForProcess {
+		maxWait, err := time.ParseDuration(c.config.Retry.MaxWait)
+		if err != nil || maxWait <= 0 {
+			maxWait = 30 * time.Second
+		}
+		q.wait = func(paths []string) { waitForRelease(paths, maxWait) }
+	}
+	return q
+}
+
+// add queues a file whose deletion failed with a retryable error
+func (q *retryQueue) add(file scanner.FileInfo, err *DeletionError) {
+	q.files = append(q.files, file)
+	q.errs[file.Path] = err
+}
+
+// queued reports whether path is waiting to be retried
+func (q *retryQueue) queued(path string) bool {
+	_, ok := q.errs[path]
+	return ok
+}
+
+// process retries the queued files with exponential backoff until they're
+// all gone or the attempts run out. It returns the errors for files that
+// still couldn't be deleted.
+func (q *retryQueue) process(del func(file scanner.FileInfo) *DeletionError) []*DeletionError {
+	var failed []*DeletionError
+	delay := q.backoff
+
+	for attempt := 0; attempt < q.attempts && len(q.files) > 0; attempt++ {
+		if q.wait != nil {
+			paths := make([]string, len(q.files))
+			for i, file := range q.files {
+				paths[i] = file.Path
+			}
+			q.wait(paths)
+		}
+		q.sleep(delay)
+		delay *= 2
+
+		var busy []scanner.FileInfo
+		for _, file := range q.files {
+			err := del(file)
+			switch {
+			case err == nil:
+				delete(q.errs, file.Path)
+			case err.Retryable:
+				q.errs[file.Path] = err
+				busy = append(busy, file)
+			default:
+				delete(q.errs, file.Path)
+				failed = append(failed, err)
+			}
+		}
+		q.files = busy
+	}
+
+	for _, file := range q.files {
+		failed = append(failed, q.errs[file.Path])
+	}
+	q.files = nil
+	return failed
+}
+
+// waitForRelease waits up to maxWait for the processes that have any of
+// paths open to exit. It returns right away if lsof isn't available.
+func waitForRelease(paths []string, maxWait time.Duration) {
+	pids := holdingProcesses(paths)
+	deadline := time.Now().Add(maxWait)
+
+	for len(pids) > 0 && time.Now().Before(deadline) {
+		time.Sleep(250 * time.Millisecond)
+
+		running := pids[:0]
+		for _, pid := range pids {
+			// Signal 0 only checks the process still exists
+			if syscall.Kill(pid, 0) == nil {
+				running = append(running, pid)
+			}
+		}
+		pids = running
+	}
+}
+
+// holdingProcesses lists the PIDs that have any of paths open, using lsof
+func holdingProcesses(paths []string) []int {
+	if _, err := exec.LookPath("lsof"); err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// lsof exits non-zero when some paths aren't open, so ignore the error
+	// and use whatever it printed
+	out, _ := exec.CommandContext(ctx, "lsof", append([]string{"-t", "--"}, paths...)...).Output()
+	return parsePIDs(string(out))
+}
+
+// parsePIDs parses lsof -t output, one PID per line
+func parsePIDs(out string) []int {
+	seen := make(map[int]bool)
+	var pids []int
+	for _, line := range strings.Split(out, "\n") {
+		pid, err := strconv.Atoi(strings.TrimSpace(line))
+		if err != nil || pid <= 0 || seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		pids = append(pids, pid)
+	}
+	return pids
+}
@@ -0,0 +1,158 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// scriptPrelude defines the remove helper used for files that don't need sudo
+const scriptPrelude = `set -u
+
+# Set TIDYUP_TRASH=1 to move files to the trash (needs a "trash" command)
+# instead of deleting them
+remove() {
+	if [ "${TIDYUP_TRASH:-0}" = 1 ] && command -v trash >/dev/null 2>&1; then
+		trash -- "$1"
+	else
+		rm -rf -- "$1"
+	fi
+}
+`
+
+// WriteScript writes a shell script that deletes the files in scanResult, for
+// people who want to review deletions and run them themselves. Commands are
+// grouped by whether they need sudo, then by category. Files the cleaner
+// would skip are listed as comments.
+func (c *Cleaner) WriteScript(w io.Writer, scanResult *scanner.ScanResult) error {
+	if err := scanResult.Load(); err != nil {
+		return err
+	}
+	report := c.GetPermissionReport(scanResult)
+
+	fileMap := make(map[string]scanner.FileInfo, len(scanResult.Files))
+	for _, file := range scanResult.Files {
+		fileMap[file.Path] = file
+	}
+
+	// Files that fail the safety checks, like those in protected locations,
+	// files never_delete_extensions protects and sudo files from categories
+	// that may not use sudo are skipped, as in Clean
+	var normalFiles, sudoFiles []string
+	skipped := make(map[string]string)
+	for _, path := range report.NormalFiles {
+		if reason, ok := c.scriptSkipReason(path, fileMap[path].Category); ok {
+			skipped[path] = reason
+			continue
+		}
+		normalFiles = append(normalFiles, path)
+	}
+	for _, path := range report.RequiresSudo {
+		if reason, ok := c.scriptSkipReason(path, fileMap[path].Category); ok {
+			skipped[path] = reason
+			continue
+		}
+		if category := fileMap[path].Category; !c.config.Sudo.AllowsCategory(category) {
+			skipped[path] = fmt.Sprintf("elevated permissions not allowed for category %q", category)
+			continue
+		}
+		sudoFiles = append(sudoFiles, path)
+	}
+	for path, err := range report.InaccessibleFiles {
+		skipped[path] = fmt.Sprintf("inaccessible: %v", err)
+	}
+	for path, kind := range report.SpecialFiles {
+		skipped[path] = fmt.Sprintf("special file (%s)", kind)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#!/bin/sh")
+	fmt.Fprintf(bw, "# Deletion script generated by tidyup on %s from a dry run.\n", time.Now().Format("2006-01-02 15:04"))
+	fmt.Fprintf(bw, "# %d files, %s. Review every line before running it.\n", len(normalFiles)+len(sudoFiles), utils.FormatBytes(sizeOf(normalFiles, fileMap)+sizeOf(sudoFiles, fileMap)))
+	fmt.Fprintln(bw)
+	fmt.Fprint(bw, scriptPrelude)
+
+	writeScriptSection(bw, "Files you can delete as yourself", "remove ", normalFiles, fileMap)
+	writeScriptSection(bw, "Files that need elevated permissions", "sudo rm -rf -- ", sudoFiles, fileMap)
+
+	writeSkipped(bw, skipped)
+
+	return bw.Flush()
+}
+
+// scriptSkipReason says why the script leaves out path, found in category,
+// whoever runs it
+func (c *Cleaner) scriptSkipReason(path, category string) (string, bool) {
+	if err := isSafeToDelete(c.fs, path); err != nil {
+		return fmt.Sprintf("safety check failed: %v", err), true
+	}
+	return c.neverDeleteReason(path, category)
+}
+
+// writeSkipped lists the files the script leaves out, and why, as comments.
+// Reasons can repeat the path in an error message, so both are quoted: a
+// newline in a file name can't end the comment and start a command.
+func writeSkipped(w io.Writer, skipped map[string]string) {
+	if len(skipped) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(skipped))
+	for path := range skipped {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(w, "\n# ==== Skipped: %d files ====\n", len(paths))
+	for _, path := range paths {
+		fmt.Fprintf(w, "# %s: %s\n", strconv.Quote(path), strconv.Quote(skipped[path]))
+	}
+}
+
+// writeScriptSection writes one group of delete commands, by category
+func writeScriptSection(w io.Writer, title, command string, paths []string, fileMap map[string]scanner.FileInfo) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n# ==== %s: %d files, %s ====\n", title, len(paths), utils.FormatBytes(sizeOf(paths, fileMap)))
+
+	byCategory := make(map[string][]string)
+	for _, path := range paths {
+		category := fileMap[path].Category
+		byCategory[category] = append(byCategory[category], path)
+	}
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		group := byCategory[category]
+		sort.Strings(group)
+		fmt.Fprintf(w, "\n# %s\n", category)
+		for _, path := range group {
+			fmt.Fprintf(w, "%s%s  # %s\n", command, shellQuote(path), utils.FormatBytes(fileMap[path].Size))
+		}
+	}
+}
+
+// sizeOf totals the sizes of paths
+func sizeOf(paths []string, fileMap map[string]scanner.FileInfo) int64 {
+	var total int64
+	for _, path := range paths {
+		total += fileMap[path].Size
+	}
+	return total
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,136 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// snapshotVersion is the format of snapshot files; older ones are refused
+const snapshotVersion = 1
+
+// Snapshot is a saved scan, written by scan --save-state, that clean --state
+// cleans from instead of scanning again, so exactly the files that were
+// reviewed are deleted. Each file's device, inode and modification time are
+// recorded so one replaced or changed since is left alone.
+type Snapshot struct {
+	Version   int            `json:"version"`
+	RunID     string         `json:"run_id,omitempty"` // The scan that took the snapshot
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile is a scan result with the identity of the file it found
+type SnapshotFile struct {
+	scanner.FileInfo
+	Dev     uint64    `json:"dev"`
+	Inode   uint64    `json:"inode"`
+	Changed time.Time `json:"changed"` // Modification time of the path itself, not of what's below it
+}
+
+// NewSnapshot records the files of result as they are now. Files that no
+// longer exist are left out.
+func NewSnapshot(runID string, result *scanner.ScanResult) (*Snapshot, error) {
+	snapshot := &Snapshot{Version: snapshotVersion, RunID: runID, CreatedAt: time.Now()}
+	err := result.Each(func(file scanner.FileInfo) error {
+		info, err := os.Lstat(file.Path)
+		if err != nil {
+			return nil
+		}
+		dev, ino, err := platform.FileID(file.Path)
+		if err != nil {
+			return nil
+		}
+		snapshot.Files = append(snapshot.Files, SnapshotFile{FileInfo: file, Dev: dev, Inode: ino, Changed: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to path (~ is expanded), readable only by its owner
+func (s *Snapshot) Save(path string) error {
+	path = expandHome(path)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create scan state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write scan state: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads the snapshot at path (~ is expanded). It's refused once
+// it's older than maxAge, as the files it lists may have been reused since;
+// a maxAge of 0 never expires it.
+func LoadSnapshot(path string, maxAge time.Duration) (*Snapshot, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan state: %w", err)
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse scan state: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("scan state %s is from another version of tidyup; scan again with --save-state", path)
+	}
+	if age := time.Since(snapshot.CreatedAt); maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("scan state %s is %s old, more than clean.state_max_age (%s); scan again with --save-state",
+			path, age.Round(time.Minute), maxAge)
+	}
+	return snapshot, nil
+}
+
+// Verify returns the files of the snapshot that are still the ones it
+// recorded. Files that were replaced, or files (not directories) modified
+// since, are returned in changed with the reason. Files that are gone are
+// dropped.
+func (s *Snapshot) Verify() (result *scanner.ScanResult, changed map[string]string) {
+	result = &scanner.ScanResult{Files: []scanner.FileInfo{}}
+	changed = make(map[string]string)
+	for _, file := range s.Files {
+		info, err := os.Lstat(file.Path)
+		if err != nil {
+			continue
+		}
+		dev, ino, err := platform.FileID(file.Path)
+		switch {
+		case err != nil:
+			changed[file.Path] = fmt.Sprintf("couldn't be checked: %v", err)
+			continue
+		case dev != file.Dev || ino != file.Inode:
+			changed[file.Path] = "replaced since the scan"
+			continue
+		case !info.IsDir() && !info.ModTime().Equal(file.Changed):
+			changed[file.Path] = "modified since the scan"
+			continue
+		}
+		result.Files = append(result.Files, file.FileInfo)
+		result.TotalSize += file.Size
+		result.TotalCount++
+	}
+	return result, changed
+}
+
+// expandHome expands a leading ~/ in path
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
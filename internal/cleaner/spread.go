@@ -0,0 +1,120 @@
+package cleaner
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// minPaceWait is the shortest wait a pacer bothers with
+const minPaceWait = 50 * time.Millisecond
+
+// sudoPacedBatch is how many files are deleted with sudo between waits when a
+// cleanup is spread
+const sudoPacedBatch = 50
+
+// SetSpread spreads each cleanup's deletions over window, at low disk and CPU
+// priority, so a large one doesn't cause latency spikes. 0 deletes as fast as
+// possible.
+func (c *Cleaner) SetSpread(window time.Duration) {
+	c.spread = window
+}
+
+// cleanSpread runs clean on a thread of its own at low priority. The thread
+// exits with it, since its priority can't be raised back.
+func (c *Cleaner) cleanSpread(clean func() (*CleanResult, error)) (*CleanResult, error) {
+	type outcome struct {
+		result *CleanResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		runtime.LockOSThread()
+		if c.native() {
+			// Where this isn't possible, pacing alone still spreads the load
+			platform.LowerThreadPriority()
+		}
+		result, err := clean()
+		done <- outcome{result, err}
+	}()
+	o := <-done
+	return o.result, o.err
+}
+
+// pacer spreads deletions over a time window. It's ahead of schedule when it
+// has done more of the files, by number or by size, than the share of the
+// window that's gone by, and waits for the schedule to catch up.
+type pacer struct {
+	window     time.Duration
+	start      time.Time
+	totalFiles int
+	totalSize  int64
+	files      int
+	size       int64
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// newPacer returns a pacer spreading the deletion of paths over window, or
+// nil if there's no window
+func newPacer(window time.Duration, paths []string, files map[string]scanner.FileInfo) *pacer {
+	if window <= 0 || len(paths) == 0 {
+		return nil
+	}
+	p := &pacer{
+		window:     window,
+		start:      time.Now(),
+		totalFiles: len(paths),
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+	for _, path := range paths {
+		p.totalSize += files[path].Size
+	}
+	return p
+}
+
+// wait counts n more files of size bytes as done, then waits until the
+// schedule catches up. It returns early with ctx's error if ctx is cancelled.
+// A nil pacer never waits.
+func (p *pacer) wait(ctx context.Context, n int, size int64) error {
+	if p == nil {
+		return nil
+	}
+	p.files += n
+	p.size += size
+	if p.files >= p.totalFiles {
+		// Nothing left to hold back
+		return nil
+	}
+	if ahead := p.ahead(); ahead >= minPaceWait {
+		return p.sleep(ctx, ahead)
+	}
+	return nil
+}
+
+// ahead returns how far ahead of schedule the files done so far are
+func (p *pacer) ahead() time.Duration {
+	done := float64(p.files) / float64(p.totalFiles)
+	if p.totalSize > 0 {
+		done = max(done, float64(p.size)/float64(p.totalSize))
+	}
+	due := time.Duration(done * float64(p.window))
+	return due - p.now().Sub(p.start)
+}
+
+// sleepContext sleeps for d, or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -21,6 +21,7 @@ import (
 // SudoManager handles sudo operations for file deletion with enhanced reliability
 type SudoManager struct {
 	password        []byte
+	tokenMode       bool // sudo's cached timestamp authorizes commands; no password is kept
 	authenticated   bool
 	available       bool
 	sessionExpiry   time.Time
@@ -110,20 +111,17 @@ func (sm *SudoManager) PromptForPassword() error {
 	}
 
 	// Check if we already have a valid sudo session (passwordless or cached)
-	if sm.CheckSession() {
-		sm.mu.Lock()
-		sm.authenticated = true
-		sm.sessionExpiry = time.Now().Add(5 * time.Minute)
-		sm.mu.Unlock()
+	if sm.UseSession() == nil {
 		return nil
 	}
 
-	fmt.Print("\n Some files require elevated permissions.\n")
-	fmt.Print("Please enter your password (or press Ctrl+C to skip): ")
+	// Prompts go to stderr so they don't mix with output meant for scripts
+	fmt.Fprint(os.Stderr, "\n Some files require elevated permissions.\n")
+	fmt.Fprint(os.Stderr, "Please enter your password (or press Ctrl+C to skip): ")
 
 	// Read password without echoing
 	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println() // Print newline after password input
+	fmt.Fprintln(os.Stderr) // Print newline after password input
 
 	if err != nil {
 		return fmt.Errorf("failed to read password: %w", err)
@@ -147,9 +145,17 @@ func (sm *SudoManager) PromptForPassword() error {
 			time.Sleep(time.Duration(attempt*100) * time.Millisecond)
 			continue
 		}
-		// Success
+		// Success. If sudo's timestamp now authorizes commands on its own, drop
+		// the password right away; only keep it when the timestamp is disabled
+		// (timestamp_timeout=0).
+		tokenMode := sm.CheckTimestamp()
 		sm.mu.Lock()
-		sm.password = passwordBytes
+		if tokenMode {
+			clearBytes(passwordBytes)
+		} else {
+			sm.password = passwordBytes
+		}
+		sm.tokenMode = tokenMode
 		sm.authenticated = true
 		sm.sessionExpiry = time.Now().Add(5 * time.Minute)
 		sm.mu.Unlock()
@@ -160,6 +166,23 @@ func (sm *SudoManager) PromptForPassword() error {
 	return fmt.Errorf("authentication failed after 3 attempts: %w", lastErr)
 }
 
+// UseSession authenticates on an existing sudo session or a rule that needs
+// no password, without ever prompting
+func (sm *SudoManager) UseSession() error {
+	if !sm.available {
+		return fmt.Errorf("sudo is not available on this system")
+	}
+	if !sm.CheckSession() {
+		return fmt.Errorf("sudo needs a password")
+	}
+	sm.mu.Lock()
+	sm.tokenMode = true
+	sm.authenticated = true
+	sm.sessionExpiry = time.Now().Add(5 * time.Minute)
+	sm.mu.Unlock()
+	return nil
+}
+
 // validatePassword validates the sudo password by running a test command
 func (sm *SudoManager) validatePassword(password []byte) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -202,6 +225,67 @@ func (sm *SudoManager) CheckSession() bool {
 	return cmd.Run() == nil
 }
 
+// CheckTimestamp checks whether sudo's cached credentials are usable
+// without a password, which fails when timestamp_timeout=0
+func (sm *SudoManager) CheckTimestamp() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sudo", "-n", "-v")
+	return cmd.Run() == nil
+}
+
+// TokenMode reports whether commands run on sudo's cached credentials
+// rather than a password held in memory
+func (sm *SudoManager) TokenMode() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.tokenMode
+}
+
+// sudoCommand builds a sudo command. In token mode it runs non-interactively
+// on the cached credentials; otherwise the password is fed on stdin.
+func (sm *SudoManager) sudoCommand(ctx context.Context, args ...string) *exec.Cmd {
+	sm.mu.RLock()
+	tokenMode := sm.tokenMode
+	var passwordInput []byte
+	if !tokenMode {
+		passwordInput = append(append([]byte(nil), sm.password...), '\n')
+	}
+	sm.mu.RUnlock()
+
+	if tokenMode {
+		return exec.CommandContext(ctx, "sudo", append([]string{"-n"}, args...)...)
+	}
+	cmd := exec.CommandContext(ctx, "sudo", append([]string{"-S"}, args...)...)
+	cmd.Stdin = bytes.NewReader(passwordInput)
+	return cmd
+}
+
+// StartKeepAlive refreshes the sudo session every interval until the
+// returned stop function is called
+func (sm *SudoManager) StartKeepAlive(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := sm.KeepAlive(); err != nil {
+					// Session expired, can't continue
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
 // ensureAuthenticated checks if the session is still valid and refreshes if needed
 func (sm *SudoManager) ensureAuthenticated() error {
 	sm.mu.RLock()
@@ -328,17 +412,11 @@ func (sm *SudoManager) deleteWithSudo(path string) error {
 	var cmd *exec.Cmd
 	if info.IsDir() {
 		// Use rm -rf for directories
-		cmd = exec.CommandContext(ctx, "sudo", "-S", "rm", "-rf", "--", path)
+		cmd = sm.sudoCommand(ctx, "rm", "-rf", "--", path)
 	} else {
-		cmd = exec.CommandContext(ctx, "sudo", "-S", "rm", "-f", "--", path)
+		cmd = sm.sudoCommand(ctx, "rm", "-f", "--", path)
 	}
 
-	sm.mu.RLock()
-	passwordInput := append([]byte(nil), sm.password...)
-	sm.mu.RUnlock()
-	passwordInput = append(passwordInput, '\n')
-	cmd.Stdin = bytes.NewReader(passwordInput)
-
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -367,13 +445,7 @@ func (sm *SudoManager) deleteWithRenameFirst(path string) error {
 	defer cancel()
 
 	// First rename the file
-	sm.mu.RLock()
-	passwordInput := append([]byte(nil), sm.password...)
-	sm.mu.RUnlock()
-	passwordInput = append(passwordInput, '\n')
-
-	renameCmd := exec.CommandContext(ctx, "sudo", "-S", "mv", "-f", "--", path, tempName)
-	renameCmd.Stdin = bytes.NewReader(passwordInput)
+	renameCmd := sm.sudoCommand(ctx, "mv", "-f", "--", path, tempName)
 
 	var renameStderr bytes.Buffer
 	renameCmd.Stderr = &renameStderr
@@ -383,24 +455,14 @@ func (sm *SudoManager) deleteWithRenameFirst(path string) error {
 	}
 
 	// Now delete the renamed file
-	deleteCmd := exec.CommandContext(ctx, "sudo", "-S", "rm", "-f", "--", tempName)
-	sm.mu.RLock()
-	passwordInput2 := append([]byte(nil), sm.password...)
-	sm.mu.RUnlock()
-	passwordInput2 = append(passwordInput2, '\n')
-	deleteCmd.Stdin = bytes.NewReader(passwordInput2)
+	deleteCmd := sm.sudoCommand(ctx, "rm", "-f", "--", tempName)
 
 	var deleteStderr bytes.Buffer
 	deleteCmd.Stderr = &deleteStderr
 
 	if err := deleteCmd.Run(); err != nil {
 		// Try to restore original name on failure
-		restoreCmd := exec.CommandContext(ctx, "sudo", "-S", "mv", "-f", "--", tempName, path)
-		sm.mu.RLock()
-		passwordInput3 := append([]byte(nil), sm.password...)
-		sm.mu.RUnlock()
-		passwordInput3 = append(passwordInput3, '\n')
-		restoreCmd.Stdin = bytes.NewReader(passwordInput3)
+		restoreCmd := sm.sudoCommand(ctx, "mv", "-f", "--", tempName, path)
 		restoreCmd.Run() // Best effort restore
 
 		return fmt.Errorf("delete after rename failed: %w", err)
@@ -510,16 +572,10 @@ func (sm *SudoManager) deleteBatch(paths []string) (succeeded []string, failed m
 	defer cancel()
 
 	// Use -rf to handle both files and directories (node_modules, venv, etc.)
-	args := []string{"-S", "rm", "-rf", "--"}
+	args := []string{"rm", "-rf", "--"}
 	args = append(args, validPaths...)
 
-	cmd := exec.CommandContext(ctx, "sudo", args...)
-
-	sm.mu.RLock()
-	passwordInput := append([]byte(nil), sm.password...)
-	sm.mu.RUnlock()
-	passwordInput = append(passwordInput, '\n')
-	cmd.Stdin = bytes.NewReader(passwordInput)
+	cmd := sm.sudoCommand(ctx, args...)
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -595,7 +651,7 @@ func (sm *SudoManager) DeleteDirectory(path string, recursive bool) error {
 		return fmt.Errorf("path is not a directory: %s", path)
 	}
 
-	args := []string{"-S", "rm"}
+	args := []string{"rm"}
 	if recursive {
 		args = append(args, "-rf", "--")
 	} else {
@@ -606,13 +662,7 @@ func (sm *SudoManager) DeleteDirectory(path string, recursive bool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "sudo", args...)
-
-	sm.mu.RLock()
-	passwordInput := append([]byte(nil), sm.password...)
-	sm.mu.RUnlock()
-	passwordInput = append(passwordInput, '\n')
-	cmd.Stdin = bytes.NewReader(passwordInput)
+	cmd := sm.sudoCommand(ctx, args...)
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -639,16 +689,12 @@ func (sm *SudoManager) KeepAlive() error {
 		sm.mu.RUnlock()
 		return fmt.Errorf("not authenticated")
 	}
-	password := append([]byte(nil), sm.password...)
 	sm.mu.RUnlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "sudo", "-S", "-v")
-
-	passwordInput := append(password, '\n')
-	cmd.Stdin = bytes.NewReader(passwordInput)
+	cmd := sm.sudoCommand(ctx, "-v")
 
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
@@ -680,6 +726,7 @@ func (sm *SudoManager) Clear() {
 		clearBytes(sm.password)
 		sm.password = nil
 	}
+	sm.tokenMode = false
 	sm.authenticated = false
 	sm.sessionExpiry = time.Time{}
 
@@ -701,6 +748,7 @@ func (sm *SudoManager) GetStatistics() map[string]interface{} {
 
 	return map[string]interface{}{
 		"authenticated":      sm.authenticated,
+		"token_mode":         sm.tokenMode,
 		"available":          sm.available,
 		"polkit_available":   sm.polkitAvailable,
 		"session_expiry":     sm.sessionExpiry,
@@ -0,0 +1,155 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package cleaner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// readDirBatch is how many entries are read from a directory at a time, so
+// huge directories don't have to be listed into memory in one go
+const readDirBatch = 1024
+
+// removeDirPasses is how many times a directory is emptied before giving up
+const removeDirPasses = 3
+
+// removeTree deletes the directory tree at path using unlinkat relative to
+// open directory descriptors, so no path is resolved more than once and
+// symlinks are never followed. Subdirectories are removed by up to workers
+// goroutines in parallel. A missing path is not an error.
+func removeTree(path string, workers int) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	parentFd, err := unix.Open(filepath.Dir(path), unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: filepath.Dir(path), Err: err}
+	}
+	defer unix.Close(parentFd)
+
+	r := &treeRemover{slots: make(chan struct{}, workers-1)}
+	r.removeDir(parentFd, filepath.Base(path), path)
+	r.wg.Wait()
+	return r.err
+}
+
+// treeRemover removes one directory tree, handing subtrees to spare workers
+type treeRemover struct {
+	slots chan struct{} // one token per extra worker goroutine
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // first error
+}
+
+// fail records the first error seen
+func (r *treeRemover) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
+
+// removeDir empties and removes directory name inside parentFd
+func (r *treeRemover) removeDir(parentFd int, name, path string) {
+	// Some filesystems skip entries when a directory changes while it's
+	// being read, so retry if entries are left behind
+	for pass := 0; ; pass++ {
+		if !r.emptyDir(parentFd, name, path) {
+			return
+		}
+		err := unix.Unlinkat(parentFd, name, unix.AT_REMOVEDIR)
+		if err == nil || err == unix.ENOENT {
+			return
+		}
+		if err != unix.ENOTEMPTY || pass == removeDirPasses-1 {
+			r.fail(&os.PathError{Op: "unlinkat", Path: path, Err: err})
+			return
+		}
+	}
+}
+
+// emptyDir removes everything inside directory name. It returns false if
+// the directory couldn't be opened, after recording any error.
+func (r *treeRemover) emptyDir(parentFd int, name, path string) bool {
+	fd, err := unix.Openat(parentFd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		switch err {
+		case unix.ENOENT:
+		case unix.ENOTDIR, unix.ELOOP:
+			// Replaced by a file or symlink since it was listed
+			r.unlink(parentFd, name, path)
+		default:
+			r.fail(&os.PathError{Op: "openat", Path: path, Err: err})
+		}
+		return false
+	}
+
+	dir := os.NewFile(uintptr(fd), path)
+	defer dir.Close()
+
+	// Subtrees use fd, so it stays open until they're done
+	var subdirs sync.WaitGroup
+	defer subdirs.Wait()
+
+	for {
+		entries, err := dir.ReadDir(readDirBatch)
+		for _, entry := range entries {
+			childPath := filepath.Join(path, entry.Name())
+			if !entry.IsDir() {
+				r.unlink(fd, entry.Name(), childPath)
+				continue
+			}
+
+			// Hand the subtree to a spare worker, or remove it inline
+			select {
+			case r.slots <- struct{}{}:
+				subdirs.Add(1)
+				r.wg.Add(1)
+				go func(name, path string) {
+					defer func() {
+						<-r.slots
+						subdirs.Done()
+						r.wg.Done()
+					}()
+					r.removeDir(fd, name, path)
+				}(entry.Name(), childPath)
+			default:
+				r.removeDir(fd, entry.Name(), childPath)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				r.fail(&os.PathError{Op: "readdir", Path: path, Err: err})
+			}
+			return true
+		}
+	}
+}
+
+// unlink removes a non-directory entry, falling back to removeDir if it
+// turned out to be a directory
+func (r *treeRemover) unlink(dirFd int, name, path string) {
+	err := unix.Unlinkat(dirFd, name, 0)
+	switch err {
+	case nil, unix.ENOENT:
+	case unix.EISDIR, unix.EPERM:
+		// Linux reports EISDIR, other systems EPERM, for directories
+		var st unix.Stat_t
+		if unix.Fstatat(dirFd, name, &st, unix.AT_SYMLINK_NOFOLLOW) == nil && st.Mode&unix.S_IFMT == unix.S_IFDIR {
+			r.removeDir(dirFd, name, path)
+			return
+		}
+		r.fail(&os.PathError{Op: "unlinkat", Path: path, Err: err})
+	default:
+		r.fail(&os.PathError{Op: "unlinkat", Path: path, Err: err})
+	}
+}
@@ -0,0 +1,148 @@
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// trimImage is the image Docker Desktop's VM is trimmed from. It enters the
+// VM's namespaces, so fstrim sees the VM's own file systems.
+const trimImage = "alpine"
+
+// CompactSteps returns the commands that compact disk: the runtime's own
+// prune, which frees space inside the VM, then an fstrim, which hands that
+// space back to the host. Docker's prune honours the docker settings for
+// images and volumes.
+func CompactSteps(disk scanner.VMDisk, cfg *config.DockerConfig) [][]string {
+	var steps [][]string
+	if disk.Docker != nil {
+		prune := append(append([]string{"docker"}, disk.Docker...), "system", "prune", "--force")
+		if cfg.CleanImages && !cfg.OnlyDanglingImages {
+			prune = append(prune, "--all")
+		}
+		if cfg.CleanVolumes {
+			prune = append(prune, "--volumes")
+		}
+		steps = append(steps, prune)
+	}
+
+	switch disk.Runtime {
+	case scanner.RuntimeDockerDesktop:
+		steps = append(steps, append(append([]string{"docker"}, disk.Docker...),
+			"run", "--rm", "--privileged", "--pid=host", trimImage, "nsenter", "-t", "1", "-m", "--", "fstrim", "-av"))
+	case scanner.RuntimeColima:
+		steps = append(steps,
+			[]string{"colima", "ssh", "--profile", disk.Name, "--", "sudo", "fstrim", "-av"},
+			[]string{"colima", "prune", "--force"})
+	case scanner.RuntimeLima:
+		steps = append(steps, []string{"limactl", "shell", disk.Name, "sudo", "fstrim", "-av"})
+	}
+	return steps
+}
+
+// CompactVMDisk runs disk's CompactSteps, writing their output to out. The
+// VM must be running. It stops at the first step that fails.
+func CompactVMDisk(ctx context.Context, disk scanner.VMDisk, cfg *config.DockerConfig, out io.Writer) error {
+	for _, step := range CompactSteps(disk, cfg) {
+		if _, err := exec.LookPath(step[0]); err != nil {
+			return fmt.Errorf("failed to compact %s: %s isn't installed", disk.Path, step[0])
+		}
+		fmt.Fprintf(out, "$ %s\n", strings.Join(step, " "))
+		cmd := exec.CommandContext(ctx, step[0], step[1:]...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to compact %s: %s: %w (is %s running?)", disk.Path, strings.Join(step, " "), err, disk.Runtime)
+		}
+	}
+	return nil
+}
+
+// DockerUsage is what Docker's images, containers, volumes and build cache
+// take up inside a VM
+type DockerUsage struct {
+	Size        int64
+	Reclaimable int64 // What a prune would free
+}
+
+// VMDiskUsage asks the Docker engine in disk's VM how much space it uses.
+// It fails if the VM has no Docker or isn't running.
+func VMDiskUsage(ctx context.Context, disk scanner.VMDisk) (DockerUsage, error) {
+	if disk.Docker == nil {
+		return DockerUsage{}, fmt.Errorf("%s has no Docker engine", disk.Path)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	args := append(append([]string{}, disk.Docker...), "system", "df", "--format", "{{json .}}")
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return DockerUsage{}, fmt.Errorf("failed to query docker: %w", err)
+	}
+	return parseDockerDF(out)
+}
+
+// parseDockerDF adds up "docker system df --format '{{json .}}'" output, one
+// JSON object per line
+func parseDockerDF(out []byte) (DockerUsage, error) {
+	var usage DockerUsage
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var row struct {
+			Size        string `json:"Size"`
+			Reclaimable string `json:"Reclaimable"`
+		}
+		if err := json.Unmarshal(line, &row); err != nil {
+			return DockerUsage{}, fmt.Errorf("failed to parse docker system df: %w", err)
+		}
+		size, err := parseDockerSize(row.Size)
+		if err != nil {
+			return DockerUsage{}, err
+		}
+		usage.Size += size
+
+		// "1.2GB (40%)"
+		if fields := strings.Fields(row.Reclaimable); len(fields) > 0 {
+			reclaimable, err := parseDockerSize(fields[0])
+			if err != nil {
+				return DockerUsage{}, err
+			}
+			usage.Reclaimable += reclaimable
+		}
+	}
+	return usage, nil
+}
+
+// dockerUnits are the units Docker prints sizes in, powers of 1000
+var dockerUnits = map[string]float64{
+	"B": 1, "kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15,
+}
+
+// parseDockerSize parses a size as Docker prints it, e.g. "1.2GB"
+func parseDockerSize(size string) (int64, error) {
+	end := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end <= 0 {
+		return 0, fmt.Errorf("invalid docker size %q", size)
+	}
+	value, err := strconv.ParseFloat(size[:end], 64)
+	multiplier, ok := dockerUnits[size[end:]]
+	if err != nil || !ok {
+		return 0, fmt.Errorf("invalid docker size %q", size)
+	}
+	return int64(math.Round(value * multiplier)), nil
+}
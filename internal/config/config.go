@@ -2,10 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fenilsonani/system-cleanup/internal/paths"
 	"github.com/fenilsonani/system-cleanup/internal/security"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,17 +25,45 @@ type Config struct {
 	ExcludePattern   []string             `yaml:"exclude_patterns"`
 	WhitelistPaths   []string             `yaml:"whitelist_paths"`
 	ProtectedPaths   []string             `yaml:"protected_paths"`
+	NeverDeleteExtensions []string        `yaml:"never_delete_extensions"` // Files with these extensions are never deleted, whatever the category, e.g. ".pem" or ".doc*"
 	DryRun           bool                 `yaml:"dry_run"`
 	MinFileAge       int                  `yaml:"min_file_age"` // in hours
 	Verbose          bool                 `yaml:"verbose"`
+	Language         string               `yaml:"language"` // e.g. "es"; empty uses LC_ALL, LC_MESSAGES or LANG
+	Units            string               `yaml:"units"`    // "binary" (KiB, MiB, GiB) or "si" (kB, MB, GB)
 	Docker           DockerConfig         `yaml:"docker"`
 	SecureDeletion   SecureDeletionConfig `yaml:"secure_deletion"`
+	Quarantine       QuarantineConfig     `yaml:"quarantine"`
+	Confirmation     ConfirmationConfig   `yaml:"confirmation"`
+	Sudo             SudoConfig           `yaml:"sudo"`
+	Retry            RetryConfig          `yaml:"retry"`
+	Clean            CleanConfig          `yaml:"clean"`
+	Scan             ScanConfig           `yaml:"scan"`
+	Reports          ReportsConfig        `yaml:"reports"`
+	Telemetry        TelemetryConfig      `yaml:"telemetry"`
+	UI               UIConfig             `yaml:"ui"`
 	Daemon           *DaemonConfig        `yaml:"daemon,omitempty"`
+	API              APIConfig            `yaml:"api"`
+	CI               CIConfig             `yaml:"ci"`
+	Onboarding       OnboardingConfig     `yaml:"onboarding"`
 	// New configuration sections
 	Dev       DevConfig        `yaml:"dev"`
 	LargeFiles LargeFilesConfig `yaml:"large_files_config"`
 	OldFiles  OldFilesConfig   `yaml:"old_files_config"`
 	AppData   AppDataConfig    `yaml:"app_data"`
+	Duplicates DuplicatesConfig `yaml:"duplicates_config"`
+	WSL        WSLConfig        `yaml:"wsl"`
+	EmptyDirs  EmptyDirsConfig  `yaml:"empty_dirs_config"`
+	VMImages   VMImagesConfig   `yaml:"vm_images_config"`
+	CloudCLI   CloudCLIConfig   `yaml:"cloud_cli"`
+	CompilerCaches CompilerCachesConfig `yaml:"compiler_caches_config"`
+	LaTeX      LaTeXConfig      `yaml:"latex_config"`
+	RPackages  RPackagesConfig  `yaml:"r_packages_config"`
+	Julia      JuliaConfig      `yaml:"julia_config"`
+	StaleRepos StaleReposConfig `yaml:"stale_repos_config"`
+	Screenshots ScreenshotsConfig `yaml:"screenshots_config"`
+	Downloads  DownloadsConfig  `yaml:"downloads_config"`
+	Cache      CacheConfig      `yaml:"cache"`
 }
 
 // Categories defines which cleanup categories are enabled
@@ -47,23 +83,120 @@ type Categories struct {
 	OldFiles   bool `yaml:"old_files"`
 	// Application data
 	AppData bool `yaml:"app_data"`
+	// Files with identical content
+	Duplicates bool `yaml:"duplicates"`
+	// Windows-side leftovers of WSL (only used under WSL)
+	WSL bool `yaml:"wsl"`
+	// Empty directories left behind by earlier cleanups
+	EmptyDirs bool `yaml:"empty_dirs"`
+	// Virtual machines and disk images not used in a while
+	VMImages bool `yaml:"vm_images"`
+	// Caches and logs of the AWS, Google Cloud and Azure CLIs
+	CloudCLI bool `yaml:"cloud_cli"`
+	// Font, icon and MIME type caches of Linux desktops
+	DesktopCaches bool `yaml:"desktop_caches"`
+	// ccache, sccache and Bazel disk caches past their age or size limit
+	CompilerCaches bool `yaml:"compiler_caches"`
+	// LaTeX auxiliary files and output trees
+	LaTeX bool `yaml:"latex"`
+	// R package caches and old versions in renv's cache
+	RPackages bool `yaml:"r_packages"`
+	// Julia's precompiled packages for older versions and superseded packages
+	Julia bool `yaml:"julia"`
+	// Git clones not committed to or fetched into for months
+	StaleRepos bool `yaml:"stale_repos"`
+	// Old screenshots and screen recordings
+	Screenshots bool `yaml:"screenshots"`
+}
+
+// fields maps each category name to its flag
+func (c *Categories) fields() map[string]*bool {
+	return map[string]*bool{
+		"cache":            &c.Cache,
+		"temp":             &c.Temp,
+		"logs":             &c.Logs,
+		"downloads":        &c.Downloads,
+		"package_managers": &c.PackageManagers,
+		"docker":           &c.Docker,
+		"node_modules":     &c.NodeModules,
+		"virtual_envs":     &c.VirtualEnvs,
+		"build_artifacts":  &c.BuildArtifacts,
+		"large_files":      &c.LargeFiles,
+		"old_files":        &c.OldFiles,
+		"app_data":         &c.AppData,
+		"duplicates":       &c.Duplicates,
+		"wsl":              &c.WSL,
+		"empty_dirs":       &c.EmptyDirs,
+		"vm_images":        &c.VMImages,
+		"cloud_cli":        &c.CloudCLI,
+		"desktop_caches":   &c.DesktopCaches,
+		"compiler_caches":  &c.CompilerCaches,
+		"latex":            &c.LaTeX,
+		"r_packages":       &c.RPackages,
+		"julia":            &c.Julia,
+		"stale_repos":      &c.StaleRepos,
+		"screenshots":      &c.Screenshots,
+	}
+}
+
+// Enabled returns the names of the enabled categories, sorted
+func (c Categories) Enabled() []string {
+	var names []string
+	for name, enabled := range c.fields() {
+		if *enabled {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Only enables the named categories and disables every other one
+func (c *Categories) Only(names []string) error {
+	fields := c.fields()
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("unknown category %q", name)
+		}
+	}
+	for _, field := range fields {
+		*field = false
+	}
+	for _, name := range names {
+		*fields[name] = true
+	}
+	return nil
 }
 
 // DockerConfig holds Docker cleanup configuration
 type DockerConfig struct {
-	Enabled               bool     `yaml:"enabled"`
-	CleanImages           bool     `yaml:"clean_images"`
-	CleanContainers       bool     `yaml:"clean_containers"`
-	CleanVolumes          bool     `yaml:"clean_volumes"`
-	CleanBuildCache       bool     `yaml:"clean_build_cache"`
-	OnlyDanglingImages    bool     `yaml:"only_dangling_images"`
-	OnlyStoppedContainers bool     `yaml:"only_stopped_containers"`
-	OnlyUnusedVolumes     bool     `yaml:"only_unused_volumes"`
-	ImageAgeDays          int      `yaml:"image_age_days"`
-	ContainerAgeDays      int      `yaml:"container_age_days"`
-	KeepImages            []string `yaml:"keep_images"`
-	KeepContainers        []string `yaml:"keep_containers"`
-	KeepVolumes           []string `yaml:"keep_volumes"`
+	Enabled               bool           `yaml:"enabled"`
+	CleanImages           bool           `yaml:"clean_images"`
+	CleanContainers       bool           `yaml:"clean_containers"`
+	CleanVolumes          bool           `yaml:"clean_volumes"`
+	CleanBuildCache       bool           `yaml:"clean_build_cache"`
+	OnlyDanglingImages    bool           `yaml:"only_dangling_images"`
+	OnlyStoppedContainers bool           `yaml:"only_stopped_containers"`
+	OnlyUnusedVolumes     bool           `yaml:"only_unused_volumes"`
+	ImageAgeDays          int            `yaml:"image_age_days"`
+	ContainerAgeDays      int            `yaml:"container_age_days"`
+	KeepImages            []string       `yaml:"keep_images"`
+	KeepContainers        []string       `yaml:"keep_containers"`
+	KeepVolumes           []string       `yaml:"keep_volumes"`
+	Registry              RegistryConfig `yaml:"registry"`
+}
+
+// RegistryConfig picks the local registry and BuildKit cache 'tidyup registry'
+// analyses and clean garbage collects
+type RegistryConfig struct {
+	GarbageCollect bool     `yaml:"garbage_collect"`        // Garbage collect them at the end of clean
+	Paths          []string `yaml:"paths"`                  // Root directories of registries using the filesystem storage driver
+	ConfigFile     string   `yaml:"config_file"`            // The registry's config, which its garbage-collect reads; empty to leave the registry alone
+	Container      string   `yaml:"container"`              // Container the registry runs in; empty if it runs on the host
+	DeleteUntagged bool     `yaml:"delete_untagged"`        // Also collect manifests no tag points to
+	BuildKit       bool     `yaml:"buildkit"`               // Analyse and prune a BuildKit daemon's cache with buildctl
+	BuildKitAddr   string   `yaml:"buildkit_addr"`          // buildkitd's address; empty for buildctl's default
+	BuildKitKeep   string   `yaml:"buildkit_keep_duration"` // Cache used within this long survives a prune
 }
 
 // SecureDeletionConfig holds secure deletion configuration
@@ -76,23 +209,220 @@ type SecureDeletionConfig struct {
 	BufferSizeKB   int    `yaml:"buffer_size_kb"`
 }
 
+// QuarantineConfig holds quarantine (undoable deletion) configuration
+type QuarantineConfig struct {
+	Enabled       bool           `yaml:"enabled"`        // Move files to the quarantine instead of deleting them
+	Dir           string         `yaml:"dir"`            // Where quarantined files are kept
+	RetentionDays int            `yaml:"retention_days"` // Days quarantined files are kept before they're purged (0 keeps them)
+	Retention     map[string]int `yaml:"retention"`      // Days to keep files of a category, overriding retention_days
+	PurgeSchedule string         `yaml:"purge_schedule"` // When the daemon purges expired files (cron expression, empty to disable)
+}
+
+// Expired reports whether a file of category quarantined at movedAt is past
+// its retention at now
+func (q QuarantineConfig) Expired(category string, movedAt, now time.Time) bool {
+	days := q.RetentionDays
+	if d, ok := q.Retention[category]; ok {
+		days = d
+	}
+	return days > 0 && !now.Before(movedAt.AddDate(0, 0, days))
+}
+
+// ConfirmationConfig controls when cleanup asks the user to type the amount
+// being deleted instead of answering y/N
+type ConfirmationConfig struct {
+	TypedThreshold string `yaml:"typed_threshold"` // Require typing the amount above this size (e.g., "50GB"), empty to disable
+	SystemPaths    bool   `yaml:"system_paths"`    // Require typing the amount when system-wide paths are touched, even with --force
+}
+
+// SudoConfig limits which categories may be deleted with elevated permissions
+type SudoConfig struct {
+	AllowCategories []string `yaml:"allow_categories"` // Only these categories may use sudo (empty allows all)
+	Never           []string `yaml:"never"`            // These categories never use sudo, even if allowed above
+}
+
+// AllowsCategory reports whether files in category may be deleted with sudo
+func (s SudoConfig) AllowsCategory(category string) bool {
+	for _, cat := range s.Never {
+		if cat == category {
+			return false
+		}
+	}
+	if len(s.AllowCategories) == 0 {
+		return true
+	}
+	for _, cat := range s.AllowCategories {
+		if cat == category {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryConfig controls how files that are busy (EBUSY/ETXTBSY) are retried
+// at the end of a cleanup
+type RetryConfig struct {
+	MaxAttempts    int    `yaml:"max_attempts"`     // Retry rounds with exponential backoff, 0 to give up right away
+	WaitForProcess bool   `yaml:"wait_for_process"` // Wait for the process holding the file to exit (uses lsof)
+	MaxWait        string `yaml:"max_wait"`         // Longest to wait for that process (e.g., "30s")
+}
+
+// CleanConfig controls the order of a cleanup and how it can be resumed
+type CleanConfig struct {
+	Order             []string     `yaml:"order"`               // Categories are cleaned in this order; unlisted ones go last
+	JournalFile       string       `yaml:"journal_file"`        // Progress of the running cleanup, for clean --resume (empty to disable)
+	SkipRunningApps   bool         `yaml:"skip_running_apps"`   // Leave browser, IDE and Electron app data alone while the app runs
+	SkipRunningBuilds bool         `yaml:"skip_running_builds"` // Leave build output and package caches alone while a build runs in their tree
+	AuditLog          string       `yaml:"audit_log"`           // Append each deleted file to this JSON lines file (empty to disable)
+	ChecksumMinSize   string       `yaml:"checksum_min_size"`   // Record the SHA-256 of files at least this big in the audit log (empty to disable)
+	StateMaxAge       string       `yaml:"state_max_age"`       // How long a scan saved with scan --save-state can be cleaned from (0 for no limit)
+	Hooks             []HookConfig `yaml:"hooks"`               // Commands and webhooks run before and after each category's cleanup (not from the project config)
+}
+
+// HookConfig is a shell command or webhook run before or after the cleanup
+// of each category it's for
+type HookConfig struct {
+	When       string            `yaml:"when"`       // "pre" or "post"
+	Categories []string          `yaml:"categories"` // Categories it runs for, once each (empty for every category cleaned)
+	Command    string            `yaml:"command"`    // Run in the shell, with TIDYUP_* variables describing the files
+	URL        string            `yaml:"url"`        // Webhook POSTed the same description as JSON, instead of a command
+	Headers    map[string]string `yaml:"headers"`    // Sent with the webhook
+	Timeout    string            `yaml:"timeout"`    // Longest the hook may take (default "1m")
+}
+
+// ScanConfig bounds the memory and parallelism of a scan
+type ScanConfig struct {
+	MaxResults int `yaml:"max_results"` // Results kept in memory; the rest are spilled to a temp file and paged through (0 for no limit)
+	Workers    int `yaml:"workers"`     // Directories read at once (0 to choose from the storage: fewer on spinning disks)
+}
+
+// ReportsConfig sets where report --save keeps reports and how many it keeps
+type ReportsConfig struct {
+	Dir        string `yaml:"dir"`          // Where saved reports are kept (empty for $XDG_DATA_HOME/tidyup/reports)
+	MaxFiles   int    `yaml:"max_files"`    // Saved reports kept, the newest (0 for no limit)
+	MaxAgeDays int    `yaml:"max_age_days"` // Remove saved reports older than this (0 for no limit)
+}
+
+// TelemetryConfig sets where traces and metrics of scans and cleanups are
+// exported over OTLP
+type TelemetryConfig struct {
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector, e.g. http://localhost:4318 (empty for $OTEL_EXPORTER_OTLP_ENDPOINT, or no export)
+	Headers     map[string]string `yaml:"headers"`      // Sent with every export, e.g. an API key
+	ServiceName string            `yaml:"service_name"` // service.name of what's exported
+	Timeout     string            `yaml:"timeout"`      // How long an export may take
+}
+
+// UIConfig holds interactive view (clean -i) settings
+type UIConfig struct {
+	Theme             string            `yaml:"theme"` // "dark", "light", "high-contrast" or "monochrome"
+	Keybindings       KeybindingsConfig `yaml:"keybindings"`
+	SaveState         bool              `yaml:"save_state"`         // Remember sort, columns and filters between sessions
+	StateFile         string            `yaml:"state_file"`         // Where view state is kept
+	RememberSelection string            `yaml:"remember_selection"` // "off", "remind" or "apply"
+}
+
+// KeybindingsConfig selects a key preset and optional per-action overrides
+type KeybindingsConfig struct {
+	Preset   string              `yaml:"preset"`   // "default", "vim" or "emacs"
+	Bindings map[string][]string `yaml:"bindings"` // action -> keys, replaces the preset's keys for that action
+}
+
 // DaemonConfig holds daemon mode configuration
 type DaemonConfig struct {
 	Enabled       bool              `yaml:"enabled"`
 	PidFile       string            `yaml:"pid_file"`
 	LogFile       string            `yaml:"log_file"`
 	LogLevel      string            `yaml:"log_level"`
+	LogMaxSize    string            `yaml:"log_max_size"`     // Rotate the log at this size (default "10MB")
+	LogMaxFiles   int               `yaml:"log_max_files"`    // Rotated logs kept (default 5)
+	LogMaxAgeDays int               `yaml:"log_max_age_days"` // Remove rotated logs older than this (default 30)
+	StateFile     string            `yaml:"state_file"` // Run history (default daemon-state.json in the state directory)
 	Schedules     []CleanupSchedule `yaml:"schedules"`
 	Notifications NotificationConfig `yaml:"notifications"`
+	Sudo          string            `yaml:"sudo"` // Files needing sudo: "never" (default), "notify" or "passwordless"
+}
+
+// Daemon sudo policies. The daemon has no terminal to ask for a password on.
+const (
+	DaemonSudoNever        = "never"        // Skip files that need sudo and log them
+	DaemonSudoNotify       = "notify"       // Skip them and send a notification listing them
+	DaemonSudoPasswordless = "passwordless" // Use sudo when it needs no password, e.g. a NOPASSWD rule
+)
+
+// APIConfig holds the remote management API settings (tidyup serve, or the daemon)
+type APIConfig struct {
+	Enabled    bool   `yaml:"enabled"`     // Serve the API from the daemon; tidyup serve always does
+	Listen     string `yaml:"listen"`      // Address to listen on, e.g. "127.0.0.1:7780"
+	TokenFile  string `yaml:"token_file"`  // File holding the bearer token clients must send
+	TLSCert    string `yaml:"tls_cert"`    // Server certificate, to serve HTTPS
+	TLSKey     string `yaml:"tls_key"`     // Server certificate key
+	ClientCA   string `yaml:"client_ca"`   // Require client certificates signed by this CA (mTLS)
+	AllowClean bool   `yaml:"allow_clean"` // Allow cleanups to be started remotely; scans and reports always are
+}
+
+// CIConfig tunes "tidyup ci" for build agents
+type CIConfig struct {
+	MinFree    string   `yaml:"min_free"`    // Clean when free space drops below this, e.g. "20GB" or "10%"
+	Path       string   `yaml:"path"`        // Disk to watch, by a path on it (empty for the home directory)
+	Categories []string `yaml:"categories"`  // Categories cleaned on agents; keep them outside the workspace
+	ToolCaches []string `yaml:"tool_caches"` // Toolchain caches emptied as a whole; $VARS are expanded, unset ones skipped
+	LockFile   string   `yaml:"lock_file"`   // Don't clean while this file exists, e.g. while a job runs
+}
+
+// MinFreeBytes returns the free space threshold for a disk of total bytes
+func (c CIConfig) MinFreeBytes(total uint64) (uint64, error) {
+	if pct, ok := strings.CutSuffix(strings.TrimSpace(c.MinFree), "%"); ok {
+		value, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || value < 0 || value > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", c.MinFree)
+		}
+		return uint64(float64(total) * value / 100), nil
+	}
+	size, err := utils.ParseSize(c.MinFree)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(size), nil
 }
 
 // CleanupSchedule defines a scheduled cleanup
 type CleanupSchedule struct {
 	Name        string          `yaml:"name"`
-	Schedule    string          `yaml:"schedule"` // Cron expression
+	Schedule    string          `yaml:"schedule"` // Cron expression; may be left out when a trigger below is set
 	Categories  map[string]bool `yaml:"categories"`
 	DryRun      bool            `yaml:"dry_run"`
 	SkipIfBusy  bool            `yaml:"skip_if_busy"`
+	Action       string          `yaml:"action"`        // "clean" (default) or "report", which only emails a digest
+	ReportFormat string          `yaml:"report_format"` // Digest format for report schedules: "html" (default) or "markdown"
+	OnLogin       bool           `yaml:"on_login"`        // Also run when the user logs in
+	OnIdleMinutes int            `yaml:"on_idle_minutes"` // Also run once the user has been idle this long (0 to disable)
+	Spread        string         `yaml:"spread"`          // Spread the deletions over this long at low I/O priority, e.g. "2h" (empty for as fast as possible)
+}
+
+// Schedule actions
+const (
+	ActionClean  = "clean"
+	ActionReport = "report"
+)
+
+// IsReport reports whether the schedule only emails a report, deleting nothing
+func (s CleanupSchedule) IsReport() bool {
+	return s.Action == ActionReport
+}
+
+// Triggers describes when the schedule runs, e.g. "0 2 * * *, on login"
+func (s CleanupSchedule) Triggers() string {
+	var triggers []string
+	if s.Schedule != "" {
+		triggers = append(triggers, s.Schedule)
+	}
+	if s.OnLogin {
+		triggers = append(triggers, "on login")
+	}
+	if s.OnIdleMinutes > 0 {
+		triggers = append(triggers, fmt.Sprintf("after %dm idle", s.OnIdleMinutes))
+	}
+	return strings.Join(triggers, ", ")
 }
 
 // NotificationConfig holds notification settings
@@ -134,6 +464,8 @@ type AgeThresholds struct {
 type DevConfig struct {
 	ProjectDirs   []string `yaml:"project_dirs"`   // Directories to scan for projects
 	BuildPatterns []string `yaml:"build_patterns"` // Patterns to match build artifacts
+	VerifySizes   bool     `yaml:"verify_sizes"`   // Walk artifacts for exact sizes and file counts instead of estimating
+	SizeWorkers   int      `yaml:"size_workers"`   // Artifact sizing processes run at once (0 to choose from the storage)
 }
 
 // LargeFilesConfig holds large file detection configuration
@@ -162,38 +494,117 @@ type AppDataConfig struct {
 	ExcludeFiles      []string `yaml:"exclude_files"`      // File patterns to exclude from deletion
 }
 
+// DuplicatesConfig holds duplicate file detection configuration
+type DuplicatesConfig struct {
+	MinSize      string   `yaml:"min_size"`      // Ignore files smaller than this (e.g., "1MB")
+	ScanPaths    []string `yaml:"scan_paths"`    // Paths to search for duplicates
+	ExcludePaths []string `yaml:"exclude_paths"` // Paths to exclude from the search
+}
+
+// WSLConfig controls scanning under Windows Subsystem for Linux
+type WSLConfig struct {
+	ScanWindowsDrives bool `yaml:"scan_windows_drives"` // Walk /mnt/c and other DrvFs mounts (very slow, and the files belong to Windows)
+	TarballAgeDays    int  `yaml:"tarball_age_days"`    // Only report distro tarballs and packages older than this
+}
+
+// EmptyDirsConfig controls the search for empty directories
+type EmptyDirsConfig struct {
+	ScanPaths  []string `yaml:"scan_paths"`   // Where to look (empty for the cache directories and old_files_config.scan_paths)
+	MinAgeDays int      `yaml:"min_age_days"` // Only report directories unchanged for this long
+}
+
+// VMImagesConfig controls the search for unused virtual machines
+type VMImagesConfig struct {
+	ScanPaths  []string `yaml:"scan_paths"`   // Other directories holding disk images (.qcow2, .vdi, .vmdk, .vhd, .vhdx)
+	MinAgeDays int      `yaml:"min_age_days"` // Only report machines and images not used for this long
+}
+
+// CacheConfig controls the cache category
+type CacheConfig struct {
+	ExcludeApps []string `yaml:"exclude_apps"` // Applications whose caches are kept, e.g. com.apple.Safari or JetBrains
+}
+
+// DownloadsConfig controls the downloads category
+type DownloadsConfig struct {
+	BrowserHistory bool     `yaml:"browser_history"` // Read Chrome and Firefox download history, read-only, for where files came from
+	FromHosts      []string `yaml:"from_hosts"`      // Hosts whose downloads are reported whatever their age, e.g. "*.cloudfront.net"
+}
+
+// ScreenshotsConfig controls the search for old screenshots and recordings
+type ScreenshotsConfig struct {
+	ScanPaths  []string `yaml:"scan_paths"`   // Where screenshots are saved, searched with their subdirectories
+	MinAgeDays int      `yaml:"min_age_days"` // Only report screenshots older than this
+}
+
+// CloudCLIConfig picks the CLIs the cloud_cli category cleans up after
+type CloudCLIConfig struct {
+	AWS    bool `yaml:"aws"`    // ~/.aws/cli/cache: role credentials the CLI fetches again
+	GCloud bool `yaml:"gcloud"` // Logs and caches in ~/.config/gcloud
+	Azure  bool `yaml:"azure"`  // Logs, command logs and telemetry in ~/.azure
+}
+
+// CompilerCachesConfig picks the caches the compiler_caches category prunes
+// and how far
+type CompilerCachesConfig struct {
+	CCache     bool     `yaml:"ccache"`       // $CCACHE_DIR, ~/.ccache or ~/.cache/ccache
+	SCCache    bool     `yaml:"sccache"`      // sccache's local disk cache, $SCCACHE_DIR
+	Bazel      bool     `yaml:"bazel"`        // --disk_cache directories set in ~/.bazelrc and /etc/bazel.bazelrc
+	ScanPaths  []string `yaml:"scan_paths"`   // Other cache directories, e.g. a bazel-remote or shared ccache directory
+	MaxAgeDays int      `yaml:"max_age_days"` // Entries unused for this long are pruned (0 to keep them)
+	MaxSize    string   `yaml:"max_size"`     // Least recently used entries past this size are pruned, per cache (empty for no limit)
+}
+
+// LaTeXConfig sets where the latex category looks for LaTeX documents
+type LaTeXConfig struct {
+	ScanPaths  []string `yaml:"scan_paths"`   // Searched along with dev.project_dirs
+	MinAgeDays int      `yaml:"min_age_days"` // Auxiliary files untouched for this long are reported
+}
+
+// RPackagesConfig sets how old package versions in renv's cache must be
+// before the r_packages category reports them
+type RPackagesConfig struct {
+	MinAgeDays int `yaml:"min_age_days"`
+}
+
+// JuliaConfig sets how old what Julia's depot keeps for superseded versions
+// must be before the julia category reports it
+type JuliaConfig struct {
+	MinAgeDays int `yaml:"min_age_days"`
+}
+
+// StaleReposConfig sets how long a git clone must have gone without a commit
+// or a fetch before the stale_repos category reports it
+type StaleReposConfig struct {
+	MinAgeMonths int `yaml:"min_age_months"`
+}
+
 // SizeLimits defines size limits for files to consider
 type SizeLimits struct {
 	MinFileSize string `yaml:"min_file_size"` // e.g., "1KB"
 	MaxFileSize string `yaml:"max_file_size"` // e.g., "10GB"
 }
 
-// Load loads configuration from a file
+// Load loads configuration from a file, falling back to the defaults if it
+// doesn't exist
 func Load(configPath string) (*Config, error) {
-	// Start with default config
-	config := GetDefault()
-
-	// If config doesn't exist, return default config
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return config, nil
-	}
+	config, _, err := LoadLayered([]string{configPath})
+	return config, err
+}
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+// followMigratedPaths replaces file locations that were the defaults of
+// earlier versions with the current defaults, since paths.Migrate has moved
+// those files there
+func (c *Config) followMigratedPaths() {
+	defaults := GetDefault()
+	if c.Quarantine.Dir == "~/.local/share/tidyup/quarantine" {
+		c.Quarantine.Dir = defaults.Quarantine.Dir
 	}
-
-	// Unmarshal on top of defaults - this allows partial configs
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if c.Clean.JournalFile == "~/.local/share/tidyup/clean-journal.json" {
+		c.Clean.JournalFile = defaults.Clean.JournalFile
 	}
-
-	// Validate config
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	if c.UI.StateFile == "~/.config/tidyup/ui-state.json" {
+		c.UI.StateFile = defaults.UI.StateFile
 	}
-
-	return config, nil
 }
 
 // Save saves configuration to a file
@@ -233,6 +644,69 @@ func (c *Config) Validate() error {
 	if c.MinFileAge < 0 {
 		return fmt.Errorf("min file age must be >= 0")
 	}
+	if c.WSL.TarballAgeDays < 0 {
+		return fmt.Errorf("wsl.tarball_age_days must be >= 0")
+	}
+	if c.EmptyDirs.MinAgeDays < 0 {
+		return fmt.Errorf("empty_dirs_config.min_age_days must be >= 0")
+	}
+	if c.VMImages.MinAgeDays < 0 {
+		return fmt.Errorf("vm_images_config.min_age_days must be >= 0")
+	}
+	for _, pattern := range c.Downloads.FromHosts {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid downloads_config.from_hosts pattern %q", pattern)
+		}
+	}
+	if c.Screenshots.MinAgeDays < 0 {
+		return fmt.Errorf("screenshots_config.min_age_days must be >= 0")
+	}
+	if c.CompilerCaches.MaxAgeDays < 0 {
+		return fmt.Errorf("compiler_caches_config.max_age_days must be >= 0")
+	}
+	if c.LaTeX.MinAgeDays < 0 {
+		return fmt.Errorf("latex_config.min_age_days must be >= 0")
+	}
+	if c.RPackages.MinAgeDays < 0 {
+		return fmt.Errorf("r_packages_config.min_age_days must be >= 0")
+	}
+	if c.Julia.MinAgeDays < 0 {
+		return fmt.Errorf("julia_config.min_age_days must be >= 0")
+	}
+	if c.StaleRepos.MinAgeMonths < 0 {
+		return fmt.Errorf("stale_repos_config.min_age_months must be >= 0")
+	}
+	for _, app := range c.Cache.ExcludeApps {
+		if app == "" || filepath.IsAbs(app) || slices.Contains(strings.Split(filepath.ToSlash(app), "/"), "..") {
+			return fmt.Errorf("invalid cache.exclude_apps entry %q: use an application name or a folder in the cache directory", app)
+		}
+	}
+	if c.Scan.MaxResults < 0 {
+		return fmt.Errorf("scan.max_results must be >= 0")
+	}
+	if c.Scan.Workers < 0 {
+		return fmt.Errorf("scan.workers must be >= 0")
+	}
+	if c.Reports.MaxFiles < 0 {
+		return fmt.Errorf("reports.max_files must be >= 0")
+	}
+	if c.Reports.MaxAgeDays < 0 {
+		return fmt.Errorf("reports.max_age_days must be >= 0")
+	}
+	if c.Dev.SizeWorkers < 0 {
+		return fmt.Errorf("dev.size_workers must be >= 0")
+	}
+	if c.Telemetry.Endpoint != "" {
+		endpoint, err := url.Parse(c.Telemetry.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("telemetry.endpoint must be an http:// or https:// URL")
+		}
+	}
+	if c.Telemetry.Timeout != "" {
+		if _, err := time.ParseDuration(c.Telemetry.Timeout); err != nil {
+			return fmt.Errorf("invalid telemetry.timeout: %w", err)
+		}
+	}
 
 	// Validate exclude patterns (glob syntax)
 	for _, pattern := range c.ExcludePattern {
@@ -241,6 +715,108 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate typed confirmation threshold
+	if _, err := parseOptionalSize("confirmation.typed_threshold", c.Confirmation.TypedThreshold); err != nil {
+		return err
+	}
+
+	// Validate the size limits
+	minFile, err := parseOptionalSize("size_limits.min_file_size", c.SizeLimits.MinFileSize)
+	if err != nil {
+		return err
+	}
+	maxFile, err := parseOptionalSize("size_limits.max_file_size", c.SizeLimits.MaxFileSize)
+	if err != nil {
+		return err
+	}
+	if c.SizeLimits.MaxFileSize != "" && minFile > maxFile {
+		return fmt.Errorf("size_limits.min_file_size (%s) must not be larger than size_limits.max_file_size (%s)",
+			c.SizeLimits.MinFileSize, c.SizeLimits.MaxFileSize)
+	}
+
+	// Validate the size thresholds the scanners use
+	for _, size := range []struct{ key, value string }{
+		{"large_files_config.min_size", c.LargeFiles.MinSize},
+		{"app_data.min_size", c.AppData.MinSize},
+		{"duplicates_config.min_size", c.Duplicates.MinSize},
+		{"compiler_caches_config.max_size", c.CompilerCaches.MaxSize},
+		{"clean.checksum_min_size", c.Clean.ChecksumMinSize},
+	} {
+		if _, err := parseOptionalSize(size.key, size.value); err != nil {
+			return err
+		}
+	}
+	if c.Clean.ChecksumMinSize != "" && c.Clean.AuditLog == "" {
+		return fmt.Errorf("clean.checksum_min_size needs clean.audit_log to record the checksums in")
+	}
+
+	// Validate busy file retries
+	if c.Retry.MaxAttempts < 0 {
+		return fmt.Errorf("retry.max_attempts must be >= 0")
+	}
+	if c.Docker.Registry.BuildKitKeep != "" {
+		if _, err := time.ParseDuration(c.Docker.Registry.BuildKitKeep); err != nil {
+			return fmt.Errorf("invalid docker.registry.buildkit_keep_duration: %w", err)
+		}
+	}
+	for i, hook := range c.Clean.Hooks {
+		if err := hook.validate(); err != nil {
+			return fmt.Errorf("clean.hooks[%d]: %w", i, err)
+		}
+	}
+	if c.Clean.StateMaxAge != "" {
+		if _, err := time.ParseDuration(c.Clean.StateMaxAge); err != nil {
+			return fmt.Errorf("invalid clean.state_max_age: %w", err)
+		}
+	}
+	if c.Retry.MaxWait != "" {
+		if _, err := time.ParseDuration(c.Retry.MaxWait); err != nil {
+			return fmt.Errorf("invalid retry.max_wait: %w", err)
+		}
+	}
+
+	// Validate the API server settings
+	if (c.API.TLSCert == "") != (c.API.TLSKey == "") {
+		return fmt.Errorf("api.tls_cert and api.tls_key must be set together")
+	}
+	if c.API.ClientCA != "" && c.API.TLSCert == "" {
+		return fmt.Errorf("api.client_ca requires api.tls_cert and api.tls_key")
+	}
+
+	// Validate the CI preset
+	if c.CI.MinFree != "" {
+		if _, err := c.CI.MinFreeBytes(100); err != nil {
+			return fmt.Errorf("invalid ci.min_free: %w", err)
+		}
+	}
+	if err := (&Categories{}).Only(c.CI.Categories); err != nil {
+		return fmt.Errorf("invalid ci.categories: %w", err)
+	}
+	if err := (&Categories{}).Only(c.Onboarding.Approved); err != nil {
+		return fmt.Errorf("invalid onboarding.approved: %w", err)
+	}
+
+	// Validate the daemon schedules and sudo policy
+	if c.Daemon != nil {
+		if err := c.Daemon.validateSchedules(); err != nil {
+			return err
+		}
+		if _, err := parseOptionalSize("daemon.log_max_size", c.Daemon.LogMaxSize); err != nil {
+			return err
+		}
+		if c.Daemon.LogMaxFiles < 0 {
+			return fmt.Errorf("daemon.log_max_files must be >= 0")
+		}
+		if c.Daemon.LogMaxAgeDays < 0 {
+			return fmt.Errorf("daemon.log_max_age_days must be >= 0")
+		}
+		switch c.Daemon.Sudo {
+		case "", DaemonSudoNever, DaemonSudoNotify, DaemonSudoPasswordless:
+		default:
+			return fmt.Errorf("invalid daemon.sudo %q (valid policies: never, notify, passwordless)", c.Daemon.Sudo)
+		}
+	}
+
 	// Validate whitelist paths are absolute
 	for _, path := range c.WhitelistPaths {
 		if !filepath.IsAbs(path) {
@@ -255,17 +831,108 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate the quarantine retention
+	if c.Quarantine.RetentionDays < 0 {
+		return fmt.Errorf("quarantine.retention_days must be >= 0")
+	}
+	for category, days := range c.Quarantine.Retention {
+		if days < 0 {
+			return fmt.Errorf("quarantine.retention.%s must be >= 0", category)
+		}
+	}
+
+	// Validate the extensions that are never deleted
+	for _, ext := range c.NeverDeleteExtensions {
+		if _, err := filepath.Match(ext, ""); err != nil || !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("invalid never_delete_extensions entry %q (e.g. \".pem\" or \".doc*\")", ext)
+		}
+	}
+
 	return nil
 }
 
+// validateSchedules checks each schedule's action and that report schedules
+// have somewhere to send their report
+func (d *DaemonConfig) validateSchedules() error {
+	email := d.Notifications.Email
+	for _, schedule := range d.Schedules {
+		switch schedule.Action {
+		case "", ActionClean, ActionReport:
+		default:
+			return fmt.Errorf("invalid action %q for schedule %q (valid actions: clean, report)", schedule.Action, schedule.Name)
+		}
+		switch schedule.ReportFormat {
+		case "", "html", "markdown":
+		default:
+			return fmt.Errorf("invalid report_format %q for schedule %q (valid formats: html, markdown)", schedule.ReportFormat, schedule.Name)
+		}
+		if schedule.IsReport() && (email.SMTPHost == "" || len(email.To) == 0) {
+			return fmt.Errorf("schedule %q emails a report, which needs daemon.notifications.email.smtp_host and to", schedule.Name)
+		}
+		if schedule.OnIdleMinutes < 0 {
+			return fmt.Errorf("on_idle_minutes for schedule %q must be >= 0", schedule.Name)
+		}
+		if schedule.Spread != "" {
+			if spread, err := time.ParseDuration(schedule.Spread); err != nil || spread < 0 {
+				return fmt.Errorf("invalid spread %q for schedule %q", schedule.Spread, schedule.Name)
+			}
+		}
+		if schedule.Schedule == "" && !schedule.OnLogin && schedule.OnIdleMinutes == 0 {
+			return fmt.Errorf("schedule %q needs a cron schedule, on_login or on_idle_minutes", schedule.Name)
+		}
+	}
+	return nil
+}
+
+// validate checks a hook has one command or URL to run, for known
+// categories
+func (h HookConfig) validate() error {
+	switch h.When {
+	case "pre", "post":
+	default:
+		return fmt.Errorf("invalid when %q (valid values: pre, post)", h.When)
+	}
+	if (h.Command == "") == (h.URL == "") {
+		return fmt.Errorf("set one of command and url")
+	}
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http:// or https:// URL")
+		}
+	}
+	if h.Timeout != "" {
+		if timeout, err := time.ParseDuration(h.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q", h.Timeout)
+		}
+	}
+	categories := (&Categories{}).fields()
+	for _, name := range h.Categories {
+		if _, ok := categories[name]; !ok {
+			return fmt.Errorf("unknown category %q", name)
+		}
+	}
+	return nil
+}
+
+// parseOptionalSize parses the size set for key, which may be left empty
+func parseOptionalSize(key, size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	n, err := utils.ParseSize(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
 // GetConfigPath returns the default config path
 func GetConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := paths.ConfigDir()
 	if err != nil {
 		return "", err
 	}
-
-	configDir := filepath.Join(homeDir, ".config", "tidyup")
 	return filepath.Join(configDir, "config.yaml"), nil
 }
 
@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // =============================================================================
@@ -482,6 +487,60 @@ func TestValidateZeroThresholds(t *testing.T) {
 	}
 }
 
+func TestValidateTypedConfirmThreshold(t *testing.T) {
+	cfg := GetDefault()
+	cfg.Confirmation.TypedThreshold = "lots"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid typed_threshold")
+	}
+
+	cfg.Confirmation.TypedThreshold = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("empty typed_threshold should be valid: %v", err)
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	cfg := GetDefault()
+	cfg.Retry.MaxWait = "soon"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid retry.max_wait")
+	}
+
+	cfg = GetDefault()
+	cfg.Retry.MaxAttempts = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative retry.max_attempts")
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	valid := []HookConfig{
+		{When: "pre", Categories: []string{"docker"}, Command: "docker compose stop", Timeout: "2m"},
+		{When: "post", URL: "https://hooks.example.com/tidyup"},
+	}
+	cfg := GetDefault()
+	cfg.Clean.Hooks = valid
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid hooks rejected: %v", err)
+	}
+
+	for _, hook := range []HookConfig{
+		{When: "during", Command: "true"},
+		{When: "pre"},
+		{When: "pre", Command: "true", URL: "https://hooks.example.com"},
+		{When: "post", URL: "ftp://hooks.example.com"},
+		{When: "pre", Command: "true", Timeout: "soon"},
+		{When: "pre", Command: "true", Categories: []string{"dockr"}},
+	} {
+		cfg := GetDefault()
+		cfg.Clean.Hooks = []HookConfig{hook}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", hook)
+		}
+	}
+}
+
 // =============================================================================
 // GetConfigPath Tests
 // =============================================================================
@@ -821,3 +880,556 @@ func TestSaveAndLoadRoundTrip(t *testing.T) {
 		t.Error("WhitelistPaths length mismatch after round-trip")
 	}
 }
+
+func TestSudoAllowsCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		sudo     SudoConfig
+		category string
+		want     bool
+	}{
+		{"empty allows all", SudoConfig{}, "logs", true},
+		{"allowed", SudoConfig{AllowCategories: []string{"logs", "temp"}}, "temp", true},
+		{"not allowed", SudoConfig{AllowCategories: []string{"logs", "temp"}}, "cache", false},
+		{"never", SudoConfig{Never: []string{"large_files"}}, "large_files", false},
+		{"never beats allowed", SudoConfig{AllowCategories: []string{"logs"}, Never: []string{"logs"}}, "logs", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sudo.AllowsCategory(tt.category); got != tt.want {
+				t.Errorf("AllowsCategory(%q) = %v, want %v", tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFollowsMigratedPaths(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	legacy := `clean:
+  journal_file: "~/.local/share/tidyup/clean-journal.json"
+ui:
+  state_file: "~/custom/ui-state.json"
+`
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Clean.JournalFile != GetDefault().Clean.JournalFile {
+		t.Errorf("expected the old default journal to follow the migration, got %s", cfg.Clean.JournalFile)
+	}
+	if cfg.UI.StateFile != "~/custom/ui-state.json" {
+		t.Errorf("custom paths should be kept, got %s", cfg.UI.StateFile)
+	}
+}
+
+// =============================================================================
+// Layered Config Tests
+// =============================================================================
+
+func TestLoadLayered(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.yaml")
+	project := filepath.Join(dir, ".tidyup.yaml")
+
+	os.WriteFile(system, []byte("verbose: true\nretry:\n  max_attempts: 2\n  max_wait: 10s\nexclude_patterns: [\"*.a\", \"*.b\"]\n"), 0644)
+	os.WriteFile(user, []byte("retry:\n  max_attempts: 5\n"), 0644)
+	os.WriteFile(project, []byte("exclude_patterns: [\"*.c\"]\n"), 0644)
+
+	cfg, sources, err := LoadLayered([]string{system, user, filepath.Join(dir, "missing.yaml"), project})
+	if err != nil {
+		t.Fatalf("LoadLayered failed: %v", err)
+	}
+
+	if !cfg.Verbose || cfg.Retry.MaxWait != "10s" {
+		t.Error("keys set only by the system config should be kept")
+	}
+	if cfg.Retry.MaxAttempts != 5 {
+		t.Errorf("user config should override system config, got max_attempts %d", cfg.Retry.MaxAttempts)
+	}
+	if len(cfg.ExcludePattern) != 1 || cfg.ExcludePattern[0] != "*.c" {
+		t.Errorf("project config should replace lists, got %v", cfg.ExcludePattern)
+	}
+
+	tests := map[string]string{
+		"verbose":            system,
+		"retry.max_wait":     system,
+		"retry.max_attempts": user,
+		"exclude_patterns":   project,
+		"dry_run":            DefaultSource,
+	}
+	for key, want := range tests {
+		if got := sources.Source(key); got != want {
+			t.Errorf("source of %s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestLoadLayeredValidatesMergedConfig(t *testing.T) {
+	user := filepath.Join(t.TempDir(), "user.yaml")
+	os.WriteFile(user, []byte("retry:\n  max_attempts: -1\n"), 0644)
+
+	if _, _, err := LoadLayered([]string{user}); err == nil {
+		t.Error("expected invalid merged config to be rejected")
+	}
+}
+
+func TestLoadLayeredProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	user := filepath.Join(dir, "user.yaml")
+	os.WriteFile(user, []byte("exclude_patterns: [\"*.keep\"]\nscan:\n  workers: 2\n"), 0644)
+
+	os.WriteFile(ProjectConfigFile, []byte("exclude_patterns: [\"vendor/*\"]\nscan:\n  workers: 8\nunits: si\n"), 0644)
+	cfg, sources, err := LoadLayered(Layers(user))
+	if err != nil {
+		t.Fatalf("LoadLayered failed: %v", err)
+	}
+	if cfg.Scan.Workers != 8 || cfg.Units != "si" {
+		t.Errorf("project config should set scan and report keys, got workers %d, units %q", cfg.Scan.Workers, cfg.Units)
+	}
+	if !slices.Equal(cfg.ExcludePattern, []string{"*.keep", "vendor/*"}) {
+		t.Errorf("project exclude_patterns should add to the user's, got %v", cfg.ExcludePattern)
+	}
+	if got, want := sources.Source("scan.workers"), filepath.Join(dir, ProjectConfigFile); got != want {
+		t.Errorf("source of scan.workers = %s, want %s", got, want)
+	}
+
+	// Nothing that loosens safety checks, widens the scan or runs commands
+	tests := map[string]string{
+		"quarantine":              "quarantine:\n  enabled: false\n",
+		"never_delete_extensions": "never_delete_extensions: []\n",
+		"scan paths":              "old_files_config:\n  scan_paths: [\"/\"]\n",
+		"sudo":                    "sudo:\n  allow_categories: [\"logs\"]\n",
+		"hooks":                   "clean:\n  hooks:\n    - when: pre\n      command: \"touch pwned\"\n",
+		"protected paths":         "protected_paths: []\n",
+		"categories":              "categories:\n  downloads: true\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			os.WriteFile(ProjectConfigFile, []byte(data), 0644)
+			key, _, _ := strings.Cut(data, ":")
+			_, _, err := LoadLayered(Layers(user))
+			if err == nil || !strings.Contains(err.Error(), key+" can only be set in the system or user config") {
+				t.Errorf("expected %s to be refused in the project config, got %v", key, err)
+			}
+
+			// The same keys are fine in the user config
+			os.WriteFile(user, []byte(data), 0644)
+			if _, _, err := LoadLayered([]string{user}); err != nil {
+				t.Errorf("expected %s to be accepted in the user config, got %v", key, err)
+			}
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	user := filepath.Join(t.TempDir(), "user.yaml")
+	os.WriteFile(user, []byte("verbose: true\n"), 0644)
+
+	cfg, sources, err := LoadLayered([]string{user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Effective(cfg, sources)
+	if err != nil {
+		t.Fatalf("Effective failed: %v", err)
+	}
+
+	out := string(data)
+	if !strings.Contains(out, "verbose: true # from "+user) {
+		t.Errorf("expected verbose to be attributed to the user config:\n%s", out)
+	}
+	if !strings.Contains(out, "dry_run: false # from default") {
+		t.Errorf("expected dry_run to be attributed to the defaults:\n%s", out)
+	}
+
+	// The annotated output is still a valid config
+	var parsed Config
+	if err := yaml.Unmarshal(data, &parsed); err != nil || !parsed.Verbose {
+		t.Errorf("effective output should parse back: %v", err)
+	}
+}
+
+func TestResolveOverrides(t *testing.T) {
+	user := filepath.Join(t.TempDir(), "user.yaml")
+	os.WriteFile(user, []byte("retry:\n  max_attempts: 5\n  max_wait: 10s\n"), 0644)
+
+	overrides := Overrides{
+		Env: []string{
+			"TIDYUP_RETRY_MAX_ATTEMPTS=6",
+			"TIDYUP_EXCLUDE_PATTERNS=[\"*.x\", \"*.y\"]",
+			"TIDYUP_TRASH=1", // not a config key
+			"HOME=/home/someone",
+		},
+		Set: []string{"retry.max_attempts=7", "categories.docker=true"},
+	}
+	cfg, sources, err := Resolve([]string{user}, overrides)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if cfg.Retry.MaxAttempts != 7 {
+		t.Errorf("--set should win over env and files, got %d", cfg.Retry.MaxAttempts)
+	}
+	if len(cfg.ExcludePattern) != 2 || cfg.ExcludePattern[1] != "*.y" {
+		t.Errorf("expected list from env, got %v", cfg.ExcludePattern)
+	}
+	if !cfg.Categories.Docker {
+		t.Error("expected categories.docker to be set")
+	}
+
+	tests := map[string]string{
+		"retry.max_attempts": "--set retry.max_attempts",
+		"retry.max_wait":     user,
+		"exclude_patterns":   "env TIDYUP_EXCLUDE_PATTERNS",
+		"categories.docker":  "--set categories.docker",
+	}
+	for key, want := range tests {
+		if got := sources.Source(key); got != want {
+			t.Errorf("source of %s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestResolveOverrideErrors(t *testing.T) {
+	tests := map[string]Overrides{
+		"unknown key":    {Set: []string{"no_such_key=1"}},
+		"missing value":  {Set: []string{"retry.max_attempts"}},
+		"wrong type":     {Env: []string{"TIDYUP_RETRY_MAX_ATTEMPTS=many"}},
+		"fails validate": {Set: []string{"retry.max_attempts=-1"}},
+	}
+	for name, overrides := range tests {
+		if _, _, err := Resolve(nil, overrides); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSourcesSection(t *testing.T) {
+	sources := Sources{"retry.max_attempts": "user.yaml"}
+	cfg := GetDefault()
+	if err := setKey(cfg, sources, "retry", "{max_wait: 5s}", "--set retry"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sources.Source("retry.max_attempts"); got != "--set retry" {
+		t.Errorf("setting a section should take over its keys, got %s", got)
+	}
+	if cfg.Retry.MaxWait != "5s" {
+		t.Errorf("expected max_wait 5s, got %s", cfg.Retry.MaxWait)
+	}
+}
+
+// =============================================================================
+// Schema Tests
+// =============================================================================
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+
+	if schema.Schema != SchemaDraft || schema.Type != "object" {
+		t.Fatalf("unexpected root: %+v", schema)
+	}
+	retry := schema.Properties["retry"]
+	if retry == nil || retry.AdditionalProperties != false {
+		t.Fatal("expected a closed retry object")
+	}
+	attempts := retry.Properties["max_attempts"]
+	if attempts.Type != "integer" || attempts.Minimum == nil || *attempts.Minimum != 0 {
+		t.Errorf("unexpected max_attempts schema: %+v", attempts)
+	}
+	if attempts.Description == "" {
+		t.Error("expected the description from the example config")
+	}
+	if theme := schema.Properties["ui"].Properties["theme"]; len(theme.Enum) == 0 {
+		t.Error("expected ui.theme to list its options")
+	}
+	if sudo := schema.Properties["daemon"].Properties["sudo"]; !slices.Equal(sudo.Enum, []string{"never", "notify", "passwordless"}) {
+		t.Errorf("expected daemon.sudo to list its policies, got %v", sudo.Enum)
+	}
+	bindings := schema.Properties["ui"].Properties["keybindings"].Properties["bindings"]
+	if items, ok := bindings.AdditionalProperties.(*SchemaNode); !ok || items.Type != "array" {
+		t.Errorf("expected bindings to map actions to key lists, got %+v", bindings.AdditionalProperties)
+	}
+}
+
+func TestExampleConfigMatchesSchema(t *testing.T) {
+	examples := map[string][]byte{"GetExampleConfig": []byte(GetExampleConfig())}
+	if data, err := os.ReadFile("../../configs/cleanup.example.yaml"); err == nil {
+		examples["configs/cleanup.example.yaml"] = data
+	}
+
+	for name, data := range examples {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(path, data, 0644)
+		if _, err := Load(path); err != nil {
+			t.Errorf("%s doesn't load: %v", name, err)
+		}
+	}
+}
+
+func TestLoadReportsLocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`retry:
+  max_atempts: 2
+ui:
+  theme: blue
+categories:
+  cache: sometimes
+min_file_age: -3
+`), 0644)
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	for _, want := range []string{
+		`line 2, column 3: unknown key "max_atempts" in retry (did you mean "max_attempts"?)`,
+		`line 4, column 10: ui.theme must be one of`,
+		`line 6, column 10: categories.cache must be true or false`,
+		`line 7, column 15: min_file_age must be >= 0`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+// =============================================================================
+// CI Preset Tests
+// =============================================================================
+
+func TestCategoriesOnly(t *testing.T) {
+	cats := GetDefault().Categories
+	if err := cats.Only([]string{"cache", "docker"}); err != nil {
+		t.Fatal(err)
+	}
+	if !cats.Cache || !cats.Docker || cats.Temp || cats.NodeModules || cats.LargeFiles {
+		t.Errorf("expected only cache and docker, got %+v", cats)
+	}
+
+	if err := cats.Only([]string{"cache", "caches"}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+	if !cats.Docker {
+		t.Error("an invalid list should leave the categories unchanged")
+	}
+}
+
+func TestCIMinFreeBytes(t *testing.T) {
+	tests := []struct {
+		minFree string
+		want    uint64
+		wantErr bool
+	}{
+		{"20GB", 20 * 1024 * 1024 * 1024, false},
+		{"10%", 100, false},
+		{" 2.5 % ", 25, false},
+		{"150%", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := CIConfig{MinFree: tt.minFree}.MinFreeBytes(1000)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("MinFreeBytes(%q) = %d, %v; want %d, error %v", tt.minFree, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	cfg := GetDefault()
+	cfg.CI.Categories = []string{"cach"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected Validate to reject an unknown ci category")
+	}
+}
+
+func TestValidateMinSizes(t *testing.T) {
+	cfg := GetDefault()
+	cfg.LargeFiles.MinSize = "1.5GB"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a decimal size to be accepted, got %v", err)
+	}
+
+	cfg.Duplicates.MinSize = "-1MB"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "duplicates_config.min_size") {
+		t.Errorf("expected a negative duplicates_config.min_size to be rejected, got %v", err)
+	}
+
+	cfg = GetDefault()
+	cfg.SizeLimits = SizeLimits{MinFileSize: "2GiB", MaxFileSize: "1.5GB"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must not be larger") {
+		t.Errorf("expected min_file_size above max_file_size to be rejected, got %v", err)
+	}
+	cfg.SizeLimits.MaxFileSize = "ten"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "size_limits.max_file_size") {
+		t.Errorf("expected an invalid max_file_size to be rejected, got %v", err)
+	}
+}
+
+func TestValidateNeverDeleteExtensions(t *testing.T) {
+	cfg := GetDefault()
+	cfg.NeverDeleteExtensions = []string{".pem", ".doc*"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected extensions to be accepted, got %v", err)
+	}
+
+	for _, ext := range []string{"pem", ".doc[", ""} {
+		cfg.NeverDeleteExtensions = []string{ext}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected never_delete_extensions entry %q to be rejected", ext)
+		}
+	}
+}
+
+func TestValidateChecksumMinSize(t *testing.T) {
+	cfg := GetDefault()
+	cfg.Clean.ChecksumMinSize = "100MB"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "clean.audit_log") {
+		t.Errorf("expected checksums without an audit log to be rejected, got %v", err)
+	}
+
+	cfg.Clean.AuditLog = "/var/log/tidyup-audit.jsonl"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected checksums with an audit log to be accepted, got %v", err)
+	}
+
+	cfg.Clean.ChecksumMinSize = "big"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an invalid clean.checksum_min_size to be rejected")
+	}
+}
+
+func TestValidateDaemonSudo(t *testing.T) {
+	cfg := GetDefault()
+	for _, policy := range []string{"", DaemonSudoNever, DaemonSudoNotify, DaemonSudoPasswordless} {
+		cfg.Daemon = &DaemonConfig{Sudo: policy}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected daemon.sudo %q to be accepted, got %v", policy, err)
+		}
+	}
+
+	cfg.Daemon = &DaemonConfig{Sudo: "ask"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "daemon.sudo") {
+		t.Errorf("expected an unknown daemon.sudo to be rejected, got %v", err)
+	}
+}
+
+func TestValidateReportSchedules(t *testing.T) {
+	cfg := GetDefault()
+	cfg.Daemon = &DaemonConfig{Schedules: []CleanupSchedule{
+		{Name: "nightly", Schedule: "0 2 * * *"},
+		{Name: "digest", Schedule: "0 8 * * 1", Action: ActionReport, ReportFormat: "markdown"},
+	}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "smtp_host") {
+		t.Errorf("expected a report schedule without an email server to be rejected, got %v", err)
+	}
+
+	cfg.Daemon.Notifications.Email = EmailConfig{SMTPHost: "smtp.example.com", To: []string{"team@example.com"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected report schedule to be accepted, got %v", err)
+	}
+
+	cfg.Daemon.Schedules[1].ReportFormat = "pdf"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "report_format") {
+		t.Errorf("expected an unknown report_format to be rejected, got %v", err)
+	}
+
+	cfg.Daemon.Schedules[1] = CleanupSchedule{Name: "digest", Action: "delete"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid action") {
+		t.Errorf("expected an unknown action to be rejected, got %v", err)
+	}
+}
+
+func TestValidateScheduleTriggers(t *testing.T) {
+	cfg := GetDefault()
+	cfg.Daemon = &DaemonConfig{Schedules: []CleanupSchedule{{Name: "away", OnIdleMinutes: 30}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected an idle-only schedule to be accepted, got %v", err)
+	}
+	if got := cfg.Daemon.Schedules[0].Triggers(); got != "after 30m idle" {
+		t.Errorf("Triggers() = %q", got)
+	}
+
+	cfg.Daemon.Schedules[0] = CleanupSchedule{Name: "away"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "on_login") {
+		t.Errorf("expected a schedule that never runs to be rejected, got %v", err)
+	}
+
+	cfg.Daemon.Schedules[0] = CleanupSchedule{Name: "away", OnLogin: true, OnIdleMinutes: -5}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "on_idle_minutes") {
+		t.Errorf("expected a negative on_idle_minutes to be rejected, got %v", err)
+	}
+}
+
+func TestQuarantineExpired(t *testing.T) {
+	q := QuarantineConfig{RetentionDays: 7, Retention: map[string]int{"large_files": 30, "logs": 0}}
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	eightDaysAgo := now.AddDate(0, 0, -8)
+
+	if !q.Expired("cache", eightDaysAgo, now) {
+		t.Error("expected a cache file quarantined 8 days ago to have expired")
+	}
+	if q.Expired("cache", now.AddDate(0, 0, -6), now) {
+		t.Error("expected a cache file quarantined 6 days ago to be kept")
+	}
+	if q.Expired("large_files", eightDaysAgo, now) {
+		t.Error("expected the large_files retention to override retention_days")
+	}
+	if q.Expired("logs", eightDaysAgo, now) {
+		t.Error("expected a retention of 0 to keep files forever")
+	}
+
+	cfg := GetDefault()
+	cfg.Quarantine.Retention = map[string]int{"cache": -1}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected a negative quarantine.retention to be rejected")
+	}
+}
+
+func TestSaveOnboarding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "# My settings\nmin_file_age: 48 # two days\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveOnboarding(path, OnboardingConfig{Completed: true, Approved: []string{"cache", "temp"}}); err != nil {
+		t.Fatalf("SaveOnboarding failed: %v", err)
+	}
+	// Saving again replaces the section instead of adding another
+	if err := SaveOnboarding(path, OnboardingConfig{Completed: true, Approved: []string{"cache"}}); err != nil {
+		t.Fatalf("SaveOnboarding failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# two days") {
+		t.Errorf("expected the comments of the file to be kept, got:\n%s", data)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load saved config: %v", err)
+	}
+	if cfg.MinFileAge != 48 {
+		t.Errorf("expected min_file_age to be kept, got %d", cfg.MinFileAge)
+	}
+	if !cfg.Onboarding.Allows("cache") || cfg.Onboarding.Allows("temp") {
+		t.Errorf("expected only cache to be approved, got %v", cfg.Onboarding.Approved)
+	}
+	if !GetDefault().Onboarding.Allows("temp") {
+		t.Error("expected every category to be allowed before onboarding is completed")
+	}
+
+	cfg.Onboarding.Approved = []string{"caches"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an unknown approved category to be rejected")
+	}
+}
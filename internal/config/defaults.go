@@ -1,5 +1,7 @@
 package config
 
+import "github.com/fenilsonani/system-cleanup/internal/paths"
+
 // GetDefault returns the default configuration
 func GetDefault() *Config {
 	return &Config{
@@ -19,6 +21,30 @@ func GetDefault() *Config {
 			OldFiles:   true,
 			// App data - disabled by default - requires explicit opt-in
 			AppData: false,
+			// Duplicates - disabled by default, hashing is slow on large trees
+			Duplicates: false,
+			// WSL leftovers live on the Windows side - requires explicit opt-in
+			WSL: false,
+			// Empty directories - disabled by default, some apps expect theirs
+			EmptyDirs: false,
+			// Virtual machines - disabled by default - requires explicit opt-in
+			VMImages: false,
+			// Cloud CLI caches and logs - disabled by default, dropping the
+			// AWS cache asks for MFA again
+			CloudCLI: false,
+			// Desktop caches are small, and apps start slower while they're rebuilt
+			DesktopCaches: false,
+			// Compiler caches - disabled by default, pruning them slows the
+			// next builds
+			CompilerCaches: false,
+			// LaTeX, R and Julia - disabled by default, for those who use them
+			LaTeX:     false,
+			RPackages: false,
+			Julia:     false,
+			// Stale clones - disabled by default, they're whole repositories
+			StaleRepos: false,
+			// Screenshots - disabled by default, they're personal files
+			Screenshots: false,
 		},
 		AgeThresholds: AgeThresholds{
 			Logs:      30, // 30 days
@@ -41,6 +67,10 @@ func GetDefault() *Config {
 		WhitelistPaths: []string{
 			// User can add paths they want to explicitly protect
 		},
+		NeverDeleteExtensions: []string{
+			// Keys and certificates, whatever category they turn up in
+			".key", ".pem", ".p12", ".pfx", ".kdbx", ".gpg", ".ovpn",
+		},
 		ProtectedPaths: []string{
 			"/",
 			"/System",
@@ -65,6 +95,7 @@ func GetDefault() *Config {
 		DryRun:     false, // Production default - actually delete files
 		MinFileAge: 1,     // 1 hour - never delete files younger than this
 		Verbose:    false,
+		Units:      "binary",
 		Docker: DockerConfig{
 			Enabled:               false,
 			CleanImages:           true,
@@ -79,6 +110,12 @@ func GetDefault() *Config {
 			KeepImages:            []string{},
 			KeepContainers:        []string{},
 			KeepVolumes:           []string{},
+			Registry: RegistryConfig{
+				GarbageCollect: false, // Collection needs the registry stopped or read-only
+				Paths:          []string{"/var/lib/registry"},
+				ConfigFile:     "/etc/docker/registry/config.yml",
+				BuildKitKeep:   "168h",
+			},
 		},
 		SecureDeletion: SecureDeletionConfig{
 			Enabled:      false,           // Disabled by default
@@ -88,6 +125,57 @@ func GetDefault() *Config {
 			ForceSync:    true,            // Force sync to disk
 			BufferSizeKB: 64,              // 64KB buffer
 		},
+		Quarantine: QuarantineConfig{
+			Enabled:       true, // Cleanups can be undone until the files are purged
+			Dir:           paths.File(paths.DataDir, "quarantine"),
+			RetentionDays: 7,
+			PurgeSchedule: "0 * * * *", // Hourly
+		},
+		Sudo: SudoConfig{
+			Never: []string{"large_files", "old_files", "empty_dirs", "screenshots", "downloads", "analyze"}, // Personal files never need root
+		},
+		Confirmation: ConfirmationConfig{
+			TypedThreshold: "50GB",
+			SystemPaths:    true,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:    4,
+			WaitForProcess: false,
+			MaxWait:        "30s",
+		},
+		Clean: CleanConfig{
+			// Cheapest and safest to lose first, personal files last
+			Order: []string{
+				"temp", "logs", "cache", "build_artifacts", "virtual_envs", "node_modules",
+				"docker", "wsl", "app_data", "duplicates", "old_files", "large_files",
+				"empty_dirs", "vm_images", "cloud_cli", "desktop_caches", "compiler_caches",
+				"latex", "r_packages", "julia", "stale_repos", "screenshots", "downloads",
+			},
+			JournalFile:       paths.File(paths.StateDir, "clean-journal.json"),
+			SkipRunningApps:   true,
+			SkipRunningBuilds: true,
+			StateMaxAge:       "1h",
+		},
+		Scan: ScanConfig{
+			MaxResults: 250000, // Roughly 60MB of results
+		},
+		Reports: ReportsConfig{
+			MaxFiles:   30,
+			MaxAgeDays: 90,
+		},
+		Telemetry: TelemetryConfig{
+			ServiceName: "tidyup",
+			Timeout:     "10s",
+		},
+		UI: UIConfig{
+			Theme: "dark",
+			Keybindings: KeybindingsConfig{
+				Preset: "default",
+			},
+			SaveState:         true,
+			StateFile:         paths.File(paths.StateDir, "ui-state.json"),
+			RememberSelection: "remind", // Mark last session's categories, don't pre-select them
+		},
 		Dev: DevConfig{
 			ProjectDirs: []string{
 				"~/Projects",
@@ -143,6 +231,71 @@ func GetDefault() *Config {
 				"~/Documents/Important",
 			},
 		},
+		Duplicates: DuplicatesConfig{
+			MinSize: "1MB",
+			ScanPaths: []string{
+				"~/Downloads",
+				"~/Documents",
+				"~/Desktop",
+				"~/Projects",
+				"~/Developer",
+				"~/Code",
+			},
+			ExcludePaths: []string{
+				"~/Library",
+				"~/.Trash",
+			},
+		},
+		API: APIConfig{
+			Enabled: false, // Remote management is opt-in
+			Listen:  "127.0.0.1:7780",
+		},
+		CI: CIConfig{
+			MinFree:    "20GB",
+			Categories: []string{"cache", "docker"},
+			ToolCaches: []string{
+				"$RUNNER_TOOL_CACHE",    // GitHub Actions
+				"$AGENT_TOOLSDIRECTORY", // Azure Pipelines
+			},
+		},
+		EmptyDirs: EmptyDirsConfig{
+			MinAgeDays: 30,
+		},
+		VMImages: VMImagesConfig{
+			MinAgeDays: 90,
+		},
+		Screenshots: ScreenshotsConfig{
+			ScanPaths:  []string{"~/Desktop", "~/Pictures", "~/Videos"},
+			MinAgeDays: 90,
+		},
+		CloudCLI: CloudCLIConfig{
+			AWS:    true,
+			GCloud: true,
+			Azure:  true,
+		},
+		CompilerCaches: CompilerCachesConfig{
+			CCache:     true,
+			SCCache:    true,
+			Bazel:      true,
+			MaxAgeDays: 30,
+		},
+		LaTeX: LaTeXConfig{
+			ScanPaths:  []string{"~/Documents"},
+			MinAgeDays: 30,
+		},
+		RPackages: RPackagesConfig{
+			MinAgeDays: 90,
+		},
+		Julia: JuliaConfig{
+			MinAgeDays: 90,
+		},
+		StaleRepos: StaleReposConfig{
+			MinAgeMonths: 12,
+		},
+		WSL: WSLConfig{
+			ScanWindowsDrives: false, // DrvFs is catastrophically slow to walk
+			TarballAgeDays:    30,
+		},
 		AppData: AppDataConfig{
 			Enabled: false, // Disabled by default - requires explicit opt-in
 			MinSize: "100MB",
@@ -194,7 +347,8 @@ func GetDefault() *Config {
 func GetExampleConfig() string {
 	return `# TidyUp Configuration File
 # This file controls what gets cleaned and how
-# Location: ~/.config/tidyup/config.yaml
+# Location: $XDG_CONFIG_HOME/tidyup/config.yaml (~/.config/tidyup/config.yaml,
+# or ~/Library/Application Support/tidyup/config.yaml on macOS)
 
 # Enable/disable cleanup categories
 categories:
@@ -207,10 +361,22 @@ categories:
   # Development artifact categories
   node_modules: true     # node_modules folders
   virtual_envs: true     # Python virtual environments (.venv, venv, etc.)
-  build_artifacts: true  # Build output folders (dist, build, target, etc.)
+  build_artifacts: true  # Build output folders (dist, build, target, etc.), vendor, bin, obj, .dart_tool and .build in PHP, Ruby, .NET, Dart and Swift projects
   # Large and old file scanning
   large_files: true      # Find large files (uses Spotlight for fast scanning)
   old_files: true        # Find old unused files
+  duplicates: false      # Files with identical content (keeps the newest copy)
+  wsl: false             # WSL leftovers on the Windows side (old distro tarballs, Docker Desktop WSL data)
+  empty_dirs: false      # Empty directories left behind by earlier cleanups
+  vm_images: false       # Virtual machines, Vagrant boxes and disk images not used in months
+  cloud_cli: false       # AWS, gcloud and Azure CLI caches and logs (pick which below)
+  desktop_caches: false  # Font, icon and MIME type caches in ~/.cache (Linux)
+  compiler_caches: false # ccache, sccache and Bazel disk cache entries past their age or size limit
+  latex: false           # LaTeX auxiliary files (.aux, .log, .toc, ...) and output folders of old documents
+  r_packages: false      # R package caches, and superseded versions in renv's cache
+  julia: false           # Julia precompile caches of older versions and superseded packages
+  stale_repos: false     # Git clones in dev.project_dirs with no commit or fetch in months, everything pushed
+  screenshots: false     # Old screenshots and screen recordings, reported by month
 
 # Age thresholds (in days) - Only clean files older than these thresholds
 age_thresholds:
@@ -250,6 +416,19 @@ protected_paths:
   - "/etc"
   - "/var"
 
+# Files with these extensions are never deleted, whatever category or rule
+# matched them: a final backstop for keys, credentials and documents.
+# Patterns may use wildcards, e.g. ".doc*" for .doc and .docx. Folders that
+# are deleted whole, like node_modules, are matched by their own name.
+never_delete_extensions:
+  - ".key"
+  - ".pem"
+  - ".p12"
+  - ".pfx"
+  - ".kdbx"
+  - ".gpg"
+  - ".ovpn"
+
 # Dry-run mode - When true, shows what would be deleted without actually deleting
 # Set to false to actually delete files (default in production)
 dry_run: false
@@ -261,6 +440,25 @@ min_file_age: 1
 # Verbose output - Show detailed information during execution
 verbose: false
 
+# Language for messages (en, es). Empty picks it from LC_ALL, LC_MESSAGES or LANG
+language: ""
+
+# Units sizes are shown in: binary (KiB, MiB, GiB, powers of 1024) or si (kB,
+# MB, GB, powers of 1000). --si switches to si. Sizes written in this file,
+# like 500MB, are always powers of 1024, whichever is set.
+units: binary
+
+# ==============================================================================
+# CACHE CONFIGURATION
+# ==============================================================================
+# Keep some applications' caches warm while the rest are cleaned. Known names
+# (safari, chrome, firefox, vscode, jetbrains, xcode, slack, spotify, ...)
+# cover all of an app's cache folders; any other name is a folder in
+# ~/Library/Caches or ~/.cache, such as a bundle ID. Case doesn't matter.
+
+cache:
+  exclude_apps: []     # e.g. [com.apple.Safari, JetBrains]
+
 # ==============================================================================
 # DEVELOPMENT ARTIFACTS CONFIGURATION
 # ==============================================================================
@@ -294,12 +492,18 @@ dev:
     - ".bundle"          # Ruby bundler
     - "Pods"             # iOS CocoaPods
 
+  # Slower, but the numbers in reports can be trusted
+  verify_sizes: false  # Count each artifact's files and size exactly instead of estimating them
+
+  # Artifacts are sized in batches; this caps how many run at once
+  size_workers: 0      # 0 to choose from the storage (2 on spinning disks, up to 8 otherwise)
+
 # ==============================================================================
 # LARGE FILES CONFIGURATION
 # ==============================================================================
 # Find large files that may be taking up unnecessary space
 
-large_files:
+large_files_config:
   # Minimum size to consider as "large"
   min_size: "500MB"
 
@@ -333,7 +537,7 @@ large_files:
 # ==============================================================================
 # Find files that haven't been accessed in a long time
 
-old_files:
+old_files_config:
   # Minimum age in days (files not accessed for this many days)
   min_age_days: 180  # 6 months
 
@@ -348,6 +552,146 @@ old_files:
     - "~/Documents/Work"
     - "~/Documents/Important"
 
+# ==============================================================================
+# EMPTY DIRECTORIES CONFIGURATION
+# ==============================================================================
+# Find directories that hold nothing, or nothing but empty directories, such
+# as the ones left behind once their files were cleaned. Only the topmost of
+# a tree of them is reported, and the scan paths themselves are kept.
+
+empty_dirs_config:
+  scan_paths: []       # Default: the cache directories and old_files_config.scan_paths
+  min_age_days: 30     # Only directories unchanged for this long
+
+# ==============================================================================
+# CLOUD CLI CONFIGURATION
+# ==============================================================================
+# Which CLIs the cloud_cli category cleans up after. Credentials and settings
+# are always kept; gcloud's logs alone can reach gigabytes.
+
+cloud_cli:
+  aws: true            # ~/.aws/cli/cache: assumed-role credentials, fetched again (may ask for MFA)
+  gcloud: true         # ~/.config/gcloud logs and caches ($CLOUDSDK_CONFIG)
+  azure: true          # ~/.azure logs, command logs and telemetry ($AZURE_CONFIG_DIR)
+
+# ==============================================================================
+# COMPILER CACHES CONFIGURATION
+# ==============================================================================
+# Prune the compiler caches of build machines, which grow until the disk
+# fills. Entries unused for max_age_days are reported, then the least
+# recently used ones until each cache fits in max_size. ccache is told to
+# recount its size once its entries are deleted.
+
+compiler_caches_config:
+  ccache: true         # $CCACHE_DIR, ~/.ccache or ~/.cache/ccache
+  sccache: true        # sccache's local disk cache ($SCCACHE_DIR)
+  bazel: true          # --disk_cache directories set in ~/.bazelrc or /etc/bazel.bazelrc
+  scan_paths: []       # Other cache directories, e.g. a bazel-remote or shared ccache directory
+  max_age_days: 30     # Entries unused for this long (0 to keep them)
+  max_size: ""         # e.g. "20GB": the most each cache may keep (empty for no limit)
+
+# ==============================================================================
+# LATEX, R AND JULIA CONFIGURATION
+# ==============================================================================
+# The latex category finds the auxiliary files beside .tex documents, and the
+# folders latexmk -outdir and packages like minted write, once they're old.
+# PDFs are kept. r_packages and julia report the package versions and builds
+# for older versions of R and Julia that something newer replaced.
+
+latex_config:
+  scan_paths:          # Searched along with dev.project_dirs
+    - "~/Documents"
+  min_age_days: 30     # Auxiliary files untouched for this long
+
+r_packages_config:
+  min_age_days: 90     # Superseded versions in renv's cache untouched for this long
+
+julia_config:
+  min_age_days: 90     # Superseded packages and precompile caches untouched for this long
+
+# ==============================================================================
+# STALE REPOSITORIES CONFIGURATION
+# ==============================================================================
+# Find the git clones in dev.project_dirs nothing was committed to or fetched
+# into for months. Clones with uncommitted changes, stashes, commits on no
+# remote or no remote at all are left alone; the rest are shown with the URL
+# to clone them again from. Archive one with "tidyup project --archive".
+
+stale_repos_config:
+  min_age_months: 12   # No commit and no fetch for this long
+
+# ==============================================================================
+# VIRTUAL MACHINES CONFIGURATION
+# ==============================================================================
+# Find VirtualBox, VMware and UTM machines, Vagrant boxes no machine was
+# created from, cached Multipass images and QEMU and other disk images that
+# haven't been used in a while. Each machine is reported once with its total
+# size, and all of them need review before they're cleaned.
+
+vm_images_config:
+  scan_paths: []       # Other directories holding disk images (.qcow2, .vdi, .vmdk, .vhd, .vhdx)
+  min_age_days: 90     # Only machines and images not started for this long
+
+# ==============================================================================
+# SCREENSHOTS CONFIGURATION
+# ==============================================================================
+# Find screenshots and screen recordings by the names macOS, Windows, GNOME
+# and KDE give them ("Screenshot 2024-01-02 at 10.11.12.png", "Screen
+# Recording ...", "Screencast from ..."). Reports total them by month.
+
+screenshots_config:
+  scan_paths:          # Searched with their subdirectories
+    - "~/Desktop"
+    - "~/Pictures"
+    - "~/Videos"
+  min_age_days: 90     # Only screenshots older than this
+
+# ==============================================================================
+# DOWNLOADS CONFIGURATION
+# ==============================================================================
+# The downloads category reports what's at the top of the Downloads folder
+# and hasn't changed for age_thresholds.downloads days, each folder as one
+# item. With browser_history, Chrome's, Chromium's, Brave's, Edge's and
+# Firefox's download history is read (read-only, with the sqlite3 command)
+# to say where each file came from.
+
+downloads_config:
+  browser_history: false  # Say which URL and date each file was downloaded from
+  from_hosts: []          # Report downloads from these hosts whatever their age, e.g. ["*.cloudfront.net"]
+
+# ==============================================================================
+# DUPLICATE FILES CONFIGURATION
+# ==============================================================================
+# Find files with identical content. Same-size files are compared by a quick
+# hash of their first and last blocks, then confirmed with a full hash.
+# The newest copy in each group is kept; use clean -i to pick a different one.
+
+duplicates_config:
+  min_size: "1MB"
+
+  scan_paths:
+    - "~/Downloads"
+    - "~/Documents"
+    - "~/Desktop"
+    - "~/Projects"
+    - "~/Developer"
+    - "~/Code"
+
+  exclude_paths:
+    - "~/Library"
+    - "~/.Trash"
+
+# ==============================================================================
+# WSL CONFIGURATION
+# ==============================================================================
+# Only used under Windows Subsystem for Linux. The Windows drives (/mnt/c, ...)
+# are left out of every scan unless scan_windows_drives is set: walking them
+# is very slow and the files belong to Windows.
+
+wsl:
+  scan_windows_drives: false   # Also scan the Windows drives
+  tarball_age_days: 30         # Only report distro tarballs and packages older than this
+
 # ==============================================================================
 # DOCKER CONFIGURATION
 # ==============================================================================
@@ -368,6 +712,19 @@ docker:
   keep_containers: []           # Container names to never delete
   keep_volumes: []              # Volume names to never delete
 
+  # A local registry and BuildKit cache: 'tidyup registry' shows the blobs
+  # garbage collection would reclaim, by age and whether a tag reaches them
+  registry:
+    garbage_collect: false      # Garbage collect them at the end of 'tidyup clean'
+    paths:                      # Registry root directories (filesystem storage driver)
+      - /var/lib/registry
+    config_file: /etc/docker/registry/config.yml  # Read by 'registry garbage-collect'; "" to skip it
+    container: ""               # Container the registry runs in, if it does
+    delete_untagged: false      # Also collect manifests no tag points to
+    buildkit: false             # Analyse and prune a BuildKit daemon's cache with buildctl
+    buildkit_addr: ""           # buildkitd's address ("" for buildctl's default)
+    buildkit_keep_duration: 168h  # Cache used within this long survives a prune
+
 # ==============================================================================
 # SECURE DELETION CONFIGURATION
 # ==============================================================================
@@ -380,5 +737,236 @@ secure_deletion:
   verify_writes: true    # Verify overwrites completed
   force_sync: true       # Force sync to disk after each pass
   buffer_size_kb: 64     # Buffer size in KB
+
+# ==============================================================================
+# QUARANTINE CONFIGURATION
+# ==============================================================================
+# Move files to a quarantine directory instead of deleting them, so the last
+# cleanup can be undone with 'tidyup undo' (or 'u' in the interactive results)
+
+quarantine:
+  enabled: true            # Move files aside so 'tidyup undo' can bring them back
+  dir: "~/.local/share/tidyup/quarantine"   # Default: $XDG_DATA_HOME/tidyup/quarantine
+  # Quarantined files are purged for good once they're older than this, by
+  # the daemon on purge_schedule or by 'tidyup purge'. 0 keeps them.
+  retention_days: 7
+  retention: {}            # Per category, e.g. {large_files: 30, cache: 1}
+  purge_schedule: "0 * * * *"   # Cron expression for the daemon, empty to disable
+
+# ==============================================================================
+# CONFIRMATION
+# ==============================================================================
+# Large or risky cleanups ask you to type the amount ("type 52GB to confirm")
+# instead of y/N. Scripts can pass the amount with --confirm 52GB.
+
+confirmation:
+  typed_threshold: "50GB"  # Empty to always use y/N
+  system_paths: true       # Also for files outside your home directory (always, even with --force)
+
+# ==============================================================================
+# ELEVATED PERMISSIONS
+# ==============================================================================
+# Files that need sudo are only deleted for categories listed here. Others
+# are skipped, even after you've entered your password for the run.
+
+sudo:
+  allow_categories: []   # Empty allows every category, e.g. [logs, temp, cache]
+  never:                 # Never use sudo for these, even if allowed above
+    - large_files
+    - old_files
+    - empty_dirs
+    - screenshots
+    - downloads
+    - analyze            # Files picked in tidyup analyze
+
+# ==============================================================================
+# BUSY FILES
+# ==============================================================================
+# Files locked by another process are retried once everything else is done,
+# instead of being reported as errors straight away
+
+retry:
+  max_attempts: 4          # Retry rounds, waiting 0.5s, 1s, 2s, 4s... between them
+  wait_for_process: false  # Wait for the process holding the file to exit (needs lsof)
+  max_wait: "30s"          # Longest to wait for that process
+
+# ==============================================================================
+# CLEANUP ORDER AND RESUME
+# ==============================================================================
+# Categories are cleaned in this order, cheapest and safest first. Progress is
+# journaled so an interrupted cleanup can be finished with "tidyup clean --resume"
+
+clean:
+  order:
+    - temp
+    - logs
+    - cache
+    - build_artifacts
+    - virtual_envs
+    - node_modules
+    - docker
+    - wsl
+    - app_data
+    - duplicates
+    - old_files
+    - large_files
+    - empty_dirs
+    - vm_images
+    - cloud_cli
+    - desktop_caches
+    - compiler_caches
+    - latex
+    - r_packages
+    - julia
+    - stale_repos
+    - screenshots
+    - downloads
+  journal_file: "~/.local/state/tidyup/clean-journal.json"   # Default: $XDG_STATE_HOME/tidyup/clean-journal.json, empty to disable resuming
+  # Skip browser, IDE and Electron app caches while the app is running;
+  # deleting them underneath it corrupts its profile. Quit the app and retry.
+  skip_running_apps: true
+  # Skip build output and package caches in or above the directory a
+  # compiler or package manager (npm, pip, cargo, gradle, xcodebuild, ...)
+  # is running in, for this run; deleting them mid-build corrupts the build
+  skip_running_builds: true
+  # Append every deleted file to a JSON lines audit log, with the SHA-256 of
+  # those at least checksum_min_size, taken before they're deleted
+  audit_log: ""            # e.g. "~/.local/state/tidyup/audit.jsonl" (empty to disable)
+  checksum_min_size: ""    # e.g. "100MB" (empty to disable; needs audit_log)
+  # How long after 'tidyup scan --save-state' its files can be cleaned with
+  # 'tidyup clean --state'; older scans are refused ("0" for no limit)
+  state_max_age: "1h"
+  # Commands or webhooks run once for each category being cleaned: "pre"
+  # hooks before the cleanup starts and "post" hooks after it ends. They get
+  # TIDYUP_CATEGORY, TIDYUP_FILES, TIDYUP_SIZE and TIDYUP_FILE_LIST (a file
+  # listing the paths), and post hooks TIDYUP_DELETED_FILES,
+  # TIDYUP_DELETED_SIZE and TIDYUP_FAILED_FILES too; webhooks are POSTed the
+  # same as JSON. A category whose pre hook fails isn't cleaned.
+  hooks: []
+  #   - when: pre
+  #     categories: [docker]
+  #     command: "docker compose -f ~/dev/compose.yaml stop"
+  #     timeout: "2m"
+  #   - when: post
+  #     categories: [docker]
+  #     command: "docker compose -f ~/dev/compose.yaml start"
+  #   - when: post
+  #     url: "https://hooks.example.com/tidyup"
+  #     headers:
+  #       Authorization: "Bearer token"
+
+# ==============================================================================
+# SCAN MEMORY AND PARALLELISM
+# ==============================================================================
+# Past max_results, scan results are spilled to a temp file and reports and
+# the interactive review page through them, so a Downloads folder with
+# millions of files doesn't need gigabytes of memory.
+#
+# By default the number of directories read at once depends on the storage
+# being scanned: up to 64 on SSDs, 4 on spinning disks where every extra
+# reader costs a seek, and 32 on network mounts where requests wait on latency.
+
+scan:
+  max_results: 250000  # Results kept in memory (0 for no limit)
+  workers: 0           # Directories read at once (0 to choose from the storage)
+
+# ==============================================================================
+# SAVED REPORTS
+# ==============================================================================
+# 'tidyup report --save' keeps a gzipped JSON report named for when it was
+# made; 'tidyup report list' lists them. The oldest are removed as new ones
+# are saved.
+
+reports:
+  dir: ""              # Default: $XDG_DATA_HOME/tidyup/reports
+  max_files: 30        # Reports kept (0 for no limit)
+  max_age_days: 90     # Remove reports older than this (0 for no limit)
+
+# ==============================================================================
+# TELEMETRY
+# ==============================================================================
+# Export a trace and metrics of every scan and cleanup to an OpenTelemetry
+# collector over OTLP/HTTP: how long each category took to scan, what was
+# found, bytes deleted and reclaimed, and errors. The daemon exports its jobs
+# too. Nothing is exported unless an endpoint is set here or in
+# OTEL_EXPORTER_OTLP_ENDPOINT.
+
+telemetry:
+  endpoint: ""         # e.g. http://localhost:4318
+  headers: {}          # e.g. {x-api-key: "..."}
+  service_name: tidyup
+  timeout: "10s"       # How long an export may take
+
+# ==============================================================================
+# CI MODE (tidyup ci)
+# ==============================================================================
+# A preset for self-hosted build agents (GitHub Actions, Buildkite, ...): when
+# free space runs low, clean caches outside the workspace without prompting
+# and print a JSON summary
+
+ci:
+  min_free: "20GB"     # Clean when free space drops below this (or a percentage, e.g. "10%")
+  path: ""             # Disk to watch, by a path on it (default: the home directory's)
+  categories:          # Never include categories that reach into the workspace
+    - cache
+    - docker
+  tool_caches:         # Toolchain caches emptied as a whole; unset variables are skipped
+    - "$RUNNER_TOOL_CACHE"
+    - "$AGENT_TOOLSDIRECTORY"
+  lock_file: ""        # Skip the cleanup while this file exists (e.g. the agent's busy marker)
+
+# ==============================================================================
+# REMOTE MANAGEMENT API
+# ==============================================================================
+# "tidyup serve" (or the daemon, with enabled: true) serves scan, report, clean
+# and schedule status over HTTP for fleet dashboards. Clients must send the
+# token in token_file as "Authorization: Bearer <token>", present a certificate
+# signed by client_ca, or both. A token is only sent over HTTPS, unless the
+# server listens on a loopback address.
+
+api:
+  enabled: false       # Also serve the API from the daemon
+  listen: "127.0.0.1:7780"   # host:port to listen on
+  token_file: ""       # e.g. /etc/tidyup/api-token
+  tls_cert: ""         # Serve HTTPS with this certificate
+  tls_key: ""
+  client_ca: ""        # Require client certificates signed by this CA (mTLS)
+  allow_clean: false   # Allow cleanups to be started remotely
+
+# ==============================================================================
+# INTERACTIVE VIEW (tidyup clean -i)
+# ==============================================================================
+# Press ? in any view to see the current bindings
+
+ui:
+  theme: "dark"         # Options: dark, light, high-contrast, monochrome
+                        # Colors are turned off when NO_COLOR is set or with --no-ui-color
+  keybindings:
+    preset: "default"   # Options: default, vim, emacs
+    # Override keys per action (replaces the preset's keys for that action)
+    # bindings:
+    #   toggle: ["x", "space"]
+    #   quit: ["q", "ctrl+g"]
+
+  # Keep sort order, column widths and the last filter between sessions
+  save_state: true
+  state_file: "~/.local/state/tidyup/ui-state.json"   # Default: $XDG_STATE_HOME/tidyup/ui-state.json
+
+  # What to do with the categories selected last time
+  #   off:    start with every category selected
+  #   remind: mark last time's categories, press p to apply them
+  #   apply:  pre-select last time's categories
+  remember_selection: "remind"
+
+# ==============================================================================
+# ONBOARDING
+# ==============================================================================
+# 'tidyup onboard' shows a sample of what each enabled category would remove
+# and records the ones you approve here. Once completed, the daemon only
+# cleans approved categories; scans and manual cleanups aren't affected.
+
+onboarding:
+  completed: false
+  approved: []         # e.g. [cache, temp, logs]
 `
 }
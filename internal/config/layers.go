@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SystemConfigPath is the machine-wide config, the lowest precedence layer
+const SystemConfigPath = "/etc/tidyup/config.yaml"
+
+// ProjectConfigFile is the per-directory config, looked up in the working
+// directory. It takes precedence over the system and user configs, but only
+// for projectKeys.
+const ProjectConfigFile = ".tidyup.yaml"
+
+// projectKeys are the top-level keys a project config may set. It's read from
+// whatever directory tidyup runs in, checkouts of other people's code
+// included, so it may only narrow the scan and change how results are
+// reported: nothing that widens what's deleted, loosens a safety check or
+// runs commands.
+var projectKeys = map[string]bool{
+	"exclude_patterns": true, // Added to the lower layers' patterns, not replacing them
+	"scan":             true,
+	"reports":          true,
+	"units":            true,
+	"verbose":          true,
+}
+
+// DefaultSource is the source of keys no config file sets
+const DefaultSource = "default"
+
+// Sources records which config file set each key, by dotted YAML path
+// (e.g. "retry.max_attempts")
+type Sources map[string]string
+
+// Source returns the file or override that set key, or DefaultSource
+func (s Sources) Source(key string) string {
+	for {
+		if source, ok := s[key]; ok {
+			return source
+		}
+		// A whole section may have been set at once
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			return DefaultSource
+		}
+		key = key[:i]
+	}
+}
+
+// Layers returns the config files to merge, lowest precedence first: the
+// system config, userPath and the project config in the working directory
+func Layers(userPath string) []string {
+	layers := []string{SystemConfigPath, userPath}
+	if project := projectConfigPath(); project != "" && project != userPath {
+		layers = append(layers, project)
+	}
+	return layers
+}
+
+// projectConfigPath returns the project config of the working directory, or
+// "" if it can't be found
+func projectConfigPath() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(wd, ProjectConfigFile)
+}
+
+// LoadLayered merges files over the defaults in order, so later files win.
+// Keys a file doesn't set keep the value from the layers below it; lists are
+// replaced, not appended to. Missing files are skipped.
+func LoadLayered(files []string) (*Config, Sources, error) {
+	return Resolve(files, Overrides{})
+}
+
+// Resolve merges files like LoadLayered, then applies the environment and
+// command-line overrides on top
+func Resolve(files []string, overrides Overrides) (*Config, Sources, error) {
+	config := GetDefault()
+	sources := make(Sources)
+	project := projectConfigPath()
+
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue // Empty file
+		}
+		problems := validateNode(doc.Content[0], configSchema(), "")
+		if file == project {
+			problems = append(problems, projectProblems(doc.Content[0])...)
+		}
+		if len(problems) > 0 {
+			return nil, nil, fmt.Errorf("invalid config file %s:\n  %s", file, strings.Join(problems, "\n  "))
+		}
+
+		// Decode on top of the layers so far - this allows partial configs
+		excludes := config.ExcludePattern
+		if err := doc.Decode(config); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+		}
+		if file == project {
+			config.ExcludePattern = appendNew(excludes, config.ExcludePattern)
+		}
+		recordSources(doc.Content[0], "", file, sources)
+	}
+	config.followMigratedPaths()
+
+	if err := overrides.apply(config, sources); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return config, sources, nil
+}
+
+// projectProblems lists the keys set in node, a project config, that
+// aren't projectKeys
+func projectProblems(node *yaml.Node) []string {
+	if node.Kind != yaml.MappingNode {
+		return nil // validateNode reports it
+	}
+	var problems []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !projectKeys[key.Value] {
+			problems = append(problems, fmt.Sprintf("line %d, column %d: %s can only be set in the system or user config, not %s",
+				key.Line, key.Column, key.Value, ProjectConfigFile))
+		}
+	}
+	return problems
+}
+
+// appendNew appends the items of extra that list doesn't hold
+func appendNew(list, extra []string) []string {
+	merged := slices.Clone(list)
+	for _, item := range extra {
+		if !slices.Contains(merged, item) {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// recordSources marks every key set in node as coming from file
+func recordSources(node *yaml.Node, prefix, file string, sources Sources) {
+	if node.Kind != yaml.MappingNode {
+		if prefix != "" {
+			sources[prefix] = file
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		recordSources(node.Content[i+1], joinKey(prefix, node.Content[i].Value), file, sources)
+	}
+}
+
+// joinKey appends key to a dotted path
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Effective returns config as YAML with the source of each value as a
+// comment, for "tidyup config show --effective"
+func Effective(config *Config, sources Sources) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(config); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	annotateSources(&doc, "", sources)
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	enc.Close()
+	return []byte(b.String()), nil
+}
+
+// annotateSources adds a "from <source>" comment to every value in node
+func annotateSources(node *yaml.Node, prefix string, sources Sources) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := joinKey(prefix, key.Value)
+
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			annotateSources(value, path, sources)
+			continue
+		}
+		comment := "from " + sources.Source(path)
+		// Empty lists and maps are written inline, after the key
+		if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+			value.LineComment = comment
+		} else {
+			key.LineComment = comment
+		}
+	}
+}
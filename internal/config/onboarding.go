@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OnboardingConfig records the categories approved in 'tidyup onboard' for
+// cleaning while nobody is watching
+type OnboardingConfig struct {
+	Completed bool     `yaml:"completed"` // Set once 'tidyup onboard' has been run through
+	Approved  []string `yaml:"approved"`  // Categories the daemon may clean
+}
+
+// Allows reports whether category may be cleaned unattended: any enabled one
+// until onboarding is completed, then only the approved ones
+func (o OnboardingConfig) Allows(category string) bool {
+	return !o.Completed || slices.Contains(o.Approved, category)
+}
+
+// SaveOnboarding writes onboarding into the config file at path, leaving the
+// rest of the file, comments included, as it is. The file is created if
+// it doesn't exist.
+func SaveOnboarding(path string, onboarding OnboardingConfig) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a mapping", path)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(onboarding); err != nil {
+		return fmt.Errorf("failed to marshal onboarding: %w", err)
+	}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "onboarding" {
+			root.Content[i+1] = &value
+			replaced = true
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "onboarding"}, &value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
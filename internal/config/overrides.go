@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config keys.
+// retry.max_attempts is set by TIDYUP_RETRY_MAX_ATTEMPTS, for example.
+const EnvPrefix = "TIDYUP_"
+
+// Overrides are settings applied on top of the config files, in order of
+// increasing precedence
+type Overrides struct {
+	Env []string // NAME=value pairs as returned by os.Environ; only TIDYUP_ ones are used
+	Set []string // key=value pairs from --set, e.g. "retry.max_attempts=2"
+}
+
+// EnvName returns the environment variable that overrides key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// apply sets the overridden keys in config and records where they came from.
+// Values are parsed as YAML, so lists can be given as ["a", "b"].
+func (o Overrides) apply(config *Config, sources Sources) error {
+	keys := knownKeys()
+
+	envKeys := make(map[string]string, len(keys))
+	for key := range keys {
+		envKeys[EnvName(key)] = key
+	}
+	// Sorted so a section variable is applied before its keys
+	env := append([]string(nil), o.Env...)
+	sort.Strings(env)
+	for _, pair := range env {
+		name, value, ok := strings.Cut(pair, "=")
+		key, known := envKeys[name]
+		if !ok || !known {
+			continue
+		}
+		if err := setKey(config, sources, key, value, "env "+name); err != nil {
+			return err
+		}
+	}
+
+	for _, pair := range o.Set {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set %q: expected key=value", pair)
+		}
+		key = strings.TrimSpace(key)
+		if !keys[key] {
+			return fmt.Errorf("invalid --set %q: unknown config key %q", pair, key)
+		}
+		if err := setKey(config, sources, key, value, "--set "+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setKey sets one dotted key in config to a YAML value
+func setKey(config *Config, sources Sources, key, value, source string) error {
+	var parsed interface{} = value
+	if value != "" {
+		if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+	}
+
+	// Build {a: {b: value}} for "a.b" and unmarshal it like a config file
+	parts := strings.Split(key, ".")
+	var overlay interface{} = parsed
+	for i := len(parts) - 1; i >= 0; i-- {
+		overlay = map[string]interface{}{parts[i]: overlay}
+	}
+	data, err := yaml.Marshal(overlay)
+	if err == nil {
+		err = yaml.Unmarshal(data, config)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s from %s: %w", key, source, err)
+	}
+
+	for existing := range sources {
+		if strings.HasPrefix(existing, key+".") {
+			delete(sources, existing)
+		}
+	}
+	sources[key] = source
+	return nil
+}
+
+// knownKeys returns every dotted key of the config, sections included
+func knownKeys() map[string]bool {
+	var node yaml.Node
+	keys := make(map[string]bool)
+	if err := node.Encode(GetDefault()); err != nil {
+		return keys
+	}
+
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := joinKey(prefix, node.Content[i].Value)
+			keys[key] = true
+			walk(node.Content[i+1], key)
+		}
+	}
+	walk(&node, "")
+	return keys
+}
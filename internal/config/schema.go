@@ -0,0 +1,308 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaDraft is the JSON Schema version the config schema follows
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaNode is one node of the JSON Schema for the config file
+type SchemaNode struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*SchemaNode `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // false or a *SchemaNode
+	Items                *SchemaNode            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+}
+
+// schemaEnums lists the accepted values of string keys that have a fixed set
+var schemaEnums = map[string][]string{
+	"ui.theme":              {"dark", "light", "high-contrast", "monochrome"},
+	"ui.keybindings.preset": {"default", "vim", "emacs"},
+	"ui.remember_selection": {"off", "remind", "apply"},
+	"units":                 {"binary", "si"},
+	"daemon.sudo":           {"never", "notify", "passwordless"},
+}
+
+// schemaMinimums are the lower bounds Validate enforces on numeric keys
+var schemaMinimums = map[string]int{
+	"age_thresholds.logs":                 0,
+	"age_thresholds.downloads":            0,
+	"age_thresholds.temp":                 0,
+	"empty_dirs_config.min_age_days":      0,
+	"min_file_age":                        0,
+	"retry.max_attempts":                  0,
+	"scan.max_results":                    0,
+	"vm_images_config.min_age_days":       0,
+	"scan.workers":                        0,
+	"dev.size_workers":                    0,
+	"screenshots_config.min_age_days":     0,
+	"compiler_caches_config.max_age_days": 0,
+	"latex_config.min_age_days":           0,
+	"r_packages_config.min_age_days":      0,
+	"julia_config.min_age_days":           0,
+	"stale_repos_config.min_age_months":   0,
+	"quarantine.retention_days":           0,
+	"reports.max_files":                   0,
+	"reports.max_age_days":                0,
+	"wsl.tarball_age_days":                0,
+	"daemon.log_max_files":                0,
+	"daemon.log_max_age_days":             0,
+}
+
+// configSchema is the schema config files are checked against when loaded
+var configSchema = sync.OnceValue(Schema)
+
+// Schema returns the JSON Schema of the config file. Descriptions come from
+// the comments in the example config.
+func Schema() *SchemaNode {
+	docs := exampleDocs()
+	root := schemaFor(reflect.TypeOf(Config{}), "", docs)
+	root.Schema = SchemaDraft
+	root.Title = "tidyup configuration"
+	return root
+}
+
+// schemaFor builds the schema of a Go type; path is its dotted config key
+func schemaFor(t reflect.Type, path string, docs map[string]string) *SchemaNode {
+	node := &SchemaNode{Description: docs[path]}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		node = schemaFor(t.Elem(), path, docs)
+	case reflect.Bool:
+		node.Type = "boolean"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		node.Type = "integer"
+		if min, ok := schemaMinimums[path]; ok {
+			node.Minimum = &min
+		}
+	case reflect.String:
+		node.Type = "string"
+		node.Enum = schemaEnums[path]
+	case reflect.Slice:
+		node.Type = "array"
+		node.Items = schemaFor(t.Elem(), "", docs)
+	case reflect.Map:
+		node.Type = "object"
+		node.AdditionalProperties = schemaFor(t.Elem(), "", docs)
+	case reflect.Struct:
+		node.Type = "object"
+		node.Properties = make(map[string]*SchemaNode)
+		node.AdditionalProperties = false
+		for i := 0; i < t.NumField(); i++ {
+			name := yamlName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			node.Properties[name] = schemaFor(t.Field(i).Type, joinKey(path, name), docs)
+		}
+	}
+	return node
+}
+
+// yamlName returns the key a struct field is stored under, or "" if it isn't
+func yamlName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// exampleDocs reads the comment of each key in the example config
+func exampleDocs() map[string]string {
+	docs := make(map[string]string)
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(GetExampleConfig()), &doc); err != nil || len(doc.Content) == 0 {
+		return docs
+	}
+
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := joinKey(prefix, key.Value)
+			comment := value.LineComment
+			if comment == "" {
+				comment = key.LineComment
+			}
+			// Comments above nested keys are often the tail of the previous
+			// key's comment, so only sections use them
+			if comment == "" && prefix == "" {
+				comment = key.HeadComment
+			}
+			if text := commentText(comment); text != "" {
+				docs[path] = text
+			}
+			walk(value, path)
+		}
+	}
+	walk(doc.Content[0], "")
+	return docs
+}
+
+// commentText turns a YAML comment into plain text, dropping section banners
+// and their titles
+func commentText(comment string) string {
+	var lines []string
+	inBanner := false
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if strings.HasPrefix(line, "==") {
+			inBanner = !inBanner
+			continue
+		}
+		if line == "" || inBanner {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// validateNode checks a parsed config file against the schema, returning
+// every problem with its line and column
+func validateNode(node *yaml.Node, schema *SchemaNode, path string) []string {
+	var problems []string
+	at := func(n *yaml.Node, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("line %d, column %d: %s", n.Line, n.Column, fmt.Sprintf(format, args...)))
+	}
+	name := path
+	if name == "" {
+		name = "config"
+	}
+
+	// An empty value (key:) leaves the default in place
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			at(node, "%s must be a mapping of keys to values", name)
+			return problems
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child, ok := schema.Properties[key.Value]
+			if !ok {
+				if extra, isSchema := schema.AdditionalProperties.(*SchemaNode); isSchema {
+					child = extra
+				} else {
+					msg := fmt.Sprintf("unknown key %q in %s", key.Value, name)
+					if suggestion := closestKey(key.Value, schema.Properties); suggestion != "" {
+						msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+					}
+					at(key, "%s", msg)
+					continue
+				}
+			}
+			problems = append(problems, validateNode(value, child, joinKey(path, key.Value))...)
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			at(node, "%s must be a list", name)
+			return problems
+		}
+		for _, item := range node.Content {
+			problems = append(problems, validateNode(item, schema.Items, path)...)
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!bool" && !yaml11Bools[strings.ToLower(node.Value)]) {
+			at(node, "%s must be true or false, got %q", name, node.Value)
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			at(node, "%s must be a whole number, got %q", name, node.Value)
+		} else if schema.Minimum != nil {
+			var n int
+			if node.Decode(&n) == nil && n < *schema.Minimum {
+				at(node, "%s must be >= %d", name, *schema.Minimum)
+			}
+		}
+	case "string":
+		// An empty string selects the default, so it passes the enum check
+		if node.Kind != yaml.ScalarNode {
+			at(node, "%s must be a single value", name)
+		} else if len(schema.Enum) > 0 && node.Value != "" && !containsString(schema.Enum, node.Value) {
+			at(node, "%s must be one of %s, got %q", name, strings.Join(schema.Enum, ", "), node.Value)
+		}
+	}
+	return problems
+}
+
+// yaml11Bools are the YAML 1.1 spellings of booleans, which the decoder still accepts
+var yaml11Bools = map[string]bool{"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey suggests the known key nearest to a misspelled one
+func closestKey(key string, known map[string]*SchemaNode) string {
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", 3 // Farther than this isn't a typo
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/paths"
 )
 
 // Session represents a saved cleanup session
@@ -26,12 +28,12 @@ type SessionManager struct {
 
 // NewSessionManager creates a new session manager
 func NewSessionManager() (*SessionManager, error) {
-	homeDir, err := os.UserHomeDir()
+	stateDir, err := paths.StateDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	sessionsDir := filepath.Join(homeDir, ".config", "cleanup-cache", "sessions")
+	sessionsDir := filepath.Join(stateDir, "sessions")
 
 	// Create sessions directory if it doesn't exist
 	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
@@ -2,18 +2,24 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/fenilsonani/system-cleanup/internal/api"
 	"github.com/fenilsonani/system-cleanup/internal/cleaner"
 	"github.com/fenilsonani/system-cleanup/internal/config"
 	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/reporter"
+	"github.com/fenilsonani/system-cleanup/internal/runid"
 	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/telemetry"
 )
 
 // Daemon represents the cleanup daemon
@@ -22,9 +28,13 @@ type Daemon struct {
 	scheduler    *Scheduler
 	notifier     *Notifier
 	logger       *Logger
+	history      *History
 	running      bool
+	version      string
 	shutdownCtx  context.Context
 	cancelFunc   context.CancelFunc
+	unlock       func() // Releases the lock file
+	triggers     sync.WaitGroup // The on_login and on_idle_minutes watcher
 	mu           sync.RWMutex
 }
 
@@ -38,7 +48,7 @@ func New(cfg *config.Config) (*Daemon, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Initialize logger
-	logger, err := NewLogger(cfg.Daemon.LogFile, cfg.Daemon.LogLevel)
+	logger, err := NewLogger(cfg.Daemon.LogFile, cfg.Daemon.LogLevel, LogRotationFor(cfg.Daemon))
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to initialize logger: %w", err)
@@ -47,6 +57,7 @@ func New(cfg *config.Config) (*Daemon, error) {
 	daemon := &Daemon{
 		config:      cfg,
 		logger:      logger,
+		history:     NewHistory(StateFile(cfg)),
 		running:     false,
 		shutdownCtx: ctx,
 		cancelFunc:  cancel,
@@ -63,6 +74,11 @@ func New(cfg *config.Config) (*Daemon, error) {
 	return daemon, nil
 }
 
+// SetVersion sets the version reported by the remote management API
+func (d *Daemon) SetVersion(version string) {
+	d.version = version
+}
+
 // Start starts the daemon
 func (d *Daemon) Start() error {
 	d.mu.Lock()
@@ -74,6 +90,11 @@ func (d *Daemon) Start() error {
 	d.mu.Unlock()
 
 	d.logger.Info("Starting cleanup daemon")
+	if d.config.Onboarding.Completed {
+		d.logger.Info("Cleaning only the categories approved in onboarding: %s", strings.Join(d.config.Onboarding.Approved, ", "))
+	} else {
+		d.logger.Info("Onboarding not completed, every enabled category is cleaned; run 'tidyup onboard' to choose")
+	}
 
 	// Check lock file
 	if err := d.acquireLock(); err != nil {
@@ -96,6 +117,27 @@ func (d *Daemon) Start() error {
 	}
 	defer d.scheduler.Stop()
 
+	// Run schedules when the user logs in or steps away
+	if hasTriggers(d.config.Daemon.Schedules) {
+		platformInfo, err := platform.GetInfo()
+		if err != nil {
+			return fmt.Errorf("failed to get platform info: %w", err)
+		}
+		d.triggers.Add(1)
+		go func() {
+			defer d.triggers.Done()
+			d.watchTriggers(platformInfo.Username)
+		}()
+		defer d.triggers.Wait()
+	}
+
+	// Serve the remote management API alongside the schedules
+	if d.config.API.Enabled {
+		if err := d.startAPI(); err != nil {
+			return fmt.Errorf("failed to start API server: %w", err)
+		}
+	}
+
 	d.logger.Info("Daemon started successfully")
 
 	// Send startup notification
@@ -134,10 +176,47 @@ func (d *Daemon) IsRunning() bool {
 	return d.running
 }
 
-// RunCleanupJob executes a cleanup job
+// RunCleanupJob executes a cleanup job and records the run in the daemon
+// state
 func (d *Daemon) RunCleanupJob(job *CleanupJob) error {
-	d.logger.Info("Running cleanup job: %s", job.Name)
-	startTime := time.Now()
+	run := &RunRecord{
+		Job:         job.Name,
+		RunID:       runid.New(),
+		TriggeredBy: job.TriggeredBy,
+		Action:      job.Action,
+		DryRun:      job.DryRun,
+		Started:     time.Now(),
+	}
+	if run.TriggeredBy == "" {
+		run.TriggeredBy = TriggerSchedule
+	}
+	if run.Action == "" {
+		run.Action = config.ActionClean
+	}
+
+	trace := telemetry.New(d.config, d.version).Start("tidyup "+run.Action, run.RunID, run.Started)
+	trace.SetJob(job.Name)
+	err := d.runCleanupJob(job, run, trace)
+	if exportErr := trace.End(err); exportErr != nil {
+		d.logger.Warn("Job %s: %v", job.Name, exportErr)
+	}
+	run.Finished = time.Now()
+	if err != nil {
+		run.Failed = err.Error()
+	}
+	if recordErr := d.history.Record(*run); recordErr != nil {
+		d.logger.Warn("Couldn't record run of job %s: %v", job.Name, recordErr)
+	}
+	return err
+}
+
+// runCleanupJob scans and cleans for a job, filling in run and trace as it
+// goes
+func (d *Daemon) runCleanupJob(job *CleanupJob, run *RunRecord, trace *telemetry.Run) error {
+	runID := run.RunID
+	logger := d.logger.WithRun(runID)
+	logger.Info("Running cleanup job: %s (run %s)", job.Name, runID)
+	startTime := run.Started
 
 	// Get platform info
 	platformInfo, err := platform.GetInfo()
@@ -150,46 +229,249 @@ func (d *Daemon) RunCleanupJob(job *CleanupJob) error {
 
 	// Create scanner (HyperScanner for blazing fast cached scans)
 	scnr := scanner.NewHyperScanner(jobConfig, platformInfo)
+	scnr.SetContext(d.shutdownCtx)
 
 	// Perform scan
 	scanResult, err := scnr.ScanAll()
 	if err != nil {
-		d.logger.Error("Scan failed for job %s: %v", job.Name, err)
+		logger.Error("Scan failed for job %s: %v", job.Name, err)
 		return fmt.Errorf("scan failed: %w", err)
 	}
-
-	d.logger.Info("Scan completed for job %s: %d files, %d bytes",
+	defer scanResult.Close()
+	trace.Scan(scanResult, startTime, scnr.CategoryDurations())
+
+	run.FilesFound = scanResult.TotalCount
+	if totals, err := scanResult.CategoryTotals(); err == nil {
+		run.Categories = make(map[string]CategoryFound, len(totals))
+		for category, total := range totals {
+			run.Categories[category] = CategoryFound{Files: total.Count, Size: total.Size}
+		}
+	}
+	for _, err := range scanResult.Errors {
+		logger.Warn("Scan for job %s: %v", job.Name, err)
+		run.Errors = append(run.Errors, err.Error())
+	}
+	logger.Info("Scan completed for job %s: %d files, %d bytes",
 		job.Name, scanResult.TotalCount, scanResult.TotalSize)
 
+	// Report jobs email what a cleanup would remove and delete nothing
+	if job.Action == config.ActionReport {
+		meta := reporter.NewMetadata(platformInfo, jobConfig, time.Since(startTime), scnr.Engine(), d.version)
+		meta.RunID = runID
+		return d.runReportJob(job, scanResult, meta)
+	}
+
 	// Skip cleanup if dry-run
 	if jobConfig.DryRun {
-		d.logger.Info("Dry-run mode - skipping cleanup for job %s", job.Name)
+		run.DryRun = true
+		logger.Info("Dry-run mode - skipping cleanup for job %s", job.Name)
 		return nil
 	}
 
 	// Create cleaner
 	clnr := cleaner.New(jobConfig)
+	clnr.SetRunID(runID)
+	clnr.SetContext(d.shutdownCtx)
+	clnr.SetSpread(job.Spread)
+
+	// There's no terminal to ask for a password on
+	clnr.SetAskSudo(false)
+	clnr.SetSessionSudo(d.config.Daemon.Sudo == config.DaemonSudoPasswordless)
 
 	// Perform cleanup
+	cleanStart := time.Now()
 	cleanResult, err := clnr.Clean(scanResult)
+	if cleanResult != nil {
+		// An interrupted cleanup still reports what it deleted
+		trace.Clean(cleanResult, cleanStart)
+		run.FilesDeleted = len(cleanResult.DeletedFiles)
+		run.BytesDeleted = cleanResult.DeletedSize
+		run.BytesReclaimed = cleanResult.ReclaimedSize()
+		for _, cleanErr := range cleanResult.Errors {
+			run.Errors = append(run.Errors, cleanErr.Error())
+		}
+		for _, hookErr := range cleanResult.HookErrors {
+			logger.Warn("Cleanup job %s: %s", job.Name, hookErr)
+			run.Errors = append(run.Errors, hookErr)
+		}
+	}
 	if err != nil {
-		d.logger.Error("Cleanup failed for job %s: %v", job.Name, err)
+		logger.Error("Cleanup failed for job %s: %v", job.Name, err)
 		return fmt.Errorf("cleanup failed: %w", err)
 	}
 
 	// Log results
 	duration := time.Since(startTime)
-	d.logger.Info("Cleanup job %s completed in %v: deleted %d files, freed %d bytes, %d errors",
-		job.Name, duration, len(cleanResult.DeletedFiles), cleanResult.DeletedSize, len(cleanResult.Errors))
+	logger.Info("Cleanup job %s completed in %v: deleted %d files (%d bytes), free space grew by %d bytes, %d errors",
+		job.Name, duration, len(cleanResult.DeletedFiles), cleanResult.DeletedSize, cleanResult.ReclaimedSize(), len(cleanResult.Errors))
+	for _, fs := range cleanResult.Filesystems {
+		if fs.Note != "" {
+			logger.Warn("Cleanup job %s: %s: deleted %d bytes but freed %d. %s", job.Name, fs.Path, fs.DeletedSize, fs.Reclaimed(), fs.Note)
+		}
+	}
 
 	// Send notification
 	if d.notifier != nil {
 		d.notifier.SendCleanupNotification(job, cleanResult, duration)
 	}
 
+	if len(cleanResult.NeedsSudo) > 0 {
+		d.reportNeedsSudo(logger, job, cleanResult, scanResult)
+	}
+
+	return nil
+}
+
+// PurgeQuarantine permanently deletes the quarantined files past their
+// retention
+func (d *Daemon) PurgeQuarantine() {
+	q := cleaner.NewQuarantine(d.config.Quarantine.Dir)
+	now := time.Now()
+	purged, size, errs := q.Purge(func(entry cleaner.QuarantineEntry) bool {
+		return d.config.Quarantine.Expired(entry.Category, entry.MovedAt, now)
+	})
+	for _, err := range errs {
+		d.logger.Warn("Quarantine purge: %v", err)
+	}
+	if purged > 0 {
+		d.logger.Info("Purged %d expired files (%d bytes) from the quarantine", purged, size)
+	}
+}
+
+// reportNeedsSudo logs the files a cleanup left because they need sudo and,
+// with the notify policy, sends a notification listing them. It's sent
+// whether or not notifications are enabled, since the policy asks for it.
+func (d *Daemon) reportNeedsSudo(logger *Logger, job *CleanupJob, result *cleaner.CleanResult, scanResult *scanner.ScanResult) {
+	needsSudo := make(map[string]bool, len(result.NeedsSudo))
+	for _, path := range result.NeedsSudo {
+		needsSudo[path] = true
+	}
+	var size int64
+	for _, file := range scanResult.Files {
+		if needsSudo[file.Path] {
+			size += file.Size
+		}
+	}
+
+	logger.Warn("Cleanup job %s: skipped %d files (%d bytes) that need sudo (daemon.sudo: %s)",
+		job.Name, len(result.NeedsSudo), size, d.sudoPolicy())
+	for _, path := range result.NeedsSudo {
+		logger.Debug("Needs sudo: %s", path)
+	}
+
+	if d.config.Daemon.Sudo != config.DaemonSudoNotify {
+		return
+	}
+	notifier := d.notifier
+	if notifier == nil {
+		notifier = NewNotifier(&d.config.Daemon.Notifications, d.logger)
+	}
+	notifier.SendSudoNotification(job, result, size)
+}
+
+// sudoPolicy returns the daemon's sudo policy, "never" if it isn't set
+func (d *Daemon) sudoPolicy() string {
+	if d.config.Daemon.Sudo == "" {
+		return config.DaemonSudoNever
+	}
+	return d.config.Daemon.Sudo
+}
+
+// runReportJob renders a digest of scanResult and emails it
+func (d *Daemon) runReportJob(job *CleanupJob, scanResult *scanner.ScanResult, meta *reporter.Metadata) error {
+	format := reporter.FormatHTML
+	if job.ReportFormat == string(reporter.FormatMarkdown) {
+		format = reporter.FormatMarkdown
+	}
+
+	var body strings.Builder
+	rptr := reporter.New(&body, format)
+	rptr.SetMetadata(meta)
+	if err := rptr.Report(scanResult); err != nil {
+		d.logger.Error("Report failed for job %s: %v", job.Name, err)
+		return fmt.Errorf("report failed: %w", err)
+	}
+
+	notifier := d.notifier
+	if notifier == nil {
+		notifier = NewNotifier(&d.config.Daemon.Notifications, d.logger)
+	}
+	if err := notifier.SendReport(job, scanResult, body.String()); err != nil {
+		d.logger.Error("Report job %s: %v", job.Name, err)
+		return err
+	}
+	return nil
+}
+
+// startAPI serves the remote management API until the daemon shuts down
+func (d *Daemon) startAPI() error {
+	platformInfo, err := platform.GetInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get platform info: %w", err)
+	}
+	server, err := api.New(d.config, platformInfo, d.version)
+	if err != nil {
+		return err
+	}
+	server.SetSchedules(d.scheduleStatus)
+	server.SetRuns(d.runSummaries)
+
+	go func() {
+		if err := server.ListenAndServe(d.shutdownCtx); err != nil {
+			d.logger.Error("API server stopped: %v", err)
+		}
+	}()
+	d.logger.Info("Serving the API on %s", d.config.API.Listen)
 	return nil
 }
 
+// scheduleStatus reports the scheduled jobs to the API
+func (d *Daemon) scheduleStatus() []api.ScheduleStatus {
+	var status []api.ScheduleStatus
+	for _, job := range d.scheduler.ListJobs() {
+		s := api.ScheduleStatus{
+			Name:     job.Name,
+			Schedule: job.Schedule,
+			DryRun:   job.DryRun,
+			NextRun:  job.NextRun,
+		}
+		if !job.PrevRun.IsZero() {
+			prev := job.PrevRun
+			s.LastRun = &prev
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// runSummaries reports the recorded runs to the API, oldest first
+func (d *Daemon) runSummaries() ([]api.RunSummary, error) {
+	runs, err := d.history.Runs()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]api.RunSummary, len(runs))
+	for i, run := range runs {
+		summaries[i] = api.RunSummary{
+			Job:            run.Job,
+			Started:        run.Started,
+			Finished:       run.Finished,
+			Failed:         run.Failed != "",
+			FilesFound:     run.FilesFound,
+			FilesDeleted:   run.FilesDeleted,
+			BytesDeleted:   run.BytesDeleted,
+			BytesReclaimed: run.BytesReclaimed,
+		}
+		if run.Categories != nil {
+			summaries[i].Categories = make(map[string]api.CategorySummary, len(run.Categories))
+			for category, found := range run.Categories {
+				summaries[i].Categories[category] = api.CategorySummary{Files: found.Files, Size: found.Size}
+			}
+		}
+	}
+	return summaries, nil
+}
+
 // createJobConfig creates a config for a specific job
 func (d *Daemon) createJobConfig(job *CleanupJob) *config.Config {
 	// Copy base config
@@ -205,6 +487,17 @@ func (d *Daemon) createJobConfig(job *CleanupJob) *config.Config {
 		cfg.Categories.Docker = job.Categories["docker"]
 	}
 
+	// Leave out the categories not approved in 'tidyup onboard'
+	if cfg.Onboarding.Completed {
+		var approved []string
+		for _, category := range cfg.Categories.Enabled() {
+			if cfg.Onboarding.Allows(category) {
+				approved = append(approved, category)
+			}
+		}
+		cfg.Categories.Only(approved)
+	}
+
 	// Override dry-run
 	if job.DryRun {
 		cfg.DryRun = true
@@ -222,7 +515,14 @@ func (d *Daemon) setupSignalHandlers() {
 		for sig := range sigChan {
 			switch sig {
 			case syscall.SIGINT, syscall.SIGTERM:
-				d.logger.Info("Received shutdown signal: %v", sig)
+				if d.shutdownCtx.Err() != nil {
+					// A second signal doesn't wait for the running job
+					d.logger.Warn("Received %v again, exiting now", sig)
+					d.removePidFile()
+					d.releaseLock()
+					os.Exit(1)
+				}
+				d.logger.Info("Received shutdown signal: %v, stopping the running job", sig)
 				d.Stop()
 			case syscall.SIGHUP:
 				d.logger.Info("Received reload signal")
@@ -232,14 +532,42 @@ func (d *Daemon) setupSignalHandlers() {
 	}()
 }
 
-// acquireLock acquires the lock file
+// lockFile returns the path of the daemon's lock file
+func lockFile(cfg *config.Config) string {
+	if cfg.Daemon != nil && cfg.Daemon.PidFile != "" {
+		return cfg.Daemon.PidFile + ".lock"
+	}
+	return "/var/run/cleanup-cache.lock"
+}
+
+// acquireLock locks the lock file for as long as the daemon runs. The lock
+// goes away with the process, so a lock file left by a daemon that crashed is
+// taken over rather than stopping this one from starting.
 func (d *Daemon) acquireLock() error {
-	lockFile := d.config.Daemon.PidFile + ".lock"
-	if lockFile == ".lock" {
-		lockFile = "/var/run/cleanup-cache.lock"
+	path := lockFile(d.config)
+	unlock, err := platform.TryLockFile(path)
+	if errors.Is(err, platform.ErrUnsupportedPlatform) {
+		return d.acquireLockExclusive(path)
+	}
+	if errors.Is(err, platform.ErrLocked) {
+		return fmt.Errorf("daemon already running (%s is locked)", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		unlock()
+		return err
 	}
+	d.unlock = unlock
+	return nil
+}
 
-	file, err := os.OpenFile(lockFile, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+// acquireLockExclusive creates the lock file, failing if it exists, where
+// file locks aren't available
+func (d *Daemon) acquireLockExclusive(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
 	if err != nil {
 		if os.IsExist(err) {
 			return fmt.Errorf("daemon already running (lock file exists)")
@@ -253,13 +581,14 @@ func (d *Daemon) acquireLock() error {
 	return err
 }
 
-// releaseLock releases the lock file
+// releaseLock removes the lock file, then releases the lock
 func (d *Daemon) releaseLock() error {
-	lockFile := d.config.Daemon.PidFile + ".lock"
-	if lockFile == ".lock" {
-		lockFile = "/var/run/cleanup-cache.lock"
+	err := os.Remove(lockFile(d.config))
+	if d.unlock != nil {
+		d.unlock()
+		d.unlock = nil
 	}
-	return os.Remove(lockFile)
+	return err
 }
 
 // writePidFile writes the PID file
@@ -286,16 +615,18 @@ func (d *Daemon) removePidFile() error {
 type Logger struct {
 	logger   *log.Logger
 	logLevel string
-	file     *os.File
+	file     *rotatingFile
+	prefix   string // Tags every message, e.g. with the run it's about
 }
 
-// NewLogger creates a new logger
-func NewLogger(logFile, logLevel string) (*Logger, error) {
-	var file *os.File
+// NewLogger creates a new logger writing to logFile, rotated as rotation
+// says, or to stdout if logFile is empty
+func NewLogger(logFile, logLevel string, rotation LogRotation) (*Logger, error) {
+	var file *rotatingFile
 	var err error
 
 	if logFile != "" {
-		file, err = os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		file, err = openRotatingFile(logFile, rotation)
 		if err != nil {
 			return nil, err
 		}
@@ -315,25 +646,33 @@ func NewLogger(logFile, logLevel string) (*Logger, error) {
 	}, nil
 }
 
+// WithRun returns a logger writing to the same place that tags every message
+// with a run ID, so a job's lines can be matched to its manifest and reports
+func (l *Logger) WithRun(id string) *Logger {
+	tagged := *l
+	tagged.prefix = fmt.Sprintf("[run %s] ", runid.Short(id))
+	return &tagged
+}
+
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
-	l.logger.Printf("[INFO] "+format, args...)
+	l.logger.Printf("[INFO] "+l.prefix+format, args...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
-	l.logger.Printf("[ERROR] "+format, args...)
+	l.logger.Printf("[ERROR] "+l.prefix+format, args...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
-	l.logger.Printf("[WARN] "+format, args...)
+	l.logger.Printf("[WARN] "+l.prefix+format, args...)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
 	if l.logLevel == "debug" {
-		l.logger.Printf("[DEBUG] "+format, args...)
+		l.logger.Printf("[DEBUG] "+l.prefix+format, args...)
 	}
 }
 
@@ -344,3 +683,55 @@ func (l *Logger) Close() error {
 	}
 	return nil
 }
+
+// Status reports the daemon's PID from its PID file and whether that process
+// is still running. Where file locks are available the daemon holds one on
+// its lock file, so a PID file left by a crash isn't mistaken for a running
+// daemon when another process has reused the PID. Elsewhere, a lock file left
+// by a daemon that died is reported as staleLock, since it stops the next
+// daemon from starting.
+func Status(cfg *config.Config) (pid int, running bool, staleLock string, err error) {
+	pidFile, lock := "/var/run/cleanup-cache.pid", lockFile(cfg)
+	if cfg.Daemon != nil && cfg.Daemon.PidFile != "" {
+		pidFile = cfg.Daemon.PidFile
+	}
+
+	held, lockErr := platform.LockHeld(lock)
+	locking := lockErr == nil
+	if data, readErr := os.ReadFile(lock); readErr == nil && !locking {
+		var lockPid int
+		if _, scanErr := fmt.Sscan(string(data), &lockPid); scanErr != nil || !processRunning(lockPid) {
+			staleLock = lock
+		}
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, staleLock, nil
+		}
+		return 0, false, staleLock, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	if _, err := fmt.Sscan(string(data), &pid); err != nil {
+		return 0, false, staleLock, fmt.Errorf("invalid PID file %s: %w", pidFile, err)
+	}
+	running = processRunning(pid)
+	if locking && !held {
+		running = false
+	}
+	return pid, running, staleLock, nil
+}
+
+// processRunning reports whether a process with the given PID exists
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 only checks; EPERM means it exists but belongs to another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
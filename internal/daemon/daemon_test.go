@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	r, err := openRotatingFile(path, LogRotation{MaxSize: 100, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 10; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	files := LogFiles(path)
+	if len(files) != 3 {
+		t.Fatalf("log files = %v, want the log and 2 rotated ones", files)
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s is %d bytes, over the 100 byte cap", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 rotated logs should be kept")
+	}
+}
+
+func TestRotatingFilePrunesOldLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	old := path + ".1"
+	if err := os.WriteFile(old, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(old, past, past)
+
+	r, err := openRotatingFile(path, LogRotation{MaxSize: 100, MaxFiles: 2, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	r.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("a rotated log past the max age should be removed")
+	}
+}
+
+func TestLogTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	var content strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&content, "line %d %s\n", i, strings.Repeat("-", 40))
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := LogTail(path, 3)
+	if err != nil {
+		t.Fatalf("LogTail failed: %v", err)
+	}
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "line 4998 ") || !strings.HasPrefix(lines[2], "line 5000 ") {
+		t.Errorf("LogTail = %q, want lines 4998 to 5000", lines)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.log")
+	os.WriteFile(empty, nil, 0644)
+	if lines, err := LogTail(empty, 3); err != nil || len(lines) != 0 {
+		t.Errorf("LogTail of an empty log = %q, %v", lines, err)
+	}
+}
+
+func TestTriggerWatcherNewSession(t *testing.T) {
+	w := &triggerWatcher{}
+	if w.newSession([]string{"2"}) {
+		t.Error("sessions open when the daemon starts shouldn't count as logins")
+	}
+	if w.newSession([]string{"2"}) {
+		t.Error("an unchanged session list isn't a login")
+	}
+	if !w.newSession([]string{"2", "7"}) {
+		t.Error("a new session should count as a login")
+	}
+	if w.newSession([]string{"7"}) {
+		t.Error("a session ending isn't a login")
+	}
+}
@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+)
+
+// What started a run
+const (
+	TriggerSchedule = "schedule" // Its cron schedule fired
+	TriggerManual   = "manual"   // It was triggered by hand
+	TriggerLogin    = "login"    // The user logged in (on_login)
+	TriggerIdle     = "idle"     // The user stepped away (on_idle_minutes)
+)
+
+// maxRuns is how many runs the daemon state keeps; older ones are dropped
+const maxRuns = 200
+
+// RunRecord is the outcome of one run of a scheduled job
+type RunRecord struct {
+	Job            string    `json:"job"`
+	RunID          string    `json:"run_id"`
+	TriggeredBy    string    `json:"triggered_by"`
+	Action         string    `json:"action"`
+	DryRun         bool      `json:"dry_run,omitempty"`
+	Started        time.Time `json:"started"`
+	Finished       time.Time `json:"finished"`
+	FilesFound     int       `json:"files_found"`
+	FilesDeleted   int       `json:"files_deleted"`
+	BytesDeleted   int64     `json:"bytes_deleted"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+	Errors         []string  `json:"errors,omitempty"`
+	Failed         string    `json:"failed,omitempty"` // Why the run stopped, if it did
+
+	Categories map[string]CategoryFound `json:"categories,omitempty"` // What the scan found, by category
+}
+
+// CategoryFound is what a run's scan found in one category
+type CategoryFound struct {
+	Files int   `json:"files"`
+	Size  int64 `json:"size"`
+}
+
+// Duration returns how long the run took
+func (r *RunRecord) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+// daemonState is what the daemon keeps between restarts
+type daemonState struct {
+	Runs []RunRecord `json:"runs"` // Oldest first
+}
+
+// History records the daemon's runs in its state file
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// StateFile returns the daemon state file: daemon.state_file, or
+// daemon-state.json in the state directory
+func StateFile(cfg *config.Config) string {
+	if cfg.Daemon != nil && cfg.Daemon.StateFile != "" {
+		return cfg.Daemon.StateFile
+	}
+	return paths.File(paths.StateDir, "daemon-state.json")
+}
+
+// NewHistory returns the run history kept in the daemon state file at path
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Runs returns the recorded runs, oldest first. It's empty if the daemon
+// hasn't finished a run yet.
+func (h *History) Runs() ([]RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	return state.Runs, nil
+}
+
+// Last returns the most recent run of each job, by job name
+func (h *History) Last() (map[string]RunRecord, error) {
+	runs, err := h.Runs()
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[string]RunRecord)
+	for _, run := range runs {
+		last[run.Job] = run
+	}
+	return last, nil
+}
+
+// Record appends a run, dropping the oldest beyond maxRuns
+func (h *History) Record(run RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.path == "" {
+		return fmt.Errorf("no daemon state file")
+	}
+	state, err := h.load()
+	if err != nil {
+		// A corrupt state file only costs the earlier history
+		state = &daemonState{}
+	}
+	state.Runs = append(state.Runs, run)
+	if len(state.Runs) > maxRuns {
+		state.Runs = state.Runs[len(state.Runs)-maxRuns:]
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode daemon state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	// Write a temporary file and rename it so a crash can't leave half a file
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write daemon state: %w", err)
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write daemon state: %w", err)
+	}
+	return nil
+}
+
+// load reads the state file; a missing one is an empty state
+func (h *History) load() (*daemonState, error) {
+	state := &daemonState{}
+	if h.path == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read daemon state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon state %s: %w", h.path, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,220 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// Log rotation defaults, used when the daemon config leaves them unset
+const (
+	defaultLogMaxSize    = 10 << 20 // 10MB
+	defaultLogMaxFiles   = 5
+	defaultLogMaxAgeDays = 30
+)
+
+// LogRotation caps how much disk the daemon's own log takes. The log is
+// rotated to log.1, log.2, ... once it reaches MaxSize, and rotated logs past
+// MaxFiles or older than MaxAge are removed.
+type LogRotation struct {
+	MaxSize  int64
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// LogRotationFor returns the log rotation set in the daemon config, with the
+// defaults for anything left unset
+func LogRotationFor(cfg *config.DaemonConfig) LogRotation {
+	rotation := LogRotation{
+		MaxSize:  defaultLogMaxSize,
+		MaxFiles: defaultLogMaxFiles,
+		MaxAge:   defaultLogMaxAgeDays * 24 * time.Hour,
+	}
+	if cfg == nil {
+		return rotation
+	}
+	if size, err := utils.ParseSize(cfg.LogMaxSize); err == nil && size > 0 {
+		rotation.MaxSize = size
+	}
+	if cfg.LogMaxFiles > 0 {
+		rotation.MaxFiles = cfg.LogMaxFiles
+	}
+	if cfg.LogMaxAgeDays > 0 {
+		rotation.MaxAge = time.Duration(cfg.LogMaxAgeDays) * 24 * time.Hour
+	}
+	return rotation
+}
+
+// rotatingFile is a log file that rotates itself as it's written to
+type rotatingFile struct {
+	path     string
+	rotation LogRotation
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotatingFile opens the log at path for appending
+func openRotatingFile(path string, rotation LogRotation) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, rotation: rotation}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.prune()
+	return r, nil
+}
+
+// open opens the current log and notes its size
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating the log first if p would take it past MaxSize
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.rotation.MaxSize {
+		if err := r.rotate(); err != nil {
+			// Keep logging to the current file rather than lose messages
+			fmt.Fprintf(os.Stderr, "Couldn't rotate %s: %v\n", r.path, err)
+		}
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts log.N to log.N+1, the current log to log.1, and starts a new
+// one. The caller must hold mu.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	os.Remove(rotatedName(r.path, r.rotation.MaxFiles))
+	for i := r.rotation.MaxFiles - 1; i >= 1; i-- {
+		os.Rename(rotatedName(r.path, i), rotatedName(r.path, i+1))
+	}
+	if err := os.Rename(r.path, rotatedName(r.path, 1)); err != nil {
+		return err
+	}
+	r.prune()
+	return r.open()
+}
+
+// prune removes rotated logs older than MaxAge
+func (r *rotatingFile) prune() {
+	if r.rotation.MaxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-r.rotation.MaxAge)
+	for i := 1; i <= r.rotation.MaxFiles; i++ {
+		name := rotatedName(r.path, i)
+		if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(name)
+		}
+	}
+}
+
+// Close closes the current log
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotatedName returns the name of the nth rotated log
+func rotatedName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// LogFiles returns the daemon's log and its rotated logs that exist, newest
+// first
+func LogFiles(path string) []string {
+	var files []string
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	rotated, _ := filepath.Glob(path + ".*")
+	for i := 1; i <= len(rotated); i++ {
+		name := rotatedName(path, i)
+		if _, err := os.Stat(name); err == nil {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// LogTail returns the last n lines of the log at path. It reads from the end
+// of the file, so a large log isn't read in full.
+func LogTail(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read blocks backwards until there are enough lines
+	const blockSize = 64 << 10
+	var data []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(data, []byte("\n")) <= n {
+		start := max(offset-blockSize, 0)
+		block := make([]byte, offset-start)
+		if _, err := f.ReadAt(block, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(block, data...)
+		offset = start
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if offset > 0 {
+		// The first line may have been cut at the block boundary
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	return lines, nil
+}
@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/smtp"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/fenilsonani/system-cleanup/internal/cleaner"
 	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/reporter"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 // Notifier handles notifications for the daemon
@@ -93,12 +97,14 @@ func (n *Notifier) SendCleanupNotification(job *CleanupJob, result *cleaner.Clea
 		Timestamp: time.Now(),
 		Type:      notificationType,
 		Data: map[string]interface{}{
-			"job_name":      job.Name,
-			"files_deleted": len(result.DeletedFiles),
-			"space_freed":   result.DeletedSize,
-			"errors":        len(result.Errors),
-			"duration":      duration.String(),
-			"dry_run":       result.DryRun,
+			"job_name":        job.Name,
+			"run_id":          result.RunID,
+			"files_deleted":   len(result.DeletedFiles),
+			"space_freed":     result.DeletedSize,
+			"space_reclaimed": result.ReclaimedSize(),
+			"errors":          len(result.Errors),
+			"duration":        duration.String(),
+			"dry_run":         result.DryRun,
 		},
 	}
 
@@ -115,6 +121,55 @@ func (n *Notifier) SendCleanupNotification(job *CleanupJob, result *cleaner.Clea
 	n.sendAll(msg)
 }
 
+// maxSudoPaths is how many of the files that need sudo a notification lists
+const maxSudoPaths = 20
+
+// SendSudoNotification tells the user a cleanup left files that need sudo,
+// so they can delete them from a terminal
+func (n *Notifier) SendSudoNotification(job *CleanupJob, result *cleaner.CleanResult, size int64) {
+	paths := result.NeedsSudo
+	if len(paths) > maxSudoPaths {
+		paths = paths[:maxSudoPaths]
+	}
+
+	msg := &NotificationMessage{
+		Title: fmt.Sprintf("Cleanup Needs Sudo: %s", job.Name),
+		Message: fmt.Sprintf("%d files (%s) need elevated permissions and were left. Run 'tidyup clean' in a terminal to delete them with sudo.",
+			len(result.NeedsSudo), formatBytes(size)),
+		Timestamp: time.Now(),
+		Type:      "sudo_required",
+		Data: map[string]interface{}{
+			"job_name": job.Name,
+			"run_id":   result.RunID,
+			"files":    len(result.NeedsSudo),
+			"size":     size,
+			"paths":    paths,
+		},
+	}
+
+	n.sendAll(msg)
+}
+
+// SendReport emails the digest of a report job. Reports are what the job is
+// for, so they're sent even when other notifications are off.
+func (n *Notifier) SendReport(job *CleanupJob, result *scanner.ScanResult, body string) error {
+	cfg := &n.config.Email
+	if cfg.SMTPHost == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("no email server or recipients configured")
+	}
+
+	contentType := "text/html"
+	if job.ReportFormat == string(reporter.FormatMarkdown) {
+		contentType = "text/plain"
+	}
+	subject := fmt.Sprintf("Disk Hygiene Report: %s (%s reclaimable)", job.Name, formatBytes(result.TotalSize))
+	if err := n.mail(subject, contentType, body); err != nil {
+		return fmt.Errorf("failed to email report: %w", err)
+	}
+	n.logger.Info("Report emailed for job %s to %s", job.Name, strings.Join(cfg.To, ", "))
+	return nil
+}
+
 // sendAll sends notification through all configured channels
 func (n *Notifier) sendAll(msg *NotificationMessage) {
 	// Send email
@@ -150,9 +205,17 @@ func (n *Notifier) sendEmail(msg *NotificationMessage) error {
 		return fmt.Errorf("failed to build email body: %w", err)
 	}
 
+	return n.mail(msg.Title, "text/html", body)
+}
+
+// mail sends an email with the given subject and body to the configured
+// recipients
+func (n *Notifier) mail(subject, contentType, body string) error {
+	cfg := &n.config.Email
+
 	// Build message
-	emailMsg := fmt.Sprintf("To: %s\r\nSubject: %s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
-		cfg.To[0], msg.Title, body)
+	emailMsg := fmt.Sprintf("To: %s\r\nSubject: %s\r\nContent-Type: %s; charset=UTF-8\r\n\r\n%s",
+		cfg.To[0], subject, contentType, body)
 
 	// Connect and send
 	auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
@@ -267,20 +330,5 @@ func (n *Notifier) sendWebhook(msg *NotificationMessage) error {
 
 // formatBytes formats bytes to human-readable string
 func formatBytes(bytes int64) string {
-	const (
-		KB = 1024
-		MB = 1024 * KB
-		GB = 1024 * MB
-	)
-
-	switch {
-	case bytes >= GB:
-		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
-	case bytes >= MB:
-		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
-	case bytes >= KB:
-		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
-	default:
-		return fmt.Sprintf("%d bytes", bytes)
-	}
+	return utils.FormatBytes(bytes)
 }
@@ -11,13 +11,34 @@ import (
 
 // CleanupJob represents a scheduled cleanup job
 type CleanupJob struct {
-	Name       string
-	Schedule   string
-	Categories map[string]bool
-	DryRun     bool
-	SkipIfBusy bool
-	NextRun    time.Time
-	LastRun    time.Time
+	Name         string
+	Schedule     string
+	Categories   map[string]bool
+	DryRun       bool
+	SkipIfBusy   bool
+	Action       string        // config.ActionClean or config.ActionReport
+	ReportFormat string        // Digest format of report jobs
+	Spread       time.Duration // How long deletions are spread over; 0 for as fast as possible
+	TriggeredBy  string        // TriggerSchedule or TriggerManual
+	NextRun      time.Time
+	LastRun      time.Time
+}
+
+// newCleanupJob creates the job for a schedule
+func newCleanupJob(schedule config.CleanupSchedule) *CleanupJob {
+	// Validated with the config
+	spread, _ := time.ParseDuration(schedule.Spread)
+	return &CleanupJob{
+		Name:         schedule.Name,
+		Schedule:     schedule.Schedule,
+		Categories:   schedule.Categories,
+		DryRun:       schedule.DryRun,
+		SkipIfBusy:   schedule.SkipIfBusy,
+		Action:       schedule.Action,
+		ReportFormat: schedule.ReportFormat,
+		Spread:       spread,
+		TriggeredBy:  TriggerSchedule,
+	}
 }
 
 // Scheduler manages scheduled cleanup jobs
@@ -65,6 +86,13 @@ func (s *Scheduler) Start() error {
 		}
 	}
 
+	// Purge the quarantine of files past their retention
+	if q := s.daemon.config.Quarantine; q.Enabled && q.Dir != "" && q.PurgeSchedule != "" {
+		if _, err := s.cron.AddFunc(q.PurgeSchedule, s.daemon.PurgeQuarantine); err != nil {
+			return fmt.Errorf("failed to add quarantine purge: %w", err)
+		}
+	}
+
 	// Start cron
 	s.cron.Start()
 	s.running = true
@@ -82,11 +110,13 @@ func (s *Scheduler) Stop() {
 		return
 	}
 
+	// Running jobs stop at the next file once the daemon's context is
+	// cancelled; wait for them to record their run
 	ctx := s.cron.Stop()
 	select {
 	case <-ctx.Done():
 		// Clean shutdown
-	case <-time.After(10 * time.Second):
+	case <-time.After(30 * time.Second):
 		s.daemon.logger.Warn("Scheduler stop timed out")
 	}
 
@@ -100,17 +130,21 @@ func (s *Scheduler) addJobInternal(schedule config.CleanupSchedule) error {
 		return fmt.Errorf("job %s already exists", schedule.Name)
 	}
 
-	// Create job
-	job := &CleanupJob{
-		Name:       schedule.Name,
-		Schedule:   schedule.Schedule,
-		Categories: schedule.Categories,
-		DryRun:     schedule.DryRun,
-		SkipIfBusy: schedule.SkipIfBusy,
+	// Schedules that only run on login or when idle have no cron entry
+	if schedule.Schedule == "" {
+		s.daemon.logger.Info("Added job: %s, runs %s", schedule.Name, schedule.Triggers())
+		return nil
 	}
 
+	// Create job
+	job := newCleanupJob(schedule)
+
 	// Create job function
 	jobFunc := func() {
+		// No new runs start once the daemon is shutting down
+		if s.daemon.shutdownCtx.Err() != nil {
+			return
+		}
 		s.daemon.logger.Info("Executing scheduled job: %s", job.Name)
 		job.LastRun = time.Now()
 
@@ -192,11 +226,18 @@ func (s *Scheduler) ListJobs() []JobInfo {
 		}
 
 		if name != "" {
-			jobs = append(jobs, JobInfo{
+			info := JobInfo{
 				Name:    name,
 				NextRun: entry.Next,
 				PrevRun: entry.Prev,
-			})
+			}
+			for _, sched := range s.schedules {
+				if sched.Name == name {
+					info.Schedule = sched.Schedule
+					info.DryRun = sched.DryRun
+				}
+			}
+			jobs = append(jobs, info)
 		}
 	}
 
@@ -223,13 +264,8 @@ func (s *Scheduler) TriggerJob(name string) error {
 	}
 
 	// Create and run job
-	job := &CleanupJob{
-		Name:       schedule.Name,
-		Schedule:   schedule.Schedule,
-		Categories: schedule.Categories,
-		DryRun:     schedule.DryRun,
-		SkipIfBusy: schedule.SkipIfBusy,
-	}
+	job := newCleanupJob(schedule)
+	job.TriggeredBy = TriggerManual
 
 	s.daemon.logger.Info("Manually triggering job: %s (entry ID: %d)", name, id)
 	return s.daemon.RunCleanupJob(job)
@@ -237,7 +273,9 @@ func (s *Scheduler) TriggerJob(name string) error {
 
 // JobInfo contains information about a scheduled job
 type JobInfo struct {
-	Name    string
-	NextRun time.Time
-	PrevRun time.Time
+	Name     string
+	Schedule string
+	DryRun   bool
+	NextRun  time.Time
+	PrevRun  time.Time
 }
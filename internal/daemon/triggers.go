@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// triggerPollInterval is how often the user's sessions and idle time are
+// checked for the on_login and on_idle_minutes triggers
+const triggerPollInterval = time.Minute
+
+// triggerWatcher runs schedules when the user logs in or steps away
+type triggerWatcher struct {
+	daemon    *Daemon
+	username  string
+	schedules []config.CleanupSchedule
+
+	sessions  map[string]bool // Sessions seen at the last poll; nil before the first
+	idleFired map[string]bool // Schedules already run in the current idle period
+}
+
+// hasTriggers reports whether any schedule runs on login or when idle
+func hasTriggers(schedules []config.CleanupSchedule) bool {
+	for _, schedule := range schedules {
+		if schedule.OnLogin || schedule.OnIdleMinutes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// watchTriggers polls for logins and idleness until the daemon shuts down
+func (d *Daemon) watchTriggers(username string) {
+	w := &triggerWatcher{
+		daemon:    d,
+		username:  username,
+		schedules: d.config.Daemon.Schedules,
+		idleFired: make(map[string]bool),
+	}
+
+	ticker := time.NewTicker(triggerPollInterval)
+	defer ticker.Stop()
+	for {
+		w.poll()
+		select {
+		case <-d.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll runs the schedules whose trigger fired since the last poll. Jobs run
+// one at a time, so a long cleanup delays the next poll.
+func (w *triggerWatcher) poll() {
+	logger := w.daemon.logger
+
+	if sessions, err := platform.LoginSessions(w.username); err != nil {
+		logger.Debug("Couldn't list login sessions: %v", err)
+	} else if w.newSession(sessions) {
+		for _, schedule := range w.schedules {
+			if schedule.OnLogin {
+				w.run(schedule, TriggerLogin)
+			}
+		}
+	}
+
+	idle, err := platform.IdleTime(w.username)
+	if err != nil {
+		logger.Debug("Couldn't read idle time: %v", err)
+		return
+	}
+	for _, schedule := range w.schedules {
+		if schedule.OnIdleMinutes <= 0 {
+			continue
+		}
+		if idle < time.Duration(schedule.OnIdleMinutes)*time.Minute {
+			// The user is back, so the next idle period runs it again
+			w.idleFired[schedule.Name] = false
+			continue
+		}
+		if !w.idleFired[schedule.Name] {
+			w.idleFired[schedule.Name] = true
+			w.run(schedule, TriggerIdle)
+		}
+	}
+}
+
+// newSession records the current sessions and reports whether one has
+// started since the last poll. The sessions open when the daemon starts
+// don't count.
+func (w *triggerWatcher) newSession(sessions []string) bool {
+	current := make(map[string]bool, len(sessions))
+	started := false
+	for _, session := range sessions {
+		current[session] = true
+		if w.sessions != nil && !w.sessions[session] {
+			started = true
+		}
+	}
+	w.sessions = current
+	return started
+}
+
+// run runs a schedule's job for a trigger, unless the daemon is shutting down
+func (w *triggerWatcher) run(schedule config.CleanupSchedule, trigger string) {
+	if w.daemon.shutdownCtx.Err() != nil {
+		return
+	}
+	job := newCleanupJob(schedule)
+	job.TriggeredBy = trigger
+	w.daemon.logger.Info("Running job %s: triggered by %s", job.Name, trigger)
+	if err := w.daemon.RunCleanupJob(job); err != nil {
+		w.daemon.logger.Error("Job %s failed: %v", job.Name, err)
+	}
+}
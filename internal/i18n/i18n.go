@@ -0,0 +1,171 @@
+// Package i18n translates user-facing messages. Messages are written in
+// English in the code and looked up in the active language's catalog, so a
+// missing translation falls back to English instead of a message ID.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLanguage is the language messages are written in
+const DefaultLanguage = "en"
+
+// localeFS holds the built-in catalogs, one <language>.yaml file each,
+// mapping English messages to their translation
+//
+//go:embed locales/*.yaml
+var localeFS embed.FS
+
+var (
+	mu       sync.RWMutex
+	catalogs map[string]map[string]string // language -> English message -> translation
+	active   map[string]string            // catalog of the current language, nil for English
+	language = DefaultLanguage
+)
+
+func init() {
+	catalogs = make(map[string]map[string]string)
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			continue
+		}
+		lang := strings.TrimSuffix(entry.Name(), ".yaml")
+		catalog, err := parseCatalog(data)
+		if err != nil {
+			panic(fmt.Sprintf("i18n: invalid built-in catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[lang] = catalog
+	}
+}
+
+// parseCatalog reads a catalog file
+func parseCatalog(data []byte) (map[string]string, error) {
+	catalog := make(map[string]string)
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// LoadFile adds the translations in a catalog file to language, overriding
+// built-in ones. This lets users add or fix a language without a new build.
+func LoadFile(lang, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	catalog, err := parseCatalog(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse catalog %s: %w", file, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	lang = normalize(lang)
+	if catalogs[lang] == nil {
+		catalogs[lang] = make(map[string]string)
+	}
+	for msg, translation := range catalog {
+		catalogs[lang][msg] = translation
+	}
+	if lang == language {
+		active = catalogs[lang]
+	}
+	return nil
+}
+
+// Languages lists the available languages, English first
+func Languages() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	langs := []string{DefaultLanguage}
+	for lang := range catalogs {
+		if lang != DefaultLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Detect picks the language to use: the configured one if set, otherwise
+// the first of LC_ALL, LC_MESSAGES and LANG that is set
+func Detect(configured string) string {
+	if configured != "" {
+		return normalize(configured)
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(env); value != "" {
+			return normalize(value)
+		}
+	}
+	return DefaultLanguage
+}
+
+// normalize reduces a locale such as "es_ES.UTF-8" to its language, "es"
+func normalize(locale string) string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "c" || locale == "posix" {
+		return DefaultLanguage
+	}
+	return locale
+}
+
+// SetLanguage switches the active language. It returns false and keeps
+// English if there's no catalog for lang.
+func SetLanguage(lang string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	lang = normalize(lang)
+	if lang == DefaultLanguage {
+		language, active = DefaultLanguage, nil
+		return true
+	}
+	catalog, ok := catalogs[lang]
+	if !ok {
+		language, active = DefaultLanguage, nil
+		return false
+	}
+	language, active = lang, catalog
+	return true
+}
+
+// Language returns the active language
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// T translates msg into the active language and, if args are given, formats
+// it like fmt.Sprintf
+func T(msg string, args ...interface{}) string {
+	mu.RLock()
+	if translation, ok := active[msg]; ok && translation != "" {
+		msg = translation
+	}
+	mu.RUnlock()
+
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// ============================================================================
+// Language Detection Tests
+// ============================================================================
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"es_ES.UTF-8": "es",
+		"es":          "es",
+		"pt-BR":       "pt",
+		"de_DE@euro":  "de",
+		"EN_US":       "en",
+		"C":           "en",
+		"POSIX":       "en",
+		"C.UTF-8":     "en",
+		"":            "en",
+	}
+	for locale, want := range tests {
+		if got := normalize(locale); got != want {
+			t.Errorf("normalize(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "es_MX.UTF-8")
+
+	if got := Detect(""); got != "es" {
+		t.Errorf("Detect from LANG = %q, want es", got)
+	}
+	if got := Detect("en"); got != "en" {
+		t.Errorf("configured language should win over LANG, got %q", got)
+	}
+
+	t.Setenv("LC_ALL", "C")
+	if got := Detect(""); got != "en" {
+		t.Errorf("LC_ALL should win over LANG, got %q", got)
+	}
+}
+
+// ============================================================================
+// Translation Tests
+// ============================================================================
+
+func TestTranslate(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if !SetLanguage("es_ES.UTF-8") {
+		t.Fatal("expected a built-in Spanish catalog")
+	}
+	if Language() != "es" {
+		t.Errorf("Language() = %q, want es", Language())
+	}
+	if got := T("Permission denied"); got != "Permiso denegado" {
+		t.Errorf("T(Permission denied) = %q", got)
+	}
+	if got := T("Restored %d files", 3); got != "Restaurados 3 archivos" {
+		t.Errorf("T with args = %q", got)
+	}
+	if got := T("not in any catalog %d", 1); got != "not in any catalog 1" {
+		t.Errorf("missing translations should fall back to English, got %q", got)
+	}
+
+	if SetLanguage("xx") {
+		t.Error("SetLanguage should fail for a language without a catalog")
+	}
+	if Language() != DefaultLanguage || T("Permission denied") != "Permission denied" {
+		t.Error("an unknown language should fall back to English")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	file := filepath.Join(t.TempDir(), "fr.yaml")
+	if err := os.WriteFile(file, []byte(`"Permission denied": "Permission refusée"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile("fr_FR", file); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !SetLanguage("fr") {
+		t.Fatal("expected the loaded catalog to be available")
+	}
+	if got := T("Permission denied"); got != "Permission refusée" {
+		t.Errorf("T(Permission denied) = %q", got)
+	}
+}
+
+// verbPattern matches fmt verbs, ignoring escaped percent signs
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+func TestBuiltInCatalogsKeepVerbs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for msg, translation := range catalog {
+			want := verbPattern.FindAllString(msg, -1)
+			got := verbPattern.FindAllString(translation, -1)
+			if len(want) != len(got) {
+				t.Errorf("%s: %q has verbs %v, want %v", lang, translation, got, want)
+				continue
+			}
+			for i := range want {
+				if want[i] != got[i] {
+					t.Errorf("%s: %q has verbs %v, want %v", lang, translation, got, want)
+					break
+				}
+			}
+		}
+	}
+}
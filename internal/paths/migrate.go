@@ -0,0 +1,107 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// migratedMarker is created in the state directory once the legacy files
+// have been moved, so the old locations are never looked at again
+const migratedMarker = ".migrated"
+
+// Move is a file or directory moved from a legacy location
+type Move struct {
+	From string
+	To   string
+}
+
+// legacyFile is a location used by earlier versions, relative to the home
+// directory, and where it belongs now
+type legacyFile struct {
+	from string
+	dir  func() (string, error)
+	name string
+}
+
+// legacyFiles lists what older versions kept in hardcoded locations. When
+// two legacy files map to the same place, the first one found wins.
+var legacyFiles = []legacyFile{
+	{".config/tidyup/config.yaml", ConfigDir, "config.yaml"},
+	{".config/cleanup-cache/config.yaml", ConfigDir, "config.yaml"},
+	{".config/tidyup/ui-state.json", StateDir, "ui-state.json"},
+	{".config/cleanup-cache/sessions", StateDir, "sessions"},
+	{".cache/tidyup/scan_cache.gob", CacheDir, "scan_cache.gob"},
+	{".local/share/tidyup/clean-journal.json", StateDir, "clean-journal.json"},
+	{".local/share/tidyup/quarantine", DataDir, "quarantine"},
+}
+
+// legacyDirs are removed after the migration if it left them empty
+var legacyDirs = []string{
+	".config/cleanup-cache",
+	".config/tidyup",
+	".cache/tidyup",
+	".local/share/tidyup",
+}
+
+// Migrate moves files from the locations used by earlier versions to the
+// current ones. It runs once: after a migration without errors, later calls
+// return straight away. Files are never overwritten; if both locations
+// exist, the current one is kept.
+func Migrate() ([]Move, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	stateDir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	marker := filepath.Join(stateDir, migratedMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil, nil
+	}
+
+	var moves []Move
+	var errs []error
+	for _, legacy := range legacyFiles {
+		from := filepath.Join(home, legacy.from)
+		to := File(legacy.dir, legacy.name)
+		if to == "" || from == to {
+			continue
+		}
+		if _, err := os.Lstat(from); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(to); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create %s: %w", filepath.Dir(to), err))
+			continue
+		}
+		if err := os.Rename(from, to); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move %s to %s: %w", from, to, err))
+			continue
+		}
+		moves = append(moves, Move{From: from, To: to})
+	}
+
+	for _, dir := range legacyDirs {
+		// Fails, as intended, unless the directory is now empty
+		os.Remove(filepath.Join(home, dir))
+	}
+
+	if len(errs) > 0 {
+		return moves, errors.Join(errs...)
+	}
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return moves, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(marker, nil, 0600); err != nil {
+		return moves, fmt.Errorf("failed to record migration: %w", err)
+	}
+	return moves, nil
+}
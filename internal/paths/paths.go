@@ -0,0 +1,66 @@
+// Package paths locates tidyup's own files. On Linux it follows the XDG base
+// directory spec; on macOS the XDG variables are honored when set and
+// ~/Library is used otherwise.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appName is the directory tidyup's files live in under each base directory
+const appName = "tidyup"
+
+// ConfigDir returns the directory for config.yaml:
+// $XDG_CONFIG_HOME/tidyup, ~/.config/tidyup or ~/Library/Application Support/tidyup
+func ConfigDir() (string, error) {
+	return baseDir("XDG_CONFIG_HOME", ".config", "Library/Application Support")
+}
+
+// CacheDir returns the directory for data that can be rebuilt, such as the
+// scan cache: $XDG_CACHE_HOME/tidyup, ~/.cache/tidyup or ~/Library/Caches/tidyup
+func CacheDir() (string, error) {
+	return baseDir("XDG_CACHE_HOME", ".cache", "Library/Caches")
+}
+
+// StateDir returns the directory for history and state kept between runs,
+// such as sessions, UI state and the clean journal: $XDG_STATE_HOME/tidyup,
+// ~/.local/state/tidyup or ~/Library/Application Support/tidyup
+func StateDir() (string, error) {
+	return baseDir("XDG_STATE_HOME", ".local/state", "Library/Application Support")
+}
+
+// DataDir returns the directory for user data, such as quarantined files:
+// $XDG_DATA_HOME/tidyup, ~/.local/share/tidyup or ~/Library/Application Support/tidyup
+func DataDir() (string, error) {
+	return baseDir("XDG_DATA_HOME", ".local/share", "Library/Application Support")
+}
+
+// baseDir resolves one base directory. Relative XDG values are ignored, as
+// the spec requires.
+func baseDir(env, linuxDefault, macOSDefault string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, macOSDefault, appName), nil
+	}
+	return filepath.Join(home, linuxDefault, appName), nil
+}
+
+// File returns name inside the directory dir returns, or "" if the directory
+// can't be determined
+func File(dir func() (string, error), name string) string {
+	base, err := dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, name)
+}
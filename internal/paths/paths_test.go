@@ -0,0 +1,163 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setHome points the home directory and XDG variables at a temporary directory
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME", "XDG_DATA_HOME"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+// ============================================================================
+// Base Directory Tests
+// ============================================================================
+
+func TestBaseDirsFollowXDG(t *testing.T) {
+	home := setHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	tests := []struct {
+		dir  func() (string, error)
+		want string
+	}{
+		{ConfigDir, filepath.Join(home, "cfg", "tidyup")},
+		{CacheDir, filepath.Join(home, "cache", "tidyup")},
+		{StateDir, filepath.Join(home, "state", "tidyup")},
+		{DataDir, filepath.Join(home, "data", "tidyup")},
+	}
+	for _, tt := range tests {
+		got, err := tt.dir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestBaseDirsDefaults(t *testing.T) {
+	home := setHome(t)
+	// Relative values must be ignored
+	t.Setenv("XDG_STATE_HOME", "relative/state")
+
+	want := map[string]string{
+		"config": filepath.Join(home, ".config", "tidyup"),
+		"cache":  filepath.Join(home, ".cache", "tidyup"),
+		"state":  filepath.Join(home, ".local", "state", "tidyup"),
+	}
+	if runtime.GOOS == "darwin" {
+		want = map[string]string{
+			"config": filepath.Join(home, "Library", "Application Support", "tidyup"),
+			"cache":  filepath.Join(home, "Library", "Caches", "tidyup"),
+			"state":  filepath.Join(home, "Library", "Application Support", "tidyup"),
+		}
+	}
+
+	for name, dir := range map[string]func() (string, error){"config": ConfigDir, "cache": CacheDir, "state": StateDir} {
+		got, err := dir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[name] {
+			t.Errorf("%s dir = %s, want %s", name, got, want[name])
+		}
+	}
+}
+
+// ============================================================================
+// Migration Tests
+// ============================================================================
+
+// writeFile creates a file with content, and its parent directories
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := setHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "xdg-cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "xdg-state"))
+
+	writeFile(t, filepath.Join(home, ".config/cleanup-cache/config.yaml"), "legacy")
+	writeFile(t, filepath.Join(home, ".config/cleanup-cache/sessions/a.json"), "{}")
+	writeFile(t, filepath.Join(home, ".cache/tidyup/scan_cache.gob"), "cache")
+	writeFile(t, filepath.Join(home, ".config/tidyup/ui-state.json"), "old state")
+	// Already present in the new location, so the legacy one is kept
+	writeFile(t, filepath.Join(home, "xdg-state/tidyup/ui-state.json"), "new state")
+
+	moves, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(moves) != 3 {
+		t.Errorf("expected 3 moves, got %d: %v", len(moves), moves)
+	}
+
+	for path, want := range map[string]string{
+		"xdg-config/tidyup/config.yaml":    "legacy",
+		"xdg-state/tidyup/sessions/a.json": "{}",
+		"xdg-cache/tidyup/scan_cache.gob":  "cache",
+		"xdg-state/tidyup/ui-state.json":   "new state",
+		".config/tidyup/ui-state.json":     "old state",
+	} {
+		data, err := os.ReadFile(filepath.Join(home, path))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", path, data, err, want)
+		}
+	}
+
+	// Emptied legacy directories are removed
+	for _, dir := range []string{".config/cleanup-cache", ".cache/tidyup"} {
+		if _, err := os.Stat(filepath.Join(home, dir)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", dir)
+		}
+	}
+
+	// Later runs leave the legacy locations alone
+	writeFile(t, filepath.Join(home, ".cache/tidyup/scan_cache.gob"), "again")
+	os.Remove(filepath.Join(home, "xdg-cache/tidyup/scan_cache.gob"))
+	moves, err = Migrate()
+	if err != nil || len(moves) != 0 {
+		t.Errorf("expected the migration to run once, got %v, %v", moves, err)
+	}
+}
+
+func TestMigrateSameLocation(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("the default config directory differs from the legacy one on macOS")
+	}
+	home := setHome(t)
+	writeFile(t, filepath.Join(home, ".config/tidyup/config.yaml"), "current")
+
+	moves, err := Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 0 {
+		t.Errorf("expected no moves, got %v", moves)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config/tidyup/config.yaml")); err != nil {
+		t.Errorf("config should be left in place: %v", err)
+	}
+}
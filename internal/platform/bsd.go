@@ -0,0 +1,120 @@
+package platform
+
+import "path/filepath"
+
+// getFreeBSDInfo returns platform-specific information for FreeBSD
+func getFreeBSDInfo(homeDir, username string) *Info {
+	return &Info{
+		OS:       FreeBSD,
+		HomeDir:  homeDir,
+		Username: username,
+		CacheDirs: []string{
+			filepath.Join(homeDir, ".cache"),
+			"/var/cache",
+			"/tmp",
+		},
+		TempDirs: []string{
+			"/tmp",
+			"/var/tmp",
+			filepath.Join(homeDir, ".local/share/Trash"),
+		},
+		LogDirs: []string{
+			"/var/log",
+			filepath.Join(homeDir, ".local/share/logs"),
+			filepath.Join(homeDir, ".xsession-errors"),
+		},
+		DownloadsDir: filepath.Join(homeDir, "Downloads"),
+		SystemCaches: append([]string{
+			// Downloaded packages, what "pkg clean" removes
+			GetPkgCachePath(),
+		}, bsdUserCaches(homeDir)...),
+		ProtectedPaths: append([]string{
+			"/boot",
+			"/compat",
+			"/libexec",
+			"/rescue",
+			"/usr/home",
+		}, bsdProtectedPaths(homeDir)...),
+	}
+}
+
+// getOpenBSDInfo returns platform-specific information for OpenBSD
+func getOpenBSDInfo(homeDir, username string) *Info {
+	return &Info{
+		OS:       OpenBSD,
+		HomeDir:  homeDir,
+		Username: username,
+		CacheDirs: []string{
+			filepath.Join(homeDir, ".cache"),
+			"/tmp",
+		},
+		TempDirs: []string{
+			"/tmp",
+			"/var/tmp",
+			filepath.Join(homeDir, ".local/share/Trash"),
+		},
+		LogDirs: []string{
+			"/var/log",
+			// httpd(8) logs, inside its chroot
+			"/var/www/logs",
+			filepath.Join(homeDir, ".xsession-errors"),
+		},
+		DownloadsDir: filepath.Join(homeDir, "Downloads"),
+		// pkg_add(1) only caches packages when PKG_CACHE is set, so there's
+		// no system package cache to clean
+		SystemCaches: bsdUserCaches(homeDir),
+		ProtectedPaths: append([]string{
+			"/altroot",
+			"/bsd",
+			"/bsd.mp",
+			"/bsd.rd",
+			// syspatch(8) rollback data
+			"/var/syspatch",
+		}, bsdProtectedPaths(homeDir)...),
+	}
+}
+
+// bsdUserCaches lists the per-user caches shared by the BSDs
+func bsdUserCaches(homeDir string) []string {
+	return []string{
+		// Browser caches
+		filepath.Join(homeDir, ".cache/chromium"),
+		filepath.Join(homeDir, ".cache/mozilla/firefox"),
+		filepath.Join(homeDir, ".mozilla/firefox/*/cache2"),
+		// Developer tools
+		filepath.Join(homeDir, ".cache/go-build"),
+		filepath.Join(homeDir, ".cache/pip"),
+		filepath.Join(homeDir, ".cache/yarn"),
+		filepath.Join(homeDir, ".npm"),
+		filepath.Join(homeDir, ".cargo/registry/cache"),
+		filepath.Join(homeDir, ".cache/composer"),
+		filepath.Join(homeDir, ".nuget/packages"),
+		// Thumbnails and font cache
+		filepath.Join(homeDir, ".cache/thumbnails"),
+		filepath.Join(homeDir, ".cache/fontconfig"),
+	}
+}
+
+// bsdProtectedPaths lists the protected paths shared by the BSDs
+func bsdProtectedPaths(homeDir string) []string {
+	return []string{
+		"/",
+		"/bin",
+		"/dev",
+		"/etc",
+		"/home",
+		"/root",
+		"/sbin",
+		"/usr",
+		"/var/db", // Package databases (pkg, ports)
+		filepath.Join(homeDir, ".config"),
+		filepath.Join(homeDir, ".local/share"),
+		filepath.Join(homeDir, "Documents"),
+		filepath.Join(homeDir, "Desktop"),
+	}
+}
+
+// GetPkgCachePath returns the FreeBSD pkg(8) cache path
+func GetPkgCachePath() string {
+	return "/var/cache/pkg"
+}
@@ -0,0 +1,35 @@
+//go:build !unix
+
+package platform
+
+import "io/fs"
+
+// DeviceID returns the ID of the file system holding path. Paths with the same
+// ID share free space.
+func DeviceID(path string) (uint64, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// FileID returns the device and inode of path, which together identify it
+// until it's deleted, without following a final symlink
+func FileID(path string) (dev, ino uint64, err error) {
+	return 0, 0, ErrUnsupportedPlatform
+}
+
+// FileOwner returns the user and group IDs that own the file info describes,
+// if the file system reports them
+func FileOwner(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+// AllocatedSize returns the space the file info describes takes on disk,
+// which is less than its size for sparse files such as VM disk images
+func AllocatedSize(info fs.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+// CanWrite reports whether the current process may add and remove entries in
+// dir, as the kernel decides it: by mode, groups, ACLs and read-only mounts
+func CanWrite(dir string) bool {
+	return true
+}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package platform
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// DeviceID returns the ID of the file system holding path. Paths with the same
+// ID share free space.
+func DeviceID(path string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return uint64(st.Dev), nil
+}
+
+// FileID returns the device and inode of path, which together identify it
+// until it's deleted, without following a final symlink
+func FileID(path string) (dev, ino uint64, err error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return uint64(st.Dev), uint64(st.Ino), nil
+}
+
+// FileOwner returns the user and group IDs that own the file info describes,
+// if the file system reports them
+func FileOwner(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return st.Uid, st.Gid, true
+}
+
+// AllocatedSize returns the space the file info describes takes on disk,
+// which is less than its size for sparse files such as VM disk images
+func AllocatedSize(info fs.FileInfo) (int64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(st.Blocks) * 512, true
+}
+
+// CanWrite reports whether the current process may add and remove entries in
+// dir, as the kernel decides it: by mode, groups, ACLs and read-only mounts
+func CanWrite(dir string) bool {
+	return unix.Access(dir, unix.W_OK) == nil
+}
@@ -0,0 +1,11 @@
+package platform
+
+// AccessControl is a mandatory access control system that can refuse an
+// operation the file's owner and mode allow
+type AccessControl string
+
+const (
+	AccessControlSELinux  AccessControl = "SELinux"
+	AccessControlAppArmor AccessControl = "AppArmor"
+	AccessControlMacOSTCC AccessControl = "macOS privacy protection"
+)
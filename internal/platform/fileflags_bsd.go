@@ -0,0 +1,40 @@
+//go:build darwin || freebsd || openbsd || netbsd || dragonfly
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// File flags from sys/stat.h, as set by chflags
+const (
+	ufImmutable = 0x00000002
+	ufAppend    = 0x00000004
+	sfImmutable = 0x00020000
+	sfAppend    = 0x00040000
+)
+
+// IsImmutable reports whether path has an immutable or append-only flag
+// (chflags uchg, schg, uappnd or sappnd), which stops it being deleted or,
+// for a directory, anything in it being deleted
+func IsImmutable(path string) (bool, error) {
+	var st unix.Stat_t
+	if err := unix.Lstat(path, &st); err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return st.Flags&(ufImmutable|ufAppend|sfImmutable|sfAppend) != 0, nil
+}
+
+// ActiveAccessControl returns the mandatory access control system enforcing
+// policy, or "" if there's none. On macOS that's System Integrity Protection
+// and the privacy controls guarding folders like Mail and Photos, which are
+// always on.
+func ActiveAccessControl() AccessControl {
+	if runtime.GOOS == "darwin" {
+		return AccessControlMacOSTCC
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Inode flags from linux/fs.h, as set by chattr
+const (
+	fsImmutableFl = 0x10
+	fsAppendFl    = 0x20
+)
+
+// IsImmutable reports whether path has the immutable or append-only
+// attribute (chattr +i or +a), which stops it being deleted or, for a
+// directory, anything in it being deleted, even by root
+func IsImmutable(path string) (bool, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		// File systems without inode flags can't have them set
+		return false, nil
+	}
+	return flags&(fsImmutableFl|fsAppendFl) != 0, nil
+}
+
+// ActiveAccessControl returns the mandatory access control system enforcing
+// policy, or "" if there's none. SELinux in permissive mode only logs.
+func ActiveAccessControl() AccessControl {
+	if enforce, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil && strings.TrimSpace(string(enforce)) == "1" {
+		return AccessControlSELinux
+	}
+	if enabled, err := os.ReadFile("/sys/module/apparmor/parameters/enabled"); err == nil && strings.TrimSpace(string(enabled)) == "Y" {
+		return AccessControlAppArmor
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !openbsd && !netbsd && !dragonfly
+
+package platform
+
+// IsImmutable reports whether path has an immutable or append-only flag
+func IsImmutable(path string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// ActiveAccessControl returns the mandatory access control system enforcing
+// policy, or "" if there's none
+func ActiveAccessControl() AccessControl {
+	return ""
+}
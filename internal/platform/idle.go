@@ -0,0 +1,123 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ioregIdlePattern finds the HID idle time, in nanoseconds, in ioreg output
+var ioregIdlePattern = regexp.MustCompile(`"HIDIdleTime"\s*=\s*(\d+)`)
+
+// IdleTime returns how long username has gone without touching the keyboard
+// or mouse. macOS reads it from IOKit with ioreg; Linux asks logind, which
+// relies on the desktop session to report idleness.
+func IdleTime(username string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.CommandContext(ctx, "ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+		if err != nil {
+			return 0, fmt.Errorf("ioreg failed: %w", err)
+		}
+		return parseIORegIdle(string(out))
+	case "linux":
+		out, err := exec.CommandContext(ctx, "loginctl", "show-user", username, "--property=IdleHint", "--property=IdleSinceHint").Output()
+		if err != nil {
+			return 0, fmt.Errorf("loginctl failed: %w", err)
+		}
+		return parseLogindIdle(string(out), time.Now())
+	default:
+		return 0, ErrUnsupportedPlatform
+	}
+}
+
+// parseIORegIdle reads the idle time from ioreg -c IOHIDSystem
+func parseIORegIdle(out string) (time.Duration, error) {
+	match := ioregIdlePattern.FindStringSubmatch(out)
+	if match == nil {
+		return 0, fmt.Errorf("no HIDIdleTime in ioreg output")
+	}
+	ns, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid HIDIdleTime %q: %w", match[1], err)
+	}
+	return time.Duration(ns), nil
+}
+
+// parseLogindIdle reads the idle time from loginctl show-user. IdleSinceHint
+// is when the user went idle, in microseconds since the epoch.
+func parseLogindIdle(out string, now time.Time) (time.Duration, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[key] = value
+		}
+	}
+
+	idle, ok := props["IdleHint"]
+	if !ok {
+		return 0, fmt.Errorf("no IdleHint in loginctl output")
+	}
+	if idle != "yes" {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(props["IdleSinceHint"], 10, 64)
+	if err != nil || usec == 0 {
+		return 0, fmt.Errorf("invalid IdleSinceHint %q", props["IdleSinceHint"])
+	}
+	return max(now.Sub(time.UnixMicro(usec)), 0), nil
+}
+
+// LoginSessions returns an ID for each of username's login sessions. Linux
+// lists logind's sessions; elsewhere they're read from who.
+func LoginSessions(username string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if runtime.GOOS == "linux" {
+		if out, err := exec.CommandContext(ctx, "loginctl", "list-sessions", "--no-legend").Output(); err == nil {
+			return parseLoginctlSessions(string(out), username), nil
+		}
+	}
+	out, err := exec.CommandContext(ctx, "who").Output()
+	if err != nil {
+		return nil, fmt.Errorf("who failed: %w", err)
+	}
+	return parseWhoSessions(string(out), username), nil
+}
+
+// parseLoginctlSessions returns the IDs of username's sessions from
+// loginctl list-sessions --no-legend: SESSION UID USER SEAT TTY ...
+func parseLoginctlSessions(out, username string) []string {
+	var sessions []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[2] == username {
+			sessions = append(sessions, fields[0])
+		}
+	}
+	return sessions
+}
+
+// parseWhoSessions returns username's sessions from who, identified by
+// terminal and login time, e.g. "console Mar 10 09:12"
+func parseWhoSessions(out, username string) []string {
+	var sessions []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == username {
+			sessions = append(sessions, strings.Join(fields[1:], " "))
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,59 @@
+package platform
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseIORegIdle(t *testing.T) {
+	out := `+-o IOHIDSystem  <class IOHIDSystem, id 0x100000123, registered, matched, active, busy 0 (0 ms), retain 20>
+    {
+      "HIDIdleTime" = 95000000000
+      "IOClass" = "IOHIDSystem"
+    }
+`
+	got, err := parseIORegIdle(out)
+	if err != nil || got != 95*time.Second {
+		t.Errorf("parseIORegIdle() = %v, %v, want 1m35s", got, err)
+	}
+	if _, err := parseIORegIdle("nothing here"); err == nil {
+		t.Error("expected output without HIDIdleTime to be rejected")
+	}
+}
+
+func TestParseLogindIdle(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-40 * time.Minute).UnixMicro()
+
+	got, err := parseLogindIdle("IdleHint=yes\nIdleSinceHint="+strconv.FormatInt(since, 10)+"\n", now)
+	if err != nil || got != 40*time.Minute {
+		t.Errorf("parseLogindIdle() = %v, %v, want 40m", got, err)
+	}
+	got, err = parseLogindIdle("IdleHint=no\nIdleSinceHint=0\n", now)
+	if err != nil || got != 0 {
+		t.Errorf("parseLogindIdle() for an active user = %v, %v, want 0", got, err)
+	}
+	if _, err := parseLogindIdle("", now); err == nil {
+		t.Error("expected output without IdleHint to be rejected")
+	}
+}
+
+func TestParseSessions(t *testing.T) {
+	loginctl := `     2 1000 alice seat0 tty2
+     5 1001 bob   -     pts/1
+     7 1000 alice -     pts/0
+`
+	if got, want := parseLoginctlSessions(loginctl, "alice"), []string{"2", "7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLoginctlSessions() = %v, want %v", got, want)
+	}
+
+	who := `alice    console  Mar 10 09:12
+alice    ttys000  Mar 10 09:15
+bob      ttys001  Mar 10 10:00
+`
+	if got, want := parseWhoSessions(who, "alice"), []string{"console Mar 10 09:12", "ttys000 Mar 10 09:15"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWhoSessions() = %v, want %v", got, want)
+	}
+}
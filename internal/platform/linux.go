@@ -45,6 +45,15 @@ func getLinuxInfo(homeDir, username string) *Info {
 			filepath.Join(homeDir, ".npm"),
 			filepath.Join(homeDir, ".yarn/cache"),
 			filepath.Join(homeDir, ".cargo/registry/cache"),
+			// Composer and NuGet
+			filepath.Join(homeDir, ".cache/composer"),
+			filepath.Join(homeDir, ".composer/cache"),
+			filepath.Join(homeDir, ".nuget/packages"),
+			// Dart and Flutter packages; ~/.pub-cache/bin holds globally activated tools
+			filepath.Join(homeDir, ".pub-cache/hosted"),
+			filepath.Join(homeDir, ".pub-cache/git"),
+			// Swift Package Manager
+			filepath.Join(homeDir, ".cache/org.swift.swiftpm"),
 			// Gradle
 			filepath.Join(homeDir, ".gradle/caches"),
 			// Maven
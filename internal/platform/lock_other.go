@@ -0,0 +1,22 @@
+//go:build !darwin && !linux && !freebsd && !dragonfly && !netbsd && !openbsd
+
+package platform
+
+// LockFile takes an exclusive advisory lock on the file at path, creating it
+// if needed, and waits for any other process holding it. Call unlock to
+// release it.
+func LockFile(path string) (unlock func(), err error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// TryLockFile takes the lock LockFile does without waiting. It returns
+// ErrLocked if another process holds it.
+func TryLockFile(path string) (unlock func(), err error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// LockHeld reports whether another process holds the lock on the file at
+// path. It's false if the file doesn't exist.
+func LockHeld(path string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
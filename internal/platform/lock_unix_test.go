@@ -0,0 +1,43 @@
+//go:build darwin || linux || freebsd || dragonfly || netbsd || openbsd
+
+package platform
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.lock")
+
+	if held, err := LockHeld(path); err != nil || held {
+		t.Fatalf("LockHeld on a missing file = %v, %v, want false", held, err)
+	}
+
+	unlock, err := TryLockFile(path)
+	if err != nil {
+		t.Fatalf("TryLockFile failed: %v", err)
+	}
+	if held, err := LockHeld(path); err != nil || !held {
+		t.Errorf("LockHeld while locked = %v, %v, want true", held, err)
+	}
+	if _, err := TryLockFile(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("second TryLockFile = %v, want ErrLocked", err)
+	}
+
+	// A lock file left behind without its lock can be taken over
+	unlock()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("lock file should still exist: %v", err)
+	}
+	if held, err := LockHeld(path); err != nil || held {
+		t.Errorf("LockHeld after unlock = %v, %v, want false", held, err)
+	}
+	unlock, err = TryLockFile(path)
+	if err != nil {
+		t.Fatalf("TryLockFile on a stale lock file failed: %v", err)
+	}
+	unlock()
+}
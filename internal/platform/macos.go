@@ -44,6 +44,15 @@ func getMacOSInfo(homeDir, username string) *Info {
 			filepath.Join(homeDir, ".cache/cargo"),
 			filepath.Join(homeDir, ".npm"),
 			filepath.Join(homeDir, ".yarn/cache"),
+			// Composer and NuGet
+			filepath.Join(homeDir, "Library/Caches/composer"),
+			filepath.Join(homeDir, ".composer/cache"),
+			filepath.Join(homeDir, ".nuget/packages"),
+			// Dart and Flutter packages; ~/.pub-cache/bin holds globally activated tools
+			filepath.Join(homeDir, ".pub-cache/hosted"),
+			filepath.Join(homeDir, ".pub-cache/git"),
+			// Swift Package Manager
+			filepath.Join(homeDir, "Library/Caches/org.swift.swiftpm"),
 			// Xcode
 			filepath.Join(homeDir, "Library/Developer/Xcode/DerivedData"),
 			filepath.Join(homeDir, "Library/Developer/Xcode/Archives"),
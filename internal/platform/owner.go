@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+// ownerNames caches user and group names by ID, since a scan looks up the
+// same few owners for every file
+var ownerNames sync.Map
+
+// UserName returns the name of the user with the given ID, or the ID itself
+// if it has no name, as for users deleted since they created their files
+func UserName(uid uint32) string {
+	return lookupName("u", uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+}
+
+// GroupName returns the name of the group with the given ID, or the ID
+// itself if it has no name
+func GroupName(gid uint32) string {
+	return lookupName("g", gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// lookupName resolves an ID with lookup, remembering the answer under kind
+func lookupName(kind string, id uint32, lookup func(id string) (string, error)) string {
+	idStr := strconv.FormatUint(uint64(id), 10)
+	key := kind + idStr
+	if name, ok := ownerNames.Load(key); ok {
+		return name.(string)
+	}
+	name, err := lookup(idStr)
+	if err != nil || name == "" {
+		name = idStr
+	}
+	ownerNames.Store(key, name)
+	return name
+}
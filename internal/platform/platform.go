@@ -12,20 +12,25 @@ type Platform string
 const (
 	MacOS   Platform = "darwin"
 	Linux   Platform = "linux"
+	FreeBSD Platform = "freebsd"
+	OpenBSD Platform = "openbsd"
 	Unknown Platform = "unknown"
 )
 
 // Info contains platform-specific information and paths
 type Info struct {
-	OS             Platform
-	HomeDir        string
-	Username       string
-	CacheDirs      []string
-	TempDirs       []string
-	LogDirs        []string
-	DownloadsDir   string
-	SystemCaches   []string
-	ProtectedPaths []string
+	OS             Platform `json:"os" yaml:"os"`
+	HomeDir        string   `json:"home_dir" yaml:"home_dir"`
+	Username       string   `json:"username" yaml:"username"`
+	CacheDirs      []string `json:"cache_dirs" yaml:"cache_dirs"`
+	TempDirs       []string `json:"temp_dirs" yaml:"temp_dirs"`
+	LogDirs        []string `json:"log_dirs" yaml:"log_dirs"`
+	DownloadsDir   string   `json:"downloads_dir" yaml:"downloads_dir"`
+	SystemCaches   []string `json:"system_caches" yaml:"system_caches"`
+	ProtectedPaths []string `json:"protected_paths" yaml:"protected_paths"`
+	// Windows Subsystem for Linux; WindowsMounts are the DrvFs mounts (/mnt/c, ...)
+	WSL           bool     `json:"wsl,omitempty" yaml:"wsl,omitempty"`
+	WindowsMounts []string `json:"windows_mounts,omitempty" yaml:"windows_mounts,omitempty"`
 }
 
 // Detect returns the current platform
@@ -35,6 +40,10 @@ func Detect() Platform {
 		return MacOS
 	case "linux":
 		return Linux
+	case "freebsd":
+		return FreeBSD
+	case "openbsd":
+		return OpenBSD
 	default:
 		return Unknown
 	}
@@ -42,29 +51,44 @@ func Detect() Platform {
 
 // GetInfo returns platform-specific information
 func GetInfo() (*Info, error) {
-	platform := Detect()
-
 	// Get current user info
 	currentUser, err := user.Current()
 	if err != nil {
 		return nil, err
 	}
 
-	homeDir := currentUser.HomeDir
-	username := currentUser.Username
-
-	var info *Info
+	info, err := InfoFor(Detect(), currentUser.HomeDir, currentUser.Username)
+	if err != nil {
+		return nil, err
+	}
+	if info.OS == Linux && isWSL() {
+		info.WSL = true
+		info.WindowsMounts = windowsMounts()
+	}
+	return info, nil
+}
 
+// InfoFor returns the paths platform uses for a user with the given home
+// directory, without looking at the running system
+func InfoFor(platform Platform, homeDir, username string) (*Info, error) {
 	switch platform {
 	case MacOS:
-		info = getMacOSInfo(homeDir, username)
+		return getMacOSInfo(homeDir, username), nil
 	case Linux:
-		info = getLinuxInfo(homeDir, username)
+		return getLinuxInfo(homeDir, username), nil
+	case FreeBSD:
+		return getFreeBSDInfo(homeDir, username), nil
+	case OpenBSD:
+		return getOpenBSDInfo(homeDir, username), nil
 	default:
 		return nil, ErrUnsupportedPlatform
 	}
+}
 
-	return info, nil
+// HasSpotlight reports whether the Spotlight index (mdfind) can be used to
+// find files. Elsewhere scanners walk the file system.
+func HasSpotlight() bool {
+	return Detect() == MacOS
 }
 
 // GetUserCacheDir returns the user's cache directory
@@ -72,7 +96,7 @@ func GetUserCacheDir() (string, error) {
 	switch Detect() {
 	case MacOS:
 		return os.UserCacheDir()
-	case Linux:
+	case Linux, FreeBSD, OpenBSD:
 		// Try XDG_CACHE_HOME first
 		if cacheDir := os.Getenv("XDG_CACHE_HOME"); cacheDir != "" {
 			return cacheDir, nil
@@ -97,7 +121,7 @@ func GetUserConfigDir() (string, error) {
 			return "", err
 		}
 		return currentUser.HomeDir + "/Library/Application Support", nil
-	case Linux:
+	case Linux, FreeBSD, OpenBSD:
 		// Try XDG_CONFIG_HOME first
 		if configDir := os.Getenv("XDG_CONFIG_HOME"); configDir != "" {
 			return configDir, nil
@@ -132,6 +156,9 @@ func IsProtectedPath(path string) bool {
 		"/System",         // macOS
 		"/Applications",   // macOS
 		"/Library/System", // macOS
+		"/libexec",        // BSD
+		"/rescue",         // FreeBSD
+		"/altroot",        // OpenBSD
 	}
 
 	for _, protected := range protectedPaths {
@@ -146,6 +173,7 @@ func IsProtectedPath(path string) bool {
 // Errors
 var (
 	ErrUnsupportedPlatform = &PlatformError{"unsupported platform"}
+	ErrLocked              = &PlatformError{"locked by another process"}
 )
 
 // PlatformError represents a platform-related error
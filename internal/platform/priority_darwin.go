@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// From sys/resource.h
+const (
+	prioDarwinThread = 3
+	prioDarwinBG     = 0x1000
+)
+
+// LowerThreadPriority puts the calling thread in the background state, where
+// its disk I/O is throttled and it gets the CPU last, so it gives way to
+// everything else. The goroutine must be locked to its thread with
+// runtime.LockOSThread and never unlocked, so the thread exits with it.
+func LowerThreadPriority() error {
+	if err := unix.Setpriority(prioDarwinThread, 0, prioDarwinBG); err != nil {
+		return fmt.Errorf("failed to lower thread priority: %w", err)
+	}
+	return nil
+}
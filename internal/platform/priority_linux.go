@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// From linux/ioprio.h
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioLowest     = 7
+)
+
+// backgroundNice is the nice value background threads run at
+const backgroundNice = 10
+
+// LowerThreadPriority moves the calling thread to the lowest best-effort I/O
+// priority and nice 10, so its disk and CPU use gives way to everything
+// else's. Processes it starts inherit both. The goroutine must be locked to
+// its thread with runtime.LockOSThread and never unlocked, so the thread exits
+// with it: without privileges the priority can't be raised back.
+func LowerThreadPriority() error {
+	tid := unix.Gettid()
+	if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassBE<<ioprioClassShift|ioprioLowest); errno != 0 {
+		return fmt.Errorf("failed to lower I/O priority: %w", errno)
+	}
+	// On Linux, PRIO_PROCESS with a thread ID sets only that thread
+	if err := unix.Setpriority(unix.PRIO_PROCESS, tid, backgroundNice); err != nil {
+		return fmt.Errorf("failed to lower CPU priority: %w", err)
+	}
+	return nil
+}
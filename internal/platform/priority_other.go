@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package platform
+
+// LowerThreadPriority lowers the calling thread's disk and CPU priority
+func LowerThreadPriority() error {
+	return ErrUnsupportedPlatform
+}
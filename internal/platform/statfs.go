@@ -0,0 +1,31 @@
+package platform
+
+// DiskUsage describes the space on the file system holding a path
+type DiskUsage struct {
+	Total     uint64 `json:"total" yaml:"total"`           // Size of the file system in bytes
+	Free      uint64 `json:"free" yaml:"free"`             // Bytes available to unprivileged users
+	Files     uint64 `json:"files" yaml:"files"`           // Inodes on the file system; 0 if it has no fixed number
+	FreeFiles uint64 `json:"free_files" yaml:"free_files"` // Inodes free
+	ReadOnly  bool   `json:"read_only" yaml:"read_only"`   // Mounted read-only, so nothing on it can be deleted
+}
+
+// lowFilesPercent is the share of free inodes below which a file system is
+// considered out of them
+const lowFilesPercent = 5
+
+// Used returns the bytes in use
+func (d DiskUsage) Used() uint64 {
+	return d.Total - d.Free
+}
+
+// UsedFiles returns the inodes in use
+func (d DiskUsage) UsedFiles() uint64 {
+	return d.Files - d.FreeFiles
+}
+
+// LowOnFiles reports whether the file system is nearly out of inodes, so new
+// files can't be created however much space is free. File systems that
+// allocate inodes as needed never are.
+func (d DiskUsage) LowOnFiles() bool {
+	return d.Files > 0 && d.FreeFiles*100 < d.Files*lowFilesPercent
+}
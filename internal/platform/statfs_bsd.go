@@ -0,0 +1,34 @@
+//go:build darwin || freebsd || dragonfly
+
+package platform
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetDiskUsage returns the space on the file system holding path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("failed to statfs %s: %w", path, err)
+	}
+	// Bavail is signed on FreeBSD: it goes negative when root eats into the reserve
+	free := int64(st.Bavail)
+	if free < 0 {
+		free = 0
+	}
+	// Ffree is signed on FreeBSD and DragonFly for the same reason
+	freeFiles := int64(st.Ffree)
+	if freeFiles < 0 {
+		freeFiles = 0
+	}
+	return DiskUsage{
+		Total:     uint64(st.Blocks) * uint64(st.Bsize),
+		Free:      uint64(free) * uint64(st.Bsize),
+		Files:     uint64(st.Files),
+		FreeFiles: uint64(freeFiles),
+		ReadOnly:  st.Flags&unix.MNT_RDONLY != 0,
+	}, nil
+}
@@ -0,0 +1,22 @@
+package platform
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetDiskUsage returns the space on the file system holding path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("failed to statfs %s: %w", path, err)
+	}
+	return DiskUsage{
+		Total:     st.Blocks * uint64(st.Bsize),
+		Free:      st.Bavail * uint64(st.Bsize),
+		Files:     st.Files,
+		FreeFiles: st.Ffree,
+		ReadOnly:  st.Flags&unix.ST_RDONLY != 0,
+	}, nil
+}
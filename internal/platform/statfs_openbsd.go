@@ -0,0 +1,31 @@
+package platform
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetDiskUsage returns the space on the file system holding path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("failed to statfs %s: %w", path, err)
+	}
+	// F_bavail goes negative when root eats into the reserve
+	free := st.F_bavail
+	if free < 0 {
+		free = 0
+	}
+	freeFiles := st.F_favail
+	if freeFiles < 0 {
+		freeFiles = 0
+	}
+	return DiskUsage{
+		Total:     st.F_blocks * uint64(st.F_bsize),
+		Free:      uint64(free) * uint64(st.F_bsize),
+		Files:     st.F_files,
+		FreeFiles: uint64(freeFiles),
+		ReadOnly:  st.F_flags&unix.MNT_RDONLY != 0,
+	}, nil
+}
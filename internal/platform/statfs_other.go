@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !openbsd
+
+package platform
+
+// GetDiskUsage returns the space on the file system holding path
+func GetDiskUsage(path string) (DiskUsage, error) {
+	return DiskUsage{}, ErrUnsupportedPlatform
+}
@@ -0,0 +1,18 @@
+package platform
+
+// StorageKind is the kind of device a path is stored on
+type StorageKind string
+
+// Kinds of storage
+const (
+	StorageUnknown StorageKind = "unknown"
+	StorageSSD     StorageKind = "ssd" // Including NVMe
+	StorageHDD     StorageKind = "hdd"
+	StorageNetwork StorageKind = "network"
+)
+
+// networkFSTypes are the BSD and macOS file system type names of network
+// mounts
+var networkFSTypes = map[string]bool{
+	"nfs": true, "smbfs": true, "afpfs": true, "webdav": true, "cifs": true,
+}
@@ -0,0 +1,42 @@
+//go:build darwin || freebsd || dragonfly
+
+package platform
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// DetectStorage returns the kind of storage holding path: network mounts by
+// their file system type and, on macOS, disks by what diskutil reports
+func DetectStorage(path string) StorageKind {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return StorageUnknown
+	}
+	if networkFSTypes[unix.ByteSliceToString(st.Fstypename[:])] {
+		return StorageNetwork
+	}
+	if runtime.GOOS != "darwin" {
+		return StorageUnknown
+	}
+
+	out, err := exec.Command("diskutil", "info", unix.ByteSliceToString(st.Mntonname[:])).Output()
+	if err != nil {
+		return StorageUnknown
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || key != "Solid State" {
+			continue
+		}
+		if strings.TrimSpace(value) == "Yes" {
+			return StorageSSD
+		}
+		return StorageHDD
+	}
+	return StorageUnknown
+}
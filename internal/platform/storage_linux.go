@@ -0,0 +1,75 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// networkFSMagics are the statfs types of network file systems. WSL's
+// Windows drives (9p) are as slow as one.
+var networkFSMagics = map[int64]bool{
+	unix.NFS_SUPER_MAGIC:  true,
+	unix.SMB_SUPER_MAGIC:  true,
+	unix.SMB2_SUPER_MAGIC: true,
+	unix.CIFS_SUPER_MAGIC: true,
+	unix.CEPH_SUPER_MAGIC: true,
+	unix.AFS_SUPER_MAGIC:  true,
+	unix.CODA_SUPER_MAGIC: true,
+	0x01021997:            true, // 9p
+}
+
+// DetectStorage returns the kind of storage holding path: network mounts by
+// their file system type, disks by whether the kernel says they rotate
+func DetectStorage(path string) StorageKind {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(path, &fs); err != nil {
+		return StorageUnknown
+	}
+	if networkFSMagics[int64(fs.Type)] {
+		return StorageNetwork
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return StorageUnknown
+	}
+	// Major 0 is a virtual file system (tmpfs, overlay) or btrfs
+	if unix.Major(uint64(st.Dev)) == 0 {
+		return StorageUnknown
+	}
+	device := fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(uint64(st.Dev)), unix.Minor(uint64(st.Dev)))
+	return rotationalStorage(device)
+}
+
+// rotationalStorage reads the queue/rotational flag of a block device in
+// sysfs. A partition has no queue of its own, so its disk's is used.
+func rotationalStorage(device string) StorageKind {
+	disk, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return StorageUnknown
+	}
+	if _, err := os.Stat(filepath.Join(disk, "queue")); err != nil {
+		disk = filepath.Dir(disk)
+	}
+	// Virtio and Xen disks report whatever the hypervisor chose, which is
+	// usually "rotational" whatever is underneath
+	if name := filepath.Base(disk); strings.HasPrefix(name, "vd") || strings.HasPrefix(name, "xvd") {
+		return StorageUnknown
+	}
+
+	data, err := os.ReadFile(filepath.Join(disk, "queue", "rotational"))
+	if err != nil {
+		return StorageUnknown
+	}
+	switch strings.TrimSpace(string(data)) {
+	case "0":
+		return StorageSSD
+	case "1":
+		return StorageHDD
+	}
+	return StorageUnknown
+}
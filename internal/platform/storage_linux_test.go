@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotationalStorage(t *testing.T) {
+	sys := t.TempDir()
+	disk := func(name, rotational string) {
+		os.MkdirAll(filepath.Join(sys, "devices", name, name+"1"), 0755)
+		os.MkdirAll(filepath.Join(sys, "devices", name, "queue"), 0755)
+		os.WriteFile(filepath.Join(sys, "devices", name, "queue", "rotational"), []byte(rotational+"\n"), 0644)
+	}
+	disk("sda", "1")
+	disk("nvme0n1", "0")
+	disk("vda", "1")
+
+	tests := map[string]StorageKind{
+		"devices/sda":          StorageHDD,
+		"devices/sda/sda1":     StorageHDD, // A partition uses its disk's queue
+		"devices/nvme0n1":      StorageSSD,
+		"devices/vda/vda1":     StorageUnknown,
+		"devices/missing/dev1": StorageUnknown,
+	}
+	for target, want := range tests {
+		link := filepath.Join(sys, "link")
+		os.Remove(link)
+		os.Symlink(filepath.Join(sys, target), link)
+		if got := rotationalStorage(link); got != want {
+			t.Errorf("rotationalStorage(%s) = %s, want %s", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package platform
+
+import "golang.org/x/sys/unix"
+
+// DetectStorage returns the kind of storage holding path. Only network mounts
+// are told apart, by their file system type.
+func DetectStorage(path string) StorageKind {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return StorageUnknown
+	}
+	if networkFSTypes[unix.ByteSliceToString(st.F_fstypename[:])] {
+		return StorageNetwork
+	}
+	return StorageUnknown
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !openbsd
+
+package platform
+
+// DetectStorage returns the kind of storage holding path, which isn't known
+// on this platform
+func DetectStorage(path string) StorageKind {
+	return StorageUnknown
+}
@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// windowsSystemProfiles are the profile folders under C:\Users that don't
+// belong to a person
+var windowsSystemProfiles = map[string]bool{
+	"all users":    true,
+	"default":      true,
+	"default user": true,
+	"public":       true,
+}
+
+// isWSL reports whether we're running under Windows Subsystem for Linux
+func isWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false
+	}
+	lower := strings.ToLower(string(release))
+	return strings.Contains(lower, "microsoft") || strings.Contains(lower, "wsl")
+}
+
+// windowsMounts returns where the Windows drives are mounted, read from
+// /proc/self/mounts
+func windowsMounts() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return parseDrvfsMounts(f)
+}
+
+// parseDrvfsMounts returns the mount points of the DrvFs file systems in a
+// mounts table. WSL 1 mounts them as "drvfs", WSL 2 as 9p with aname=drvfs.
+func parseDrvfsMounts(r io.Reader) []string {
+	var mounts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		fsType, options := fields[2], fields[3]
+		if fsType != "drvfs" && !(fsType == "9p" && strings.Contains(options, "aname=drvfs")) {
+			continue
+		}
+		mounts = append(mounts, unescapeMount(fields[1]))
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes (\040 for a space) the kernel
+// writes in mount points
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// WindowsProfiles returns the Windows user folders (C:\Users\<name>) reachable
+// through the Windows mounts. The one matching username comes first.
+func WindowsProfiles(info *Info) []string {
+	var profiles []string
+	for _, mount := range info.WindowsMounts {
+		entries, err := os.ReadDir(filepath.Join(mount, "Users"))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || windowsSystemProfiles[strings.ToLower(entry.Name())] {
+				continue
+			}
+			profile := filepath.Join(mount, "Users", entry.Name())
+			if strings.EqualFold(entry.Name(), info.Username) {
+				profiles = append([]string{profile}, profiles...)
+			} else {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,39 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDrvfsMounts(t *testing.T) {
+	mounts := `/dev/sdc / ext4 rw,relatime,discard,errors=remount-ro,data=ordered 0 0
+C:\134 /mnt/c 9p rw,noatime,dirsync,aname=drvfs;path=C:\;uid=1000;gid=1000;symlinkroot=/mnt/ 0 0
+D:\134 /mnt/d\040drive 9p rw,noatime,aname=drvfs;path=D:\;uid=1000 0 0
+E: /mnt/e drvfs rw,noatime,uid=1000,gid=1000 0 0
+drvfs /usr/lib/wsl/drivers 9p ro,nosuid,nodev,noatime,aname=drivers;fmask=222;dmask=222 0 0
+none /mnt/wsl tmpfs rw,relatime 0 0
+`
+	got := parseDrvfsMounts(strings.NewReader(mounts))
+	want := []string{"/mnt/c", "/mnt/d drive", "/mnt/e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDrvfsMounts() = %v, want %v", got, want)
+	}
+}
+
+func TestWindowsProfiles(t *testing.T) {
+	mount := t.TempDir()
+	for _, name := range []string{"Public", "Default", "bob", "Alice"} {
+		if err := os.MkdirAll(filepath.Join(mount, "Users", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := WindowsProfiles(&Info{Username: "alice", WindowsMounts: []string{mount}})
+	want := []string{filepath.Join(mount, "Users", "Alice"), filepath.Join(mount, "Users", "bob")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WindowsProfiles() = %v, want %v", got, want)
+	}
+}
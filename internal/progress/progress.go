@@ -4,16 +4,20 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 // Phase represents the current phase of operation
 type Phase string
 
 const (
-	PhaseScanning Phase = "scanning"
-	PhaseCleaning Phase = "cleaning"
-	PhaseComplete Phase = "complete"
-	PhaseError    Phase = "error"
+	PhaseScanning     Phase = "scanning"
+	PhaseSizing       Phase = "sizing" // Measuring directories found by the scan
+	PhaseCleaning     Phase = "cleaning"
+	PhaseChecksumming Phase = "checksumming" // Hashing large files for the audit log before deleting
+	PhaseComplete     Phase = "complete"
+	PhaseError        Phase = "error"
 )
 
 // ScanProgress represents progress during scanning
@@ -31,18 +35,22 @@ type ScanProgress struct {
 
 // CleanProgress represents progress during cleanup
 type CleanProgress struct {
-	Phase        Phase
-	CurrentFile  string
-	DeletedFiles int
-	TotalFiles   int
-	DeletedSize  int64
-	TotalSize    int64
-	SkippedFiles int
-	ErrorCount   int
-	StartTime    time.Time
-	UsingSudo    bool
-	SudoPrompted bool
-	Error        error
+	RunID          string
+	Phase          Phase
+	CurrentFile    string
+	DeletedFiles   int
+	TotalFiles     int
+	DeletedSize    int64
+	TotalSize      int64
+	SkippedFiles   int
+	ErrorCount     int
+	StartTime      time.Time
+	UsingSudo      bool
+	SudoPrompted   bool
+	Error          error
+	FilesPerSecond float64       // Since the cleanup started
+	BytesPerSecond float64       // Since the cleanup started
+	ETA            time.Duration // Time left at the current rate; 0 until there's a rate
 }
 
 // ProgressReporter provides thread-safe progress reporting
@@ -195,6 +203,12 @@ func FormatCleanProgress(p *CleanProgress) string {
 			FormatBytes(p.DeletedSize),
 			sudo,
 			eta)
+	case PhaseChecksumming:
+		return fmt.Sprintf("Checksumming... %d/%d files (%s of %s)",
+			p.DeletedFiles,
+			p.TotalFiles,
+			FormatBytes(p.DeletedSize),
+			FormatBytes(p.TotalSize))
 	case PhaseComplete:
 		return fmt.Sprintf("Cleanup complete: %d files deleted (%s) in %s",
 			p.DeletedFiles,
@@ -209,16 +223,7 @@ func FormatCleanProgress(p *CleanProgress) string {
 
 // FormatBytes formats bytes in human-readable format
 func FormatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGT"[exp])
+	return utils.FormatBytes(bytes)
 }
 
 // FormatDuration formats duration in human-readable format
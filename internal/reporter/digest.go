@@ -0,0 +1,258 @@
+package reporter
+
+import (
+	htmltemplate "html/template"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// digestLargest is how many of the largest results a digest lists
+const digestLargest = 20
+
+// digest is what the HTML and Markdown reports are rendered from: the totals
+// of a scan and its largest results, not every file
+type digest struct {
+	Hostname   string
+	RunID      string
+	Generated  time.Time
+	TotalCount int
+	TotalSize  int64
+	Categories []digestRow
+	Risks      []digestRow
+	Owners     []digestRow // Empty unless several users' files were found
+	Access     []digestRow // Empty unless some items need sudo or can't be deleted
+	Largest    []scanner.FileInfo
+}
+
+// digestRow is one line of a digest's breakdowns
+type digestRow struct {
+	Name  string
+	Count int
+	Size  int64
+}
+
+// newDigest sums up result. Spilled results are read a batch at a time.
+func (r *Reporter) newDigest(result *scanner.ScanResult) (*digest, error) {
+	d := &digest{Generated: time.Now(), TotalCount: result.TotalCount, TotalSize: result.TotalSize}
+	if r.metadata != nil {
+		d.Hostname, d.RunID = r.metadata.Hostname, r.metadata.RunID
+	} else {
+		d.Hostname, _ = os.Hostname()
+	}
+
+	categories, err := result.CategoryTotals()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range largestFirst(categories) {
+		d.Categories = append(d.Categories, digestRow{name, categories[name].Count, categories[name].Size})
+	}
+
+	risks, err := result.RiskTotals()
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range scanner.RiskLevels {
+		if total, ok := risks[level]; ok {
+			d.Risks = append(d.Risks, digestRow{level, total.Count, total.Size})
+		}
+	}
+
+	owners, err := result.OwnerTotals()
+	if err != nil {
+		return nil, err
+	}
+	if len(owners) > 1 {
+		for _, name := range largestFirst(owners) {
+			d.Owners = append(d.Owners, digestRow{name, owners[name].Count, owners[name].Size})
+		}
+	}
+
+	access, err := result.AccessTotals()
+	if err != nil {
+		return nil, err
+	}
+	if access[scanner.AccessNeedsSudo].Count+access[scanner.AccessInaccessible].Count > 0 {
+		for _, class := range scanner.AccessLevels {
+			if total, ok := access[class]; ok {
+				d.Access = append(d.Access, digestRow{class, total.Count, total.Size})
+			}
+		}
+	}
+
+	err = result.Each(func(file scanner.FileInfo) error {
+		if len(d.Largest) == digestLargest && file.Size <= d.Largest[len(d.Largest)-1].Size {
+			return nil
+		}
+		i := sort.Search(len(d.Largest), func(i int) bool { return d.Largest[i].Size < file.Size })
+		d.Largest = append(d.Largest[:i], append([]scanner.FileInfo{file}, d.Largest[i:]...)...)
+		if len(d.Largest) > digestLargest {
+			d.Largest = d.Largest[:digestLargest]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// reportHTML writes a self-contained HTML report, suitable for email
+func (r *Reporter) reportHTML(result *scanner.ScanResult) error {
+	d, err := r.newDigest(result)
+	if err != nil {
+		return err
+	}
+	return htmlDigest.Execute(r.writer, d)
+}
+
+// reportMarkdown writes a Markdown report
+func (r *Reporter) reportMarkdown(result *scanner.ScanResult) error {
+	d, err := r.newDigest(result)
+	if err != nil {
+		return err
+	}
+	return markdownDigest.Execute(r.writer, d)
+}
+
+// mdCell keeps text from breaking out of a Markdown table cell
+var mdCell = strings.NewReplacer("|", `\|`, "\n", " ", "\r", " ", "`", "'")
+
+var digestFuncs = map[string]interface{}{
+	"humanBytes": utils.FormatBytes,
+	"md":         mdCell.Replace,
+	"relTime":    relTime,
+}
+
+var htmlDigest = htmltemplate.Must(htmltemplate.New("html").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Disk hygiene report for {{.Hostname}}</title>
+<style>
+  body { font-family: Arial, sans-serif; margin: 20px; color: #222; }
+  h1 { font-size: 22px; }
+  h2 { font-size: 17px; margin-top: 28px; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { padding: 6px 8px; text-align: left; border-bottom: 1px solid #ddd; }
+  th { background-color: #f5f5f5; }
+  td.num { text-align: right; white-space: nowrap; }
+  .review { color: #c0392b; }
+  .footer { font-size: 12px; color: #666; margin-top: 28px; }
+</style>
+</head>
+<body>
+<h1>Disk hygiene report for {{.Hostname}}</h1>
+<p><strong>{{humanBytes .TotalSize}}</strong> in {{.TotalCount}} items could be cleaned.</p>
+{{- if .Categories}}
+<h2>By category</h2>
+<table>
+<tr><th>Category</th><th>Items</th><th>Size</th></tr>
+{{- range .Categories}}
+<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{humanBytes .Size}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Risks}}
+<h2>By risk</h2>
+<table>
+<tr><th>Risk</th><th>Items</th><th>Size</th></tr>
+{{- range .Risks}}
+<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{humanBytes .Size}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Owners}}
+<h2>By owner</h2>
+<table>
+<tr><th>Owner</th><th>Items</th><th>Size</th></tr>
+{{- range .Owners}}
+<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{humanBytes .Size}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Access}}
+<h2>By access</h2>
+<table>
+<tr><th>Access</th><th>Items</th><th>Size</th></tr>
+{{- range .Access}}
+<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{humanBytes .Size}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Largest}}
+<h2>Largest items</h2>
+<table>
+<tr><th>Path</th><th>Category</th><th>Why</th><th>Risk</th><th>Access</th><th>Owner</th><th>Modified</th><th>Size</th></tr>
+{{- range .Largest}}
+<tr><td>{{.Path}}</td><td>{{.Category}}</td><td>{{.Reason}}</td><td{{if eq .Risk "review"}} class="review"{{end}}>{{.Risk}}</td><td>{{.Access}}</td><td>{{.Owner}}</td><td>{{relTime .ModTime}}</td><td class="num">{{humanBytes .Size}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<p class="footer">Generated by tidyup on {{.Generated.Format "2006-01-02 15:04"}}{{if .RunID}}, run {{.RunID}}{{end}}. Nothing was deleted.</p>
+</body>
+</html>
+`))
+
+var markdownDigest = template.Must(template.New("markdown").Funcs(digestFuncs).Parse(`# Disk hygiene report for {{md .Hostname}}
+
+**{{humanBytes .TotalSize}}** in {{.TotalCount}} items could be cleaned.
+{{- if .Categories}}
+
+## By category
+
+| Category | Items | Size |
+|---|---:|---:|
+{{- range .Categories}}
+| {{md .Name}} | {{.Count}} | {{humanBytes .Size}} |
+{{- end}}
+{{- end}}
+{{- if .Risks}}
+
+## By risk
+
+| Risk | Items | Size |
+|---|---:|---:|
+{{- range .Risks}}
+| {{.Name}} | {{.Count}} | {{humanBytes .Size}} |
+{{- end}}
+{{- end}}
+{{- if .Owners}}
+
+## By owner
+
+| Owner | Items | Size |
+|---|---:|---:|
+{{- range .Owners}}
+| {{md .Name}} | {{.Count}} | {{humanBytes .Size}} |
+{{- end}}
+{{- end}}
+{{- if .Access}}
+
+## By access
+
+| Access | Items | Size |
+|---|---:|---:|
+{{- range .Access}}
+| {{.Name}} | {{.Count}} | {{humanBytes .Size}} |
+{{- end}}
+{{- end}}
+{{- if .Largest}}
+
+## Largest items
+
+| Path | Category | Why | Risk | Access | Owner | Modified | Size |
+|---|---|---|---|---|---|---|---:|
+{{- range .Largest}}
+| ` + "`{{md .Path}}`" + ` | {{md .Category}} | {{md .Reason}} | {{.Risk}} | {{.Access}} | {{md .Owner}} | {{relTime .ModTime}} | {{humanBytes .Size}} |
+{{- end}}
+{{- end}}
+
+_Generated by tidyup on {{.Generated.Format "2006-01-02 15:04"}}{{if .RunID}}, run {{.RunID}}{{end}}. Nothing was deleted._
+`))
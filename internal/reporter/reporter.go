@@ -1,31 +1,102 @@
 package reporter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
 	"time"
 
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
 	"github.com/fenilsonani/system-cleanup/internal/scanner"
 	"github.com/fenilsonani/system-cleanup/pkg/utils"
 	"gopkg.in/yaml.v3"
 )
 
+// SchemaVersion is the version of the JSON/YAML report layout. It's bumped
+// whenever a field is renamed, removed or changes meaning; new fields don't
+// change it.
+const SchemaVersion = 1
+
 // OutputFormat represents the output format type
 type OutputFormat string
 
 const (
-	FormatTable   OutputFormat = "table"
-	FormatJSON    OutputFormat = "json"
-	FormatYAML    OutputFormat = "yaml"
-	FormatSummary OutputFormat = "summary"
+	FormatTable    OutputFormat = "table"
+	FormatJSON     OutputFormat = "json"
+	FormatYAML     OutputFormat = "yaml"
+	FormatSummary  OutputFormat = "summary"
+	FormatHTML     OutputFormat = "html"
+	FormatMarkdown OutputFormat = "markdown"
 )
 
 // Reporter handles report generation
 type Reporter struct {
-	writer io.Writer
-	format OutputFormat
+	writer   io.Writer
+	format   OutputFormat
+	metadata *Metadata
+}
+
+// Metadata identifies the machine and run a report came from
+type Metadata struct {
+	RunID          string         `json:"run_id,omitempty" yaml:"run_id,omitempty"`
+	Hostname       string         `json:"hostname" yaml:"hostname"`
+	Platform       *platform.Info `json:"platform,omitempty" yaml:"platform,omitempty"`
+	ConfigHash     string         `json:"config_hash,omitempty" yaml:"config_hash,omitempty"`
+	ScanDurationMS int64          `json:"scan_duration_ms" yaml:"scan_duration_ms"`
+	Engine         string         `json:"engine,omitempty" yaml:"engine,omitempty"`
+	ToolVersion    string         `json:"tool_version,omitempty" yaml:"tool_version,omitempty"`
+}
+
+// envelope wraps JSON and YAML reports with the schema version and metadata
+type envelope struct {
+	SchemaVersion int         `json:"schema_version" yaml:"schema_version"`
+	Metadata      *Metadata   `json:"metadata" yaml:"metadata"`
+	Report        interface{} `json:"report" yaml:"report"`
+}
+
+// NewMetadata describes a scan of this machine. The config is hashed so
+// reports made with the same settings can be grouped.
+func NewMetadata(info *platform.Info, cfg *config.Config, scanDuration time.Duration, engine, toolVersion string) *Metadata {
+	hostname, _ := os.Hostname()
+	meta := &Metadata{
+		Hostname:       hostname,
+		Platform:       info,
+		ScanDurationMS: scanDuration.Milliseconds(),
+		Engine:         engine,
+		ToolVersion:    toolVersion,
+	}
+	if cfg != nil {
+		if data, err := yaml.Marshal(cfg); err == nil {
+			sum := sha256.Sum256(data)
+			meta.ConfigHash = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return meta
+}
+
+// SetMetadata sets the metadata included in JSON, YAML, HTML and Markdown
+// reports
+func (r *Reporter) SetMetadata(meta *Metadata) {
+	r.metadata = meta
+}
+
+// wrap puts report in the versioned envelope. Without metadata only the
+// hostname is filled in.
+func (r *Reporter) wrap(report interface{}) envelope {
+	meta := r.metadata
+	if meta == nil {
+		hostname, _ := os.Hostname()
+		meta = &Metadata{Hostname: hostname}
+	}
+	return envelope{SchemaVersion: SchemaVersion, Metadata: meta, Report: report}
 }
 
 // New creates a new Reporter
@@ -47,6 +118,12 @@ func (r *Reporter) Report(result *scanner.ScanResult) error {
 		return r.reportYAML(result)
 	case FormatSummary:
 		return r.reportSummary(result)
+	case FormatHTML:
+		return r.reportHTML(result)
+	case FormatMarkdown:
+		return r.reportMarkdown(result)
+	case FormatCSV:
+		return r.reportCSV(result)
 	default:
 		return fmt.Errorf("unsupported format: %s", r.format)
 	}
@@ -54,19 +131,95 @@ func (r *Reporter) Report(result *scanner.ScanResult) error {
 
 // reportSummary generates a summary report
 func (r *Reporter) reportSummary(result *scanner.ScanResult) error {
-	fmt.Fprintf(r.writer, "=== Cleanup Summary ===\n")
-	fmt.Fprintf(r.writer, "Total Files: %d\n", result.TotalCount)
-	fmt.Fprintf(r.writer, "Total Size: %s\n", utils.FormatBytes(result.TotalSize))
-	fmt.Fprintf(r.writer, "\nBreakdown by Category:\n")
+	fmt.Fprint(r.writer, i18n.T("=== Cleanup Summary ===\n"))
+	fmt.Fprint(r.writer, i18n.T("Total Files: %d\n", result.TotalCount))
+	fmt.Fprint(r.writer, i18n.T("Total Size: %s\n", utils.FormatBytes(result.TotalSize)))
+	fmt.Fprint(r.writer, i18n.T("\nBreakdown by Category:\n"))
+
+	totals, err := result.CategoryTotals()
+	if err != nil {
+		return err
+	}
+	for category, total := range totals {
+		fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+			category, total.Count, utils.FormatBytes(total.Size)))
+	}
 
-	grouped := result.GroupByCategory()
-	for category, catResult := range grouped {
-		fmt.Fprintf(r.writer, "  %s: %d files, %s\n",
-			category, catResult.TotalCount, utils.FormatBytes(catResult.TotalSize))
+	risks, err := result.RiskTotals()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(r.writer, i18n.T("\nBreakdown by Risk:\n"))
+	for _, level := range scanner.RiskLevels {
+		if total, ok := risks[level]; ok {
+			fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+				level, total.Count, utils.FormatBytes(total.Size)))
+		}
+	}
+
+	// Only worth showing on systems where several users' files were found
+	owners, err := result.OwnerTotals()
+	if err != nil {
+		return err
+	}
+	if len(owners) > 1 {
+		fmt.Fprint(r.writer, i18n.T("\nBreakdown by Owner:\n"))
+		for _, owner := range largestFirst(owners) {
+			total := owners[owner]
+			fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+				owner, total.Count, utils.FormatBytes(total.Size)))
+		}
+	}
+
+	// Only worth showing when cleaning will ask for a password or skip files
+	access, err := result.AccessTotals()
+	if err != nil {
+		return err
+	}
+	if access[scanner.AccessNeedsSudo].Count+access[scanner.AccessInaccessible].Count > 0 {
+		fmt.Fprint(r.writer, i18n.T("\nBreakdown by Access:\n"))
+		for _, class := range scanner.AccessLevels {
+			if total, ok := access[class]; ok {
+				fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+					class, total.Count, utils.FormatBytes(total.Size)))
+			}
+		}
+	}
+
+	types, err := result.TypeTotals()
+	if err != nil {
+		return err
+	}
+	if len(types) > 0 {
+		fmt.Fprint(r.writer, i18n.T("\nLarge Files by Type:\n"))
+		for _, kind := range scanner.FileTypes {
+			if total, ok := types[kind]; ok {
+				fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+					kind, total.Count, utils.FormatBytes(total.Size)))
+			}
+		}
+	}
+
+	months, err := result.ScreenshotMonths()
+	if err != nil {
+		return err
+	}
+	if len(months) > 0 {
+		fmt.Fprint(r.writer, i18n.T("\nScreenshots by Month:\n"))
+		keys := make([]string, 0, len(months))
+		for month := range months {
+			keys = append(keys, month)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		for _, month := range keys {
+			total := months[month]
+			fmt.Fprint(r.writer, i18n.T("  %s: %d files, %s\n",
+				month, total.Count, utils.FormatBytes(total.Size)))
+		}
 	}
 
 	if len(result.Errors) > 0 {
-		fmt.Fprintf(r.writer, "\nErrors: %d\n", len(result.Errors))
+		fmt.Fprint(r.writer, i18n.T("\nErrors: %d\n", len(result.Errors)))
 	}
 
 	return nil
@@ -75,21 +228,29 @@ func (r *Reporter) reportSummary(result *scanner.ScanResult) error {
 // reportTable generates a table report
 func (r *Reporter) reportTable(result *scanner.ScanResult) error {
 	// Print header
-	fmt.Fprintf(r.writer, "%-60s | %-12s | %-20s | %s\n", "Path", "Size", "Category", "Modified")
+	fmt.Fprintf(r.writer, "%-60s | %-12s | %-20s | %-6s | %-12s | %-12s | %-19s | %s\n", "Path", "Size", "Category", "Risk", "Access", "Owner", "Modified", "Why")
 	fmt.Fprintf(r.writer, "%s\n", string(make([]byte, 120)))
 
-	// Print rows
-	for _, file := range result.Files {
+	// Print rows, reading spilled results back a batch at a time
+	err := result.Each(func(file scanner.FileInfo) error {
 		path := file.Path
 		if len(path) > 60 {
 			path = "..." + path[len(path)-57:]
 		}
 
-		fmt.Fprintf(r.writer, "%-60s | %-12s | %-20s | %s\n",
+		fmt.Fprintf(r.writer, "%-60s | %-12s | %-20s | %-6s | %-12s | %-12s | %-19s | %s\n",
 			path,
 			utils.FormatBytes(file.Size),
 			file.Category,
-			file.ModTime.Format("2006-01-02 15:04:05"))
+			file.Risk,
+			file.Access,
+			file.Owner,
+			file.ModTime.Format("2006-01-02 15:04:05"),
+			file.Reason)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Print summary
@@ -99,54 +260,193 @@ func (r *Reporter) reportTable(result *scanner.ScanResult) error {
 	return nil
 }
 
-// reportJSON generates a JSON report
+// reportJSON generates a JSON report. It needs every file at once, so
+// spilled results are loaded.
 func (r *Reporter) reportJSON(result *scanner.ScanResult) error {
+	if err := result.Load(); err != nil {
+		return err
+	}
 	report := struct {
-		Timestamp          string             `json:"timestamp"`
-		TotalFiles         int                `json:"total_files"`
-		TotalSize          int64              `json:"total_size"`
-		TotalSizeFormatted string             `json:"total_size_formatted"`
-		Files              []scanner.FileInfo `json:"files"`
-		Errors             int                `json:"errors"`
+		Timestamp          string               `json:"timestamp"`
+		TotalFiles         int                  `json:"total_files"`
+		TotalSize          int64                `json:"total_size"`
+		TotalSizeFormatted string               `json:"total_size_formatted"`
+		Types              map[string]typeTotal `json:"types,omitempty"`
+		Files              []scanner.FileInfo   `json:"files"`
+		Errors             int                  `json:"errors"`
 	}{
 		Timestamp:          time.Now().Format(time.RFC3339),
 		TotalFiles:         result.TotalCount,
 		TotalSize:          result.TotalSize,
 		TotalSizeFormatted: utils.FormatBytes(result.TotalSize),
+		Types:              typeTotals(result),
 		Files:              result.Files,
 		Errors:             len(result.Errors),
 	}
 
 	encoder := json.NewEncoder(r.writer)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(report)
+	return encoder.Encode(r.wrap(report))
 }
 
-// reportYAML generates a YAML report
+// reportYAML generates a YAML report. It needs every file at once, so
+// spilled results are loaded.
 func (r *Reporter) reportYAML(result *scanner.ScanResult) error {
+	if err := result.Load(); err != nil {
+		return err
+	}
 	report := struct {
-		Timestamp          string             `yaml:"timestamp"`
-		TotalFiles         int                `yaml:"total_files"`
-		TotalSize          int64              `yaml:"total_size"`
-		TotalSizeFormatted string             `yaml:"total_size_formatted"`
-		Files              []scanner.FileInfo `yaml:"files"`
-		Errors             int                `yaml:"errors"`
+		Timestamp          string               `yaml:"timestamp"`
+		TotalFiles         int                  `yaml:"total_files"`
+		TotalSize          int64                `yaml:"total_size"`
+		TotalSizeFormatted string               `yaml:"total_size_formatted"`
+		Types              map[string]typeTotal `yaml:"types,omitempty"`
+		Files              []scanner.FileInfo   `yaml:"files"`
+		Errors             int                  `yaml:"errors"`
 	}{
 		Timestamp:          time.Now().Format(time.RFC3339),
 		TotalFiles:         result.TotalCount,
 		TotalSize:          result.TotalSize,
 		TotalSizeFormatted: utils.FormatBytes(result.TotalSize),
+		Types:              typeTotals(result),
 		Files:              result.Files,
 		Errors:             len(result.Errors),
 	}
 
 	encoder := yaml.NewEncoder(r.writer)
 	defer encoder.Close()
-	return encoder.Encode(report)
+	return encoder.Encode(r.wrap(report))
+}
+
+// largestFirst returns the keys of totals by size, largest first
+func largestFirst(totals map[string]scanner.CategoryTotal) []string {
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]].Size != totals[keys[j]].Size {
+			return totals[keys[i]].Size > totals[keys[j]].Size
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// typeTotal is the count and size of the large files of one type
+type typeTotal struct {
+	Count int   `json:"count" yaml:"count"`
+	Size  int64 `json:"size" yaml:"size"`
+}
+
+// typeTotals returns the totals of each type of large file in result, which
+// must be loaded
+func typeTotals(result *scanner.ScanResult) map[string]typeTotal {
+	totals := make(map[string]typeTotal)
+	for _, file := range result.Files {
+		if file.Type == "" {
+			continue
+		}
+		total := totals[file.Type]
+		total.Count++
+		total.Size += file.Size
+		totals[file.Type] = total
+	}
+	return totals
+}
+
+// templateData is what custom report templates are executed with. The scan
+// result's fields (.Files, .TotalSize, ...) are available directly.
+type templateData struct {
+	*scanner.ScanResult
+	Metadata  *Metadata
+	Generated time.Time
+}
+
+// CategoryGroup is one category in the groupByCategory template helper
+type CategoryGroup struct {
+	Category string
+	Count    int
+	Size     int64
+	Files    []scanner.FileInfo
+}
+
+// templateFuncs are the helpers available in custom report templates
+var templateFuncs = template.FuncMap{
+	"humanBytes":      utils.FormatBytes,
+	"relTime":         relTime,
+	"groupByCategory": groupByCategory,
+}
+
+// ReportTemplate renders result through the text/template in path, for
+// output formats the reporter doesn't have built in
+func (r *Reporter) ReportTemplate(result *scanner.ScanResult, path string) error {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	if err := result.Load(); err != nil {
+		return err
+	}
+
+	data := templateData{ScanResult: result, Metadata: r.metadata, Generated: time.Now()}
+	if err := tmpl.Execute(r.writer, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
+
+// groupByCategory returns the files per category, largest category first
+func groupByCategory(files []scanner.FileInfo) []CategoryGroup {
+	result := &scanner.ScanResult{Files: files}
+	var groups []CategoryGroup
+	for category, catResult := range result.GroupByCategory() {
+		groups = append(groups, CategoryGroup{
+			Category: category,
+			Count:    catResult.TotalCount,
+			Size:     catResult.TotalSize,
+			Files:    catResult.Files,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Category < groups[j].Category
+	})
+	return groups
+}
+
+// relTime describes how long ago t was, e.g. "3 days ago"
+func relTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	d := time.Since(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year")
+	}
 }
 
 // SaveToFile saves the report to a file
-func SaveToFile(result *scanner.ScanResult, path string, format OutputFormat) error {
+func SaveToFile(result *scanner.ScanResult, path string, format OutputFormat, meta *Metadata) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -154,5 +454,6 @@ func SaveToFile(result *scanner.ScanResult, path string, format OutputFormat) er
 	defer file.Close()
 
 	reporter := New(file, format)
+	reporter.SetMetadata(meta)
 	return reporter.Report(result)
 }
@@ -0,0 +1,386 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/internal/testutil"
+)
+
+// =============================================================================
+// Streamed Report Tests
+// =============================================================================
+
+// cacheFixture creates a fixture whose cache directory holds count files
+func cacheFixture(t *testing.T, count int) *testutil.TestFixture {
+	f := testutil.NewFixture(t)
+	for i := 0; i < count; i++ {
+		f.CreateCacheFile(fmt.Sprintf("file%02d.cache", i), 100+i)
+	}
+	return f
+}
+
+// cacheScanner scans f's cache directory. Each scan needs its own scanner:
+// a second scan would list the directory, unchanged since the first, as a
+// whole.
+func cacheScanner(f *testutil.TestFixture) *scanner.HyperScanner {
+	cfg := &config.Config{Categories: config.Categories{Cache: true}}
+	hs := scanner.NewHyperScanner(cfg, &platform.Info{HomeDir: f.RootDir, CacheDirs: []string{f.CacheDir}})
+	hs.DisableCache()
+	return hs
+}
+
+// decodeReport decodes a JSON report, leaving out what differs between
+// runs: the timestamp, and the files' order
+func decodeReport(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var report map[string]any
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report isn't valid JSON: %v\n%s", err, data)
+	}
+	body := report["report"].(map[string]any)
+	delete(body, "timestamp")
+	files := body["files"].([]any)
+	slices.SortFunc(files, func(a, b any) int {
+		return strings.Compare(a.(map[string]any)["Path"].(string), b.(map[string]any)["Path"].(string))
+	})
+	return report
+}
+
+func TestReportStreamJSON(t *testing.T) {
+	f := cacheFixture(t, 25)
+	meta := &Metadata{RunID: "run-1", Hostname: "host", ScanDurationMS: 5}
+
+	var streamed bytes.Buffer
+	r := New(&streamed, FormatJSON)
+	r.SetMetadata(meta)
+	result, err := r.ReportStream(cacheScanner(f).Stream(10))
+	if err != nil {
+		t.Fatalf("ReportStream failed: %v", err)
+	}
+	if result.TotalCount != 25 {
+		t.Errorf("expected 25 files in the result, got %d", result.TotalCount)
+	}
+
+	full, err := cacheScanner(f).ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	var reported bytes.Buffer
+	r = New(&reported, FormatJSON)
+	r.SetMetadata(meta)
+	if err := r.Report(full); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	got, want := decodeReport(t, streamed.Bytes()), decodeReport(t, reported.Bytes())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed report differs from Report's:\n%s\nwant:\n%s", streamed.String(), reported.String())
+	}
+}
+
+func TestReportStreamJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := New(&buf, FormatJSON).ReportStream(cacheScanner(cacheFixture(t, 0)).Stream(10)); err != nil {
+		t.Fatalf("ReportStream failed: %v", err)
+	}
+	report := decodeReport(t, buf.Bytes())
+	body := report["report"].(map[string]any)
+	if files := body["files"].([]any); len(files) != 0 || body["total_files"] != float64(0) {
+		t.Errorf("expected an empty report, got %s", buf.String())
+	}
+}
+
+func TestReportStreamCSV(t *testing.T) {
+	f := cacheFixture(t, 25)
+
+	var buf bytes.Buffer
+	if _, err := New(&buf, FormatCSV).ReportStream(cacheScanner(f).Stream(10)); err != nil {
+		t.Fatalf("ReportStream failed: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("report isn't valid CSV: %v", err)
+	}
+	if len(rows) == 0 || !slices.Equal(rows[0], csvHeader) {
+		t.Fatalf("expected the header row first, got %v", rows)
+	}
+
+	full, err := cacheScanner(f).ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	var want [][]string
+	for _, file := range full.Files {
+		want = append(want, csvRow(file))
+	}
+	got := rows[1:]
+	for _, rows := range [][][]string{got, want} {
+		slices.SortFunc(rows, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected rows\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestReportStreamUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := New(&buf, FormatYAML).ReportStream(cacheScanner(cacheFixture(t, 3)).Stream(1)); err == nil {
+		t.Error("expected YAML reports to be refused")
+	}
+}
+
+// =============================================================================
+// Saved Report Tests
+// =============================================================================
+
+// savedNow is when the saved reports in these tests were made
+var savedNow = time.Date(2026, 10, 16, 15, 4, 5, 0, time.Local)
+
+// writeSavedReport saves a report holding content in dir, made at now
+func writeSavedReport(t *testing.T, dir string, now time.Time, content string) string {
+	t.Helper()
+	path, err := writeSaved(dir, now, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("writeSaved failed: %v", err)
+	}
+	return path
+}
+
+// reportNames returns the file names of reports
+func reportNames(reports []SavedReport) []string {
+	var names []string
+	for _, report := range reports {
+		names = append(names, filepath.Base(report.Path))
+	}
+	return names
+}
+
+func TestSavedTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantSeq int
+		wantOK  bool
+	}{
+		{"first of the second", "report-20261016-150405.json.gz", 1, true},
+		{"second of the second", "report-20261016-150405-2.json.gz", 2, true},
+		{"twelfth of the second", "report-20261016-150405-12.json.gz", 12, true},
+		{"suffix without dash", "report-20261016-1504052.json.gz", 0, false},
+		{"suffix not a number", "report-20261016-150405-x.json.gz", 0, false},
+		{"bad time", "report-20261316-150405.json.gz", 0, false},
+		{"short time", "report-20261016.json.gz", 0, false},
+		{"not gzipped", "report-20261016-150405.json", 0, false},
+		{"other file", "notes-20261016-150405.json.gz", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, seq, ok := savedTime(tt.file)
+			if ok != tt.wantOK || seq != tt.wantSeq {
+				t.Fatalf("savedTime(%q) = _, %d, %v, want _, %d, %v", tt.file, seq, ok, tt.wantSeq, tt.wantOK)
+			}
+			if ok && !created.Equal(savedNow) {
+				t.Errorf("savedTime(%q) created %v, want %v", tt.file, created, savedNow)
+			}
+		})
+	}
+}
+
+func TestWriteSavedSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 3; i++ {
+		names = append(names, filepath.Base(writeSavedReport(t, dir, savedNow, "{}")))
+	}
+	want := []string{
+		"report-20261016-150405.json.gz",
+		"report-20261016-150405-2.json.gz",
+		"report-20261016-150405-3.json.gz",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected reports %v, got %v", want, names)
+	}
+}
+
+func TestListReports(t *testing.T) {
+	dir := t.TempDir()
+	writeSavedReport(t, dir, savedNow.Add(-time.Hour), "{}")
+	writeSavedReport(t, dir, savedNow, "{}")
+	writeSavedReport(t, dir, savedNow, "{}")
+	writeSavedReport(t, dir, savedNow.Add(-24*time.Hour), "{}")
+	for _, name := range []string{"notes.txt", "report-latest.json.gz", ".report-123"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "report-20261017-000000.json.gz"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := ListReports(dir)
+	if err != nil {
+		t.Fatalf("ListReports failed: %v", err)
+	}
+	want := []string{
+		"report-20261016-150405-2.json.gz",
+		"report-20261016-150405.json.gz",
+		"report-20261016-140405.json.gz",
+		"report-20261015-150405.json.gz",
+	}
+	if got := reportNames(reports); !slices.Equal(got, want) {
+		t.Errorf("expected reports newest first %v, got %v", want, got)
+	}
+
+	if reports, err := ListReports(filepath.Join(dir, "missing")); err != nil || len(reports) != 0 {
+		t.Errorf("expected no reports in a missing directory, got %v, %v", reports, err)
+	}
+}
+
+func TestPruneReports(t *testing.T) {
+	ages := map[string]time.Duration{"now": 0, "1h": time.Hour, "2d": 2 * 24 * time.Hour, "10d": 10 * 24 * time.Hour}
+	tests := []struct {
+		name     string
+		maxFiles int
+		maxAge   time.Duration
+		wantKept []string
+	}{
+		{"unlimited", 0, 0, []string{"now", "1h", "2d", "10d"}},
+		{"max files", 2, 0, []string{"now", "1h"}},
+		{"max age", 0, 7 * 24 * time.Hour, []string{"now", "1h", "2d"}},
+		{"max age at the limit", 0, 2 * 24 * time.Hour, []string{"now", "1h", "2d"}},
+		{"both", 3, 24 * time.Hour, []string{"now", "1h"}},
+		{"max files tighter than max age", 1, 7 * 24 * time.Hour, []string{"now"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			labels := make(map[string]string)
+			for label, age := range ages {
+				labels[filepath.Base(writeSavedReport(t, dir, savedNow.Add(-age), "{}"))] = label
+			}
+
+			removed, err := PruneReports(dir, tt.maxFiles, tt.maxAge, savedNow)
+			if err != nil {
+				t.Fatalf("PruneReports failed: %v", err)
+			}
+			reports, err := ListReports(dir)
+			if err != nil {
+				t.Fatalf("ListReports failed: %v", err)
+			}
+			var kept []string
+			for _, name := range reportNames(reports) {
+				kept = append(kept, labels[name])
+			}
+			if !slices.Equal(kept, tt.wantKept) {
+				t.Errorf("expected to keep %v, kept %v", tt.wantKept, kept)
+			}
+			if len(removed)+len(kept) != len(ages) {
+				t.Errorf("expected %d reports removed, got %v", len(ages)-len(kept), removed)
+			}
+		})
+	}
+}
+
+func TestReadSavedTotals(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantRunID string
+		wantFiles int
+		wantSize  int64
+	}{
+		{
+			name:      "as Report writes it",
+			content:   `{"schema_version":1,"metadata":{"run_id":"run-1"},"report":{"timestamp":"2026-10-16T15:04:05Z","total_files":3,"total_size":300,"files":[{"Path":"/a"}],"errors":0}}`,
+			wantRunID: "run-1", wantFiles: 3, wantSize: 300,
+		},
+		{
+			name:      "unknown fields",
+			content:   `{"extra":{"nested":[1,2]},"metadata":{"run_id":"run-1","more":true},"report":{"types":{"x":1},"total_size":300,"total_files":3,"files":[]}}`,
+			wantRunID: "run-1", wantFiles: 3, wantSize: 300,
+		},
+		{
+			name:      "totals after the files",
+			content:   `{"metadata":{"run_id":"run-1"},"report":{"files":[],"total_files":3,"total_size":300}}`,
+			wantRunID: "run-1", wantFiles: -1, wantSize: -1,
+		},
+		{
+			name:      "metadata after the report",
+			content:   `{"report":{"total_files":3,"total_size":300,"files":[]},"metadata":{"run_id":"run-1"}}`,
+			wantRunID: "", wantFiles: 3, wantSize: 300,
+		},
+		{
+			name:      "no metadata",
+			content:   `{"report":{"total_files":3,"total_size":300,"files":[]}}`,
+			wantRunID: "", wantFiles: 3, wantSize: 300,
+		},
+		{
+			name:      "truncated",
+			content:   `{"metadata":{"run_id":"run-1"},"report":{"total_fi`,
+			wantRunID: "run-1", wantFiles: -1, wantSize: -1,
+		},
+		{
+			name:      "not JSON",
+			content:   "not a report",
+			wantRunID: "", wantFiles: -1, wantSize: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := SavedReport{
+				Path:       writeSavedReport(t, t.TempDir(), savedNow, tt.content),
+				TotalFiles: -1,
+				TotalSize:  -1,
+			}
+			readSavedTotals(&report)
+			if report.RunID != tt.wantRunID || report.TotalFiles != tt.wantFiles || report.TotalSize != tt.wantSize {
+				t.Errorf("expected %q, %d files, %d bytes, got %q, %d files, %d bytes",
+					tt.wantRunID, tt.wantFiles, tt.wantSize, report.RunID, report.TotalFiles, report.TotalSize)
+			}
+		})
+	}
+}
+
+func TestSaveReportListsTotals(t *testing.T) {
+	f := cacheFixture(t, 3)
+	result, err := cacheScanner(f).ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	cfg := &config.Config{Reports: config.ReportsConfig{Dir: t.TempDir(), MaxFiles: 2}}
+	for i := 0; i < 3; i++ {
+		if _, err := SaveReport(cfg, result, &Metadata{RunID: fmt.Sprintf("run-%d", i)}, savedNow); err != nil {
+			t.Fatalf("SaveReport failed: %v", err)
+		}
+	}
+
+	reports, err := ListReports(cfg.Reports.Dir)
+	if err != nil {
+		t.Fatalf("ListReports failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected reports.max_files to keep 2 reports, got %v", reportNames(reports))
+	}
+	for i, report := range reports {
+		wantRunID := fmt.Sprintf("run-%d", 2-i)
+		if report.RunID != wantRunID || report.TotalFiles != result.TotalCount || report.TotalSize != result.TotalSize {
+			t.Errorf("expected %s with %d files, %d bytes, got %+v", wantRunID, result.TotalCount, result.TotalSize, report)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package reporter
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// Saved reports are named for when they were made, e.g.
+// report-20261016-150405.json.gz
+const (
+	savedPrefix = "report-"
+	savedSuffix = ".json.gz"
+	savedLayout = "20060102-150405"
+)
+
+// SavedReport is a report kept by SaveReport
+type SavedReport struct {
+	Path       string
+	Created    time.Time
+	Size       int64 // Compressed
+	RunID      string
+	TotalFiles int   // -1 if the report couldn't be read
+	TotalSize  int64 // -1 if the report couldn't be read
+
+	seq int // 2, 3, ... for later reports made in the same second
+}
+
+// ReportsDir returns where saved reports are kept: reports.dir, or
+// $XDG_DATA_HOME/tidyup/reports if it isn't set
+func ReportsDir(cfg *config.Config) string {
+	if cfg.Reports.Dir != "" {
+		return cfg.Reports.Dir
+	}
+	return paths.File(paths.DataDir, "reports")
+}
+
+// SaveReport writes a gzipped JSON report of result to ReportsDir, named for
+// now, then removes the saved reports reports.max_files and
+// reports.max_age_days don't keep. It returns the path of the new report.
+func SaveReport(cfg *config.Config, result *scanner.ScanResult, meta *Metadata, now time.Time) (string, error) {
+	dir := ReportsDir(cfg)
+	if dir == "" {
+		return "", fmt.Errorf("no directory to save reports in (set reports.dir)")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+
+	path, err := writeSaved(dir, now, func(w io.Writer) error {
+		rptr := New(w, FormatJSON)
+		rptr.SetMetadata(meta)
+		return rptr.Report(result)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+	if _, err := PruneReports(dir, cfg.Reports.MaxFiles, time.Duration(cfg.Reports.MaxAgeDays)*24*time.Hour, now); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// writeSaved gzips what write writes to a new report in dir. It's written
+// beside the report and renamed into place once it's complete. Reports made
+// in the same second get -2, -3, ... after the time.
+func writeSaved(dir string, now time.Time, write func(w io.Writer) error) (string, error) {
+	tmp, err := os.CreateTemp(dir, ".report-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := gzip.NewWriter(tmp)
+	err = write(zw)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name := savedPrefix + now.Format(savedLayout)
+	path := filepath.Join(dir, name+savedSuffix)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, n, savedSuffix))
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ListReports returns the reports saved in dir, newest first. A missing dir
+// has none.
+func ListReports(dir string) ([]SavedReport, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved reports: %w", err)
+	}
+
+	var reports []SavedReport
+	for _, entry := range entries {
+		created, seq, ok := savedTime(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		report := SavedReport{
+			Path:       filepath.Join(dir, entry.Name()),
+			Created:    created,
+			Size:       info.Size(),
+			TotalFiles: -1,
+			TotalSize:  -1,
+			seq:        seq,
+		}
+		readSavedTotals(&report)
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].Created.Equal(reports[j].Created) {
+			return reports[i].Created.After(reports[j].Created)
+		}
+		return reports[i].seq > reports[j].seq
+	})
+	return reports, nil
+}
+
+// PruneReports removes the reports saved in dir past the newest maxFiles or
+// older than maxAge at now; 0 leaves either unlimited. It returns the paths
+// removed.
+func PruneReports(dir string, maxFiles int, maxAge time.Duration, now time.Time) ([]string, error) {
+	reports, err := ListReports(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var errs []error
+	for i, report := range reports {
+		if (maxFiles == 0 || i < maxFiles) && (maxAge == 0 || now.Sub(report.Created) <= maxAge) {
+			continue
+		}
+		if err := os.Remove(report.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, report.Path)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return removed, fmt.Errorf("failed to remove old reports: %w", err)
+	}
+	return removed, nil
+}
+
+// savedTime returns when the report named name was saved and its number
+// among those saved that second, if it's a saved report's name
+func savedTime(name string) (time.Time, int, bool) {
+	stamp, ok := strings.CutPrefix(name, savedPrefix)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	if stamp, ok = strings.CutSuffix(stamp, savedSuffix); !ok || len(stamp) < len(savedLayout) {
+		return time.Time{}, 0, false
+	}
+	created, err := time.ParseInLocation(savedLayout, stamp[:len(savedLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	seq := 1
+	if rest := stamp[len(savedLayout):]; rest != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+		if err != nil || !strings.HasPrefix(rest, "-") {
+			return time.Time{}, 0, false
+		}
+		seq = n
+	}
+	return created, seq, true
+}
+
+// readSavedTotals fills in the run ID and totals of report from its start,
+// where Report writes them, without reading its files
+func readSavedTotals(report *SavedReport) {
+	f, err := os.Open(report.Path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(zr)
+	if !expectDelim(dec, '{') {
+		return
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		key, ok := token.(string)
+		if err != nil || !ok {
+			return
+		}
+		switch key {
+		case "metadata":
+			var meta Metadata
+			if dec.Decode(&meta) != nil {
+				return
+			}
+			report.RunID = meta.RunID
+		case "report":
+			readReportTotals(dec, report)
+			return
+		default:
+			var skip json.RawMessage
+			if dec.Decode(&skip) != nil {
+				return
+			}
+		}
+	}
+}
+
+// readReportTotals reads the totals of the report object dec is at, up to
+// its files
+func readReportTotals(dec *json.Decoder, report *SavedReport) {
+	if !expectDelim(dec, '{') {
+		return
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		key, ok := token.(string)
+		if err != nil || !ok {
+			return
+		}
+		switch key {
+		case "total_files":
+			dec.Decode(&report.TotalFiles)
+		case "total_size":
+			dec.Decode(&report.TotalSize)
+		case "files":
+			return
+		default:
+			var skip json.RawMessage
+			if dec.Decode(&skip) != nil {
+				return
+			}
+		}
+	}
+}
+
+// expectDelim reads the next token from dec, reporting whether it's delim
+func expectDelim(dec *json.Decoder, delim json.Delim) bool {
+	token, err := dec.Token()
+	return err == nil && token == delim
+}
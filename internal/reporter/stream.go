@@ -0,0 +1,176 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// FormatCSV lists one file per row, with a header row
+const FormatCSV OutputFormat = "csv"
+
+// csvHeader names the columns of CSV reports
+var csvHeader = []string{"path", "size", "files", "category", "risk", "access", "owner", "type", "modified", "reason"}
+
+// csvRow is file's row in a CSV report
+func csvRow(file scanner.FileInfo) []string {
+	return []string{
+		file.Path,
+		strconv.FormatInt(file.Size, 10),
+		strconv.Itoa(file.Files),
+		file.Category,
+		file.Risk,
+		file.Access,
+		file.Owner,
+		file.Type,
+		file.ModTime.Format(time.RFC3339),
+		file.Reason,
+	}
+}
+
+// reportCSV generates a CSV report, reading spilled results back a batch at
+// a time
+func (r *Reporter) reportCSV(result *scanner.ScanResult) error {
+	w := csv.NewWriter(r.writer)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	err := result.Each(func(file scanner.FileInfo) error {
+		return w.Write(csvRow(file))
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ReportStream writes a JSON or CSV report of the scan behind stream as its
+// batches arrive, so only one batch is held at a time however many files are
+// found. The scan's result, with its totals, is returned once it's done.
+//
+// A streamed JSON report has the same fields as one from Report, but the
+// totals follow the files, as they're only known at the end, and so does the
+// metadata, whose scan duration is measured here if it isn't set. Files found
+// more than once, through a symlink say, aren't merged as they are by
+// ScanAll.
+func (r *Reporter) ReportStream(stream *scanner.ScanStream) (*scanner.ScanResult, error) {
+	if r.format != FormatJSON && r.format != FormatCSV {
+		// Let the scan finish rather than leave it waiting on a batch
+		for range stream.Batches {
+		}
+		stream.Wait()
+		return nil, fmt.Errorf("%s reports can't be streamed (use json or csv)", r.format)
+	}
+
+	// Writing stops at the first error, but the batches are still taken so
+	// the scan can finish
+	var err error
+	start := time.Now()
+	w := bufio.NewWriter(r.writer)
+	var write func(file scanner.FileInfo) error
+	var finish func(result *scanner.ScanResult) error
+	if r.format == FormatCSV {
+		cw := csv.NewWriter(w)
+		err = cw.Write(csvHeader)
+		write = func(file scanner.FileInfo) error {
+			return cw.Write(csvRow(file))
+		}
+		finish = func(*scanner.ScanResult) error {
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		write, finish = r.streamJSON(w, start)
+	}
+
+	for batch := range stream.Batches {
+		for _, file := range batch {
+			if err == nil {
+				err = write(file)
+			}
+		}
+	}
+	result, scanErr := stream.Wait()
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	if err == nil {
+		err = finish(result)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// streamJSON starts a streamed JSON report on w, in the envelope of Report's,
+// and returns the functions that add a file to it and close it with the
+// totals and metadata. Errors writing to w are left for its Flush to report.
+func (r *Reporter) streamJSON(w *bufio.Writer, start time.Time) (write func(scanner.FileInfo) error, finish func(*scanner.ScanResult) error) {
+	// Indented as by Report's encoder
+	fmt.Fprintf(w, "{\n  \"schema_version\": %d,\n  \"report\": {\n    \"timestamp\": %q,\n    \"files\": [",
+		SchemaVersion, start.Format(time.RFC3339))
+
+	types := make(map[string]typeTotal)
+	files := 0
+	write = func(file scanner.FileInfo) error {
+		if file.Type != "" {
+			total := types[file.Type]
+			total.Count++
+			total.Size += file.Size
+			types[file.Type] = total
+		}
+		data, err := json.MarshalIndent(file, "      ", "  ")
+		if err != nil {
+			return err
+		}
+		if files > 0 {
+			w.WriteByte(',')
+		}
+		files++
+		fmt.Fprintf(w, "\n      %s", data)
+		return nil
+	}
+
+	finish = func(result *scanner.ScanResult) error {
+		if files > 0 {
+			w.WriteString("\n    ")
+		}
+		formatted, err := json.Marshal(utils.FormatBytes(result.TotalSize))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "],\n    \"total_files\": %d,\n    \"total_size\": %d,\n    \"total_size_formatted\": %s,\n",
+			result.TotalCount, result.TotalSize, formatted)
+		if len(types) > 0 {
+			data, err := json.MarshalIndent(types, "    ", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "    \"types\": %s,\n", data)
+		}
+		fmt.Fprintf(w, "    \"errors\": %d\n  },\n", len(result.Errors))
+
+		meta := *r.wrap(nil).Metadata
+		if meta.ScanDurationMS == 0 {
+			meta.ScanDurationMS = time.Since(start).Milliseconds()
+		}
+		data, err := json.MarshalIndent(meta, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  \"metadata\": %s\n}\n", data)
+		return nil
+	}
+	return write, finish
+}
@@ -0,0 +1,27 @@
+// Package runid identifies a single scan or cleanup across everything it
+// produces: progress events, the deletion manifest, the quarantine, reports
+// and daemon logs.
+package runid
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// New returns a random (version 4) UUID
+func New() string {
+	var b [16]byte
+	rand.Read(b[:]) // Never fails
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Short returns the first 8 characters of id, enough to tell runs apart in
+// logs and messages
+func Short(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,30 @@
+package runid
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := New()
+		if !uuid.MatchString(id) {
+			t.Fatalf("%q is not a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestShort(t *testing.T) {
+	if got := Short("7f3a9c12-0000-4000-8000-000000000000"); got != "7f3a9c12" {
+		t.Errorf("unexpected short ID %q", got)
+	}
+	if got := Short("abc"); got != "abc" {
+		t.Errorf("short IDs should be kept, got %q", got)
+	}
+}
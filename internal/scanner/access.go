@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/security"
+)
+
+// What deleting a result takes, as a dry run would find it
+const (
+	AccessDeletable    = "deletable"    // The current user can delete it
+	AccessNeedsSudo    = "sudo"         // Deleting it will ask for a password
+	AccessInaccessible = "inaccessible" // It won't be deleted, with or without sudo
+)
+
+// AccessLevels lists the access classes, from the one needing nothing
+var AccessLevels = []string{AccessDeletable, AccessNeedsSudo, AccessInaccessible}
+
+// assessAccess classifies what deleting file takes, the way the cleaner will
+// find it: special and protected files are never deleted, and the rest need
+// sudo unless the user may remove entries from the parent directory, owning
+// the file or the directory if it's sticky. Only the top of a directory
+// result is checked. info and err are file's Lstat.
+func (hs *HyperScanner) assessAccess(file FileInfo, info fs.FileInfo, err error) string {
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return AccessNeedsSudo
+		}
+		return ""
+	}
+	if info.Mode()&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeSocket|fs.ModeNamedPipe|fs.ModeSetuid|fs.ModeSetgid) != 0 {
+		return AccessInaccessible
+	}
+	if security.CheckProtectedLocation(file.Path) != nil {
+		return AccessInaccessible
+	}
+	if !hs.native() || os.Geteuid() == 0 {
+		return AccessDeletable
+	}
+
+	parent := filepath.Dir(file.Path)
+	if !hs.canWrite(parent) {
+		return AccessNeedsSudo
+	}
+	if dirInfo, err := os.Stat(parent); err == nil && dirInfo.Mode()&fs.ModeSticky != 0 {
+		uid := uint32(os.Geteuid())
+		fileUID, _, ok := platform.FileOwner(info)
+		dirUID, _, _ := platform.FileOwner(dirInfo)
+		if ok && fileUID != uid && dirUID != uid {
+			return AccessNeedsSudo
+		}
+	}
+	return AccessDeletable
+}
+
+// canWrite is platform.CanWrite, remembered for the scan since results
+// share a few parent directories
+func (hs *HyperScanner) canWrite(dir string) bool {
+	if writable, ok := hs.writableDirs.Load(dir); ok {
+		return writable.(bool)
+	}
+	writable := platform.CanWrite(dir)
+	hs.writableDirs.Store(dir, writable)
+	return writable
+}
+
+// AccessTotals returns the count and size of the results in each access
+// class. Results whose access isn't known are left out.
+func (r *ScanResult) AccessTotals() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		if file.Access == "" {
+			return nil
+		}
+		total := totals[file.Access]
+		total.Count++
+		total.Size += file.Size
+		totals[file.Access] = total
+		return nil
+	})
+	return totals, err
+}
@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// AnalyzeCategory is the category of files found by AnalyzeDir
+const AnalyzeCategory = "analyze"
+
+// AnalyzeDir lists every file below root, whatever category it would be in,
+// for exploring where disk space goes. Symlinks are listed but not followed,
+// and directories that can't be read are reported in the result's errors.
+func (hs *HyperScanner) AnalyzeDir(root string) (*ScanResult, error) {
+	root, err := filepath.Abs(expandPath(root, hs.homeDir()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	info, err := hs.fs.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	hs.resetResults(10000)
+	hs.startProgress([]string{AnalyzeCategory})
+
+	var errs []error
+	vfs.WalkDir(hs.fs, root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		hs.appendResult(FileInfo{
+			Path:     path,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Category: AnalyzeCategory,
+			Reason:   "Chosen in analyzer",
+		}, 1)
+		return nil
+	})
+	hs.finishCategories(AnalyzeCategory)
+
+	return hs.results.result(AnalyzeCategory, errs), nil
+}
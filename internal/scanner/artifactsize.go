@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+)
+
+// artifactBatchSize is how many artifact directories one du process sizes
+const artifactBatchSize = 32
+
+// artifact is a dev artifact directory waiting to be sized
+type artifact struct {
+	path     string
+	category string
+	info     os.FileInfo
+}
+
+// artifactSizeWorkers returns how many du processes may size dev artifacts
+// at once: dev.size_workers if it's set, or else what suits the storage.
+// Each one walks a whole tree, so far fewer run than directories are read.
+func artifactSizeWorkers(cfg *config.Config, storage platform.StorageKind) int {
+	if cfg.Dev.SizeWorkers > 0 {
+		return cfg.Dev.SizeWorkers
+	}
+	return sizeWorkersFor(storage, runtime.NumCPU())
+}
+
+// sizeWorkersFor returns the du parallelism that suits a kind of storage
+func sizeWorkersFor(storage platform.StorageKind, cpus int) int {
+	if storage == platform.StorageHDD {
+		return 2
+	}
+	return min(max(cpus, 2), 8)
+}
+
+// addArtifactResult sizes one dev artifact directory and adds it
+func (hs *HyperScanner) addArtifactResult(path, category string) {
+	hs.addArtifactResults([]artifact{{path: path, category: category}})
+}
+
+// addArtifactResults adds dev artifact directories. Ones unchanged since
+// they were cached are added straight away; the rest are sized with du in
+// batches, with at most dev.size_workers du processes running across the
+// whole scan. The file count is estimated from the size, unless
+// dev.verify_sizes asks for both to be counted exactly.
+func (hs *HyperScanner) addArtifactResults(artifacts []artifact) {
+	exact := hs.config.Dev.VerifySizes
+
+	var pending []artifact
+	for _, a := range artifacts {
+		if hs.cancelled() {
+			return
+		}
+		info, err := hs.fs.Stat(a.path)
+		if err != nil {
+			continue // Skip non-existent paths
+		}
+		a.info = info
+		if !hs.addCachedArtifact(a, exact) {
+			pending = append(pending, a)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(pending); start += artifactBatchSize {
+		batch := pending[start:min(start+artifactBatchSize, len(pending))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hs.sizeSem <- struct{}{}
+			defer func() { <-hs.sizeSem }()
+			if !hs.cancelled() {
+				hs.sizeArtifacts(batch, exact)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// addCachedArtifact adds an artifact from the scan cache, reporting false
+// if it isn't cached or has changed since
+func (hs *HyperScanner) addCachedArtifact(a artifact, exact bool) bool {
+	cacheKey := fmt.Sprintf("artifact:%s", a.path)
+
+	hs.cacheMu.RLock()
+	cached, hasCached := hs.cache.DirResults[cacheKey]
+	cachedMtime, hasMtime := hs.cache.DirMtimes[cacheKey]
+	hs.cacheMu.RUnlock()
+
+	// An estimate can't stand in for an exact count
+	if !hasCached || (exact && !cached.Exact) || !hasMtime || a.info.ModTime().After(cachedMtime) {
+		return false
+	}
+	name, project := artifactNames(a.path)
+	hs.appendResult(FileInfo{
+		Path:     cached.Path,
+		Size:     cached.TotalSize,
+		Category: a.category,
+		Reason:   artifactReason(name, project, a.info.ModTime(), cached.FileCount, cached.Exact) + " (cached)",
+		Files:    cached.FileCount,
+	}, 1)
+	return true
+}
+
+// sizeArtifacts sizes a batch of artifacts, caches the sizes and adds them
+func (hs *HyperScanner) sizeArtifacts(batch []artifact, exact bool) {
+	hs.reportProgress(progress.PhaseSizing, batch[0].category, batch[0].path)
+
+	sizes := make(map[string]int64, len(batch))
+	counts := make(map[string]int, len(batch))
+	if exact || !hs.native() {
+		for _, a := range batch {
+			sizes[a.path], counts[a.path] = hs.measureArtifact(a.path)
+		}
+	} else {
+		sizes = duSizes(batch)
+		for path, size := range sizes {
+			// Estimate file count from size (avg 10KB per file)
+			counts[path] = max(int(size/(10*1024)), 1)
+		}
+	}
+
+	for _, a := range batch {
+		size, fileCount := sizes[a.path], counts[a.path]
+		if !exact && hs.native() {
+			fileCount = max(fileCount, 1)
+		}
+
+		// Update cache (write lock)
+		if info, err := hs.fs.Stat(a.path); err == nil {
+			hs.cacheMu.Lock()
+			hs.cache.DirMtimes["artifact:"+a.path] = info.ModTime()
+			hs.cache.DirResults["artifact:"+a.path] = &CachedDirInfo{
+				Path:      a.path,
+				TotalSize: size,
+				FileCount: fileCount,
+				Exact:     exact,
+				Category:  a.category,
+				ScannedAt: time.Now(),
+			}
+			hs.cacheMu.Unlock()
+		}
+
+		name, project := artifactNames(a.path)
+		hs.appendResult(FileInfo{
+			Path:     a.path,
+			Size:     size,
+			Category: a.category,
+			Reason:   artifactReason(name, project, a.info.ModTime(), fileCount, exact),
+			Files:    fileCount,
+		}, 1)
+	}
+}
+
+// duSizes sizes the directories of a batch with one du process. Directories
+// du couldn't read are left out.
+func duSizes(batch []artifact) map[string]int64 {
+	args := []string{"-sk", "--"}
+	for _, a := range batch {
+		args = append(args, a.path)
+	}
+	cmd := exec.Command("du", args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = nil
+
+	// du fails if any directory can't be read, but still sizes the others
+	cmd.Run()
+	return parseDuSizes(out.String())
+}
+
+// parseDuSizes reads du -sk output, "<KB>\t<path>" per line, into bytes by
+// path
+func parseDuSizes(out string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(out, "\n") {
+		kb, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(kb), 10, 64); err == nil {
+			sizes[path] = n * 1024 // du -sk returns KB
+		}
+	}
+	return sizes
+}
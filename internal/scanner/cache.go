@@ -0,0 +1,212 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// cacheMagic starts every scan cache file, followed by the SHA-256 of the
+// gob-encoded cache and then the cache itself
+const cacheMagic = "TIDYSC01"
+
+// errCacheFormat is returned for a cache written by an older version, which
+// is replaced without a warning
+var errCacheFormat = errors.New("unknown scan cache format")
+
+// cacheFile returns where scans keep their cache between runs
+func cacheFile() string {
+	return paths.File(paths.CacheDir, "scan_cache.gob")
+}
+
+// CheckCache reads and verifies the scan cache, returning its path and when it
+// was written. A cache that doesn't exist yet fails with an error for which
+// os.IsNotExist is true.
+func CheckCache() (path string, lastScan time.Time, err error) {
+	path = cacheFile()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return path, time.Time{}, err
+	}
+	cache, err := decodeCache(data)
+	if err != nil {
+		return path, time.Time{}, err
+	}
+	return path, cache.LastScan, nil
+}
+
+// loadCache loads the scan cache from disk. A corrupt cache is deleted, so the
+// next scan starts afresh, and reported with the next result.
+func (hs *HyperScanner) loadCache() {
+	hs.cache = newScanCache()
+	if hs.cachePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(hs.cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			hs.cacheErrs = append(hs.cacheErrs, fmt.Errorf("failed to read scan cache: %w", err))
+		}
+		return
+	}
+
+	cache, err := decodeCache(data)
+	if err != nil {
+		hs.discardCache(err)
+		return
+	}
+	// Only use cache if it's recent (within 1 hour). Timings are still good
+	// for an estimate after that.
+	if time.Since(cache.LastScan) < time.Hour {
+		hs.cache = cache
+	} else {
+		hs.cache.CategoryDurations = cache.CategoryDurations
+	}
+}
+
+// discardCache deletes a cache that failed to decode with err. Another run may
+// have replaced it meanwhile, so it's checked again under the lock.
+func (hs *HyperScanner) discardCache(err error) {
+	if unlock, lockErr := platform.LockFile(hs.cachePath + ".lock"); lockErr == nil {
+		defer unlock()
+	}
+	if data, readErr := os.ReadFile(hs.cachePath); readErr == nil {
+		if _, err = decodeCache(data); err == nil {
+			return
+		}
+	}
+
+	if removeErr := os.Remove(hs.cachePath); removeErr != nil && !os.IsNotExist(removeErr) {
+		hs.cacheErrs = append(hs.cacheErrs, fmt.Errorf("failed to remove corrupt scan cache: %w", removeErr))
+		return
+	}
+	if !errors.Is(err, errCacheFormat) {
+		hs.cacheErrs = append(hs.cacheErrs, fmt.Errorf("discarded corrupt scan cache: %w", err))
+	}
+}
+
+// saveCache saves the scan cache to disk. Concurrent runs take turns through a
+// lock file, and the cache is written to a temporary file that replaces the
+// old one in a single rename, so readers never see a partial cache.
+func (hs *HyperScanner) saveCache() error {
+	if hs.cachePath == "" {
+		return nil
+	}
+	dir := filepath.Dir(hs.cachePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	unlock, err := platform.LockFile(hs.cachePath + ".lock")
+	if err != nil && !errors.Is(err, platform.ErrUnsupportedPlatform) {
+		return err
+	}
+	if unlock != nil {
+		defer unlock()
+	}
+
+	hs.cacheMu.Lock()
+	hs.cache.LastScan = time.Now()
+	data, err := encodeCache(hs.cache)
+	hs.cacheMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(hs.cachePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	defer os.Remove(f.Name()) // Fails harmlessly once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	if err := os.Rename(f.Name(), hs.cachePath); err != nil {
+		return fmt.Errorf("failed to replace scan cache: %w", err)
+	}
+	return nil
+}
+
+// takeCacheErrors returns the cache problems not yet reported and forgets them
+func (hs *HyperScanner) takeCacheErrors() []error {
+	errs := hs.cacheErrs
+	hs.cacheErrs = nil
+	return errs
+}
+
+// newScanCache returns an empty cache
+func newScanCache() *ScanCache {
+	return &ScanCache{
+		Version:           1,
+		DirMtimes:         make(map[string]time.Time),
+		DirResults:        make(map[string]*CachedDirInfo),
+		ArtifactDirs:      make(map[string][]string),
+		CategoryDurations: make(map[string]time.Duration),
+	}
+}
+
+// encodeCache serializes cache with its header and checksum
+func encodeCache(cache *ScanCache) ([]byte, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(cache); err != nil {
+		return nil, fmt.Errorf("failed to encode scan cache: %w", err)
+	}
+	sum := sha256.Sum256(payload.Bytes())
+
+	data := make([]byte, 0, len(cacheMagic)+len(sum)+payload.Len())
+	data = append(data, cacheMagic...)
+	data = append(data, sum[:]...)
+	return append(data, payload.Bytes()...), nil
+}
+
+// decodeCache parses a cache written by encodeCache, checking it's intact
+func decodeCache(data []byte) (*ScanCache, error) {
+	if !bytes.HasPrefix(data, []byte(cacheMagic)) {
+		return nil, errCacheFormat
+	}
+	data = data[len(cacheMagic):]
+	if len(data) < sha256.Size {
+		return nil, errors.New("scan cache is truncated")
+	}
+	payload := data[sha256.Size:]
+	if sum := sha256.Sum256(payload); !bytes.Equal(sum[:], data[:sha256.Size]) {
+		return nil, errors.New("scan cache checksum mismatch")
+	}
+
+	var cache ScanCache
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&cache); err != nil {
+		return nil, fmt.Errorf("failed to decode scan cache: %w", err)
+	}
+	// Ensure maps are initialized
+	if cache.DirMtimes == nil {
+		cache.DirMtimes = make(map[string]time.Time)
+	}
+	if cache.DirResults == nil {
+		cache.DirResults = make(map[string]*CachedDirInfo)
+	}
+	if cache.ArtifactDirs == nil {
+		cache.ArtifactDirs = make(map[string][]string)
+	}
+	if cache.CategoryDurations == nil {
+		cache.CategoryDurations = make(map[string]time.Duration)
+	}
+	return &cache, nil
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// appCaches maps the names cache.exclude_apps knows applications by, in
+// lower case, to their directories in the cache directories
+// (~/Library/Caches, ~/.cache, ...). Any other name is taken as a
+// directory of its own there, so bundle IDs and vendor folders work too.
+var appCaches = map[string][]string{
+	"safari":             {"com.apple.Safari", "com.apple.WebKit.Networking", "com.apple.WebKit.WebContent"},
+	"chrome":             {"Google/Chrome", "google-chrome"},
+	"google chrome":      {"Google/Chrome", "google-chrome"},
+	"chromium":           {"Chromium", "chromium"},
+	"firefox":            {"Firefox", "mozilla/firefox"},
+	"brave":              {"BraveSoftware"},
+	"edge":               {"Microsoft Edge", "microsoft-edge"},
+	"microsoft edge":     {"Microsoft Edge", "microsoft-edge"},
+	"vscode":             {"com.microsoft.VSCode", "com.microsoft.VSCode.ShipIt", "vscode-cpptools"},
+	"visual studio code": {"com.microsoft.VSCode", "com.microsoft.VSCode.ShipIt", "vscode-cpptools"},
+	"jetbrains":          {"JetBrains"},
+	"xcode":              {"com.apple.dt.Xcode"},
+	"slack":              {"com.tinyspeck.slackmacgap"},
+	"discord":            {"com.hnc.Discord"},
+	"spotify":            {"com.spotify.client", "spotify"},
+	"pip":                {"pip"},
+	"yarn":               {"Yarn", "yarn"},
+	"homebrew":           {"Homebrew"},
+}
+
+// excludedAppCaches returns the directories of the applications in
+// cache.exclude_apps found in the cache directories dirs. Names are matched
+// case-insensitively.
+func (hs *HyperScanner) excludedAppCaches(dirs []string) []string {
+	var excluded []string
+	seen := make(map[string]bool)
+	for _, app := range hs.config.Cache.ExcludeApps {
+		subdirs := append([]string{app}, appCaches[strings.ToLower(app)]...)
+		for _, dir := range dirs {
+			for _, subdir := range subdirs {
+				if path, ok := hs.findFold(dir, subdir); ok && !seen[path] {
+					seen[path] = true
+					excluded = append(excluded, path)
+				}
+			}
+		}
+	}
+	return excluded
+}
+
+// findFold finds the path rel below dir, matching each element of it
+// case-insensitively, and returns it as it's spelled on disk
+func (hs *HyperScanner) findFold(dir, rel string) (string, bool) {
+	path := dir
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		entries, err := hs.fs.ReadDir(path)
+		if err != nil {
+			return "", false
+		}
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+				path = filepath.Join(path, entry.Name())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return path, true
+}
+
+// containsAny reports whether any of paths is below dir
+func containsAny(dir string, paths []string) bool {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for _, path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
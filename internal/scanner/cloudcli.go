@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CloudCLICategory is the category for the caches and logs of cloud CLIs
+const CloudCLICategory = "cloud_cli"
+
+// scanCloudCLICategory finds the caches and logs of the AWS, Google Cloud and
+// Azure CLIs enabled in cloud_cli. Credentials and settings are kept: only
+// cached role credentials the AWS CLI fetches again, and logs, completion
+// caches and telemetry the others rebuild or don't need.
+func (hs *HyperScanner) scanCloudCLICategory() {
+	home := hs.homeDir()
+	cfg := hs.config.CloudCLI
+	var dirs []string
+
+	if cfg.AWS {
+		dirs = append(dirs, filepath.Join(home, ".aws/cli/cache"))
+	}
+	if cfg.GCloud {
+		gcloud := os.Getenv("CLOUDSDK_CONFIG")
+		if gcloud == "" {
+			gcloud = filepath.Join(home, ".config/gcloud")
+		}
+		dirs = append(dirs,
+			filepath.Join(gcloud, "logs"),
+			filepath.Join(gcloud, "cache"),
+			filepath.Join(gcloud, ".cache"))
+	}
+	if cfg.Azure {
+		azure := os.Getenv("AZURE_CONFIG_DIR")
+		if azure == "" {
+			azure = filepath.Join(home, ".azure")
+		}
+		dirs = append(dirs,
+			filepath.Join(azure, "logs"),
+			filepath.Join(azure, "commands"),
+			filepath.Join(azure, "telemetry"))
+	}
+
+	hs.scanDirsWithCache(dirs, CloudCLICategory)
+}
@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// collector gathers the results of a scan. Categories are scanned in
+// parallel, so it's safe for concurrent use. Results leave it only as a
+// ScanResult, or in batches handed to the stream as they're found.
+type collector struct {
+	mu         sync.Mutex
+	files      []FileInfo
+	totals     map[string]*CategoryTotal
+	duplicates []DuplicateGroup
+
+	// Running totals, read by progress reports without waiting on mu
+	filesFound atomic.Int64
+	totalSize  atomic.Int64
+
+	// Streaming of results as they're found, guarded by mu. streamMu hands
+	// the stream one batch at a time.
+	streamMu    sync.Mutex
+	stream      func(batch []FileInfo) error
+	streamBatch int
+	streamed    int   // Results already handed to stream
+	streamErr   error // Why streaming stopped early
+}
+
+// newCollector returns an empty collector with room for capacity results
+func newCollector(capacity int) *collector {
+	c := &collector{}
+	c.reset(capacity)
+	return c
+}
+
+// reset clears the results before a new scan. The stream is kept.
+func (c *collector) reset(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files = make([]FileInfo, 0, capacity)
+	c.totals = make(map[string]*CategoryTotal)
+	c.duplicates = nil
+	c.streamed = 0
+	c.streamErr = nil
+	c.filesFound.Store(0)
+	c.totalSize.Store(0)
+}
+
+// add records a result. filesFound is how many files it stands for.
+func (c *collector) add(file FileInfo, filesFound int64) {
+	c.mu.Lock()
+	c.files = append(c.files, file)
+	total, ok := c.totals[file.Category]
+	if !ok {
+		total = &CategoryTotal{}
+		c.totals[file.Category] = total
+	}
+	total.Count++
+	total.Size += file.Size
+	batch := c.takeBatch()
+	c.mu.Unlock()
+
+	c.filesFound.Add(filesFound)
+	c.totalSize.Add(file.Size)
+	if batch != nil {
+		c.flush(batch)
+	}
+}
+
+// takeBatch takes the collected results once there's a batch of them for
+// the stream. The caller must hold mu.
+func (c *collector) takeBatch() []FileInfo {
+	if c.stream == nil || len(c.files) < c.streamBatch {
+		return nil
+	}
+	batch := c.files
+	c.files = make([]FileInfo, 0, c.streamBatch)
+	return batch
+}
+
+// flush hands a batch to the stream. mu isn't held meanwhile, so a slow
+// reader of the stream doesn't hold up totals and progress reports.
+func (c *collector) flush(batch []FileInfo) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+
+	c.mu.Lock()
+	stream := c.stream
+	c.mu.Unlock()
+
+	var err error
+	if stream != nil {
+		err = stream(batch)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stream != nil && err == nil {
+		c.streamed += len(batch)
+		return
+	}
+	if err != nil {
+		c.streamErr = err
+		c.stream = nil
+	}
+	// Keep the rest in memory rather than lose them
+	c.files = append(batch, c.files...)
+}
+
+// setStream hands results to fn in batches of batchSize from now on; a nil
+// fn keeps them instead
+func (c *collector) setStream(batchSize int, fn func(batch []FileInfo) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stream = fn
+	c.streamBatch = batchSize
+}
+
+// setDuplicates records the groups of duplicate files found
+func (c *collector) setDuplicates(groups []DuplicateGroup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.duplicates = groups
+}
+
+// categoryTotal returns the running count and size of a category
+func (c *collector) categoryTotal(category string) CategoryTotal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t, ok := c.totals[category]; ok {
+		return *t
+	}
+	return CategoryTotal{}
+}
+
+// categoryTotals returns the running count and size of each category
+func (c *collector) categoryTotals() map[string]CategoryTotal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	totals := make(map[string]CategoryTotal, len(c.totals))
+	for category, t := range c.totals {
+		totals[category] = *t
+	}
+	return totals
+}
+
+// result returns what was collected as the result of a scan of category
+// ("" for several). Results already streamed are counted but not included.
+func (c *collector) result(category string, errs []error) *ScanResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streamErr != nil {
+		errs = append(errs, c.streamErr)
+	}
+	return &ScanResult{
+		Files:      c.files,
+		TotalSize:  c.totalSize.Load(),
+		TotalCount: c.streamed + len(c.files),
+		Category:   category,
+		Errors:     errs,
+		Duplicates: c.duplicates,
+	}
+}
+
+// paths returns the paths of the results collected so far, other than the
+// ones already streamed
+func (c *collector) paths() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	paths := make([]string, len(c.files))
+	for i, file := range c.files {
+		paths[i] = file.Path
+	}
+	return paths
+}
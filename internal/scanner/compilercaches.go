@@ -0,0 +1,234 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// CompilerCachesCategory is the category for compiler and build cache
+// entries past their age or size limit
+const CompilerCachesCategory = "compiler_caches"
+
+// compilerCache is a cache directory and what it belongs to
+type compilerCache struct {
+	tool string
+	dir  string
+}
+
+// cacheEntry is a file in a compiler cache
+type cacheEntry struct {
+	path string
+	size int64
+	used time.Time // The caches touch an entry when it's used
+}
+
+// compilerCacheKeep are files the caches keep their settings and counters
+// in, which are never pruned
+var compilerCacheKeep = map[string]bool{
+	"ccache.conf":   true,
+	"stats":         true,
+	"CACHEDIR.TAG":  true,
+	"sccache.conf":  true,
+	"config.toml":   true,
+	"stats.json":    true,
+	".gitignore":    true,
+	"README.txt":    true,
+	"LOCK":          true,
+	"lock":          true,
+	"cache.version": true,
+}
+
+// scanCompilerCachesCategory prunes the ccache, sccache and Bazel disk caches
+// enabled in compiler_caches_config, and the directories in its scan_paths.
+// Entries unused for max_age_days are reported, then the least recently
+// used until each cache fits in max_size.
+func (hs *HyperScanner) scanCompilerCachesCategory() {
+	cfg := hs.config.CompilerCaches
+	var cutoff time.Time
+	if cfg.MaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -cfg.MaxAgeDays)
+	}
+	maxSize := hs.parseSize(cfg.MaxSize)
+	if cutoff.IsZero() && maxSize <= 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, cache := range hs.compilerCaches() {
+		dir := filepath.Clean(cache.dir)
+		if seen[dir] || hs.onWindowsDrive(dir) {
+			continue
+		}
+		seen[dir] = true
+		if info, err := hs.fs.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		stop := hs.timeDir(dir, CompilerCachesCategory)
+		entries := hs.cacheEntries(dir)
+		old, over := pruneCache(entries, cutoff, maxSize)
+		for _, entry := range old {
+			hs.addCacheEntry(entry, fmt.Sprintf("%s entry unused for %s (compiler_caches_config.max_age_days: %d)",
+				cache.tool, since(entry.used), cfg.MaxAgeDays))
+		}
+		for _, entry := range over {
+			hs.addCacheEntry(entry, fmt.Sprintf("%s entry among the least recently used, past compiler_caches_config.max_size (%s)",
+				cache.tool, cfg.MaxSize))
+		}
+		stop()
+	}
+}
+
+// addCacheEntry adds a compiler cache entry as a result
+func (hs *HyperScanner) addCacheEntry(entry cacheEntry, reason string) {
+	hs.appendResult(FileInfo{
+		Path:     entry.path,
+		Size:     entry.size,
+		ModTime:  entry.used,
+		Category: CompilerCachesCategory,
+		Reason:   reason,
+	}, 1)
+}
+
+// compilerCaches returns the cache directories to prune, which may not exist
+func (hs *HyperScanner) compilerCaches() []compilerCache {
+	home := hs.homeDir()
+	cfg := hs.config.CompilerCaches
+	var caches []compilerCache
+
+	if cfg.CCache {
+		if dir := os.Getenv("CCACHE_DIR"); dir != "" {
+			caches = append(caches, compilerCache{"ccache", dir})
+		} else {
+			// ccache uses ~/.ccache if it's there, and its cache directory otherwise
+			caches = append(caches,
+				compilerCache{"ccache", filepath.Join(home, ".ccache")},
+				compilerCache{"ccache", filepath.Join(hs.userCacheDir(), "ccache")})
+		}
+	}
+	if cfg.SCCache {
+		dir := os.Getenv("SCCACHE_DIR")
+		if dir == "" {
+			name := "sccache"
+			if hs.platformInfo.OS == platform.MacOS {
+				name = "Mozilla.sccache"
+			}
+			dir = filepath.Join(hs.userCacheDir(), name)
+		}
+		caches = append(caches, compilerCache{"sccache", dir})
+	}
+	if cfg.Bazel {
+		for _, rc := range []string{filepath.Join(home, ".bazelrc"), "/etc/bazel.bazelrc"} {
+			data, err := hs.readFile(rc)
+			if err != nil {
+				continue
+			}
+			for _, dir := range bazelDiskCaches(data) {
+				caches = append(caches, compilerCache{"Bazel disk cache", expandPath(dir, home)})
+			}
+		}
+	}
+	for _, dir := range cfg.ScanPaths {
+		caches = append(caches, compilerCache{"Build cache", expandPath(dir, home)})
+	}
+	return caches
+}
+
+// userCacheDir returns the user's cache directory: ~/Library/Caches on
+// macOS, and $XDG_CACHE_HOME or ~/.cache elsewhere
+func (hs *HyperScanner) userCacheDir() string {
+	home := hs.homeDir()
+	if hs.platformInfo.OS == platform.MacOS {
+		return filepath.Join(home, "Library", "Caches")
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, ".cache")
+}
+
+// bazelDiskCaches returns the --disk_cache directories set in a bazelrc,
+// e.g. "build --disk_cache=~/.cache/bazel-disk". Ones relative to a
+// workspace can't be found from here and are left out.
+func bazelDiskCaches(bazelrc []byte) []string {
+	var dirs []string
+	scanner := bufio.NewScanner(bytes.NewReader(bazelrc))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for i, field := range fields {
+			var dir string
+			if value, ok := strings.CutPrefix(field, "--disk_cache="); ok {
+				dir = value
+			} else if field == "--disk_cache" && i+1 < len(fields) {
+				dir = fields[i+1]
+			}
+			dir = strings.Trim(dir, `"'`)
+			if dir != "" && (filepath.IsAbs(dir) || strings.HasPrefix(dir, "~/")) && !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// cacheEntries returns the files in a cache directory, leaving out the
+// cache's settings, counters and locks
+func (hs *HyperScanner) cacheEntries(dir string) []cacheEntry {
+	var entries []cacheEntry
+	vfs.WalkDir(hs.fs, dir, func(path string, d os.DirEntry, err error) error {
+		if hs.cancelled() {
+			return filepath.SkipAll
+		}
+		if err != nil || d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		name := d.Name()
+		if compilerCacheKeep[name] || strings.HasSuffix(name, ".lock") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, cacheEntry{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	return entries
+}
+
+// pruneCache picks the entries of one cache to remove: the ones last used
+// before cutoff (unless it's zero), then the least recently used of the rest
+// until what's left fits in maxSize (unless it's 0)
+func pruneCache(entries []cacheEntry, cutoff time.Time, maxSize int64) (old, over []cacheEntry) {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b cacheEntry) int {
+		return a.used.Compare(b.used)
+	})
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+	i := 0
+	for ; i < len(entries) && !cutoff.IsZero() && entries[i].used.Before(cutoff); i++ {
+		old = append(old, entries[i])
+		total -= entries[i].size
+	}
+	for ; i < len(entries) && maxSize > 0 && total > maxSize; i++ {
+		over = append(over, entries[i])
+		total -= entries[i].size
+	}
+	return old, over
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// DesktopCachesCategory is the category for the font, icon and MIME type
+// caches of Linux desktops
+const DesktopCachesCategory = "desktop_caches"
+
+// desktopCaches are the caches in the user's cache directory the desktop
+// rebuilds by itself, matched as globs
+var desktopCaches = []struct {
+	pattern string
+	reason  string
+}{
+	{"fontconfig", "Fontconfig font cache, rebuilt by fc-cache or the next app that draws text"},
+	{"icon-cache.kcache", "KDE icon cache, rebuilt at login"},
+	{"ksycoca5_*", "KDE service and MIME type cache, rebuilt by kbuildsycoca5"},
+	{"ksycoca6_*", "KDE service and MIME type cache, rebuilt by kbuildsycoca6"},
+	{"gnome-software/icons", "GNOME Software icon cache"},
+}
+
+// scanDesktopCachesCategory finds desktop caches on Linux. Each cache is one
+// result, whatever its age: they're rebuilt whole, so cleaning half of one
+// only gets it rebuilt sooner.
+func (hs *HyperScanner) scanDesktopCachesCategory() {
+	if hs.platformInfo.OS != platform.Linux {
+		return
+	}
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		cacheHome = filepath.Join(hs.homeDir(), ".cache")
+	}
+
+	for _, cache := range desktopCaches {
+		paths, _ := vfs.Glob(hs.fs, filepath.Join(cacheHome, cache.pattern))
+		for _, path := range paths {
+			info, err := hs.fs.Lstat(path)
+			if err != nil {
+				continue
+			}
+			file := FileInfo{
+				Path:     path,
+				Size:     info.Size(),
+				ModTime:  info.ModTime(),
+				Category: DesktopCachesCategory,
+				Reason:   cache.reason,
+			}
+			if info.IsDir() {
+				file.Size, file.Files, file.ModTime = hs.dirUsage(path)
+				if file.Files == 0 {
+					continue
+				}
+			} else if !info.Mode().IsRegular() {
+				continue
+			}
+			hs.appendResult(file, int64(max(file.Files, 1)))
+		}
+	}
+}
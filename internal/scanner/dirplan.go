@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// dirCategories are the categories that walk whole directories. A directory
+// more than one of them lists goes to the first, the most specific: /tmp is
+// both a Linux cache and a temp directory, and is reported as temp.
+var dirCategories = []string{"temp", "logs", "cache"}
+
+// maxSymlinkHops bounds how many symlinks realPath follows, in case of a loop
+const maxSymlinkHops = 40
+
+// dirPlan is where each directory category walks
+type dirPlan struct {
+	dirs     map[string][]string // Per category; no directory is in or below another, across categories too
+	excluded map[string][]string // Per category, directories below its own that another category walks
+}
+
+// categoryDirs returns the directories category lists, before deduplication
+func (hs *HyperScanner) categoryDirs(category string) []string {
+	switch category {
+	case "temp":
+		return hs.platformInfo.TempDirs
+	case "logs":
+		return hs.platformInfo.LogDirs
+	case "cache":
+		return hs.getCacheDirs()
+	}
+	return nil
+}
+
+// planDirs builds the directories the directory categories among categories
+// walk, once, before any of them starts. Directories are resolved through
+// symlinks, so /tmp and /private/tmp on macOS are one, and each is walked by
+// one category only: files aren't reported twice, and no worker walks what
+// another already does.
+func (hs *HyperScanner) planDirs(categories []string) dirPlan {
+	plan := dirPlan{dirs: make(map[string][]string), excluded: make(map[string][]string)}
+
+	type claim struct{ dir, category string }
+	var claimed []claim
+	for _, category := range dirCategories {
+		if !slices.Contains(categories, category) {
+			continue
+		}
+		for _, dir := range hs.categoryDirs(category) {
+			if hs.onWindowsDrive(dir) {
+				continue
+			}
+			if _, err := hs.fs.Stat(dir); err != nil {
+				continue
+			}
+			dir = hs.realPath(dir)
+			covered := false
+			for _, c := range claimed {
+				if underAny(dir, []string{c.dir}) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				claimed = append(claimed, claim{dir, category})
+			}
+		}
+	}
+
+	for i, c := range claimed {
+		inner := false
+		for j, other := range claimed {
+			if i == j || !underAny(other.dir, []string{c.dir}) {
+				continue
+			}
+			if other.category != c.category {
+				// Listed by a more specific category first
+				plan.excluded[c.category] = append(plan.excluded[c.category], other.dir)
+			}
+		}
+		for _, other := range claimed {
+			if other.category == c.category && other.dir != c.dir && underAny(c.dir, []string{other.dir}) {
+				inner = true
+				break
+			}
+		}
+		if !inner {
+			plan.dirs[c.category] = append(plan.dirs[c.category], c.dir)
+		}
+	}
+	return plan
+}
+
+// realPath resolves the symlinks in the absolute path through hs.fs. Links it
+// can't read are left as they are.
+func (hs *HyperScanner) realPath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(filepath.Clean(path), "/"), "/")
+	resolved := "/"
+	for hops := 0; len(parts) > 0; {
+		next := filepath.Join(resolved, parts[0])
+		parts = parts[1:]
+
+		info, err := hs.fs.Lstat(next)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if hops++; hops > maxSymlinkHops {
+			return filepath.Clean(path)
+		}
+		target, err := hs.fs.Readlink(next)
+		if err != nil {
+			resolved = next
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+		parts = append(strings.Split(strings.TrimPrefix(filepath.Clean(target), "/"), "/"), parts...)
+		resolved = "/"
+	}
+	return resolved
+}
@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// DownloadsCategory is the category for old files in the Downloads folder
+const DownloadsCategory = "downloads"
+
+// chromeEpoch is the Unix time of 1601-01-01, where Chrome's timestamps start
+const chromeEpoch = -11644473600
+
+// chromeDownloads lists each download with the last URL in its redirect
+// chain, where the file really came from
+const chromeDownloads = `SELECT d.target_path AS path, d.end_time AS time,
+	COALESCE((SELECT c.url FROM downloads_url_chains c WHERE c.id = d.id ORDER BY c.chain_index DESC LIMIT 1), d.tab_url) AS url
+	FROM downloads d`
+
+// firefoxDownloads lists each download by the annotation Firefox keeps on
+// the page it was downloaded from
+const firefoxDownloads = `SELECT a.content AS path, a.dateAdded AS time, p.url AS url
+	FROM moz_annos a
+	JOIN moz_anno_attributes n ON n.id = a.anno_attribute_id
+	JOIN moz_places p ON p.id = a.place_id
+	WHERE n.name = 'downloads/destinationFileURI'`
+
+// downloadRecord is where and when a browser downloaded a file
+type downloadRecord struct {
+	URL  string
+	When time.Time
+}
+
+// scanDownloadsCategory reports each file and folder at the top of the
+// Downloads folder not modified for age_thresholds.downloads days. With
+// downloads_config.browser_history, Chrome's and Firefox's download history
+// says where each came from, and downloads from downloads_config.from_hosts
+// are reported whatever their age.
+func (hs *HyperScanner) scanDownloadsCategory() {
+	dir := hs.platformInfo.DownloadsDir
+	if dir == "" || hs.onWindowsDrive(dir) {
+		return
+	}
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var history map[string]downloadRecord
+	if hs.config.Downloads.BrowserHistory && hs.native() {
+		history = hs.downloadHistory()
+	}
+	cutoff := time.Now().AddDate(0, 0, -hs.config.AgeThresholds.Downloads)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		size, files, modTime := info.Size(), 1, info.ModTime()
+		switch {
+		case entry.IsDir():
+			size, files, modTime = hs.dirUsage(path)
+			if files == 0 {
+				continue
+			}
+		case !entry.Type().IsRegular():
+			continue
+		}
+
+		record, known := history[path]
+		mirror := known && matchesHost(record.URL, hs.config.Downloads.FromHosts)
+		if !modTime.Before(cutoff) && !mirror {
+			continue
+		}
+
+		reason := "In Downloads, last modified " + modTime.Format("2006-01-02")
+		if known {
+			reason = fmt.Sprintf("Downloaded from %s on %s", redactURL(record.URL), record.When.Format("2006-01-02"))
+		}
+		file := FileInfo{
+			Path:     path,
+			Size:     size,
+			ModTime:  modTime,
+			Category: DownloadsCategory,
+			Reason:   reason,
+		}
+		if entry.IsDir() {
+			file.Files = files
+		}
+		hs.appendResult(file, int64(files))
+	}
+}
+
+// downloadHistory reads the download history of every Chrome, Chromium,
+// Brave, Edge and Firefox profile with the sqlite3 command, keyed by the
+// file's path. The databases are opened read-only and immutable, so a
+// running browser's locks don't get in the way and nothing is written.
+// Without sqlite3 the history is empty.
+func (hs *HyperScanner) downloadHistory() map[string]downloadRecord {
+	history := make(map[string]downloadRecord)
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return history
+	}
+
+	home := hs.homeDir()
+	var chrome, firefox []string
+	for _, pattern := range []string{
+		".config/google-chrome/*/History",
+		".config/chromium/*/History",
+		".config/BraveSoftware/Brave-Browser/*/History",
+		".config/microsoft-edge/*/History",
+		"Library/Application Support/Google/Chrome/*/History",
+		"Library/Application Support/Chromium/*/History",
+		"Library/Application Support/BraveSoftware/Brave-Browser/*/History",
+		"Library/Application Support/Microsoft Edge/*/History",
+	} {
+		matches, _ := vfs.Glob(hs.fs, filepath.Join(home, pattern))
+		chrome = append(chrome, matches...)
+	}
+	for _, pattern := range []string{
+		".mozilla/firefox/*/places.sqlite",
+		"Library/Application Support/Firefox/Profiles/*/places.sqlite",
+	} {
+		matches, _ := vfs.Glob(hs.fs, filepath.Join(home, pattern))
+		firefox = append(firefox, matches...)
+	}
+
+	for _, db := range chrome {
+		if out, err := querySQLite(db, chromeDownloads); err == nil {
+			parseDownloadHistory(out, chromeTime, history)
+		}
+	}
+	for _, db := range firefox {
+		if out, err := querySQLite(db, firefoxDownloads); err == nil {
+			parseDownloadHistory(out, unixTime, history)
+		}
+	}
+	return history
+}
+
+// querySQLite runs query on the database at path with the sqlite3 command,
+// returning its rows as JSON
+func querySQLite(path, query string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	uri := (&url.URL{Scheme: "file", Path: path, RawQuery: "immutable=1"}).String()
+	out, err := exec.CommandContext(ctx, "sqlite3", "-readonly", "-json", uri, query).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// parseDownloadHistory adds the rows sqlite3 printed as JSON to history,
+// converting their timestamps with toTime. Paths may be file URLs. The
+// newest download of a path wins.
+func parseDownloadHistory(out []byte, toTime func(int64) time.Time, history map[string]downloadRecord) {
+	var rows []struct {
+		Path string `json:"path"`
+		Time int64  `json:"time"`
+		URL  string `json:"url"`
+	}
+	if len(out) == 0 || json.Unmarshal(out, &rows) != nil {
+		return
+	}
+	for _, row := range rows {
+		file := row.Path
+		if u, err := url.Parse(file); err == nil && u.Scheme == "file" {
+			file = u.Path
+		}
+		if file == "" || row.URL == "" {
+			continue
+		}
+		record := downloadRecord{URL: row.URL, When: toTime(row.Time)}
+		if old, ok := history[file]; !ok || record.When.After(old.When) {
+			history[file] = record
+		}
+	}
+}
+
+// chromeTime converts a Chrome timestamp, microseconds since 1601
+func chromeTime(n int64) time.Time {
+	return time.Unix(n/1e6+chromeEpoch, n%1e6*1e3)
+}
+
+// matchesHost reports whether rawURL's host matches one of patterns, which
+// are shell patterns like "*.cloudfront.net"
+func matchesHost(rawURL string, patterns []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL drops the query and fragment from a URL, which often hold
+// signatures and tokens that shouldn't end up in reports
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.User = nil
+	return u.String()
+}
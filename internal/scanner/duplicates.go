@@ -0,0 +1,219 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// DuplicatesCategory is the category for files with identical content
+const DuplicatesCategory = "duplicates"
+
+// quickHashChunk is how much of each end of a file the quick hash reads
+const quickHashChunk = 64 * 1024
+
+// scanDuplicatesCategory finds files with identical content. Files are
+// bucketed by size, then by a quick hash of both ends, then confirmed with a
+// full hash. The newest copy in each group is kept; the rest are reported.
+func (hs *HyperScanner) scanDuplicatesCategory() {
+	home := hs.homeDir()
+	minSize := hs.parseSize(hs.config.Duplicates.MinSize)
+
+	var excludes []string
+	for _, excl := range hs.config.Duplicates.ExcludePaths {
+		excludes = append(excludes, expandPath(excl, hs.configHome()))
+	}
+
+	bySize := make(map[int64][]FileInfo)
+	seen := make(map[string]bool) // scan paths may overlap
+
+	for _, scanPath := range hs.config.Duplicates.ScanPaths {
+		scanPath = expandPath(scanPath, home)
+		stop := hs.timeDir(scanPath, DuplicatesCategory)
+
+		vfs.WalkDir(hs.fs, scanPath, func(path string, d os.DirEntry, err error) error {
+			if hs.cancelled() {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				return nil
+			}
+
+			for _, excl := range excludes {
+				if strings.HasPrefix(path, excl) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+
+			if d.IsDir() {
+				if hs.onWindowsDrive(path) {
+					return filepath.SkipDir
+				}
+				// Hidden directories and dependency trees are full of expected copies
+				name := d.Name()
+				if path != scanPath && (strings.HasPrefix(name, ".") || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Symlinks and special files are never duplicates worth removing
+			if !d.Type().IsRegular() || seen[path] {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil || info.Size() < minSize || info.Size() == 0 {
+				return nil
+			}
+
+			seen[path] = true
+			bySize[info.Size()] = append(bySize[info.Size()], FileInfo{
+				Path:    path,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+			return nil
+		})
+		stop()
+	}
+
+	groups := hs.findDuplicateGroups(bySize)
+
+	hs.results.setDuplicates(groups)
+
+	for _, group := range groups {
+		// Keep the newest copy
+		for _, file := range group.Files[1:] {
+			hs.appendResult(duplicateResult(file, group), 1)
+		}
+	}
+}
+
+// findDuplicateGroups narrows same-size files down to groups with identical
+// content, largest reclaimable space first
+func (hs *HyperScanner) findDuplicateGroups(bySize map[int64][]FileInfo) []DuplicateGroup {
+	var groups []DuplicateGroup
+
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+
+		byQuick := make(map[string][]FileInfo)
+		for _, file := range files {
+			hash, err := hs.hashFile(file.Path, true)
+			if err != nil {
+				continue
+			}
+			byQuick[hash] = append(byQuick[hash], file)
+		}
+
+		for quick, candidates := range byQuick {
+			if len(candidates) < 2 {
+				continue
+			}
+
+			// The quick hash only reads both ends of large files - confirm
+			// with a full hash before calling them identical
+			byFull := map[string][]FileInfo{quick: candidates}
+			if size > 2*quickHashChunk {
+				byFull = make(map[string][]FileInfo)
+				for _, file := range candidates {
+					hash, err := hs.hashFile(file.Path, false)
+					if err != nil {
+						continue
+					}
+					byFull[hash] = append(byFull[hash], file)
+				}
+			}
+
+			for hash, same := range byFull {
+				same = dropHardLinks(hs.fs, same)
+				if len(same) < 2 {
+					continue
+				}
+				sort.Slice(same, func(i, j int) bool {
+					if !same[i].ModTime.Equal(same[j].ModTime) {
+						return same[i].ModTime.After(same[j].ModTime)
+					}
+					return same[i].Path < same[j].Path
+				})
+				for i := range same {
+					same[i].Hash = hash
+				}
+				groups = append(groups, DuplicateGroup{Hash: hash, Size: size, Files: same})
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		ri := groups[i].Size * int64(len(groups[i].Files)-1)
+		rj := groups[j].Size * int64(len(groups[j].Files)-1)
+		if ri != rj {
+			return ri > rj
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+
+	return groups
+}
+
+// dropHardLinks removes files that are hard links to an earlier file in the
+// list - deleting them frees nothing
+func dropHardLinks(fsys vfs.FS, files []FileInfo) []FileInfo {
+	var kept []FileInfo
+	var infos []os.FileInfo
+	for _, file := range files {
+		info, err := fsys.Stat(file.Path)
+		if err != nil {
+			continue
+		}
+		linked := false
+		for _, other := range infos {
+			if os.SameFile(info, other) {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			kept = append(kept, file)
+			infos = append(infos, info)
+		}
+	}
+	return kept
+}
+
+// hashFile hashes a file's content, or just both ends of it if quick is set
+func (hs *HyperScanner) hashFile(path string, quick bool) (string, error) {
+	f, err := hs.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if !quick {
+		return utils.HashReader(f)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return utils.HashReaderQuick(f, info.Size(), quickHashChunk)
+}
+
+// duplicateResult returns a group member as a scan result
+func duplicateResult(file FileInfo, group DuplicateGroup) FileInfo {
+	file.Category = DuplicatesCategory
+	file.Hash = group.Hash
+	file.Reason = fmt.Sprintf("Identical to %d other file(s)", len(group.Files)-1)
+	return file
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EmptyDirsCategory is the category for empty directories
+const EmptyDirsCategory = "empty_dirs"
+
+// scanEmptyDirsCategory finds directories holding nothing but other empty
+// directories, such as the ones earlier cleanups emptied. Only the topmost
+// directory of each such tree is reported; the scan paths themselves are kept.
+func (hs *HyperScanner) scanEmptyDirsCategory() {
+	home := hs.homeDir()
+	cutoff := time.Now().AddDate(0, 0, -hs.config.EmptyDirs.MinAgeDays)
+
+	roots := hs.config.EmptyDirs.ScanPaths
+	if len(roots) == 0 {
+		// Where cleanups leave directories behind
+		roots = append(append(roots, hs.platformInfo.CacheDirs...), hs.config.OldFiles.ScanPaths...)
+	}
+
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		root = filepath.Clean(expandPath(root, home))
+		if seen[root] || hs.onWindowsDrive(root) {
+			continue
+		}
+		seen[root] = true
+
+		info, err := hs.fs.Lstat(root)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		stop := hs.timeDir(root, EmptyDirsCategory)
+		hs.findEmptyDirs(root, info, cutoff, true)
+		stop()
+	}
+}
+
+// findEmptyDirs reports whether dir holds no files and hasn't changed since
+// cutoff. If it does hold files, its empty subdirectories are reported
+// instead. top marks a scan path, which is never empty itself.
+func (hs *HyperScanner) findEmptyDirs(dir string, info os.FileInfo, cutoff time.Time, top bool) bool {
+	// Git keeps empty directories it needs, like refs/tags
+	if info.Name() == ".git" {
+		return false
+	}
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	empty := !top && info.ModTime().Before(cutoff)
+	var pending []FileInfo
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() || hs.onWindowsDrive(path) {
+			empty = false
+			continue
+		}
+		childInfo, err := entry.Info()
+		if err != nil {
+			empty = false
+			continue
+		}
+		if hs.findEmptyDirs(path, childInfo, cutoff, false) {
+			pending = append(pending, FileInfo{
+				Path:     path,
+				ModTime:  childInfo.ModTime(),
+				Category: EmptyDirsCategory,
+				Reason:   "Empty directory",
+			})
+		} else {
+			empty = false
+		}
+	}
+
+	if !empty {
+		for _, file := range pending {
+			hs.appendResult(file, 1)
+		}
+	}
+	return empty
+}
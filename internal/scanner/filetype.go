@@ -0,0 +1,145 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// Kinds of large files, for FileInfo.Type
+const (
+	TypeVideo     = "video"
+	TypeDiskImage = "disk_image"
+	TypeArchive   = "archive"
+	TypeDatabase  = "database"
+	TypeVMImage   = "vm_image"
+	TypeOther     = "other"
+)
+
+// FileTypes lists the kinds of large files, in the order they're reported
+var FileTypes = []string{TypeVideo, TypeDiskImage, TypeArchive, TypeDatabase, TypeVMImage, TypeOther}
+
+// typeExts maps file extensions to the kind of file they name
+var typeExts = map[string]string{
+	".mp4": TypeVideo, ".m4v": TypeVideo, ".mkv": TypeVideo, ".webm": TypeVideo, ".avi": TypeVideo,
+	".mov": TypeVideo, ".wmv": TypeVideo, ".flv": TypeVideo, ".mpg": TypeVideo, ".mpeg": TypeVideo,
+	".ts": TypeVideo, ".m2ts": TypeVideo,
+
+	".iso": TypeDiskImage, ".dmg": TypeDiskImage, ".img": TypeDiskImage, ".toast": TypeDiskImage,
+	".sparseimage": TypeDiskImage, ".sparsebundle": TypeDiskImage,
+
+	".zip": TypeArchive, ".tar": TypeArchive, ".gz": TypeArchive, ".tgz": TypeArchive,
+	".bz2": TypeArchive, ".xz": TypeArchive, ".zst": TypeArchive, ".7z": TypeArchive,
+	".rar": TypeArchive, ".pkg": TypeArchive, ".deb": TypeArchive, ".rpm": TypeArchive,
+
+	".db": TypeDatabase, ".sqlite": TypeDatabase, ".sqlite3": TypeDatabase, ".mdb": TypeDatabase,
+	".accdb": TypeDatabase, ".realm": TypeDatabase, ".ibd": TypeDatabase, ".mdf": TypeDatabase,
+
+	".vmdk": TypeVMImage, ".vdi": TypeVMImage, ".qcow2": TypeVMImage, ".vhd": TypeVMImage,
+	".vhdx": TypeVMImage, ".ova": TypeVMImage, ".hdd": TypeVMImage, ".raw": TypeVMImage,
+}
+
+// typeMagic is a signature at a fixed offset from the start of a file
+type typeMagic struct {
+	offset int64
+	magic  []byte
+	kind   string
+}
+
+// typeMagics identify files whose extension doesn't say what they are
+var typeMagics = []typeMagic{
+	{0, []byte("\x1a\x45\xdf\xa3"), TypeVideo}, // Matroska, WebM
+	{0, []byte("SQLite format 3\x00"), TypeDatabase},
+	{0, []byte("QFI\xfb"), TypeVMImage},           // qcow2
+	{0, []byte("KDMV"), TypeVMImage},              // Sparse VMDK
+	{0, []byte("vhdxfile"), TypeVMImage},          // VHDX
+	{0, []byte("conectix"), TypeVMImage},          // Dynamic VHD
+	{64, []byte("\x7f\x10\xda\xbe"), TypeVMImage}, // VDI
+	{0, []byte("PK\x03\x04"), TypeArchive},
+	{0, []byte("\x1f\x8b"), TypeArchive},         // gzip
+	{0, []byte("BZh"), TypeArchive},              // bzip2
+	{0, []byte("\xfd7zXZ\x00"), TypeArchive},     // xz
+	{0, []byte("\x28\xb5\x2f\xfd"), TypeArchive}, // zstd
+	{0, []byte("7z\xbc\xaf\x27\x1c"), TypeArchive},
+	{0, []byte("Rar!\x1a\x07"), TypeArchive},
+	{257, []byte("ustar"), TypeArchive},
+	{0x8001, []byte("CD001"), TypeDiskImage}, // ISO 9660
+}
+
+// videoBrands are the ISO media brands of video files; other brands (HEIC
+// photos, AVIF) use the same container
+var videoBrands = []string{"isom", "iso2", "mp41", "mp42", "M4V ", "qt  ", "avc1", "3gp", "dash", "MSNV"}
+
+// ClassifyFile returns the kind of file at path: by its extension, or else by
+// sniffing its first bytes
+func ClassifyFile(fsys vfs.FS, path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	if kind, ok := typeExts[filepath.Ext(name)]; ok {
+		return kind
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return TypeOther
+	}
+	defer f.Close()
+	return sniffType(f)
+}
+
+// sniffType identifies a file from its signature
+func sniffType(r io.ReadSeeker) string {
+	head := make([]byte, 0x8001+5)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+
+	for _, m := range typeMagics {
+		end := m.offset + int64(len(m.magic))
+		if int64(len(head)) >= end && bytes.Equal(head[m.offset:end], m.magic) {
+			return m.kind
+		}
+	}
+
+	// ISO media: a box size, then "ftyp" and the brand
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		for _, brand := range videoBrands {
+			if strings.HasPrefix(string(head[8:12]), brand) {
+				return TypeVideo
+			}
+		}
+	}
+	if len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "AVI " {
+		return TypeVideo
+	}
+
+	// Disk images and fixed VHDs keep their signature in a 512-byte trailer
+	if _, err := r.Seek(-512, io.SeekEnd); err == nil {
+		tail := make([]byte, 512)
+		if n, _ := io.ReadFull(r, tail); n == 512 {
+			switch {
+			case string(tail[0:4]) == "koly":
+				return TypeDiskImage
+			case string(tail[0:8]) == "conectix":
+				return TypeVMImage
+			}
+		}
+	}
+	return TypeOther
+}
+
+// addLargeFile records a large file along with its type
+func (hs *HyperScanner) addLargeFile(path string, size int64, modTime time.Time) {
+	hs.appendResult(FileInfo{
+		Path:     path,
+		Size:     size,
+		ModTime:  modTime,
+		Category: "large_files",
+		Reason: fmt.Sprintf("Large file, unmodified for %s (large_files.min_size: %s)",
+			since(modTime), hs.config.LargeFiles.MinSize),
+		Type: ClassifyFile(hs.fs, path),
+	}, 1)
+}
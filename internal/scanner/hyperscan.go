@@ -2,14 +2,14 @@ package scanner
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
-	"encoding/gob"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,6 +17,10 @@ import (
 
 	"github.com/fenilsonani/system-cleanup/internal/config"
 	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/security"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 // HyperScanner uses advanced techniques for blazing fast scanning
@@ -28,32 +32,53 @@ type HyperScanner struct {
 	config       *config.Config
 	platformInfo *platform.Info
 	progressCb   ProgressCallback
+	ctx          context.Context // Stops the scan early when cancelled
+	fs           vfs.FS          // What is scanned; the real file system unless SetFS is called
+	root         string          // The only tree results may come from, when SetRoot is called
+	userHome     string          // The home directory root stands in for, when SetRoot is called
+	profile      *scanProfile    // Where scans spend their time, when EnableProfile is called
+
+	// Parent directories the user can write to, by path
+	writableDirs sync.Map
 
 	// Scan cache - persisted between runs
 	cache     *ScanCache
 	cachePath string
 	cacheMu   sync.RWMutex // Protects cache map access
+	cacheErrs []error      // Problems reading or writing the cache, reported with the next result
 
-	// Runtime state
-	filesFound int64
-	totalSize  int64
+	// Files the user dismissed for good, and why the list couldn't be read
+	ignored   *IgnoreList
+	ignoreErr error
 
-	// Worker pool
+	// Worker pool, sized for the storage being scanned
 	workerCount int
+	storage     platform.StorageKind
 	sem         chan struct{}
+	sizeSem     chan struct{} // du processes sizing dev artifacts
+
+	// Results of the current scan
+	results *collector
+
+	// Directories the cache, temp and logs categories walk in this scan,
+	// planned before any of them starts
+	plan dirPlan
 
-	// Results
-	resultMu sync.Mutex
-	results  []FileInfo
+	// Progress of the current scan, guarded by progressMu
+	progressMu     sync.Mutex
+	scanStart      time.Time
+	categoryOrder  []string
+	categoriesDone map[string]time.Duration
 }
 
 // ScanCache stores scan results for fast re-scanning
 type ScanCache struct {
-	Version      int                       `json:"version"`
-	LastScan     time.Time                 `json:"last_scan"`
-	DirMtimes    map[string]time.Time      `json:"dir_mtimes"`    // Directory -> last modified
-	DirResults   map[string]*CachedDirInfo `json:"dir_results"`   // Directory -> cached scan results
-	ArtifactDirs map[string][]string       `json:"artifact_dirs"` // DevDir -> list of artifact paths
+	Version           int                       `json:"version"`
+	LastScan          time.Time                 `json:"last_scan"`
+	DirMtimes         map[string]time.Time      `json:"dir_mtimes"`         // Directory -> last modified
+	DirResults        map[string]*CachedDirInfo `json:"dir_results"`        // Directory -> cached scan results
+	ArtifactDirs      map[string][]string       `json:"artifact_dirs"`      // DevDir -> list of artifact paths
+	CategoryDurations map[string]time.Duration  `json:"category_durations"` // How long each category took last time, for the ETA
 }
 
 // CachedDirInfo stores cached info about a directory
@@ -61,6 +86,7 @@ type CachedDirInfo struct {
 	Path      string    `json:"path"`
 	TotalSize int64     `json:"total_size"`
 	FileCount int       `json:"file_count"`
+	Exact     bool      `json:"exact"` // FileCount and TotalSize were counted, not estimated
 	Category  string    `json:"category"`
 	ScannedAt time.Time `json:"scanned_at"`
 	Checksum  string    `json:"checksum"` // For validation
@@ -68,93 +94,210 @@ type CachedDirInfo struct {
 
 // NewHyperScanner creates a new hyper-optimized scanner
 func NewHyperScanner(cfg *config.Config, platformInfo *platform.Info) *HyperScanner {
-	workers := runtime.NumCPU() * 4
-	if workers < 16 {
-		workers = 16
-	}
-	if workers > 64 {
-		workers = 64
-	}
-
-	home, _ := os.UserHomeDir()
-	cachePath := filepath.Join(home, ".cache", "tidyup", "scan_cache.gob")
+	workers, storage := scanWorkers(cfg, platformInfo)
 
 	hs := &HyperScanner{
 		config:       cfg,
 		platformInfo: platformInfo,
+		ctx:          context.Background(),
+		fs:           vfs.OS,
 		workerCount:  workers,
+		storage:      storage,
 		sem:          make(chan struct{}, workers),
-		cachePath:    cachePath,
-		results:      make([]FileInfo, 0, 10000),
+		sizeSem:      make(chan struct{}, artifactSizeWorkers(cfg, storage)),
+		cachePath:    cacheFile(),
+		results:      newCollector(10000),
 	}
 
 	// Load existing cache
 	hs.loadCache()
+	hs.ignored, hs.ignoreErr = LoadIgnoreList(IgnoreListPath())
 
 	return hs
 }
 
+// SetIgnoreList replaces the files the user dismissed for good
+func (hs *HyperScanner) SetIgnoreList(list *IgnoreList) {
+	hs.ignored, hs.ignoreErr = list, nil
+}
+
+// SetFS scans fsys instead of the real file system. Results aren't cached
+// between runs, and tools that only see the real file system (find, du,
+// mdfind, docker) aren't used.
+func (hs *HyperScanner) SetFS(fsys vfs.FS) {
+	hs.fs = fsys
+	if !vfs.IsOS(fsys) {
+		hs.DisableCache()
+	}
+}
+
+// DisableCache scans without the results of earlier runs, and doesn't save
+// this run's for later ones
+func (hs *HyperScanner) DisableCache() {
+	hs.cachePath = ""
+	hs.cacheErrs = nil
+	hs.loadCache()
+}
+
+// native reports whether the real file system is being scanned
+func (hs *HyperScanner) native() bool {
+	return vfs.IsOS(hs.fs)
+}
+
+// homeDir returns the home directory of the user being scanned for
+func (hs *HyperScanner) homeDir() string {
+	if hs.platformInfo.HomeDir != "" {
+		return hs.platformInfo.HomeDir
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// configHome returns the home directory ~ means in exclude paths: the
+// user's, even when SetRoot made another tree stand in for it
+func (hs *HyperScanner) configHome() string {
+	if hs.userHome != "" {
+		return hs.userHome
+	}
+	return hs.homeDir()
+}
+
 // SetProgressCallback sets the progress callback
 func (hs *HyperScanner) SetProgressCallback(cb ProgressCallback) {
 	hs.progressCb = cb
 }
 
-// loadCache loads the scan cache from disk
-func (hs *HyperScanner) loadCache() {
-	hs.cache = &ScanCache{
-		Version:      1,
-		DirMtimes:    make(map[string]time.Time),
-		DirResults:   make(map[string]*CachedDirInfo),
-		ArtifactDirs: make(map[string][]string),
-	}
+// SetContext stops the scan when ctx is cancelled. ScanAll then returns
+// ctx's error instead of a partial result.
+func (hs *HyperScanner) SetContext(ctx context.Context) {
+	hs.ctx = ctx
+}
 
-	f, err := os.Open(hs.cachePath)
-	if err != nil {
-		return // No cache yet
-	}
-	defer f.Close()
-
-	dec := gob.NewDecoder(f)
-	var cache ScanCache
-	if err := dec.Decode(&cache); err == nil {
-		// Only use cache if it's recent (within 1 hour)
-		if time.Since(cache.LastScan) < time.Hour {
-			// Ensure maps are initialized
-			if cache.ArtifactDirs == nil {
-				cache.ArtifactDirs = make(map[string][]string)
-			}
-			hs.cache = &cache
-		}
-	}
+// cancelled reports whether the scan's context was cancelled
+func (hs *HyperScanner) cancelled() bool {
+	return hs.ctx.Err() != nil
 }
 
-// saveCache saves the scan cache to disk
-func (hs *HyperScanner) saveCache() {
-	if err := os.MkdirAll(filepath.Dir(hs.cachePath), 0755); err != nil {
-		return
+// Engine names the scanning engine, for report metadata
+func (hs *HyperScanner) Engine() string {
+	return "hyperscan"
+}
+
+// EnabledCategories returns the categories ScanAll will report on, in dispatch order
+func (hs *HyperScanner) EnabledCategories() []string {
+	cats := hs.config.Categories
+	var enabled []string
+
+	if cats.Cache {
+		enabled = append(enabled, "cache")
+	}
+	if cats.Temp {
+		enabled = append(enabled, "temp")
+	}
+	if cats.Logs {
+		enabled = append(enabled, "logs")
+	}
+	if cats.NodeModules {
+		enabled = append(enabled, "node_modules")
+	}
+	if cats.VirtualEnvs {
+		enabled = append(enabled, "virtual_envs")
+	}
+	if cats.BuildArtifacts {
+		enabled = append(enabled, "build_artifacts")
+	}
+	if cats.LargeFiles {
+		enabled = append(enabled, "large_files")
+	}
+	if cats.OldFiles {
+		enabled = append(enabled, "old_files")
+	}
+	if cats.Docker {
+		enabled = append(enabled, "docker")
+	}
+	if cats.AppData {
+		enabled = append(enabled, "app_data")
+	}
+	if cats.Duplicates {
+		enabled = append(enabled, DuplicatesCategory)
+	}
+	if cats.WSL && hs.platformInfo.WSL {
+		enabled = append(enabled, WSLCategory)
+	}
+	if cats.EmptyDirs {
+		enabled = append(enabled, EmptyDirsCategory)
+	}
+	if cats.VMImages {
+		enabled = append(enabled, VMImagesCategory)
+	}
+	if cats.CloudCLI {
+		enabled = append(enabled, CloudCLICategory)
+	}
+	if cats.DesktopCaches && hs.platformInfo.OS == platform.Linux {
+		enabled = append(enabled, DesktopCachesCategory)
+	}
+	if cats.CompilerCaches {
+		enabled = append(enabled, CompilerCachesCategory)
+	}
+	if cats.LaTeX {
+		enabled = append(enabled, LaTeXCategory)
+	}
+	if cats.RPackages {
+		enabled = append(enabled, RPackagesCategory)
+	}
+	if cats.Julia {
+		enabled = append(enabled, JuliaCategory)
+	}
+	if cats.StaleRepos {
+		enabled = append(enabled, StaleReposCategory)
+	}
+	if cats.Screenshots {
+		enabled = append(enabled, ScreenshotsCategory)
+	}
+	if cats.Downloads {
+		enabled = append(enabled, DownloadsCategory)
 	}
 
-	f, err := os.Create(hs.cachePath)
+	return enabled
+}
+
+// CategoryTotal returns the running item count and size for a category.
+// Safe to call from a progress callback while a scan is in flight.
+func (hs *HyperScanner) CategoryTotal(category string) CategoryTotal {
+	return hs.results.categoryTotal(category)
+}
+
+// resetResults clears collected results before a new scan
+func (hs *HyperScanner) resetResults(capacity int) {
+	hs.results.reset(capacity)
+}
+
+// ScanAll performs a hyper-fast scan of all enabled categories. Results past
+// scan.max_results are spilled to a temp file; see ScanResult.Spilled. A file
+// found by more than one category is reported once.
+func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
+	var result *ScanResult
+	var err error
+	if limit := hs.config.Scan.MaxResults; limit > 0 {
+		result, err = hs.scanAllSpilled(limit)
+	} else {
+		result, err = hs.scanAll()
+	}
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer f.Close()
-
-	hs.cacheMu.RLock()
-	hs.cache.LastScan = time.Now()
-	enc := gob.NewEncoder(f)
-	if err := enc.Encode(hs.cache); err != nil {
-		hs.cacheMu.RUnlock()
-		return
+	if err := hs.ctx.Err(); err != nil {
+		result.Close()
+		return nil, fmt.Errorf("scan interrupted: %w", err)
 	}
-	hs.cacheMu.RUnlock()
+	return hs.dedupResult(result), nil
 }
 
-// ScanAll performs a hyper-fast scan of all enabled categories
-func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
-	atomic.StoreInt64(&hs.filesFound, 0)
-	atomic.StoreInt64(&hs.totalSize, 0)
-	hs.results = make([]FileInfo, 0, 10000)
+// scanAll scans every enabled category
+func (hs *HyperScanner) scanAll() (*ScanResult, error) {
+	hs.resetResults(10000)
+	hs.startProgress(hs.EnabledCategories())
+	hs.plan = hs.planDirs(hs.EnabledCategories())
 
 	var wg sync.WaitGroup
 
@@ -163,6 +306,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("cache")
 			hs.scanCacheCategory()
 		}()
 	}
@@ -171,6 +315,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("temp")
 			hs.scanTempCategory()
 		}()
 	}
@@ -179,6 +324,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("logs")
 			hs.scanLogsCategory()
 		}()
 	}
@@ -188,6 +334,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("node_modules", "virtual_envs", "build_artifacts")
 			hs.scanDevArtifacts()
 		}()
 	}
@@ -197,6 +344,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("large_files")
 			hs.scanLargeFilesSpotlight()
 		}()
 	}
@@ -206,6 +354,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("old_files")
 			hs.scanOldFilesSpotlight()
 		}()
 	}
@@ -215,6 +364,7 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("docker")
 			hs.scanDockerCategory()
 		}()
 	}
@@ -224,27 +374,157 @@ func (hs *HyperScanner) ScanAll() (*ScanResult, error) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer hs.finishCategories("app_data")
 			hs.scanAppDataCategory()
 		}()
 	}
 
+	// Duplicates - hash same-size files
+	if hs.config.Categories.Duplicates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(DuplicatesCategory)
+			hs.scanDuplicatesCategory()
+		}()
+	}
+
+	// WSL - Windows-side leftovers, only under WSL
+	if hs.config.Categories.WSL {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(WSLCategory)
+			hs.scanWSLCategory()
+		}()
+	}
+
+	// Empty directories - left behind by earlier cleanups
+	if hs.config.Categories.EmptyDirs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(EmptyDirsCategory)
+			hs.scanEmptyDirsCategory()
+		}()
+	}
+
+	// Virtual machines - unused VMs, boxes and disk images
+	if hs.config.Categories.VMImages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(VMImagesCategory)
+			hs.scanVMImagesCategory()
+		}()
+	}
+
+	// Cloud CLIs - AWS, gcloud and Azure caches and logs
+	if hs.config.Categories.CloudCLI {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(CloudCLICategory)
+			hs.scanCloudCLICategory()
+		}()
+	}
+
+	// Desktop caches - font, icon and MIME type caches, only on Linux
+	if hs.config.Categories.DesktopCaches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(DesktopCachesCategory)
+			hs.scanDesktopCachesCategory()
+		}()
+	}
+
+	// Compiler caches - ccache, sccache and Bazel disk cache entries past
+	// their age or size limit
+	if hs.config.Categories.CompilerCaches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(CompilerCachesCategory)
+			hs.scanCompilerCachesCategory()
+		}()
+	}
+
+	// LaTeX - auxiliary files and output folders of old documents
+	if hs.config.Categories.LaTeX {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(LaTeXCategory)
+			hs.scanLaTeXCategory()
+		}()
+	}
+
+	// R and Julia - package caches and superseded package versions
+	if hs.config.Categories.RPackages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(RPackagesCategory)
+			hs.scanRPackagesCategory()
+		}()
+	}
+	if hs.config.Categories.Julia {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(JuliaCategory)
+			hs.scanJuliaCategory()
+		}()
+	}
+
+	// Stale clones - git repositories no one has touched in months
+	if hs.config.Categories.StaleRepos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(StaleReposCategory)
+			hs.scanStaleReposCategory()
+		}()
+	}
+
+	// Screenshots - old screenshots and screen recordings
+	if hs.config.Categories.Screenshots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(ScreenshotsCategory)
+			hs.scanScreenshotsCategory()
+		}()
+	}
+
+	// Downloads - old files at the top of the Downloads folder
+	if hs.config.Categories.Downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hs.finishCategories(DownloadsCategory)
+			hs.scanDownloadsCategory()
+		}()
+	}
+
 	wg.Wait()
 
-	// Save cache for next run
-	hs.saveCache()
+	// Save cache for next run, unless the scan was cut short
+	if !hs.cancelled() {
+		if err := hs.saveCache(); err != nil {
+			hs.cacheErrs = append(hs.cacheErrs, err)
+		}
+	}
 
-	return &ScanResult{
-		Files:      hs.results,
-		TotalSize:  atomic.LoadInt64(&hs.totalSize),
-		TotalCount: len(hs.results),
-	}, nil
+	return hs.results.result("", append(hs.takeCacheErrors(), hs.takeIgnoreErrors()...)), nil
 }
 
 // ScanCategory scans only one category
 func (hs *HyperScanner) ScanCategory(category string) *ScanResult {
-	atomic.StoreInt64(&hs.filesFound, 0)
-	atomic.StoreInt64(&hs.totalSize, 0)
-	hs.results = make([]FileInfo, 0, 5000)
+	hs.resetResults(5000)
+	hs.startProgress([]string{category})
+	hs.plan = hs.planDirs([]string{category})
 
 	switch category {
 	case "cache":
@@ -267,32 +547,52 @@ func (hs *HyperScanner) ScanCategory(category string) *ScanResult {
 		hs.scanDockerCategory()
 	case "app_data":
 		hs.scanAppDataCategory()
-	}
-
-	return &ScanResult{
-		Files:      hs.results,
-		TotalSize:  atomic.LoadInt64(&hs.totalSize),
-		TotalCount: len(hs.results),
-		Category:   category,
-	}
+	case DuplicatesCategory:
+		hs.scanDuplicatesCategory()
+	case WSLCategory:
+		hs.scanWSLCategory()
+	case EmptyDirsCategory:
+		hs.scanEmptyDirsCategory()
+	case VMImagesCategory:
+		hs.scanVMImagesCategory()
+	case CloudCLICategory:
+		hs.scanCloudCLICategory()
+	case DesktopCachesCategory:
+		hs.scanDesktopCachesCategory()
+	case CompilerCachesCategory:
+		hs.scanCompilerCachesCategory()
+	case LaTeXCategory:
+		hs.scanLaTeXCategory()
+	case RPackagesCategory:
+		hs.scanRPackagesCategory()
+	case JuliaCategory:
+		hs.scanJuliaCategory()
+	case StaleReposCategory:
+		hs.scanStaleReposCategory()
+	case ScreenshotsCategory:
+		hs.scanScreenshotsCategory()
+	case DownloadsCategory:
+		hs.scanDownloadsCategory()
+	}
+	hs.finishCategories(category)
+
+	return hs.dedupResult(hs.results.result(category, append(hs.takeCacheErrors(), hs.takeIgnoreErrors()...)))
 }
 
 // scanCacheCategory scans cache directories with mtime optimization
 func (hs *HyperScanner) scanCacheCategory() {
-	dirs := hs.getCacheDirs()
-	hs.scanDirsWithCache(dirs, "cache")
+	dirs := hs.plan.dirs["cache"]
+	hs.scanDirsExcluding(dirs, "cache", append(hs.excludedAppCaches(dirs), hs.plan.excluded["cache"]...))
 }
 
 // scanTempCategory scans temp directories
 func (hs *HyperScanner) scanTempCategory() {
-	dirs := hs.platformInfo.TempDirs
-	hs.scanDirsWithCache(dirs, "temp")
+	hs.scanDirsExcluding(hs.plan.dirs["temp"], "temp", hs.plan.excluded["temp"])
 }
 
 // scanLogsCategory scans log directories
 func (hs *HyperScanner) scanLogsCategory() {
-	dirs := hs.platformInfo.LogDirs
-	hs.scanDirsWithCache(dirs, "logs")
+	hs.scanDirsExcluding(hs.plan.dirs["logs"], "logs", hs.plan.excluded["logs"])
 }
 
 // scanDockerCategory scans Docker artifacts and unused containers/images
@@ -301,22 +601,23 @@ func (hs *HyperScanner) scanDockerCategory() {
 		return
 	}
 
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 
 	// Get Docker artifact directories based on platform
 	var dockerDirs []string
 
+	// The VM disks are left to FindVMDisks: deleting them loses everything
+	// in Docker, so they're pruned and compacted by the runtime instead
 	if runtime.GOOS == "darwin" {
-		// macOS Docker Desktop - scan specific subdirectories
-		basePath := filepath.Join(home, "Library/Containers/com.docker.docker/Data")
+		// macOS Docker Desktop - only its logs
 		dockerDirs = []string{
-			filepath.Join(basePath, "vms"),
-			filepath.Join(basePath, "log"),
+			filepath.Join(home, "Library/Containers/com.docker.docker/Data/log"),
 		}
 	} else if runtime.GOOS == "linux" {
-		// Linux Docker
+		// Linux Docker Desktop's logs; the rest of ~/.docker holds the
+		// client's config and credentials
 		dockerDirs = []string{
-			filepath.Join(home, ".docker"),
+			filepath.Join(home, ".docker/desktop/log"),
 		}
 		// Also try system Docker if not running as root
 		if os.Getuid() != 0 {
@@ -326,12 +627,17 @@ func (hs *HyperScanner) scanDockerCategory() {
 
 	// Scan Docker artifact directories and get total size
 	for _, dir := range dockerDirs {
-		if info, err := os.Stat(dir); err == nil {
+		// The runtime's own storage is left to docker's prune commands
+		if security.CheckProtectedLocation(dir) != nil {
+			continue
+		}
+		if info, err := hs.fs.Stat(dir); err == nil {
 			// For Docker directories, treat as a single item with the directory size
 			totalSize := hs.getDirSize(dir)
 			if totalSize > 0 {
 				// Add as a single "file" item for deletion
-				hs.addResult(dir, "docker", totalSize, info.ModTime())
+				hs.addResult(dir, "docker", totalSize, info.ModTime(),
+					fmt.Sprintf("Docker data, last modified %s ago", since(info.ModTime())))
 			}
 		}
 	}
@@ -345,7 +651,7 @@ func (hs *HyperScanner) scanDockerCategory() {
 // getDirSize recursively calculates directory size
 func (hs *HyperScanner) getDirSize(path string) int64 {
 	var size int64
-	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+	vfs.WalkDir(hs.fs, path, func(p string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -361,6 +667,10 @@ func (hs *HyperScanner) getDirSize(path string) int64 {
 
 // scanDockerCLI scans Docker artifacts using the Docker CLI
 func (hs *HyperScanner) scanDockerCLI() {
+	if !hs.native() {
+		return
+	}
+
 	// Check if docker command is available
 	_, err := exec.LookPath("docker")
 	if err != nil {
@@ -386,13 +696,16 @@ func (hs *HyperScanner) scanAppDataCategory() {
 		return
 	}
 
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 
 	// Expand paths
 	var scanDirs []string
 	for _, p := range hs.config.AppData.ScanPaths {
 		expanded := expandPath(p, home)
-		if _, err := os.Stat(expanded); err == nil {
+		if hs.onWindowsDrive(expanded) {
+			continue
+		}
+		if _, err := hs.fs.Stat(expanded); err == nil {
 			scanDirs = append(scanDirs, expanded)
 		}
 	}
@@ -401,8 +714,10 @@ func (hs *HyperScanner) scanAppDataCategory() {
 
 	// Scan directories for app data
 	for _, scanDir := range scanDirs {
-		entries, err := os.ReadDir(scanDir)
+		stop := hs.timeDir(scanDir, "app_data")
+		entries, err := hs.fs.ReadDir(scanDir)
 		if err != nil {
+			stop()
 			continue
 		}
 
@@ -438,10 +753,21 @@ func (hs *HyperScanner) scanAppDataCategory() {
 
 			// Calculate size
 			totalSize := hs.getDirSize(appPath)
-			if totalSize >= minBytes {
-				hs.addResult(appPath, "app_data", totalSize, time.Now())
+			if totalSize < minBytes {
+				continue
+			}
+			reason := fmt.Sprintf("App data matching app_data.cache_patterns, %s", utils.FormatBytes(totalSize))
+			if !isCacheDir {
+				reason = fmt.Sprintf("App data untouched for over %d days (app_data.max_age_days: %d)",
+					hs.config.AppData.MaxAgeDays, hs.config.AppData.MaxAgeDays)
+				if info, err := hs.fs.Stat(appPath); err == nil {
+					reason = fmt.Sprintf("App data untouched for %s (app_data.max_age_days: %d)",
+						since(info.ModTime()), hs.config.AppData.MaxAgeDays)
+				}
 			}
+			hs.addResult(appPath, "app_data", totalSize, time.Now(), reason)
 		}
+		stop()
 	}
 }
 
@@ -458,12 +784,12 @@ func (hs *HyperScanner) matchesPattern(name string, patterns []string) bool {
 // isAppDataSafeToClean analyzes directory structure to determine if it's safe to delete
 func (hs *HyperScanner) isAppDataSafeToClean(appPath string) bool {
 	// Scan directory for indicators
-	hasDatabase := false      // .db, .sqlite files suggest important data
-	hasSettings := false      // .plist, config files suggest app settings
-	hasCacheIndicators := 0   // Count cache-like subdirectories
-	hasDataIndicators := 0    // Count data-like subdirectories
+	hasDatabase := false    // .db, .sqlite files suggest important data
+	hasSettings := false    // .plist, config files suggest app settings
+	hasCacheIndicators := 0 // Count cache-like subdirectories
+	hasDataIndicators := 0  // Count data-like subdirectories
 
-	filepath.WalkDir(appPath, func(path string, d os.DirEntry, err error) error {
+	vfs.WalkDir(hs.fs, appPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -514,7 +840,7 @@ func (hs *HyperScanner) isAppDataSafeToClean(appPath string) bool {
 
 // isDirectoryOldEnough checks if directory hasn't been accessed recently
 func (hs *HyperScanner) isDirectoryOldEnough(dirPath string, maxDays int) bool {
-	info, err := os.Stat(dirPath)
+	info, err := hs.fs.Stat(dirPath)
 	if err != nil {
 		return false
 	}
@@ -526,59 +852,58 @@ func (hs *HyperScanner) isDirectoryOldEnough(dirPath string, maxDays int) bool {
 	return age > time.Duration(maxDays)*24*time.Hour
 }
 
-// parseSize converts size string like "500MB", "1GB" to bytes
+// parseSize converts size string like "500MB", "1GB" to bytes. Invalid
+// sizes, which Validate rejects, count as 0.
 func (hs *HyperScanner) parseSize(sizeStr string) int64 {
-	sizeStr = strings.TrimSpace(strings.ToUpper(sizeStr))
-
-	var multiplier int64 = 1
-	if strings.HasSuffix(sizeStr, "GB") {
-		multiplier = 1024 * 1024 * 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "GB")
-	} else if strings.HasSuffix(sizeStr, "MB") {
-		multiplier = 1024 * 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "MB")
-	} else if strings.HasSuffix(sizeStr, "KB") {
-		multiplier = 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "KB")
-	}
-
-	num, err := strconv.ParseFloat(strings.TrimSpace(sizeStr), 64)
+	size, err := utils.ParseSize(sizeStr)
 	if err != nil {
 		return 0
 	}
-
-	return int64(num * float64(multiplier))
+	return size
 }
 
 // scanDirsWithCache scans directories using mtime caching
 func (hs *HyperScanner) scanDirsWithCache(dirs []string, category string) {
+	hs.scanDirsExcluding(dirs, category, nil)
+}
+
+// scanDirsExcluding is scanDirsWithCache, leaving out the directories in
+// excluded and everything below them
+func (hs *HyperScanner) scanDirsExcluding(dirs []string, category string, excluded []string) {
 	var wg sync.WaitGroup
 
 	for _, dir := range dirs {
-		if _, err := os.Stat(dir); err != nil {
+		if hs.onWindowsDrive(dir) {
+			continue
+		}
+		if _, err := hs.fs.Stat(dir); err != nil {
 			continue
 		}
 
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
-			hs.scanDirOptimized(d, category)
+			defer hs.timeDir(d, category)()
+			hs.scanDirOptimized(d, category, excluded)
 		}(dir)
 	}
 
 	wg.Wait()
 }
 
-// scanDirOptimized scans a directory with mtime-based caching
-func (hs *HyperScanner) scanDirOptimized(dir, category string) {
+// scanDirOptimized scans a directory with mtime-based caching. A directory
+// with excluded ones below it is always walked: its cached result stands for
+// the whole directory, excluded ones included.
+func (hs *HyperScanner) scanDirOptimized(dir, category string, excluded []string) {
 	// Check if directory has changed since last scan
-	info, err := os.Stat(dir)
+	info, err := hs.fs.Stat(dir)
 	if err != nil {
 		return
 	}
 
 	dirMtime := info.ModTime()
 	cacheKey := fmt.Sprintf("%s:%s", dir, category)
+	cacheable := !containsAny(dir, excluded)
 
 	// Check cache (read lock)
 	hs.cacheMu.RLock()
@@ -586,7 +911,7 @@ func (hs *HyperScanner) scanDirOptimized(dir, category string) {
 	cached, hasCached := hs.cache.DirResults[cacheKey]
 	hs.cacheMu.RUnlock()
 
-	if hasMtime && !dirMtime.After(cachedMtime) && hasCached {
+	if cacheable && hasMtime && !dirMtime.After(cachedMtime) && hasCached {
 		// Directory unchanged, use cached results
 		hs.addCachedResult(cached)
 		return
@@ -597,7 +922,10 @@ func (hs *HyperScanner) scanDirOptimized(dir, category string) {
 	var fileCount int
 
 	hs.sem <- struct{}{}
-	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+	vfs.WalkDir(hs.fs, dir, func(path string, d os.DirEntry, err error) error {
+		if hs.cancelled() {
+			return filepath.SkipAll
+		}
 		if err != nil {
 			return nil
 		}
@@ -606,6 +934,9 @@ func (hs *HyperScanner) scanDirOptimized(dir, category string) {
 			if len(name) > 0 && name[0] == '.' && name != ".cache" && name != ".npm" {
 				return filepath.SkipDir
 			}
+			if underAny(path, excluded) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -623,11 +954,15 @@ func (hs *HyperScanner) scanDirOptimized(dir, category string) {
 		totalSize += info.Size()
 		fileCount++
 
-		hs.addResult(path, category, info.Size(), info.ModTime())
+		hs.addResult(path, category, info.Size(), info.ModTime(), hs.walkReason(category, info.ModTime()))
 		return nil
 	})
 	<-hs.sem
 
+	if !cacheable || hs.cancelled() {
+		return
+	}
+
 	// Update cache (write lock)
 	hs.cacheMu.Lock()
 	hs.cache.DirMtimes[cacheKey] = dirMtime
@@ -643,12 +978,15 @@ func (hs *HyperScanner) scanDirOptimized(dir, category string) {
 
 // scanDevArtifacts uses smart detection for dev artifacts
 func (hs *HyperScanner) scanDevArtifacts() {
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 	devDirs := make([]string, 0)
 
 	for _, d := range hs.config.Dev.ProjectDirs {
 		d = expandPath(d, home)
-		if _, err := os.Stat(d); err == nil {
+		if hs.onWindowsDrive(d) {
+			continue
+		}
+		if _, err := hs.fs.Stat(d); err == nil {
 			devDirs = append(devDirs, d)
 		}
 	}
@@ -658,6 +996,7 @@ func (hs *HyperScanner) scanDevArtifacts() {
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
+			defer hs.timeDir(d, "dev")()
 			hs.findDevArtifactsInDir(d)
 		}(dir)
 	}
@@ -666,12 +1005,15 @@ func (hs *HyperScanner) scanDevArtifacts() {
 
 // scanDevArtifactsType scans for a specific type of dev artifact
 func (hs *HyperScanner) scanDevArtifactsType(artifactType string) {
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 	devDirs := make([]string, 0)
 
 	for _, d := range hs.config.Dev.ProjectDirs {
 		d = expandPath(d, home)
-		if _, err := os.Stat(d); err == nil {
+		if hs.onWindowsDrive(d) {
+			continue
+		}
+		if _, err := hs.fs.Stat(d); err == nil {
 			devDirs = append(devDirs, d)
 		}
 	}
@@ -681,6 +1023,7 @@ func (hs *HyperScanner) scanDevArtifactsType(artifactType string) {
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
+			defer hs.timeDir(d, "dev")()
 			hs.findDevArtifactsOfType(d, artifactType)
 		}(dir)
 	}
@@ -692,7 +1035,7 @@ func (hs *HyperScanner) findDevArtifactsInDir(dir string) {
 	// Check cache first - skip find command if dev dir hasn't changed
 	cacheKey := fmt.Sprintf("devdir:%s", dir)
 
-	info, err := os.Stat(dir)
+	info, err := hs.fs.Stat(dir)
 	if err != nil {
 		return
 	}
@@ -707,61 +1050,42 @@ func (hs *HyperScanner) findDevArtifactsInDir(dir string) {
 	// If we have cached artifacts and directory hasn't changed, use cache
 	if hasMtime && !dirMtime.After(cachedMtime) && hasPaths {
 		// Use cached artifact paths - super fast!
-		var artifactWg sync.WaitGroup
-		for _, path := range cachedPaths {
-			// Verify path still exists
-			if _, err := os.Stat(path); err == nil {
-				category := hs.categorizeArtifact(filepath.Base(path))
-				if category != "" {
-					artifactWg.Add(1)
-					go func(p, cat string) {
-						defer artifactWg.Done()
-						hs.addArtifactResult(p, cat)
-					}(path, category)
-				}
-			}
-		}
-		artifactWg.Wait()
+		hs.addArtifactResults(hs.projectArtifacts(cachedPaths, hs.projectArtifact))
 		return
 	}
 
-	// Directory changed or not cached - run find
-	patterns := []string{}
+	// find only sees the real file system
+	if !hs.native() {
+		hs.findDevArtifactsManual(dir, hs.projectArtifact)
+		return
+	}
 
+	// Directory changed or not cached - run find
+	var names, gated []string
 	if hs.config.Categories.NodeModules {
-		patterns = append(patterns, "-name", "node_modules", "-type", "d", "-o")
+		names = append(names, "node_modules")
 	}
 	if hs.config.Categories.VirtualEnvs {
-		patterns = append(patterns, "-name", "venv", "-type", "d", "-o", "-name", ".venv", "-type", "d", "-o",
-			"-name", "virtualenv", "-type", "d", "-o")
+		names = append(names, "venv", ".venv", "virtualenv")
 	}
 	if hs.config.Categories.BuildArtifacts {
-		patterns = append(patterns, "-name", "dist", "-type", "d", "-o", "-name", "build", "-type", "d", "-o",
-			"-name", ".next", "-type", "d", "-o", "-name", "__pycache__", "-type", "d", "-o",
-			"-name", "target", "-type", "d", "-o", "-name", ".gradle", "-type", "d", "-o",
-			"-name", "out", "-type", "d", "-o")
+		names = append(names, "dist", "build", ".next", "__pycache__", "target", ".gradle", "out")
+		gated = gatedArtifactNames()
 	}
 
-	if len(patterns) == 0 {
+	if len(names) == 0 {
 		return
 	}
 
-	// Remove trailing -o
-	patterns = patterns[:len(patterns)-1]
-
 	// Build find command with pruning for speed
-	args := []string{dir, "-maxdepth", "6", "("}
-	args = append(args, patterns...)
-	args = append(args, ")", "-prune", "-print")
-
-	cmd := exec.Command("find", args...)
+	cmd := exec.Command("find", findArtifactArgs(dir, names, gated)...)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = nil
 
 	if err := cmd.Run(); err != nil {
 		// Fallback to manual scan
-		hs.findDevArtifactsManual(dir)
+		hs.findDevArtifactsManual(dir, hs.projectArtifact)
 		return
 	}
 
@@ -776,19 +1100,7 @@ func (hs *HyperScanner) findDevArtifactsInDir(dir string) {
 		}
 	}
 
-	// Process artifacts in parallel for faster du calls
-	var artifactWg sync.WaitGroup
-	for _, line := range foundPaths {
-		category := hs.categorizeArtifact(filepath.Base(line))
-		if category != "" {
-			artifactWg.Add(1)
-			go func(p, cat string) {
-				defer artifactWg.Done()
-				hs.addArtifactResult(p, cat)
-			}(line, category)
-		}
-	}
-	artifactWg.Wait()
+	hs.addArtifactResults(hs.projectArtifacts(foundPaths, hs.projectArtifact))
 
 	// Update cache with found artifact paths (write lock)
 	hs.cacheMu.Lock()
@@ -799,7 +1111,7 @@ func (hs *HyperScanner) findDevArtifactsInDir(dir string) {
 
 // findDevArtifactsOfType finds artifacts of a specific type
 func (hs *HyperScanner) findDevArtifactsOfType(dir, artifactType string) {
-	var names []string
+	var names, gated []string
 	var category string
 
 	switch artifactType {
@@ -811,13 +1123,25 @@ func (hs *HyperScanner) findDevArtifactsOfType(dir, artifactType string) {
 		category = "virtual_envs"
 	case "build":
 		names = []string{"dist", "build", ".next", "__pycache__", "target", ".gradle", "out"}
+		gated = gatedArtifactNames()
 		category = "build_artifacts"
 	}
+	categorize := func(dir string) (string, string) {
+		if slices.Contains(names, filepath.Base(dir)) {
+			return dir, category
+		}
+		if len(gated) > 0 {
+			if path := hs.gatedArtifact(dir); path != "" {
+				return path, category
+			}
+		}
+		return "", ""
+	}
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("devdir:%s:%s", dir, artifactType)
 
-	info, err := os.Stat(dir)
+	info, err := hs.fs.Stat(dir)
 	if err != nil {
 		return
 	}
@@ -830,31 +1154,17 @@ func (hs *HyperScanner) findDevArtifactsOfType(dir, artifactType string) {
 	hs.cacheMu.RUnlock()
 
 	if hasMtime && !dirMtime.After(cachedMtime) && hasPaths {
-		var artifactWg sync.WaitGroup
-		for _, path := range cachedPaths {
-			if _, err := os.Stat(path); err == nil {
-				artifactWg.Add(1)
-				go func(p string) {
-					defer artifactWg.Done()
-					hs.addArtifactResult(p, category)
-				}(path)
-			}
-		}
-		artifactWg.Wait()
+		hs.addArtifactResults(hs.projectArtifacts(cachedPaths, categorize))
 		return
 	}
 
-	// Run find command
-	args := []string{dir, "-maxdepth", "6", "("}
-	for i, name := range names {
-		if i > 0 {
-			args = append(args, "-o")
-		}
-		args = append(args, "-name", name, "-type", "d")
+	if !hs.native() {
+		hs.findDevArtifactsManual(dir, categorize)
+		return
 	}
-	args = append(args, ")", "-prune", "-print")
 
-	cmd := exec.Command("find", args...)
+	// Run find command
+	cmd := exec.Command("find", findArtifactArgs(dir, names, gated)...)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = nil
@@ -871,16 +1181,7 @@ func (hs *HyperScanner) findDevArtifactsOfType(dir, artifactType string) {
 		}
 	}
 
-	// Process artifacts in parallel for faster du calls
-	var artifactWg sync.WaitGroup
-	for _, line := range foundPaths {
-		artifactWg.Add(1)
-		go func(p string) {
-			defer artifactWg.Done()
-			hs.addArtifactResult(p, category)
-		}(line)
-	}
-	artifactWg.Wait()
+	hs.addArtifactResults(hs.projectArtifacts(foundPaths, categorize))
 
 	// Update cache (write lock)
 	hs.cacheMu.Lock()
@@ -889,10 +1190,15 @@ func (hs *HyperScanner) findDevArtifactsOfType(dir, artifactType string) {
 	hs.cacheMu.Unlock()
 }
 
-// findDevArtifactsManual fallback manual scan
-func (hs *HyperScanner) findDevArtifactsManual(dir string) {
+// findDevArtifactsManual fallback manual scan. categorize returns the
+// artifact a directory is or holds and its category, or "" for both to look
+// inside it.
+func (hs *HyperScanner) findDevArtifactsManual(dir string, categorize func(dir string) (string, string)) {
 	var wg sync.WaitGroup
 	var depth int32
+	var mu sync.Mutex
+	var artifacts []artifact
+	gatedNames := gatedArtifactNames()
 
 	var scanDir func(path string)
 	scanDir = func(path string) {
@@ -906,7 +1212,7 @@ func (hs *HyperScanner) findDevArtifactsManual(dir string) {
 		}
 
 		hs.sem <- struct{}{}
-		entries, err := os.ReadDir(path)
+		entries, err := hs.fs.ReadDir(path)
 		<-hs.sem
 
 		if err != nil {
@@ -919,15 +1225,20 @@ func (hs *HyperScanner) findDevArtifactsManual(dir string) {
 			}
 
 			name := entry.Name()
-			if len(name) > 0 && name[0] == '.' && name != ".venv" && name != ".next" {
+			if len(name) > 0 && name[0] == '.' && name != ".venv" && name != ".next" && !slices.Contains(gatedNames, name) {
 				continue
 			}
 
 			fullPath := filepath.Join(path, name)
-			category := hs.categorizeArtifact(name)
+			if hs.onWindowsDrive(fullPath) {
+				continue
+			}
+			artifactPath, category := categorize(fullPath)
 
-			if category != "" {
-				hs.addArtifactResult(fullPath, category)
+			if category != "" && !hs.checkout(fullPath) {
+				mu.Lock()
+				artifacts = append(artifacts, artifact{path: artifactPath, category: category})
+				mu.Unlock()
 			} else {
 				wg.Add(1)
 				go scanDir(fullPath)
@@ -938,6 +1249,8 @@ func (hs *HyperScanner) findDevArtifactsManual(dir string) {
 	wg.Add(1)
 	go scanDir(dir)
 	wg.Wait()
+
+	hs.addArtifactResults(artifacts)
 }
 
 // categorizeArtifact returns the category for a dev artifact directory
@@ -961,8 +1274,13 @@ func (hs *HyperScanner) categorizeArtifact(name string) string {
 
 // scanLargeFilesSpotlight uses Spotlight for fast large file discovery on macOS
 func (hs *HyperScanner) scanLargeFilesSpotlight() {
+	if !hs.native() || !platform.HasSpotlight() {
+		hs.scanLargeFilesManual()
+		return
+	}
+
 	minSize := hs.parseSize(hs.config.LargeFiles.MinSize)
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 
 	// Use mdfind (Spotlight) on macOS for instant results
 	// Query: files larger than minSize in home directory
@@ -973,11 +1291,14 @@ func (hs *HyperScanner) scanLargeFilesSpotlight() {
 	cmd.Stdout = &out
 	cmd.Stderr = nil
 
+	stop := hs.timeDir(home, "large_files")
 	if err := cmd.Run(); err != nil {
+		stop()
 		// Fallback to manual scan
 		hs.scanLargeFilesManual()
 		return
 	}
+	defer stop()
 
 	for _, line := range strings.Split(out.String(), "\n") {
 		line = strings.TrimSpace(line)
@@ -988,7 +1309,7 @@ func (hs *HyperScanner) scanLargeFilesSpotlight() {
 		// Skip excluded paths
 		skip := false
 		for _, excl := range hs.config.LargeFiles.ExcludePaths {
-			excl = expandPath(excl, home)
+			excl = expandPath(excl, hs.configHome())
 			if strings.HasPrefix(line, excl) {
 				skip = true
 				break
@@ -998,31 +1319,35 @@ func (hs *HyperScanner) scanLargeFilesSpotlight() {
 			continue
 		}
 
-		info, err := os.Stat(line)
+		info, err := hs.fs.Stat(line)
 		if err != nil || info.IsDir() {
 			continue
 		}
 
-		hs.addResult(line, "large_files", info.Size(), info.ModTime())
+		hs.addLargeFile(line, info.Size(), info.ModTime())
 	}
 }
 
 // scanLargeFilesManual fallback for non-macOS or when Spotlight fails
 func (hs *HyperScanner) scanLargeFilesManual() {
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 	minSize := hs.parseSize(hs.config.LargeFiles.MinSize)
 
 	for _, scanPath := range hs.config.LargeFiles.ScanPaths {
 		scanPath = expandPath(scanPath, home)
+		stop := hs.timeDir(scanPath, "large_files")
 
-		filepath.WalkDir(scanPath, func(path string, d os.DirEntry, err error) error {
+		vfs.WalkDir(hs.fs, scanPath, func(path string, d os.DirEntry, err error) error {
+			if hs.cancelled() {
+				return filepath.SkipAll
+			}
 			if err != nil {
 				return nil
 			}
 
 			// Skip excluded paths
 			for _, excl := range hs.config.LargeFiles.ExcludePaths {
-				excl = expandPath(excl, home)
+				excl = expandPath(excl, hs.configHome())
 				if strings.HasPrefix(path, excl) {
 					if d.IsDir() {
 						return filepath.SkipDir
@@ -1030,6 +1355,9 @@ func (hs *HyperScanner) scanLargeFilesManual() {
 					return nil
 				}
 			}
+			if d.IsDir() && hs.onWindowsDrive(path) {
+				return filepath.SkipDir
+			}
 
 			if d.IsDir() {
 				return nil
@@ -1041,11 +1369,12 @@ func (hs *HyperScanner) scanLargeFilesManual() {
 			}
 
 			if info.Size() >= minSize {
-				hs.addResult(path, "large_files", info.Size(), info.ModTime())
+				hs.addLargeFile(path, info.Size(), info.ModTime())
 			}
 
 			return nil
 		})
+		stop()
 	}
 }
 
@@ -1053,7 +1382,7 @@ func (hs *HyperScanner) scanLargeFilesManual() {
 func (hs *HyperScanner) scanOldFilesSpotlight() {
 	minAgeDays := hs.config.OldFiles.MinAgeDays
 	cutoff := time.Now().AddDate(0, 0, -minAgeDays)
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 
 	// Use mdfind for files not accessed since cutoff
 	// kMDItemLastUsedDate < cutoff
@@ -1061,13 +1390,19 @@ func (hs *HyperScanner) scanOldFilesSpotlight() {
 
 	for _, scanPath := range hs.config.OldFiles.ScanPaths {
 		scanPath = expandPath(scanPath, home)
+		if !hs.native() || !platform.HasSpotlight() {
+			hs.scanOldFilesManual(scanPath)
+			continue
+		}
 
 		cmd := exec.Command("mdfind", "-onlyin", scanPath, query)
 		var out bytes.Buffer
 		cmd.Stdout = &out
 		cmd.Stderr = nil
 
+		stop := hs.timeDir(scanPath, "old_files")
 		if err := cmd.Run(); err != nil {
+			stop()
 			// Fallback to manual scan for this path
 			hs.scanOldFilesManual(scanPath)
 			continue
@@ -1079,168 +1414,180 @@ func (hs *HyperScanner) scanOldFilesSpotlight() {
 				continue
 			}
 
-			info, err := os.Stat(line)
+			info, err := hs.fs.Stat(line)
 			if err != nil || info.IsDir() {
 				continue
 			}
 
-			hs.addResult(line, "old_files", info.Size(), info.ModTime())
+			hs.addResult(line, "old_files", info.Size(), info.ModTime(),
+				fmt.Sprintf("Not opened since before %s (old_files.min_age_days: %d)", cutoff.Format("2006-01-02"), minAgeDays))
 		}
+		stop()
 	}
 }
 
-// scanOldFilesManual fallback for old files scanning
+// scanOldFilesManual fallback for old files scanning. Directories with
+// nothing modified since the cutoff are reported as one entry.
 func (hs *HyperScanner) scanOldFilesManual(dir string) {
-	minAgeDays := hs.config.OldFiles.MinAgeDays
-	cutoff := time.Now().AddDate(0, 0, -minAgeDays)
+	info, err := hs.fs.Lstat(dir)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	defer hs.timeDir(dir, "old_files")()
 
-	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
+	home := hs.configHome()
+	var excluded []string
+	for _, path := range hs.config.OldFiles.ExcludePaths {
+		excluded = append(excluded, expandPath(path, home))
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -hs.config.OldFiles.MinAgeDays)
+	hs.scanOldDir(dir, info, cutoff, excluded, true)
+}
+
+// oldTree sums up a directory scanned for old files
+type oldTree struct {
+	old    bool // The directory and everything under it predate the cutoff
+	newest time.Time
+	size   int64
+	files  int64
+}
+
+// scanOldDir reports the old files under dir. If dir turns out to be old
+// through and through, nothing under it is reported and the caller decides
+// what to do with it; the scan path itself (top) is never summed up.
+func (hs *HyperScanner) scanOldDir(dir string, info os.FileInfo, cutoff time.Time, excluded []string, top bool) oldTree {
+	tree := oldTree{old: !top && info.ModTime().Before(cutoff), newest: info.ModTime()}
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		// What can't be seen can't be vouched for
+		tree.old = false
+		return tree
+	}
+
+	// Old entries are held back until it's known whether dir is old too
+	var pending []FileInfo
+	var pendingFiles []int64
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if underAny(path, excluded) || (entry.IsDir() && hs.onWindowsDrive(path)) {
+			tree.old = false
+			continue
 		}
-		if d.IsDir() {
-			return nil
+		info, err := entry.Info()
+		if err != nil {
+			tree.old = false
+			continue
 		}
 
-		info, err := d.Info()
-		if err != nil {
-			return nil
+		var child oldTree
+		if entry.IsDir() {
+			child = hs.scanOldDir(path, info, cutoff, excluded, false)
+			// Empty directories are left to the empty_dirs category
+			if child.old && child.files > 0 {
+				pending = append(pending, FileInfo{
+					Path:     path,
+					Size:     child.size,
+					ModTime:  child.newest,
+					Category: "old_files",
+					Reason: fmt.Sprintf("Directory of %s, untouched for %s (old_files.min_age_days: %d)",
+						plural(int(child.files), "file"), since(child.newest), hs.config.OldFiles.MinAgeDays),
+				})
+				pendingFiles = append(pendingFiles, child.files)
+			}
+		} else {
+			child = oldTree{old: info.ModTime().Before(cutoff), newest: info.ModTime(), size: info.Size(), files: 1}
+			if child.old {
+				pending = append(pending, FileInfo{
+					Path:     path,
+					Size:     info.Size(),
+					ModTime:  info.ModTime(),
+					Category: "old_files",
+					Reason: fmt.Sprintf("Unmodified for %s (old_files.min_age_days: %d)",
+						since(info.ModTime()), hs.config.OldFiles.MinAgeDays),
+				})
+				pendingFiles = append(pendingFiles, 1)
+			}
 		}
 
-		if info.ModTime().Before(cutoff) {
-			hs.addResult(path, "old_files", info.Size(), info.ModTime())
+		if !child.old {
+			tree.old = false
 		}
+		if child.newest.After(tree.newest) {
+			tree.newest = child.newest
+		}
+		tree.size += child.size
+		tree.files += child.files
+	}
 
-		return nil
-	})
+	if !tree.old {
+		for i, file := range pending {
+			hs.appendResult(file, pendingFiles[i])
+		}
+	}
+	return tree
 }
 
 // addResult adds a file result
-func (hs *HyperScanner) addResult(path, category string, size int64, modTime time.Time) {
-	hs.resultMu.Lock()
-	hs.results = append(hs.results, FileInfo{
+func (hs *HyperScanner) addResult(path, category string, size int64, modTime time.Time, reason string) {
+	hs.appendResult(FileInfo{
 		Path:     path,
 		Size:     size,
 		ModTime:  modTime,
 		Category: category,
-		Reason:   "Matches cleanup criteria",
-	})
-	hs.resultMu.Unlock()
-
-	atomic.AddInt64(&hs.filesFound, 1)
-	atomic.AddInt64(&hs.totalSize, size)
-
-	if hs.progressCb != nil {
-		hs.progressCb(category, path, int(atomic.LoadInt64(&hs.filesFound)), atomic.LoadInt64(&hs.totalSize))
-	}
+		Reason:   reason,
+	}, 1)
 }
 
-// addArtifactResult adds a dev artifact directory result with caching
-func (hs *HyperScanner) addArtifactResult(path, category string) {
-	// First verify the path exists
-	if _, err := os.Stat(path); err != nil {
-		return // Skip non-existent paths
+// appendResult records a result, updates running totals and fires the progress callback.
+// filesFound is how many files the entry represents (cached entries may stand for many).
+func (hs *HyperScanner) appendResult(file FileInfo, filesFound int64) {
+	if hs.cancelled() {
+		return
 	}
-
-	cacheKey := fmt.Sprintf("artifact:%s", path)
-
-	// Check cache first (read lock)
-	hs.cacheMu.RLock()
-	cached, hasCached := hs.cache.DirResults[cacheKey]
-	cachedMtime, hasMtime := hs.cache.DirMtimes[cacheKey]
-	hs.cacheMu.RUnlock()
-
-	if hasCached {
-		// Verify directory hasn't changed
-		info, err := os.Stat(path)
-		if err == nil && hasMtime && !info.ModTime().After(cachedMtime) {
-			// Use cached result
-			hs.resultMu.Lock()
-			hs.results = append(hs.results, FileInfo{
-				Path:     cached.Path,
-				Size:     cached.TotalSize,
-				Category: category,
-				Reason:   fmt.Sprintf("Dev artifact: ~%d files (cached)", cached.FileCount),
-			})
-			hs.resultMu.Unlock()
-			atomic.AddInt64(&hs.filesFound, 1)
-			atomic.AddInt64(&hs.totalSize, cached.TotalSize)
-			return
-		}
+	// The analyzer shows everything, dismissed or not
+	if file.Category != AnalyzeCategory && hs.ignored.ignores(hs.fs, file.Path) {
+		return
 	}
-
-	// Quick size calculation using du - run with semaphore for parallelism
-	hs.sem <- struct{}{}
-
-	var size int64
-	var fileCount int
-
-	cmd := exec.Command("du", "-sk", path)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = nil
-
-	if err := cmd.Run(); err == nil {
-		parts := strings.Fields(out.String())
-		if len(parts) > 0 {
-			fmt.Sscanf(parts[0], "%d", &size)
-			size *= 1024 // du -sk returns KB
-		}
+	if hs.root != "" && !underAny(file.Path, []string{hs.root}) {
+		return
 	}
+	hs.annotate(&file)
+	hs.results.add(file, filesFound)
 
-	<-hs.sem
-
-	// Estimate file count from size (avg 10KB per file)
-	fileCount = int(size / (10 * 1024))
-	if fileCount < 1 {
-		fileCount = 1
-	}
+	hs.reportProgress(progress.PhaseScanning, file.Category, file.Path)
+}
 
-	// Update cache (write lock)
-	if info, err := os.Stat(path); err == nil {
-		hs.cacheMu.Lock()
-		hs.cache.DirMtimes[cacheKey] = info.ModTime()
-		hs.cache.DirResults[cacheKey] = &CachedDirInfo{
-			Path:      path,
-			TotalSize: size,
-			FileCount: fileCount,
-			Category:  category,
-			ScannedAt: time.Now(),
+// measureArtifact walks path and returns the exact size and number of the
+// regular files under it
+func (hs *HyperScanner) measureArtifact(path string) (size int64, fileCount int) {
+	vfs.WalkDir(hs.fs, path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
 		}
-		hs.cacheMu.Unlock()
-	}
-
-	hs.resultMu.Lock()
-	hs.results = append(hs.results, FileInfo{
-		Path:     path,
-		Size:     size,
-		Category: category,
-		Reason:   fmt.Sprintf("Dev artifact: ~%d files", fileCount),
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+			fileCount++
+		}
+		return nil
 	})
-	hs.resultMu.Unlock()
-
-	atomic.AddInt64(&hs.filesFound, 1)
-	atomic.AddInt64(&hs.totalSize, size)
+	return size, fileCount
 }
 
 // addCachedResult adds results from cache
 func (hs *HyperScanner) addCachedResult(cached *CachedDirInfo) {
-	hs.resultMu.Lock()
-	hs.results = append(hs.results, FileInfo{
+	hs.appendResult(FileInfo{
 		Path:     cached.Path,
 		Size:     cached.TotalSize,
 		Category: cached.Category,
-		Reason:   fmt.Sprintf("Cached: %d files", cached.FileCount),
-	})
-	hs.resultMu.Unlock()
-
-	atomic.AddInt64(&hs.filesFound, int64(cached.FileCount))
-	atomic.AddInt64(&hs.totalSize, cached.TotalSize)
+		Reason:   hs.cachedWalkReason(cached.Category, cached.FileCount),
+		Files:    cached.FileCount,
+	}, int64(cached.FileCount))
 }
 
 // getCacheDirs returns cache directories
 func (hs *HyperScanner) getCacheDirs() []string {
-	home, _ := os.UserHomeDir()
+	home := hs.homeDir()
 	dirs := []string{
 		filepath.Join(home, "Library", "Caches"),
 		filepath.Join(home, ".cache"),
@@ -1248,15 +1595,10 @@ func (hs *HyperScanner) getCacheDirs() []string {
 		filepath.Join(home, "go", "pkg", "mod", "cache"),
 	}
 	dirs = append(dirs, hs.platformInfo.CacheDirs...)
-	dirs = append(dirs, hs.platformInfo.SystemCaches...)
 
-	result := make([]string, 0, len(dirs))
-	for _, d := range dirs {
-		if _, err := os.Stat(d); err == nil {
-			result = append(result, d)
-		}
-	}
-	return result
+	// Platforms list ~/.cache and folders in it too; planDirs leaves out the
+	// ones below another so their files aren't reported twice
+	return append(dirs, hs.platformInfo.SystemCaches...)
 }
 
 // dirChecksum creates a quick checksum for directory validation
@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// ignoreMagic starts every ignore list file, followed by the SHA-256 of the
+// JSON-encoded entries and then the entries themselves
+const ignoreMagic = "TIDYIG01"
+
+// IgnoreEntry is a file or directory the user dismissed for good
+type IgnoreEntry struct {
+	Path  string    `json:"path"`
+	Inode uint64    `json:"inode,omitempty"` // 0 if unknown, when the path alone matches
+	Added time.Time `json:"added"`
+}
+
+// IgnoreList holds the files that are never flagged again, unlike whitelist
+// paths a list of individual files kept by tidyup. An entry matches the same
+// path with the same inode, so a new file created in its place is flagged.
+// Adding and removing entries isn't safe while a scan is reading the list.
+type IgnoreList struct {
+	path    string
+	entries map[string]IgnoreEntry
+}
+
+// IgnoreListPath returns where the ignore list is kept
+func IgnoreListPath() string {
+	return paths.File(paths.DataDir, "ignored")
+}
+
+// LoadIgnoreList reads the ignore list at path. A list that doesn't exist yet
+// is empty; one that fails its checksum is an error, rather than silently
+// flagging everything in it again.
+func LoadIgnoreList(path string) (*IgnoreList, error) {
+	list := &IgnoreList{path: path, entries: make(map[string]IgnoreEntry)}
+	if path == "" {
+		return list, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return list, fmt.Errorf("failed to read ignore list: %w", err)
+	}
+
+	header := len(ignoreMagic) + sha256.Size
+	if len(data) < header || string(data[:len(ignoreMagic)]) != ignoreMagic {
+		return list, fmt.Errorf("%s is not an ignore list", path)
+	}
+	sum := sha256.Sum256(data[header:])
+	if !bytes.Equal(sum[:], data[len(ignoreMagic):header]) {
+		return list, fmt.Errorf("ignore list %s is corrupt: checksum mismatch", path)
+	}
+	var entries []IgnoreEntry
+	if err := json.Unmarshal(data[header:], &entries); err != nil {
+		return list, fmt.Errorf("ignore list %s is corrupt: %w", path, err)
+	}
+	for _, entry := range entries {
+		list.entries[entry.Path] = entry
+	}
+	return list, nil
+}
+
+// Save writes the list back to where it was loaded from, replacing the old
+// file in a single rename
+func (l *IgnoreList) Save() error {
+	if l.path == "" {
+		return nil
+	}
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	unlock, err := platform.LockFile(l.path + ".lock")
+	if err != nil && !errors.Is(err, platform.ErrUnsupportedPlatform) {
+		return err
+	}
+	if unlock != nil {
+		defer unlock()
+	}
+
+	payload, err := json.MarshalIndent(l.Entries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode ignore list: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	data := make([]byte, 0, len(ignoreMagic)+len(sum)+len(payload))
+	data = append(data, ignoreMagic...)
+	data = append(data, sum[:]...)
+	data = append(data, payload...)
+
+	f, err := os.CreateTemp(dir, filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write ignore list: %w", err)
+	}
+	defer os.Remove(f.Name()) // Fails harmlessly once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write ignore list: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write ignore list: %w", err)
+	}
+	if err := os.Rename(f.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to replace ignore list: %w", err)
+	}
+	return nil
+}
+
+// Add dismisses path, recording its inode if it exists. Adding it again
+// records the inode of whatever is there now.
+func (l *IgnoreList) Add(path string) (IgnoreEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return IgnoreEntry{}, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	entry := IgnoreEntry{Path: abs, Added: time.Now()}
+	if _, ino, err := platform.FileID(abs); err == nil {
+		entry.Inode = ino
+	} else if !errors.Is(err, platform.ErrUnsupportedPlatform) {
+		return IgnoreEntry{}, err
+	}
+	l.entries[abs] = entry
+	return entry, nil
+}
+
+// Remove forgets path, reporting whether it was in the list
+func (l *IgnoreList) Remove(path string) bool {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	_, ok := l.entries[path]
+	delete(l.entries, path)
+	return ok
+}
+
+// Entries returns the entries sorted by path
+func (l *IgnoreList) Entries() []IgnoreEntry {
+	entries := make([]IgnoreEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// Len returns the number of entries
+func (l *IgnoreList) Len() int {
+	return len(l.entries)
+}
+
+// ignores reports whether a result at path was dismissed. Inodes are only
+// compared on the real file system.
+func (l *IgnoreList) ignores(fsys vfs.FS, path string) bool {
+	if l == nil {
+		return false
+	}
+	entry, ok := l.entries[path]
+	if !ok {
+		return false
+	}
+	if entry.Inode == 0 || !vfs.IsOS(fsys) {
+		return true
+	}
+	_, ino, err := platform.FileID(path)
+	return err == nil && ino == entry.Inode
+}
+
+// takeIgnoreErrors returns the problem reading the ignore list, if it's not
+// been reported yet
+func (hs *HyperScanner) takeIgnoreErrors() []error {
+	err := hs.ignoreErr
+	hs.ignoreErr = nil
+	if err == nil {
+		return nil
+	}
+	return []error{err}
+}
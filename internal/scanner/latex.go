@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LaTeXCategory is the category for the auxiliary files and output trees
+// LaTeX leaves beside documents
+const LaTeXCategory = "latex"
+
+// latexMaxDepth is how deep below a scan path LaTeX projects are looked for
+const latexMaxDepth = 8
+
+// latexAuxExts are the files LaTeX, BibTeX, Biber, makeindex and latexmk
+// write beside a document, named after it. The PDF is kept.
+var latexAuxExts = []string{
+	".aux", ".bbl", ".bcf", ".blg", ".fdb_latexmk", ".fls", ".log", ".lof", ".lot",
+	".nav", ".out", ".run.xml", ".snm", ".synctex.gz", ".toc", ".vrb", ".xdv", ".dvi",
+	".idx", ".ilg", ".ind", ".glo", ".gls", ".glg", ".ist", ".acn", ".acr", ".alg",
+}
+
+// latexAuxDirs are directories packages write beside a document: minted's
+// highlighting cache and svg's converted figures
+var latexAuxDirs = []string{"_minted", "svg-inkscape"}
+
+// scanLaTeXCategory finds LaTeX auxiliary files untouched for
+// latex_config.min_age_days in latex_config.scan_paths and dev.project_dirs.
+// A file only counts beside the .tex document it's named after, so logs and
+// tables of contents of anything else are left alone.
+func (hs *HyperScanner) scanLaTeXCategory() {
+	home := hs.homeDir()
+	cutoff := time.Now().AddDate(0, 0, -hs.config.LaTeX.MinAgeDays)
+
+	seen := make(map[string]bool)
+	for _, dir := range append(append([]string{}, hs.config.LaTeX.ScanPaths...), hs.config.Dev.ProjectDirs...) {
+		dir = expandPath(dir, home)
+		if seen[dir] || hs.onWindowsDrive(dir) {
+			continue
+		}
+		seen[dir] = true
+		if info, err := hs.fs.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		stop := hs.timeDir(dir, LaTeXCategory)
+		hs.scanLaTeXDir(dir, cutoff, 0)
+		stop()
+	}
+}
+
+// scanLaTeXDir reports the auxiliary files of the documents in dir, and
+// looks for more documents below it
+func (hs *HyperScanner) scanLaTeXDir(dir string, cutoff time.Time, depth int) {
+	if hs.cancelled() {
+		return
+	}
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	documents := make(map[string]bool)
+	for _, entry := range entries {
+		if stem, ok := strings.CutSuffix(entry.Name(), ".tex"); ok && !entry.IsDir() {
+			documents[stem] = true
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() {
+			if strings.HasPrefix(name, ".") || name == "node_modules" || hs.onWindowsDrive(path) {
+				continue
+			}
+			if len(documents) > 0 && (latexAuxDir(name) || hs.latexOutputTree(path, documents)) {
+				hs.addLaTeXTree(path, cutoff)
+			} else if depth < latexMaxDepth {
+				hs.scanLaTeXDir(path, cutoff, depth+1)
+			}
+			continue
+		}
+
+		stem, ok := latexAuxStem(name)
+		if !ok || !documents[stem] || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		hs.appendResult(FileInfo{
+			Path:     path,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Category: LaTeXCategory,
+			Reason: fmt.Sprintf("LaTeX auxiliary file of %s.tex, untouched for %s (latex_config.min_age_days: %d)",
+				stem, since(info.ModTime()), hs.config.LaTeX.MinAgeDays),
+		}, 1)
+	}
+}
+
+// latexAuxStem returns the document an auxiliary file is named after
+func latexAuxStem(name string) (string, bool) {
+	for _, ext := range latexAuxExts {
+		if stem, ok := strings.CutSuffix(name, ext); ok && stem != "" {
+			return stem, true
+		}
+	}
+	return "", false
+}
+
+// latexAuxDir reports whether name is a directory a LaTeX package writes,
+// e.g. "_minted-thesis"
+func latexAuxDir(name string) bool {
+	for _, prefix := range latexAuxDirs {
+		if name == prefix || strings.HasPrefix(name, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// latexOutputTree reports whether dir is where latexmk -outdir or -auxdir
+// put the output of one of documents, the ones in its parent: it holds the
+// document's .aux or .fls and no documents of its own
+func (hs *HyperScanner) latexOutputTree(dir string, documents map[string]bool) bool {
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	found := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tex") {
+			return false
+		}
+		for _, ext := range []string{".aux", ".fls"} {
+			if stem, ok := strings.CutSuffix(name, ext); ok && documents[stem] {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// addLaTeXTree reports a directory of LaTeX output if nothing in it was
+// touched since cutoff
+func (hs *HyperScanner) addLaTeXTree(dir string, cutoff time.Time) {
+	size, files, newest := hs.dirUsage(dir)
+	if files == 0 || !newest.Before(cutoff) {
+		return
+	}
+	hs.appendResult(FileInfo{
+		Path:     dir,
+		Size:     size,
+		ModTime:  newest,
+		Category: LaTeXCategory,
+		Reason: fmt.Sprintf("LaTeX output of %s, untouched for %s: %s (latex_config.min_age_days: %d)",
+			filepath.Base(filepath.Dir(dir)), since(newest), plural(files, "file"), hs.config.LaTeX.MinAgeDays),
+		Files: files,
+	}, int64(files))
+}
@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// dedupResult drops the results that are the same file as another: the same
+// cleaned path, or, on the real file system, the same device and inode, as
+// when a directory is reached through a symlink or a bind mount. Of each set
+// the one in the category that comes first in clean.order is kept, so its
+// size is counted once and the cleaner doesn't try to delete it twice.
+func (hs *HyperScanner) dedupResult(r *ScanResult) *ScanResult {
+	rank := make(map[string]int, len(hs.config.Clean.Order))
+	for i, category := range hs.config.Clean.Order {
+		rank[category] = i
+	}
+	rankOf := func(category string) int {
+		if r, ok := rank[category]; ok {
+			return r
+		}
+		return len(rank)
+	}
+
+	type entry struct {
+		index    int
+		category string
+	}
+	best := make(map[string]*entry)
+	drop := make(map[int]bool)
+	i := 0
+	r.Each(func(file FileInfo) error {
+		keys := hs.fileKeys(file.Path)
+		var kept *entry
+		for _, key := range keys {
+			if kept = best[key]; kept != nil {
+				break
+			}
+		}
+		switch {
+		case kept == nil:
+			kept = &entry{i, file.Category}
+		case rankOf(file.Category) < rankOf(kept.category):
+			drop[kept.index] = true
+			*kept = entry{i, file.Category}
+		default:
+			drop[i] = true
+		}
+		for _, key := range keys {
+			best[key] = kept
+		}
+		i++
+		return nil
+	})
+	if len(drop) == 0 {
+		return r
+	}
+
+	i = 0
+	deduped := r.filter(func(FileInfo) bool {
+		keep := !drop[i]
+		i++
+		return keep
+	})
+	deduped.Duplicates = r.Duplicates
+	r.Close()
+	return deduped
+}
+
+// fileKeys identify the file at path: its cleaned path and, on the real file
+// system, its device and inode
+func (hs *HyperScanner) fileKeys(path string) []string {
+	keys := []string{filepath.Clean(path)}
+	if hs.native() {
+		if dev, ino, err := platform.FileID(path); err == nil {
+			keys = append(keys, fmt.Sprintf("%d:%d", dev, ino))
+		}
+	}
+	return keys
+}
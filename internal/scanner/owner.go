@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"io/fs"
+	"strconv"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// annotate fills in who owns file, how risky deleting it is and what
+// deleting it takes
+func (hs *HyperScanner) annotate(file *FileInfo) {
+	var info fs.FileInfo
+	fi, err := hs.fs.Lstat(file.Path)
+	if err == nil {
+		info = fi
+		if uid, gid, ok := platform.FileOwner(info); ok {
+			file.Owner = platform.UserName(uid)
+			file.Group = platform.GroupName(gid)
+		}
+	}
+	file.Risk = hs.assessRisk(*file, info)
+	file.Access = hs.assessAccess(*file, info, err)
+}
+
+// FilterOwners returns a new result containing only files owned by one of
+// owners, given as user names or numeric IDs. Duplicate groups, whose other
+// copies may belong to anyone, are left out; the copies the scanner picked
+// are kept like any other file.
+func (r *ScanResult) FilterOwners(owners []string) *ScanResult {
+	keep := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		keep[owner] = true
+		// A numeric ID also matches the name it stands for
+		if uid, err := strconv.ParseUint(owner, 10, 32); err == nil {
+			keep[platform.UserName(uint32(uid))] = true
+		}
+	}
+
+	return r.filter(func(file FileInfo) bool {
+		return keep[file.Owner]
+	})
+}
+
+// OwnerTotals returns the count and size of the results of each owner.
+// Results whose owner isn't known are left out.
+func (r *ScanResult) OwnerTotals() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		if file.Owner == "" {
+			return nil
+		}
+		total := totals[file.Owner]
+		total.Count++
+		total.Size += file.Size
+		totals[file.Owner] = total
+		return nil
+	})
+	return totals, err
+}
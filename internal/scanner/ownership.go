@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// ForeignOwned is a file or directory tree under the user's home that
+// someone else owns, such as a cache npm or Docker created while running as
+// root. Cleaning it takes sudo on every run until it's handed back.
+type ForeignOwned struct {
+	Path  string
+	Owner string // User name, or the numeric ID if it has none
+	Files int
+	Size  int64
+}
+
+// CacheRoots returns the cache directories under the user's home that
+// exist, leaving out the ones below another
+func (hs *HyperScanner) CacheRoots() []string {
+	home := hs.homeDir()
+	var roots []string
+	for _, pattern := range hs.getCacheDirs() {
+		paths, _ := vfs.Glob(hs.fs, pattern)
+		for _, path := range paths {
+			if path != home && underAny(path, []string{home}) {
+				roots = append(roots, filepath.Clean(path))
+			}
+		}
+	}
+	slices.Sort(roots)
+	var top []string
+	for _, root := range roots {
+		if underAny(root, top) {
+			continue
+		}
+		top = append(top, root)
+	}
+	return top
+}
+
+// FindForeignOwned finds the topmost files and directories under roots that
+// the owner of the home directory doesn't own. Roots outside the home
+// directory are skipped: handing those over isn't the user's to ask for.
+func (hs *HyperScanner) FindForeignOwned(roots []string) []ForeignOwned {
+	home := hs.homeDir()
+	homeInfo, err := hs.fs.Stat(home)
+	if err != nil {
+		return nil
+	}
+	uid, _, ok := platform.FileOwner(homeInfo)
+	if !ok {
+		return nil
+	}
+
+	var found []ForeignOwned
+	for _, root := range roots {
+		if root == home || !underAny(root, []string{home}) {
+			continue
+		}
+		vfs.WalkDir(hs.fs, root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := hs.fs.Lstat(path)
+			if err != nil {
+				return nil
+			}
+			owner, _, ok := platform.FileOwner(info)
+			if !ok || owner == uid {
+				return nil
+			}
+
+			tree := ForeignOwned{Path: path, Owner: platform.UserName(owner), Files: 1, Size: info.Size()}
+			if d.IsDir() {
+				tree.Files, tree.Size = hs.treeTotals(path)
+			}
+			found = append(found, tree)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		})
+	}
+	return found
+}
+
+// treeTotals counts the files under dir and adds up their size
+func (hs *HyperScanner) treeTotals(dir string) (files int, size int64) {
+	vfs.WalkDir(hs.fs, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// Categories for the package caches of R and Julia
+const (
+	RPackagesCategory = "r_packages"
+	JuliaCategory     = "julia"
+)
+
+// scanRPackagesCategory finds R's package caches: the files of pak and
+// other tools in R's user cache directory untouched for min_file_age, and in
+// renv's cache the package versions superseded by a newer one, and whole
+// builds for older versions of R, untouched for r_packages_config.min_age_days.
+func (hs *HyperScanner) scanRPackagesCategory() {
+	cacheDir := hs.rCacheDir()
+	renv := os.Getenv("RENV_PATHS_CACHE")
+	if renv == "" {
+		if root := os.Getenv("RENV_PATHS_ROOT"); root != "" {
+			renv = filepath.Join(root, "cache")
+		} else {
+			renv = filepath.Join(cacheDir, "renv", "cache")
+		}
+	}
+
+	// renv's cache is pruned by version below, not file by file
+	hs.scanDirsExcluding([]string{cacheDir}, RPackagesCategory, []string{filepath.Join(cacheDir, "renv"), renv})
+
+	cutoff := time.Now().AddDate(0, 0, -hs.config.RPackages.MinAgeDays)
+	defer hs.timeDir(renv, RPackagesCategory)()
+	for _, builds := range hs.renvBuilds(renv) {
+		// Packages built for older versions of R are only used by projects
+		// still on them
+		newest := slices.MaxFunc(builds, func(a, b string) int {
+			return compareVersions(strings.TrimPrefix(filepath.Base(a), "R-"), strings.TrimPrefix(filepath.Base(b), "R-"))
+		})
+		for _, build := range builds {
+			if build != newest {
+				hs.addDepotDir(build, RPackagesCategory, cutoff, fmt.Sprintf("renv cache for %s, superseded by %s",
+					filepath.Base(build), filepath.Base(newest)), hs.config.RPackages.MinAgeDays)
+			}
+		}
+
+		// <build>/<platform>/<package>/<version>
+		for _, pkg := range hs.globDirsIn(filepath.Join(newest, "*", "*")) {
+			hs.addOldVersions(pkg, RPackagesCategory, cutoff, "renv", hs.config.RPackages.MinAgeDays)
+		}
+	}
+}
+
+// rCacheDir returns the directory R packages keep their caches in, as
+// tools::R_user_dir finds it: renv, pak and pkgcache each have one in it
+func (hs *HyperScanner) rCacheDir() string {
+	if dir := os.Getenv("R_USER_CACHE_DIR"); dir != "" {
+		return filepath.Join(dir, "R")
+	}
+	if hs.platformInfo.OS == platform.MacOS {
+		return filepath.Join(hs.homeDir(), "Library", "Caches", "org.R-project.R", "R")
+	}
+	return filepath.Join(hs.userCacheDir(), "R")
+}
+
+// renvBuilds returns the builds in renv's cache for each version of R,
+// "R-4.3", grouped by the directory holding them. Newer versions of renv
+// put a platform directory above them.
+func (hs *HyperScanner) renvBuilds(renv string) [][]string {
+	groups := make(map[string][]string)
+	for _, pattern := range []string{"v*/R-*", "v*/*/R-*"} {
+		for _, build := range hs.globDirsIn(filepath.Join(renv, pattern)) {
+			parent := filepath.Dir(build)
+			groups[parent] = append(groups[parent], build)
+		}
+	}
+	var builds [][]string
+	for _, parent := range slices.Sorted(maps.Keys(groups)) {
+		builds = append(builds, groups[parent])
+	}
+	return builds
+}
+
+// scanJuliaCategory finds what Julia's depot keeps for versions no longer in
+// use, untouched for julia_config.min_age_days: precompiled packages for
+// older versions of Julia, and package versions superseded by a newer one.
+// Pkg.gc() knows which versions manifests still use; this goes by age.
+func (hs *HyperScanner) scanJuliaCategory() {
+	depot := filepath.Join(hs.homeDir(), ".julia")
+	// The first depot is the user's; an empty entry stands for the default
+	if paths := os.Getenv("JULIA_DEPOT_PATH"); paths != "" {
+		if first := strings.Split(paths, string(os.PathListSeparator))[0]; first != "" {
+			depot = first
+		}
+	}
+	if info, err := hs.fs.Stat(depot); err != nil || !info.IsDir() {
+		return
+	}
+	defer hs.timeDir(depot, JuliaCategory)()
+	cutoff := time.Now().AddDate(0, 0, -hs.config.Julia.MinAgeDays)
+
+	compiled := hs.globDirsIn(filepath.Join(depot, "compiled", "v*"))
+	if len(compiled) > 1 {
+		newest := slices.MaxFunc(compiled, func(a, b string) int {
+			return compareVersions(strings.TrimPrefix(filepath.Base(a), "v"), strings.TrimPrefix(filepath.Base(b), "v"))
+		})
+		for _, dir := range compiled {
+			if dir != newest {
+				hs.addDepotDir(dir, JuliaCategory, cutoff, fmt.Sprintf("Julia %s precompiled packages, superseded by %s",
+					filepath.Base(dir), filepath.Base(newest)), hs.config.Julia.MinAgeDays)
+			}
+		}
+	}
+
+	for _, pkg := range hs.globDirsIn(filepath.Join(depot, "packages", "*")) {
+		hs.addOldVersions(pkg, JuliaCategory, cutoff, "Julia", hs.config.Julia.MinAgeDays)
+	}
+}
+
+// addOldVersions reports the versions of a package, the directories in
+// pkg, other than the newest one
+func (hs *HyperScanner) addOldVersions(pkg, category string, cutoff time.Time, tool string, minAgeDays int) {
+	entries, err := hs.fs.ReadDir(pkg)
+	if err != nil {
+		return
+	}
+	type version struct {
+		path    string
+		modTime time.Time
+	}
+	var versions []version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			versions = append(versions, version{filepath.Join(pkg, entry.Name()), info.ModTime()})
+		}
+	}
+	if len(versions) < 2 {
+		return
+	}
+	// The one installed last stays
+	slices.SortFunc(versions, func(a, b version) int {
+		return cmp.Or(b.modTime.Compare(a.modTime), strings.Compare(a.path, b.path))
+	})
+	for _, v := range versions[1:] {
+		hs.addDepotDir(v.path, category, cutoff, fmt.Sprintf("%s %s %s, superseded by %s",
+			tool, filepath.Base(pkg), filepath.Base(v.path), filepath.Base(versions[0].path)), minAgeDays)
+	}
+}
+
+// addDepotDir reports a directory of a package depot if nothing in it was
+// touched since cutoff
+func (hs *HyperScanner) addDepotDir(dir, category string, cutoff time.Time, what string, minAgeDays int) {
+	size, files, newest := hs.dirUsage(dir)
+	if files == 0 || !newest.Before(cutoff) {
+		return
+	}
+	hs.appendResult(FileInfo{
+		Path:     dir,
+		Size:     size,
+		ModTime:  newest,
+		Category: category,
+		Reason: fmt.Sprintf("%s, untouched for %s: %s (%s_config.min_age_days: %d)",
+			what, since(newest), plural(files, "file"), category, minAgeDays),
+		Files: files,
+	}, int64(files))
+}
+
+// globDirsIn returns the directories matching pattern
+func (hs *HyperScanner) globDirsIn(pattern string) []string {
+	var dirs []string
+	hs.globDirs(pattern, "", func(dir string) {
+		dirs = append(dirs, dir)
+	})
+	return dirs
+}
+
+// compareVersions orders dotted version numbers, "4.10" after "4.9"
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
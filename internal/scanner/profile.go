@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"cmp"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// DirProfile is how long a scan spent in one of the directories it starts
+// from, such as a cache directory, a project directory or a large files scan
+// path
+type DirProfile struct {
+	Path       string
+	Categories []string      // The categories that scanned it
+	Elapsed    time.Duration // Time spent by those categories, added up
+	Entries    int64         // Files and directories listed below it while it was scanned
+
+	scanning int // Categories scanning it now
+}
+
+// scanProfile records where a scan spends its time
+type scanProfile struct {
+	mu   sync.Mutex
+	dirs map[string]*DirProfile
+}
+
+// EnableProfile records the time spent and the entries listed in each
+// directory the following scans start from; see Profile. Entries listed by
+// find, du and mdfind aren't seen. Call it after SetFS.
+func (hs *HyperScanner) EnableProfile() {
+	if hs.profile != nil {
+		return
+	}
+	hs.profile = &scanProfile{dirs: make(map[string]*DirProfile)}
+	hs.fs = &profileFS{FS: hs.fs, profile: hs.profile}
+}
+
+// Profile returns the directories scanned since EnableProfile, slowest first
+func (hs *HyperScanner) Profile() []DirProfile {
+	if hs.profile == nil {
+		return nil
+	}
+	hs.profile.mu.Lock()
+	defer hs.profile.mu.Unlock()
+
+	dirs := make([]DirProfile, 0, len(hs.profile.dirs))
+	for _, dir := range hs.profile.dirs {
+		profile := *dir
+		profile.Categories = slices.Clone(dir.Categories)
+		dirs = append(dirs, profile)
+	}
+	slices.SortFunc(dirs, func(a, b DirProfile) int {
+		return cmp.Or(cmp.Compare(b.Elapsed, a.Elapsed), cmp.Compare(b.Entries, a.Entries), cmp.Compare(a.Path, b.Path))
+	})
+	return dirs
+}
+
+// timeDir starts timing a category's scan of dir, and returns the function
+// that stops it. It does nothing unless profiling is enabled.
+func (hs *HyperScanner) timeDir(dir, category string) func() {
+	if hs.profile == nil {
+		return func() {}
+	}
+	profile := hs.profile.start(filepath.Clean(dir))
+	start := time.Now()
+	return func() {
+		hs.profile.mu.Lock()
+		defer hs.profile.mu.Unlock()
+		profile.scanning--
+		profile.Elapsed += time.Since(start)
+		if !slices.Contains(profile.Categories, category) {
+			profile.Categories = append(profile.Categories, category)
+		}
+	}
+}
+
+// start returns the profile of dir, adding it if it's new, and marks it as
+// being scanned
+func (p *scanProfile) start(dir string) *DirProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profile, ok := p.dirs[dir]
+	if !ok {
+		profile = &DirProfile{Path: dir}
+		p.dirs[dir] = profile
+	}
+	profile.scanning++
+	return profile
+}
+
+// listed counts entries listed in dir toward the profiled directories
+// holding it that are being scanned. Another category may have listed them,
+// if it's scanning a directory that overlaps.
+func (p *scanProfile) listed(dir string, entries int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		if profile, ok := p.dirs[dir]; ok && profile.scanning > 0 {
+			profile.Entries += int64(entries)
+		}
+		if dir == filepath.Dir(dir) {
+			return
+		}
+	}
+}
+
+// profileFS counts the entries listed in each profiled directory
+type profileFS struct {
+	vfs.FS
+	profile *scanProfile
+}
+
+// Unwrap returns the wrapped file system
+func (p *profileFS) Unwrap() vfs.FS {
+	return p.FS
+}
+
+func (p *profileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := p.FS.ReadDir(name)
+	p.profile.listed(name, len(entries))
+	return entries, err
+}
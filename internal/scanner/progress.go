@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"maps"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+)
+
+// startProgress starts timing a scan of categories
+func (hs *HyperScanner) startProgress(categories []string) {
+	hs.progressMu.Lock()
+	defer hs.progressMu.Unlock()
+
+	hs.scanStart = time.Now()
+	hs.categoryOrder = categories
+	hs.categoriesDone = make(map[string]time.Duration, len(categories))
+}
+
+// finishCategories marks categories as scanned, remembers how long they took
+// for the next scan's ETA and reports the progress. Categories that aren't
+// being scanned are ignored.
+func (hs *HyperScanner) finishCategories(categories ...string) {
+	hs.progressMu.Lock()
+	elapsed := time.Since(hs.scanStart)
+	var finished []string
+	for _, name := range hs.categoryOrder {
+		for _, category := range categories {
+			if _, done := hs.categoriesDone[name]; name == category && !done {
+				hs.categoriesDone[name] = elapsed
+				finished = append(finished, name)
+			}
+		}
+	}
+	hs.progressMu.Unlock()
+	if len(finished) == 0 {
+		return
+	}
+
+	hs.cacheMu.Lock()
+	if hs.cache != nil {
+		if hs.cache.CategoryDurations == nil {
+			hs.cache.CategoryDurations = make(map[string]time.Duration)
+		}
+		for _, name := range finished {
+			hs.cache.CategoryDurations[name] = elapsed
+		}
+	}
+	hs.cacheMu.Unlock()
+
+	hs.reportProgress(progress.PhaseScanning, finished[0], "")
+}
+
+// CategoryDurations returns how long each category of the last scan took,
+// from the start of the scan until it finished. Categories still being
+// scanned are left out.
+func (hs *HyperScanner) CategoryDurations() map[string]time.Duration {
+	hs.progressMu.Lock()
+	defer hs.progressMu.Unlock()
+	return maps.Clone(hs.categoriesDone)
+}
+
+// reportProgress passes the state of the scan to the progress callback
+func (hs *HyperScanner) reportProgress(phase progress.Phase, category, path string) {
+	if hs.progressCb == nil {
+		return
+	}
+
+	event := ProgressEvent{
+		Phase:       phase,
+		Category:    category,
+		CurrentPath: path,
+		FilesFound:  int(hs.results.filesFound.Load()),
+		TotalSize:   hs.results.totalSize.Load(),
+	}
+
+	totals := hs.results.categoryTotals()
+	hs.progressMu.Lock()
+	event.Elapsed = time.Since(hs.scanStart)
+	event.Categories = make([]CategoryProgress, len(hs.categoryOrder))
+	for i, name := range hs.categoryOrder {
+		_, done := hs.categoriesDone[name]
+		event.Categories[i] = CategoryProgress{Name: name, Done: done, CategoryTotal: totals[name]}
+		if event.Categories[i].Done {
+			event.CategoriesDone++
+		}
+	}
+	hs.progressMu.Unlock()
+
+	if seconds := event.Elapsed.Seconds(); seconds > 0 {
+		event.FilesPerSecond = float64(event.FilesFound) / seconds
+	}
+	event.ETA = hs.estimateRemaining(event.Categories, event.Elapsed)
+	hs.progressCb(event)
+}
+
+// estimateRemaining returns how much longer the scan should take. Categories
+// are scanned in parallel, so it's the time the slowest unfinished category
+// took last time, less the time spent so far. It's 0 if a category has no
+// timing yet or is taking longer than it did.
+func (hs *HyperScanner) estimateRemaining(categories []CategoryProgress, elapsed time.Duration) time.Duration {
+	hs.cacheMu.RLock()
+	defer hs.cacheMu.RUnlock()
+	if hs.cache == nil {
+		return 0
+	}
+
+	var slowest time.Duration
+	for _, category := range categories {
+		if category.Done {
+			continue
+		}
+		last, ok := hs.cache.CategoryDurations[category.Name]
+		if !ok {
+			return 0
+		}
+		slowest = max(slowest, last)
+	}
+	return max(slowest-elapsed, 0)
+}
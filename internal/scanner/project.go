@@ -0,0 +1,208 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// Categories of what ScanProject finds in a project besides its dev artifacts
+const (
+	ProjectCachesCategory = "project_caches"
+	VCSIgnoredCategory    = "vcs_ignored"
+)
+
+// gitTimeout bounds listing the files git ignores in a project
+const gitTimeout = time.Minute
+
+// projectCacheNames are the caches test runners, type checkers, linters and
+// bundlers keep inside a project
+var projectCacheNames = []string{
+	".pytest_cache", ".mypy_cache", ".ruff_cache", ".hypothesis", ".tox", ".nox",
+	".parcel-cache", ".turbo", ".angular", ".nyc_output", ".sass-cache",
+	".eslintcache", ".stylelintcache",
+}
+
+// vcsIgnoredKeep are files git is often told to ignore because they hold
+// secrets or local settings, which nothing can regenerate. They're never
+// reported, and neither is a directory holding one.
+var vcsIgnoredKeep = []string{".env", ".env.*", ".envrc", "*.pem", "*.key", "*.p12", ".idea", ".vscode"}
+
+// ScanProject finds what can go from the project at dir, say before it's
+// archived or sent to someone: its dev artifacts, as the dev categories that
+// are on see them, the caches of its tools and, in a git work tree, the
+// other files git ignores. Nothing outside dir is scanned and nothing goes
+// by age.
+func (hs *HyperScanner) ScanProject(dir string) (*ScanResult, error) {
+	dir, err := filepath.Abs(expandPath(dir, hs.homeDir()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	info, err := hs.fs.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	hs.SetRoot(dir)
+
+	dev := []string{"node_modules", "virtual_envs", "build_artifacts"}
+	hs.resetResults(1000)
+	hs.startProgress(append(slices.Clone(dev), ProjectCachesCategory, VCSIgnoredCategory))
+
+	hs.scanDevArtifacts()
+	hs.finishCategories(dev...)
+	hs.scanProjectCaches(dir, hs.results.paths())
+	hs.finishCategories(ProjectCachesCategory)
+	var errs []error
+	if err := hs.scanVCSIgnored(dir, hs.results.paths()); err != nil {
+		errs = append(errs, err)
+	}
+	hs.finishCategories(VCSIgnoredCategory)
+
+	if err := hs.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan interrupted: %w", err)
+	}
+	return hs.dedupResult(hs.results.result("", errs)), nil
+}
+
+// scanProjectCaches reports the tool caches in the project at dir, leaving
+// out the artifacts already found
+func (hs *HyperScanner) scanProjectCaches(dir string, found []string) {
+	vfs.WalkDir(hs.fs, dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || hs.cancelled() {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() && path != dir && (name == ".git" || slices.Contains(found, path)) {
+			return filepath.SkipDir
+		}
+		if !slices.Contains(projectCacheNames, name) {
+			return nil
+		}
+		hs.addProjectEntry(path, d, ProjectCachesCategory, fmt.Sprintf("%s cache of %s", name, filepath.Base(dir)))
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// scanVCSIgnored reports the files and directories git ignores in the
+// project at dir, other than the ones in vcsIgnoredKeep and the results
+// already found. A directory holding either is reported entry by entry.
+// It does nothing outside a git work tree.
+func (hs *HyperScanner) scanVCSIgnored(dir string, found []string) error {
+	if !hs.native() {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(hs.ctx, gitTimeout)
+	defer cancel()
+	if exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run() != nil {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "ls-files", "-z",
+		"--others", "--ignored", "--exclude-standard", "--directory").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list the files git ignores in %s: %w", dir, err)
+	}
+	for _, rel := range bytes.Split(out, []byte{0}) {
+		if len(rel) > 0 {
+			hs.addIgnored(filepath.Join(dir, string(rel)), found)
+		}
+	}
+	return nil
+}
+
+// addIgnored reports path, which git ignores
+func (hs *HyperScanner) addIgnored(path string, found []string) {
+	if hs.cancelled() || underAny(path, found) || keptIgnored(filepath.Base(path)) {
+		return
+	}
+	info, err := hs.fs.Lstat(path)
+	if err != nil {
+		return
+	}
+	if info.IsDir() && hs.holdsKept(path, found) {
+		entries, err := hs.fs.ReadDir(path)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			hs.addIgnored(filepath.Join(path, entry.Name()), found)
+		}
+		return
+	}
+	hs.addProjectEntry(path, fs.FileInfoToDirEntry(info), VCSIgnoredCategory, "Ignored by git")
+}
+
+// holdsKept reports whether dir holds a file in vcsIgnoredKeep or a result
+// already found
+func (hs *HyperScanner) holdsKept(dir string, found []string) bool {
+	holds := false
+	vfs.WalkDir(hs.fs, dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		if keptIgnored(d.Name()) || slices.Contains(found, path) {
+			holds = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return holds
+}
+
+// keptIgnored reports whether name matches vcsIgnoredKeep
+func keptIgnored(name string) bool {
+	for _, pattern := range vcsIgnoredKeep {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addProjectEntry reports a file or a whole directory of a project
+func (hs *HyperScanner) addProjectEntry(path string, d os.DirEntry, category, reason string) {
+	if !d.IsDir() {
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		hs.appendResult(FileInfo{
+			Path:     path,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Category: category,
+			Reason:   reason,
+		}, 1)
+		return
+	}
+
+	size, files, newest := hs.dirUsage(path)
+	if files == 0 {
+		return
+	}
+	hs.appendResult(FileInfo{
+		Path:     path,
+		Size:     size,
+		ModTime:  newest,
+		Category: category,
+		Reason:   fmt.Sprintf("%s: %s", reason, plural(files, "file")),
+		Files:    files,
+	}, int64(files))
+}
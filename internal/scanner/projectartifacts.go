@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// gatedArtifact is a build artifact with a name too common to go by alone,
+// which only counts in a project that has one of its marker files
+type gatedArtifact struct {
+	name    string   // Directory found in the project
+	sub     string   // The artifact inside it, or "" for the directory itself
+	markers []string // Globs matched in the project directory
+}
+
+// dotnetProjects mark a .NET project
+var dotnetProjects = []string{"*.csproj", "*.fsproj", "*.vbproj"}
+
+// gatedArtifacts are checked in order, so a Composer project's whole vendor
+// directory is reported rather than a Bundler install inside it
+var gatedArtifacts = []gatedArtifact{
+	{"vendor", "", []string{"composer.json"}}, // Composer packages
+	{"vendor", "bundle", []string{"Gemfile"}}, // bundle install --deployment
+	{".bundle", "ruby", []string{"Gemfile"}},  // bundle install --path .bundle
+	{"tmp", "cache", []string{"Gemfile"}},     // Rails and Bootsnap caches
+	{"bin", "", dotnetProjects},
+	{"obj", "", dotnetProjects},
+	{".dart_tool", "", []string{"pubspec.yaml"}}, // Dart and Flutter tooling state
+	{".build", "", []string{"Package.swift"}},    // Swift Package Manager builds and checkouts
+}
+
+// gatedArtifactNames returns the directory names of gatedArtifacts
+func gatedArtifactNames() []string {
+	var names []string
+	for _, g := range gatedArtifacts {
+		if !slices.Contains(names, g.name) {
+			names = append(names, g.name)
+		}
+	}
+	return names
+}
+
+// projectArtifact returns the dev artifact that dir, a directory found in a
+// project, is or holds, and its category. It returns "" for both if it's
+// neither or the category is off.
+func (hs *HyperScanner) projectArtifact(dir string) (string, string) {
+	if category := hs.categorizeArtifact(filepath.Base(dir)); category != "" {
+		return dir, category
+	}
+	if !hs.config.Categories.BuildArtifacts {
+		return "", ""
+	}
+	if path := hs.gatedArtifact(dir); path != "" {
+		return path, "build_artifacts"
+	}
+	return "", ""
+}
+
+// gatedArtifact returns the gated artifact dir is or holds, or ""
+func (hs *HyperScanner) gatedArtifact(dir string) string {
+	name, project := filepath.Base(dir), filepath.Dir(dir)
+	for _, g := range gatedArtifacts {
+		if g.name != name || !hs.hasMarker(project, g.markers) {
+			continue
+		}
+		if g.sub == "" {
+			return dir
+		}
+		path := filepath.Join(dir, g.sub)
+		if info, err := hs.fs.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// checkout reports whether dir is a git work tree: a clone, a submodule or
+// a linked worktree, whose .git is a file pointing at the repository. Its
+// files are sources, whatever its name, so it's never an artifact.
+func (hs *HyperScanner) checkout(dir string) bool {
+	_, err := hs.fs.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// hasMarker reports whether one of markers matches a file in project
+func (hs *HyperScanner) hasMarker(project string, markers []string) bool {
+	for _, marker := range markers {
+		if found, _ := vfs.Glob(hs.fs, filepath.Join(project, marker)); len(found) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// projectArtifacts returns the artifacts among dirs, found in a project
+// directory, as categorize sees them. Directories inside an artifact
+// already found are left out, so nothing is counted twice, and so are git
+// work trees.
+func (hs *HyperScanner) projectArtifacts(dirs []string, categorize func(dir string) (string, string)) []artifact {
+	dirs = slices.Clone(dirs)
+	slices.Sort(dirs)
+
+	var artifacts []artifact
+	var found []string
+	for _, dir := range dirs {
+		if underAny(dir, found) {
+			continue
+		}
+		if path, category := categorize(dir); category != "" && !hs.checkout(dir) {
+			artifacts = append(artifacts, artifact{path: path, category: category})
+			found = append(found, path)
+		}
+	}
+	return artifacts
+}
+
+// artifactNames returns the name of an artifact and of its project, for
+// reasons: "node_modules" of "app", or "vendor/bundle" of "app"
+func artifactNames(path string) (string, string) {
+	name, parent := filepath.Base(path), filepath.Dir(path)
+	for _, g := range gatedArtifacts {
+		if g.sub != "" && g.sub == name && g.name == filepath.Base(parent) {
+			return g.name + "/" + g.sub, filepath.Base(filepath.Dir(parent))
+		}
+	}
+	return name, filepath.Base(parent)
+}
+
+// findArtifactArgs returns the find arguments that list the directories in
+// dir named one of names, which aren't descended into, and the ones named
+// one of gated, which are, since they're only artifacts in some projects.
+// Git's own directories are skipped: submodules keep their repositories in
+// .git/modules under their own names, such as "build".
+func findArtifactArgs(dir string, names, gated []string) []string {
+	args := []string{dir, "-maxdepth", "6", "-name", ".git", "-prune", "-o", "-type", "d", "("}
+	for i, name := range names {
+		if i > 0 {
+			args = append(args, "-o")
+		}
+		args = append(args, "-name", name)
+	}
+	args = append(args, ")", "-prune", "-print")
+	if len(gated) == 0 {
+		return args
+	}
+
+	args = append(args, "-o", "-type", "d", "(")
+	for i, name := range gated {
+		if i > 0 {
+			args = append(args, "-o")
+		}
+		args = append(args, "-name", name)
+	}
+	return append(args, ")", "-print")
+}
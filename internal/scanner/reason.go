@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"fmt"
+	"time"
+)
+
+// walkedKinds name what the files found walking a category's directories are
+var walkedKinds = map[string]string{
+	"cache":           "cache file",
+	"temp":            "temp file",
+	"logs":            "log file",
+	CloudCLICategory:  "cloud CLI cache file",
+	RPackagesCategory: "R package cache file",
+}
+
+// walkedKind names what a file found walking category's directories is
+func walkedKind(category string) string {
+	if kind, ok := walkedKinds[category]; ok {
+		return kind
+	}
+	return "file"
+}
+
+// walkReason explains a file found walking category's directories, with the
+// min_file_age rule it passed
+func (hs *HyperScanner) walkReason(category string, modTime time.Time) string {
+	return fmt.Sprintf("%s, unmodified for %s (min_file_age: %dh)",
+		capitalize(walkedKind(category)), since(modTime), hs.config.MinFileAge)
+}
+
+// cachedWalkReason explains a directory reported from the scan cache, whose
+// files were all found walking it last time
+func (hs *HyperScanner) cachedWalkReason(category string, files int) string {
+	return fmt.Sprintf("%s, unchanged since the last scan (min_file_age: %dh)",
+		plural(files, walkedKind(category)), hs.config.MinFileAge)
+}
+
+// artifactReason describes a dev artifact, name in project, holding
+// fileCount files, marking estimated counts with a ~
+func artifactReason(name, project string, modTime time.Time, fileCount int, exact bool) string {
+	files := fmt.Sprintf("%d", fileCount)
+	if !exact {
+		files = "~" + files
+	}
+	return fmt.Sprintf("%s of %s, untouched for %s: %s files", name, project, since(modTime), files)
+}
+
+// since says how long ago t was, in the largest unit that fits: "5 hours",
+// "94 days", "7 months" or "2 years"
+func since(t time.Time) string {
+	age := time.Since(t)
+	days := int(age.Hours() / 24)
+	switch {
+	case age < time.Hour:
+		return "less than an hour"
+	case days < 1:
+		return plural(int(age.Hours()), "hour")
+	case days < 60:
+		return plural(days, "day")
+	case days < 730:
+		return plural(days/30, "month")
+	default:
+		return plural(days/365, "year")
+	}
+}
+
+// plural is n followed by noun, pluralized unless n is 1
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// capitalize upper-cases the first letter of an ASCII string
+func capitalize(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
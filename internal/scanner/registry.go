@@ -0,0 +1,220 @@
+package scanner
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// How a registry's garbage collection treats a blob, by what reaches it
+const (
+	BlobTagged       = "tagged"       // Reached from a tag, so always kept
+	BlobUntagged     = "untagged"     // Reached only from manifests no tag points to; collected with delete_untagged
+	BlobUnreferenced = "unreferenced" // Reached from no manifest; collected by any garbage collection
+)
+
+// registryStorage is where the filesystem storage driver keeps a registry's
+// data under its root directory
+const registryStorage = "docker/registry/v2"
+
+// maxManifestSize bounds the manifests read: they're small JSON documents,
+// and anything bigger isn't one
+const maxManifestSize = 4 << 20
+
+// RegistryBlob is a blob in a registry's storage: a manifest, an image
+// config or a layer
+type RegistryBlob struct {
+	Digest   string // sha256:...
+	Size     int64
+	Modified time.Time // When it was pushed
+	Reach    string    // BlobTagged, BlobUntagged or BlobUnreferenced
+}
+
+// Registry is what a local registry's storage holds. Blobs are stored once
+// however many repositories share them, so each is counted once.
+type Registry struct {
+	Root         string
+	Repositories int
+	Tags         int
+	Blobs        []RegistryBlob // Largest first
+}
+
+// descriptor points from a manifest to another blob
+type descriptor struct {
+	Digest string `json:"digest"`
+}
+
+// manifestRefs are the blobs an image manifest, an image index or a
+// schema 1 manifest points to
+type manifestRefs struct {
+	Config    *descriptor  `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+	FSLayers  []struct {
+		BlobSum string `json:"blobSum"`
+	} `json:"fsLayers"`
+}
+
+// AnalyzeRegistry reads the storage of a registry that uses the filesystem
+// storage driver with root as its root directory, such as /var/lib/registry,
+// and works out which blobs its garbage collection would remove: the ones
+// no manifest reaches, and with delete_untagged the ones only untagged
+// manifests reach.
+func (hs *HyperScanner) AnalyzeRegistry(root string) (*Registry, error) {
+	storage := filepath.Join(root, registryStorage)
+	blobDir := filepath.Join(storage, "blobs", "sha256")
+	prefixes, err := hs.fs.ReadDir(blobDir)
+	if err != nil {
+		return nil, fmt.Errorf("no registry storage under %s: %w", root, err)
+	}
+
+	blobs := make(map[string]*RegistryBlob)
+	for _, prefix := range prefixes {
+		entries, err := hs.fs.ReadDir(filepath.Join(blobDir, prefix.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			info, err := hs.fs.Stat(filepath.Join(blobDir, prefix.Name(), entry.Name(), "data"))
+			if err != nil || !info.Mode().IsRegular() {
+				continue // An upload that never finished
+			}
+			blobs[entry.Name()] = &RegistryBlob{
+				Digest:   "sha256:" + entry.Name(),
+				Size:     info.Size(),
+				Modified: info.ModTime(),
+				Reach:    BlobUnreferenced,
+			}
+		}
+	}
+
+	r := &Registry{Root: root}
+	m := &registryMarker{hs: hs, blobDir: blobDir, blobs: blobs}
+	repositories := filepath.Join(storage, "repositories")
+	err = vfs.WalkDir(hs.fs, repositories, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case "_layers", "_uploads":
+			return fs.SkipDir
+		case "_manifests":
+			// Everything the garbage collection marks starts from a
+			// repository's manifests
+			r.Repositories++
+			tags, _ := hs.fs.ReadDir(filepath.Join(path, "tags"))
+			for _, tag := range tags {
+				if digest, err := hs.readLink(filepath.Join(path, "tags", tag.Name(), "current", "link")); err == nil {
+					r.Tags++
+					m.manifest(digest, BlobTagged)
+				}
+			}
+			revisions, _ := hs.fs.ReadDir(filepath.Join(path, "revisions", "sha256"))
+			for _, revision := range revisions {
+				m.manifest("sha256:"+revision.Name(), BlobUntagged)
+			}
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blob := range blobs {
+		r.Blobs = append(r.Blobs, *blob)
+	}
+	slices.SortFunc(r.Blobs, func(a, b RegistryBlob) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Digest, b.Digest))
+	})
+	return r, nil
+}
+
+// registryMarker marks the blobs manifests reach, as the registry's garbage
+// collection does
+type registryMarker struct {
+	hs      *HyperScanner
+	blobDir string
+	blobs   map[string]*RegistryBlob // By hex digest
+}
+
+// manifest marks the manifest with digest, and everything it points to, as
+// reached at reach. Marks only ever go from unreferenced to untagged to
+// tagged, so shared blobs and cycles are followed once per level.
+func (m *registryMarker) manifest(digest, reach string) {
+	blob := m.mark(digest, reach)
+	if blob == nil || blob.Size > maxManifestSize {
+		return
+	}
+	hex := strings.TrimPrefix(blob.Digest, "sha256:")
+	if len(hex) < 2 {
+		return
+	}
+	f, err := m.hs.fs.Open(filepath.Join(m.blobDir, hex[:2], hex, "data"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var refs manifestRefs
+	if err := json.NewDecoder(io.LimitReader(f, maxManifestSize)).Decode(&refs); err != nil {
+		return
+	}
+
+	if refs.Config != nil {
+		m.mark(refs.Config.Digest, reach)
+	}
+	for _, layer := range refs.Layers {
+		m.mark(layer.Digest, reach)
+	}
+	for _, layer := range refs.FSLayers {
+		m.mark(layer.BlobSum, reach)
+	}
+	for _, child := range refs.Manifests {
+		m.manifest(child.Digest, reach)
+	}
+}
+
+// mark marks the blob with digest as reached at reach. It returns the blob,
+// or nil if there's no such blob or it was already reached at that level.
+func (m *registryMarker) mark(digest, reach string) *RegistryBlob {
+	hex, ok := strings.CutPrefix(digest, "sha256:")
+	blob := m.blobs[hex]
+	if !ok || blob == nil || reachRank(blob.Reach) >= reachRank(reach) {
+		return nil
+	}
+	blob.Reach = reach
+	return blob
+}
+
+// reachRank orders reaches by how surely the blob is kept
+func reachRank(reach string) int {
+	switch reach {
+	case BlobTagged:
+		return 2
+	case BlobUntagged:
+		return 1
+	}
+	return 0
+}
+
+// readLink reads a registry link file, which holds the digest it points to
+func (hs *HyperScanner) readLink(path string) (string, error) {
+	f, err := hs.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, 1024))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
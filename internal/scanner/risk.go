@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// Risk levels of deleting a result, from data that's always regenerated to
+// data someone should look at first
+const (
+	RiskSafe   = "safe"   // Rebuilt on demand: caches, dependencies, build output
+	RiskLow    = "low"    // Unlikely to be missed: logs, temp files, app leftovers
+	RiskReview = "review" // May be wanted: large, old and duplicate files, other users' files
+)
+
+// RiskLevels lists the risk levels, lowest first
+var RiskLevels = []string{RiskSafe, RiskLow, RiskReview}
+
+// recentlyModified is how new a result must be to be treated as in use
+const recentlyModified = 24 * time.Hour
+
+// categoryRisk is the starting risk of each category. Categories not listed,
+// including ones added later, need review, as do WSL leftovers: distro
+// exports are often backups.
+var categoryRisk = map[string]string{
+	"cache":                RiskSafe,
+	"node_modules":         RiskSafe,
+	"virtual_envs":         RiskSafe,
+	"build_artifacts":      RiskSafe,
+	"docker":               RiskSafe,
+	"desktop_caches":       RiskSafe,
+	CompilerCachesCategory: RiskSafe,
+	ProjectCachesCategory:  RiskSafe,
+	LaTeXCategory:          RiskLow,
+	JuliaCategory:          RiskLow,
+	"temp":                 RiskLow,
+	"logs":                 RiskLow,
+	"app_data":             RiskLow,
+	CloudCLICategory:       RiskLow,
+	EmptyDirsCategory:      RiskLow,
+}
+
+// RiskRank orders risk levels, 0 for safe. Unknown levels rank as review.
+func RiskRank(level string) int {
+	for i, l := range RiskLevels {
+		if l == level {
+			return i
+		}
+	}
+	return len(RiskLevels) - 1
+}
+
+// ValidRisk checks that level is one of RiskLevels
+func ValidRisk(level string) error {
+	for _, l := range RiskLevels {
+		if l == level {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown risk level %q (valid levels: safe, low, review)", level)
+}
+
+// scanningUser is the user whose files are being cleaned: the one who ran
+// sudo, or the current user
+var scanningUser = sync.OnceValue(func() int {
+	if os.Geteuid() == 0 {
+		if uid, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+			return uid
+		}
+	}
+	return os.Getuid()
+})
+
+// assessRisk rates how risky deleting file is. It starts from the category
+// and is raised for system-wide locations and files owned by root, to review
+// for files belonging to another user, and a level for logs, temp files and
+// the like modified in the last day, which may still be in use. Regenerable
+// data stays safe however new it is. info is file's Lstat, nil if unknown.
+func (hs *HyperScanner) assessRisk(file FileInfo, info fs.FileInfo) string {
+	base, ok := categoryRisk[file.Category]
+	if !ok {
+		return RiskReview
+	}
+	rank := RiskRank(base)
+
+	if IsSystemPath(file.Path, hs.homeDir()) {
+		rank = max(rank, RiskRank(RiskLow))
+	}
+	if base != RiskSafe && !file.ModTime.IsZero() && time.Since(file.ModTime) < recentlyModified {
+		rank++
+	}
+	if info != nil {
+		if uid, _, ok := platform.FileOwner(info); ok && int(uid) != scanningUser() {
+			if uid == 0 {
+				rank = max(rank, RiskRank(RiskLow))
+			} else {
+				rank = RiskRank(RiskReview)
+			}
+		}
+	}
+
+	return RiskLevels[min(rank, len(RiskLevels)-1)]
+}
+
+// FilterRisk returns a new result containing only files at or below the
+// given risk level
+func (r *ScanResult) FilterRisk(level string) *ScanResult {
+	limit := RiskRank(level)
+	filtered := r.filter(func(file FileInfo) bool {
+		return RiskRank(file.Risk) <= limit
+	})
+	if limit >= RiskRank(RiskReview) {
+		filtered.Duplicates = r.Duplicates
+	}
+	return filtered
+}
+
+// RiskTotals returns the count and size of the results at each risk level
+func (r *ScanResult) RiskTotals() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		level := RiskLevels[RiskRank(file.Risk)]
+		total := totals[level]
+		total.Count++
+		total.Size += file.Size
+		totals[level] = total
+		return nil
+	})
+	return totals, err
+}
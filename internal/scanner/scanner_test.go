@@ -1,15 +1,24 @@
 package scanner
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"sync/atomic"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/fenilsonani/system-cleanup/internal/config"
 	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
 	"github.com/fenilsonani/system-cleanup/internal/testutil"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 )
 
 // =============================================================================
@@ -62,6 +71,16 @@ func TestParseSize(t *testing.T) {
 		// Edge cases
 		{"single digit KB", "1KB", 1024},
 		{"large GB", "100GB", 100 * 1024 * 1024 * 1024},
+
+		// Fractions, larger units and binary suffixes
+		{"decimal MB", "1.5MB", 1536 * 1024},
+		{"decimal GB", "0.5GB", 512 * 1024 * 1024},
+		{"TB", "100TB", 100 << 40},
+		{"PB", "2PB", 2 << 50},
+		{"short unit", "10M", 10 * 1024 * 1024},
+		{"bytes unit", "512B", 512},
+		{"binary suffix", "1GiB", 1024 * 1024 * 1024},
+		{"space before unit", "2 GB", 2 * 1024 * 1024 * 1024},
 	}
 
 	for _, tt := range tests {
@@ -84,17 +103,22 @@ func TestParseSizeInvalidInputs(t *testing.T) {
 		{"empty string", ""},
 		{"only spaces", "   "},
 		{"only unit", "MB"},
-		{"invalid unit", "100TB"},
+		{"invalid unit", "100XB"},
 		{"negative value", "-100MB"},
-		{"decimal value", "1.5MB"},
+		{"two dots", "1.2.3MB"},
 		{"letters mixed", "abc100MB"},
+		{"trailing letters", "100MBs"},
+		{"too large", "99999999PB"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Should not panic, may return 0 or unexpected value
-			result := hs.parseSize(tt.input)
-			_ = result // Just ensure no panic
+			if _, err := utils.ParseSize(tt.input); err == nil {
+				t.Errorf("ParseSize(%q) should fail", tt.input)
+			}
+			if result := hs.parseSize(tt.input); result != 0 {
+				t.Errorf("parseSize(%q) = %d, want 0", tt.input, result)
+			}
 		})
 	}
 }
@@ -408,6 +432,63 @@ func TestNewHyperScanner(t *testing.T) {
 	}
 }
 
+func TestScanWorkers(t *testing.T) {
+	cfg := &config.Config{Scan: config.ScanConfig{Workers: 7}}
+	if workers, _ := scanWorkers(cfg, &platform.Info{}); workers != 7 {
+		t.Errorf("expected scan.workers to set 7 workers, got %d", workers)
+	}
+
+	tests := []struct {
+		storage platform.StorageKind
+		cpus    int
+		want    int
+	}{
+		{platform.StorageHDD, 32, 4},
+		{platform.StorageNetwork, 2, 32},
+		{platform.StorageSSD, 2, 16},
+		{platform.StorageSSD, 8, 32},
+		{platform.StorageUnknown, 32, 64},
+	}
+	for _, tt := range tests {
+		if got := workersFor(tt.storage, tt.cpus); got != tt.want {
+			t.Errorf("workersFor(%s, %d) = %d, want %d", tt.storage, tt.cpus, got, tt.want)
+		}
+	}
+}
+
+func TestScanProfile(t *testing.T) {
+	f := testutil.NewFixture(t)
+	for i := 0; i < 5; i++ {
+		f.CreateCacheFile(fmt.Sprintf("app/file%d.cache", i), 100)
+	}
+
+	cfg := &config.Config{Categories: config.Categories{Cache: true}}
+	hs := NewHyperScanner(cfg, &platform.Info{HomeDir: f.RootDir, CacheDirs: []string{f.CacheDir}})
+	hs.DisableCache()
+	hs.EnableProfile()
+
+	result, err := hs.ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	defer result.Close()
+
+	profile := hs.Profile()
+	if len(profile) != 1 || profile[0].Path != f.CacheDir {
+		t.Fatalf("expected the cache directory to be profiled, got %+v", profile)
+	}
+	if !reflect.DeepEqual(profile[0].Categories, []string{"cache"}) {
+		t.Errorf("expected the cache category, got %v", profile[0].Categories)
+	}
+	// The app directory and its 5 files
+	if profile[0].Entries < 6 {
+		t.Errorf("expected at least 6 entries listed, got %d", profile[0].Entries)
+	}
+	if !vfs.IsOS(hs.fs) {
+		t.Error("profiling shouldn't stop the real file system being used")
+	}
+}
+
 func TestNewHyperScannerNilConfig(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -426,7 +507,7 @@ func TestSetProgressCallback(t *testing.T) {
 		t.Error("callback should be nil initially")
 	}
 
-	scanner.SetProgressCallback(func(category, path string, count int, size int64) {
+	scanner.SetProgressCallback(func(event ProgressEvent) {
 		// Callback registered
 	})
 
@@ -466,13 +547,13 @@ func TestScanCategoryWithFixture(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != 3 {
-		t.Errorf("TotalCount = %d, want 3", len(hs.results))
+	if len(collectedResult(hs).Files) != 3 {
+		t.Errorf("TotalCount = %d, want 3", len(collectedResult(hs).Files))
 	}
 
 	expectedSize := int64(1024 + 2048 + 512)
 	var totalSize int64
-	for _, r := range hs.results {
+	for _, r := range collectedResult(hs).Files {
 		totalSize += r.Size
 	}
 	if totalSize != expectedSize {
@@ -508,8 +589,8 @@ func TestScanSkipsNewFiles(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != 1 {
-		t.Errorf("TotalCount = %d, want 1 (only old file)", len(hs.results))
+	if len(collectedResult(hs).Files) != 1 {
+		t.Errorf("TotalCount = %d, want 1 (only old file)", len(collectedResult(hs).Files))
 	}
 }
 
@@ -556,8 +637,8 @@ func TestScanEmptyDirectory(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != 0 {
-		t.Errorf("expected 0 results for empty directory, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 0 {
+		t.Errorf("expected 0 results for empty directory, got %d", len(collectedResult(hs).Files))
 	}
 }
 
@@ -582,8 +663,8 @@ func TestScanNonExistentDirectory(t *testing.T) {
 	// Should not panic
 	hs.scanDirsWithCache([]string{"/nonexistent/path/12345"}, "cache")
 
-	if len(hs.results) != 0 {
-		t.Errorf("expected 0 results for non-existent directory, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 0 {
+		t.Errorf("expected 0 results for non-existent directory, got %d", len(collectedResult(hs).Files))
 	}
 }
 
@@ -619,7 +700,7 @@ func TestScanWithSymlinks(t *testing.T) {
 
 	// Results depend on how scanner handles symlinks
 	// At minimum, should not panic
-	t.Logf("Found %d results (symlink handling test)", len(hs.results))
+	t.Logf("Found %d results (symlink handling test)", len(collectedResult(hs).Files))
 }
 
 func TestScanMultipleCategories(t *testing.T) {
@@ -809,7 +890,7 @@ func TestScanHiddenDirectories(t *testing.T) {
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
 	// Scanner may or may not include hidden directories based on implementation
-	t.Logf("Hidden directory test: %s, found %d results", hiddenDir, len(hs.results))
+	t.Logf("Hidden directory test: %s, found %d results", hiddenDir, len(collectedResult(hs).Files))
 }
 
 func TestScanDeepNestedStructure(t *testing.T) {
@@ -838,8 +919,8 @@ func TestScanDeepNestedStructure(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != 1 {
-		t.Errorf("expected 1 result from deep structure, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 1 {
+		t.Errorf("expected 1 result from deep structure, got %d", len(collectedResult(hs).Files))
 	}
 }
 
@@ -875,8 +956,8 @@ func TestScanLargeNumberOfFiles(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != numFiles {
-		t.Errorf("expected %d results, got %d", numFiles, len(hs.results))
+	if len(collectedResult(hs).Files) != numFiles {
+		t.Errorf("expected %d results, got %d", numFiles, len(collectedResult(hs).Files))
 	}
 }
 
@@ -910,7 +991,7 @@ func TestScanWithBrokenSymlinks(t *testing.T) {
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
 	// At least the valid file should be found
-	if len(hs.results) < 1 {
+	if len(collectedResult(hs).Files) < 1 {
 		t.Error("expected at least 1 result (the valid file)")
 	}
 }
@@ -951,8 +1032,8 @@ func TestScanWithSpecialCharactersInFilename(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != len(specialNames) {
-		t.Errorf("expected %d results, got %d", len(specialNames), len(hs.results))
+	if len(collectedResult(hs).Files) != len(specialNames) {
+		t.Errorf("expected %d results, got %d", len(specialNames), len(collectedResult(hs).Files))
 	}
 }
 
@@ -981,11 +1062,11 @@ func TestScanZeroSizeFiles(t *testing.T) {
 
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if len(hs.results) != 1 {
-		t.Errorf("expected 1 result (zero-size file), got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 1 {
+		t.Errorf("expected 1 result (zero-size file), got %d", len(collectedResult(hs).Files))
 	}
-	if len(hs.results) > 0 && hs.results[0].Size != 0 {
-		t.Errorf("expected size 0, got %d", hs.results[0].Size)
+	if len(collectedResult(hs).Files) > 0 && collectedResult(hs).Files[0].Size != 0 {
+		t.Errorf("expected size 0, got %d", collectedResult(hs).Files[0].Size)
 	}
 }
 
@@ -1178,6 +1259,150 @@ func TestScanCategoryBuildArtifacts(t *testing.T) {
 	t.Logf("Found %d build artifacts", result.TotalCount)
 }
 
+func TestScanGatedBuildArtifacts(t *testing.T) {
+	files := []string{
+		"shop/Gemfile",
+		"shop/vendor/bundle/ruby/3.3.0/gems/rack/lib/rack.rb",
+		"shop/.bundle/config",
+		"shop/.bundle/ruby/3.3.0/gems/rails/README",
+		"shop/tmp/cache/bootsnap/compile-cache",
+		"shop/tmp/pids/server.pid",
+		"site/composer.json",
+		"site/vendor/autoload.php",
+		"site/vendor/acme/lib/build/out.js",
+		"api/Api.csproj",
+		"api/bin/Debug/Api.dll",
+		"api/obj/project.assets.json",
+		"tool/go.mod",
+		"tool/bin/run",
+		"tool/vendor/modules.txt",
+		"tool/tmp/cache/x",
+		"tool/.build/x",
+		"app/pubspec.yaml",
+		"app/.dart_tool/package_config.json",
+		"app/build/app/outputs/app.apk",
+		"pkg/Package.swift",
+		"pkg/.build/debug/pkg",
+	}
+	want := []string{
+		"api/bin", "api/obj", "app/.dart_tool", "app/build", "pkg/.build",
+		"shop/.bundle/ruby", "shop/tmp/cache", "shop/vendor/bundle", "site/vendor",
+	}
+
+	for _, native := range []bool{false, true} {
+		root := "/home/user/dev"
+		mem := vfs.NewMemFS()
+		if native {
+			root = t.TempDir()
+		}
+		for _, file := range files {
+			path := filepath.Join(root, file)
+			if native {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				mem.AddFile(path, 1, time.Now())
+			}
+		}
+
+		cfg := &config.Config{
+			Categories: config.Categories{BuildArtifacts: true},
+			Dev:        config.DevConfig{ProjectDirs: []string{root}},
+		}
+		for _, all := range []bool{false, true} {
+			hs := NewHyperScanner(cfg, &platform.Info{HomeDir: root})
+			if !native {
+				hs.SetFS(mem)
+			}
+
+			result := hs.ScanCategory("build_artifacts")
+			if all {
+				var err error
+				if result, err = hs.ScanAll(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var paths []string
+			reasons := map[string]string{}
+			for _, file := range result.Files {
+				rel, _ := filepath.Rel(root, file.Path)
+				paths = append(paths, rel)
+				reasons[rel] = file.Reason
+			}
+			sort.Strings(paths)
+			if strings.Join(paths, ",") != strings.Join(want, ",") {
+				t.Fatalf("native %v, all %v: expected %v, got %v", native, all, want, paths)
+			}
+			if !strings.HasPrefix(reasons["shop/vendor/bundle"], "vendor/bundle of shop,") {
+				t.Errorf("expected the reason to name vendor/bundle of shop, got %q", reasons["shop/vendor/bundle"])
+			}
+		}
+	}
+}
+
+func TestScanSkipsGitCheckouts(t *testing.T) {
+	files := []string{
+		"app/package.json",
+		"app/.git/HEAD",
+		"app/.git/modules/build/HEAD",
+		"app/build/.git",
+		"app/build/src/main.c",
+		"app/dist/bundle.js",
+		"app/out/.git",
+		"app/out/README",
+	}
+	for _, native := range []bool{false, true} {
+		root := "/home/user/dev"
+		mem := vfs.NewMemFS()
+		if native {
+			root = t.TempDir()
+		}
+		for _, file := range files {
+			path := filepath.Join(root, file)
+			if native {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				mem.AddFile(path, 1, time.Now())
+			}
+		}
+
+		cfg := &config.Config{
+			Categories: config.Categories{BuildArtifacts: true},
+			Dev:        config.DevConfig{ProjectDirs: []string{root}},
+		}
+		for _, all := range []bool{false, true} {
+			hs := NewHyperScanner(cfg, &platform.Info{HomeDir: root})
+			if !native {
+				hs.SetFS(mem)
+			}
+			result := hs.ScanCategory("build_artifacts")
+			if all {
+				var err error
+				if result, err = hs.ScanAll(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var paths []string
+			for _, file := range result.Files {
+				rel, _ := filepath.Rel(root, file.Path)
+				paths = append(paths, rel)
+			}
+			if strings.Join(paths, ",") != "app/dist" {
+				t.Errorf("native %v, all %v: expected only app/dist, got %v", native, all, paths)
+			}
+		}
+	}
+}
+
 func TestScanCategoryUnknown(t *testing.T) {
 	cfg := &config.Config{}
 	pInfo := &platform.Info{}
@@ -1358,6 +1583,25 @@ func TestScanLargeFiles(t *testing.T) {
 	t.Logf("Found %d large files", result.TotalCount)
 }
 
+func TestScanLargeFilesIgnoresSI(t *testing.T) {
+	f := testutil.NewFixture(t)
+	// Over 1 MB in decimal units, under in binary ones
+	f.CreateRandomFile(filepath.Join("large", "between.bin"), 1_000_500)
+	big := f.CreateRandomFile(filepath.Join("large", "big.bin"), 2*utils.MB)
+
+	cfg := &config.Config{
+		LargeFiles: config.LargeFilesConfig{MinSize: "1MB", ScanPaths: []string{f.LargeFilesDir}},
+	}
+	defer utils.SetSI(false)
+	for _, on := range []bool{false, true} {
+		utils.SetSI(on)
+		result := NewHyperScanner(cfg, &platform.Info{}).ScanCategory("large_files")
+		if result.TotalCount != 1 || result.Files[0].Path != big {
+			t.Errorf("with si=%v, expected only %s at min_size 1MB, got %v", on, big, result.Files)
+		}
+	}
+}
+
 func TestScanOldFiles(t *testing.T) {
 	f := testutil.NewFixture(t)
 
@@ -1411,20 +1655,20 @@ func TestAddArtifactResultCalculatesSize(t *testing.T) {
 	hs := NewHyperScanner(cfg, pInfo)
 	hs.addArtifactResult(artifactDir, "node_modules")
 
-	if len(hs.results) != 1 {
-		t.Errorf("expected 1 result, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 1 {
+		t.Errorf("expected 1 result, got %d", len(collectedResult(hs).Files))
 	}
 
-	if len(hs.results) > 0 {
+	if len(collectedResult(hs).Files) > 0 {
 		// Total size should be 100 + 200 + 300 = 600
-		if hs.results[0].Size < 600 {
-			t.Errorf("expected size >= 600, got %d", hs.results[0].Size)
+		if collectedResult(hs).Files[0].Size < 600 {
+			t.Errorf("expected size >= 600, got %d", collectedResult(hs).Files[0].Size)
 		}
-		if hs.results[0].Category != "node_modules" {
-			t.Errorf("expected category 'node_modules', got %q", hs.results[0].Category)
+		if collectedResult(hs).Files[0].Category != "node_modules" {
+			t.Errorf("expected category 'node_modules', got %q", collectedResult(hs).Files[0].Category)
 		}
-		if hs.results[0].Path != artifactDir {
-			t.Errorf("expected path %q, got %q", artifactDir, hs.results[0].Path)
+		if collectedResult(hs).Files[0].Path != artifactDir {
+			t.Errorf("expected path %q, got %q", artifactDir, collectedResult(hs).Files[0].Path)
 		}
 	}
 }
@@ -1437,8 +1681,8 @@ func TestAddArtifactResultNonExistent(t *testing.T) {
 	hs.addArtifactResult("/nonexistent/path", "node_modules")
 
 	// Non-existent paths should be skipped - no result added
-	if len(hs.results) != 0 {
-		t.Errorf("expected 0 results for nonexistent path, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 0 {
+		t.Errorf("expected 0 results for nonexistent path, got %d", len(collectedResult(hs).Files))
 	}
 }
 
@@ -1457,11 +1701,106 @@ func TestAddArtifactResultEmptyDirectory(t *testing.T) {
 	hs := NewHyperScanner(cfg, pInfo)
 	hs.addArtifactResult(emptyDir, "node_modules")
 
-	if len(hs.results) != 1 {
-		t.Errorf("expected 1 result for empty dir, got %d", len(hs.results))
+	if len(collectedResult(hs).Files) != 1 {
+		t.Errorf("expected 1 result for empty dir, got %d", len(collectedResult(hs).Files))
+	}
+	if len(collectedResult(hs).Files) > 0 && collectedResult(hs).Files[0].Size != 0 {
+		t.Errorf("expected size 0 for empty dir, got %d", collectedResult(hs).Files[0].Size)
+	}
+}
+
+func TestAddArtifactResultVerifySizes(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	artifactDir := filepath.Join(f.RootDir, "node_modules")
+	f.CreateRandomFile(filepath.Join("node_modules", "file1.js"), 100)
+	f.CreateRandomFile(filepath.Join("node_modules", "subpkg", "file2.js"), 250)
+
+	cfg := &config.Config{
+		Categories: config.Categories{NodeModules: true},
+		Dev:        config.DevConfig{VerifySizes: true},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{})
+
+	// An estimate in the cache must not be reused for an exact count
+	hs.cache.DirResults["artifact:"+artifactDir] = &CachedDirInfo{Path: artifactDir, TotalSize: 8192, FileCount: 1}
+	hs.cache.DirMtimes["artifact:"+artifactDir] = time.Now().Add(time.Hour)
+
+	hs.addArtifactResult(artifactDir, "node_modules")
+
+	if len(collectedResult(hs).Files) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(collectedResult(hs).Files))
+	}
+	if collectedResult(hs).Files[0].Size != 350 {
+		t.Errorf("expected exact size 350, got %d", collectedResult(hs).Files[0].Size)
+	}
+	if want := "node_modules of " + filepath.Base(filepath.Dir(artifactDir)) + ", untouched for less than an hour: 2 files"; collectedResult(hs).Files[0].Reason != want {
+		t.Errorf("unexpected reason %q", collectedResult(hs).Files[0].Reason)
+	}
+	if collectedResult(hs).Files[0].Files != 2 {
+		t.Errorf("expected the result to hold 2 files, got %d", collectedResult(hs).Files[0].Files)
+	}
+	if cached := hs.cache.DirResults["artifact:"+artifactDir]; !cached.Exact || cached.FileCount != 2 {
+		t.Errorf("exact count should be cached, got %+v", cached)
+	}
+}
+
+func TestAddArtifactResultsBatches(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	// More artifacts than one du call sizes
+	var artifacts []artifact
+	for i := 0; i < artifactBatchSize+5; i++ {
+		rel := filepath.Join(fmt.Sprintf("project%d", i), "node_modules")
+		f.CreateRandomFile(filepath.Join(rel, "index.js"), 20*1024)
+		artifacts = append(artifacts, artifact{path: filepath.Join(f.RootDir, rel), category: "node_modules"})
+	}
+
+	cfg := &config.Config{
+		Categories: config.Categories{NodeModules: true},
+		Dev:        config.DevConfig{SizeWorkers: 1},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{})
+	hs.DisableCache()
+	hs.addArtifactResults(artifacts)
+
+	if len(collectedResult(hs).Files) != len(artifacts) {
+		t.Fatalf("expected %d results, got %d", len(artifacts), len(collectedResult(hs).Files))
 	}
-	if len(hs.results) > 0 && hs.results[0].Size != 0 {
-		t.Errorf("expected size 0 for empty dir, got %d", hs.results[0].Size)
+	for _, result := range collectedResult(hs).Files {
+		if result.Size < 20*1024 {
+			t.Errorf("expected %s to be sized at least 20KB, got %d", result.Path, result.Size)
+		}
+	}
+}
+
+func TestParseDuSizes(t *testing.T) {
+	sizes := parseDuSizes("8\t/p/a/node_modules\n1024\t/p/with space/dist\ndu: /p/c: Permission denied\n")
+
+	if len(sizes) != 2 {
+		t.Fatalf("expected 2 sizes, got %v", sizes)
+	}
+	if sizes["/p/a/node_modules"] != 8*1024 {
+		t.Errorf("expected 8KB, got %d", sizes["/p/a/node_modules"])
+	}
+	if sizes["/p/with space/dist"] != 1024*1024 {
+		t.Errorf("expected 1MB, got %d", sizes["/p/with space/dist"])
+	}
+}
+
+func TestSizeWorkersFor(t *testing.T) {
+	cfg := &config.Config{Dev: config.DevConfig{SizeWorkers: 3}}
+	if got := artifactSizeWorkers(cfg, platform.StorageHDD); got != 3 {
+		t.Errorf("expected dev.size_workers to set 3 workers, got %d", got)
+	}
+	if got := sizeWorkersFor(platform.StorageHDD, 16); got != 2 {
+		t.Errorf("sizeWorkersFor(hdd, 16) = %d, want 2", got)
+	}
+	if got := sizeWorkersFor(platform.StorageSSD, 1); got != 2 {
+		t.Errorf("sizeWorkersFor(ssd, 1) = %d, want 2", got)
+	}
+	if got := sizeWorkersFor(platform.StorageSSD, 32); got != 8 {
+		t.Errorf("sizeWorkersFor(ssd, 32) = %d, want 8", got)
 	}
 }
 
@@ -1488,9 +1827,12 @@ func TestProgressCallbackCalledDuringScan(t *testing.T) {
 
 	hs := NewHyperScanner(cfg, pInfo)
 
-	callCount := 0
-	hs.SetProgressCallback(func(category, currentPath string, filesFound int, totalSize int64) {
-		callCount++
+	var mu sync.Mutex
+	var events []ProgressEvent
+	hs.SetProgressCallback(func(event ProgressEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
 	})
 
 	_, err := hs.ScanAll()
@@ -1498,7 +1840,51 @@ func TestProgressCallbackCalledDuringScan(t *testing.T) {
 		t.Fatalf("ScanAll failed: %v", err)
 	}
 
-	t.Logf("Progress callback called %d times", callCount)
+	t.Logf("Progress callback called %d times", len(events))
+	if len(events) == 0 {
+		t.Fatal("expected progress events")
+	}
+	last := events[len(events)-1]
+	if last.CategoriesDone != len(last.Categories) {
+		t.Errorf("expected every category done at the end, got %d of %d", last.CategoriesDone, len(last.Categories))
+	}
+	for _, event := range events {
+		if event.Phase != progress.PhaseScanning && event.Phase != progress.PhaseSizing {
+			t.Errorf("unexpected phase %q", event.Phase)
+		}
+	}
+
+	durations := hs.CategoryDurations()
+	if len(durations) != len(last.Categories) {
+		t.Errorf("expected a duration for every category, got %v", durations)
+	}
+	if _, ok := durations["cache"]; !ok {
+		t.Errorf("expected the cache category to be timed, got %v", durations)
+	}
+}
+
+func TestEstimateRemaining(t *testing.T) {
+	hs := &HyperScanner{cache: newScanCache()}
+	hs.cache.CategoryDurations["cache"] = 10 * time.Second
+	hs.cache.CategoryDurations["logs"] = 4 * time.Second
+
+	categories := []CategoryProgress{{Name: "cache"}, {Name: "logs"}}
+	if eta := hs.estimateRemaining(categories, 3*time.Second); eta != 7*time.Second {
+		t.Errorf("expected the slowest category to set the ETA, got %v", eta)
+	}
+	if eta := hs.estimateRemaining(categories, 15*time.Second); eta != 0 {
+		t.Errorf("expected 0 once the scan overruns, got %v", eta)
+	}
+
+	categories[0].Done = true
+	if eta := hs.estimateRemaining(categories, 3*time.Second); eta != time.Second {
+		t.Errorf("expected finished categories to be ignored, got %v", eta)
+	}
+
+	categories = append(categories, CategoryProgress{Name: "temp"})
+	if eta := hs.estimateRemaining(categories, 3*time.Second); eta != 0 {
+		t.Errorf("expected 0 when a category has no timing, got %v", eta)
+	}
 }
 
 // =============================================================================
@@ -1536,11 +1922,11 @@ func TestScanResultTotalSizeAccurate(t *testing.T) {
 	// Use direct scan method to ensure isolation
 	hs.scanDirsWithCache([]string{f.CacheDir}, "cache")
 
-	if atomic.LoadInt64(&hs.totalSize) != expectedTotal {
-		t.Errorf("expected total size %d, got %d", expectedTotal, atomic.LoadInt64(&hs.totalSize))
+	if collectedResult(hs).TotalSize != expectedTotal {
+		t.Errorf("expected total size %d, got %d", expectedTotal, collectedResult(hs).TotalSize)
 	}
-	if len(hs.results) != len(sizes) {
-		t.Errorf("expected %d files, got %d", len(sizes), len(hs.results))
+	if len(collectedResult(hs).Files) != len(sizes) {
+		t.Errorf("expected %d files, got %d", len(sizes), len(collectedResult(hs).Files))
 	}
 }
 
@@ -1571,3 +1957,2194 @@ func TestScanResultFilesMatchCount(t *testing.T) {
 		t.Errorf("Files length %d doesn't match TotalCount %d", len(result.Files), result.TotalCount)
 	}
 }
+
+// =============================================================================
+// Duplicate Files Tests
+// =============================================================================
+
+func TestScanDuplicates(t *testing.T) {
+	f := testutil.NewFixture(t)
+
+	// Large enough that the full hash has to confirm the quick hash
+	content := make([]byte, 200*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	sameEnds := make([]byte, len(content))
+	copy(sameEnds, content)
+	sameEnds[len(sameEnds)/2] ^= 0xff // identical first and last blocks, different middle
+
+	oldest := f.CreateFileWithAge(filepath.Join("dups", "Downloads", "a.bin"), content, 48*time.Hour)
+	older := f.CreateFileWithAge(filepath.Join("dups", "Projects", "app", "a.bin"), content, 24*time.Hour)
+	newest := f.CreateFile(filepath.Join("dups", "Documents", "a copy.bin"), content)
+	f.CreateFile(filepath.Join("dups", "Documents", "different.bin"), sameEnds)
+	f.CreateFile(filepath.Join("dups", ".hidden", "a.bin"), content)
+	os.Link(newest, f.Path(filepath.Join("dups", "Documents", "hardlink.bin")))
+
+	cfg := &config.Config{
+		Duplicates: config.DuplicatesConfig{
+			MinSize:   "1KB",
+			ScanPaths: []string{f.Path("dups")},
+		},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{})
+	result := hs.ScanCategory(DuplicatesCategory)
+
+	if len(result.Duplicates) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d", len(result.Duplicates))
+	}
+	group := result.Duplicates[0]
+	if len(group.Files) != 3 || group.Size != int64(len(content)) {
+		t.Fatalf("expected 3 copies of %d bytes, got %d of %d", len(content), len(group.Files), group.Size)
+	}
+	if group.Files[0].Path != newest && !strings.HasSuffix(group.Files[0].Path, "hardlink.bin") {
+		t.Errorf("expected newest copy first, got %s", group.Files[0].Path)
+	}
+
+	// The newest copy is kept, the rest are results
+	if result.TotalCount != 2 {
+		t.Fatalf("expected 2 copies to remove, got %d", result.TotalCount)
+	}
+	removed := map[string]bool{}
+	for _, file := range result.Files {
+		removed[file.Path] = true
+		if file.Category != DuplicatesCategory || file.Hash != group.Hash {
+			t.Errorf("unexpected result %+v", file)
+		}
+	}
+	if !removed[oldest] || !removed[older] {
+		t.Errorf("expected older copies to be removed, got %v", removed)
+	}
+}
+
+func TestChooseDuplicates(t *testing.T) {
+	now := time.Now()
+	group := DuplicateGroup{Hash: "h", Size: 10, Files: []FileInfo{
+		{Path: "/new", Size: 10, ModTime: now},
+		{Path: "/old", Size: 10, ModTime: now.Add(-time.Hour)},
+	}}
+	result := &ScanResult{
+		Files: []FileInfo{
+			{Path: "/cache/x", Size: 5, Category: "cache"},
+			duplicateResult(group.Files[1], group),
+		},
+		Duplicates: []DuplicateGroup{group},
+	}
+
+	// Keep the older copy instead
+	chosen := result.ChooseDuplicates([]string{"/new"})
+	if chosen.TotalCount != 2 || chosen.TotalSize != 15 || chosen.Files[1].Path != "/new" {
+		t.Errorf("unexpected files after choosing: %+v", chosen.Files)
+	}
+
+	// Removing every copy drops the whole group
+	chosen = result.ChooseDuplicates([]string{"/new", "/old"})
+	if chosen.TotalCount != 1 || chosen.Files[0].Category != "cache" {
+		t.Errorf("expected no duplicates when every copy is chosen, got %+v", chosen.Files)
+	}
+
+	if filtered := result.FilterCategories([]string{"cache"}); filtered.Duplicates != nil {
+		t.Error("expected duplicate groups to be dropped with the category")
+	}
+}
+
+func TestSystemFiles(t *testing.T) {
+	result := &ScanResult{Files: []FileInfo{
+		{Path: "/home/user/.cache/pip/x"},
+		{Path: "/tmp/build.log"},
+		{Path: "/private/var/tmp/x"},
+		{Path: "/var/cache/apt/archives/pkg.deb"},
+		{Path: "/Library/Caches/Homebrew/y"},
+		{Path: "/home/username/z"}, // not under /home/user
+	}}
+
+	files, err := result.SystemFiles("/home/user")
+	if err != nil {
+		t.Fatalf("SystemFiles failed: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	want := []string{"/var/cache/apt/archives/pkg.deb", "/Library/Caches/Homebrew/y", "/home/username/z"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("SystemFiles = %v, want %v", paths, want)
+	}
+}
+
+// =============================================================================
+// Spilled Result Tests
+// =============================================================================
+
+// spilledResult returns a result with n files, all but the last few spilled
+// to a result index
+func spilledResult(t *testing.T, n int) *ScanResult {
+	t.Helper()
+	var files []FileInfo
+	for i := 0; i < n; i++ {
+		category := "cache"
+		if i%2 == 1 {
+			category = "logs"
+		}
+		files = append(files, FileInfo{Path: fmt.Sprintf("/f/%d", i), Size: 10, Category: category})
+	}
+
+	index, err := newResultIndex()
+	if err != nil {
+		t.Fatalf("newResultIndex failed: %v", err)
+	}
+	if err := index.append(files[:n-5]); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	result := &ScanResult{Files: files[n-5:], TotalCount: n, TotalSize: int64(n) * 10, spilled: index}
+	t.Cleanup(func() { result.Close() })
+	return result
+}
+
+func TestSpilledResultPaging(t *testing.T) {
+	n := spillBatchSize*2 + 100
+	result := spilledResult(t, n)
+
+	if !result.Spilled() || result.Len() != n {
+		t.Fatalf("expected %d spilled results, got %d (spilled %v)", n, result.Len(), result.Spilled())
+	}
+
+	// Pages crossing a batch boundary and the end of the spilled files
+	for _, offset := range []int{0, spillBatchSize - 3, n - 8} {
+		page, err := result.Page(offset, 6)
+		if err != nil {
+			t.Fatalf("Page failed: %v", err)
+		}
+		if len(page) != 6 {
+			t.Fatalf("expected 6 files at %d, got %d", offset, len(page))
+		}
+		for i, file := range page {
+			if want := fmt.Sprintf("/f/%d", offset+i); file.Path != want {
+				t.Errorf("expected %s at %d, got %s", want, offset+i, file.Path)
+			}
+		}
+	}
+	if page, _ := result.Page(n-2, 10); len(page) != 2 {
+		t.Errorf("expected the last page cut short, got %d files", len(page))
+	}
+
+	var count int
+	result.Each(func(file FileInfo) error {
+		if want := fmt.Sprintf("/f/%d", count); file.Path != want {
+			t.Fatalf("expected %s, got %s", want, file.Path)
+		}
+		count++
+		return nil
+	})
+	if count != n {
+		t.Errorf("Each visited %d of %d files", count, n)
+	}
+}
+
+func TestSpilledResultFilterAndLoad(t *testing.T) {
+	result := spilledResult(t, spillBatchSize+10)
+
+	totals, err := result.CategoryTotals()
+	if err != nil {
+		t.Fatalf("CategoryTotals failed: %v", err)
+	}
+	if totals["cache"].Count != spillBatchSize/2+5 || totals["logs"].Count != spillBatchSize/2+5 {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+
+	logs := result.FilterCategories([]string{"logs"})
+	defer logs.Close()
+	if !logs.Spilled() {
+		t.Error("filtering spilled files should keep them spilled")
+	}
+	if logs.TotalCount != spillBatchSize/2+5 || logs.Len() != logs.TotalCount {
+		t.Errorf("expected %d logs, got count %d and length %d", spillBatchSize/2+5, logs.TotalCount, logs.Len())
+	}
+
+	path := result.spilled.file.Name()
+	if err := result.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if result.Spilled() || len(result.Files) != spillBatchSize+10 || result.Files[0].Path != "/f/0" {
+		t.Errorf("expected every file loaded in order, got %d", len(result.Files))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("loading should remove the result index")
+	}
+}
+
+func TestScanAllSpillsPastMaxResults(t *testing.T) {
+	f := testutil.NewFixture(t)
+	for i := 0; i < 25; i++ {
+		f.CreateCacheFile(fmt.Sprintf("file%d.cache", i), 100)
+	}
+
+	cfg := &config.Config{
+		Categories: config.Categories{Cache: true},
+		Scan:       config.ScanConfig{MaxResults: 10},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{CacheDirs: []string{f.CacheDir}})
+	hs.cachePath = filepath.Join(t.TempDir(), "cache")
+
+	result, err := hs.ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	defer result.Close()
+
+	if !result.Spilled() {
+		t.Fatal("expected results past max_results to be spilled")
+	}
+	if len(result.Files) >= 10 {
+		t.Errorf("expected fewer than 10 results in memory, got %d", len(result.Files))
+	}
+	if result.Len() != result.TotalCount || result.TotalCount < 25 {
+		t.Errorf("expected every result counted, got length %d and count %d", result.Len(), result.TotalCount)
+	}
+}
+
+func TestStreamSendsEveryResult(t *testing.T) {
+	f := testutil.NewFixture(t)
+	for i := 0; i < 25; i++ {
+		f.CreateCacheFile(fmt.Sprintf("file%d.cache", i), 100)
+	}
+
+	cfg := &config.Config{Categories: config.Categories{Cache: true}}
+	hs := NewHyperScanner(cfg, &platform.Info{HomeDir: f.RootDir, CacheDirs: []string{f.CacheDir}})
+	hs.DisableCache()
+
+	stream := hs.Stream(10)
+	sent := 0
+	for batch := range stream.Batches {
+		sent += len(batch)
+		// Totals can be read while the scan waits for the next batch to be taken
+		hs.CategoryTotal("cache")
+	}
+	result, err := stream.Wait()
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("expected every file to be sent on Batches, %d were kept", len(result.Files))
+	}
+	if sent != result.TotalCount || sent < 25 {
+		t.Errorf("expected every result sent, got %d sent and a count of %d", sent, result.TotalCount)
+	}
+}
+
+// =============================================================================
+// Collector Tests
+// =============================================================================
+
+// collectedResult returns what hs has found so far
+func collectedResult(hs *HyperScanner) *ScanResult {
+	return hs.results.result("", nil)
+}
+
+func TestCollectorConcurrentAdds(t *testing.T) {
+	c := newCollector(0)
+	var streamed []FileInfo
+	c.setStream(7, func(batch []FileInfo) error {
+		streamed = append(streamed, batch...)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.add(FileInfo{Path: fmt.Sprintf("/f/%d/%d", g, i), Size: 1, Category: "cache"}, 1)
+				c.categoryTotal("cache")
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := c.result("", nil)
+	if result.TotalCount != 800 || len(streamed)+len(result.Files) != 800 {
+		t.Errorf("expected 800 results, got a count of %d with %d streamed and %d kept", result.TotalCount, len(streamed), len(result.Files))
+	}
+	if result.TotalSize != 800 || c.categoryTotal("cache").Count != 800 {
+		t.Errorf("expected totals for 800 results, got size %d and %+v", result.TotalSize, c.categoryTotal("cache"))
+	}
+}
+
+func TestCollectorKeepsResultsWhenStreamFails(t *testing.T) {
+	c := newCollector(0)
+	c.setStream(2, func(batch []FileInfo) error {
+		return fmt.Errorf("disk full")
+	})
+	for i := 0; i < 5; i++ {
+		c.add(FileInfo{Path: fmt.Sprintf("/f/%d", i), Category: "cache"}, 1)
+	}
+
+	result := c.result("", nil)
+	if len(result.Files) != 5 || result.TotalCount != 5 {
+		t.Errorf("expected all 5 results kept, got %d of %d", len(result.Files), result.TotalCount)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("expected the stream error to be reported, got %v", result.Errors)
+	}
+}
+
+// =============================================================================
+// WSL Tests
+// =============================================================================
+
+func TestScanWSLCategory(t *testing.T) {
+	f := testutil.NewFixture(t)
+	mount := f.Path("mnt/c")
+
+	old := 60 * 24 * time.Hour
+	tarball := f.CreateFileWithAge("mnt/c/Users/alice/Downloads/ubuntu-22.04-rootfs.tar.gz", []byte("rootfs"), old)
+	export := f.CreateFileWithAge("mnt/c/Users/alice/kali-backup.tar", []byte("export"), old)
+	disk := f.CreateFile("mnt/c/Users/alice/AppData/Local/Docker/wsl/disk/docker_data.vhdx", []byte("disk"))
+	f.CreateFile("mnt/c/Users/alice/Downloads/debian.wsl", []byte("too new"))
+	f.CreateFileWithAge("mnt/c/Users/alice/Downloads/photos.tar", []byte("not a distro"), old)
+	f.CreateFileWithAge("mnt/c/Users/Public/Downloads/alpine.tar", []byte("shared"), old)
+
+	cfg := &config.Config{WSL: config.WSLConfig{TarballAgeDays: 30}}
+	pInfo := &platform.Info{WSL: true, Username: "alice", WindowsMounts: []string{mount}}
+
+	result := NewHyperScanner(cfg, pInfo).ScanCategory(WSLCategory)
+
+	found := map[string]bool{}
+	for _, file := range result.Files {
+		found[file.Path] = true
+		if file.Category != WSLCategory {
+			t.Errorf("unexpected category %q for %s", file.Category, file.Path)
+		}
+	}
+	if len(found) != 2 || !found[tarball] || !found[export] {
+		t.Errorf("expected the old tarballs, got %v", found)
+	}
+
+	// Docker Desktop's disk is a VM disk, compacted rather than deleted
+	pInfo.HomeDir = f.Path("home/alice")
+	disks := NewHyperScanner(cfg, pInfo).FindVMDisks()
+	if len(disks) != 1 || disks[0].Path != disk || disks[0].Runtime != RuntimeDockerDesktop || disks[0].Docker == nil {
+		t.Errorf("expected the Docker Desktop disk among the VM disks, got %+v", disks)
+	}
+
+	// Nothing to find outside WSL
+	result = NewHyperScanner(cfg, &platform.Info{WindowsMounts: []string{mount}}).ScanCategory(WSLCategory)
+	if result.TotalCount != 0 {
+		t.Errorf("expected no results outside WSL, got %d", result.TotalCount)
+	}
+}
+
+func TestScansSkipWindowsDrives(t *testing.T) {
+	f := testutil.NewFixture(t)
+	f.CreateFileWithAge("root/home/old.txt", []byte("linux"), 400*24*time.Hour)
+	f.CreateFileWithAge("root/mnt/c/old.txt", []byte("windows"), 400*24*time.Hour)
+
+	cfg := &config.Config{OldFiles: config.OldFilesConfig{MinAgeDays: 30}}
+	pInfo := &platform.Info{WSL: true, WindowsMounts: []string{f.Path("root/mnt/c")}}
+	hs := NewHyperScanner(cfg, pInfo)
+
+	hs.scanOldFilesManual(f.Path("root"))
+	if len(collectedResult(hs).Files) != 1 || strings.Contains(collectedResult(hs).Files[0].Path, "mnt") {
+		t.Errorf("expected only the Linux file, got %+v", collectedResult(hs).Files)
+	}
+
+	cfg.WSL.ScanWindowsDrives = true
+	hs.resetResults(0)
+	hs.scanOldFilesManual(f.Path("root"))
+	if len(collectedResult(hs).Files) != 2 {
+		t.Errorf("expected the Windows drive to be scanned when enabled, got %+v", collectedResult(hs).Files)
+	}
+}
+
+func TestIsDistroImage(t *testing.T) {
+	tests := map[string]bool{
+		"Ubuntu2204.appxbundle":       true,
+		"debian.wsl":                  true,
+		"alpine-minirootfs.tar.gz":    true,
+		"Fedora-Export.TAR":           true,
+		"archlinux-bootstrap.tar.zst": true,
+		"project.tar.gz":              false,
+		"ubuntu-22.04.iso":            false,
+		"wsl-notes.txt":               false,
+	}
+	for name, want := range tests {
+		if got := isDistroImage(name); got != want {
+			t.Errorf("isDistroImage(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// =============================================================================
+// Virtual File System Tests
+// =============================================================================
+
+func TestScanVirtualFS(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Projects/app/node_modules/lib/index.js", 3000, old)
+	mem.AddFile("/home/user/Projects/app/node_modules/lib/util.js", 1000, old)
+	mem.AddFile("/home/user/Projects/app/package.json", 100, old)
+	mem.WriteFile("/home/user/Downloads/a.txt", []byte(strings.Repeat("x", 2048)), old)
+	mem.WriteFile("/home/user/Documents/a.txt", []byte(strings.Repeat("x", 2048)), time.Now())
+	mem.AddFile("/home/user/Documents/b.txt", 2048, old)
+
+	cfg := &config.Config{
+		Categories: config.Categories{NodeModules: true},
+		Dev:        config.DevConfig{ProjectDirs: []string{"~/Projects"}},
+		Duplicates: config.DuplicatesConfig{MinSize: "1KB", ScanPaths: []string{"~"}},
+	}
+	pInfo, err := platform.InfoFor(platform.Linux, "/home/user", "user")
+	if err != nil {
+		t.Fatalf("InfoFor failed: %v", err)
+	}
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory("node_modules")
+	if len(result.Files) != 1 || result.Files[0].Path != "/home/user/Projects/app/node_modules" {
+		t.Fatalf("expected the node_modules directory, got %+v", result.Files)
+	}
+	if result.Files[0].Size != 4000 {
+		t.Errorf("expected 4000 bytes, got %d", result.Files[0].Size)
+	}
+
+	// Size-only files never look like duplicates of each other
+	result = hs.ScanCategory(DuplicatesCategory)
+	if len(result.Files) != 1 || result.Files[0].Path != "/home/user/Downloads/a.txt" {
+		t.Errorf("expected the older copy of a.txt, got %+v", result.Files)
+	}
+}
+
+func TestScanOldFilesDirectories(t *testing.T) {
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Downloads/project/a.txt", 100, old)
+	mem.AddFile("/home/user/Downloads/project/sub/b.txt", 200, old)
+	mem.AddFile("/home/user/Downloads/mixed/old.txt", 10, old)
+	mem.AddFile("/home/user/Downloads/mixed/new.txt", 10, time.Now())
+	mem.AddFile("/home/user/Downloads/loose.txt", 5, old)
+	mem.AddFile("/home/user/Downloads/Important/x.txt", 5, old)
+	mem.MkdirAll("/home/user/Downloads/empty", old)
+
+	cfg := &config.Config{OldFiles: config.OldFilesConfig{
+		MinAgeDays:   30,
+		ExcludePaths: []string{"~/Downloads/Important"},
+	}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	hs.scanOldFilesManual("/home/user/Downloads")
+	found := make(map[string]FileInfo)
+	for _, file := range collectedResult(hs).Files {
+		found[file.Path] = file
+	}
+	if len(found) != 3 {
+		t.Errorf("expected 3 results, got %+v", collectedResult(hs).Files)
+	}
+	project, ok := found["/home/user/Downloads/project"]
+	if !ok || project.Size != 300 || !strings.HasPrefix(project.Reason, "Directory of 2 files, untouched for ") {
+		t.Errorf("expected the old directory as one entry, got %+v", project)
+	}
+	if _, ok := found["/home/user/Downloads/mixed/old.txt"]; !ok {
+		t.Error("old files in a directory with new ones should be reported one by one")
+	}
+	if _, ok := found["/home/user/Downloads/loose.txt"]; !ok {
+		t.Error("expected the old file at the top of the scan path")
+	}
+}
+
+func TestScanEmptyDirs(t *testing.T) {
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.MkdirAll("/home/user/.cache/gone/a/b", old)
+	mem.MkdirAll("/home/user/.cache/gone/c", old)
+	mem.MkdirAll("/home/user/.cache/app/tmp", old)
+	mem.AddFile("/home/user/.cache/app/data.db", 10, old)
+	mem.MkdirAll("/home/user/.cache/repo/.git/refs/tags", old)
+	mem.MkdirAll("/home/user/.cache/fresh", time.Now())
+
+	cfg := &config.Config{EmptyDirs: config.EmptyDirsConfig{ScanPaths: []string{"~/.cache"}, MinAgeDays: 7}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(EmptyDirsCategory)
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	want := []string{"/home/user/.cache/app/tmp", "/home/user/.cache/gone"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestScanVMImages(t *testing.T) {
+	t.Setenv("VAGRANT_HOME", "")
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	recent := time.Now().Add(-24 * time.Hour)
+	mem := vfs.NewMemFS()
+
+	// VirtualBox: one machine idle, one started yesterday, one registered elsewhere
+	mem.WriteFile("/home/user/VirtualBox VMs/Idle/Idle.vbox", []byte("<VirtualBox/>"), old)
+	mem.AddFile("/home/user/VirtualBox VMs/Idle/Idle.vdi", 4000, old)
+	mem.AddFile("/home/user/VirtualBox VMs/Idle/Logs/VBox.log", 100, old)
+	mem.WriteFile("/home/user/VirtualBox VMs/Busy/Busy.vbox", []byte("<VirtualBox/>"), old)
+	mem.AddFile("/home/user/VirtualBox VMs/Busy/Logs/VBox.log", 100, recent)
+	mem.WriteFile("/home/user/.config/VirtualBox/VirtualBox.xml", []byte(`<VirtualBox><Global><MachineRegistry>
+  <MachineEntry uuid="{1}" src="/data/vms/Away/Away.vbox"/>
+  <MachineEntry uuid="{2}" src="/home/user/VirtualBox VMs/Idle/Idle.vbox"/>
+</MachineRegistry></Global></VirtualBox>`), old)
+	mem.WriteFile("/data/vms/Away/Away.vbox", []byte("<VirtualBox/>"), old)
+
+	// Vagrant: one box version no machine uses, one a machine was created from
+	mem.AddFile("/home/user/.vagrant.d/boxes/hashicorp-VAGRANTSLASH-bionic64/1.0.0/virtualbox/box.vmdk", 3000, old)
+	mem.AddFile("/home/user/.vagrant.d/boxes/generic-VAGRANTSLASH-alpine/2.0.0/virtualbox/box.vmdk", 3000, old)
+	mem.WriteFile("/home/user/.vagrant.d/data/machine-index/index", []byte(`{"version":1,"machines":{"abc":
+  {"name":"default","extra_data":{"box":{"name":"generic/alpine","provider":"virtualbox","version":"2.0.0"}}}}}`), old)
+
+	// Multipass: an image last launched long ago, whatever its file times
+	vault := "/var/snap/multipass/common/data/multipassd/vault"
+	mem.AddFile(vault+"/images/jammy-20240101/ubuntu-22.04-server-cloudimg-amd64.img", 2000, recent)
+	mem.WriteFile(vault+"/multipassd-image-records.json", []byte(fmt.Sprintf(`{"abc":{"image":{
+  "path":"%s/images/jammy-20240101/ubuntu-22.04-server-cloudimg-amd64.img","original_release":"22.04 LTS"},
+  "last_accessed":%d}}`, vault, old.Unix())), recent)
+
+	// Loose disks
+	mem.AddFile("/home/user/.local/share/libvirt/images/win.qcow2", 5000, old)
+	mem.AddFile("/home/user/.local/share/libvirt/images/notes.txt", 10, old)
+	mem.AddFile("/home/user/isos/fresh.vmdk", 10, recent)
+
+	cfg := &config.Config{VMImages: config.VMImagesConfig{ScanPaths: []string{"~/isos"}, MinAgeDays: 90}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(VMImagesCategory)
+	found := map[string]FileInfo{}
+	for _, file := range result.Files {
+		found[file.Path] = file
+	}
+	want := []string{
+		"/data/vms/Away",
+		"/home/user/.local/share/libvirt/images/win.qcow2",
+		"/home/user/.vagrant.d/boxes/hashicorp-VAGRANTSLASH-bionic64/1.0.0",
+		"/home/user/VirtualBox VMs/Idle",
+		vault + "/images/jammy-20240101",
+	}
+	var paths []string
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+
+	idle := found["/home/user/VirtualBox VMs/Idle"]
+	if idle.Size != 4000+100+int64(len("<VirtualBox/>")) || idle.Files != 3 {
+		t.Errorf("expected the whole machine folder to be one result, got %d bytes in %d files", idle.Size, idle.Files)
+	}
+	if !strings.Contains(idle.Reason, `VirtualBox VM "Idle"`) || idle.Risk != RiskReview {
+		t.Errorf("unexpected reason %q or risk %q", idle.Reason, idle.Risk)
+	}
+	if image := found[vault+"/images/jammy-20240101"]; !strings.Contains(image.Reason, "22.04 LTS") {
+		t.Errorf("expected the Multipass release in the reason, got %q", image.Reason)
+	}
+}
+
+func TestUnixTime(t *testing.T) {
+	want := time.Unix(1700000000, 0)
+	for _, n := range []int64{want.Unix(), want.UnixMilli(), want.UnixMicro(), want.UnixNano()} {
+		if got := unixTime(n); !got.Equal(want) {
+			t.Errorf("unixTime(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestFindVMDisks(t *testing.T) {
+	t.Setenv("COLIMA_HOME", "")
+	t.Setenv("LIMA_HOME", "")
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Library/Containers/com.docker.docker/Data/vms/0/data/Docker.raw", 64<<30, now)
+	mem.AddFile("/home/user/Library/Containers/com.docker.docker/Data/log/vm/console.log", 10, now)
+	mem.AddFile("/home/user/.colima/_lima/colima/diffdisk", 100<<30, now)
+	mem.AddFile("/home/user/.colima/_lima/colima-work/diffdisk", 60<<30, now)
+	mem.AddFile("/home/user/.colima/_lima/_disks/colima/datadisk", 100<<30, now)
+	mem.AddFile("/home/user/.lima/default/diffdisk", 100<<30, now)
+	mem.AddFile("/home/user/.lima/docker/diffdisk", 100<<30, now)
+	mem.AddFile("/home/user/.lima/docker/sock/docker.sock", 0, now)
+	mem.MkdirAll("/home/user/.lima/_config", now)
+
+	pInfo, _ := platform.InfoFor(platform.MacOS, "/home/user", "user")
+	hs := NewHyperScanner(&config.Config{}, pInfo)
+	hs.SetFS(mem)
+
+	var got []string
+	for _, disk := range hs.FindVMDisks() {
+		got = append(got, fmt.Sprintf("%s:%s:%s:%v", disk.Runtime, disk.Name, filepath.Base(disk.Path), disk.Docker))
+		if disk.Size == 0 || disk.Allocated == 0 {
+			t.Errorf("expected %s to be measured, got %+v", disk.Path, disk)
+		}
+	}
+	want := []string{
+		"docker-desktop::Docker.raw:[--context desktop-linux]",
+		"colima:work:diffdisk:[--context colima-work]",
+		"colima:default:diffdisk:[--context colima]",
+		"colima:default:datadisk:[--context colima]",
+		"lima:default:diffdisk:[]",
+		"lima:docker:diffdisk:[--host unix:///home/user/.lima/docker/sock/docker.sock]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestAnalyzeRegistry(t *testing.T) {
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	root := "/var/lib/registry/docker/registry/v2"
+	digest := func(n int) string { return fmt.Sprintf("%064x", n) }
+	blob := func(n int, data string, size int64, age time.Duration) {
+		path := filepath.Join(root, "blobs/sha256", digest(n)[:2], digest(n), "data")
+		if data != "" {
+			mem.WriteFile(path, []byte(data), now.Add(-age))
+		} else {
+			mem.AddFile(path, size, now.Add(-age))
+		}
+	}
+	link := func(path string, n int) {
+		mem.WriteFile(filepath.Join(root, "repositories", path), []byte("sha256:"+digest(n)), now)
+	}
+	manifest := func(config int, layers ...int) string {
+		m := fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":"sha256:%s"},"layers":[`, digest(config))
+		for i, layer := range layers {
+			if i > 0 {
+				m += ","
+			}
+			m += fmt.Sprintf(`{"digest":"sha256:%s"}`, digest(layer))
+		}
+		return m + "]}"
+	}
+
+	// app:v1 is manifest 1, which shares layer 11 with the untagged manifest 2
+	blob(1, manifest(10, 11, 12), 0, 0)
+	blob(10, "", 1000, 0)
+	blob(11, "", 5000, 0)
+	blob(12, "", 3000, 0)
+	blob(2, manifest(20, 21, 11), 0, 40*24*time.Hour)
+	blob(20, "", 1000, 40*24*time.Hour)
+	blob(21, "", 4000, 40*24*time.Hour)
+	link("library/app/_manifests/tags/v1/current/link", 1)
+	link("library/app/_manifests/revisions/sha256/"+digest(1)+"/link", 1)
+	link("library/app/_manifests/revisions/sha256/"+digest(2)+"/link", 2)
+	link("library/app/_layers/sha256/"+digest(11)+"/link", 11)
+
+	// tools:latest is an index of manifest 4
+	blob(3, fmt.Sprintf(`{"manifests":[{"digest":"sha256:%s"}]}`, digest(4)), 0, 0)
+	blob(4, manifest(40, 12), 0, 0)
+	blob(40, "", 1000, 0)
+	link("tools/_manifests/tags/latest/current/link", 3)
+	link("tools/_manifests/tags/gone/index/sha256/"+digest(9)+"/link", 9)
+
+	// Blob 50 is left from a deleted image; 51 is an unfinished upload
+	blob(50, "", 7000, 100*24*time.Hour)
+	mem.MkdirAll(filepath.Join(root, "blobs/sha256", digest(51)[:2], digest(51)), now)
+
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{HomeDir: "/home/user"})
+	hs.SetFS(mem)
+	registry, err := hs.AnalyzeRegistry("/var/lib/registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.Repositories != 2 || registry.Tags != 2 {
+		t.Errorf("expected 2 repositories with 2 tags, got %d with %d", registry.Repositories, registry.Tags)
+	}
+
+	want := map[int]string{
+		1: BlobTagged, 10: BlobTagged, 11: BlobTagged, 12: BlobTagged,
+		3: BlobTagged, 4: BlobTagged, 40: BlobTagged,
+		2: BlobUntagged, 20: BlobUntagged, 21: BlobUntagged,
+		50: BlobUnreferenced,
+	}
+	if len(registry.Blobs) != len(want) {
+		t.Errorf("expected %d blobs, got %d", len(want), len(registry.Blobs))
+	}
+	for n, reach := range want {
+		for _, b := range registry.Blobs {
+			if b.Digest == "sha256:"+digest(n) && b.Reach != reach {
+				t.Errorf("blob %d: expected %s, got %s", n, reach, b.Reach)
+			}
+		}
+	}
+	if registry.Blobs[0].Digest != "sha256:"+digest(50) {
+		t.Errorf("expected the largest blob first, got %+v", registry.Blobs[0])
+	}
+
+	if _, err := hs.AnalyzeRegistry("/srv/registry"); err == nil {
+		t.Error("expected an error for a directory without registry storage")
+	}
+}
+
+func TestCacheRoots(t *testing.T) {
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	mem.MkdirAll("/home/user/.cache/pip", now)
+	mem.MkdirAll("/home/user/.cache-extra", now)
+	mem.MkdirAll("/home/user/.npm/_cacache", now)
+	mem.MkdirAll("/home/user/.mozilla/firefox/abc.default/cache2", now)
+	mem.MkdirAll("/var/cache/apt", now)
+
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{
+		HomeDir: "/home/user",
+		CacheDirs: []string{
+			"/home/user/.cache/pip",
+			"/home/user/.cache-extra",
+			"/home/user/.npm",
+			"/home/user/.mozilla/firefox/*/cache2",
+			"/home/user/.gradle/caches",
+			"/var/cache/apt",
+		},
+	})
+	hs.SetFS(mem)
+
+	want := []string{
+		"/home/user/.cache",
+		"/home/user/.cache-extra",
+		"/home/user/.mozilla/firefox/abc.default/cache2",
+		"/home/user/.npm",
+	}
+	if got := hs.CacheRoots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFindForeignOwned(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root to give files to another user")
+	}
+	home := t.TempDir()
+	npm := filepath.Join(home, ".npm")
+	stuck := filepath.Join(npm, "_cacache")
+	if err := os.MkdirAll(filepath.Join(stuck, "index"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(stuck, "index", "a"), make([]byte, 100), 0644)
+	os.WriteFile(filepath.Join(stuck, "b"), make([]byte, 50), 0644)
+	os.WriteFile(filepath.Join(npm, "mine"), make([]byte, 10), 0644)
+	for _, path := range []string{stuck, filepath.Join(stuck, "index"), filepath.Join(stuck, "index", "a"), filepath.Join(stuck, "b")} {
+		if err := os.Chown(path, 4242, 4242); err != nil {
+			t.Skipf("can't change owner: %v", err)
+		}
+	}
+
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{HomeDir: home})
+	found := hs.FindForeignOwned([]string{npm, "/etc"})
+	if len(found) != 1 {
+		t.Fatalf("expected only the topmost foreign directory, got %+v", found)
+	}
+	if got := found[0]; got.Path != stuck || got.Files != 2 || got.Size != 150 || got.Owner != "4242" {
+		t.Errorf("unexpected %+v", got)
+	}
+}
+
+func TestClassifyFile(t *testing.T) {
+	now := time.Now()
+	iso := make([]byte, 0x8001+5)
+	copy(iso[0x8001:], "CD001")
+	dmg := make([]byte, 2048)
+	copy(dmg[len(dmg)-512:], "koly")
+
+	mem := vfs.NewMemFS()
+	files := map[string][]byte{
+		"/data/movie.MKV":     nil,
+		"/data/backup.tar.gz": nil,
+		"/data/clip":          append([]byte("\x00\x00\x00\x18ftypmp42"), make([]byte, 16)...),
+		"/data/photo":         append([]byte("\x00\x00\x00\x18ftypheic"), make([]byte, 16)...),
+		"/data/install":       iso,
+		"/data/app":           dmg,
+		"/data/bundle":        []byte("PK\x03\x04rest"),
+		"/data/store":         []byte("SQLite format 3\x00rest"),
+		"/data/disk":          []byte("QFI\xfb\x00\x00\x00\x03"),
+		"/data/blob":          []byte("nothing to see here"),
+	}
+	for path, data := range files {
+		mem.WriteFile(path, data, now)
+	}
+
+	tests := map[string]string{
+		"/data/movie.MKV":     TypeVideo,
+		"/data/backup.tar.gz": TypeArchive,
+		"/data/clip":          TypeVideo,
+		"/data/photo":         TypeOther,
+		"/data/install":       TypeDiskImage,
+		"/data/app":           TypeDiskImage,
+		"/data/bundle":        TypeArchive,
+		"/data/store":         TypeDatabase,
+		"/data/disk":          TypeVMImage,
+		"/data/blob":          TypeOther,
+		"/data/missing":       TypeOther,
+	}
+	for path, want := range tests {
+		if got := ClassifyFile(mem, path); got != want {
+			t.Errorf("ClassifyFile(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLargeFileTypes(t *testing.T) {
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Movies/film.mp4", 3000, now)
+	mem.AddFile("/home/user/Movies/trailer.mov", 2000, now)
+	mem.AddFile("/home/user/Downloads/ubuntu.iso", 5000, now)
+	mem.AddFile("/home/user/Downloads/notes.txt", 1500, now)
+
+	cfg := &config.Config{LargeFiles: config.LargeFilesConfig{MinSize: "1KB", ScanPaths: []string{"~"}}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory("large_files")
+	totals, err := result.TypeTotals()
+	if err != nil {
+		t.Fatalf("TypeTotals failed: %v", err)
+	}
+	want := map[string]CategoryTotal{
+		TypeVideo:     {Count: 2, Size: 5000},
+		TypeDiskImage: {Count: 1, Size: 5000},
+		TypeOther:     {Count: 1, Size: 1500},
+	}
+	if !reflect.DeepEqual(totals, want) {
+		t.Errorf("expected totals %v, got %v", want, totals)
+	}
+
+	videos := result.FilterTypes([]string{TypeVideo})
+	if videos.TotalCount != 2 || videos.TotalSize != 5000 {
+		t.Errorf("expected 2 videos of 5000 bytes, got %d of %d", videos.TotalCount, videos.TotalSize)
+	}
+}
+
+func TestAnalyzeDir(t *testing.T) {
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Projects/app/main.go", 100, now)
+	mem.AddFile("/home/user/Projects/app/build/app.bin", 4000, now)
+	mem.AddFile("/home/user/Projects/notes.txt", 20, now)
+	mem.AddFile("/home/user/Documents/other.pdf", 500, now)
+
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(&config.Config{}, pInfo)
+	hs.SetFS(mem)
+
+	result, err := hs.AnalyzeDir("~/Projects")
+	if err != nil {
+		t.Fatalf("AnalyzeDir failed: %v", err)
+	}
+	if result.TotalCount != 3 || result.TotalSize != 4120 {
+		t.Errorf("expected 3 files of 4120 bytes, got %d of %d", result.TotalCount, result.TotalSize)
+	}
+	for _, file := range result.Files {
+		if file.Category != AnalyzeCategory {
+			t.Errorf("expected category %q, got %q", AnalyzeCategory, file.Category)
+		}
+	}
+
+	if _, err := hs.AnalyzeDir("/home/user/Projects/notes.txt"); err == nil {
+		t.Error("expected an error analyzing a file")
+	}
+	if _, err := hs.AnalyzeDir("/home/user/missing"); err == nil {
+		t.Error("expected an error analyzing a missing directory")
+	}
+}
+
+func TestSortByFiles(t *testing.T) {
+	result := &ScanResult{Files: []FileInfo{
+		{Path: "/big.iso", Size: 4 << 30},
+		{Path: "/app/node_modules", Size: 300 << 20, Files: 40000},
+		{Path: "/lib/__pycache__", Size: 2 << 20, Files: 900},
+		{Path: "/web/node_modules", Size: 100 << 20, Files: 40000},
+	}}
+	if err := result.SortByFiles(); err != nil {
+		t.Fatalf("SortByFiles failed: %v", err)
+	}
+
+	var got []string
+	for _, file := range result.Files {
+		got = append(got, file.Path)
+	}
+	want := []string{"/web/node_modules", "/app/node_modules", "/lib/__pycache__", "/big.iso"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if n := result.Files[3].FileCount(); n != 1 {
+		t.Errorf("a single file should count as 1, got %d", n)
+	}
+}
+
+// =============================================================================
+// Scan Cache Tests
+// =============================================================================
+
+func TestScanCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan_cache.gob")
+	hs := &HyperScanner{cachePath: path}
+	hs.loadCache()
+	hs.cache.DirMtimes["/a:cache"] = time.Unix(1700000000, 0)
+	if err := hs.saveCache(); err != nil {
+		t.Fatalf("saveCache failed: %v", err)
+	}
+
+	loaded := &HyperScanner{cachePath: path}
+	loaded.loadCache()
+	if errs := loaded.takeCacheErrors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if !loaded.cache.DirMtimes["/a:cache"].Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("cache not restored: %+v", loaded.cache)
+	}
+	if loaded.cache.DirResults == nil || loaded.cache.ArtifactDirs == nil {
+		t.Error("maps should be initialized after loading")
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if _, _, err := CheckCache(); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing cache, got %v", err)
+	}
+
+	hs := &HyperScanner{cachePath: cacheFile()}
+	hs.loadCache()
+	if err := hs.saveCache(); err != nil {
+		t.Fatalf("saveCache failed: %v", err)
+	}
+	path, lastScan, err := CheckCache()
+	if err != nil || time.Since(lastScan) > time.Minute {
+		t.Fatalf("expected a fresh cache, got %v written %v", err, lastScan)
+	}
+
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0644)
+	if _, _, err := CheckCache(); err == nil || os.IsNotExist(err) {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+}
+
+func TestScanCacheCorruptDiscarded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan_cache.gob")
+	hs := &HyperScanner{cachePath: path}
+	hs.loadCache()
+	hs.saveCache()
+
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0644)
+
+	loaded := &HyperScanner{cachePath: path}
+	loaded.loadCache()
+	errs := loaded.takeCacheErrors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "checksum") {
+		t.Fatalf("expected a checksum error, got %v", errs)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("corrupt cache should be deleted")
+	}
+	if len(loaded.cache.DirMtimes) != 0 {
+		t.Error("expected an empty cache")
+	}
+	if loaded.takeCacheErrors() != nil {
+		t.Error("errors should only be reported once")
+	}
+
+	// A cache from an older version is replaced quietly
+	os.WriteFile(path, []byte("raw gob"), 0644)
+	loaded.loadCache()
+	if errs := loaded.takeCacheErrors(); len(errs) != 0 {
+		t.Errorf("expected no warning for an old format, got %v", errs)
+	}
+}
+
+func TestIgnoreList(t *testing.T) {
+	dir := t.TempDir()
+	listPath := filepath.Join(dir, "data", "ignored")
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	other := filepath.Join(dir, "other.log")
+	for _, path := range []string{a, b, other} {
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+
+	list, err := LoadIgnoreList(listPath)
+	if err != nil || list.Len() != 0 {
+		t.Fatalf("expected an empty list before the first save, got %d entries, %v", list.Len(), err)
+	}
+	for _, path := range []string{a, b} {
+		if _, err := list.Add(path); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := list.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Replace b with a new file, which gets a new inode
+	os.WriteFile(b+".new", []byte("y"), 0644)
+	os.Rename(b+".new", b)
+
+	loaded, err := LoadIgnoreList(listPath)
+	if err != nil || loaded.Len() != 2 {
+		t.Fatalf("expected 2 entries after reloading, got %d, %v", loaded.Len(), err)
+	}
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{})
+	hs.SetIgnoreList(loaded)
+	for _, path := range []string{a, b, other} {
+		hs.appendResult(FileInfo{Path: path, Size: 1, Category: "logs"}, 1)
+	}
+	var got []string
+	for _, file := range collectedResult(hs).Files {
+		got = append(got, file.Path)
+	}
+	if want := []string{b, other}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+
+	if !loaded.Remove(a) || loaded.Remove(a) {
+		t.Error("expected Remove to report whether the path was listed")
+	}
+
+	// A corrupt list is an error rather than an empty one
+	data, _ := os.ReadFile(listPath)
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(listPath, data, 0644)
+	if _, err := LoadIgnoreList(listPath); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+}
+
+func TestScanCacheConcurrentSaves(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scan_cache.gob")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hs := &HyperScanner{cachePath: path}
+			hs.loadCache()
+			hs.cacheErrs = nil // Reads racing the first write may see nothing
+			for j := 0; j < 200; j++ {
+				hs.cache.DirMtimes[fmt.Sprintf("/dir%d/%d", i, j)] = time.Now()
+			}
+			if err := hs.saveCache(); err != nil {
+				t.Errorf("saveCache failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	hs := &HyperScanner{cachePath: path}
+	hs.loadCache()
+	if errs := hs.takeCacheErrors(); len(errs) != 0 {
+		t.Errorf("cache corrupted by concurrent saves: %v", errs)
+	}
+	// Runs that loaded an earlier run's cache add to it
+	if n := len(hs.cache.DirMtimes); n == 0 || n%200 != 0 {
+		t.Errorf("expected whole runs' entries, got %d", n)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+// =============================================================================
+// Risk Tests
+// =============================================================================
+
+func TestAssessRisk(t *testing.T) {
+	home := t.TempDir()
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{HomeDir: home})
+
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		file     FileInfo
+		expected string
+	}{
+		{"old cache", FileInfo{Path: filepath.Join(home, ".cache/x"), Category: "cache", ModTime: old}, RiskSafe},
+		{"new cache stays safe", FileInfo{Path: filepath.Join(home, ".cache/x"), Category: "cache", ModTime: recent}, RiskSafe},
+		{"system location", FileInfo{Path: "/opt/app/node_modules", Category: "node_modules", ModTime: old}, RiskLow},
+		{"scratch location", FileInfo{Path: "/tmp/build", Category: "build_artifacts", ModTime: old}, RiskSafe},
+		{"old log", FileInfo{Path: filepath.Join(home, "app.log"), Category: "logs", ModTime: old}, RiskLow},
+		{"new log", FileInfo{Path: filepath.Join(home, "app.log"), Category: "logs", ModTime: recent}, RiskReview},
+		{"large file", FileInfo{Path: filepath.Join(home, "movie.mkv"), Category: "large_files", ModTime: old}, RiskReview},
+		{"duplicate", FileInfo{Path: filepath.Join(home, "copy.txt"), Category: DuplicatesCategory, ModTime: old}, RiskReview},
+		{"wsl leftovers", FileInfo{Path: "/mnt/c/Users/alice/kali-backup.tar", Category: WSLCategory, ModTime: old}, RiskReview},
+		{"unknown category", FileInfo{Path: filepath.Join(home, "x"), Category: "something_new", ModTime: old}, RiskReview},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hs.assessRisk(tt.file, nil); got != tt.expected {
+				t.Errorf("assessRisk(%s, %s) = %s, want %s", tt.file.Category, tt.file.Path, got, tt.expected)
+			}
+		})
+	}
+
+	// Another user's files always need review
+	if os.Geteuid() != 0 {
+		return
+	}
+	theirs := filepath.Join(home, ".cache", "theirs")
+	if err := os.MkdirAll(theirs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(theirs, 4242, 4242); err != nil {
+		t.Skipf("can't change owner: %v", err)
+	}
+	info, err := os.Lstat(theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hs.assessRisk(FileInfo{Path: theirs, Category: "cache", ModTime: old}, info); got != RiskReview {
+		t.Errorf("assessRisk of another user's cache = %s, want %s", got, RiskReview)
+	}
+}
+
+func TestFilterRisk(t *testing.T) {
+	result := &ScanResult{
+		Files: []FileInfo{
+			{Path: "/a", Size: 1, Risk: RiskSafe},
+			{Path: "/b", Size: 2, Risk: RiskLow},
+			{Path: "/c", Size: 4, Risk: RiskReview},
+			{Path: "/d", Size: 8},
+		},
+		TotalSize:  15,
+		TotalCount: 4,
+		Duplicates: []DuplicateGroup{{Hash: "h"}},
+	}
+
+	safe := result.FilterRisk(RiskSafe)
+	if safe.TotalCount != 1 || safe.TotalSize != 1 || safe.Duplicates != nil {
+		t.Errorf("FilterRisk(safe) = %d files, %d bytes, %d duplicate groups", safe.TotalCount, safe.TotalSize, len(safe.Duplicates))
+	}
+	if low := result.FilterRisk(RiskLow); low.TotalCount != 2 || low.TotalSize != 3 {
+		t.Errorf("FilterRisk(low) = %d files, %d bytes", low.TotalCount, low.TotalSize)
+	}
+	if all := result.FilterRisk(RiskReview); all.TotalCount != 4 || len(all.Duplicates) != 1 {
+		t.Errorf("FilterRisk(review) = %d files, %d duplicate groups", all.TotalCount, len(all.Duplicates))
+	}
+
+	// Results without a level count as needing review
+	totals, err := result.RiskTotals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals[RiskReview].Count != 2 || totals[RiskReview].Size != 12 || totals[RiskSafe].Count != 1 {
+		t.Errorf("RiskTotals = %v", totals)
+	}
+
+	if err := ValidRisk("medium"); err == nil {
+		t.Error("expected an unknown risk level to be rejected")
+	}
+}
+
+func TestAnnotateOwner(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hs := NewHyperScanner(&config.Config{}, &platform.Info{HomeDir: dir})
+
+	file := FileInfo{Path: path, Category: "cache"}
+	hs.annotate(&file)
+	info, _ := os.Lstat(path)
+	uid, gid, ok := platform.FileOwner(info)
+	if !ok {
+		t.Skip("owners aren't reported on this platform")
+	}
+	if file.Owner != platform.UserName(uid) || file.Group != platform.GroupName(gid) || file.Owner == "" {
+		t.Errorf("owner = %q:%q, want %q:%q", file.Owner, file.Group, platform.UserName(uid), platform.GroupName(gid))
+	}
+
+	// Missing files have no owner
+	missing := FileInfo{Path: filepath.Join(dir, "missing"), Category: "cache"}
+	hs.annotate(&missing)
+	if missing.Owner != "" || missing.Risk == "" {
+		t.Errorf("missing file: owner %q, risk %q", missing.Owner, missing.Risk)
+	}
+}
+
+func TestFilterOwners(t *testing.T) {
+	result := &ScanResult{
+		Files: []FileInfo{
+			{Path: "/tmp/a", Size: 1, Owner: "alice"},
+			{Path: "/tmp/b", Size: 2, Owner: "bob"},
+			{Path: "/tmp/c", Size: 4, Owner: "alice"},
+			{Path: "/tmp/d", Size: 8},
+		},
+		TotalSize:  15,
+		TotalCount: 4,
+		Duplicates: []DuplicateGroup{{Hash: "h"}},
+	}
+
+	alice := result.FilterOwners([]string{"alice"})
+	if alice.TotalCount != 2 || alice.TotalSize != 5 || alice.Duplicates != nil {
+		t.Errorf("FilterOwners(alice) = %d files, %d bytes, %d duplicate groups", alice.TotalCount, alice.TotalSize, len(alice.Duplicates))
+	}
+	if both := result.FilterOwners([]string{"alice", "bob"}); both.TotalCount != 3 {
+		t.Errorf("FilterOwners(alice, bob) = %d files, want 3", both.TotalCount)
+	}
+
+	totals, err := result.OwnerTotals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]CategoryTotal{"alice": {Count: 2, Size: 5}, "bob": {Count: 1, Size: 2}}
+	if !reflect.DeepEqual(totals, want) {
+		t.Errorf("OwnerTotals = %v, want %v", totals, want)
+	}
+
+	// Numeric IDs match the name they stand for
+	root := platform.UserName(0)
+	result.Files[1].Owner = root
+	if byID := result.FilterOwners([]string{"0"}); byID.TotalCount != 1 || byID.Files[0].Path != "/tmp/b" {
+		t.Errorf("FilterOwners(0) = %v, want /tmp/b owned by %s", byID.Files, root)
+	}
+}
+
+func TestScanCloudCLI(t *testing.T) {
+	t.Setenv("CLOUDSDK_CONFIG", "")
+	t.Setenv("AZURE_CONFIG_DIR", "/home/user/az")
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.aws/cli/cache/abc.json", 100, old)
+	mem.AddFile("/home/user/.aws/credentials", 100, old)
+	mem.AddFile("/home/user/.config/gcloud/logs/2026.01.01/10.00.00.log", 5000, old)
+	mem.AddFile("/home/user/.config/gcloud/credentials.db", 100, old)
+	mem.AddFile("/home/user/az/telemetry/20260101", 300, old)
+	mem.AddFile("/home/user/az/azureProfile.json", 100, old)
+	mem.AddFile("/home/user/.azure/logs/az.log", 300, old)
+
+	cfg := &config.Config{CloudCLI: config.CloudCLIConfig{AWS: false, GCloud: true, Azure: true}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(CloudCLICategory)
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+		if file.Risk != RiskLow {
+			t.Errorf("expected %s to be rated low risk, got %q", file.Path, file.Risk)
+		}
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/home/user/.config/gcloud/logs/2026.01.01/10.00.00.log",
+		"/home/user/az/telemetry/20260101",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestScanDesktopCaches(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "")
+	mem := vfs.NewMemFS()
+	now := time.Now()
+	mem.AddFile("/home/user/.cache/fontconfig/abc-le64.cache-9", 2000, now)
+	mem.AddFile("/home/user/.cache/fontconfig/def-le64.cache-9", 3000, now)
+	mem.AddFile("/home/user/.cache/icon-cache.kcache", 500, now)
+	mem.AddFile("/home/user/.cache/ksycoca6_en_abc", 700, now)
+	mem.AddFile("/home/user/.cache/mozilla/firefox/cache2/entry", 100, now)
+
+	cfg := &config.Config{}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(DesktopCachesCategory)
+	found := map[string]FileInfo{}
+	for _, file := range result.Files {
+		found[file.Path] = file
+	}
+	if len(found) != 3 {
+		t.Fatalf("expected the font, icon and ksycoca caches, got %v", found)
+	}
+	fonts := found["/home/user/.cache/fontconfig"]
+	if fonts.Size != 5000 || fonts.Files != 2 || fonts.Risk != RiskSafe {
+		t.Errorf("expected the font cache as one safe result of 2 files, got %+v", fonts)
+	}
+	if _, ok := found["/home/user/.cache/ksycoca6_en_abc"]; !ok {
+		t.Error("expected the KDE MIME type cache")
+	}
+
+	// Other platforms keep their font caches elsewhere
+	macInfo, _ := platform.InfoFor(platform.MacOS, "/home/user", "user")
+	hs = NewHyperScanner(cfg, macInfo)
+	hs.SetFS(mem)
+	if result := hs.ScanCategory(DesktopCachesCategory); len(result.Files) != 0 {
+		t.Errorf("expected nothing outside Linux, got %d results", len(result.Files))
+	}
+}
+
+func TestScanCompilerCaches(t *testing.T) {
+	t.Setenv("CCACHE_DIR", "")
+	t.Setenv("SCCACHE_DIR", "/home/user/sccache")
+	t.Setenv("XDG_CACHE_HOME", "")
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.ccache/a/b/old.o", 1000, old)
+	mem.AddFile("/home/user/.ccache/a/b/new.o", 1000, now)
+	mem.AddFile("/home/user/.ccache/a/stats", 10, old)
+	mem.AddFile("/home/user/.ccache/ccache.conf", 10, old)
+	mem.AddFile("/home/user/sccache/0/1/old", 500, old)
+	mem.AddFile("/home/user/bazel-disk/cas/aa/first", 400, now.Add(-3*time.Hour))
+	mem.AddFile("/home/user/bazel-disk/cas/bb/second", 400, now.Add(-2*time.Hour))
+	mem.AddFile("/home/user/bazel-disk/ac/cc/third", 400, now.Add(-time.Hour))
+	if err := mem.WriteFile("/home/user/.bazelrc", []byte("build --disk_cache=~/bazel-disk\n"), now); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{CompilerCaches: config.CompilerCachesConfig{
+		CCache: true, SCCache: true, Bazel: true, MaxAgeDays: 30, MaxSize: "1KB",
+	}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(CompilerCachesCategory)
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+		if file.Risk != RiskSafe {
+			t.Errorf("expected %s to be rated safe, got %q", file.Path, file.Risk)
+		}
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/home/user/.ccache/a/b/old.o",
+		"/home/user/bazel-disk/cas/aa/first",
+		"/home/user/sccache/0/1/old",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	now := time.Now()
+	entries := []cacheEntry{
+		{path: "c", size: 300, used: now.Add(-time.Hour)},
+		{path: "a", size: 100, used: now.Add(-50 * 24 * time.Hour)},
+		{path: "b", size: 200, used: now.Add(-2 * time.Hour)},
+		{path: "d", size: 400, used: now},
+	}
+	names := func(entries []cacheEntry) string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.path)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		name      string
+		cutoff    time.Time
+		maxSize   int64
+		old, over string
+	}{
+		{"age only", now.Add(-30 * 24 * time.Hour), 0, "a", ""},
+		{"size only", time.Time{}, 500, "", "a,b,c"},
+		{"age then size", now.Add(-30 * 24 * time.Hour), 700, "a", "b"},
+		{"fits", time.Time{}, 1000, "", ""},
+	}
+	for _, tt := range tests {
+		old, over := pruneCache(entries, tt.cutoff, tt.maxSize)
+		if names(old) != tt.old || names(over) != tt.over {
+			t.Errorf("%s: expected old %q and over %q, got %q and %q", tt.name, tt.old, tt.over, names(old), names(over))
+		}
+	}
+}
+
+func TestBazelDiskCaches(t *testing.T) {
+	bazelrc := `# build --disk_cache=/commented
+build --disk_cache=~/.cache/bazel-disk
+test --config=ci --disk_cache "/var/cache/bazel"
+build:ci --disk_cache=%workspace%/cache
+build --disk_cache=~/.cache/bazel-disk
+`
+	got := bazelDiskCaches([]byte(bazelrc))
+	want := []string{"~/.cache/bazel-disk", "/var/cache/bazel"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestScanLaTeX(t *testing.T) {
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	now := time.Now()
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Documents/thesis/thesis.tex", 100, old)
+	mem.AddFile("/home/user/Documents/thesis/thesis.aux", 10, old)
+	mem.AddFile("/home/user/Documents/thesis/thesis.synctex.gz", 10, old)
+	mem.AddFile("/home/user/Documents/thesis/thesis.pdf", 1000, old)
+	mem.AddFile("/home/user/Documents/thesis/notes.log", 10, old) // No notes.tex
+	mem.AddFile("/home/user/Documents/thesis/_minted-thesis/default.pygstyle", 10, old)
+	mem.AddFile("/home/user/Documents/thesis/build/thesis.aux", 10, old)
+	mem.AddFile("/home/user/Documents/thesis/build/thesis.pdf", 1000, old)
+	mem.AddFile("/home/user/Documents/paper/paper.tex", 100, now)
+	mem.AddFile("/home/user/Documents/paper/paper.aux", 10, now) // Compiled today
+	mem.AddFile("/home/user/Documents/server/server.log", 10, old)
+
+	cfg := &config.Config{LaTeX: config.LaTeXConfig{ScanPaths: []string{"~/Documents"}, MinAgeDays: 30}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(LaTeXCategory)
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/home/user/Documents/thesis/_minted-thesis",
+		"/home/user/Documents/thesis/build",
+		"/home/user/Documents/thesis/thesis.aux",
+		"/home/user/Documents/thesis/thesis.synctex.gz",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestScanRPackages(t *testing.T) {
+	t.Setenv("R_USER_CACHE_DIR", "")
+	t.Setenv("RENV_PATHS_CACHE", "")
+	t.Setenv("RENV_PATHS_ROOT", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	older := old.Add(-24 * time.Hour)
+	renv := "/home/user/.cache/R/renv/cache/v5"
+	mem := vfs.NewMemFS()
+	mem.AddFile(renv+"/R-4.2/x86_64-pc-linux-gnu/dplyr/1.0.0/abc/dplyr/DESCRIPTION", 100, older)
+	mem.AddFile(renv+"/R-4.4/x86_64-pc-linux-gnu/dplyr/1.1.0/abc/dplyr/DESCRIPTION", 100, older)
+	mem.AddFile(renv+"/R-4.4/x86_64-pc-linux-gnu/dplyr/1.1.4/def/dplyr/DESCRIPTION", 100, old)
+	mem.AddFile(renv+"/R-4.4/x86_64-pc-linux-gnu/rlang/1.1.0/abc/rlang/DESCRIPTION", 100, older)
+	mem.AddFile(renv+"/R-4.10/x86_64-pc-linux-gnu/rlang/1.1.0/abc/rlang/DESCRIPTION", 100, time.Now())
+	mem.AddFile("/home/user/.cache/R/pkgcache/pkg/dplyr_1.1.4.tar.gz", 1000, old)
+
+	cfg := &config.Config{MinFileAge: 24, RPackages: config.RPackagesConfig{MinAgeDays: 90}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(RPackagesCategory)
+	var paths []string
+	for _, file := range result.Files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	// R 4.10 is the newest, so 4.2's and 4.4's builds go
+	want := []string{
+		"/home/user/.cache/R/pkgcache/pkg/dplyr_1.1.4.tar.gz",
+		renv + "/R-4.2",
+		renv + "/R-4.4",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestScanJulia(t *testing.T) {
+	t.Setenv("JULIA_DEPOT_PATH", "")
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	older := old.Add(-24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.julia/compiled/v1.9/Plots/a.ji", 100, old)
+	mem.AddFile("/home/user/.julia/compiled/v1.10/Plots/a.ji", 100, old)
+	mem.AddFile("/home/user/.julia/packages/Plots/aaaa/src/Plots.jl", 100, older)
+	mem.AddFile("/home/user/.julia/packages/Plots/bbbb/src/Plots.jl", 100, old)
+	mem.AddFile("/home/user/.julia/packages/JSON/cccc/src/JSON.jl", 100, older)
+	mem.AddFile("/home/user/.julia/packages/CSV/dddd/src/CSV.jl", 100, older)
+	mem.AddFile("/home/user/.julia/packages/CSV/eeee/src/CSV.jl", 100, time.Now())
+
+	cfg := &config.Config{Julia: config.JuliaConfig{MinAgeDays: 90}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(JuliaCategory)
+	found := map[string]FileInfo{}
+	var paths []string
+	for _, file := range result.Files {
+		found[file.Path] = file
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/home/user/.julia/compiled/v1.9",
+		"/home/user/.julia/packages/CSV/dddd",
+		"/home/user/.julia/packages/Plots/aaaa",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+	if reason := found["/home/user/.julia/packages/Plots/aaaa"].Reason; !strings.HasPrefix(reason, "Julia Plots aaaa, superseded by bbbb") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func TestScanProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	project := filepath.Join(root, "app")
+	files := map[string]string{
+		"app/.gitignore":                      "node_modules/\n*.log\ncoverage/\n.env\nsecrets/\n.pytest_cache/\n",
+		"app/src/main.js":                     "x",
+		"app/notes.txt":                       "x",
+		"app/node_modules/lodash/index.js":    "x",
+		"app/.pytest_cache/v/cache/lastfailed": "x",
+		"app/debug.log":                       "x",
+		"app/coverage/lcov.info":              "x",
+		"app/.env":                            "x",
+		"app/secrets/.env.local":              "x",
+		"app/secrets/dump.sql":                "x",
+		"other/node_modules/react/index.js":   "x",
+	}
+	for file, data := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "-C", project, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	cfg := &config.Config{
+		Categories: config.Categories{NodeModules: true, BuildArtifacts: true},
+		Dev:        config.DevConfig{ProjectDirs: []string{root}},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{HomeDir: root})
+	result, err := hs.ScanProject(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, file := range result.Files {
+		rel, _ := filepath.Rel(project, file.Path)
+		got = append(got, file.Category+":"+rel)
+	}
+	sort.Strings(got)
+	want := []string{
+		"node_modules:node_modules",
+		"project_caches:.pytest_cache",
+		"vcs_ignored:coverage",
+		"vcs_ignored:debug.log",
+		"vcs_ignored:secrets/dump.sql",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := hs.ScanProject(filepath.Join(project, "debug.log")); err == nil {
+		t.Error("expected an error for a file")
+	}
+}
+
+func TestRepoProblems(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	if problems, err := RepoProblems(ctx, dir); err != nil || len(problems) != 0 {
+		t.Fatalf("expected no problems outside a work tree, got %v, %v", problems, err)
+	}
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	remote := filepath.Join(dir, "remote.git")
+	project := filepath.Join(dir, "app")
+	git(dir, "init", "-q", "--bare", remote)
+	git(dir, "init", "-q", project)
+	if err := os.WriteFile(filepath.Join(project, "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want int) {
+		t.Helper()
+		problems, err := RepoProblems(ctx, project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != want {
+			t.Errorf("expected %d problems, got %v", want, problems)
+		}
+	}
+	check(2) // Untracked file, no remote
+	git(project, "add", ".")
+	git(project, "commit", "-qm", "init")
+	git(project, "remote", "add", "origin", remote)
+	check(1) // Unpushed commit
+	git(project, "push", "-q", "origin", "HEAD")
+	check(0)
+	if err := os.WriteFile(filepath.Join(project, "main.go"), []byte("package app"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(project, "stash", "-q")
+	check(1) // Stash
+}
+
+func TestRepoProblemsWorktreesAndSubmodules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	git := func(dir string, args ...string) {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t", "-c", "protocol.file.allow=always"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	// repo makes a clone of a new remote, with one commit pushed
+	repo := func(name string) string {
+		t.Helper()
+		remote := filepath.Join(dir, name+".git")
+		clone := filepath.Join(dir, name)
+		git(dir, "init", "-q", "--bare", remote)
+		git(dir, "clone", "-q", remote, clone)
+		if err := os.WriteFile(filepath.Join(clone, "README"), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git(clone, "add", ".")
+		git(clone, "commit", "-qm", "init")
+		git(clone, "push", "-q", "origin", "HEAD")
+		return clone
+	}
+	problems := func(dir string) string {
+		t.Helper()
+		problems, err := RepoProblems(ctx, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(problems, "; ")
+	}
+
+	app := repo("app")
+	lib := repo("lib")
+	git(app, "submodule", "add", "-q", lib, "lib")
+	git(app, "commit", "-qm", "add lib")
+	git(app, "push", "-q", "origin", "HEAD")
+	if got := problems(app); got != "" {
+		t.Fatalf("expected no problems, got %q", got)
+	}
+
+	// A commit in the submodule that only it has
+	sub := filepath.Join(app, "lib")
+	if err := os.WriteFile(filepath.Join(sub, "lib.go"), []byte("package lib"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(sub, "add", ".")
+	git(sub, "commit", "-qm", "wip")
+	if got := problems(app); !strings.Contains(got, "submodule lib has 1 commit(s) on no remote") {
+		t.Errorf("expected the submodule's commit to be a problem, got %q", got)
+	}
+	git(sub, "reset", "-q", "--hard", "HEAD~1")
+
+	// A linked worktree outside the clone needs its repository
+	worktree := filepath.Join(dir, "app-feature")
+	git(app, "worktree", "add", "-q", "-b", "feature", worktree)
+	if got := problems(app); !strings.Contains(got, "would lose its repository") {
+		t.Errorf("expected the worktree to be a problem, got %q", got)
+	}
+	if got := problems(worktree); got != "" {
+		t.Errorf("expected no problems in the worktree itself, got %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, "notes.txt"), []byte("wip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := problems(app); !strings.Contains(got, "has uncommitted changes") {
+		t.Errorf("expected the worktree's changes to be a problem, got %q", got)
+	}
+	if got := problems(worktree); !strings.Contains(got, "uncommitted changes") {
+		t.Errorf("expected the worktree's changes to be checked in the worktree, got %q", got)
+	}
+}
+
+func TestScanStaleRepos(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	old := time.Now().AddDate(-2, 0, 0)
+	git := func(dir string, date time.Time, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		stamp := date.Format(time.RFC3339)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+stamp, "GIT_COMMITTER_DATE="+stamp)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	// clone makes a repository in dev/name with one commit made at date,
+	// pushed unless remote is false
+	clone := func(name string, date time.Time, remote bool) string {
+		t.Helper()
+		repo := filepath.Join(root, "dev", name)
+		if err := os.MkdirAll(repo, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		git(repo, date, "init", "-q")
+		if err := os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git(repo, date, "add", ".")
+		git(repo, date, "commit", "-qm", "init")
+		if remote {
+			bare := filepath.Join(root, "remotes", name+".git")
+			git(root, date, "init", "-q", "--bare", bare)
+			git(repo, date, "remote", "add", "origin", bare)
+			git(repo, date, "push", "-q", "origin", "HEAD")
+		}
+		if err := os.Chtimes(filepath.Join(repo, ".git", "HEAD"), date, date); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+
+	stale := clone("stale", old, true)
+	clone("recent", time.Now(), true)
+	clone("local", old, false)
+	dirty := clone("group/dirty", old, true)
+	if err := os.WriteFile(filepath.Join(dirty, "notes.txt"), []byte("wip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fetched := clone("fetched", old, true)
+	if err := os.WriteFile(filepath.Join(fetched, ".git", "FETCH_HEAD"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Categories: config.Categories{StaleRepos: true},
+		Dev:        config.DevConfig{ProjectDirs: []string{filepath.Join(root, "dev")}},
+		StaleRepos: config.StaleReposConfig{MinAgeMonths: 12},
+	}
+	hs := NewHyperScanner(cfg, &platform.Info{HomeDir: root})
+	result := hs.ScanCategory(StaleReposCategory)
+	if len(result.Files) != 1 || result.Files[0].Path != stale {
+		t.Fatalf("expected only %s, got %v", stale, result.Files)
+	}
+	reason := result.Files[0].Reason
+	if !strings.Contains(reason, filepath.Join(root, "remotes", "stale.git")) || !strings.Contains(reason, "last commit 2 years ago") {
+		t.Errorf("expected the reason to give the remote and the last commit, got %q", reason)
+	}
+}
+
+func TestScanScreenshots(t *testing.T) {
+	jan := time.Date(2024, time.January, 15, 10, 0, 0, 0, time.Local)
+	feb := time.Date(2024, time.February, 3, 10, 0, 0, 0, time.Local)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Desktop/Screenshot 2024-01-15 at 10.00.00.png", 1000, jan)
+	mem.AddFile("/home/user/Desktop/Screen Recording 2024-02-03 at 10.00.00.mov", 9000, feb)
+	mem.AddFile("/home/user/Pictures/Screenshots/Screenshot from 2024-01-15 10-00-00.png", 2000, jan)
+	mem.AddFile("/home/user/Pictures/Screenshot_20240115_100000.PNG", 3000, jan)
+	mem.AddFile("/home/user/Pictures/holiday.jpg", 5000, jan)
+	mem.AddFile("/home/user/Desktop/screenshot notes.txt", 10, jan)
+	mem.AddFile("/home/user/Desktop/Screenshot today.png", 10, time.Now())
+
+	cfg := &config.Config{Screenshots: config.ScreenshotsConfig{ScanPaths: []string{"~/Desktop", "~/Pictures"}, MinAgeDays: 30}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(ScreenshotsCategory)
+	if result.TotalCount != 4 {
+		t.Fatalf("expected 4 screenshots and recordings, got %d", result.TotalCount)
+	}
+	for _, file := range result.Files {
+		if strings.HasSuffix(file.Path, ".mov") && file.Reason != "Screen recording from February 2024" {
+			t.Errorf("unexpected reason %q", file.Reason)
+		}
+	}
+
+	months, err := result.ScreenshotMonths()
+	if err != nil {
+		t.Fatalf("ScreenshotMonths failed: %v", err)
+	}
+	if months["2024-01"].Count != 3 || months["2024-01"].Size != 6000 || months["2024-02"].Size != 9000 {
+		t.Errorf("unexpected months %v", months)
+	}
+}
+
+func TestScanDownloads(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -120)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Downloads/installer.dmg", 1000, old)
+	mem.AddFile("/home/user/Downloads/report.pdf", 10, time.Now())
+	mem.AddFile("/home/user/Downloads/sdk/bin/tool", 300, old)
+	mem.AddFile("/home/user/Downloads/sdk/README", 200, old)
+	mem.AddFile("/home/user/Downloads/.DS_Store", 5, old)
+
+	cfg := &config.Config{AgeThresholds: config.AgeThresholds{Downloads: 90}}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+
+	result := hs.ScanCategory(DownloadsCategory)
+	if len(result.Files) != 2 {
+		t.Fatalf("expected the installer and the sdk folder, got %v", result.Files)
+	}
+	for _, file := range result.Files {
+		switch file.Path {
+		case "/home/user/Downloads/installer.dmg":
+		case "/home/user/Downloads/sdk":
+			if file.Size != 500 || file.Files != 2 {
+				t.Errorf("expected the sdk folder to hold 2 files of 500 bytes, got %d of %d", file.Files, file.Size)
+			}
+		default:
+			t.Errorf("unexpected result %s", file.Path)
+		}
+		if !strings.HasPrefix(file.Reason, "In Downloads, last modified ") {
+			t.Errorf("unexpected reason %q", file.Reason)
+		}
+	}
+}
+
+func TestParseDownloadHistory(t *testing.T) {
+	history := make(map[string]downloadRecord)
+	chrome := `[{"path":"/home/user/Downloads/go.tar.gz","time":13350000000000000,"url":"https://dl.google.com/go/go.tar.gz?token=secret"}]`
+	firefox := `[{"path":"file:///home/user/Downloads/My%20App.dmg","time":1700000000000000,"url":"https://d1.cloudfront.net/app.dmg"},
+		{"path":"file:///home/user/Downloads/gone","time":1700000000000000,"url":""}]`
+	parseDownloadHistory([]byte(chrome), chromeTime, history)
+	parseDownloadHistory([]byte(firefox), unixTime, history)
+	parseDownloadHistory([]byte("not json"), unixTime, history)
+
+	if len(history) != 2 {
+		t.Fatalf("expected 2 downloads, got %v", history)
+	}
+	goRecord := history["/home/user/Downloads/go.tar.gz"]
+	if goRecord.When.UTC().Year() != 2024 {
+		t.Errorf("expected the Chrome download in 2024, got %v", goRecord.When)
+	}
+	if got := redactURL(goRecord.URL); got != "https://dl.google.com/go/go.tar.gz" {
+		t.Errorf("redactURL = %q", got)
+	}
+	appRecord, ok := history["/home/user/Downloads/My App.dmg"]
+	if !ok || !appRecord.When.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected Firefox download %v", appRecord)
+	}
+
+	patterns := []string{"*.CloudFront.net"}
+	if !matchesHost(appRecord.URL, patterns) || matchesHost(goRecord.URL, patterns) {
+		t.Errorf("expected only the cloudfront download to match %v", patterns)
+	}
+}
+
+func TestCacheExcludeApps(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.cache/JetBrains/GoLand2024.1/index/a", 100, old)
+	mem.AddFile("/home/user/.cache/google-chrome/Default/Cache/b", 200, old)
+	mem.AddFile("/home/user/.cache/pip/http/c", 300, old)
+	mem.AddFile("/home/user/.cache/com.apple.Safari/d", 400, old)
+
+	cfg := &config.Config{
+		Categories: config.Categories{Cache: true},
+		Cache:      config.CacheConfig{ExcludeApps: []string{"jetbrains", "Chrome", "com.apple.safari"}},
+	}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+	hs.cache = &ScanCache{
+		Version:      1,
+		DirMtimes:    make(map[string]time.Time),
+		DirResults:   make(map[string]*CachedDirInfo),
+		ArtifactDirs: make(map[string][]string),
+	}
+
+	excluded := hs.excludedAppCaches([]string{"/home/user/.cache"})
+	sort.Strings(excluded)
+	want := []string{"/home/user/.cache/JetBrains", "/home/user/.cache/com.apple.Safari", "/home/user/.cache/google-chrome"}
+	if strings.Join(excluded, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, excluded)
+	}
+
+	// A second scan must walk again rather than report the whole directory
+	for range 2 {
+		result := hs.ScanCategory("cache")
+		if len(result.Files) != 1 || result.Files[0].Path != "/home/user/.cache/pip/http/c" {
+			t.Fatalf("expected only the pip cache, got %+v", result.Files)
+		}
+	}
+}
+
+func TestSetRoot(t *testing.T) {
+	old := time.Now().Add(-400 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/.cache/pip/a", 100, old)
+	mem.AddFile("/home/user/Downloads/big.iso", 200<<20, old)
+	mem.AddFile("/var/cache/apt/b", 100, old)
+	mem.AddFile("/mnt/work/.cache/go-build/c", 300, old)
+	mem.AddFile("/mnt/work/app/node_modules/left-pad/index.js", 10, old)
+	mem.AddFile("/mnt/work/app/package.json", 10, old)
+	mem.AddFile("/mnt/work/media/render.mov", 200<<20, old)
+
+	cfg := &config.Config{
+		Categories: config.Categories{Cache: true, NodeModules: true, LargeFiles: true},
+		Dev:        config.DevConfig{ProjectDirs: []string{"~/Projects"}},
+		LargeFiles: config.LargeFilesConfig{MinSize: "100MB", ScanPaths: []string{"~/Downloads"}},
+	}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+	hs.SetRoot("/mnt/work/")
+
+	result, err := hs.ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	found := map[string]string{}
+	for _, file := range result.Files {
+		if !strings.HasPrefix(file.Path, "/mnt/work/") {
+			t.Errorf("found %s outside the root", file.Path)
+		}
+		found[file.Path] = file.Category
+	}
+	for path, category := range map[string]string{
+		"/mnt/work/.cache/go-build/c": "cache",
+		"/mnt/work/app/node_modules":  "node_modules",
+		"/mnt/work/media/render.mov":  "large_files",
+	} {
+		if found[path] != category {
+			t.Errorf("expected %s in %s, got %v", path, category, found)
+		}
+	}
+	if len(cfg.Dev.ProjectDirs) != 1 || cfg.Dev.ProjectDirs[0] != "~/Projects" {
+		t.Errorf("SetRoot changed the caller's config: %v", cfg.Dev.ProjectDirs)
+	}
+}
+
+func TestSetRootKeepsHomeExcludes(t *testing.T) {
+	old := time.Now().Add(-400 * 24 * time.Hour)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/home/user/Documents/Work/deck.key", 200<<20, old)
+	mem.AddFile("/home/user/Library/Mail/archive.mbox", 200<<20, old)
+	mem.AddFile("/home/user/Videos/talk.mov", 200<<20, old)
+	mem.AddFile("/home/other/Documents/Work/plan.iso", 200<<20, old)
+
+	cfg := &config.Config{
+		Categories: config.Categories{LargeFiles: true, OldFiles: true},
+		LargeFiles: config.LargeFilesConfig{MinSize: "100MB", ExcludePaths: []string{"~/Documents/Work", "~/Library"}},
+		OldFiles:   config.OldFilesConfig{MinAgeDays: 30, ExcludePaths: []string{"~/Documents/Work", "~/Library"}},
+	}
+	pInfo, _ := platform.InfoFor(platform.Linux, "/home/user", "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+	hs.SetRoot("/home")
+
+	result, err := hs.ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	found := map[string]bool{}
+	for _, file := range result.Files {
+		found[file.Path] = true
+		if strings.HasPrefix(file.Path, "/home/user/Documents/Work") || strings.HasPrefix(file.Path, "/home/user/Library") {
+			t.Errorf("found %s, which ~ excludes, in %s", file.Path, file.Category)
+		}
+	}
+	// ~ is the user's home, not the root: others' directories aren't excluded
+	for _, path := range []string{"/home/user/Videos/talk.mov", "/home/other/Documents/Work/plan.iso"} {
+		if !found[path] {
+			t.Errorf("expected %s found, got %v", path, found)
+		}
+	}
+}
+
+func TestAssessAccess(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.cache")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setuid := filepath.Join(dir, "tool")
+	if err := os.WriteFile(setuid, []byte("x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(setuid, 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lockedFile := filepath.Join(locked, "b.cache")
+	if err := os.WriteFile(lockedFile, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(locked, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	pInfo, _ := platform.InfoFor(platform.Linux, dir, "user")
+	hs := NewHyperScanner(&config.Config{}, pInfo)
+	hs.DisableCache()
+
+	want := map[string]string{file: AccessDeletable, setuid: AccessInaccessible, lockedFile: AccessNeedsSudo}
+	if os.Geteuid() == 0 {
+		want[lockedFile] = AccessDeletable
+	}
+	for path, access := range want {
+		info, err := os.Lstat(path)
+		if got := hs.assessAccess(FileInfo{Path: path}, info, err); got != access {
+			t.Errorf("%s: expected %q, got %q", path, access, got)
+		}
+	}
+
+	result := &ScanResult{Files: []FileInfo{
+		{Path: file, Size: 10, Access: AccessDeletable},
+		{Path: lockedFile, Size: 20, Access: AccessNeedsSudo},
+		{Path: setuid, Size: 30},
+	}}
+	totals, err := result.AccessTotals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 || totals[AccessNeedsSudo].Size != 20 {
+		t.Errorf("unexpected totals %v", totals)
+	}
+}
+
+func TestScanAllDeduplicatesDirs(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -30)
+	mem := vfs.NewMemFS()
+	mem.AddFile("/tmp/build.o", 10, old)
+	mem.AddFile("/home/user/.cache/pip/wheel", 20, old)
+	mem.AddFile("/home/user/.cache/tmp/scratch", 30, old)
+	mem.Symlink("/tmp", "/private/tmp")
+
+	cfg := &config.Config{Categories: config.Categories{Cache: true, Temp: true}}
+	pInfo := &platform.Info{
+		OS:        platform.Linux,
+		HomeDir:   "/home/user",
+		CacheDirs: []string{"/home/user/.cache", "/home/user/.cache/pip", "/tmp"},
+		TempDirs:  []string{"/private/tmp", "/tmp", "/home/user/.cache/tmp"},
+	}
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.SetFS(mem)
+	hs.DisableCache()
+
+	result, err := hs.ScanAll()
+	if err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	want := map[string]string{
+		"/tmp/build.o":                  "temp",
+		"/home/user/.cache/pip/wheel":   "cache",
+		"/home/user/.cache/tmp/scratch": "temp",
+	}
+	if len(result.Files) != len(want) {
+		t.Fatalf("expected each file once, got %v", result.Files)
+	}
+	for _, file := range result.Files {
+		if want[file.Path] != file.Category {
+			t.Errorf("%s: expected category %q, got %q", file.Path, want[file.Path], file.Category)
+		}
+	}
+}
+
+func TestDedupResult(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(target, "big.log")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(target, "other.log")
+	if err := os.WriteFile(other, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Clean: config.CleanConfig{Order: []string{"temp", "logs", "large_files"}}}
+	pInfo, _ := platform.InfoFor(platform.Linux, dir, "user")
+	hs := NewHyperScanner(cfg, pInfo)
+	hs.DisableCache()
+
+	result := hs.dedupResult(&ScanResult{
+		Files: []FileInfo{
+			{Path: file, Size: 4, Category: "large_files"},
+			{Path: filepath.Join(link, "big.log"), Size: 4, Category: "logs"},
+			{Path: file + "/", Size: 4, Category: "large_files"},
+			{Path: other, Size: 1, Category: "large_files"},
+		},
+		TotalSize:  13,
+		TotalCount: 4,
+	})
+	if result.TotalCount != 2 || result.TotalSize != 5 {
+		t.Fatalf("expected 2 files of 5 bytes, got %d of %d: %v", result.TotalCount, result.TotalSize, result.Files)
+	}
+	if result.Files[0].Category != "logs" || result.Files[1].Path != other {
+		t.Errorf("expected the logs entry and %s to be kept, got %v", other, result.Files)
+	}
+}
+
+func TestReasons(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		when time.Time
+		want string
+	}{
+		{now.Add(-10 * time.Minute), "less than an hour"},
+		{now.Add(-5 * time.Hour), "5 hours"},
+		{now.AddDate(0, 0, -1).Add(-time.Minute), "1 day"},
+		{now.AddDate(0, 0, -94), "3 months"},
+		{now.AddDate(0, 0, -45), "45 days"},
+		{now.AddDate(-3, 0, -1), "3 years"},
+	} {
+		if got := since(tt.when); got != tt.want {
+			t.Errorf("since(%s) = %q, want %q", now.Sub(tt.when), got, tt.want)
+		}
+	}
+
+	hs := NewHyperScanner(&config.Config{MinFileAge: 24}, &platform.Info{})
+	if got, want := hs.walkReason("logs", now.AddDate(0, 0, -45)), "Log file, unmodified for 45 days (min_file_age: 24h)"; got != want {
+		t.Errorf("walkReason = %q, want %q", got, want)
+	}
+	if got, want := hs.cachedWalkReason("cache", 1), "1 cache file, unchanged since the last scan (min_file_age: 24h)"; got != want {
+		t.Errorf("cachedWalkReason = %q, want %q", got, want)
+	}
+}
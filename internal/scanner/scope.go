@@ -0,0 +1,54 @@
+package scanner
+
+import "path/filepath"
+
+// SetRoot limits the scan to the directory tree at root, such as a project
+// drive or a CI workspace. root stands in for the home directory, so caches
+// are looked for in root/.cache and root/Library/Caches; projects and large,
+// old, duplicate and empty files are looked for anywhere in it; system
+// directories outside it are left out, and so is any result outside it.
+// Exclude paths starting with ~ still mean the user's home directory.
+func (hs *HyperScanner) SetRoot(root string) {
+	root = filepath.Clean(root)
+	hs.root = root
+	if hs.userHome == "" {
+		hs.userHome = hs.homeDir()
+	}
+
+	info := *hs.platformInfo
+	info.HomeDir = root
+	info.CacheDirs = dirsUnder(root, info.CacheDirs)
+	info.SystemCaches = dirsUnder(root, info.SystemCaches)
+	info.TempDirs = dirsUnder(root, info.TempDirs)
+	info.LogDirs = dirsUnder(root, info.LogDirs)
+	info.DownloadsDir = filepath.Join(root, "Downloads")
+	hs.platformInfo = &info
+
+	cfg := *hs.config
+	only := []string{root}
+	cfg.Dev.ProjectDirs = only
+	cfg.LargeFiles.ScanPaths = only
+	cfg.OldFiles.ScanPaths = only
+	cfg.Duplicates.ScanPaths = only
+	cfg.EmptyDirs.ScanPaths = only
+	cfg.Screenshots.ScanPaths = only
+	cfg.VMImages.ScanPaths = only
+	cfg.LaTeX.ScanPaths = only
+	hs.config = &cfg
+
+	// The tree may be on slower storage than the home directory
+	hs.workerCount, hs.storage = scanWorkers(hs.config, hs.platformInfo)
+	hs.sem = make(chan struct{}, hs.workerCount)
+	hs.sizeSem = make(chan struct{}, artifactSizeWorkers(hs.config, hs.storage))
+}
+
+// dirsUnder returns the dirs that are root or inside it
+func dirsUnder(root string, dirs []string) []string {
+	var kept []string
+	for _, dir := range dirs {
+		if underAny(dir, []string{root}) {
+			kept = append(kept, dir)
+		}
+	}
+	return kept
+}
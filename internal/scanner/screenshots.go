@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// ScreenshotsCategory is the category for old screenshots and screen
+// recordings
+const ScreenshotsCategory = "screenshots"
+
+// screenshotPrefixes start the names macOS, Windows, GNOME, KDE and other
+// tools give screenshots and recordings, in English and a few other
+// languages. Names are compared in lower case.
+var screenshotPrefixes = []string{
+	"screenshot", "screen shot", "screen recording", "screencast",
+	"bildschirmfoto", "bildschirmaufnahme",
+	"capture d’écran", "capture d'écran", "enregistrement de l’écran", "enregistrement de l'écran",
+	"captura de pantalla", "grabación de pantalla",
+}
+
+// screenshotExts are the image and video types screenshots and recordings
+// are saved as
+var screenshotExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".heic": true, ".webp": true, ".gif": true,
+	".mov": true, ".mp4": true, ".webm": true, ".mkv": true,
+}
+
+// scanScreenshotsCategory finds screenshots and screen recordings in
+// screenshots_config.scan_paths older than screenshots_config.min_age_days.
+// They're reported by month, from their modification time.
+func (hs *HyperScanner) scanScreenshotsCategory() {
+	home := hs.homeDir()
+	cutoff := time.Now().AddDate(0, 0, -hs.config.Screenshots.MinAgeDays)
+	seen := make(map[string]bool)
+
+	for _, root := range hs.config.Screenshots.ScanPaths {
+		root = filepath.Clean(expandPath(root, home))
+		if hs.onWindowsDrive(root) {
+			continue
+		}
+		stop := hs.timeDir(root, ScreenshotsCategory)
+		vfs.WalkDir(hs.fs, root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && (strings.HasPrefix(d.Name(), ".") || hs.onWindowsDrive(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			kind := screenshotKind(d.Name())
+			if kind == "" || !d.Type().IsRegular() || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			info, err := d.Info()
+			if err != nil || !info.ModTime().Before(cutoff) {
+				return nil
+			}
+			hs.appendResult(FileInfo{
+				Path:     path,
+				Size:     info.Size(),
+				ModTime:  info.ModTime(),
+				Category: ScreenshotsCategory,
+				Reason:   kind + " from " + info.ModTime().Format("January 2006"),
+			}, 1)
+			return nil
+		})
+		stop()
+	}
+}
+
+// screenshotKind returns "Screenshot" or "Screen recording" if name is
+// named like one, or ""
+func screenshotKind(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if !screenshotExts[ext] {
+		return ""
+	}
+	lower := strings.ToLower(name)
+	for _, prefix := range screenshotPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			if typeExts[ext] == TypeVideo {
+				return "Screen recording"
+			}
+			return "Screenshot"
+		}
+	}
+	return ""
+}
+
+// ScreenshotMonths returns the count and size of the screenshots and screen
+// recordings in r by the month they were taken, as "2006-01"
+func (r *ScanResult) ScreenshotMonths() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		if file.Category != ScreenshotsCategory {
+			return nil
+		}
+		month := file.ModTime.Format("2006-01")
+		total := totals[month]
+		total.Count++
+		total.Size += file.Size
+		totals[month] = total
+		return nil
+	})
+	return totals, err
+}
@@ -0,0 +1,328 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spillBatchSize is how many results are encoded together in a result index,
+// which is also the smallest amount read back to serve a page
+const spillBatchSize = 10000
+
+// resultIndex is a temp file of scan results, so scans with millions of
+// results don't have to hold them all in memory. Results are gob-encoded in
+// batches that can be decoded on their own.
+type resultIndex struct {
+	file    *os.File
+	offsets []int64 // Where each batch starts
+	counts  []int   // Results in each batch
+	size    int64
+	count   int
+}
+
+// newResultIndex creates an empty index in a temp file
+func newResultIndex() (*resultIndex, error) {
+	file, err := os.CreateTemp("", "tidyup-results-*.idx")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result index: %w", err)
+	}
+	return &resultIndex{file: file}, nil
+}
+
+// append writes files to the end of the index
+func (ix *resultIndex) append(files []FileInfo) error {
+	if _, err := ix.file.Seek(ix.size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write result index: %w", err)
+	}
+	w := bufio.NewWriter(ix.file)
+	for start := 0; start < len(files); start += spillBatchSize {
+		batch := files[start:min(start+spillBatchSize, len(files))]
+		counter := &countingWriter{w: w}
+		if err := gob.NewEncoder(counter).Encode(batch); err != nil {
+			return fmt.Errorf("failed to write result index: %w", err)
+		}
+		ix.offsets = append(ix.offsets, ix.size)
+		ix.counts = append(ix.counts, len(batch))
+		ix.size += counter.n
+		ix.count += len(batch)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write result index: %w", err)
+	}
+	return nil
+}
+
+// batch reads back the i'th batch
+func (ix *resultIndex) batch(i int) ([]FileInfo, error) {
+	end := ix.size
+	if i+1 < len(ix.offsets) {
+		end = ix.offsets[i+1]
+	}
+	var files []FileInfo
+	section := io.NewSectionReader(ix.file, ix.offsets[i], end-ix.offsets[i])
+	if err := gob.NewDecoder(bufio.NewReader(section)).Decode(&files); err != nil {
+		return nil, fmt.Errorf("failed to read result index: %w", err)
+	}
+	return files, nil
+}
+
+// each calls fn with every batch in order, stopping at the first error
+func (ix *resultIndex) each(fn func(batch []FileInfo) error) error {
+	for i := range ix.offsets {
+		files, err := ix.batch(i)
+		if err != nil {
+			return err
+		}
+		if err := fn(files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// page returns up to limit results starting at offset
+func (ix *resultIndex) page(offset, limit int) ([]FileInfo, error) {
+	var page []FileInfo
+	start := 0
+	for i, n := range ix.counts {
+		if len(page) >= limit {
+			break
+		}
+		if start+n > offset {
+			files, err := ix.batch(i)
+			if err != nil {
+				return nil, err
+			}
+			from := max(offset-start, 0)
+			page = append(page, files[from:min(from+limit-len(page), n)]...)
+		}
+		start += n
+	}
+	return page, nil
+}
+
+// close removes the index
+func (ix *resultIndex) close() error {
+	ix.file.Close()
+	if err := os.Remove(ix.file.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove result index: %w", err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Spilled reports whether some of the result's files were written to a temp
+// file instead of being kept in Files. Use Len, Page and Each to see them all,
+// or Load to read them back into Files, and Close when done.
+func (r *ScanResult) Spilled() bool {
+	return r.spilled != nil
+}
+
+// Len returns the number of files in the result, including spilled ones
+func (r *ScanResult) Len() int {
+	if r.spilled == nil {
+		return len(r.Files)
+	}
+	return r.spilled.count + len(r.Files)
+}
+
+// Page returns up to limit files starting at offset. Spilled files come
+// before the ones in Files.
+func (r *ScanResult) Page(offset, limit int) ([]FileInfo, error) {
+	var page []FileInfo
+	if r.spilled != nil {
+		var err error
+		if page, err = r.spilled.page(offset, limit); err != nil {
+			return nil, err
+		}
+		offset = max(offset-r.spilled.count, 0)
+	}
+	if offset < len(r.Files) {
+		end := min(offset+limit-len(page), len(r.Files))
+		page = append(page, r.Files[offset:end]...)
+	}
+	return page, nil
+}
+
+// Each calls fn for every file, spilled ones first, stopping at the first
+// error
+func (r *ScanResult) Each(fn func(file FileInfo) error) error {
+	if r.spilled != nil {
+		err := r.spilled.each(func(batch []FileInfo) error {
+			for _, file := range batch {
+				if err := fn(file); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, file := range r.Files {
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads spilled files back into Files, for callers that need every file
+// at once, and removes the temp file
+func (r *ScanResult) Load() error {
+	if r.spilled == nil {
+		return nil
+	}
+	files := make([]FileInfo, 0, r.Len())
+	err := r.spilled.each(func(batch []FileInfo) error {
+		files = append(files, batch...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.Files = append(files, r.Files...)
+	return r.Close()
+}
+
+// Close removes the temp file holding spilled files, if there is one
+func (r *ScanResult) Close() error {
+	if r.spilled == nil {
+		return nil
+	}
+	err := r.spilled.close()
+	r.spilled = nil
+	return err
+}
+
+// filter returns a new result with the files keep accepts. Spilled files that
+// are kept are spilled again rather than read into memory.
+func (r *ScanResult) filter(keep func(file FileInfo) bool) *ScanResult {
+	filtered := &ScanResult{
+		Category: r.Category,
+		Errors:   r.Errors[:len(r.Errors):len(r.Errors)],
+	}
+	add := func(files []FileInfo) []FileInfo {
+		var kept []FileInfo
+		for _, file := range files {
+			if keep(file) {
+				kept = append(kept, file)
+				filtered.TotalSize += file.Size
+				filtered.TotalCount++
+			}
+		}
+		return kept
+	}
+
+	if r.spilled != nil {
+		err := r.spilled.each(func(batch []FileInfo) error {
+			kept := add(batch)
+			if len(kept) == 0 {
+				return nil
+			}
+			if filtered.spilled == nil {
+				index, err := newResultIndex()
+				if err != nil {
+					return err
+				}
+				filtered.spilled = index
+			}
+			return filtered.spilled.append(kept)
+		})
+		if err != nil {
+			filtered.Errors = append(filtered.Errors, err)
+		}
+	}
+	filtered.Files = add(r.Files)
+	if filtered.Files == nil {
+		filtered.Files = []FileInfo{}
+	}
+	return filtered
+}
+
+// ScanAllStreaming scans like ScanAll, but hands results to fn in batches of
+// batchSize as they're found instead of keeping them. The returned result
+// holds the ones found after the last batch, with totals for the whole scan.
+// If fn fails, the rest of the results are kept and the error is reported in
+// the result.
+func (hs *HyperScanner) ScanAllStreaming(batchSize int, fn func(batch []FileInfo) error) (*ScanResult, error) {
+	hs.results.setStream(batchSize, fn)
+	defer hs.results.setStream(0, nil)
+	return hs.scanAll()
+}
+
+// ScanStream is a scan running in the background, whose results arrive on
+// Batches as they're found
+type ScanStream struct {
+	Batches <-chan []FileInfo // Closed when the scan is done
+
+	done   chan struct{}
+	result *ScanResult
+	err    error
+}
+
+// Stream starts a scan like ScanAllStreaming in the background and sends
+// the results on the stream's Batches, batchSize at a time. Batches must be
+// read until it's closed, or the scan stops to wait.
+func (hs *HyperScanner) Stream(batchSize int) *ScanStream {
+	batches := make(chan []FileInfo)
+	stream := &ScanStream{Batches: batches, done: make(chan struct{})}
+	go func() {
+		defer close(stream.done)
+		defer close(batches)
+		stream.result, stream.err = hs.ScanAllStreaming(batchSize, func(batch []FileInfo) error {
+			batches <- batch
+			return nil
+		})
+		if stream.err == nil && len(stream.result.Files) > 0 {
+			batches <- stream.result.Files
+			stream.result.Files = nil
+		}
+	}()
+	return stream
+}
+
+// Wait returns the result of the scan once it's done. Its files were all
+// sent on Batches, so it only holds the totals, errors and duplicates.
+func (s *ScanStream) Wait() (*ScanResult, error) {
+	<-s.done
+	return s.result, s.err
+}
+
+// scanAllSpilled scans, spilling results past scan.max_results to a result
+// index
+func (hs *HyperScanner) scanAllSpilled(limit int) (*ScanResult, error) {
+	var index *resultIndex
+	result, err := hs.ScanAllStreaming(limit, func(batch []FileInfo) error {
+		if index == nil {
+			var err error
+			if index, err = newResultIndex(); err != nil {
+				return err
+			}
+		}
+		return index.append(batch)
+	})
+	if err != nil {
+		if index != nil {
+			index.close()
+		}
+		return nil, err
+	}
+	result.spilled = index
+	return result, nil
+}
@@ -0,0 +1,291 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaleReposCategory is the category for git clones no one has worked in or
+// fetched into for months
+const StaleReposCategory = "stale_repos"
+
+// staleRepoMaxDepth is how deep below a project directory clones are looked for
+const staleRepoMaxDepth = 6
+
+// RepoProblems returns why the git work tree dir is in can't go without
+// losing work: changes in dir not committed, commits on no remote and
+// stashes, and in its submodules, commits on no remote. A clone's linked
+// worktrees keep their repository in it, so they mustn't have changes
+// either, and those outside dir need removing first. It returns none for a
+// directory outside a git work tree.
+func RepoProblems(ctx context.Context, dir string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, nil
+	}
+	if _, err := gitLines(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, nil
+	}
+
+	var problems []string
+	changes, err := gitLines(ctx, dir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		problems = append(problems, "it has uncommitted changes or untracked files")
+	}
+	remotes, err := gitLines(ctx, dir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	if len(remotes) == 0 {
+		problems = append(problems, "it has no remote to push to")
+	} else {
+		unpushed, err := gitLines(ctx, dir, "log", "--branches", "--not", "--remotes", "--format=%h")
+		if err != nil {
+			return nil, err
+		}
+		if len(unpushed) > 0 {
+			problems = append(problems, fmt.Sprintf("%d commit(s) aren't pushed to any remote", len(unpushed)))
+		}
+	}
+	stashes, err := gitLines(ctx, dir, "stash", "list", "--format=%h")
+	if err != nil {
+		return nil, err
+	}
+	if len(stashes) > 0 {
+		problems = append(problems, fmt.Sprintf("it has %d stash(es)", len(stashes)))
+	}
+
+	submodules, err := gitLines(ctx, dir, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range submodules {
+		// " <commit> <path> (<describe>)", "-" first if it isn't checked out
+		fields := strings.Fields(line)
+		if strings.HasPrefix(line, "-") || len(fields) < 2 {
+			continue
+		}
+		unpushed, err := gitLines(ctx, filepath.Join(dir, fields[1]), "log", "HEAD", "--not", "--remotes", "--format=%h")
+		if err != nil {
+			return nil, err
+		}
+		if len(unpushed) > 0 {
+			problems = append(problems, fmt.Sprintf("submodule %s has %d commit(s) on no remote", fields[1], len(unpushed)))
+		}
+	}
+
+	worktrees, err := linkedWorktrees(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, worktree := range worktrees {
+		changes, err := gitLines(ctx, worktree, "status", "--porcelain")
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(changes) > 0:
+			problems = append(problems, fmt.Sprintf("its worktree %s has uncommitted changes or untracked files", worktree))
+		case !underAny(worktree, []string{dir}):
+			problems = append(problems, fmt.Sprintf("its worktree %s would lose its repository", worktree))
+		}
+	}
+	return problems, nil
+}
+
+// linkedWorktrees returns the linked worktrees of the clone dir is the main
+// worktree of, the ones still there. A linked worktree has none of its own.
+func linkedWorktrees(ctx context.Context, dir string) ([]string, error) {
+	dirs, err := gitLines(ctx, dir, "rev-parse", "--path-format=absolute", "--git-dir", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) != 2 || dirs[0] != dirs[1] {
+		return nil, nil
+	}
+	list, err := gitLines(ctx, dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	var worktrees []string
+	for i, line := range list {
+		path, ok := strings.CutPrefix(line, "worktree ")
+		// The main worktree comes first
+		if !ok || i == 0 {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			worktrees = append(worktrees, path)
+		}
+	}
+	return worktrees, nil
+}
+
+// gitLines runs git in dir and returns the lines it prints. It never takes
+// the index lock, so a status doesn't touch the repository.
+func gitLines(ctx context.Context, dir string, args ...string) ([]string, error) {
+	args = append([]string{"--no-optional-locks", "-C", dir}, args...)
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: git %s: %w", dir, strings.Join(args[3:], " "), err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// scanStaleReposCategory finds the git clones in dev.project_dirs with no
+// commit and no fetch for stale_repos_config.min_age_months. Clones holding
+// work found nowhere else are left alone: uncommitted changes, commits on no
+// remote, stashes, or no remote at all. The rest can be cloned again from
+// the remote shown.
+func (hs *HyperScanner) scanStaleReposCategory() {
+	if !hs.native() {
+		return
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	home := hs.homeDir()
+	cutoff := time.Now().AddDate(0, -hs.config.StaleRepos.MinAgeMonths, 0)
+
+	seen := make(map[string]bool)
+	for _, dir := range hs.config.Dev.ProjectDirs {
+		dir = expandPath(dir, home)
+		if seen[dir] || hs.onWindowsDrive(dir) {
+			continue
+		}
+		seen[dir] = true
+		if info, err := hs.fs.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		stop := hs.timeDir(dir, StaleReposCategory)
+		for _, repo := range hs.findRepos(dir, 0) {
+			hs.checkStaleRepo(repo, cutoff)
+		}
+		stop()
+	}
+}
+
+// findRepos returns the clones in dir: the git work trees with their own
+// repository. Submodules go with their clone. Linked worktrees, whose .git
+// is a file, are checked with the clone they belong to.
+func (hs *HyperScanner) findRepos(dir string, depth int) []string {
+	if hs.cancelled() {
+		return nil
+	}
+	entries, err := hs.fs.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			if entry.IsDir() {
+				return []string{dir}
+			}
+			return nil
+		}
+	}
+	if depth >= staleRepoMaxDepth {
+		return nil
+	}
+
+	var repos []string
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if !entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if (hs.categorizeArtifact(name) != "" || name == "node_modules") && !hs.checkout(path) {
+			continue
+		}
+		repos = append(repos, hs.findRepos(path, depth+1)...)
+	}
+	return repos
+}
+
+// checkStaleRepo reports the clone at repo if nothing was committed to or
+// fetched into it since cutoff and it holds no work found nowhere else
+func (hs *HyperScanner) checkStaleRepo(repo string, cutoff time.Time) {
+	// Read before git runs. A clone never fetched into since has no
+	// FETCH_HEAD; HEAD was written when it was cloned or last checked out.
+	gitDir := filepath.Join(repo, ".git")
+	var fetched time.Time
+	for _, name := range []string{"FETCH_HEAD", "HEAD"} {
+		if info, err := hs.fs.Stat(filepath.Join(gitDir, name)); err == nil {
+			fetched = info.ModTime()
+			break
+		}
+	}
+	if fetched.IsZero() || !fetched.Before(cutoff) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(hs.ctx, gitTimeout)
+	defer cancel()
+	last, err := gitLines(ctx, repo, "log", "-1", "--format=%ct")
+	if err != nil || len(last) == 0 {
+		return
+	}
+	seconds, err := strconv.ParseInt(last[0], 10, 64)
+	if err != nil {
+		return
+	}
+	committed := time.Unix(seconds, 0)
+	if !committed.Before(cutoff) {
+		return
+	}
+	if problems, err := RepoProblems(ctx, repo); err != nil || len(problems) > 0 {
+		return
+	}
+	remote := repoRemote(ctx, repo)
+	if remote == "" {
+		return
+	}
+
+	size, files, newest := hs.dirUsage(repo)
+	if files == 0 {
+		return
+	}
+	hs.appendResult(FileInfo{
+		Path:     repo,
+		Size:     size,
+		ModTime:  newest,
+		Category: StaleReposCategory,
+		Reason: fmt.Sprintf("Git clone of %s, last commit %s ago, last fetched %s ago: %s (stale_repos_config.min_age_months: %d)",
+			remote, since(committed), since(fetched), plural(files, "file"), hs.config.StaleRepos.MinAgeMonths),
+		Files: files,
+	}, int64(files))
+}
+
+// repoRemote returns the URL repo can be cloned again from: origin's, or
+// else the first remote's
+func repoRemote(ctx context.Context, repo string) string {
+	remotes, err := gitLines(ctx, repo, "remote")
+	if err != nil || len(remotes) == 0 {
+		return ""
+	}
+	name := remotes[0]
+	if slices.Contains(remotes, "origin") {
+		name = "origin"
+	}
+	url, err := gitLines(ctx, repo, "remote", "get-url", name)
+	if err != nil || len(url) == 0 {
+		return ""
+	}
+	return url[0]
+}
@@ -1,6 +1,13 @@
 package scanner
 
-import "time"
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+)
 
 // FileInfo represents information about a file found during scanning
 type FileInfo struct {
@@ -10,6 +17,12 @@ type FileInfo struct {
 	Category string
 	Reason   string // Why this file was flagged for cleanup
 	Hash     string // For duplicate detection
+	Type     string // Kind of file, for large files: video, archive, ...
+	Files    int    // Files below a directory result, when known; 0 for a file
+	Risk     string // RiskSafe, RiskLow or RiskReview
+	Owner    string // User name, or the ID if it has none; empty if unknown
+	Group    string // Group name, or the ID if it has none; empty if unknown
+	Access   string // AccessDeletable, AccessNeedsSudo or AccessInaccessible; empty if unknown
 }
 
 // ScanResult represents the result of a scan operation
@@ -19,16 +32,55 @@ type ScanResult struct {
 	TotalCount int
 	Category   string
 	Errors     []error
+	Duplicates []DuplicateGroup // Every copy of each duplicate, including the ones kept
+
+	spilled *resultIndex // Files found before the ones in Files, when the scan outgrew scan.max_results
+}
+
+// DuplicateGroup is a set of files with identical content
+type DuplicateGroup struct {
+	Hash  string
+	Size  int64      // Size of one copy
+	Files []FileInfo // Every copy, newest first
+}
+
+// CategoryTotal holds the running item count and size for a single category
+type CategoryTotal struct {
+	Count int
+	Size  int64
+}
+
+// ProgressEvent describes how far a scan has got. One is sent for each result
+// found, each directory being measured and each category finished.
+type ProgressEvent struct {
+	Phase          progress.Phase // progress.PhaseScanning, or progress.PhaseSizing while measuring CurrentPath
+	Category       string         // Category of CurrentPath
+	CurrentPath    string
+	FilesFound     int
+	TotalSize      int64
+	Categories     []CategoryProgress // Every category being scanned, in dispatch order
+	CategoriesDone int
+	Elapsed        time.Duration
+	FilesPerSecond float64       // Since the scan started
+	ETA            time.Duration // Estimated from the last scan's timings; 0 if unknown
+}
+
+// CategoryProgress is how far one category of a scan has got
+type CategoryProgress struct {
+	Name string
+	Done bool
+	CategoryTotal
 }
 
 // ProgressCallback is called during scanning to report progress
-type ProgressCallback func(category, currentPath string, filesFound int, totalSize int64)
+type ProgressCallback func(event ProgressEvent)
 
-// GroupByCategory groups results by their category
+// GroupByCategory groups results by their category. Spilled results are read
+// back into memory, so use CategoryTotals if only the totals are needed.
 func (r *ScanResult) GroupByCategory() map[string]*ScanResult {
 	grouped := make(map[string]*ScanResult)
 
-	for _, file := range r.Files {
+	err := r.Each(func(file FileInfo) error {
 		if _, ok := grouped[file.Category]; !ok {
 			grouped[file.Category] = &ScanResult{
 				Category: file.Category,
@@ -38,7 +90,178 @@ func (r *ScanResult) GroupByCategory() map[string]*ScanResult {
 		grouped[file.Category].Files = append(grouped[file.Category].Files, file)
 		grouped[file.Category].TotalSize += file.Size
 		grouped[file.Category].TotalCount++
+		return nil
+	})
+	if err != nil {
+		for _, group := range grouped {
+			group.Errors = append(group.Errors, err)
+		}
 	}
 
 	return grouped
 }
+
+// CategoryTotals returns the count and size of the results in each category
+func (r *ScanResult) CategoryTotals() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		total := totals[file.Category]
+		total.Count++
+		total.Size += file.Size
+		totals[file.Category] = total
+		return nil
+	})
+	return totals, err
+}
+
+// TypeTotals returns the count and size of the results of each file type.
+// Results without a type, from categories other than large files, are left out.
+func (r *ScanResult) TypeTotals() (map[string]CategoryTotal, error) {
+	totals := make(map[string]CategoryTotal)
+	err := r.Each(func(file FileInfo) error {
+		if file.Type == "" {
+			return nil
+		}
+		total := totals[file.Type]
+		total.Count++
+		total.Size += file.Size
+		totals[file.Type] = total
+		return nil
+	})
+	return totals, err
+}
+
+// FileCount returns the number of files f stands for: the files below a
+// directory result, or 1
+func (f FileInfo) FileCount() int {
+	return max(f.Files, 1)
+}
+
+// SortByFiles orders the files by how many files each stands for, most first,
+// and the smallest first among equals, so the deletions that free the most
+// inodes for the least data come first. Spilled files are read back in.
+func (r *ScanResult) SortByFiles() error {
+	if err := r.Load(); err != nil {
+		return err
+	}
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		a, b := r.Files[i], r.Files[j]
+		if a.FileCount() != b.FileCount() {
+			return a.FileCount() > b.FileCount()
+		}
+		return a.Size < b.Size
+	})
+	return nil
+}
+
+// FilterTypes returns a new result containing only files of the given types
+func (r *ScanResult) FilterTypes(types []string) *ScanResult {
+	keep := make(map[string]bool, len(types))
+	for _, t := range types {
+		keep[t] = true
+	}
+	return r.filter(func(file FileInfo) bool {
+		return keep[file.Type]
+	})
+}
+
+// FilterCategories returns a new result containing only files from the given categories
+func (r *ScanResult) FilterCategories(categories []string) *ScanResult {
+	keep := make(map[string]bool, len(categories))
+	for _, cat := range categories {
+		keep[cat] = true
+	}
+
+	filtered := r.filter(func(file FileInfo) bool {
+		return keep[file.Category]
+	})
+	if keep[DuplicatesCategory] {
+		filtered.Duplicates = r.Duplicates
+	}
+
+	return filtered
+}
+
+// FilterPaths returns a new result containing only files whose path is in paths
+func (r *ScanResult) FilterPaths(paths []string) *ScanResult {
+	keep := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		keep[path] = true
+	}
+
+	filtered := r.filter(func(file FileInfo) bool {
+		return keep[file.Path]
+	})
+	filtered.Duplicates = r.Duplicates
+
+	return filtered
+}
+
+// ChooseDuplicates returns a new result whose duplicate files are the copies in
+// remove instead of the ones picked by the scanner. Groups where every copy
+// would be removed are left out entirely, so at least one copy always survives.
+func (r *ScanResult) ChooseDuplicates(remove []string) *ScanResult {
+	drop := make(map[string]bool, len(remove))
+	for _, path := range remove {
+		drop[path] = true
+	}
+
+	chosen := r.filter(func(file FileInfo) bool {
+		return file.Category != DuplicatesCategory
+	})
+	chosen.Duplicates = r.Duplicates
+
+	for _, group := range r.Duplicates {
+		var removed []FileInfo
+		for _, file := range group.Files {
+			if drop[file.Path] {
+				removed = append(removed, duplicateResult(file, group))
+			}
+		}
+		if len(removed) == len(group.Files) {
+			continue
+		}
+		chosen.Files = append(chosen.Files, removed...)
+		for _, file := range removed {
+			chosen.TotalSize += file.Size
+		}
+		chosen.TotalCount += len(removed)
+	}
+
+	return chosen
+}
+
+// scratchRoots are system-wide locations that only hold scratch data
+var scratchRoots = []string{"/tmp", "/var/tmp", "/private/tmp", "/private/var/tmp"}
+
+// IsSystemPath reports whether path is a system-wide location: outside the
+// user's home directory and not a scratch directory like /tmp
+func IsSystemPath(path, home string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	under := func(root string) bool {
+		return root != "" && (path == root || strings.HasPrefix(path, strings.TrimSuffix(root, "/")+"/"))
+	}
+	if under(home) {
+		return false
+	}
+	for _, root := range scratchRoots {
+		if under(root) {
+			return false
+		}
+	}
+	return true
+}
+
+// SystemFiles returns the files in system-wide locations
+func (r *ScanResult) SystemFiles(home string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := r.Each(func(file FileInfo) error {
+		if IsSystemPath(file.Path, home) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
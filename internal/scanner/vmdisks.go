@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// Container runtimes that keep Docker in a VM
+const (
+	RuntimeDockerDesktop = "docker-desktop"
+	RuntimeColima        = "colima"
+	RuntimeLima          = "lima"
+)
+
+// VMDisk is the disk image of a container runtime's VM. It's never a scan
+// result: deleting it loses every image, container and volume, and the space
+// Docker frees inside it only goes back to the host once it's compacted.
+type VMDisk struct {
+	Runtime   string   // RuntimeDockerDesktop, RuntimeColima or RuntimeLima
+	Name      string   // Colima profile or Lima instance; empty for Docker Desktop
+	Path      string   // The disk image
+	Size      int64    // Apparent size, the most the disk can take up
+	Allocated int64    // Space it takes on the host
+	Docker    []string // docker flags reaching the engine in the VM, nil if it has none
+}
+
+// FindVMDisks finds the VM disks of Docker Desktop, Colima and Lima, and
+// under WSL those of Docker Desktop on Windows
+func (hs *HyperScanner) FindVMDisks() []VMDisk {
+	home := hs.homeDir()
+	var disks []VMDisk
+
+	// Docker Desktop, on macOS and on Linux
+	for _, pattern := range []string{
+		filepath.Join(home, "Library/Containers/com.docker.docker/Data/vms/*/data/Docker.*"),
+		filepath.Join(home, ".docker/desktop/vms/*/data/Docker.*"),
+	} {
+		paths, _ := vfs.Glob(hs.fs, pattern)
+		for _, path := range paths {
+			disks = hs.appendVMDisk(disks, VMDisk{
+				Runtime: RuntimeDockerDesktop,
+				Path:    path,
+				Docker:  []string{"--context", "desktop-linux"},
+			})
+		}
+	}
+
+	// Docker Desktop on Windows, seen from WSL, where its engine is the
+	// default one
+	if hs.platformInfo.WSL {
+		for _, profile := range platform.WindowsProfiles(hs.platformInfo) {
+			paths, _ := vfs.Glob(hs.fs, filepath.Join(profile, "AppData/Local/Docker/wsl/*/*.vhdx"))
+			for _, path := range paths {
+				disks = hs.appendVMDisk(disks, VMDisk{
+					Runtime: RuntimeDockerDesktop,
+					Path:    path,
+					Docker:  []string{},
+				})
+			}
+		}
+	}
+
+	// Colima runs each profile as a Lima instance of its own; newer versions
+	// keep Docker's data on a separate disk
+	colimaHome := os.Getenv("COLIMA_HOME")
+	if colimaHome == "" {
+		colimaHome = filepath.Join(home, ".colima")
+	}
+	for _, pattern := range []string{
+		filepath.Join(colimaHome, "_lima", "*", "diffdisk"),
+		filepath.Join(colimaHome, "_lima", "_disks", "*", "datadisk"),
+	} {
+		paths, _ := vfs.Glob(hs.fs, pattern)
+		for _, path := range paths {
+			instance := filepath.Base(filepath.Dir(path))
+			if strings.HasPrefix(instance, "_") {
+				continue
+			}
+			disks = hs.appendVMDisk(disks, VMDisk{
+				Runtime: RuntimeColima,
+				Name:    colimaProfile(instance),
+				Path:    path,
+				Docker:  []string{"--context", instance},
+			})
+		}
+	}
+
+	// Lima instances, with Docker when they were created from its template
+	limaHome := os.Getenv("LIMA_HOME")
+	if limaHome == "" {
+		limaHome = filepath.Join(home, ".lima")
+	}
+	paths, _ := vfs.Glob(hs.fs, filepath.Join(limaHome, "*", "diffdisk"))
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if strings.HasPrefix(filepath.Base(dir), "_") {
+			continue
+		}
+		disk := VMDisk{Runtime: RuntimeLima, Name: filepath.Base(dir), Path: path}
+		socket := filepath.Join(dir, "sock", "docker.sock")
+		if _, err := hs.fs.Lstat(socket); err == nil {
+			disk.Docker = []string{"--host", "unix://" + socket}
+		}
+		disks = hs.appendVMDisk(disks, disk)
+	}
+
+	return disks
+}
+
+// appendVMDisk measures disk and adds it to disks if its image exists
+func (hs *HyperScanner) appendVMDisk(disks []VMDisk, disk VMDisk) []VMDisk {
+	info, err := hs.fs.Stat(disk.Path)
+	if err != nil || !info.Mode().IsRegular() {
+		return disks
+	}
+	disk.Size = info.Size()
+	disk.Allocated = info.Size()
+	if allocated, ok := platform.AllocatedSize(info); ok {
+		disk.Allocated = allocated
+	}
+	return append(disks, disk)
+}
+
+// colimaProfile returns the Colima profile a Lima instance belongs to
+func colimaProfile(instance string) string {
+	if profile, ok := strings.CutPrefix(instance, "colima-"); ok {
+		return profile
+	}
+	return "default"
+}
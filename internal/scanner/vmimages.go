@@ -0,0 +1,341 @@
+package scanner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/vfs"
+)
+
+// VMImagesCategory is the category for virtual machines and disk images that
+// haven't been used in a while
+const VMImagesCategory = "vm_images"
+
+// vmDiskExts are the disk image types found loose in the scan paths
+var vmDiskExts = []string{".qcow2", ".vdi", ".vmdk", ".vhd", ".vhdx"}
+
+// vagrantSlash stands for "/" in the directory names of Vagrant boxes
+const vagrantSlash = "-VAGRANTSLASH-"
+
+// multipassVaults are where multipassd keeps its downloaded images: the snap
+// on Linux, the QEMU and HyperKit back ends on macOS
+var multipassVaults = []string{
+	"/var/snap/multipass/common/data/multipassd/vault",
+	"/var/root/Library/Application Support/multipassd/qemu/vault",
+	"/var/root/Library/Application Support/multipassd/vault",
+}
+
+// scanVMImagesCategory finds virtual machines not started since the cutoff:
+// VirtualBox, VMware and UTM machines, Vagrant boxes no machine uses, cached
+// Multipass images and loose QEMU and other disk images. Each machine is one
+// result holding all its files, so the report shows what each VM takes up.
+func (hs *HyperScanner) scanVMImagesCategory() {
+	home := hs.homeDir()
+	cutoff := time.Now().AddDate(0, 0, -hs.config.VMImages.MinAgeDays)
+	seen := make(map[string]bool)
+
+	addMachine := func(dir, kind string) {
+		dir = filepath.Clean(dir)
+		if seen[dir] || hs.onWindowsDrive(dir) {
+			return
+		}
+		seen[dir] = true
+		hs.addVMDir(dir, fmt.Sprintf("%s VM %q", kind, vmName(dir)), cutoff)
+	}
+
+	// VirtualBox: the machines it has registered, wherever they are, and the
+	// default machine folder
+	for _, dir := range hs.virtualBoxMachines(home) {
+		addMachine(dir, "VirtualBox")
+	}
+	hs.globDirs(filepath.Join(home, "VirtualBox VMs", "*"), "*.vbox", func(dir string) { addMachine(dir, "VirtualBox") })
+
+	// VMware Fusion bundles and Workstation machine folders
+	for _, pattern := range []string{
+		filepath.Join(home, "Virtual Machines.localized", "*.vmwarevm"),
+		filepath.Join(home, "Virtual Machines", "*.vmwarevm"),
+		filepath.Join(home, "vmware", "*"),
+	} {
+		hs.globDirs(pattern, "*.vmx", func(dir string) { addMachine(dir, "VMware") })
+	}
+
+	// UTM bundles
+	hs.globDirs(filepath.Join(home, "Library/Containers/com.utmapp.UTM/Data/Documents", "*.utm"), "", func(dir string) {
+		addMachine(dir, "UTM")
+	})
+
+	hs.scanVagrantBoxes(home, cutoff)
+	hs.scanMultipassImages(cutoff)
+
+	// Loose disk images: libvirt's user session pool and the configured paths
+	roots := append([]string{filepath.Join(home, ".local/share/libvirt/images")}, hs.config.VMImages.ScanPaths...)
+	for _, root := range roots {
+		root = filepath.Clean(expandPath(root, home))
+		entries, err := hs.fs.ReadDir(root)
+		if err != nil || hs.onWindowsDrive(root) {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(root, entry.Name())
+			if !entry.Type().IsRegular() || !isVMDisk(entry.Name()) || seen[path] {
+				continue
+			}
+			seen[path] = true
+			info, err := entry.Info()
+			if err != nil || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			hs.appendResult(FileInfo{
+				Path:     path,
+				Size:     info.Size(),
+				ModTime:  info.ModTime(),
+				Category: VMImagesCategory,
+				Reason:   vmReason("Disk image", info.ModTime()),
+			}, 1)
+		}
+	}
+}
+
+// addVMDir reports a machine's directory if nothing in it changed since
+// cutoff. A running machine writes to its disks and logs, so its newest file
+// is when it was last used.
+func (hs *HyperScanner) addVMDir(dir, what string, cutoff time.Time) {
+	info, err := hs.fs.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	size, files, lastUsed := hs.dirUsage(dir)
+	if lastUsed.IsZero() || !lastUsed.Before(cutoff) {
+		return
+	}
+	hs.appendResult(FileInfo{
+		Path:     dir,
+		Size:     size,
+		ModTime:  lastUsed,
+		Category: VMImagesCategory,
+		Reason:   vmReason(what, lastUsed),
+		Files:    files,
+	}, int64(files))
+}
+
+// dirUsage returns the size and number of files below dir, and when the
+// newest of them was modified
+func (hs *HyperScanner) dirUsage(dir string) (size int64, files int, newest time.Time) {
+	vfs.WalkDir(hs.fs, dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+			files++
+			if info.ModTime().After(newest) {
+				newest = info.ModTime()
+			}
+		}
+		return nil
+	})
+	return size, files, newest
+}
+
+// globDirs calls fn for each directory matching pattern that holds a file
+// matching marker, or for each directory if marker is empty
+func (hs *HyperScanner) globDirs(pattern, marker string, fn func(dir string)) {
+	dirs, _ := vfs.Glob(hs.fs, pattern)
+	for _, dir := range dirs {
+		if info, err := hs.fs.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if marker != "" {
+			if found, _ := vfs.Glob(hs.fs, filepath.Join(dir, marker)); len(found) == 0 {
+				continue
+			}
+		}
+		fn(dir)
+	}
+}
+
+// virtualBoxMachines returns the folders of the machines in VirtualBox's
+// registry, VirtualBox.xml
+func (hs *HyperScanner) virtualBoxMachines(home string) []string {
+	var dirs []string
+	for _, registry := range []string{
+		filepath.Join(home, ".config/VirtualBox/VirtualBox.xml"),
+		filepath.Join(home, "Library/VirtualBox/VirtualBox.xml"),
+		filepath.Join(home, ".VirtualBox/VirtualBox.xml"),
+	} {
+		data, err := hs.readFile(registry)
+		if err != nil {
+			continue
+		}
+		var doc struct {
+			Machines []struct {
+				Src string `xml:"src,attr"`
+			} `xml:"Global>MachineRegistry>MachineEntry"`
+		}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			continue
+		}
+		for _, machine := range doc.Machines {
+			src := machine.Src
+			if src == "" {
+				continue
+			}
+			if !filepath.IsAbs(src) {
+				src = filepath.Join(filepath.Dir(registry), src)
+			}
+			dirs = append(dirs, filepath.Dir(src))
+		}
+	}
+	return dirs
+}
+
+// scanVagrantBoxes reports each version of a Vagrant box that no machine in
+// Vagrant's machine index was created from. Vagrant downloads them again
+// when a Vagrantfile needs them.
+func (hs *HyperScanner) scanVagrantBoxes(home string, cutoff time.Time) {
+	vagrantHome := os.Getenv("VAGRANT_HOME")
+	if vagrantHome == "" {
+		vagrantHome = filepath.Join(home, ".vagrant.d")
+	}
+
+	inUse := make(map[string]bool)
+	if data, err := hs.readFile(filepath.Join(vagrantHome, "data/machine-index/index")); err == nil {
+		var index struct {
+			Machines map[string]struct {
+				ExtraData struct {
+					Box struct {
+						Name    string `json:"name"`
+						Version string `json:"version"`
+					} `json:"box"`
+				} `json:"extra_data"`
+			} `json:"machines"`
+		}
+		if json.Unmarshal(data, &index) == nil {
+			for _, machine := range index.Machines {
+				box := machine.ExtraData.Box
+				inUse[box.Name+"@"+box.Version] = true
+			}
+		}
+	}
+
+	versions, _ := vfs.Glob(hs.fs, filepath.Join(vagrantHome, "boxes", "*", "*"))
+	for _, dir := range versions {
+		name := strings.ReplaceAll(filepath.Base(filepath.Dir(dir)), vagrantSlash, "/")
+		version := filepath.Base(dir)
+		if inUse[name+"@"+version] {
+			continue
+		}
+		hs.addVMDir(dir, fmt.Sprintf("Vagrant box %s %s, used by no machine", name, version), cutoff)
+	}
+}
+
+// scanMultipassImages reports the images in Multipass's cache, by the last
+// access its image records hold. Multipass downloads them again to launch
+// another instance; its instances are left to "multipass delete --purge".
+func (hs *HyperScanner) scanMultipassImages(cutoff time.Time) {
+	for _, vault := range multipassVaults {
+		data, err := hs.readFile(filepath.Join(vault, "multipassd-image-records.json"))
+		if err != nil {
+			continue
+		}
+		var records map[string]struct {
+			Image struct {
+				Path    string `json:"path"`
+				Release string `json:"original_release"`
+			} `json:"image"`
+			LastAccessed int64 `json:"last_accessed"`
+		}
+		if err := json.Unmarshal(data, &records); err != nil {
+			continue
+		}
+		for _, record := range records {
+			if record.Image.Path == "" {
+				continue
+			}
+			dir := filepath.Dir(record.Image.Path)
+			if filepath.Dir(dir) != filepath.Join(vault, "images") {
+				// Only whole image directories in the vault's cache
+				continue
+			}
+			info, err := hs.fs.Stat(dir)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			size, files, lastUsed := hs.dirUsage(dir)
+			if record.LastAccessed > 0 {
+				lastUsed = unixTime(record.LastAccessed)
+			}
+			if lastUsed.IsZero() || !lastUsed.Before(cutoff) {
+				continue
+			}
+			name := record.Image.Release
+			if name == "" {
+				name = filepath.Base(dir)
+			}
+			hs.appendResult(FileInfo{
+				Path:     dir,
+				Size:     size,
+				ModTime:  lastUsed,
+				Category: VMImagesCategory,
+				Reason:   vmReason(fmt.Sprintf("Multipass image %q", name), lastUsed),
+				Files:    files,
+			}, int64(files))
+		}
+	}
+}
+
+// readFile reads a small metadata file from the scanned file system
+func (hs *HyperScanner) readFile(path string) ([]byte, error) {
+	f, err := hs.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, 16<<20))
+}
+
+// unixTime converts a timestamp in seconds, or in milli-, micro- or
+// nanoseconds, since the epoch
+func unixTime(n int64) time.Time {
+	switch {
+	case n > 1e17:
+		return time.Unix(0, n)
+	case n > 1e14:
+		return time.UnixMicro(n)
+	case n > 1e11:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+// vmName is the name of the machine kept in dir, from the folder or bundle
+func vmName(dir string) string {
+	name := filepath.Base(dir)
+	for _, ext := range []string{".vmwarevm", ".utm"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// vmReason describes a VM result and when it was last used
+func vmReason(what string, lastUsed time.Time) string {
+	return fmt.Sprintf("%s, last used %s", what, lastUsed.Format("2006-01-02"))
+}
+
+// isVMDisk reports whether a file name is a virtual machine disk image
+func isVMDisk(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, disk := range vmDiskExts {
+		if ext == disk {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"runtime"
+
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// scanWorkers returns how many directories to read at once: scan.workers if
+// it's set, or else what suits the slowest storage being scanned
+func scanWorkers(cfg *config.Config, info *platform.Info) (int, platform.StorageKind) {
+	if cfg.Scan.Workers > 0 {
+		return cfg.Scan.Workers, platform.StorageUnknown
+	}
+	storage := scanStorage(info)
+	return workersFor(storage, runtime.NumCPU()), storage
+}
+
+// workersFor returns the parallelism that suits a kind of storage
+func workersFor(storage platform.StorageKind, cpus int) int {
+	switch storage {
+	case platform.StorageHDD:
+		// Every extra reader costs a seek
+		return 4
+	case platform.StorageNetwork:
+		// Readers mostly wait on round trips
+		return 32
+	}
+	return min(max(cpus*4, 16), 64)
+}
+
+// scanStorage returns the slowest kind of storage holding the directories
+// scans start from. Spinning disks suffer most from parallel reads, then
+// network mounts.
+func scanStorage(info *platform.Info) platform.StorageKind {
+	roots := []string{info.HomeDir}
+	roots = append(roots, info.CacheDirs...)
+	roots = append(roots, info.TempDirs...)
+	roots = append(roots, info.LogDirs...)
+
+	storage := platform.StorageUnknown
+	seen := make(map[uint64]bool)
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		device, err := platform.DeviceID(root)
+		if err != nil || seen[device] {
+			continue
+		}
+		seen[device] = true
+
+		switch platform.DetectStorage(root) {
+		case platform.StorageHDD:
+			return platform.StorageHDD
+		case platform.StorageNetwork:
+			storage = platform.StorageNetwork
+		case platform.StorageSSD:
+			if storage == platform.StorageUnknown {
+				storage = platform.StorageSSD
+			}
+		}
+	}
+	return storage
+}
+
+// Parallelism returns how many directories the scanner reads at once, and
+// the storage it chose that for (unknown if scan.workers set it)
+func (hs *HyperScanner) Parallelism() (workers int, storage platform.StorageKind) {
+	return hs.workerCount, hs.storage
+}
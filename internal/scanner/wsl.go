@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/platform"
+)
+
+// WSLCategory is the category for Windows-side leftovers of WSL
+const WSLCategory = "wsl"
+
+// distroImageExts are the file types WSL distros are installed or imported from
+var distroImageExts = []string{".wsl", ".appx", ".appxbundle"}
+
+// distroNames mark a tarball as a distro rootfs or a "wsl --export" of one
+var distroNames = []string{"rootfs", "wsl", "ubuntu", "debian", "kali", "alpine", "fedora", "opensuse", "archlinux"}
+
+// onWindowsDrive reports whether path is on a Windows drive that scans skip.
+// Walking DrvFs is very slow, and the files there belong to Windows.
+func (hs *HyperScanner) onWindowsDrive(path string) bool {
+	if !hs.platformInfo.WSL || hs.config.WSL.ScanWindowsDrives {
+		return false
+	}
+	return underAny(path, hs.platformInfo.WindowsMounts)
+}
+
+// underAny reports whether path is one of dirs or inside one of them
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scanWSLCategory finds WSL leftovers in the Windows user profiles: old distro
+// tarballs and packages. Docker Desktop's WSL disks are VM disks, which
+// FindVMDisks finds for compaction instead.
+func (hs *HyperScanner) scanWSLCategory() {
+	if !hs.platformInfo.WSL {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -hs.config.WSL.TarballAgeDays)
+
+	for _, profile := range platform.WindowsProfiles(hs.platformInfo) {
+		for _, dir := range []string{profile, filepath.Join(profile, "Downloads")} {
+			entries, err := hs.fs.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if !entry.Type().IsRegular() || !isDistroImage(entry.Name()) {
+					continue
+				}
+				info, err := entry.Info()
+				if err != nil || !info.ModTime().Before(cutoff) {
+					continue
+				}
+				hs.addWSLResult(filepath.Join(dir, entry.Name()), info, "Old WSL distro image")
+			}
+		}
+	}
+}
+
+// addWSLResult records a WSL leftover with the reason it was found
+func (hs *HyperScanner) addWSLResult(path string, info os.FileInfo, reason string) {
+	hs.appendResult(FileInfo{
+		Path:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Category: WSLCategory,
+		Reason:   reason,
+	}, 1)
+}
+
+// isDistroImage reports whether a file name looks like a WSL distro package or
+// rootfs tarball
+func isDistroImage(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range distroImageExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	if !strings.HasSuffix(name, ".tar") && !strings.Contains(name, ".tar.") {
+		return false
+	}
+	for _, distro := range distroNames {
+		if strings.Contains(name, distro) {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ import (
 // PathValidator handles secure path validation for file operations
 type PathValidator struct {
 	protectedPaths []string
+	protected      protectedDB // Built-in locations protected on this system
 	cache          *PathValidatorCache
 }
 
@@ -35,7 +36,12 @@ func NewPathValidator() *PathValidator {
 			"/System",
 			"/Applications",
 			"/Library/System",
+			// BSD system directories
+			"/libexec",
+			"/rescue",
+			"/altroot",
 		},
+		protected: currentProtectedDB(),
 	}
 }
 
@@ -84,6 +90,11 @@ func (pv *PathValidator) ValidatePathForDeletion(path string) error {
 		return err
 	}
 
+	// Step 7: Check against the built-in database, which protects whole trees
+	if err := pv.protected.check(cleanPath); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,185 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ProtectedDatabaseVersion is the revision of protectedLocations. The entries
+// are reviewed against each supported OS release before every tidyup release;
+// bump this whenever they change.
+const ProtectedDatabaseVersion = "2026.10.1"
+
+// Reasons a location is protected
+const (
+	reasonSIP       = "protected by macOS System Integrity Protection"
+	reasonSystem    = "part of the operating system"
+	reasonPackages  = "holds the package manager's database"
+	reasonContainer = "internal to a container runtime; use its own prune commands"
+)
+
+// protectedLocation is a path that nothing below is ever deleted from,
+// whether or not tidyup runs with sudo
+type protectedLocation struct {
+	path   string   // Absolute, or below the home directory if it starts with ~/
+	goos   []string // Systems it applies to, all if empty
+	reason string
+	except []string // Paths below it that may still be deleted, in the same form
+}
+
+var (
+	darwin = []string{"darwin"}
+	linux  = []string{"linux"}
+	bsd    = []string{"freebsd", "openbsd", "dragonfly"}
+)
+
+// protectedLocations is the built-in database of places deletions are
+// refused in. macOS entries follow /System/Library/Sandbox/rootless.conf;
+// paths behind the /var, /etc and /tmp symlinks are listed under /private.
+var protectedLocations = []protectedLocation{
+	// macOS System Integrity Protection
+	{path: "/System", goos: darwin, reason: reasonSIP, except: []string{"/System/Volumes/Data"}},
+	{path: "/usr", goos: darwin, reason: reasonSIP, except: []string{"/usr/local"}},
+	{path: "/bin", goos: darwin, reason: reasonSIP},
+	{path: "/sbin", goos: darwin, reason: reasonSIP},
+	{path: "/Library/Apple", goos: darwin, reason: reasonSIP},
+	{path: "/Applications/Safari.app", goos: darwin, reason: reasonSIP},
+	{path: "/private/var/db/SystemPolicyConfiguration", goos: darwin, reason: reasonSIP},
+	{path: "/private/var/db/timezone", goos: darwin, reason: reasonSIP},
+
+	// Linux system directories; /bin, /lib and friends are often symlinks
+	// into /usr and resolve to the entries below them
+	{path: "/bin", goos: linux, reason: reasonSystem},
+	{path: "/sbin", goos: linux, reason: reasonSystem},
+	{path: "/lib", goos: linux, reason: reasonSystem},
+	{path: "/lib32", goos: linux, reason: reasonSystem},
+	{path: "/lib64", goos: linux, reason: reasonSystem},
+	{path: "/libx32", goos: linux, reason: reasonSystem},
+	{path: "/usr/bin", goos: linux, reason: reasonSystem},
+	{path: "/usr/sbin", goos: linux, reason: reasonSystem},
+	{path: "/usr/lib", goos: linux, reason: reasonSystem},
+	{path: "/usr/lib32", goos: linux, reason: reasonSystem},
+	{path: "/usr/lib64", goos: linux, reason: reasonSystem},
+	{path: "/usr/libexec", goos: linux, reason: reasonSystem},
+	{path: "/boot", goos: linux, reason: reasonSystem},
+	{path: "/etc", goos: linux, reason: reasonSystem},
+	{path: "/proc", goos: linux, reason: reasonSystem},
+	{path: "/sys", goos: linux, reason: reasonSystem},
+	{path: "/dev", goos: linux, reason: reasonSystem},
+	{path: "/run", goos: linux, reason: reasonSystem},
+	{path: "/snap", goos: linux, reason: reasonSystem},
+	{path: "/var/lib/systemd", goos: linux, reason: reasonSystem},
+	{path: "/var/lib/dpkg", goos: linux, reason: reasonPackages},
+	{path: "/var/lib/rpm", goos: linux, reason: reasonPackages},
+	{path: "/var/lib/pacman", goos: linux, reason: reasonPackages},
+	{path: "/var/lib/flatpak", goos: linux, reason: reasonPackages},
+
+	// BSD system directories
+	{path: "/bin", goos: bsd, reason: reasonSystem},
+	{path: "/sbin", goos: bsd, reason: reasonSystem},
+	{path: "/lib", goos: bsd, reason: reasonSystem},
+	{path: "/libexec", goos: bsd, reason: reasonSystem},
+	{path: "/usr/bin", goos: bsd, reason: reasonSystem},
+	{path: "/usr/sbin", goos: bsd, reason: reasonSystem},
+	{path: "/usr/lib", goos: bsd, reason: reasonSystem},
+	{path: "/usr/libexec", goos: bsd, reason: reasonSystem},
+	{path: "/boot", goos: bsd, reason: reasonSystem},
+	{path: "/etc", goos: bsd, reason: reasonSystem},
+	{path: "/dev", goos: bsd, reason: reasonSystem},
+	{path: "/var/db/pkg", goos: bsd, reason: reasonPackages},
+
+	// Container runtimes, whose storage only they can keep consistent
+	{path: "/var/lib/docker", goos: linux, reason: reasonContainer, except: []string{"/var/lib/docker/tmp"}},
+	{path: "/var/lib/containerd", goos: linux, reason: reasonContainer},
+	{path: "/var/lib/containers", goos: linux, reason: reasonContainer},
+	{path: "/var/lib/kubelet", goos: linux, reason: reasonContainer},
+	{path: "/var/lib/lxc", goos: linux, reason: reasonContainer},
+	{path: "/var/lib/lxd", goos: linux, reason: reasonContainer},
+	{path: "/var/lib/incus", goos: linux, reason: reasonContainer},
+	{path: "~/.local/share/containers", goos: linux, reason: reasonContainer},
+	{path: "~/.docker/desktop", goos: linux, reason: reasonContainer, except: []string{"~/.docker/desktop/log"}},
+	{path: "~/.colima", goos: linux, reason: reasonContainer},
+	{path: "~/.lima", goos: linux, reason: reasonContainer},
+	{path: "~/Library/Containers/com.docker.docker/Data", goos: darwin, reason: reasonContainer,
+		except: []string{"~/Library/Containers/com.docker.docker/Data/log"}},
+	{path: "~/.colima", goos: darwin, reason: reasonContainer},
+	{path: "~/.lima", goos: darwin, reason: reasonContainer},
+	{path: "~/.orbstack", goos: darwin, reason: reasonContainer},
+}
+
+// protectedDB is protectedLocations resolved for one system and user
+type protectedDB struct {
+	goos string
+	home string
+}
+
+// currentProtectedDB resolves the database for the running system and user
+func currentProtectedDB() protectedDB {
+	home, _ := os.UserHomeDir()
+	return protectedDB{goos: runtime.GOOS, home: home}
+}
+
+// expand resolves a database path starting with ~/. Those don't apply
+// without a home directory.
+func (db protectedDB) expand(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if db.home == "" {
+			return ""
+		}
+		return filepath.Join(db.home, rest)
+	}
+	return path
+}
+
+// lookup returns the entry cleanPath is at or below, if any
+func (db protectedDB) lookup(cleanPath string) (protectedLocation, bool) {
+	for _, loc := range protectedLocations {
+		if len(loc.goos) > 0 && !slices.Contains(loc.goos, db.goos) {
+			continue
+		}
+		if !within(cleanPath, db.expand(loc.path)) {
+			continue
+		}
+		excepted := false
+		for _, except := range loc.except {
+			if within(cleanPath, db.expand(except)) {
+				excepted = true
+				break
+			}
+		}
+		if !excepted {
+			return loc, true
+		}
+	}
+	return protectedLocation{}, false
+}
+
+// check refuses cleanPath if it's at or below a protected location
+func (db protectedDB) check(cleanPath string) error {
+	if loc, ok := db.lookup(cleanPath); ok {
+		return fmt.Errorf("refusing to delete %s: %s", cleanPath, loc.reason)
+	}
+	return nil
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	return dir != "" && (path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)))
+}
+
+// CheckProtectedLocation refuses a path in one of the locations the built-in
+// database protects on this system, such as those covered by macOS System
+// Integrity Protection or a container runtime's storage. Symlinks above the
+// path are resolved first, so /var/db on macOS is checked as /private/var/db;
+// a symlink itself only removes the link, so it's checked where it is.
+func CheckProtectedLocation(path string) error {
+	cleanPath := filepath.Clean(path)
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(cleanPath)); err == nil {
+		cleanPath = filepath.Join(dir, filepath.Base(cleanPath))
+	}
+	return currentProtectedDB().check(cleanPath)
+}
@@ -0,0 +1,112 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestProtectedLocationsEntries(t *testing.T) {
+	for _, loc := range protectedLocations {
+		systems := loc.goos
+		if len(systems) == 0 {
+			systems = []string{"linux", "darwin", "freebsd", "openbsd"}
+		}
+		for _, goos := range systems {
+			db := protectedDB{goos: goos, home: "/home/user"}
+			path := db.expand(loc.path)
+			t.Run(goos+path, func(t *testing.T) {
+				for _, p := range []string{path, filepath.Join(path, "child"), filepath.Join(path, "deep", "nested", "file")} {
+					err := db.check(p)
+					if err == nil || !strings.Contains(err.Error(), loc.reason) {
+						t.Errorf("check(%s) = %v, want refusal: %s", p, err, loc.reason)
+					}
+				}
+				// A sibling sharing the name as a prefix isn't protected by it
+				if got, ok := db.lookup(path + "-other"); ok && got.path == loc.path {
+					t.Errorf("%s-other matched %s", path, loc.path)
+				}
+				for _, except := range loc.except {
+					for _, p := range []string{db.expand(except), filepath.Join(db.expand(except), "file")} {
+						if err := db.check(p); err != nil {
+							t.Errorf("check(%s) = %v, want it allowed as an exception", p, err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestProtectedLocationsPerSystem(t *testing.T) {
+	tests := []struct {
+		goos      string
+		path      string
+		protected bool
+	}{
+		{"darwin", "/System/Library/Caches/com.apple.foo", true},
+		{"darwin", "/System/Volumes/Data/Users/user/Library/Caches/x", false},
+		{"darwin", "/usr/local/Cellar/old", false},
+		{"darwin", "/Users/user/Library/Containers/com.docker.docker/Data/vms/0/data/Docker.raw", true},
+		{"linux", "/System/Library/foo", false},
+		{"linux", "/usr/local/lib/foo", false},
+		{"linux", "/var/lib/docker/overlay2/abc", true},
+		{"linux", "/var/lib/docker/tmp/docker-build123", false},
+		{"linux", "/var/cache/apt/archives/foo.deb", false},
+		{"linux", "/home/user/.local/share/containers/storage/overlay", true},
+		{"linux", "/home/user/.docker/desktop/vms/0/data/Docker.raw", true},
+		{"linux", "/home/user/.docker/desktop/log/host/backend.log", false},
+		{"linux", "/home/user/.colima/_lima/colima/diffdisk", true},
+		{"freebsd", "/var/db/pkg/local.sqlite", true},
+		{"plan9", "/usr/bin/foo", false},
+	}
+	for _, tt := range tests {
+		db := protectedDB{goos: tt.goos, home: map[string]string{"darwin": "/Users/user"}[tt.goos]}
+		if db.home == "" {
+			db.home = "/home/user"
+		}
+		if _, got := db.lookup(tt.path); got != tt.protected {
+			t.Errorf("%s: lookup(%s) = %v, want %v", tt.goos, tt.path, got, tt.protected)
+		}
+	}
+
+	// Home entries don't apply without a home directory
+	if _, ok := (protectedDB{goos: "linux"}).lookup("/.local/share/containers/x"); ok {
+		t.Error("expected ~ entries to be skipped without a home directory")
+	}
+}
+
+func TestValidatePathForDeletionProtectedLocation(t *testing.T) {
+	pv := NewPathValidator()
+	pv.protected = protectedDB{goos: "darwin", home: "/Users/user"}
+
+	// Deep paths pass the one-level check, but not the database
+	err := pv.ValidatePathForDeletion("/System/Library/Caches/com.apple.test/data")
+	if err == nil || !strings.Contains(err.Error(), reasonSIP) {
+		t.Errorf("expected a SIP refusal, got %v", err)
+	}
+	if err := pv.ValidatePathForDeletion("/Users/user/Library/Caches/test"); err != nil {
+		t.Errorf("expected a user cache to be allowed, got %v", err)
+	}
+}
+
+func TestCheckProtectedLocationResolvesParents(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses the Linux entries")
+	}
+	dir := t.TempDir()
+	link := filepath.Join(dir, "lib")
+	if err := os.Symlink("/usr/lib", link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link itself can go, but not what's reached through it
+	if err := CheckProtectedLocation(link); err != nil {
+		t.Errorf("expected the symlink itself to be allowed, got %v", err)
+	}
+	if err := CheckProtectedLocation(filepath.Join(link, "libfoo.so")); err == nil {
+		t.Error("expected a file reached through the symlink to be refused")
+	}
+}
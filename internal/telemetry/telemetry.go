@@ -0,0 +1,326 @@
+// Package telemetry exports a trace and metrics of each scan and cleanup to
+// an OpenTelemetry collector over OTLP/HTTP, so tidyup on shared machines can
+// be watched alongside everything else. It speaks OTLP's JSON encoding, which
+// every collector accepts, rather than pulling in the OpenTelemetry SDK.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// scopeName names tidyup as the instrumentation scope of what's exported
+const scopeName = "github.com/fenilsonani/system-cleanup"
+
+// Exporter sends the spans and metrics of runs to an OTLP/HTTP collector
+type Exporter struct {
+	endpoint string // Base URL; /v1/traces and /v1/metrics are appended
+	headers  map[string]string
+	version  string
+	resource resource
+	client   *http.Client
+}
+
+// New returns an exporter to telemetry.endpoint, or OTEL_EXPORTER_OTLP_ENDPOINT
+// if that isn't set. It's nil if neither is, and a nil exporter's runs record
+// nothing. Headers from OTEL_EXPORTER_OTLP_HEADERS are sent along with
+// telemetry.headers, which win.
+func New(cfg *config.Config, version string) *Exporter {
+	endpoint := cfg.Telemetry.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	headers := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for key, value := range cfg.Telemetry.Headers {
+		headers[key] = value
+	}
+
+	timeout := 10 * time.Second
+	if cfg.Telemetry.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Telemetry.Timeout); err == nil {
+			timeout = d
+		}
+	}
+
+	service := cfg.Telemetry.ServiceName
+	if service == "" {
+		service = "tidyup"
+	}
+	attrs := []keyValue{
+		stringAttr("service.name", service),
+		stringAttr("service.version", version),
+		stringAttr("os.type", runtime.GOOS),
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, stringAttr("host.name", host))
+	}
+
+	return &Exporter{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		headers:  headers,
+		version:  version,
+		resource: resource{Attributes: attrs},
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// parseHeaders parses OTEL_EXPORTER_OTLP_HEADERS: key=value pairs separated
+// by commas, with the values URL-encoded
+func parseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// Run collects the spans and metrics of one scan or cleanup until End
+// exports them. Its methods do nothing on a nil Run.
+type Run struct {
+	exporter *Exporter
+	root     span
+	start    time.Time
+	spans    []span
+	metrics  []metric
+	attrs    []keyValue // Added to every data point
+}
+
+// Start starts a run named name, such as "tidyup clean", that began at start.
+// The trace ID is runID's, so the trace can be found from the run ID in logs
+// and reports.
+func (e *Exporter) Start(name, runID string, start time.Time) *Run {
+	if e == nil {
+		return nil
+	}
+	traceID := strings.ReplaceAll(runID, "-", "")
+	if _, err := hex.DecodeString(traceID); err != nil || len(traceID) != 32 {
+		traceID = randomID(16)
+	}
+	r := &Run{
+		exporter: e,
+		start:    start,
+		root: span{
+			TraceID: traceID,
+			SpanID:  randomID(8),
+			Name:    name,
+			Kind:    spanKindInternal,
+		},
+	}
+	if runID != "" {
+		r.root.Attributes = append(r.root.Attributes, stringAttr("tidyup.run_id", runID))
+	}
+	return r
+}
+
+// SetJob records the daemon job the run is for, on the trace and every metric
+func (r *Run) SetJob(job string) {
+	if r == nil {
+		return
+	}
+	r.root.Attributes = append(r.root.Attributes, stringAttr("tidyup.job", job))
+	r.attrs = append(r.attrs, stringAttr("tidyup.job", job))
+}
+
+// Scan records a scan that started at start and found result, with a child
+// span for each category from durations, as from
+// HyperScanner.CategoryDurations. Categories are scanned side by side, so
+// each one's span runs from start for as long as it took, and the scan ends
+// with the slowest.
+func (r *Run) Scan(result *scanner.ScanResult, start time.Time, durations map[string]time.Duration) {
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	end := now
+	if len(durations) > 0 {
+		end = start.Add(slices.Max(slices.Collect(maps.Values(durations))))
+	}
+	scan := r.child("scan", start, end,
+		intAttr("tidyup.files", int64(result.TotalCount)),
+		intAttr("tidyup.size", result.TotalSize),
+		intAttr("tidyup.errors", int64(len(result.Errors))))
+	r.spans = append(r.spans, scan)
+
+	// Spilled results are read back for the totals; without them only the
+	// timings are recorded
+	totals, _ := result.CategoryTotals()
+	categories := make([]string, 0, len(durations))
+	for category := range durations {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+
+	var durationPoints, filePoints, sizePoints []dataPoint
+	for _, category := range categories {
+		total := totals[category]
+		attr := stringAttr("tidyup.category", category)
+		child := r.child("scan "+category, start, start.Add(durations[category]),
+			attr, intAttr("tidyup.files", int64(total.Count)), intAttr("tidyup.size", total.Size))
+		child.ParentSpanID = scan.SpanID
+		r.spans = append(r.spans, child)
+
+		durationPoints = append(durationPoints, doublePoint(end, durations[category].Seconds(), r.with(attr)...))
+		filePoints = append(filePoints, intPoint(time.Time{}, end, int64(total.Count), r.with(attr)...))
+		sizePoints = append(sizePoints, intPoint(time.Time{}, end, total.Size, r.with(attr)...))
+	}
+
+	r.metrics = append(r.metrics,
+		metric{Name: "tidyup.scan.duration", Description: "Time taken to scan each category", Unit: "s", Gauge: &gauge{DataPoints: durationPoints}},
+		metric{Name: "tidyup.scan.files", Description: "Files found to clean, per category", Unit: "{file}", Gauge: &gauge{DataPoints: filePoints}},
+		metric{Name: "tidyup.scan.size", Description: "Size of the files found to clean, per category", Unit: "By", Gauge: &gauge{DataPoints: sizePoints}},
+		counter("tidyup.scan.errors", "Errors scanning", "{error}", start, end, int64(len(result.Errors)), r.attrs),
+	)
+}
+
+// Clean records a cleanup that started at start and ended with result
+func (r *Run) Clean(result *cleaner.CleanResult, start time.Time) {
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	clean := r.child("clean", start, now,
+		intAttr("tidyup.deleted_files", int64(len(result.DeletedFiles))),
+		intAttr("tidyup.deleted_size", result.DeletedSize),
+		intAttr("tidyup.reclaimed_size", result.ReclaimedSize()),
+		intAttr("tidyup.skipped_files", int64(len(result.SkippedFiles))),
+		intAttr("tidyup.errors", int64(len(result.Errors))),
+		boolAttr("tidyup.dry_run", result.DryRun))
+	r.spans = append(r.spans, clean)
+
+	attrs := append(slices.Clone(r.attrs), boolAttr("tidyup.dry_run", result.DryRun))
+	r.metrics = append(r.metrics,
+		counter("tidyup.clean.deleted.files", "Files deleted", "{file}", start, now, int64(len(result.DeletedFiles)), attrs),
+		counter("tidyup.clean.deleted.size", "Size of the files deleted", "By", start, now, result.DeletedSize, attrs),
+		counter("tidyup.clean.reclaimed", "Free space gained on disk", "By", start, now, result.ReclaimedSize(), attrs),
+		counter("tidyup.clean.errors", "Files that couldn't be deleted", "{error}", start, now, int64(len(result.Errors)), attrs),
+	)
+}
+
+// End finishes the run, failed with err if it isn't nil, and exports it
+func (r *Run) End(err error) error {
+	if r == nil {
+		return nil
+	}
+	r.root.StartTimeUnixNano = unixNano(r.start)
+	r.root.EndTimeUnixNano = unixNano(time.Now())
+	if err != nil {
+		r.root.Status = &status{Code: statusCodeError, Message: err.Error()}
+	}
+
+	e := r.exporter
+	sc := scope{Name: scopeName, Version: e.version}
+	traces := tracesRequest{ResourceSpans: []resourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []scopeSpans{{Scope: sc, Spans: append([]span{r.root}, r.spans...)}},
+	}}}
+	var errs []string
+	if err := e.post("/v1/traces", traces); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(r.metrics) > 0 {
+		metrics := metricsRequest{ResourceMetrics: []resourceMetrics{{
+			Resource:     e.resource,
+			ScopeMetrics: []scopeMetrics{{Scope: sc, Metrics: r.metrics}},
+		}}}
+		if err := e.post("/v1/metrics", metrics); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to export telemetry: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// child returns a span under the run's root from start to end
+func (r *Run) child(name string, start, end time.Time, attrs ...keyValue) span {
+	return span{
+		TraceID:           r.root.TraceID,
+		SpanID:            randomID(8),
+		ParentSpanID:      r.root.SpanID,
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        attrs,
+	}
+}
+
+// with returns the run's data point attributes followed by attrs
+func (r *Run) with(attrs ...keyValue) []keyValue {
+	return append(slices.Clone(r.attrs), attrs...)
+}
+
+// counter is a metric counting value between start and end. Each run
+// exports only its own counts (delta temporality), as it exits after.
+func counter(name, description, unit string, start, end time.Time, value int64, attrs []keyValue) metric {
+	return metric{
+		Name:        name,
+		Description: description,
+		Unit:        unit,
+		Sum: &sum{
+			DataPoints:             []dataPoint{intPoint(start, end, value, attrs...)},
+			AggregationTemporality: temporalityDelta,
+			IsMonotonic:            true,
+		},
+	}
+}
+
+// post sends body as JSON to path under the exporter's endpoint
+func (e *Exporter) post(path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", e.endpoint+path, resp.StatusCode)
+	}
+	return nil
+}
+
+// randomID returns n random bytes in hex, for a trace or span ID
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) // Never fails
+	return hex.EncodeToString(b)
+}
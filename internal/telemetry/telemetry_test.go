@@ -0,0 +1,160 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/config"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// collector records the OTLP requests it's sent, by path
+type collector struct {
+	mu       sync.Mutex
+	requests map[string][]byte
+	headers  http.Header
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{requests: make(map[string][]byte)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		c.requests[r.URL.Path] = body
+		c.headers = r.Header.Clone()
+		c.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+func TestNewDisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	exporter := New(config.GetDefault(), "1.0")
+	if exporter != nil {
+		t.Fatal("expected no exporter without an endpoint")
+	}
+
+	// A nil exporter's runs are no-ops
+	run := exporter.Start("tidyup scan", "", time.Now())
+	run.SetJob("nightly")
+	run.Scan(&scanner.ScanResult{}, time.Now(), nil)
+	run.Clean(&cleaner.CleanResult{}, time.Now())
+	if err := run.End(nil); err != nil {
+		t.Fatalf("End on a nil run: %v", err)
+	}
+}
+
+func TestRunExportsSpansAndMetrics(t *testing.T) {
+	c, server := newCollector(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-team=platform,x-key=a%20b")
+	cfg := config.GetDefault()
+	cfg.Telemetry.Endpoint = server.URL + "/"
+	cfg.Telemetry.Headers = map[string]string{"x-key": "secret"}
+
+	runID := "0123abcd-4567-4890-abcd-ef0123456789"
+	run := New(cfg, "1.0").Start("tidyup clean", runID, time.Now())
+	run.SetJob("nightly")
+	start := time.Now().Add(-time.Second)
+	run.Scan(&scanner.ScanResult{
+		Files: []scanner.FileInfo{
+			{Path: "/tmp/a", Size: 100, Category: "cache"},
+			{Path: "/tmp/b", Size: 50, Category: "temp"},
+		},
+		TotalCount: 2,
+		TotalSize:  150,
+		Errors:     []error{errors.New("permission denied")},
+	}, start, map[string]time.Duration{"cache": 300 * time.Millisecond, "temp": 100 * time.Millisecond})
+	run.Clean(&cleaner.CleanResult{DeletedFiles: []string{"/tmp/a"}, DeletedSize: 100}, time.Now())
+	if err := run.End(errors.New("interrupted")); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+
+	if got := c.headers.Get("x-key"); got != "secret" {
+		t.Errorf("x-key header = %q, want the configured one", got)
+	}
+	if got := c.headers.Get("x-team"); got != "platform" {
+		t.Errorf("x-team header = %q, want it from OTEL_EXPORTER_OTLP_HEADERS", got)
+	}
+
+	var traces tracesRequest
+	if err := json.Unmarshal(c.requests["/v1/traces"], &traces); err != nil {
+		t.Fatalf("traces: %v", err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	byName := make(map[string]span)
+	for _, s := range spans {
+		if s.TraceID != "0123abcd45674890abcdef0123456789" {
+			t.Errorf("span %s has trace ID %s, want the run ID's", s.Name, s.TraceID)
+		}
+		byName[s.Name] = s
+	}
+	root := byName["tidyup clean"]
+	if root.Status == nil || root.Status.Code != statusCodeError || root.Status.Message != "interrupted" {
+		t.Errorf("root status = %+v, want the run's error", root.Status)
+	}
+	for _, name := range []string{"scan", "clean"} {
+		if byName[name].ParentSpanID != root.SpanID {
+			t.Errorf("%s span isn't under the root", name)
+		}
+	}
+	cache := byName["scan cache"]
+	if cache.ParentSpanID != byName["scan"].SpanID {
+		t.Error("category span isn't under the scan span")
+	}
+	if want := unixNano(start.Add(300 * time.Millisecond)); cache.EndTimeUnixNano != want {
+		t.Errorf("cache span ends at %s, want %s", cache.EndTimeUnixNano, want)
+	}
+
+	var metrics metricsRequest
+	if err := json.Unmarshal(c.requests["/v1/metrics"], &metrics); err != nil {
+		t.Fatalf("metrics: %v", err)
+	}
+	byMetric := make(map[string]metric)
+	for _, m := range metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		byMetric[m.Name] = m
+	}
+	duration := byMetric["tidyup.scan.duration"].Gauge
+	if duration == nil || len(duration.DataPoints) != 2 || *duration.DataPoints[0].AsDouble != 0.3 {
+		t.Errorf("tidyup.scan.duration = %+v, want 0.3s for cache first", duration)
+	}
+	size := byMetric["tidyup.scan.size"].Gauge
+	if size == nil || *size.DataPoints[1].AsInt != "50" {
+		t.Errorf("tidyup.scan.size = %+v, want 50 bytes of temp", size)
+	}
+	deleted := byMetric["tidyup.clean.deleted.size"].Sum
+	if deleted == nil || *deleted.DataPoints[0].AsInt != "100" || deleted.AggregationTemporality != temporalityDelta {
+		t.Errorf("tidyup.clean.deleted.size = %+v, want a delta of 100", deleted)
+	}
+	errs := byMetric["tidyup.scan.errors"].Sum
+	if errs == nil || *errs.DataPoints[0].AsInt != "1" {
+		t.Errorf("tidyup.scan.errors = %+v, want 1", errs)
+	}
+	if attrs := deleted.DataPoints[0].Attributes; len(attrs) == 0 || attrs[0].Key != "tidyup.job" {
+		t.Errorf("data point attributes = %+v, want the job", attrs)
+	}
+}
+
+func TestRunEndReportsCollectorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	cfg := config.GetDefault()
+	cfg.Telemetry.Endpoint = server.URL
+
+	run := New(cfg, "1.0").Start("tidyup scan", "not-a-uuid", time.Now())
+	if len(run.root.TraceID) != 32 {
+		t.Errorf("trace ID %q isn't 16 bytes of hex", run.root.TraceID)
+	}
+	if err := run.End(nil); err == nil {
+		t.Fatal("expected an error when the collector refuses the export")
+	}
+}
@@ -0,0 +1,954 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// regexPrefix switches the browser filter from fuzzy matching to a regular expression
+const regexPrefix = "re:"
+
+// matchRange is a byte range [start, end) of a matched segment within a path
+type matchRange struct {
+	start, end int
+}
+
+// browserAction is a side effect requested by a keypress that Run carries out
+type browserAction int
+
+const (
+	actionNone browserAction = iota
+	actionReveal
+	actionShell
+	actionIgnore
+)
+
+// BrowserViewModel is a scrollable, filterable list of files the user can
+// select or deselect before cleaning
+type BrowserViewModel struct {
+	files    []FileInfo
+	selected []bool
+	visible  []int                // indices into files that match the filter
+	matches  map[int][]matchRange // matched segments per file, relative to the full path
+
+	cursor int
+	offset int
+	height int
+
+	filter        string
+	filtering     bool // typing into the filter line
+	matchFullPath bool // match against the full path instead of the basename
+	filterErr     error
+
+	sortField  string
+	sortDesc   bool
+	catWidth   int // category column width, 0 hides it
+	ownerWidth int // owner column width, 0 when the files have a single owner
+
+	treeMode bool            // group files by directory
+	tree     *treeNode       // directory tree of the visible files
+	rows     []treeRow       // flattened tree, one entry per line
+	expanded map[string]bool // directories the user expanded or collapsed, by path
+
+	page  int // 1-based page of a spilled scan being reviewed, 0 if it all fits
+	pages int
+
+	action   browserAction
+	ignore   func(paths []string) error // dismisses files for good; nil if not offered
+	status   string                     // one-off message shown until the next keypress
+	keymap   *Keymap
+	showHelp bool
+}
+
+// browserHelp lists the browser bindings for the help overlay
+var browserHelp = []helpSection{
+	{
+		title: "File browser",
+		actions: []Action{ActionUp, ActionDown, ActionPageUp, ActionPageDown, ActionTop, ActionBottom,
+			ActionToggle, ActionToggleAll, ActionFilter, ActionPathMode, ActionSort, ActionReverse,
+			ActionTree, ActionExpand, ActionWiden, ActionNarrow, ActionReveal, ActionShell, ActionIgnore, ActionConfirm, ActionQuit, ActionHelp},
+	},
+	{
+		title: "While typing a filter",
+		fixed: [][2]string{
+			{"re:<pattern>", "Regular expression instead of fuzzy match"},
+			{"tab", "Match name / full path"},
+			{"enter", "Keep filter and return to the list"},
+			{"esc", "Clear filter"},
+		},
+	},
+}
+
+// NewBrowserViewModel creates a browser over files, largest first, with everything selected
+func NewBrowserViewModel(files []FileInfo) *BrowserViewModel {
+	defaults := DefaultPrefs()
+	m := &BrowserViewModel{
+		files:     make([]FileInfo, len(files)),
+		selected:  make([]bool, len(files)),
+		height:    20,
+		keymap:    DefaultKeymap(),
+		sortField: defaults.SortField,
+		sortDesc:  defaults.SortDesc,
+		catWidth:  defaults.ColumnWidths["category"],
+		expanded:  make(map[string]bool),
+	}
+	copy(m.files, files)
+	for i := range m.selected {
+		m.selected[i] = true
+	}
+	m.ownerWidth = ownerColumnWidth(files)
+	m.sortFiles()
+	return m
+}
+
+// SetPage shows which page of a scan too large to review at once the browser holds
+func (m *BrowserViewModel) SetPage(page, pages int) {
+	m.page = page
+	m.pages = pages
+}
+
+// ApplyPrefs restores the sort order, column widths and filter from a previous session
+func (m *BrowserViewModel) ApplyPrefs(p *Prefs) {
+	m.sortField = p.SortField
+	m.sortDesc = p.SortDesc
+	if width, ok := p.ColumnWidths["category"]; ok {
+		m.catWidth = width
+	}
+	m.filter = p.LastFilter
+	m.matchFullPath = p.MatchFullPath
+	m.treeMode = p.TreeView
+	m.sortFiles()
+	if m.filterErr != nil {
+		m.SetFilter("")
+	}
+}
+
+// UpdatePrefs records the current sort order, column widths and filter in p
+func (m *BrowserViewModel) UpdatePrefs(p *Prefs) {
+	p.SortField = m.sortField
+	p.SortDesc = m.sortDesc
+	if p.ColumnWidths == nil {
+		p.ColumnWidths = make(map[string]int)
+	}
+	p.ColumnWidths["category"] = m.catWidth
+	p.LastFilter = m.filter
+	p.MatchFullPath = m.matchFullPath
+	p.TreeView = m.treeMode
+}
+
+// SetTreeMode switches between the flat list and the directory tree
+func (m *BrowserViewModel) SetTreeMode(tree bool) {
+	current, hasCurrent := m.Current()
+	m.treeMode = tree
+	m.applyFilter()
+	if hasCurrent {
+		m.moveCursorTo(current.Path)
+	}
+}
+
+// SetSort changes the sort field and direction
+func (m *BrowserViewModel) SetSort(field string, desc bool) {
+	m.sortField = field
+	m.sortDesc = desc
+	m.sortFiles()
+}
+
+// sortFiles reorders files (and their selection state) by the current sort
+// field, keeping the cursor on the same file
+func (m *BrowserViewModel) sortFiles() {
+	current, hasCurrent := m.Current()
+
+	less := func(a, b FileInfo) bool {
+		switch m.sortField {
+		case SortByAge:
+			// Oldest first reads as "largest age first" when descending
+			return a.ModTime.After(b.ModTime)
+		case SortByPath:
+			return a.Path < b.Path
+		case SortByCategory:
+			if a.Category != b.Category {
+				return a.Category < b.Category
+			}
+			return a.Size < b.Size
+		default:
+			return a.Size < b.Size
+		}
+	}
+
+	order := make([]int, len(m.files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := m.files[order[i]], m.files[order[j]]
+		if m.sortDesc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+
+	files := make([]FileInfo, len(m.files))
+	selected := make([]bool, len(m.files))
+	for i, idx := range order {
+		files[i] = m.files[idx]
+		selected[i] = m.selected[idx]
+	}
+	m.files, m.selected = files, selected
+
+	m.applyFilter()
+
+	if hasCurrent {
+		m.moveCursorTo(current.Path)
+	}
+}
+
+// moveCursorTo puts the cursor on path. In tree mode a file hidden in a
+// collapsed directory puts the cursor on the nearest visible ancestor.
+func (m *BrowserViewModel) moveCursorTo(path string) {
+	if !m.treeMode {
+		for i, idx := range m.visible {
+			if m.files[idx].Path == path {
+				m.cursor = i
+				break
+			}
+		}
+		m.clampOffset()
+		return
+	}
+
+	for {
+		for i, row := range m.rows {
+			if row.node.path == path {
+				m.cursor = i
+				m.clampOffset()
+				return
+			}
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return
+		}
+		path = parent
+	}
+}
+
+// listLen returns the number of rows in the list area
+func (m *BrowserViewModel) listLen() int {
+	if m.treeMode {
+		return len(m.rows)
+	}
+	return len(m.visible)
+}
+
+// rebuildTree regroups the visible files by directory
+func (m *BrowserViewModel) rebuildTree() {
+	if !m.treeMode {
+		m.tree, m.rows = nil, nil
+		return
+	}
+	m.tree = buildTree(m.files, m.visible)
+	sortTree(m.tree, m.files, m.sortField, m.sortDesc)
+	m.rows = flattenTree(m.tree, m.isExpanded)
+}
+
+// isExpanded reports whether a tree directory shows its children. Top-level
+// directories start expanded, as does everything while a filter is active.
+func (m *BrowserViewModel) isExpanded(node *treeNode, depth int) bool {
+	if expanded, ok := m.expanded[node.path]; ok {
+		return expanded
+	}
+	return depth == 0 || m.filter != ""
+}
+
+// setExpanded expands or collapses a tree directory
+func (m *BrowserViewModel) setExpanded(node *treeNode, expanded bool) {
+	m.expanded[node.path] = expanded
+	m.rows = flattenTree(m.tree, m.isExpanded)
+	m.moveCursorTo(node.path)
+}
+
+// toggleFiles selects all the given files unless they're all selected already
+func (m *BrowserViewModel) toggleFiles(indices []int) {
+	allSelected := true
+	for _, idx := range indices {
+		if !m.selected[idx] {
+			allSelected = false
+			break
+		}
+	}
+	for _, idx := range indices {
+		m.selected[idx] = !allSelected
+	}
+}
+
+// checkState returns the checkbox for a set of files: all, some or none selected
+func (m *BrowserViewModel) checkState(indices []int) string {
+	count := 0
+	for _, idx := range indices {
+		if m.selected[idx] {
+			count++
+		}
+	}
+	switch {
+	case count == 0:
+		return "[ ]"
+	case count == len(indices):
+		return "[x]"
+	default:
+		return "[-]"
+	}
+}
+
+// SetIgnore offers dismissing files for good, with fn recording their paths.
+// Dismissed files are taken off the list.
+func (m *BrowserViewModel) SetIgnore(fn func(paths []string) error) {
+	m.ignore = fn
+}
+
+// currentFiles returns the indices of the files under the cursor: every file
+// below a directory in tree mode
+func (m *BrowserViewModel) currentFiles() []int {
+	if m.treeMode {
+		if m.cursor >= len(m.rows) {
+			return nil
+		}
+		return treeFiles(m.rows[m.cursor].node)
+	}
+	if m.cursor >= len(m.visible) {
+		return nil
+	}
+	return []int{m.visible[m.cursor]}
+}
+
+// removeFiles takes files off the list
+func (m *BrowserViewModel) removeFiles(indices []int) {
+	drop := make(map[int]bool, len(indices))
+	for _, idx := range indices {
+		drop[idx] = true
+	}
+	files := m.files[:0]
+	selected := m.selected[:0]
+	for i, file := range m.files {
+		if !drop[i] {
+			files = append(files, file)
+			selected = append(selected, m.selected[i])
+		}
+	}
+	m.files, m.selected = files, selected
+	m.applyFilter()
+}
+
+// SetKeymap replaces the key bindings
+func (m *BrowserViewModel) SetKeymap(km *Keymap) {
+	m.keymap = km
+}
+
+// SetFilter replaces the filter text and recomputes the visible files
+func (m *BrowserViewModel) SetFilter(filter string) {
+	m.filter = filter
+	m.applyFilter()
+}
+
+// SetMatchFullPath toggles matching against the full path instead of the basename
+func (m *BrowserViewModel) SetMatchFullPath(full bool) {
+	m.matchFullPath = full
+	m.applyFilter()
+}
+
+// Visible returns the files that currently match the filter
+func (m *BrowserViewModel) Visible() []FileInfo {
+	files := make([]FileInfo, len(m.visible))
+	for i, idx := range m.visible {
+		files[i] = m.files[idx]
+	}
+	return files
+}
+
+// Current returns the file under the cursor. In tree mode a directory row
+// returns the directory with its total size.
+func (m *BrowserViewModel) Current() (FileInfo, bool) {
+	if m.treeMode {
+		if m.cursor >= len(m.rows) {
+			return FileInfo{}, false
+		}
+		node := m.rows[m.cursor].node
+		if node.isDir() {
+			return FileInfo{Path: node.path, Size: node.size}, true
+		}
+		return m.files[node.file], true
+	}
+
+	if m.cursor >= len(m.visible) {
+		return FileInfo{}, false
+	}
+	return m.files[m.visible[m.cursor]], true
+}
+
+// Selected returns all selected files, including ones hidden by the filter
+func (m *BrowserViewModel) Selected() []FileInfo {
+	files := []FileInfo{}
+	for i, file := range m.files {
+		if m.selected[i] {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// applyFilter recomputes the visible list and match ranges for the current filter
+func (m *BrowserViewModel) applyFilter() {
+	m.filterErr = nil
+
+	var re *regexp.Regexp
+	if strings.HasPrefix(m.filter, regexPrefix) {
+		var err error
+		re, err = regexp.Compile(strings.TrimPrefix(m.filter, regexPrefix))
+		if err != nil {
+			// Keep showing the last valid result while the pattern is incomplete
+			m.filterErr = err
+			return
+		}
+	}
+
+	m.visible = m.visible[:0]
+	m.matches = make(map[int][]matchRange)
+
+	for i, file := range m.files {
+		target, base := file.Path, 0
+		if !m.matchFullPath {
+			base = strings.LastIndex(file.Path, "/") + 1
+			target = file.Path[base:]
+		}
+
+		var ranges []matchRange
+		var ok bool
+		if re != nil {
+			ranges, ok = regexMatch(re, target)
+		} else {
+			ranges, ok = fuzzyMatch(m.filter, target)
+		}
+		if !ok {
+			continue
+		}
+
+		for j := range ranges {
+			ranges[j].start += base
+			ranges[j].end += base
+		}
+		m.visible = append(m.visible, i)
+		m.matches[i] = ranges
+	}
+
+	m.rebuildTree()
+
+	if m.cursor >= m.listLen() {
+		m.cursor = m.listLen() - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.clampOffset()
+}
+
+// fuzzyMatch reports whether all runes of pattern appear in order in target
+// (case-insensitive) and returns the matched positions
+func fuzzyMatch(pattern, target string) ([]matchRange, bool) {
+	if pattern == "" {
+		return nil, true
+	}
+
+	var ranges []matchRange
+	p := []rune(pattern)
+	pi := 0
+	for i, r := range target {
+		if pi == len(p) {
+			break
+		}
+		if unicode.ToLower(r) != unicode.ToLower(p[pi]) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		// Merge adjacent matches so highlighting is one segment
+		if n := len(ranges); n > 0 && ranges[n-1].end == i {
+			ranges[n-1].end = end
+		} else {
+			ranges = append(ranges, matchRange{start: i, end: end})
+		}
+		pi++
+	}
+
+	return ranges, pi == len(p)
+}
+
+// regexMatch returns every non-empty match of re in target
+func regexMatch(re *regexp.Regexp, target string) ([]matchRange, bool) {
+	locs := re.FindAllStringIndex(target, -1)
+	if locs == nil {
+		return nil, false
+	}
+
+	var ranges []matchRange
+	for _, loc := range locs {
+		if loc[1] > loc[0] {
+			ranges = append(ranges, matchRange{start: loc[0], end: loc[1]})
+		}
+	}
+	return ranges, true
+}
+
+// HandleKey applies a keypress. It returns true once the user has confirmed or cancelled.
+func (m *BrowserViewModel) HandleKey(key Key) (finished, cancelled bool) {
+	m.status = ""
+
+	if m.filtering {
+		switch key {
+		case KeyEnter:
+			m.filtering = false
+		case KeyEscape:
+			m.filtering = false
+			m.SetFilter("")
+		case KeyBackspace:
+			if m.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(m.filter)
+				m.SetFilter(m.filter[:len(m.filter)-size])
+			}
+		case KeySpace:
+			m.SetFilter(m.filter + " ")
+		case KeyTab:
+			m.SetMatchFullPath(!m.matchFullPath)
+		case KeyCtrlC:
+			return true, true
+		default:
+			if utf8.RuneCountInString(string(key)) == 1 {
+				m.SetFilter(m.filter + string(key))
+			}
+		}
+		return false, false
+	}
+
+	// Any key closes the help overlay
+	if m.showHelp {
+		m.showHelp = false
+		return false, false
+	}
+
+	switch m.keymap.Action(key) {
+	case ActionUp:
+		m.moveCursor(-1)
+	case ActionDown:
+		m.moveCursor(1)
+	case ActionPageUp:
+		m.moveCursor(-m.height)
+	case ActionPageDown:
+		m.moveCursor(m.height)
+	case ActionTop:
+		m.moveCursor(-m.listLen())
+	case ActionBottom:
+		m.moveCursor(m.listLen())
+	case ActionToggle:
+		if m.treeMode && m.cursor < len(m.rows) {
+			// A directory toggles everything under it
+			m.toggleFiles(treeFiles(m.rows[m.cursor].node))
+		} else if !m.treeMode && m.cursor < len(m.visible) {
+			idx := m.visible[m.cursor]
+			m.selected[idx] = !m.selected[idx]
+		}
+	case ActionToggleAll:
+		// Toggle every visible file; select all unless all are already selected
+		m.toggleFiles(m.visible)
+	case ActionTree:
+		m.SetTreeMode(!m.treeMode)
+	case ActionExpand:
+		if m.treeMode && m.cursor < len(m.rows) {
+			node := m.rows[m.cursor].node
+			if node.isDir() {
+				m.setExpanded(node, !m.isExpanded(node, m.rows[m.cursor].depth))
+			} else if node.parent != m.tree {
+				// On a file, collapse the directory it's in
+				m.setExpanded(node.parent, false)
+			}
+		}
+	case ActionFilter:
+		m.filtering = true
+	case ActionReveal:
+		m.action = actionReveal
+	case ActionShell:
+		m.action = actionShell
+	case ActionIgnore:
+		if m.ignore != nil {
+			m.action = actionIgnore
+		}
+	case ActionPathMode:
+		m.SetMatchFullPath(!m.matchFullPath)
+	case ActionSort:
+		next := sortFields[0]
+		for i, field := range sortFields {
+			if field == m.sortField && i+1 < len(sortFields) {
+				next = sortFields[i+1]
+			}
+		}
+		// Size and age read best largest/oldest first, path and category A-Z
+		m.SetSort(next, next == SortBySize || next == SortByAge)
+	case ActionReverse:
+		m.SetSort(m.sortField, !m.sortDesc)
+	case ActionWiden:
+		if m.catWidth < maxCategoryWidth {
+			m.catWidth += 2
+		}
+	case ActionNarrow:
+		m.catWidth -= 2
+		if m.catWidth < minCategoryWidth {
+			// Narrowing past the minimum hides the column
+			m.catWidth = 0
+		}
+	case ActionHelp:
+		m.showHelp = true
+	case ActionConfirm:
+		return true, false
+	case ActionQuit:
+		return true, true
+	}
+
+	return false, false
+}
+
+// moveCursor moves the cursor by delta, keeping it on screen
+func (m *BrowserViewModel) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= m.listLen() {
+		m.cursor = m.listLen() - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.clampOffset()
+}
+
+// clampOffset scrolls so the cursor row is within the list area
+func (m *BrowserViewModel) clampOffset() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+// View renders the browser as lines of text for the given terminal size
+func (m *BrowserViewModel) View(width, height int) []string {
+	// Title, filter line, blank, then list, then blank, status and key hints
+	m.height = height - 6
+	if m.height < 1 {
+		m.height = 1
+	}
+	m.clampOffset()
+
+	if m.showHelp {
+		return m.keymap.helpView(browserHelp)
+	}
+
+	var lines []string
+	direction := "↑"
+	if m.sortDesc {
+		direction = "↓"
+	}
+	info := fmt.Sprintf("sort: %s %s", m.sortField, direction)
+	if m.treeMode {
+		info += ", by directory"
+	}
+	if m.pages > 1 {
+		info += ", " + i18n.T("page %d of %d", m.page, m.pages)
+	}
+	lines = append(lines, " "+styles.Title.Render(i18n.T("Review files to clean (%d of %d shown)", len(m.visible), len(m.files)))+
+		"  "+styles.Dim.Render(info))
+
+	mode := "name"
+	if m.matchFullPath {
+		mode = "path"
+	}
+	if strings.HasPrefix(m.filter, regexPrefix) {
+		mode += ", regex"
+	}
+	filterLine := i18n.T(" Filter [%s]: %s", mode, m.filter)
+	if m.filtering {
+		filterLine += "█"
+	}
+	if m.filterErr != nil {
+		filterLine += "  " + styles.Error.Render(i18n.T("(invalid pattern)"))
+	}
+	lines = append(lines, filterLine, "")
+
+	end := m.offset + m.height
+	if end > m.listLen() {
+		end = m.listLen()
+	}
+	for i := m.offset; i < end; i++ {
+		if m.treeMode {
+			row := m.rows[i]
+			if row.node.isDir() {
+				lines = append(lines, renderTreeRow(row, FileInfo{}, nil, width, m.catWidth, m.ownerWidth,
+					m.checkState(treeFiles(row.node)), m.isExpanded(row.node, row.depth), i == m.cursor))
+			} else {
+				idx := row.node.file
+				lines = append(lines, renderTreeRow(row, m.files[idx], m.matches[idx], width, m.catWidth, m.ownerWidth,
+					m.checkState([]int{idx}), false, i == m.cursor))
+			}
+			continue
+		}
+		idx := m.visible[i]
+		lines = append(lines, renderFileRow(m.files[idx], m.matches[idx], width, m.catWidth, m.ownerWidth, m.selected[idx], i == m.cursor))
+	}
+	for i := end - m.offset; i < m.height; i++ {
+		lines = append(lines, "")
+	}
+
+	var count, sudo int
+	var size int64
+	for i, file := range m.files {
+		if m.selected[i] {
+			count++
+			size += file.Size
+			if file.Access == scanner.AccessNeedsSudo {
+				sudo++
+			}
+		}
+	}
+	statusLine := i18n.T(" Selected: %d files, %s", count, formatBytes(size))
+	if sudo > 0 {
+		statusLine += "  " + styles.Error.Render(i18n.T("%d need sudo (#)", sudo))
+	}
+	if m.status != "" {
+		statusLine += "  " + styles.Dim.Render(m.status)
+	}
+	lines = append(lines, "", statusLine)
+
+	if m.filtering {
+		lines = append(lines, " "+styles.Dim.Render(i18n.T("type to filter  re: regex  tab name/path  enter done  esc clear")))
+	} else {
+		lines = append(lines, m.keymap.hintLine(
+			keyHint{ActionToggle, "toggle"},
+			keyHint{ActionToggleAll, "all"},
+			keyHint{ActionFilter, "filter"},
+			keyHint{ActionSort, "sort"},
+			keyHint{ActionTree, "tree"},
+			keyHint{ActionReveal, "reveal"},
+			keyHint{ActionConfirm, "confirm"},
+			keyHint{ActionQuit, "quit"},
+			keyHint{ActionHelp, "help"},
+		))
+	}
+
+	return lines
+}
+
+// Category column width limits
+const (
+	minCategoryWidth = 6
+	maxCategoryWidth = 40
+)
+
+// renderFileRow renders one file line, highlighting the matched segments of its
+// path. catWidth is the width of the category column, 0 to leave it and the
+// risk column out; ownerWidth is the width of the owner column, 0 to leave it out.
+func renderFileRow(file FileInfo, matches []matchRange, width, catWidth, ownerWidth int, selected, current bool) string {
+	size := fmt.Sprintf("%10s", formatBytes(file.Size))
+	category, risk := "", ""
+	if catWidth > 0 {
+		category = fitWidth(file.Category, catWidth) + "  "
+		risk = fitWidth(file.Risk, riskWidth) + "  "
+	}
+	access := accessMark(file.Access)
+	owner := ""
+	if ownerWidth > 0 {
+		owner = fitWidth(file.Owner, ownerWidth) + "  "
+	}
+	cursor := "  "
+	if current {
+		cursor = styles.Cursor.Render("> ")
+		size = styles.Cursor.Render(size)
+	}
+	check := "[ ]"
+	if selected {
+		check = styles.Selected.Render("[x]")
+	}
+	prefix := fmt.Sprintf(" %s%s %s%s %s%s%s", cursor, check, size, access, styles.Dim.Render(category), riskStyle(file.Risk).Render(risk), styles.Dim.Render(owner))
+
+	// Trim the start of long paths so the file name stays visible
+	path := file.Path
+	shift := 0
+	avail := width - utf8.RuneCountInString(fmt.Sprintf(" > [x] %10s  %s%s%s", formatBytes(file.Size), category, risk, owner))
+	if avail > 3 && utf8.RuneCountInString(path) > avail {
+		cut := len(path) - (avail - 3)
+		for cut < len(path) && !utf8.RuneStart(path[cut]) {
+			cut++
+		}
+		path = "..." + path[cut:]
+		shift = 3 - cut
+	}
+
+	minStart := 0
+	if shift != 0 {
+		// Segments starting in the trimmed part of the path begin after "..."
+		minStart = 3
+	}
+	return prefix + highlightSegments(path, matches, shift, minStart)
+}
+
+// highlightSegments renders text with the matched ranges highlighted. Ranges
+// are moved by shift and clipped to start no earlier than minStart.
+func highlightSegments(text string, matches []matchRange, shift, minStart int) string {
+	var b strings.Builder
+	pos := 0
+	for _, r := range matches {
+		start, end := r.start+shift, r.end+shift
+		if start < minStart {
+			start = minStart
+		}
+		if start < pos {
+			start = pos
+		}
+		if start >= end || end > len(text) {
+			continue
+		}
+		b.WriteString(text[pos:start])
+		b.WriteString(styles.Match.Render(text[start:end]))
+		pos = end
+	}
+	b.WriteString(text[pos:])
+
+	return b.String()
+}
+
+// riskWidth is the width of the risk column, shown beside the category
+const riskWidth = 6
+
+// maxOwnerWidth caps the owner column, so long names don't crowd out paths
+const maxOwnerWidth = 12
+
+// ownerColumnWidth returns how wide the owner column must be for files, or 0
+// if they all have the same owner and it would say nothing
+func ownerColumnWidth(files []FileInfo) int {
+	width := 0
+	owners := make(map[string]bool)
+	for _, file := range files {
+		owners[file.Owner] = true
+		width = max(width, utf8.RuneCountInString(file.Owner))
+	}
+	if len(owners) < 2 {
+		return 0
+	}
+	return min(width, maxOwnerWidth)
+}
+
+// accessMark flags the files cleaning won't delete as the current user: #
+// for those that need sudo, as in a root shell's prompt, and - for those
+// that won't be deleted at all
+func accessMark(access string) string {
+	switch access {
+	case scanner.AccessNeedsSudo:
+		return styles.Error.Render("#")
+	case scanner.AccessInaccessible:
+		return styles.Dim.Render("-")
+	}
+	return " "
+}
+
+// riskStyle is how a risk level is shown: files that need review stand out
+func riskStyle(risk string) Style {
+	if risk == scanner.RiskReview {
+		return styles.Error
+	}
+	return styles.Dim
+}
+
+// fitWidth pads or truncates s to exactly width runes
+func fitWidth(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		if width > 1 {
+			return string(runes[:width-1]) + "…"
+		}
+		return string(runes[:width])
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// Run displays the browser until the user confirms or cancels. It returns the
+// selected files, or nil if the user cancelled.
+func (m *BrowserViewModel) Run() ([]FileInfo, error) {
+	t, err := OpenTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	for {
+		width, height := t.Size()
+		t.Draw(m.View(width, height))
+
+		key := <-t.Keys()
+		if finished, cancelled := m.HandleKey(key); finished {
+			if cancelled {
+				return nil, nil
+			}
+			return m.Selected(), nil
+		}
+
+		if err := m.runAction(t); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// runAction carries out the action requested by the last keypress
+func (m *BrowserViewModel) runAction(t *Terminal) error {
+	action := m.action
+	m.action = actionNone
+
+	file, ok := m.Current()
+	if action == actionNone || !ok {
+		return nil
+	}
+
+	switch action {
+	case actionReveal:
+		if err := RevealInFileManager(file.Path); err != nil {
+			m.status = err.Error()
+		} else {
+			m.status = i18n.T("Revealed %s", getFileName(file.Path))
+		}
+	case actionShell:
+		t.Suspend()
+		if err := OpenShell(ShellDir(file.Path)); err != nil {
+			m.status = err.Error()
+		}
+		if err := t.Resume(); err != nil {
+			return err
+		}
+	case actionIgnore:
+		indices := m.currentFiles()
+		paths := make([]string, len(indices))
+		for i, idx := range indices {
+			paths[i] = m.files[idx].Path
+		}
+		if err := m.ignore(paths); err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		m.removeFiles(indices)
+		m.status = i18n.T("%d files won't be flagged again", len(paths))
+	}
+
+	return nil
+}
@@ -0,0 +1,512 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// maxExpandedPaths limits how many paths an expanded failure group shows
+const maxExpandedPaths = 10
+
+// failureGroup is a set of files that could not be deleted for the same reason
+type failureGroup struct {
+	title       string
+	paths       []string
+	retryable   bool // worth trying again without elevated permissions
+	escalatable bool // may succeed with sudo
+	expanded    bool
+}
+
+// CleanView runs a cleanup inside the TUI, showing live progress and then a
+// results screen where failures can be drilled into, retried or escalated to sudo
+type CleanView struct {
+	clnr   *cleaner.Cleaner
+	files  map[string]scanner.FileInfo
+	result *cleaner.CleanResult
+	groups []*failureGroup
+
+	cursor   int
+	status   string
+	duration time.Duration
+	keymap   *Keymap
+	showHelp bool
+
+	// Latest progress update while a clean is running
+	progress *progress.CleanProgress
+}
+
+// NewCleanView creates a clean view for the files in scanResult
+func NewCleanView(clnr *cleaner.Cleaner, scanResult *scanner.ScanResult) *CleanView {
+	v := &CleanView{
+		clnr:   clnr,
+		files:  make(map[string]scanner.FileInfo, len(scanResult.Files)),
+		keymap: DefaultKeymap(),
+	}
+	for _, file := range scanResult.Files {
+		v.files[file.Path] = file
+	}
+	return v
+}
+
+// RunCleanView deletes the files in scanResult inside the TUI and returns the
+// final result, including any retries the user made from the results screen.
+// The cleaner must not prompt for sudo itself; escalation is offered per group.
+func RunCleanView(clnr *cleaner.Cleaner, scanResult *scanner.ScanResult, km *Keymap) (*cleaner.CleanResult, error) {
+	t, err := OpenTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	v := NewCleanView(clnr, scanResult)
+	if km != nil {
+		v.keymap = km
+	}
+
+	start := time.Now()
+	result, err := v.runClean(t, scanResult)
+	if err != nil {
+		return nil, err
+	}
+	v.result = result
+	v.duration = time.Since(start)
+	v.buildGroups()
+
+	for {
+		width, height := t.Size()
+		t.Draw(v.View(width, height))
+
+		key := <-t.Keys()
+		action, done := v.HandleKey(key)
+		if done {
+			return v.result, nil
+		}
+		if err := v.runAction(t, action); err != nil {
+			return v.result, err
+		}
+	}
+}
+
+// runClean runs the cleaner in the background while drawing the progress screen
+func (v *CleanView) runClean(t *Terminal, scanResult *scanner.ScanResult) (*cleaner.CleanResult, error) {
+	reporter := v.clnr.GetProgressReporter()
+	updates := reporter.Subscribe()
+	defer reporter.Unsubscribe(updates)
+
+	type cleanOutcome struct {
+		result *cleaner.CleanResult
+		err    error
+	}
+	done := make(chan cleanOutcome, 1)
+	go func() {
+		result, err := v.clnr.Clean(scanResult)
+		done <- cleanOutcome{result, err}
+	}()
+
+	v.progress = &progress.CleanProgress{
+		TotalFiles: len(scanResult.Files),
+		TotalSize:  scanResult.TotalSize,
+		StartTime:  time.Now(),
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		width, _ := t.Size()
+		t.Draw(v.progressView(width))
+
+		select {
+		case update := <-updates:
+			if p, ok := update.(*progress.CleanProgress); ok {
+				v.progress = p
+			}
+		case outcome := <-done:
+			v.progress = nil
+			return outcome.result, outcome.err
+		case <-t.Keys():
+			// Deletion can't be interrupted safely part-way through a file
+		case <-ticker.C:
+		}
+	}
+}
+
+// progressView renders the live deletion progress screen
+func (v *CleanView) progressView(width int) []string {
+	p := v.progress
+	elapsed := time.Since(p.StartTime)
+
+	rate := p.FilesPerSecond
+	if rate == 0 && elapsed > 0 {
+		rate = float64(p.DeletedFiles) / elapsed.Seconds()
+	}
+
+	percent := 0
+	if p.TotalFiles > 0 {
+		percent = p.DeletedFiles * 100 / p.TotalFiles
+	}
+	barWidth := 40
+	filled := barWidth * percent / 100
+
+	title, freed := i18n.T("Cleaning..."), i18n.T(" Freed:   %s / %s", formatBytes(p.DeletedSize), formatBytes(p.TotalSize))
+	if p.Phase == progress.PhaseChecksumming {
+		title = i18n.T("Checksumming large files for the audit log...")
+		freed = i18n.T(" Hashed:  %s / %s", formatBytes(p.DeletedSize), formatBytes(p.TotalSize))
+	}
+	lines := []string{
+		" " + styles.Title.Render(title),
+		"",
+		fmt.Sprintf(" [%s%s] %d%%", strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), percent),
+		"",
+		i18n.T(" Files:   %d / %d  (%.0f files/sec)", p.DeletedFiles, p.TotalFiles, rate),
+		freed,
+		i18n.T(" Elapsed: %s", elapsed.Round(time.Second)),
+	}
+	if p.ETA > 0 {
+		lines = append(lines, i18n.T(" Left:    about %s", p.ETA.Round(time.Second)))
+	}
+	if p.UsingSudo {
+		lines = append(lines, " Using elevated permissions")
+	}
+	if p.CurrentFile != "" {
+		lines = append(lines, "", " "+truncate(p.CurrentFile, width-2))
+	}
+
+	return lines
+}
+
+// buildGroups groups the current failures by reason for the results screen
+func (v *CleanView) buildGroups() {
+	expanded := make(map[string]bool)
+	for _, g := range v.groups {
+		expanded[g.title] = g.expanded
+	}
+
+	var groups []*failureGroup
+	inError := make(map[string]bool)
+
+	grouped := cleaner.GroupErrors(v.result.Errors)
+	reasons := make([]cleaner.ErrorReason, 0, len(grouped))
+	for reason := range grouped {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i] < reasons[j] })
+
+	for _, reason := range reasons {
+		errs := grouped[reason]
+		g := &failureGroup{title: reason.String()}
+		for _, err := range errs {
+			g.paths = append(g.paths, err.Path)
+			g.retryable = g.retryable || err.Retryable
+			g.escalatable = g.escalatable || err.NeedsSudo
+			inError[err.Path] = true
+		}
+		// Files already gone need no further action
+		if reason == cleaner.ErrorFileNotFound {
+			g.retryable = false
+		}
+		// A busy file may have been released since the first attempt
+		if reason == cleaner.ErrorFileInUse || reason == cleaner.ErrorUnknown {
+			g.retryable = true
+		}
+		groups = append(groups, g)
+	}
+
+	// Files the cleaner skipped without trying, because they need sudo
+	sudoGroup := &failureGroup{title: i18n.T("Requires elevated permissions"), escalatable: true}
+	readOnlyGroup := &failureGroup{title: i18n.T(cleaner.SkippedReadOnly)}
+	otherGroup := &failureGroup{title: i18n.T("Skipped by safety checks")}
+	for _, path := range v.result.SkippedFiles {
+		if inError[path] {
+			continue
+		}
+		switch reason := v.result.SkippedReason[path]; {
+		case strings.HasPrefix(reason, "Requires elevated permissions"):
+			sudoGroup.paths = append(sudoGroup.paths, path)
+		case strings.HasPrefix(reason, cleaner.SkippedReadOnly):
+			readOnlyGroup.paths = append(readOnlyGroup.paths, path)
+		default:
+			otherGroup.paths = append(otherGroup.paths, path)
+		}
+	}
+	for _, g := range []*failureGroup{sudoGroup, readOnlyGroup, otherGroup} {
+		if len(g.paths) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	for _, g := range groups {
+		sort.Strings(g.paths)
+		g.expanded = expanded[g.title]
+	}
+
+	v.groups = groups
+	if v.cursor >= len(v.groups) {
+		v.cursor = len(v.groups) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+}
+
+// cleanAction is a follow-up requested from the results screen
+type cleanAction int
+
+const (
+	cleanActionNone cleanAction = iota
+	cleanActionRetry
+	cleanActionEscalate
+	cleanActionUndo
+)
+
+// HandleKey applies a keypress on the results screen
+func (v *CleanView) HandleKey(key Key) (action cleanAction, done bool) {
+	v.status = ""
+
+	// Any key closes the help overlay
+	if v.showHelp {
+		v.showHelp = false
+		return cleanActionNone, false
+	}
+
+	switch v.keymap.Action(key) {
+	case ActionUp:
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case ActionDown:
+		if v.cursor < len(v.groups)-1 {
+			v.cursor++
+		}
+	case ActionExpand, ActionToggle, ActionConfirm:
+		if g := v.currentGroup(); g != nil {
+			g.expanded = !g.expanded
+		}
+	case ActionRetry:
+		if g := v.currentGroup(); g != nil && g.retryable {
+			return cleanActionRetry, false
+		}
+		v.status = i18n.T("Nothing to retry in this group")
+	case ActionEscalate:
+		if g := v.currentGroup(); g != nil && g.escalatable {
+			return cleanActionEscalate, false
+		}
+		v.status = i18n.T("Elevated permissions won't help this group")
+	case ActionUndo:
+		if v.canUndo() {
+			return cleanActionUndo, false
+		}
+		v.status = i18n.T("Undo needs quarantine mode (quarantine.enabled in the config)")
+	case ActionHelp:
+		v.showHelp = true
+	case ActionQuit:
+		return cleanActionNone, true
+	}
+
+	return cleanActionNone, false
+}
+
+// currentGroup returns the failure group under the cursor
+func (v *CleanView) currentGroup() *failureGroup {
+	if v.cursor < len(v.groups) {
+		return v.groups[v.cursor]
+	}
+	return nil
+}
+
+// canUndo reports whether this cleanup's files are in the quarantine
+func (v *CleanView) canUndo() bool {
+	if v.clnr == nil {
+		return false
+	}
+	run := v.clnr.QuarantineRun()
+	return run != nil && len(run.Entries) > 0
+}
+
+// undo restores the quarantined files and removes them from the result
+func (v *CleanView) undo() {
+	restored, errs := v.clnr.QuarantineRun().Restore()
+
+	back := make(map[string]bool, len(restored))
+	for _, path := range restored {
+		back[path] = true
+	}
+	deleted := v.result.DeletedFiles[:0]
+	for _, path := range v.result.DeletedFiles {
+		if back[path] {
+			v.result.DeletedSize -= v.files[path].Size
+			continue
+		}
+		deleted = append(deleted, path)
+	}
+	v.result.DeletedFiles = deleted
+	// Quarantined files never freed any space, so there's nothing left to compare
+	v.result.Filesystems = nil
+
+	v.status = i18n.T("Restored %d files", len(restored))
+	if len(errs) > 0 {
+		v.status += fmt.Sprintf(", %d could not be restored (see 'tidyup undo')", len(errs))
+	}
+}
+
+// runAction retries or escalates the highlighted group, or undoes the cleanup
+func (v *CleanView) runAction(t *Terminal, action cleanAction) error {
+	if action == cleanActionUndo {
+		v.undo()
+		return nil
+	}
+
+	g := v.currentGroup()
+	if action == cleanActionNone || g == nil {
+		return nil
+	}
+
+	files := make([]scanner.FileInfo, 0, len(g.paths))
+	var size int64
+	for _, path := range g.paths {
+		file, ok := v.files[path]
+		if !ok {
+			file = scanner.FileInfo{Path: path}
+		}
+		files = append(files, file)
+		size += file.Size
+	}
+
+	var result *cleaner.CleanResult
+	var err error
+
+	switch action {
+	case cleanActionRetry:
+		retry := &scanner.ScanResult{Files: files, TotalSize: size, TotalCount: len(files)}
+		result, err = v.runClean(t, retry)
+	case cleanActionEscalate:
+		// The sudo prompt needs a normal terminal
+		t.Suspend()
+		fmt.Printf("Deleting %d files with elevated permissions\n", len(files))
+		result, err = v.clnr.EscalateFiles(files)
+		if resumeErr := t.Resume(); resumeErr != nil {
+			return resumeErr
+		}
+	}
+
+	if err != nil {
+		v.status = err.Error()
+		return nil
+	}
+
+	before := len(v.result.DeletedFiles)
+	v.result.Merge(result, g.paths)
+	v.buildGroups()
+	v.status = i18n.T("Deleted %d of %d files", len(v.result.DeletedFiles)-before, len(files))
+
+	return nil
+}
+
+// View renders the results screen
+func (v *CleanView) View(width, height int) []string {
+	if v.showHelp {
+		return v.keymap.helpView([]helpSection{{
+			title: "Cleanup results",
+			actions: []Action{ActionUp, ActionDown, ActionExpand, ActionConfirm, ActionRetry,
+				ActionEscalate, ActionUndo, ActionQuit, ActionHelp},
+		}})
+	}
+
+	r := v.result
+
+	lines := []string{
+		" " + styles.Title.Render(i18n.T("Cleanup complete")),
+		"",
+		" " + styles.Success.Render(i18n.T("Deleted: %d files (%s) in %s", len(r.DeletedFiles), formatBytes(r.DeletedSize), v.duration.Round(time.Millisecond))),
+	}
+	if len(r.Filesystems) > 0 {
+		lines = append(lines, i18n.T(" Space actually freed: %s", formatSignedBytes(r.ReclaimedSize())))
+		if files := r.ReclaimedFiles(); files != 0 {
+			lines = append(lines, i18n.T(" Inodes actually freed: %d", files))
+		}
+		for _, fs := range r.Filesystems {
+			if fs.Note != "" {
+				lines = append(lines, " "+styles.Dim.Render(fs.Path+": "+i18n.T(fs.Note)))
+			}
+		}
+	}
+	if len(r.Copied) > 0 {
+		lines = append(lines, i18n.T(" Copied to the quarantine from another disk (slower): %d files", len(r.Copied)))
+	}
+	if r.UsedSudo {
+		lines = append(lines, i18n.T(" Used elevated permissions: %d succeeded, %d failed", r.SudoSucceeded, r.SudoFailed))
+	}
+	lines = append(lines, "")
+
+	if len(v.groups) == 0 {
+		lines = append(lines, " "+styles.Success.Render(i18n.T("No issues encountered")))
+	} else {
+		lines = append(lines, " "+styles.Error.Render(i18n.T("Issues:")))
+	}
+
+	for i, g := range v.groups {
+		title := fmt.Sprintf("%s: %d files", g.title, len(g.paths))
+		cursor := "  "
+		if i == v.cursor {
+			cursor = styles.Cursor.Render("> ")
+			title = styles.Cursor.Render(title)
+		}
+		arrow := "▸"
+		if g.expanded {
+			arrow = "▾"
+		}
+
+		var hints []string
+		if g.retryable {
+			hints = append(hints, v.keymap.Hint(ActionRetry, "retry"))
+		}
+		if g.escalatable {
+			hints = append(hints, v.keymap.Hint(ActionEscalate, "sudo"))
+		}
+		hint := ""
+		if len(hints) > 0 {
+			hint = "  " + styles.Dim.Render("("+strings.Join(hints, ", ")+")")
+		}
+
+		lines = append(lines, fmt.Sprintf(" %s%s %s%s", cursor, arrow, title, hint))
+
+		if g.expanded {
+			for j, path := range g.paths {
+				if j == maxExpandedPaths {
+					lines = append(lines, i18n.T("        ... and %d more", len(g.paths)-maxExpandedPaths))
+					break
+				}
+				lines = append(lines, "        "+truncate(path, width-9))
+			}
+		}
+	}
+
+	lines = append(lines, "")
+	if v.status != "" {
+		lines = append(lines, " "+styles.Dim.Render(v.status))
+	}
+	hints := []keyHint{
+		{ActionConfirm, "expand"},
+		{ActionRetry, "retry"},
+		{ActionEscalate, "sudo"},
+	}
+	if v.canUndo() {
+		hints = append(hints, keyHint{ActionUndo, "undo"})
+	}
+	hints = append(hints, keyHint{ActionQuit, "done"}, keyHint{ActionHelp, "help"})
+	lines = append(lines, v.keymap.hintLine(hints...))
+
+	// Keep the key hints visible on short terminals
+	if len(lines) > height && height > 2 {
+		lines = append(lines[:height-2], lines[len(lines)-2:]...)
+	}
+
+	return lines
+}
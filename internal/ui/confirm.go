@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+)
+
+// ConfirmToken returns what the user types to confirm deleting size bytes,
+// the amount rounded to a whole number in its largest unit, e.g. "52GB". The
+// units are powers of 1000 with --si, as in the sizes shown beside it.
+func ConfirmToken(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	base := 1024.0
+	if utils.SI() {
+		base = 1000
+	}
+	value := float64(size)
+	unit := 0
+	for value >= base && unit < len(units)-1 {
+		value /= base
+		unit++
+	}
+	return fmt.Sprintf("%d%s", int64(math.Round(value)), units[unit])
+}
+
+// TypedConfirmMatches reports whether input is token, ignoring case and spaces
+func TypedConfirmMatches(input, token string) bool {
+	normalize := func(s string) string {
+		return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	}
+	return normalize(input) != "" && normalize(input) == normalize(token)
+}
+
+// PromptTypedConfirm explains why a cleanup needs a typed confirmation, asks
+// the user to type token and reports whether they did
+func PromptTypedConfirm(in io.Reader, out io.Writer, token string, reasons []string) bool {
+	fmt.Fprintln(out)
+	for _, reason := range reasons {
+		fmt.Fprintf(out, "  %s\n", reason)
+	}
+	fmt.Fprint(out, i18n.T("Type %s to confirm: ", token))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	return TypedConfirmMatches(line, token)
+}
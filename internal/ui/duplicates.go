@@ -0,0 +1,337 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+)
+
+// DuplicateGroup is a set of files with identical content
+type DuplicateGroup struct {
+	Size  int64      // size of one copy
+	Files []FileInfo // newest first
+}
+
+// DuplicatesView shows each duplicate group side by side and lets the user
+// choose which copies to keep. At least one copy per group is always kept.
+type DuplicatesView struct {
+	groups []DuplicateGroup
+	remove [][]bool // per group and file, true if the copy will be deleted
+
+	group  int // cursor group
+	file   int // cursor file within the group
+	offset int // first line shown
+	height int
+
+	home        string
+	projectDirs []string
+
+	status   string
+	keymap   *Keymap
+	showHelp bool
+}
+
+// duplicatesHelp lists the duplicates view bindings for the help overlay
+var duplicatesHelp = []helpSection{{
+	title: "Duplicate files",
+	actions: []Action{ActionUp, ActionDown, ActionPageUp, ActionPageDown, ActionTop, ActionBottom,
+		ActionToggle, ActionKeepThis, ActionKeepNew, ActionKeepAll, ActionConfirm, ActionQuit, ActionHelp},
+}}
+
+// NewDuplicatesView creates a view over groups that keeps the newest copy of each
+func NewDuplicatesView(groups []DuplicateGroup) *DuplicatesView {
+	home, _ := os.UserHomeDir()
+	v := &DuplicatesView{
+		groups: groups,
+		remove: make([][]bool, len(groups)),
+		height: 20,
+		home:   home,
+		keymap: DefaultKeymap(),
+	}
+	for g := range groups {
+		v.remove[g] = make([]bool, len(groups[g].Files))
+		v.keepNewest(g)
+	}
+	return v
+}
+
+// SetKeymap replaces the key bindings
+func (v *DuplicatesView) SetKeymap(km *Keymap) {
+	v.keymap = km
+}
+
+// SetProjectDirs sets the directories whose copies are labeled "project" (~ is expanded)
+func (v *DuplicatesView) SetProjectDirs(dirs []string) {
+	v.projectDirs = v.projectDirs[:0]
+	for _, dir := range dirs {
+		if strings.HasPrefix(dir, "~/") {
+			dir = filepath.Join(v.home, dir[2:])
+		}
+		v.projectDirs = append(v.projectDirs, dir)
+	}
+}
+
+// Remove returns the copies marked for deletion
+func (v *DuplicatesView) Remove() []FileInfo {
+	files := []FileInfo{}
+	for g, group := range v.groups {
+		for i, file := range group.Files {
+			if v.remove[g][i] {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// keepNewest keeps only the first (newest) copy of group g
+func (v *DuplicatesView) keepNewest(g int) {
+	v.keepOnly(g, 0)
+}
+
+// keepOnly keeps only copy i of group g
+func (v *DuplicatesView) keepOnly(g, i int) {
+	for j := range v.remove[g] {
+		v.remove[g][j] = j != i
+	}
+}
+
+// HandleKey applies a keypress. It returns true once the user has confirmed or cancelled.
+func (v *DuplicatesView) HandleKey(key Key) (finished, cancelled bool) {
+	v.status = ""
+
+	// Any key closes the help overlay
+	if v.showHelp {
+		v.showHelp = false
+		return false, false
+	}
+
+	if len(v.groups) == 0 {
+		switch v.keymap.Action(key) {
+		case ActionConfirm:
+			return true, false
+		case ActionQuit:
+			return true, true
+		}
+		return false, false
+	}
+
+	switch v.keymap.Action(key) {
+	case ActionUp:
+		if v.file > 0 {
+			v.file--
+		} else if v.group > 0 {
+			v.group--
+			v.file = len(v.groups[v.group].Files) - 1
+		}
+	case ActionDown:
+		if v.file < len(v.groups[v.group].Files)-1 {
+			v.file++
+		} else if v.group < len(v.groups)-1 {
+			v.group++
+			v.file = 0
+		}
+	case ActionPageUp:
+		// Page keys jump a group at a time
+		if v.group > 0 {
+			v.group--
+		}
+		v.file = 0
+	case ActionPageDown:
+		if v.group < len(v.groups)-1 {
+			v.group++
+		}
+		v.file = 0
+	case ActionTop:
+		v.group, v.file = 0, 0
+	case ActionBottom:
+		v.group = len(v.groups) - 1
+		v.file = len(v.groups[v.group].Files) - 1
+	case ActionToggle:
+		remove := v.remove[v.group]
+		if !remove[v.file] {
+			kept := 0
+			for _, r := range remove {
+				if !r {
+					kept++
+				}
+			}
+			if kept == 1 {
+				v.status = i18n.T("At least one copy must be kept")
+				break
+			}
+		}
+		remove[v.file] = !remove[v.file]
+	case ActionKeepThis:
+		v.keepOnly(v.group, v.file)
+	case ActionKeepNew:
+		v.keepNewest(v.group)
+	case ActionKeepAll:
+		for g := range v.groups {
+			v.keepNewest(g)
+		}
+		v.status = i18n.T("Keeping the newest copy in every group")
+	case ActionHelp:
+		v.showHelp = true
+	case ActionConfirm:
+		return true, false
+	case ActionQuit:
+		return true, true
+	}
+
+	return false, false
+}
+
+// location labels where a copy lives, so it's clear which one is in a
+// project and which is a stray download
+func (v *DuplicatesView) location(path string) string {
+	for _, dir := range v.projectDirs {
+		if strings.HasPrefix(path, dir+"/") {
+			return "project"
+		}
+	}
+	for _, dir := range []string{"Downloads", "Desktop", "Documents"} {
+		if v.home != "" && strings.HasPrefix(path, filepath.Join(v.home, dir)+"/") {
+			return dir
+		}
+	}
+	return ""
+}
+
+// View renders the duplicate groups as lines of text for the given terminal size
+func (v *DuplicatesView) View(width, height int) []string {
+	if v.showHelp {
+		return v.keymap.helpView(duplicatesHelp)
+	}
+
+	var reclaim, removing int64
+	var removeCount int
+	for g, group := range v.groups {
+		reclaim += group.Size * int64(len(group.Files)-1)
+		for _, r := range v.remove[g] {
+			if r {
+				removeCount++
+				removing += group.Size
+			}
+		}
+	}
+
+	lines := []string{
+		" " + styles.Title.Render(i18n.T("Duplicate files: %d groups, up to %s reclaimable", len(v.groups), formatBytes(reclaim))),
+		"",
+	}
+
+	// Render every group, remembering which line the cursor is on
+	var body []string
+	cursorLine := 0
+	for g, group := range v.groups {
+		header := i18n.T(" Group %d of %d: %d copies of %s", g+1, len(v.groups), len(group.Files), formatBytes(group.Size))
+		body = append(body, styles.Dim.Render(header))
+		for i, file := range group.Files {
+			current := g == v.group && i == v.file
+			if current {
+				cursorLine = len(body)
+			}
+			body = append(body, v.renderCopy(file, v.remove[g][i], current, width))
+		}
+		body = append(body, "")
+	}
+
+	// Title, blank, list, blank, status and key hints
+	v.height = height - 5
+	if v.height < 1 {
+		v.height = 1
+	}
+	if cursorLine < v.offset+1 {
+		// Keep the group header in view when moving up
+		v.offset = cursorLine - 1
+	}
+	if cursorLine >= v.offset+v.height {
+		v.offset = cursorLine - v.height + 1
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+
+	end := v.offset + v.height
+	if end > len(body) {
+		end = len(body)
+	}
+	lines = append(lines, body[v.offset:end]...)
+	for i := end - v.offset; i < v.height; i++ {
+		lines = append(lines, "")
+	}
+
+	statusLine := i18n.T(" Removing %d copies, %s", removeCount, formatBytes(removing))
+	if v.status != "" {
+		statusLine += "  " + styles.Dim.Render(v.status)
+	}
+	lines = append(lines, "", statusLine)
+	lines = append(lines, v.keymap.hintLine(
+		keyHint{ActionToggle, "keep/delete"},
+		keyHint{ActionKeepThis, "keep this"},
+		keyHint{ActionKeepNew, "keep newest"},
+		keyHint{ActionKeepAll, "newest everywhere"},
+		keyHint{ActionConfirm, "confirm"},
+		keyHint{ActionQuit, "quit"},
+		keyHint{ActionHelp, "help"},
+	))
+
+	return lines
+}
+
+// renderCopy renders one copy in a group: keep/delete, mtime, location and path
+func (v *DuplicatesView) renderCopy(file FileInfo, remove, current bool, width int) string {
+	cursor := "  "
+	if current {
+		cursor = styles.Cursor.Render("> ")
+	}
+	mark := styles.Selected.Render("[keep]")
+	if remove {
+		mark = styles.Error.Render("[del ]")
+	}
+
+	mtime := file.ModTime.Format("2006-01-02 15:04")
+	where := fitWidth(v.location(file.Path), 9)
+	prefix := fmt.Sprintf(" %s%s  %s  %s  ", cursor, mark, mtime, styles.Dim.Render(where))
+
+	// Trim the start of long paths so the file name stays visible
+	path := file.Path
+	avail := width - utf8.RuneCountInString(fmt.Sprintf(" > [keep]  %s  %s  ", mtime, where))
+	if avail > 3 && utf8.RuneCountInString(path) > avail {
+		runes := []rune(path)
+		path = "..." + string(runes[len(runes)-(avail-3):])
+	}
+	if current {
+		path = styles.Cursor.Render(path)
+	}
+
+	return prefix + path
+}
+
+// Run displays the view until the user confirms or cancels. It returns the
+// copies to delete, or nil if the user cancelled.
+func (v *DuplicatesView) Run() ([]FileInfo, error) {
+	t, err := OpenTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	for {
+		width, height := t.Size()
+		t.Draw(v.View(width, height))
+
+		key := <-t.Keys()
+		if finished, cancelled := v.HandleKey(key); finished {
+			if cancelled {
+				return nil, nil
+			}
+			return v.Remove(), nil
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+)
+
+// Action is something a keypress does in an interactive view
+type Action string
+
+const (
+	ActionNone      Action = ""
+	ActionUp        Action = "up"
+	ActionDown      Action = "down"
+	ActionPageUp    Action = "page_up"
+	ActionPageDown  Action = "page_down"
+	ActionTop       Action = "top"
+	ActionBottom    Action = "bottom"
+	ActionToggle    Action = "toggle"
+	ActionToggleAll Action = "toggle_all"
+	ActionFilter    Action = "filter"
+	ActionPathMode  Action = "path_mode"
+	ActionReveal    Action = "reveal"
+	ActionShell     Action = "shell"
+	ActionExpand    Action = "expand"
+	ActionTree      Action = "tree"
+	ActionKeepNew   Action = "keep_newest"
+	ActionKeepAll   Action = "keep_newest_all"
+	ActionKeepThis  Action = "keep_this"
+	ActionIgnore    Action = "ignore"
+	ActionSort      Action = "sort"
+	ActionReverse   Action = "reverse"
+	ActionWiden     Action = "widen"
+	ActionNarrow    Action = "narrow"
+	ActionRestore   Action = "restore"
+	ActionRetry     Action = "retry"
+	ActionEscalate  Action = "escalate"
+	ActionUndo      Action = "undo"
+	ActionConfirm   Action = "confirm"
+	ActionQuit      Action = "quit"
+	ActionHelp      Action = "help"
+)
+
+// actionDescriptions are shown in the help overlay
+var actionDescriptions = map[Action]string{
+	ActionUp:        "Move up",
+	ActionDown:      "Move down",
+	ActionPageUp:    "Page up",
+	ActionPageDown:  "Page down",
+	ActionTop:       "Go to top",
+	ActionBottom:    "Go to bottom",
+	ActionToggle:    "Select / deselect",
+	ActionToggleAll: "Select / deselect all",
+	ActionFilter:    "Filter (prefix re: for regex)",
+	ActionPathMode:  "Match name / full path",
+	ActionReveal:    "Reveal in file manager",
+	ActionShell:     "Open shell in directory",
+	ActionExpand:    "Expand / collapse",
+	ActionTree:      "Group by directory (tree view)",
+	ActionKeepNew:   "Keep only the newest copy in this group",
+	ActionKeepAll:   "Keep only the newest copy in every group",
+	ActionKeepThis:  "Keep only this copy",
+	ActionIgnore:    "Never flag these files again",
+	ActionSort:      "Sort by size / age / path / category",
+	ActionReverse:   "Reverse sort order",
+	ActionWiden:     "Widen category column",
+	ActionNarrow:    "Narrow category column",
+	ActionRestore:   "Restore last session's selection",
+	ActionRetry:     "Retry group",
+	ActionEscalate:  "Retry group with sudo",
+	ActionUndo:      "Undo cleanup (quarantine mode)",
+	ActionConfirm:   "Confirm",
+	ActionQuit:      "Quit / cancel",
+	ActionHelp:      "Toggle this help",
+}
+
+// baseBindings apply in every preset
+var baseBindings = map[Key]Action{
+	KeyUp:       ActionUp,
+	KeyDown:     ActionDown,
+	KeyPageUp:   ActionPageUp,
+	KeyPageDown: ActionPageDown,
+	KeyHome:     ActionTop,
+	KeyEnd:      ActionBottom,
+	KeyLeft:     ActionExpand,
+	KeyRight:    ActionExpand,
+	KeySpace:    ActionToggle,
+	KeyEnter:    ActionConfirm,
+	KeyTab:      ActionPathMode,
+	KeyEscape:   ActionQuit,
+	KeyCtrlC:    ActionQuit,
+	"?":         ActionHelp,
+}
+
+// presetBindings are added on top of baseBindings for each preset
+var presetBindings = map[string]map[Key]Action{
+	"default": {
+		"k": ActionUp, "j": ActionDown, "g": ActionTop, "G": ActionBottom,
+		"x": ActionToggle, "a": ActionToggleAll, "/": ActionFilter,
+		"o": ActionReveal, "O": ActionShell, "r": ActionRetry, "s": ActionEscalate,
+		"u": ActionUndo, "S": ActionSort, "R": ActionReverse, "+": ActionWiden, "-": ActionNarrow,
+		"p": ActionRestore, "t": ActionTree, "n": ActionKeepNew, "N": ActionKeepAll, "c": ActionKeepThis,
+		"i": ActionIgnore, "q": ActionQuit,
+	},
+	"vim": {
+		"k": ActionUp, "j": ActionDown, "g": ActionTop, "G": ActionBottom,
+		"ctrl+u": ActionPageUp, "ctrl+d": ActionPageDown, "ctrl+b": ActionPageUp, "ctrl+f": ActionPageDown,
+		"x": ActionToggle, "a": ActionToggleAll, "/": ActionFilter,
+		"o": ActionReveal, "O": ActionShell, "l": ActionExpand, "h": ActionExpand,
+		"r": ActionRetry, "s": ActionEscalate, "u": ActionUndo, "S": ActionSort, "R": ActionReverse,
+		">": ActionWiden, "<": ActionNarrow, "p": ActionRestore, "t": ActionTree, "n": ActionKeepNew,
+		"N": ActionKeepAll, "c": ActionKeepThis, "i": ActionIgnore, "q": ActionQuit,
+	},
+	"emacs": {
+		"ctrl+p": ActionUp, "ctrl+n": ActionDown, "alt+v": ActionPageUp, "ctrl+v": ActionPageDown,
+		"alt+<": ActionTop, "alt+>": ActionBottom, "ctrl+a": ActionTop, "ctrl+e": ActionBottom,
+		"ctrl+t": ActionToggle, "alt+a": ActionToggleAll, "ctrl+s": ActionFilter,
+		"ctrl+o": ActionReveal, "alt+o": ActionShell, "ctrl+r": ActionRetry, "alt+s": ActionEscalate,
+		"ctrl+_": ActionUndo, "alt+t": ActionSort, "alt+r": ActionReverse, "alt+=": ActionWiden,
+		"alt+-": ActionNarrow, "alt+p": ActionRestore, "alt+g": ActionTree, "alt+n": ActionKeepNew,
+		"alt+N": ActionKeepAll, "alt+k": ActionKeepThis, "alt+i": ActionIgnore, "ctrl+g": ActionQuit,
+	},
+}
+
+// Keymap maps keys to actions
+type Keymap struct {
+	bindings map[Key]Action
+}
+
+// KeymapPresets returns the names of the built-in presets
+func KeymapPresets() []string {
+	names := make([]string, 0, len(presetBindings))
+	for name := range presetBindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultKeymap returns the default bindings
+func DefaultKeymap() *Keymap {
+	km, _ := NewKeymap("default", nil)
+	return km
+}
+
+// NewKeymap builds a keymap from a preset plus per-action overrides. An
+// override replaces every key the preset bound to that action.
+func NewKeymap(preset string, overrides map[string][]string) (*Keymap, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	extra, ok := presetBindings[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown keybinding preset %q (available: %s)", preset, strings.Join(KeymapPresets(), ", "))
+	}
+
+	km := &Keymap{bindings: make(map[Key]Action)}
+	for key, action := range baseBindings {
+		km.bindings[key] = action
+	}
+	for key, action := range extra {
+		km.bindings[key] = action
+	}
+
+	for name, keys := range overrides {
+		action := Action(name)
+		if _, ok := actionDescriptions[action]; !ok {
+			return nil, fmt.Errorf("unknown keybinding action %q", name)
+		}
+		for key, bound := range km.bindings {
+			if bound == action {
+				delete(km.bindings, key)
+			}
+		}
+		for _, key := range keys {
+			km.bindings[Key(key)] = action
+		}
+	}
+
+	return km, nil
+}
+
+// Action returns the action bound to key
+func (km *Keymap) Action(key Key) Action {
+	if km == nil {
+		km = DefaultKeymap()
+	}
+	return km.bindings[key]
+}
+
+// Keys returns the keys bound to action, named keys first
+func (km *Keymap) Keys(action Action) []Key {
+	if km == nil {
+		km = DefaultKeymap()
+	}
+
+	var keys []Key
+	for key, bound := range km.bindings {
+		if bound == action {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Hint returns a short "key label" string for the key hint line
+func (km *Keymap) Hint(action Action, label string) string {
+	keys := km.Keys(action)
+	if len(keys) == 0 {
+		return ""
+	}
+	// Prefer the obvious keys, then a single character since it's what people type
+	key := keys[0]
+	for _, k := range keys {
+		if k == KeySpace || k == KeyEnter || k == KeyTab {
+			return string(k) + " " + label
+		}
+		if len(k) == 1 && len(key) != 1 {
+			key = k
+		}
+	}
+	return string(key) + " " + label
+}
+
+// keyHint is one entry of a view's key hint line
+type keyHint struct {
+	action Action
+	label  string
+}
+
+// hintLine joins hints for the given actions, skipping unbound ones
+func (km *Keymap) hintLine(hints ...keyHint) string {
+	parts := []string{"↑/↓ move"}
+	for _, h := range hints {
+		if hint := km.Hint(h.action, i18n.T(h.label)); hint != "" {
+			parts = append(parts, hint)
+		}
+	}
+	return " " + styles.Dim.Render(strings.Join(parts, "  "))
+}
+
+// helpView renders the help overlay for the given sections
+func (km *Keymap) helpView(sections []helpSection) []string {
+	lines := []string{" " + styles.Title.Render(i18n.T("Keyboard shortcuts")), ""}
+
+	for _, section := range sections {
+		lines = append(lines, " "+styles.Selected.Render(i18n.T(section.title)))
+		for _, action := range section.actions {
+			keys := km.Keys(action)
+			if len(keys) == 0 {
+				continue
+			}
+			names := make([]string, len(keys))
+			for i, k := range keys {
+				names[i] = string(k)
+			}
+			lines = append(lines, fmt.Sprintf("   %-22s %s", strings.Join(names, ", "), i18n.T(actionDescriptions[action])))
+		}
+		for _, fixed := range section.fixed {
+			lines = append(lines, fmt.Sprintf("   %-22s %s", fixed[0], i18n.T(fixed[1])))
+		}
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, " "+styles.Dim.Render(i18n.T("Press any key to close")))
+	return lines
+}
+
+// helpSection is one mode's group of bindings in the help overlay
+type helpSection struct {
+	title   string
+	actions []Action
+	fixed   [][2]string // keys that can't be remapped, e.g. while typing a filter
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fenilsonani/system-cleanup/internal/paths"
+)
+
+// Sort fields for the file browser
+const (
+	SortBySize     = "size"
+	SortByAge      = "age"
+	SortByPath     = "path"
+	SortByCategory = "category"
+)
+
+// sortFields is the order the sort key cycles through
+var sortFields = []string{SortBySize, SortByAge, SortByPath, SortByCategory}
+
+// Selection memory modes for the category selector
+const (
+	SelectionOff    = "off"    // don't remember previous selections
+	SelectionRemind = "remind" // mark the previous selection, apply it on request
+	SelectionApply  = "apply"  // pre-select the previous selection
+)
+
+// Prefs is interactive view state kept between sessions
+type Prefs struct {
+	SortField          string         `json:"sort_field"`
+	SortDesc           bool           `json:"sort_desc"`
+	ColumnWidths       map[string]int `json:"column_widths"`
+	LastFilter         string         `json:"last_filter"`
+	MatchFullPath      bool           `json:"match_full_path"`
+	TreeView           bool           `json:"tree_view"`
+	SelectedCategories []string       `json:"selected_categories"`
+}
+
+// DefaultPrefs returns the preferences used when no state file exists
+func DefaultPrefs() *Prefs {
+	return &Prefs{
+		SortField:    SortBySize,
+		SortDesc:     true,
+		ColumnWidths: map[string]int{"category": 14},
+	}
+}
+
+// PrefsPath resolves the state file location, expanding ~. An empty path
+// means the default location.
+func PrefsPath(path string) (string, error) {
+	if path != "" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	if path == "" {
+		stateDir, err := paths.StateDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(stateDir, "ui-state.json"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
+
+// LoadPrefs reads preferences from path, falling back to defaults if the file
+// doesn't exist. Unknown or invalid values are replaced by their defaults.
+func LoadPrefs(path string) (*Prefs, error) {
+	prefs := DefaultPrefs()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return prefs, nil
+		}
+		return prefs, fmt.Errorf("failed to read ui state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, prefs); err != nil {
+		return DefaultPrefs(), fmt.Errorf("failed to parse ui state: %w", err)
+	}
+
+	valid := false
+	for _, field := range sortFields {
+		if prefs.SortField == field {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		prefs.SortField = SortBySize
+	}
+	if prefs.ColumnWidths == nil {
+		prefs.ColumnWidths = DefaultPrefs().ColumnWidths
+	}
+
+	return prefs, nil
+}
+
+// Save writes preferences to path
+func (p *Prefs) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create ui state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ui state: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ui state: %w", err)
+	}
+	return nil
+}
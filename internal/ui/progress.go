@@ -7,21 +7,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/fenilsonani/system-cleanup/internal/progress"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
 	"golang.org/x/term"
 )
 
 // LiveProgress handles live terminal progress display
 type LiveProgress struct {
-	mu           sync.Mutex
-	currentPath  string
-	filesFound   int
-	totalSize    int64
-	category     string
-	startTime    time.Time
-	lastUpdate   time.Time
-	termWidth    int
-	enabled      bool
-	statusLines  int
+	mu          sync.Mutex
+	event       scanner.ProgressEvent
+	startTime   time.Time
+	lastUpdate  time.Time
+	termWidth   int
+	enabled     bool
+	statusLines int
 }
 
 // NewLiveProgress creates a new live progress display
@@ -51,7 +51,7 @@ func (lp *LiveProgress) Start() {
 }
 
 // Update updates the progress display
-func (lp *LiveProgress) Update(category, currentPath string, filesFound int, totalSize int64) {
+func (lp *LiveProgress) Update(event scanner.ProgressEvent) {
 	lp.mu.Lock()
 	defer lp.mu.Unlock()
 
@@ -66,10 +66,12 @@ func (lp *LiveProgress) Update(category, currentPath string, filesFound int, tot
 	}
 	lp.lastUpdate = now
 
-	lp.category = category
-	lp.currentPath = currentPath
-	lp.filesFound = filesFound
-	lp.totalSize = totalSize
+	// Finishing a category doesn't say where the scan is now
+	path := lp.event.CurrentPath
+	lp.event = event
+	if event.CurrentPath == "" {
+		lp.event.CurrentPath = path
+	}
 
 	lp.render()
 }
@@ -83,15 +85,20 @@ func (lp *LiveProgress) render() {
 	width := lp.termWidth - 2
 
 	// Line 1: Category and stats
+	e := lp.event
 	elapsed := time.Since(lp.startTime).Round(time.Second)
-	line1 := fmt.Sprintf("📂 Scanning: %-20s | Found: %d files | Size: %s | Time: %s",
-		lp.category, lp.filesFound, formatBytes(lp.totalSize), elapsed)
+	phase := "📂 Scanning"
+	if e.Phase == progress.PhaseSizing {
+		phase = "📏 Sizing  "
+	}
+	line1 := fmt.Sprintf("%s: %-20s | Found: %d files | Size: %s | Time: %s",
+		phase, e.Category, e.FilesFound, formatBytes(e.TotalSize), elapsed)
 	fmt.Printf("\033[K%s\n", truncate(line1, width))
 
 	// Line 2: Current path with animation
 	spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	spinIdx := int(time.Now().UnixMilli()/100) % len(spinner)
-	path := lp.currentPath
+	path := e.CurrentPath
 	if len(path) > width-10 {
 		// Show last part of path
 		path = "..." + path[len(path)-(width-13):]
@@ -99,9 +106,8 @@ func (lp *LiveProgress) render() {
 	line2 := fmt.Sprintf("%s %s", spinner[spinIdx], path)
 	fmt.Printf("\033[K%s\n", truncate(line2, width))
 
-	// Line 3: Progress bar
-	line3 := strings.Repeat("─", width)
-	fmt.Printf("\033[K%s", line3)
+	// Line 3: Categories finished, rate and time left
+	fmt.Printf("\033[K%s", truncate(progressLine(e, width), width))
 
 	// Restore cursor position
 	fmt.Print("\033[u")
@@ -120,6 +126,22 @@ func (lp *LiveProgress) Finish() {
 	fmt.Print("\033[K\n")
 }
 
+// progressLine draws a bar of the categories scanned so far, followed by the
+// rate and the time left if it's known
+func progressLine(e scanner.ProgressEvent, width int) string {
+	stats := fmt.Sprintf(" %d/%d categories | %.0f files/s", e.CategoriesDone, len(e.Categories), e.FilesPerSecond)
+	if e.ETA > 0 {
+		stats += fmt.Sprintf(" | about %s left", e.ETA.Round(time.Second))
+	}
+
+	barWidth := width - len([]rune(stats))
+	if barWidth < 10 || len(e.Categories) == 0 {
+		return strings.Repeat("─", width)
+	}
+	filled := barWidth * e.CategoriesDone / len(e.Categories)
+	return strings.Repeat("━", filled) + strings.Repeat("─", barWidth-filled) + stats
+}
+
 // SetEnabled enables or disables live progress
 func (lp *LiveProgress) SetEnabled(enabled bool) {
 	lp.mu.Lock()
@@ -137,16 +159,15 @@ func truncate(s string, width int) string {
 
 // formatBytes formats bytes to human readable
 func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	return utils.FormatBytesN(bytes, 1)
+}
+
+// formatSignedBytes is formatBytes for sizes that may be negative
+func formatSignedBytes(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatBytes(-bytes)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return formatBytes(bytes)
 }
 
 // PrintDetailedTree prints a detailed tree view of scan results
@@ -212,7 +233,11 @@ func PrintDetailedTree(files []FileInfo, totalSize int64) {
 						fileConnector = "│   ╰"
 					}
 				}
-				fmt.Printf("%s── %s (%s)\n", fileConnector, getFileName(f.Path), formatBytes(f.Size))
+				fmt.Printf("%s── %s (%s)", fileConnector, getFileName(f.Path), formatBytes(f.Size))
+				if f.Reason != "" {
+					fmt.Printf(" · %s", f.Reason)
+				}
+				fmt.Println()
 			}
 
 			if fileCount > maxFiles {
@@ -235,6 +260,10 @@ type FileInfo struct {
 	Size     int64
 	Category string
 	Reason   string
+	ModTime  time.Time
+	Risk     string
+	Owner    string
+	Access   string
 }
 
 // categoryName returns a friendly name for a category
@@ -248,6 +277,21 @@ func categoryName(cat string) string {
 		"logs":            "📜 Log Files",
 		"large_files":     "📀 Large Files",
 		"old_files":       "📅 Old Files",
+		"duplicates":      "📑 Duplicate Files",
+		"wsl":             "🐧 WSL Leftovers",
+		"empty_dirs":      "📂 Empty Folders",
+		"vm_images":       "💽 Virtual Machines",
+		"cloud_cli":       "☁️  Cloud CLI Caches",
+		"desktop_caches":  "🔤 Desktop Caches",
+		"compiler_caches": "🛠️  Compiler Caches",
+		"latex":           "📝 LaTeX Files",
+		"r_packages":      "📊 R Packages",
+		"julia":           "🔬 Julia Depot",
+		"stale_repos":     "🗄️  Stale Git Clones",
+		"project_caches":  "🧪 Project Caches",
+		"vcs_ignored":     "🙈 Ignored by Git",
+		"screenshots":     "📸 Screenshots",
+		"downloads":       "📥 Downloads",
 		"homebrew_cache":  "🍺 Homebrew Cache",
 		"npm_cache":       "📦 NPM Cache",
 		"go_cache":        "🐹 Go Cache",
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// RevealInFileManager shows path in Finder on macOS, or opens its parent
+// directory in the default file manager on Linux
+func RevealInFileManager(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "-R", path)
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return fmt.Errorf("xdg-open not found")
+		}
+		cmd = exec.Command("xdg-open", filepath.Dir(path))
+	}
+
+	// Some file managers keep xdg-open attached; don't block the UI on it
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open file manager: %w", err)
+	}
+	go cmd.Wait()
+
+	return nil
+}
+
+// ShellDir returns the directory to open a shell in for path: the path itself
+// if it is a directory, otherwise its parent
+func ShellDir(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	return filepath.Dir(path)
+}
+
+// OpenShell runs the user's shell in dir and waits for it to exit.
+// The terminal must not be in raw mode while the shell runs.
+func OpenShell(dir string) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	fmt.Printf("Opening shell in %s (exit to return to tidyup)\n", dir)
+
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// Non-zero exit from the last command in the shell is not our problem
+			return nil
+		}
+		return fmt.Errorf("failed to start shell: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/i18n"
+	"github.com/fenilsonani/system-cleanup/internal/scanner"
+)
+
+// CategoryItem is a row in the category selection screen
+type CategoryItem struct {
+	Name     string
+	Count    int
+	Size     int64
+	Selected bool
+	Done     bool // Finished scanning
+}
+
+// CategorySelector lets the user pick categories while the scan is still running.
+// Counts and sizes are fed in from the scanner progress callback via Update.
+type CategorySelector struct {
+	mu        sync.Mutex
+	items     []*CategoryItem
+	cursor    int
+	scanning  bool
+	confirmed bool
+	startTime time.Time
+	scanTime  time.Duration
+	done      chan struct{}
+	keymap    *Keymap
+	showHelp  bool
+	previous  map[string]bool // categories selected last session, shown as a hint
+	eta       time.Time       // When the scan is expected to finish; zero if unknown
+}
+
+// NewCategorySelector creates a selector with all given categories pre-selected
+func NewCategorySelector(categories []string) *CategorySelector {
+	s := &CategorySelector{
+		scanning:  true,
+		startTime: time.Now(),
+		done:      make(chan struct{}),
+		keymap:    DefaultKeymap(),
+	}
+	for _, cat := range categories {
+		s.items = append(s.items, &CategoryItem{Name: cat, Selected: true})
+	}
+	return s
+}
+
+// Update sets the live count and size for a category
+func (s *CategorySelector) Update(category string, count int, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.items {
+		if item.Name == category {
+			item.Count = count
+			item.Size = size
+			return
+		}
+	}
+
+	// Category we weren't told about up front - show it anyway
+	s.items = append(s.items, &CategoryItem{Name: category, Count: count, Size: size, Selected: true})
+}
+
+// SetProgress updates every category's count, size and whether it's
+// finished from a scanner progress event
+func (s *CategorySelector) SetProgress(event scanner.ProgressEvent) {
+	for _, cat := range event.Categories {
+		s.Update(cat.Name, cat.Count, cat.Size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cat := range event.Categories {
+		for _, item := range s.items {
+			if item.Name == cat.Name {
+				item.Done = cat.Done
+			}
+		}
+	}
+	s.eta = time.Time{}
+	if event.ETA > 0 {
+		s.eta = time.Now().Add(event.ETA)
+	}
+}
+
+// SetPreviousSelection remembers the categories selected in the last session.
+// In SelectionApply mode they replace the default selection; in
+// SelectionRemind mode they're marked and the restore key applies them.
+func (s *CategorySelector) SetPreviousSelection(categories []string, mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(categories) == 0 || mode == SelectionOff {
+		s.previous = nil
+		return
+	}
+
+	s.previous = make(map[string]bool, len(categories))
+	for _, cat := range categories {
+		s.previous[cat] = true
+	}
+	if mode == SelectionApply {
+		s.restorePrevious()
+	}
+}
+
+// restorePrevious selects exactly the previous session's categories
+func (s *CategorySelector) restorePrevious() {
+	for _, item := range s.items {
+		item.Selected = s.previous[item.Name]
+	}
+}
+
+// SetKeymap replaces the key bindings
+func (s *CategorySelector) SetKeymap(km *Keymap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keymap = km
+}
+
+// Finish marks the scan as complete
+func (s *CategorySelector) Finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scanning {
+		s.scanning = false
+		s.scanTime = time.Since(s.startTime)
+		close(s.done)
+	}
+}
+
+// Selected returns the names of the selected categories that found something
+func (s *CategorySelector) Selected() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var selected []string
+	for _, item := range s.items {
+		if item.Selected && (item.Count > 0 || s.scanning) {
+			selected = append(selected, item.Name)
+		}
+	}
+	return selected
+}
+
+// HandleKey applies a keypress. It returns true once the user has confirmed
+// (and the scan has finished) or cancelled.
+func (s *CategorySelector) HandleKey(key Key) (finished, cancelled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Any key closes the help overlay
+	if s.showHelp {
+		s.showHelp = false
+		return false, false
+	}
+
+	switch s.keymap.Action(key) {
+	case ActionUp:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case ActionDown:
+		if s.cursor < len(s.items)-1 {
+			s.cursor++
+		}
+	case ActionTop, ActionPageUp:
+		s.cursor = 0
+	case ActionBottom, ActionPageDown:
+		s.cursor = len(s.items) - 1
+	case ActionToggle:
+		if s.cursor < len(s.items) {
+			s.items[s.cursor].Selected = !s.items[s.cursor].Selected
+		}
+	case ActionToggleAll:
+		// Select all unless everything is already selected
+		allSelected := true
+		for _, item := range s.items {
+			if !item.Selected {
+				allSelected = false
+				break
+			}
+		}
+		for _, item := range s.items {
+			item.Selected = !allSelected
+		}
+	case ActionRestore:
+		if s.previous != nil {
+			s.restorePrevious()
+		}
+	case ActionHelp:
+		s.showHelp = true
+	case ActionConfirm:
+		s.confirmed = true
+		return !s.scanning, false
+	case ActionQuit:
+		return true, true
+	}
+
+	return s.confirmed && !s.scanning, false
+}
+
+// View renders the selector as lines of text
+func (s *CategorySelector) View(width int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.showHelp {
+		return s.keymap.helpView([]helpSection{{
+			title: "Category selection",
+			actions: []Action{ActionUp, ActionDown, ActionTop, ActionBottom, ActionToggle,
+				ActionToggleAll, ActionRestore, ActionConfirm, ActionQuit, ActionHelp},
+		}})
+	}
+
+	var lines []string
+
+	if s.scanning {
+		spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+		spinIdx := int(time.Now().UnixMilli()/100) % len(spinner)
+		elapsed := time.Since(s.startTime).Round(time.Second)
+		if left := time.Until(s.eta); !s.eta.IsZero() && left > 0 {
+			lines = append(lines, " "+styles.Title.Render(i18n.T("%s Select categories to clean (scanning... %s, about %s left)", spinner[spinIdx], elapsed, left.Round(time.Second))))
+		} else {
+			lines = append(lines, " "+styles.Title.Render(i18n.T("%s Select categories to clean (scanning... %s)", spinner[spinIdx], elapsed)))
+		}
+	} else {
+		lines = append(lines, " "+styles.Title.Render(i18n.T("Select categories to clean (scan finished in %s)", s.scanTime.Round(time.Millisecond))))
+	}
+	lines = append(lines, "")
+
+	var selectedCount int
+	var selectedSize int64
+	for i, item := range s.items {
+		name := fmt.Sprintf("%-28s", categoryName(item.Name))
+		cursor := "  "
+		if i == s.cursor {
+			cursor = styles.Cursor.Render("> ")
+			name = styles.Cursor.Render(name)
+		}
+		check := "[ ]"
+		if item.Selected {
+			check = styles.Selected.Render("[x]")
+			selectedCount += item.Count
+			selectedSize += item.Size
+		}
+
+		status := fmt.Sprintf("%6d items  %10s", item.Count, formatBytes(item.Size))
+		if !s.scanning && item.Count == 0 {
+			status = styles.Dim.Render(i18n.T("       nothing found"))
+		} else if s.scanning && item.Done {
+			status += styles.Dim.Render(" ✓")
+		}
+		if s.previous[item.Name] {
+			status += styles.Dim.Render(i18n.T("  • last time"))
+		}
+
+		lines = append(lines, fmt.Sprintf(" %s%s %s %s", cursor, check, name, status))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, i18n.T(" Selected: %d items, %s", selectedCount, formatBytes(selectedSize)))
+
+	if s.confirmed && s.scanning {
+		lines = append(lines, " "+styles.Dim.Render(i18n.T("Waiting for scan to finish...")))
+	}
+
+	hints := []keyHint{{ActionToggle, "toggle"}, {ActionToggleAll, "all"}}
+	if s.previous != nil {
+		hints = append(hints, keyHint{ActionRestore, "last time"})
+	}
+	hints = append(hints,
+		keyHint{ActionConfirm, "confirm"},
+		keyHint{ActionQuit, "quit"},
+		keyHint{ActionHelp, "help"},
+	)
+
+	lines = append(lines, "")
+	lines = append(lines, s.keymap.hintLine(hints...))
+
+	return lines
+}
+
+// Run displays the selector until the user confirms or cancels. It returns
+// the selected categories, or nil if the user cancelled.
+func (s *CategorySelector) Run() ([]string, error) {
+	t, err := OpenTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		width, _ := t.Size()
+		t.Draw(s.View(width))
+
+		select {
+		case key := <-t.Keys():
+			if finished, cancelled := s.HandleKey(key); finished {
+				if cancelled {
+					return nil, nil
+				}
+				return s.Selected(), nil
+			}
+		case <-s.done:
+			// Scan finished - exit right away if the user already confirmed
+			s.mu.Lock()
+			confirmed := s.confirmed
+			s.mu.Unlock()
+			if confirmed {
+				return s.Selected(), nil
+			}
+			s.done = nil
+		case <-ticker.C:
+		}
+	}
+}
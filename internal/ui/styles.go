@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ColorProfile is how many colors the terminal can show
+type ColorProfile int
+
+const (
+	ProfileNone      ColorProfile = iota // attributes (bold, reverse) only
+	ProfileANSI                          // 16 basic colors
+	ProfileANSI256                       // xterm 256-color palette
+	ProfileTrueColor                     // 24-bit color
+)
+
+// DetectColorProfile works out color support from the environment, honoring NO_COLOR
+func DetectColorProfile() ColorProfile {
+	if os.Getenv("NO_COLOR") != "" {
+		return ProfileNone
+	}
+
+	term := os.Getenv("TERM")
+	if term == "dumb" {
+		return ProfileNone
+	}
+
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ProfileTrueColor
+	}
+	if strings.Contains(term, "256color") {
+		return ProfileANSI256
+	}
+	return ProfileANSI
+}
+
+// Style is a foreground color plus text attributes
+type Style struct {
+	Color     string // "#rrggbb", empty for the terminal default
+	Bold      bool
+	Underline bool
+	Reverse   bool
+
+	profile ColorProfile
+}
+
+// Render wraps s in the escape codes for this style
+func (st Style) Render(s string) string {
+	var codes []string
+	if st.Bold {
+		codes = append(codes, "1")
+	}
+	if st.Underline {
+		codes = append(codes, "4")
+	}
+	if st.Reverse {
+		codes = append(codes, "7")
+	}
+	if st.Color != "" && st.profile != ProfileNone {
+		if code := colorCode(st.Color, st.profile); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	if len(codes) == 0 || s == "" {
+		return s
+	}
+	return "\033[" + strings.Join(codes, ";") + "m" + s + "\033[0m"
+}
+
+// Theme is the set of styles the interactive views use
+type Theme struct {
+	Title    Style // screen headings
+	Cursor   Style // highlighted row
+	Selected Style // [x] checkboxes
+	Match    Style // filter matches
+	Dim      Style // key hints and secondary text
+	Error    Style // failures and invalid input
+	Success  Style // completed work
+}
+
+// themes are the built-in color themes
+var themes = map[string]Theme{
+	"dark": {
+		Title:    Style{Color: "#89b4fa", Bold: true},
+		Cursor:   Style{Color: "#f9e2af", Bold: true},
+		Selected: Style{Color: "#a6e3a1"},
+		Match:    Style{Color: "#fab387", Bold: true},
+		Dim:      Style{Color: "#7f849c"},
+		Error:    Style{Color: "#f38ba8"},
+		Success:  Style{Color: "#a6e3a1", Bold: true},
+	},
+	"light": {
+		Title:    Style{Color: "#1e66f5", Bold: true},
+		Cursor:   Style{Color: "#8839ef", Bold: true},
+		Selected: Style{Color: "#40a02b"},
+		Match:    Style{Color: "#d20f39", Bold: true},
+		Dim:      Style{Color: "#6c6f85"},
+		Error:    Style{Color: "#d20f39"},
+		Success:  Style{Color: "#40a02b", Bold: true},
+	},
+	"high-contrast": {
+		Title:    Style{Color: "#ffffff", Bold: true, Underline: true},
+		Cursor:   Style{Bold: true, Reverse: true},
+		Selected: Style{Color: "#00ff00", Bold: true},
+		Match:    Style{Color: "#ffff00", Bold: true, Underline: true},
+		Dim:      Style{Color: "#ffffff"},
+		Error:    Style{Color: "#ff0000", Bold: true},
+		Success:  Style{Color: "#00ff00", Bold: true},
+	},
+	"monochrome": {
+		Title:    Style{Bold: true},
+		Cursor:   Style{Reverse: true},
+		Selected: Style{Bold: true},
+		Match:    Style{Bold: true, Underline: true},
+		Error:    Style{Bold: true},
+		Success:  Style{Bold: true},
+	},
+}
+
+// styles is the active theme, adjusted to the terminal's color profile
+var styles = newStyles(themes["dark"], DetectColorProfile())
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTheme selects the theme for the interactive views. noColor forces
+// attribute-only output, as does NO_COLOR in the environment.
+func SetTheme(name string, noColor bool) error {
+	if name == "" {
+		name = "dark"
+	}
+	theme, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	profile := DetectColorProfile()
+	if noColor {
+		profile = ProfileNone
+	}
+	styles = newStyles(theme, profile)
+	return nil
+}
+
+// newStyles binds every style in theme to a color profile
+func newStyles(theme Theme, profile ColorProfile) Theme {
+	for _, st := range []*Style{&theme.Title, &theme.Cursor, &theme.Selected, &theme.Match, &theme.Dim, &theme.Error, &theme.Success} {
+		st.profile = profile
+	}
+	return theme
+}
+
+// colorCode returns the SGR foreground code for a hex color in the given profile
+func colorCode(hex string, profile ColorProfile) string {
+	r, g, b, ok := parseHex(hex)
+	if !ok {
+		return ""
+	}
+
+	switch profile {
+	case ProfileTrueColor:
+		return fmt.Sprintf("38;2;%d;%d;%d", r, g, b)
+	case ProfileANSI256:
+		return fmt.Sprintf("38;5;%d", ansi256(r, g, b))
+	case ProfileANSI:
+		return strconv.Itoa(ansi16(r, g, b))
+	}
+	return ""
+}
+
+// parseHex parses "#rrggbb"
+func parseHex(hex string) (r, g, b int, ok bool) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// ansi256 maps a color to the nearest entry in the xterm 6x6x6 cube or gray ramp
+func ansi256(r, g, b int) int {
+	// Grays get the finer 24-step ramp
+	if r == g && g == b {
+		if r < 8 {
+			return 16
+		}
+		if r > 248 {
+			return 231
+		}
+		return 232 + (r-8)*24/247
+	}
+
+	toCube := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	return 16 + 36*toCube(r) + 6*toCube(g) + toCube(b)
+}
+
+// ansi16 maps a color to the nearest of the 16 basic terminal colors
+func ansi16(r, g, b int) int {
+	basic := [16][3]int{
+		{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+		{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+		{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+		{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+	}
+
+	best, bestDist := 0, -1
+	for i, c := range basic {
+		dr, dg, db := r-c[0], g-c[1], b-c[2]
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	if best < 8 {
+		return 30 + best
+	}
+	return 90 + best - 8
+}
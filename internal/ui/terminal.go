@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Key identifies a single keypress read from the terminal
+type Key string
+
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyEnter     Key = "enter"
+	KeySpace     Key = "space"
+	KeyTab       Key = "tab"
+	KeyBackspace Key = "backspace"
+	KeyEscape    Key = "esc"
+	KeyCtrlC     Key = "ctrl+c"
+)
+
+// Terminal puts the controlling terminal into raw mode and provides
+// keypress input and full-screen rendering for interactive views
+type Terminal struct {
+	in       *os.File
+	out      *os.File
+	oldState *term.State
+	keys     chan Key
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	closeMu  sync.Once
+}
+
+// IsInteractive reports whether stdin and stdout are both attached to a terminal
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// OpenTerminal switches the terminal into raw mode on the alternate screen
+func OpenTerminal() (*Terminal, error) {
+	if !IsInteractive() {
+		return nil, fmt.Errorf("interactive mode requires a terminal")
+	}
+
+	t := &Terminal{
+		in:   os.Stdin,
+		out:  os.Stdout,
+		keys: make(chan Key, 16),
+	}
+	if err := t.Resume(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Resume enters raw mode on the alternate screen and starts reading keys
+func (t *Terminal) Resume() error {
+	fd := int(t.in.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+
+	// Reads return every 100ms even without input so the reader can be stopped
+	// without leaving a goroutine blocked on stdin (it would eat later prompts)
+	if err := setReadTimeout(fd); err != nil {
+		term.Restore(fd, oldState)
+		return fmt.Errorf("failed to configure terminal: %w", err)
+	}
+
+	t.oldState = oldState
+	t.stop = make(chan struct{})
+
+	// Alternate screen + hidden cursor
+	fmt.Fprint(t.out, "\033[?1049h\033[?25l")
+
+	t.wg.Add(1)
+	go t.readKeys()
+
+	return nil
+}
+
+// Suspend restores the normal terminal so another program (a shell, a sudo
+// prompt) can use it. Call Resume to return to the interactive view.
+func (t *Terminal) Suspend() {
+	if t.oldState == nil {
+		return
+	}
+
+	close(t.stop)
+	t.wg.Wait()
+
+	fmt.Fprint(t.out, "\033[?25h\033[?1049l")
+	term.Restore(int(t.in.Fd()), t.oldState)
+	t.oldState = nil
+}
+
+// Keys returns the channel of decoded keypresses
+func (t *Terminal) Keys() <-chan Key {
+	return t.keys
+}
+
+// Size returns the terminal width and height
+func (t *Terminal) Size() (int, int) {
+	w, h, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Draw clears the screen and renders the given lines, clipped to the terminal size
+func (t *Terminal) Draw(lines []string) {
+	width, height := t.Size()
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(clipVisible(line, width))
+	}
+	fmt.Fprint(t.out, b.String())
+}
+
+// Close restores the terminal to its original state
+func (t *Terminal) Close() {
+	t.closeMu.Do(t.Suspend)
+}
+
+// readKeys decodes raw input bytes into keys until the terminal is closed
+func (t *Terminal) readKeys() {
+	defer t.wg.Done()
+
+	buf := make([]byte, 16)
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		n, err := t.in.Read(buf)
+		if err != nil || n == 0 {
+			continue
+		}
+
+		for _, key := range decodeKeys(buf[:n]) {
+			select {
+			case t.keys <- key:
+			case <-t.stop:
+				return
+			}
+		}
+	}
+}
+
+// decodeKeys turns a chunk of raw terminal input into keys
+func decodeKeys(b []byte) []Key {
+	var keys []Key
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == 0x1b:
+			// Escape sequences: ESC [ X or ESC O X
+			if i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+				seq := b[i+2]
+				switch seq {
+				case 'A':
+					keys = append(keys, KeyUp)
+				case 'B':
+					keys = append(keys, KeyDown)
+				case 'C':
+					keys = append(keys, KeyRight)
+				case 'D':
+					keys = append(keys, KeyLeft)
+				case 'H':
+					keys = append(keys, KeyHome)
+				case 'F':
+					keys = append(keys, KeyEnd)
+				case '5', '6':
+					if i+3 < len(b) && b[i+3] == '~' {
+						if seq == '5' {
+							keys = append(keys, KeyPageUp)
+						} else {
+							keys = append(keys, KeyPageDown)
+						}
+						i++
+					}
+				}
+				i += 2
+				continue
+			}
+			// ESC followed by a printable character is how terminals send Alt+key
+			if i+1 < len(b) && b[i+1] >= 0x20 && b[i+1] < 0x7f {
+				keys = append(keys, Key("alt+"+string(b[i+1])))
+				i++
+				continue
+			}
+			keys = append(keys, KeyEscape)
+		case c == '\r' || c == '\n':
+			keys = append(keys, KeyEnter)
+		case c == ' ':
+			keys = append(keys, KeySpace)
+		case c == '\t':
+			keys = append(keys, KeyTab)
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, KeyBackspace)
+		case c == 0x03:
+			keys = append(keys, KeyCtrlC)
+		case c >= 0x01 && c <= 0x1a:
+			keys = append(keys, Key(fmt.Sprintf("ctrl+%c", c+'a'-1)))
+		case c < 0x20:
+			// ctrl+\ ctrl+] ctrl+^ ctrl+_
+			keys = append(keys, Key(fmt.Sprintf("ctrl+%c", c+0x40)))
+		default:
+			// Printable input, including multi-byte UTF-8 runes
+			r := []rune(string(b[i:]))[0]
+			keys = append(keys, Key(string(r)))
+			i += len(string(r)) - 1
+		}
+	}
+
+	return keys
+}
+
+// clipVisible truncates a line to width visible columns, ignoring ANSI escape codes
+func clipVisible(s string, width int) string {
+	var b strings.Builder
+	visible := 0
+	inEscape := false
+
+	for _, r := range s {
+		if inEscape {
+			b.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+			}
+			continue
+		}
+		if r == '\033' {
+			inEscape = true
+			b.WriteRune(r)
+			continue
+		}
+		if visible >= width {
+			continue
+		}
+		b.WriteRune(r)
+		visible++
+	}
+
+	return b.String()
+}
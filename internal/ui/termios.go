@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package ui
+
+import "golang.org/x/sys/unix"
+
+// setReadTimeout makes terminal reads return after 100ms when no input is pending
+func setReadTimeout(fd int) error {
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return err
+	}
+
+	termios.Cc[unix.VMIN] = 0
+	termios.Cc[unix.VTIME] = 1
+
+	return unix.IoctlSetTermios(fd, ioctlWriteTermios, termios)
+}
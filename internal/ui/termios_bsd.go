@@ -0,0 +1,10 @@
+//go:build darwin || freebsd || openbsd || netbsd || dragonfly
+
+package ui
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlReadTermios  = unix.TIOCGETA
+	ioctlWriteTermios = unix.TIOCSETA
+)
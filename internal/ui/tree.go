@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// treeNode is a directory or file in the browser's tree view
+type treeNode struct {
+	name     string // label relative to the parent node, e.g. "Caches/com.foo"
+	path     string
+	file     int // index into the browser's files, -1 for directories
+	parent   *treeNode
+	children []*treeNode
+
+	// Aggregates over every file below a directory
+	size    int64
+	count   int
+	modTime time.Time // newest file
+}
+
+// isDir reports whether the node is a directory
+func (n *treeNode) isDir() bool {
+	return n.file < 0
+}
+
+// treeRow is one line of the flattened tree view
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// buildTree groups the given files by directory. Chains of directories with
+// a single subdirectory and no files are collapsed into one node.
+func buildTree(files []FileInfo, indices []int) *treeNode {
+	root := &treeNode{file: -1}
+	dirs := map[string]*treeNode{}
+
+	var dirNode func(path string) *treeNode
+	dirNode = func(path string) *treeNode {
+		if node, ok := dirs[path]; ok {
+			return node
+		}
+		parentPath := filepath.Dir(path)
+		if parentPath == path {
+			return root
+		}
+		parent := dirNode(parentPath)
+		node := &treeNode{name: filepath.Base(path), path: path, file: -1, parent: parent}
+		parent.children = append(parent.children, node)
+		dirs[path] = node
+		return node
+	}
+
+	for _, idx := range indices {
+		path := files[idx].Path
+		parent := dirNode(filepath.Dir(path))
+		parent.children = append(parent.children, &treeNode{
+			name:   filepath.Base(path),
+			path:   path,
+			file:   idx,
+			parent: parent,
+		})
+	}
+
+	for _, child := range root.children {
+		compressTree(child)
+		// Top-level nodes show where they are
+		child.name = child.path
+	}
+	summarizeTree(root, files)
+	return root
+}
+
+// compressTree merges single-child directory chains into one node
+func compressTree(node *treeNode) {
+	for len(node.children) == 1 && node.children[0].isDir() {
+		child := node.children[0]
+		node.name += "/" + child.name
+		node.path = child.path
+		node.children = child.children
+		for _, c := range node.children {
+			c.parent = node
+		}
+	}
+	for _, child := range node.children {
+		if child.isDir() {
+			compressTree(child)
+		}
+	}
+}
+
+// summarizeTree fills in directory sizes, counts and times
+func summarizeTree(node *treeNode, files []FileInfo) {
+	if !node.isDir() {
+		file := files[node.file]
+		node.size, node.count, node.modTime = file.Size, 1, file.ModTime
+		return
+	}
+	for _, child := range node.children {
+		summarizeTree(child, files)
+		node.size += child.size
+		node.count += child.count
+		if child.modTime.After(node.modTime) {
+			node.modTime = child.modTime
+		}
+	}
+}
+
+// sortTree orders every directory's children by field, directories first
+func sortTree(node *treeNode, files []FileInfo, field string, desc bool) {
+	less := func(a, b *treeNode) bool {
+		switch field {
+		case SortByAge:
+			return a.modTime.After(b.modTime)
+		case SortByPath:
+			return a.name < b.name
+		case SortByCategory:
+			if !a.isDir() && !b.isDir() && files[a.file].Category != files[b.file].Category {
+				return files[a.file].Category < files[b.file].Category
+			}
+			return a.size < b.size
+		default:
+			return a.size < b.size
+		}
+	}
+
+	sort.SliceStable(node.children, func(i, j int) bool {
+		a, b := node.children[i], node.children[j]
+		if a.isDir() != b.isDir() {
+			return a.isDir()
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+
+	for _, child := range node.children {
+		if child.isDir() {
+			sortTree(child, files, field, desc)
+		}
+	}
+}
+
+// flattenTree lists the rows shown for the tree, descending into expanded directories
+func flattenTree(root *treeNode, expanded func(node *treeNode, depth int) bool) []treeRow {
+	var rows []treeRow
+	var walk func(node *treeNode, depth int)
+	walk = func(node *treeNode, depth int) {
+		for _, child := range node.children {
+			rows = append(rows, treeRow{node: child, depth: depth})
+			if child.isDir() && expanded(child, depth) {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+	return rows
+}
+
+// treeFiles returns the file indices below node
+func treeFiles(node *treeNode) []int {
+	if !node.isDir() {
+		return []int{node.file}
+	}
+	var indices []int
+	for _, child := range node.children {
+		indices = append(indices, treeFiles(child)...)
+	}
+	return indices
+}
+
+// renderTreeRow renders one directory or file line of the tree view
+func renderTreeRow(row treeRow, file FileInfo, matches []matchRange, width, catWidth, ownerWidth int, check string, expanded, current bool) string {
+	size := fmt.Sprintf("%10s", formatBytes(row.node.size))
+	cursor := "  "
+	if current {
+		cursor = styles.Cursor.Render("> ")
+		size = styles.Cursor.Render(size)
+	}
+	if check != "[ ]" {
+		check = styles.Selected.Render(check)
+	}
+
+	category, risk := "", ""
+	if catWidth > 0 {
+		if !row.node.isDir() {
+			category = fitWidth(file.Category, catWidth)
+			risk = fitWidth(file.Risk, riskWidth)
+		} else {
+			category = strings.Repeat(" ", catWidth)
+			risk = strings.Repeat(" ", riskWidth)
+		}
+		category += "  "
+		risk += "  "
+	}
+	owner := ""
+	if ownerWidth > 0 {
+		owner = fitWidth(file.Owner, ownerWidth) + "  "
+	}
+	indent := strings.Repeat("  ", row.depth)
+	prefix := fmt.Sprintf(" %s%s %s%s %s%s%s%s", cursor, check, size, accessMark(file.Access), styles.Dim.Render(category),
+		riskStyle(file.Risk).Render(risk), styles.Dim.Render(owner), indent)
+	avail := width - utf8.RuneCountInString(fmt.Sprintf(" > [x] %10s  %s%s%s%s", formatBytes(row.node.size), category, risk, owner, indent))
+
+	if row.node.isDir() {
+		marker := "▸ "
+		if expanded {
+			marker = "▾ "
+		}
+		label := marker + row.node.name + "/"
+		detail := fmt.Sprintf("  (%d files)", row.node.count)
+		if utf8.RuneCountInString(label+detail) > avail && avail > 0 {
+			label = fitWidth(label, max(avail-utf8.RuneCountInString(detail), 1))
+		}
+		return prefix + label + styles.Dim.Render(detail)
+	}
+
+	// File rows show the name only; shift the full-path matches to match
+	base := len(file.Path) - len(row.node.name)
+	name := row.node.name
+	if avail > 1 && utf8.RuneCountInString(name) > avail {
+		name = fitWidth(name, avail)
+	}
+	return prefix + "  " + highlightSegments(name, matches, -base, 0)
+}
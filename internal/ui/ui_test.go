@@ -0,0 +1,863 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/internal/cleaner"
+)
+
+// =============================================================================
+// Browser Filter Tests
+// =============================================================================
+
+func testBrowserFiles() []FileInfo {
+	return []FileInfo{
+		{Path: "/home/user/project/node_modules", Size: 300, Category: "node_modules"},
+		{Path: "/home/user/.cache/pip/wheel.whl", Size: 200, Category: "cache"},
+		{Path: "/home/user/logs/app.log", Size: 100, Category: "logs"},
+	}
+}
+
+// stripANSI removes SGR escape codes so tests can check the visible text
+func stripANSI(s string) string {
+	var b strings.Builder
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case r == '\033':
+			inEscape = true
+		case inEscape:
+			if r == 'm' {
+				inEscape = false
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func visiblePaths(m *BrowserViewModel) []string {
+	var paths []string
+	for _, f := range m.Visible() {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestBrowserFuzzyFilterBasename(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	m.SetFilter("nmod")
+	paths := visiblePaths(m)
+	if len(paths) != 1 || paths[0] != "/home/user/project/node_modules" {
+		t.Errorf("expected node_modules match, got %v", paths)
+	}
+
+	// "user" is only in the directory part, so basename mode must not match it
+	m.SetFilter("user")
+	if paths := visiblePaths(m); len(paths) != 0 {
+		t.Errorf("basename filter should not match directories, got %v", paths)
+	}
+}
+
+func TestBrowserFullPathFilter(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+	m.SetMatchFullPath(true)
+
+	m.SetFilter("cachepip")
+	paths := visiblePaths(m)
+	if len(paths) != 1 || paths[0] != "/home/user/.cache/pip/wheel.whl" {
+		t.Errorf("expected pip cache match, got %v", paths)
+	}
+
+	m.SetFilter("user")
+	if paths := visiblePaths(m); len(paths) != 3 {
+		t.Errorf("expected all files to match in full-path mode, got %v", paths)
+	}
+}
+
+func TestBrowserRegexFilter(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	m.SetFilter(`re:\.(log|whl)$`)
+	if paths := visiblePaths(m); len(paths) != 2 {
+		t.Errorf("expected 2 regex matches, got %v", paths)
+	}
+
+	// Invalid patterns keep the last valid result
+	m.SetFilter(`re:\.(log`)
+	if m.filterErr == nil {
+		t.Error("expected error for invalid regex")
+	}
+	if paths := visiblePaths(m); len(paths) != 2 {
+		t.Errorf("invalid regex should keep previous result, got %v", paths)
+	}
+}
+
+func TestBrowserSelectionSurvivesFilter(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	m.SetFilter("app")
+	m.HandleKey(KeySpace)
+	m.SetFilter("")
+
+	selected := m.Selected()
+	if len(selected) != 2 {
+		t.Fatalf("expected 2 selected files, got %d", len(selected))
+	}
+	for _, f := range selected {
+		if f.Path == "/home/user/logs/app.log" {
+			t.Error("deselected file should not be returned")
+		}
+	}
+}
+
+func TestRenderFileRowHighlight(t *testing.T) {
+	file := FileInfo{Path: "/tmp/app.log", Size: 10}
+
+	fuzzy, _ := fuzzyMatch("log", "app.log")
+	for i := range fuzzy {
+		fuzzy[i].start += len("/tmp/")
+		fuzzy[i].end += len("/tmp/")
+	}
+
+	row := renderFileRow(file, fuzzy, 80, 0, 0, true, true)
+	if !strings.Contains(row, styles.Match.Render("log")) {
+		t.Errorf("expected highlighted match in row, got %q", row)
+	}
+	if !strings.HasPrefix(stripANSI(row), " > [x]") {
+		t.Errorf("expected cursor and checkbox prefix, got %q", row)
+	}
+}
+
+func TestRenderFileRowShowsRisk(t *testing.T) {
+	file := FileInfo{Path: "/tmp/app.log", Size: 10, Category: "logs", Risk: "review"}
+
+	row := renderFileRow(file, nil, 80, 14, 0, false, false)
+	if !strings.Contains(row, styles.Error.Render("review  ")) {
+		t.Errorf("expected the review level highlighted, got %q", row)
+	}
+	if row := stripANSI(renderFileRow(file, nil, 80, 0, 0, false, false)); strings.Contains(row, "review") {
+		t.Errorf("expected the risk hidden with the category column, got %q", row)
+	}
+}
+
+func TestOwnerColumn(t *testing.T) {
+	same := []FileInfo{{Path: "/a", Owner: "alice"}, {Path: "/b", Owner: "alice"}}
+	if w := ownerColumnWidth(same); w != 0 {
+		t.Errorf("expected no owner column for a single owner, got width %d", w)
+	}
+	mixed := []FileInfo{{Path: "/a", Owner: "alice"}, {Path: "/b", Owner: "a-very-long-user-name"}}
+	if w := ownerColumnWidth(mixed); w != maxOwnerWidth {
+		t.Errorf("owner column width = %d, want %d", w, maxOwnerWidth)
+	}
+
+	row := stripANSI(renderFileRow(mixed[0], nil, 80, 0, maxOwnerWidth, false, false))
+	if !strings.Contains(row, "alice  ") || !strings.HasSuffix(row, "/a") {
+		t.Errorf("expected the owner before the path, got %q", row)
+	}
+}
+
+func TestRenderFileRowTruncatesLongPaths(t *testing.T) {
+	file := FileInfo{Path: "/very/long/path/" + strings.Repeat("d/", 40) + "file.txt", Size: 10}
+
+	row := stripANSI(renderFileRow(file, nil, 60, 0, 0, false, false))
+	if !strings.Contains(row, "...") || !strings.HasSuffix(row, "file.txt") {
+		t.Errorf("expected path trimmed from the start, got %q", row)
+	}
+	if len([]rune(row)) > 60 {
+		t.Errorf("row exceeds width: %d", len([]rune(row)))
+	}
+}
+
+// =============================================================================
+// Reveal / Shell Tests
+// =============================================================================
+
+func TestBrowserRevealKeysRequestActions(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	m.HandleKey("o")
+	if m.action != actionReveal {
+		t.Errorf("expected reveal action, got %v", m.action)
+	}
+
+	m.action = actionNone
+	m.HandleKey("O")
+	if m.action != actionShell {
+		t.Errorf("expected shell action, got %v", m.action)
+	}
+
+	// While typing a filter, o is just text
+	m.action = actionNone
+	m.HandleKey("/")
+	m.HandleKey("o")
+	if m.action != actionNone || m.filter != "o" {
+		t.Errorf("expected filter text, got action=%v filter=%q", m.action, m.filter)
+	}
+}
+
+func TestBrowserIgnore(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	// Without somewhere to record them, files can't be dismissed
+	m.HandleKey("i")
+	if m.action != actionNone {
+		t.Fatalf("expected no action without SetIgnore, got %v", m.action)
+	}
+
+	var ignored []string
+	m.SetIgnore(func(paths []string) error {
+		ignored = append(ignored, paths...)
+		return nil
+	})
+	m.HandleKey("i")
+	if err := m.runAction(nil); err != nil {
+		t.Fatalf("runAction failed: %v", err)
+	}
+	if len(ignored) != 1 || ignored[0] != "/home/user/project/node_modules" {
+		t.Errorf("expected the largest file to be ignored, got %v", ignored)
+	}
+	if got := visiblePaths(m); len(got) != 2 || got[0] != "/home/user/.cache/pip/wheel.whl" {
+		t.Errorf("expected the ignored file to leave the list, got %v", got)
+	}
+	if selected := m.Selected(); len(selected) != 2 {
+		t.Errorf("expected 2 files still selected, got %d", len(selected))
+	}
+}
+
+func TestShellDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ShellDir(dir); got != dir {
+		t.Errorf("ShellDir(dir) = %q, want %q", got, dir)
+	}
+	if got := ShellDir(file); got != dir {
+		t.Errorf("ShellDir(file) = %q, want %q", got, dir)
+	}
+}
+
+// =============================================================================
+// Clean View Tests
+// =============================================================================
+
+func TestCleanViewGroupsFailures(t *testing.T) {
+	v := &CleanView{
+		result: &cleaner.CleanResult{
+			SkippedFiles: []string{"/busy", "/root-owned", "/new"},
+			SkippedReason: map[string]string{
+				"/busy":       "File is being used",
+				"/root-owned": "Requires elevated permissions",
+				"/new":        "File too new (safety check)",
+			},
+			Errors: []*cleaner.DeletionError{
+				{Path: "/busy", Reason: cleaner.ErrorFileInUse, Retryable: true},
+				{Path: "/locked", Reason: cleaner.ErrorPermissionDenied, NeedsSudo: true},
+			},
+		},
+	}
+	v.buildGroups()
+
+	if len(v.groups) != 4 {
+		t.Fatalf("expected 4 groups, got %d", len(v.groups))
+	}
+
+	titles := map[string]*failureGroup{}
+	for _, g := range v.groups {
+		titles[g.title] = g
+	}
+	if g := titles["Permission denied"]; g == nil || !g.escalatable {
+		t.Error("permission denied group should offer sudo")
+	}
+	if g := titles["File is in use"]; g == nil || !g.retryable {
+		t.Error("file in use group should offer retry")
+	}
+	if g := titles["Requires elevated permissions"]; g == nil || len(g.paths) != 1 {
+		t.Error("skipped sudo files should be grouped")
+	}
+	if g := titles["Skipped by safety checks"]; g == nil || g.retryable || g.escalatable {
+		t.Error("safety skips should not be actionable")
+	}
+}
+
+func TestCleanViewExpandAndActions(t *testing.T) {
+	v := &CleanView{
+		result: &cleaner.CleanResult{
+			SkippedReason: map[string]string{},
+			Errors: []*cleaner.DeletionError{
+				{Path: "/locked", Reason: cleaner.ErrorPermissionDenied, NeedsSudo: true},
+			},
+		},
+	}
+	v.buildGroups()
+
+	v.HandleKey(KeyEnter)
+	if !v.groups[0].expanded {
+		t.Error("enter should expand the group")
+	}
+	lines := strings.Join(v.View(80, 24), "\n")
+	if !strings.Contains(lines, "/locked") {
+		t.Error("expanded group should list its paths")
+	}
+
+	if action, _ := v.HandleKey("r"); action != cleanActionNone {
+		t.Error("permission errors should not offer a plain retry")
+	}
+	if action, _ := v.HandleKey("s"); action != cleanActionEscalate {
+		t.Error("s should escalate permission errors")
+	}
+	if _, done := v.HandleKey("q"); !done {
+		t.Error("q should close the results screen")
+	}
+}
+
+// =============================================================================
+// Keymap Tests
+// =============================================================================
+
+func TestKeymapPresets(t *testing.T) {
+	tests := []struct {
+		preset string
+		key    Key
+		want   Action
+	}{
+		{"default", "j", ActionDown},
+		{"default", KeyUp, ActionUp},
+		{"vim", "ctrl+d", ActionPageDown},
+		{"emacs", "ctrl+n", ActionDown},
+		{"emacs", "j", ActionNone},
+		{"emacs", "?", ActionHelp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset+"/"+string(tt.key), func(t *testing.T) {
+			km, err := NewKeymap(tt.preset, nil)
+			if err != nil {
+				t.Fatalf("NewKeymap failed: %v", err)
+			}
+			if got := km.Action(tt.key); got != tt.want {
+				t.Errorf("Action(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeymapOverrides(t *testing.T) {
+	km, err := NewKeymap("default", map[string][]string{"toggle": {"t"}})
+	if err != nil {
+		t.Fatalf("NewKeymap failed: %v", err)
+	}
+
+	if km.Action("t") != ActionToggle {
+		t.Error("override key should toggle")
+	}
+	if km.Action(KeySpace) != ActionNone || km.Action("x") != ActionNone {
+		t.Error("override should replace the preset's keys for the action")
+	}
+}
+
+func TestKeymapErrors(t *testing.T) {
+	if _, err := NewKeymap("nano", nil); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+	if _, err := NewKeymap("default", map[string][]string{"explode": {"x"}}); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
+
+func TestHelpOverlay(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	m.HandleKey("?")
+	view := strings.Join(m.View(80, 40), "\n")
+	if !strings.Contains(view, "Keyboard shortcuts") || !strings.Contains(view, "Reveal in file manager") {
+		t.Errorf("expected help overlay, got:\n%s", view)
+	}
+
+	// Any key closes it without acting
+	if finished, _ := m.HandleKey("q"); finished {
+		t.Error("key that closes help should not quit")
+	}
+	if m.showHelp {
+		t.Error("help should be closed")
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  []Key
+	}{
+		{"arrow", []byte("\033[A"), []Key{KeyUp}},
+		{"page down", []byte("\033[6~"), []Key{KeyPageDown}},
+		{"alt", []byte("\033v"), []Key{"alt+v"}},
+		{"ctrl", []byte{0x0e}, []Key{"ctrl+n"}},
+		{"ctrl underscore", []byte{0x1f}, []Key{"ctrl+_"}},
+		{"lone escape", []byte{0x1b}, []Key{KeyEscape}},
+		{"text", []byte("aé"), []Key{"a", "é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeKeys(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("decodeKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("decodeKeys(%q)[%d] = %q, want %q", tt.input, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Style Tests
+// =============================================================================
+
+func TestDetectColorProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		noColor   string
+		term      string
+		colorterm string
+		want      ColorProfile
+	}{
+		{"NO_COLOR wins", "1", "xterm-256color", "truecolor", ProfileNone},
+		{"dumb terminal", "", "dumb", "", ProfileNone},
+		{"truecolor", "", "xterm-256color", "truecolor", ProfileTrueColor},
+		{"256 colors", "", "xterm-256color", "", ProfileANSI256},
+		{"basic", "", "xterm", "", ProfileANSI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("TERM", tt.term)
+			t.Setenv("COLORTERM", tt.colorterm)
+			if got := DetectColorProfile(); got != tt.want {
+				t.Errorf("DetectColorProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleRenderDegrades(t *testing.T) {
+	st := Style{Color: "#ff0000", Bold: true}
+
+	tests := []struct {
+		profile ColorProfile
+		want    string
+	}{
+		{ProfileTrueColor, "\033[1;38;2;255;0;0mx\033[0m"},
+		{ProfileANSI256, "\033[1;38;5;196mx\033[0m"},
+		{ProfileANSI, "\033[1;91mx\033[0m"},
+		{ProfileNone, "\033[1mx\033[0m"},
+	}
+
+	for _, tt := range tests {
+		st.profile = tt.profile
+		if got := st.Render("x"); got != tt.want {
+			t.Errorf("profile %d: Render = %q, want %q", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme("dark", false)
+
+	if err := SetTheme("solarized", false); err == nil {
+		t.Error("expected error for unknown theme")
+	}
+
+	for _, name := range ThemeNames() {
+		if err := SetTheme(name, true); err != nil {
+			t.Errorf("SetTheme(%q) failed: %v", name, err)
+		}
+		// No color codes at all with --no-ui-color
+		if out := styles.Error.Render("x"); strings.Contains(out, "38;") || strings.Contains(out, "[3") || strings.Contains(out, "[9") {
+			t.Errorf("theme %q rendered color with colors disabled: %q", name, out)
+		}
+	}
+}
+
+// =============================================================================
+// Preferences Tests
+// =============================================================================
+
+func TestPrefsSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ui-state.json")
+
+	prefs, err := LoadPrefs(path)
+	if err != nil {
+		t.Fatalf("LoadPrefs on missing file failed: %v", err)
+	}
+	if prefs.SortField != SortBySize || !prefs.SortDesc {
+		t.Errorf("expected size descending by default, got %s desc=%v", prefs.SortField, prefs.SortDesc)
+	}
+
+	prefs.SortField = SortByPath
+	prefs.SortDesc = false
+	prefs.ColumnWidths["category"] = 20
+	prefs.LastFilter = "log"
+	prefs.SelectedCategories = []string{"logs", "cache"}
+	if err := prefs.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadPrefs(path)
+	if err != nil {
+		t.Fatalf("LoadPrefs failed: %v", err)
+	}
+	if loaded.SortField != SortByPath || loaded.SortDesc || loaded.ColumnWidths["category"] != 20 ||
+		loaded.LastFilter != "log" || len(loaded.SelectedCategories) != 2 {
+		t.Errorf("prefs did not round-trip: %+v", loaded)
+	}
+}
+
+func TestLoadPrefsInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	badSort := filepath.Join(dir, "bad-sort.json")
+	os.WriteFile(badSort, []byte(`{"sort_field": "color"}`), 0644)
+	prefs, err := LoadPrefs(badSort)
+	if err != nil {
+		t.Fatalf("LoadPrefs failed: %v", err)
+	}
+	if prefs.SortField != SortBySize {
+		t.Errorf("expected unknown sort field to fall back to size, got %q", prefs.SortField)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("{"), 0644)
+	prefs, err = LoadPrefs(corrupt)
+	if err == nil {
+		t.Error("expected error for corrupt state file")
+	}
+	if prefs == nil || prefs.SortField != SortBySize {
+		t.Error("expected defaults alongside the error")
+	}
+}
+
+func TestBrowserSortKeepsSelection(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+
+	// Deselect the largest file, then sort by path
+	m.HandleKey(KeySpace)
+	m.SetSort(SortByPath, false)
+
+	want := []string{"/home/user/.cache/pip/wheel.whl", "/home/user/logs/app.log", "/home/user/project/node_modules"}
+	if got := visiblePaths(m); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected path order %v, got %v", want, got)
+	}
+	if current, _ := m.Current(); current.Path != "/home/user/project/node_modules" {
+		t.Errorf("expected cursor to follow the file, got %s", current.Path)
+	}
+	for _, f := range m.Selected() {
+		if f.Path == "/home/user/project/node_modules" {
+			t.Error("expected deselected file to stay deselected after sorting")
+		}
+	}
+
+	// Sort key cycles size -> age -> path -> category
+	m.SetSort(SortBySize, true)
+	m.HandleKey("S")
+	if m.sortField != SortByAge {
+		t.Errorf("expected age after size, got %s", m.sortField)
+	}
+	m.HandleKey("R")
+	if m.sortDesc {
+		t.Error("expected reverse to flip the sort direction")
+	}
+}
+
+func TestBrowserPrefsRoundTrip(t *testing.T) {
+	m := NewBrowserViewModel(testBrowserFiles())
+	m.ApplyPrefs(&Prefs{
+		SortField:     SortByCategory,
+		ColumnWidths:  map[string]int{"category": 8},
+		LastFilter:    "re:\\.(log|whl)$",
+		MatchFullPath: true,
+	})
+
+	if got := visiblePaths(m); len(got) != 2 || got[0] != "/home/user/.cache/pip/wheel.whl" {
+		t.Errorf("expected restored filter and category sort, got %v", got)
+	}
+
+	m.HandleKey("-")
+	m.HandleKey("-")
+	prefs := DefaultPrefs()
+	m.UpdatePrefs(prefs)
+	if prefs.SortField != SortByCategory || prefs.ColumnWidths["category"] != 0 || !prefs.MatchFullPath {
+		t.Errorf("unexpected prefs: %+v", prefs)
+	}
+
+	// An invalid saved pattern is dropped rather than hiding everything
+	m.ApplyPrefs(&Prefs{SortField: SortBySize, LastFilter: "re:("})
+	if len(m.Visible()) != 3 {
+		t.Errorf("expected invalid filter to be cleared, got %v", visiblePaths(m))
+	}
+}
+
+func TestSelectorPreviousSelection(t *testing.T) {
+	remind := NewCategorySelector([]string{"logs", "cache", "temp"})
+	remind.SetPreviousSelection([]string{"logs"}, SelectionRemind)
+	if len(remind.Selected()) != 3 {
+		t.Errorf("remind mode should not change the selection, got %v", remind.Selected())
+	}
+	if view := stripANSI(strings.Join(remind.View(80), "\n")); !strings.Contains(view, "last time") {
+		t.Errorf("expected previous selection hint in view:\n%s", view)
+	}
+	remind.HandleKey("p")
+	if got := remind.Selected(); len(got) != 1 || got[0] != "logs" {
+		t.Errorf("expected restore key to apply previous selection, got %v", got)
+	}
+
+	apply := NewCategorySelector([]string{"logs", "cache", "temp"})
+	apply.SetPreviousSelection([]string{"cache", "temp"}, SelectionApply)
+	if got := apply.Selected(); len(got) != 2 || got[0] != "cache" {
+		t.Errorf("expected previous selection applied, got %v", got)
+	}
+
+	off := NewCategorySelector([]string{"logs"})
+	off.SetPreviousSelection([]string{"logs"}, SelectionOff)
+	off.HandleKey(KeySpace)
+	off.HandleKey("p")
+	if len(off.Selected()) != 0 {
+		t.Error("restore key should do nothing when selection memory is off")
+	}
+}
+
+// =============================================================================
+// Tree View Tests
+// =============================================================================
+
+func testTreeFiles() []FileInfo {
+	return []FileInfo{
+		{Path: "/home/user/Library/Caches/com.foo/a/1.bin", Size: 100},
+		{Path: "/home/user/Library/Caches/com.foo/a/2.bin", Size: 200},
+		{Path: "/home/user/Library/Caches/com.foo/b.bin", Size: 50},
+		{Path: "/home/user/Library/Caches/com.bar/c.bin", Size: 400},
+	}
+}
+
+func TestBuildTreeAggregatesAndCompresses(t *testing.T) {
+	files := testTreeFiles()
+	root := buildTree(files, []int{0, 1, 2, 3})
+
+	if len(root.children) != 1 {
+		t.Fatalf("expected a single top-level node, got %d", len(root.children))
+	}
+	top := root.children[0]
+	if top.name != "/home/user/Library/Caches" || top.size != 750 || top.count != 4 {
+		t.Errorf("unexpected top node: name=%q size=%d count=%d", top.name, top.size, top.count)
+	}
+
+	sortTree(root, files, SortBySize, true)
+	rows := flattenTree(root, func(node *treeNode, depth int) bool { return true })
+	var names []string
+	for _, row := range rows {
+		names = append(names, strings.Repeat(" ", row.depth)+row.node.name)
+	}
+	want := []string{"/home/user/Library/Caches", " com.bar", "  c.bin", " com.foo", "  a", "   2.bin", "   1.bin", "  b.bin"}
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected tree rows:\n got %v\nwant %v", names, want)
+	}
+}
+
+func TestBrowserTreeToggleDirectory(t *testing.T) {
+	m := NewBrowserViewModel(testTreeFiles())
+	m.HandleKey("t")
+	if !m.treeMode {
+		t.Fatal("expected tree mode after t")
+	}
+
+	// Top-level directory starts expanded, its subdirectories collapsed
+	if len(m.rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(m.rows))
+	}
+
+	// Deselect everything under com.foo with one keypress
+	m.moveCursorTo("/home/user/Library/Caches/com.foo")
+	if current, _ := m.Current(); current.Path != "/home/user/Library/Caches/com.foo" || current.Size != 350 {
+		t.Fatalf("expected cursor on com.foo, got %+v", current)
+	}
+	m.HandleKey(KeySpace)
+	if selected := m.Selected(); len(selected) != 1 || selected[0].Path != "/home/user/Library/Caches/com.bar/c.bin" {
+		t.Errorf("expected only com.bar to stay selected, got %v", selected)
+	}
+	if state := m.checkState(treeFiles(m.tree.children[0])); state != "[-]" {
+		t.Errorf("expected partial check state on parent, got %s", state)
+	}
+
+	// Expanding shows the children; collapsing from a file goes back to its directory
+	m.HandleKey(KeyRight)
+	if len(m.rows) != 5 {
+		t.Fatalf("expected 5 rows after expanding, got %d", len(m.rows))
+	}
+	m.moveCursorTo("/home/user/Library/Caches/com.foo/b.bin")
+	m.HandleKey(KeyLeft)
+	if current, _ := m.Current(); current.Path != "/home/user/Library/Caches/com.foo" || len(m.rows) != 3 {
+		t.Errorf("expected collapse back to com.foo, got %s with %d rows", current.Path, len(m.rows))
+	}
+
+	// Leaving tree mode keeps the cursor near where it was
+	m.HandleKey("t")
+	if m.treeMode || len(m.Visible()) != 4 {
+		t.Error("expected flat list after second t")
+	}
+}
+
+func TestBrowserTreeFilterExpands(t *testing.T) {
+	m := NewBrowserViewModel(testTreeFiles())
+	m.SetTreeMode(true)
+	m.SetFilter("2.bin")
+
+	var files []string
+	for _, row := range m.rows {
+		if !row.node.isDir() {
+			files = append(files, row.node.path)
+		}
+	}
+	if len(files) != 1 || files[0] != "/home/user/Library/Caches/com.foo/a/2.bin" {
+		t.Errorf("expected filtered match shown expanded, got %v", files)
+	}
+
+	view := stripANSI(strings.Join(m.View(100, 20), "\n"))
+	if !strings.Contains(view, "▾ /home/user/Library/Caches/com.foo/a/") || !strings.Contains(view, "(1 files)") {
+		t.Errorf("expected compressed, expanded directory row in view:\n%s", view)
+	}
+}
+
+// =============================================================================
+// Duplicates View Tests
+// =============================================================================
+
+func testDuplicateGroups() []DuplicateGroup {
+	now := time.Now()
+	return []DuplicateGroup{
+		{Size: 100, Files: []FileInfo{
+			{Path: "/home/user/Projects/app/logo.png", ModTime: now},
+			{Path: "/home/user/Downloads/logo.png", ModTime: now.Add(-time.Hour)},
+			{Path: "/home/user/Desktop/logo (1).png", ModTime: now.Add(-2 * time.Hour)},
+		}},
+		{Size: 50, Files: []FileInfo{
+			{Path: "/home/user/Downloads/a.zip", ModTime: now},
+			{Path: "/home/user/Downloads/b.zip", ModTime: now.Add(-time.Hour)},
+		}},
+	}
+}
+
+func removedPaths(v *DuplicatesView) []string {
+	var paths []string
+	for _, f := range v.Remove() {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestDuplicatesViewKeepsNewestByDefault(t *testing.T) {
+	v := NewDuplicatesView(testDuplicateGroups())
+
+	want := []string{"/home/user/Downloads/logo.png", "/home/user/Desktop/logo (1).png", "/home/user/Downloads/b.zip"}
+	if got := removedPaths(v); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected all but the newest removed, got %v", got)
+	}
+}
+
+func TestDuplicatesViewActions(t *testing.T) {
+	v := NewDuplicatesView(testDuplicateGroups())
+
+	// Keep the Downloads copy of the logo instead of the project one
+	v.HandleKey(KeyDown)
+	v.HandleKey("c")
+	if got := removedPaths(v); len(got) != 3 || got[0] != "/home/user/Projects/app/logo.png" {
+		t.Errorf("expected keep-this to swap the kept copy, got %v", got)
+	}
+
+	// The last kept copy can't be marked for deletion
+	v.HandleKey(KeySpace)
+	if v.status == "" || len(v.Remove()) != 3 {
+		t.Errorf("expected refusal to delete every copy, got %v", removedPaths(v))
+	}
+
+	// Moving past the end of a group goes to the next one
+	v.HandleKey(KeyDown)
+	v.HandleKey(KeyDown)
+	if v.group != 1 || v.file != 0 {
+		t.Errorf("expected cursor on second group, got group %d file %d", v.group, v.file)
+	}
+	v.HandleKey(KeySpace)
+	v.HandleKey("N")
+	if got := removedPaths(v); len(got) != 3 || got[0] != "/home/user/Downloads/logo.png" {
+		t.Errorf("expected keep newest everywhere to reset every group, got %v", got)
+	}
+}
+
+func TestDuplicatesViewLocations(t *testing.T) {
+	v := NewDuplicatesView(testDuplicateGroups())
+	v.home = "/home/user"
+	v.SetProjectDirs([]string{"~/Projects"})
+
+	view := stripANSI(strings.Join(v.View(120, 20), "\n"))
+	for _, want := range []string{"project", "Downloads", "Desktop", "Group 1 of 2: 3 copies", "[keep]", "[del ]"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view:\n%s", want, view)
+		}
+	}
+}
+
+// =============================================================================
+// Typed Confirmation Tests
+// =============================================================================
+
+func TestConfirmToken(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{500, "500B"},
+		{1536, "2KB"},
+		{52*1024*1024*1024 + 300*1024*1024, "52GB"},
+		{3 * 1024 * 1024 * 1024 * 1024, "3TB"},
+	}
+	for _, tt := range tests {
+		if got := ConfirmToken(tt.size); got != tt.want {
+			t.Errorf("ConfirmToken(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestPromptTypedConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"52GB\n", true},
+		{" 52 gb \n", true},
+		{"y\n", false},
+		{"52\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		got := PromptTypedConfirm(strings.NewReader(tt.input), &out, "52GB", []string{"This cleanup removes 52 GB."})
+		if got != tt.want {
+			t.Errorf("input %q: got %v, want %v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "Type 52GB to confirm") || !strings.Contains(out.String(), "removes 52 GB") {
+			t.Errorf("unexpected prompt: %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package vfs
+
+import (
+	"io/fs"
+	"sync/atomic"
+)
+
+// CountingFS counts the calls made to the file system it wraps, as a rough
+// measure of the work a scan does. Wrapping the real file system still counts
+// as the real one for IsOS, but calls made by external tools aren't seen.
+type CountingFS struct {
+	fs FS
+
+	Stats     atomic.Int64
+	Lstats    atomic.Int64
+	ReadDirs  atomic.Int64
+	Readlinks atomic.Int64
+	Opens     atomic.Int64
+}
+
+// NewCountingFS wraps fsys
+func NewCountingFS(fsys FS) *CountingFS {
+	return &CountingFS{fs: fsys}
+}
+
+// Unwrap returns the wrapped file system
+func (c *CountingFS) Unwrap() FS {
+	return c.fs
+}
+
+// Total returns the number of calls counted
+func (c *CountingFS) Total() int64 {
+	return c.Stats.Load() + c.Lstats.Load() + c.ReadDirs.Load() + c.Readlinks.Load() + c.Opens.Load()
+}
+
+func (c *CountingFS) Stat(name string) (fs.FileInfo, error) {
+	c.Stats.Add(1)
+	return c.fs.Stat(name)
+}
+
+func (c *CountingFS) Lstat(name string) (fs.FileInfo, error) {
+	c.Lstats.Add(1)
+	return c.fs.Lstat(name)
+}
+
+func (c *CountingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.ReadDirs.Add(1)
+	return c.fs.ReadDir(name)
+}
+
+func (c *CountingFS) Readlink(name string) (string, error) {
+	c.Readlinks.Add(1)
+	return c.fs.Readlink(name)
+}
+
+func (c *CountingFS) Open(name string) (File, error) {
+	c.Opens.Add(1)
+	return c.fs.Open(name)
+}
+
+func (c *CountingFS) Remove(name string) error    { return c.fs.Remove(name) }
+func (c *CountingFS) RemoveAll(name string) error { return c.fs.RemoveAll(name) }
@@ -0,0 +1,161 @@
+package vfs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenilsonani/system-cleanup/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes a synthetic home directory and system tree to simulate a
+// cleanup against
+type Fixture struct {
+	OS      string         `yaml:"os"`   // Platform whose default paths are used; empty for this one
+	Home    string         `yaml:"home"` // ~ in entry paths expands to this
+	User    string         `yaml:"user"`
+	Entries []FixtureEntry `yaml:"files"`
+}
+
+// FixtureEntry is a file, directory or symlink in a Fixture. Parent
+// directories are created as needed.
+type FixtureEntry struct {
+	Path    string `yaml:"path"`
+	Size    string `yaml:"size"`    // e.g. "120MB"; ignored if Content is set
+	Content string `yaml:"content"` // Exact contents, for duplicate detection
+	Age     string `yaml:"age"`     // Time since last modified, e.g. "36h" or "30d"
+	Dir     bool   `yaml:"dir"`
+	Symlink string `yaml:"symlink"` // Makes the entry a symlink to this target
+	Mode    string `yaml:"mode"`    // Octal permissions, e.g. "0555" for a read-only directory
+}
+
+// LoadFixture reads a fixture from a YAML file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	if fixture.Home == "" {
+		fixture.Home = "/home/user"
+	}
+	if fixture.User == "" {
+		fixture.User = filepath.Base(fixture.Home)
+	}
+	return &fixture, nil
+}
+
+// Expand replaces a leading ~ in path with the fixture's home directory
+func (f *Fixture) Expand(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(f.Home, path[1:])
+	}
+	return path
+}
+
+// Build creates a MemFS holding the fixture's entries
+func (f *Fixture) Build() (*MemFS, error) {
+	m := NewMemFS()
+	now := time.Now()
+	if err := m.MkdirAll(f.Home, now); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range f.Entries {
+		path := f.Expand(entry.Path)
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("fixture path %q must be absolute or start with ~", entry.Path)
+		}
+		age, err := parseAge(entry.Age)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Path, err)
+		}
+		modTime := now.Add(-age)
+
+		switch {
+		case entry.Symlink != "":
+			err = m.Symlink(f.Expand(entry.Symlink), path)
+		case entry.Dir:
+			err = m.MkdirAll(path, modTime)
+		case entry.Content != "":
+			err = m.WriteFile(path, []byte(entry.Content), modTime)
+		default:
+			var size int64
+			if size, err = parseFixtureSize(entry.Size); err == nil {
+				err = m.AddFile(path, size, modTime)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Path, err)
+		}
+
+		if entry.Mode != "" {
+			mode, err := parseMode(entry.Mode)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.Path, err)
+			}
+			if err := m.Chmod(path, mode); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// parseMode parses octal permissions as chmod(1) takes them, including the
+// setuid (4000), setgid (2000) and sticky (1000) bits
+func parseMode(mode string) (fs.FileMode, error) {
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, fmt.Errorf("invalid mode %q", mode)
+	}
+	m := fs.FileMode(bits) & fs.ModePerm
+	if bits&04000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m, nil
+}
+
+// parseAge parses a duration, also accepting whole days such as "30d"
+func parseAge(age string) (time.Duration, error) {
+	if age == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", age)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(age)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", age)
+	}
+	return d, nil
+}
+
+// parseFixtureSize parses a size such as "120MB", or a plain number of bytes
+func parseFixtureSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+		return n, nil
+	}
+	return utils.ParseSize(size)
+}
@@ -0,0 +1,373 @@
+package vfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errNotEmpty is returned when removing a directory that still has entries
+var errNotEmpty = errors.New("directory not empty")
+
+// maxSymlinks bounds how many symlinks Stat follows before giving up
+const maxSymlinks = 40
+
+// MemFS is an in-memory file system. Files can be added with just a size;
+// they read back as content unique to their path, so they never look like
+// duplicates of each other. Only the last element of a path is resolved if
+// it's a symlink.
+type MemFS struct {
+	mu       sync.RWMutex
+	nodes    map[string]*memNode
+	children map[string]map[string]bool // Directory -> entry names
+}
+
+// memNode is a file, directory or symlink in a MemFS
+type memNode struct {
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	data    []byte // nil for files added with just a size
+	target  string // Symlink target
+}
+
+// NewMemFS creates an empty file system holding only the root directory
+func NewMemFS() *MemFS {
+	return &MemFS{
+		nodes:    map[string]*memNode{"/": {mode: fs.ModeDir | 0755, modTime: time.Now()}},
+		children: map[string]map[string]bool{"/": {}},
+	}
+}
+
+// AddFile adds a file of the given size, creating its parent directories
+func (m *MemFS) AddFile(path string, size int64, modTime time.Time) error {
+	return m.add(path, &memNode{mode: 0644, size: size, modTime: modTime})
+}
+
+// WriteFile adds a file holding data, creating its parent directories
+func (m *MemFS) WriteFile(path string, data []byte, modTime time.Time) error {
+	return m.add(path, &memNode{mode: 0644, size: int64(len(data)), modTime: modTime, data: data})
+}
+
+// Symlink adds a symlink at path pointing to target
+func (m *MemFS) Symlink(target, path string) error {
+	return m.add(path, &memNode{mode: fs.ModeSymlink | 0777, size: int64(len(target)), modTime: time.Now(), target: target})
+}
+
+// MkdirAll adds a directory and any missing parents
+func (m *MemFS) MkdirAll(path string, modTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(cleanPath(path), modTime)
+}
+
+// Chmod sets the permission bits of path, along with setuid, setgid and
+// sticky if they're in mode
+func (m *MemFS) Chmod(path string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, ok := m.nodes[cleanPath(path)]
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: path, Err: fs.ErrNotExist}
+	}
+	const settable = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+	node.mode = node.mode&^settable | mode&settable
+	return nil
+}
+
+// Exists reports whether path is in the file system
+func (m *MemFS) Exists(path string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.nodes[cleanPath(path)]
+	return ok
+}
+
+// Stat returns information about path, following a symlink
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	path, node, err := m.resolve(cleanPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return &memInfo{name: filepath.Base(path), node: *node}, nil
+}
+
+// Lstat returns information about path without following a symlink
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	path := cleanPath(name)
+	node, ok := m.nodes[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memInfo{name: filepath.Base(path), node: *node}, nil
+}
+
+// ReadDir returns the entries of a directory sorted by name
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	path, node, err := m.resolve(cleanPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	names := make([]string, 0, len(m.children[path]))
+	for child := range m.children[path] {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+
+	entries := make([]fs.DirEntry, len(names))
+	for i, child := range names {
+		entries[i] = fs.FileInfoToDirEntry(&memInfo{name: child, node: *m.nodes[filepath.Join(path, child)]})
+	}
+	return entries, nil
+}
+
+// Readlink returns the target of a symlink
+func (m *MemFS) Readlink(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, ok := m.nodes[cleanPath(name)]
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return node.target, nil
+}
+
+// Open opens a file for reading
+func (m *MemFS) Open(name string) (File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	path, node, err := m.resolve(cleanPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	info := &memInfo{name: filepath.Base(path), node: *node}
+	if node.data != nil {
+		return &memFile{info: info, r: bytes.NewReader(node.data)}, nil
+	}
+	return &memFile{info: info, r: &patternReader{seed: sha256.Sum256([]byte(path)), size: node.size}}, nil
+}
+
+// Remove removes a file, symlink or empty directory
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := cleanPath(name)
+	node, ok := m.nodes[path]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := m.checkWritable(filepath.Dir(path)); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	if node.mode.IsDir() && len(m.children[path]) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	m.unlink(path)
+	return nil
+}
+
+// RemoveAll removes path and everything under it. It's not an error if path
+// doesn't exist.
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := cleanPath(name)
+	if _, ok := m.nodes[path]; !ok {
+		return nil
+	}
+	if err := m.checkWritable(filepath.Dir(path)); err != nil {
+		return &fs.PathError{Op: "unlinkat", Path: name, Err: err}
+	}
+	return m.removeTree(path)
+}
+
+// removeTree removes path and its descendants, stopping at a directory that
+// isn't writable
+func (m *MemFS) removeTree(path string) error {
+	if m.nodes[path].mode.IsDir() {
+		for child := range m.children[path] {
+			childPath := filepath.Join(path, child)
+			if err := m.checkWritable(path); err != nil {
+				return &fs.PathError{Op: "unlinkat", Path: childPath, Err: err}
+			}
+			if err := m.removeTree(childPath); err != nil {
+				return err
+			}
+		}
+	}
+	m.unlink(path)
+	return nil
+}
+
+// add puts node at path, creating missing parent directories
+func (m *MemFS) add(name string, node *memNode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := cleanPath(name)
+	if _, ok := m.nodes[path]; ok {
+		return &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	}
+	if err := m.mkdirAll(filepath.Dir(path), node.modTime); err != nil {
+		return err
+	}
+	m.nodes[path] = node
+	m.link(path, node.modTime)
+	return nil
+}
+
+// mkdirAll creates path and its parents; the caller holds the lock
+func (m *MemFS) mkdirAll(path string, modTime time.Time) error {
+	if node, ok := m.nodes[path]; ok {
+		if !node.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: path, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(filepath.Dir(path), modTime); err != nil {
+		return err
+	}
+	m.nodes[path] = &memNode{mode: fs.ModeDir | 0755, modTime: modTime}
+	m.children[path] = map[string]bool{}
+	m.link(path, modTime)
+	return nil
+}
+
+// link adds path to its parent's entries. Like a real file system, the
+// parent counts as modified when the entry was created; the caller holds the
+// lock.
+func (m *MemFS) link(path string, modTime time.Time) {
+	dir := filepath.Dir(path)
+	m.children[dir][filepath.Base(path)] = true
+	if parent := m.nodes[dir]; modTime.After(parent.modTime) {
+		parent.modTime = modTime
+	}
+}
+
+// unlink drops path from the tree, marking its parent as modified now; the
+// caller holds the lock
+func (m *MemFS) unlink(path string) {
+	delete(m.nodes, path)
+	delete(m.children, path)
+	dir := filepath.Dir(path)
+	delete(m.children[dir], filepath.Base(path))
+	if parent, ok := m.nodes[dir]; ok {
+		parent.modTime = time.Now()
+	}
+}
+
+// resolve follows symlinks at the end of path; the caller holds the lock
+func (m *MemFS) resolve(path string) (string, *memNode, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		node, ok := m.nodes[path]
+		if !ok {
+			return "", nil, fs.ErrNotExist
+		}
+		if node.mode&fs.ModeSymlink == 0 {
+			return path, node, nil
+		}
+		target := node.target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = cleanPath(target)
+	}
+	return "", nil, fmt.Errorf("too many levels of symbolic links")
+}
+
+// checkWritable returns fs.ErrPermission unless the owner can write to dir
+func (m *MemFS) checkWritable(dir string) error {
+	if node, ok := m.nodes[dir]; ok && node.mode&0200 == 0 {
+		return fs.ErrPermission
+	}
+	return nil
+}
+
+// cleanPath makes name absolute and clean, the form nodes are keyed by
+func cleanPath(name string) string {
+	return filepath.Join("/", name)
+}
+
+// memInfo describes a node of a MemFS
+type memInfo struct {
+	name string
+	node memNode
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.node.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i *memInfo) ModTime() time.Time { return i.node.modTime }
+func (i *memInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
+
+// memFile is an open MemFS file
+type memFile struct {
+	info *memInfo
+	r    io.ReadSeeker
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error)                   { return f.info, nil }
+func (f *memFile) Read(p []byte) (int, error)                   { return f.r.Read(p) }
+func (f *memFile) Seek(offset int64, whence int) (int64, error) { return f.r.Seek(offset, whence) }
+func (f *memFile) Close() error                                 { return nil }
+
+// patternReader reads size bytes of seed repeated, without holding them in
+// memory
+type patternReader struct {
+	seed [sha256.Size]byte
+	size int64
+	off  int64
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if remaining := r.size - r.off; n > remaining {
+		n = remaining
+	}
+	for i := int64(0); i < n; i++ {
+		p[i] = r.seed[(r.off+i)%sha256.Size]
+	}
+	r.off += n
+	return int(n), nil
+}
+
+func (r *patternReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.off = offset
+	return offset, nil
+}
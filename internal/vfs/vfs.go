@@ -0,0 +1,155 @@
+// Package vfs abstracts the file system access of the scanner and cleaner, so
+// rules can be exercised against a synthetic tree instead of the real disk.
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FS is the file system access the scanner and cleaner need
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Readlink(name string) (string, error)
+	Open(name string) (File, error)
+	Remove(name string) error
+	RemoveAll(name string) error
+}
+
+// File is an open file, readable from any offset
+type File interface {
+	fs.File
+	io.Seeker
+}
+
+// OS is the real file system
+var OS FS = osFS{}
+
+// IsOS reports whether fsys is the real file system, or wraps it. External
+// tools (find, du, mdfind, sudo) only see the real one, so they're skipped
+// otherwise.
+func IsOS(fsys FS) bool {
+	for {
+		switch f := fsys.(type) {
+		case osFS:
+			return true
+		case interface{ Unwrap() FS }:
+			fsys = f.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// osFS implements FS with the os package
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Readlink(name string) (string, error)       { return os.Readlink(name) }
+func (osFS) Remove(name string) error                   { return os.Remove(name) }
+func (osFS) RemoveAll(name string) error                { return os.RemoveAll(name) }
+
+func (osFS) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// WalkDir walks the tree at root like filepath.WalkDir, reading it from fsys
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	// A wrapper of the real file system is walked through, so it sees the calls
+	if _, ok := fsys.(osFS); ok {
+		return filepath.WalkDir(root, fn)
+	}
+
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir visits path and, if it's a directory, everything under it
+func walkDir(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Give fn a second chance to see the error, as filepath.WalkDir does
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walkDir(fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Glob returns the paths in fsys matching pattern, like filepath.Glob
+func Glob(fsys FS, pattern string) ([]string, error) {
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Glob(pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches := []string{"."}
+	if filepath.IsAbs(pattern) {
+		matches = []string{string(filepath.Separator)}
+	}
+	for _, part := range strings.Split(strings.Trim(filepath.ToSlash(pattern), "/"), "/") {
+		var next []string
+		for _, dir := range matches {
+			if !strings.ContainsAny(part, `*?[\`) {
+				path := filepath.Join(dir, part)
+				if _, err := fsys.Lstat(path); err == nil {
+					next = append(next, path)
+				}
+				continue
+			}
+			entries, err := fsys.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if ok, _ := filepath.Match(part, entry.Name()); ok {
+					next = append(next, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+		matches = next
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -0,0 +1,262 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// MemFS Tests
+// =============================================================================
+
+func TestMemFSStatAndRead(t *testing.T) {
+	m := NewMemFS()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	m.AddFile("/a/b/big.bin", 100000, modTime)
+	m.WriteFile("/a/note.txt", []byte("hello"), modTime)
+	m.Symlink("/a/note.txt", "/a/link")
+
+	info, err := m.Stat("/a/b/big.bin")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != 100000 || !info.ModTime().Equal(modTime) || info.IsDir() {
+		t.Errorf("unexpected info %v %v %v", info.Size(), info.ModTime(), info.IsDir())
+	}
+	if info, _ := m.Stat("/a/b"); !info.IsDir() {
+		t.Error("parent directories should be created")
+	}
+
+	f, _ := m.Open("/a/b/big.bin")
+	data, _ := io.ReadAll(f)
+	if len(data) != 100000 {
+		t.Errorf("expected 100000 bytes, read %d", len(data))
+	}
+
+	// Symlinks are followed by Stat and Open but not Lstat
+	if info, _ := m.Lstat("/a/link"); info.Mode()&fs.ModeSymlink == 0 {
+		t.Error("Lstat should not follow the symlink")
+	}
+	if info, _ := m.Stat("/a/link"); info.Size() != 5 {
+		t.Errorf("Stat should follow the symlink, got size %d", info.Size())
+	}
+	f, _ = m.Open("/a/link")
+	if data, _ := io.ReadAll(f); string(data) != "hello" {
+		t.Errorf("expected hello, got %q", data)
+	}
+	if target, _ := m.Readlink("/a/link"); target != "/a/note.txt" {
+		t.Errorf("unexpected target %q", target)
+	}
+}
+
+func TestMemFSSizedFilesDiffer(t *testing.T) {
+	m := NewMemFS()
+	m.AddFile("/x", 4096, time.Now())
+	m.AddFile("/y", 4096, time.Now())
+
+	fx, _ := m.Open("/x")
+	fy, _ := m.Open("/y")
+	x, _ := io.ReadAll(fx)
+	y, _ := io.ReadAll(fy)
+	if string(x) == string(y) {
+		t.Error("files added with just a size should have different contents")
+	}
+
+	fx.Seek(100, io.SeekStart)
+	tail, _ := io.ReadAll(fx)
+	if string(tail) != string(x[100:]) {
+		t.Error("reading after a seek should match the full contents")
+	}
+}
+
+func TestMemFSRemove(t *testing.T) {
+	m := NewMemFS()
+	m.AddFile("/dir/sub/a", 1, time.Now())
+	m.AddFile("/locked/b", 1, time.Now())
+	m.Chmod("/locked", 0555)
+
+	if err := m.Remove("/dir"); err == nil {
+		t.Error("removing a non-empty directory should fail")
+	}
+	if err := m.Remove("/locked/b"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected permission error in a read-only directory, got %v", err)
+	}
+	if err := m.Remove("/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+
+	if err := m.RemoveAll("/dir"); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if m.Exists("/dir") || m.Exists("/dir/sub/a") {
+		t.Error("RemoveAll should remove the whole tree")
+	}
+	if err := m.RemoveAll("/dir"); err != nil {
+		t.Errorf("RemoveAll of a missing path should succeed, got %v", err)
+	}
+}
+
+func TestMemFSParentModTime(t *testing.T) {
+	m := NewMemFS()
+	old := time.Now().Add(-48 * time.Hour)
+	m.AddFile("/dir/old", 1, old)
+	if info, _ := m.Stat("/dir"); !info.ModTime().Equal(old) {
+		t.Errorf("expected directory modified when its file was, got %v", info.ModTime())
+	}
+
+	m.Remove("/dir/old")
+	if info, _ := m.Stat("/dir"); time.Since(info.ModTime()) > time.Minute {
+		t.Error("removing an entry should mark the directory modified")
+	}
+}
+
+// =============================================================================
+// WalkDir and Glob Tests
+// =============================================================================
+
+func TestWalkDir(t *testing.T) {
+	m := NewMemFS()
+	for _, path := range []string{"/r/a", "/r/skip/b", "/r/z/c"} {
+		m.AddFile(path, 1, time.Now())
+	}
+
+	var visited []string
+	err := WalkDir(m, "/r", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		if d.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir failed: %v", err)
+	}
+	want := []string{"/r", "/r/a", "/r/skip", "/r/z", "/r/z/c"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("expected %v, got %v", want, visited)
+	}
+
+	var missing error
+	WalkDir(m, "/nope", func(path string, d fs.DirEntry, err error) error {
+		missing = err
+		return nil
+	})
+	if !errors.Is(missing, fs.ErrNotExist) {
+		t.Errorf("expected not-exist error for a missing root, got %v", missing)
+	}
+}
+
+func TestCountingFS(t *testing.T) {
+	m := NewMemFS()
+	m.AddFile("/r/a", 1, time.Now())
+	m.AddFile("/r/d/b", 1, time.Now())
+
+	c := NewCountingFS(m)
+	WalkDir(c, "/r", func(path string, d fs.DirEntry, err error) error { return nil })
+	c.Stat("/r/a")
+	if got := c.ReadDirs.Load(); got != 2 {
+		t.Errorf("expected 2 ReadDir calls, got %d", got)
+	}
+	if got := c.Total(); got != 4 {
+		t.Errorf("expected 4 calls in total, got %d", got)
+	}
+
+	if IsOS(c) {
+		t.Error("a wrapped MemFS should not count as the real file system")
+	}
+	if !IsOS(NewCountingFS(OS)) {
+		t.Error("a wrapped OS should count as the real file system")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	m := NewMemFS()
+	for _, path := range []string{"/mnt/c/Users/a/x", "/mnt/d/Users/b/x", "/mnt/c/Other/x"} {
+		m.AddFile(path, 1, time.Now())
+	}
+
+	matches, err := Glob(m, "/mnt/*/Users/*")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	want := []string{"/mnt/c/Users/a", "/mnt/d/Users/b"}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("expected %v, got %v", want, matches)
+	}
+	if _, err := Glob(m, "/mnt/["); err == nil {
+		t.Error("expected an error for a bad pattern")
+	}
+}
+
+// =============================================================================
+// Fixture Tests
+// =============================================================================
+
+func TestFixtureBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	os.WriteFile(path, []byte(`
+home: /home/alice
+files:
+  - path: ~/.cache/pip/wheel.whl
+    size: 2MB
+    age: 30d
+  - path: ~/notes.txt
+    content: hi
+    age: 36h
+  - path: /tmp/suid
+    size: "10"
+    mode: "4755"
+  - path: ~/link
+    symlink: ~/notes.txt
+`), 0644)
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if fixture.User != "alice" {
+		t.Errorf("expected user from home directory, got %q", fixture.User)
+	}
+	m, err := fixture.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	info, _ := m.Stat("/home/alice/.cache/pip/wheel.whl")
+	if info.Size() != 2*1024*1024 {
+		t.Errorf("unexpected size %d", info.Size())
+	}
+	if age := time.Since(info.ModTime()); age < 29*24*time.Hour || age > 31*24*time.Hour {
+		t.Errorf("expected about 30 days old, got %v", age)
+	}
+	if info, _ := m.Stat("/tmp/suid"); info.Mode() != fs.ModeSetuid|0755 {
+		t.Errorf("expected setuid 0755, got %v", info.Mode())
+	}
+	if target, _ := m.Readlink("/home/alice/link"); target != "/home/alice/notes.txt" {
+		t.Errorf("expected ~ expanded in symlink target, got %q", target)
+	}
+}
+
+func TestFixtureBuildErrors(t *testing.T) {
+	tests := []FixtureEntry{
+		{Path: "relative/path"},
+		{Path: "/a", Age: "soon"},
+		{Path: "/a", Size: "lots"},
+		{Path: "/a", Mode: "999"},
+	}
+	for _, entry := range tests {
+		fixture := &Fixture{Home: "/home/user", Entries: []FixtureEntry{entry}}
+		if _, err := fixture.Build(); err == nil {
+			t.Errorf("expected an error for %+v", entry)
+		}
+	}
+}
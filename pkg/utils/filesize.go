@@ -1,6 +1,12 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
 
 const (
 	B  = 1
@@ -8,52 +14,104 @@ const (
 	MB = 1024 * KB
 	GB = 1024 * MB
 	TB = 1024 * GB
+	PB = 1024 * TB
 )
 
-// FormatBytes converts bytes to human-readable format
+// si selects decimal units (kB, MB, GB) over binary ones (KiB, MiB, GiB)
+var si atomic.Bool
+
+// SetSI switches sizes to decimal units, powers of 1000, when on, and back to
+// binary units, powers of 1024, when off
+func SetSI(on bool) {
+	si.Store(on)
+}
+
+// SI reports whether sizes use decimal units
+func SI() bool {
+	return si.Load()
+}
+
+// FormatBytes converts bytes to human-readable format with two decimals
 func FormatBytes(bytes int64) string {
+	return FormatBytesN(bytes, 2)
+}
+
+// FormatBytesN converts bytes to human-readable format with the given number
+// of decimals, in binary or decimal units as set by SetSI
+func FormatBytesN(bytes int64, decimals int) string {
 	if bytes < 0 {
 		return "0 B"
 	}
 
-	switch {
-	case bytes >= TB:
-		return fmt.Sprintf("%.2f TB", float64(bytes)/float64(TB))
-	case bytes >= GB:
-		return fmt.Sprintf("%.2f GB", float64(bytes)/float64(GB))
-	case bytes >= MB:
-		return fmt.Sprintf("%.2f MB", float64(bytes)/float64(MB))
-	case bytes >= KB:
-		return fmt.Sprintf("%.2f KB", float64(bytes)/float64(KB))
-	default:
+	base, units := int64(1024), []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	if SI() {
+		base, units = 1000, []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	}
+	if bytes < base {
 		return fmt.Sprintf("%d B", bytes)
 	}
+	div, exp := base, 0
+	for n := bytes / base; n >= base; n /= base {
+		div *= base
+		exp++
+	}
+	return fmt.Sprintf("%.*f %s", decimals, float64(bytes)/float64(div), units[exp])
 }
 
-// ParseSize converts human-readable size to bytes
+// ParseSize converts human-readable size to bytes. It accepts fractions
+// ("1.5GB") and the units B, K/KB, M/MB, G/GB, T/TB and P/PB in any case, or
+// KiB, MiB and the other binary suffixes; all are powers of 1024. SetSI only
+// changes how sizes are shown, so a config means the same with --si.
 func ParseSize(size string) (int64, error) {
-	var value float64
-	var unit string
+	s := strings.TrimSpace(size)
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("invalid size %q: negative sizes aren't allowed", size)
+	}
 
-	_, err := fmt.Sscanf(size, "%f%s", &value, &unit)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	number, unit := s[:end], strings.TrimSpace(s[end:])
+	if number == "" {
+		return 0, fmt.Errorf("invalid size %q: expected a number such as 500MB", size)
+	}
+	value, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		return 0, fmt.Errorf("invalid size format: %s", size)
+		return 0, fmt.Errorf("invalid size %q: expected a number such as 500MB", size)
 	}
 
-	switch unit {
-	case "B", "b":
-		return int64(value), nil
-	case "KB", "kb", "K", "k":
-		return int64(value * KB), nil
-	case "MB", "mb", "M", "m":
-		return int64(value * MB), nil
-	case "GB", "gb", "G", "g":
-		return int64(value * GB), nil
-	case "TB", "tb", "T", "t":
-		return int64(value * TB), nil
+	multiplier, ok := unitMultiplier(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", size, unit)
+	}
+
+	bytes := math.Round(value * multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", size)
+	}
+	return int64(bytes), nil
+}
+
+// unitMultiplier returns the number of bytes in a size unit
+func unitMultiplier(unit string) (float64, bool) {
+	unit = strings.ToUpper(unit)
+	if unit == "" || unit == "B" {
+		return 1, true
+	}
+
+	exp := strings.Index("KMGTP", unit[:1])
+	if exp < 0 {
+		return 0, false
+	}
+	switch unit[1:] {
+	case "", "B", "IB":
 	default:
-		return 0, fmt.Errorf("unknown unit: %s", unit)
+		return 0, false
 	}
+	return math.Pow(1024, float64(exp+1)), true
 }
 
 // SumSizes adds up a slice of sizes
@@ -0,0 +1,89 @@
+package utils
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"512B", 512},
+		{"1K", KB},
+		{"1KB", KB},
+		{"1KiB", KB},
+		{"100kb", 100 * KB},
+		{"1.5MB", 1536 * KB},
+		{"2MiB", 2 * MB},
+		{"0.5GB", 512 * MB},
+		{"1gib", GB},
+		{"3TB", 3 * TB},
+		{"1TiB", TB},
+		{"2PB", 2 * PB},
+		{" 500 MB ", 500 * MB},
+		{"1.", 1},
+		{".5K", 512},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSizeInvalid(t *testing.T) {
+	for _, input := range []string{
+		"", "   ", "MB", "-1MB", "- 1MB", "1.2.3MB", "abc100MB", "100XB", "100MBs",
+		"1 M B", "1e3", "1,5GB", "99999999PB",
+	} {
+		if got, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) = %d, want an error", input, got)
+		}
+	}
+}
+
+func TestParseSizeIgnoresSI(t *testing.T) {
+	defer SetSI(false)
+
+	// Display units don't change what a config's sizes mean
+	for _, on := range []bool{false, true} {
+		SetSI(on)
+		for input, want := range map[string]int64{"500MB": 500 * MB, "1k": KB, "1.5GB": 1536 * MB, "1GiB": GB} {
+			if got, err := ParseSize(input); err != nil || got != want {
+				t.Errorf("ParseSize(%q) with si=%v = %d, %v; want %d", input, on, got, err, want)
+			}
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		si    bool
+		want  string
+	}{
+		{-5, false, "0 B"},
+		{0, false, "0 B"},
+		{1023, false, "1023 B"},
+		{1536, false, "1.50 KiB"},
+		{1536 * MB, false, "1.50 GiB"},
+		{2 * TB, false, "2.00 TiB"},
+		{999, true, "999 B"},
+		{1500, true, "1.50 kB"},
+		{1500 * 1000 * 1000, true, "1.50 GB"},
+		{3e15, true, "3.00 PB"},
+	}
+	for _, tt := range tests {
+		SetSI(tt.si)
+		if got := FormatBytes(tt.bytes); got != tt.want {
+			t.Errorf("FormatBytes(%d) with si=%v = %q, want %q", tt.bytes, tt.si, got, tt.want)
+		}
+	}
+	SetSI(false)
+
+	if got := FormatBytesN(1536, 1); got != "1.5 KiB" {
+		t.Errorf("FormatBytesN(1536, 1) = %q, want 1.5 KiB", got)
+	}
+}
@@ -15,8 +15,13 @@ func HashFile(filepath string) (string, error) {
 	}
 	defer file.Close()
 
+	return HashReader(file)
+}
+
+// HashReader computes SHA256 hash of everything read from r
+func HashReader(r io.Reader) (string, error) {
 	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	if _, err := io.Copy(hash, r); err != nil {
 		return "", err
 	}
 
@@ -37,7 +42,11 @@ func HashFileQuick(filepath string, chunkSize int64) (string, error) {
 		return "", err
 	}
 
-	fileSize := fileInfo.Size()
+	return HashReaderQuick(file, fileInfo.Size(), chunkSize)
+}
+
+// HashReaderQuick is HashFileQuick for an open file of the given size
+func HashReaderQuick(file io.ReadSeeker, fileSize, chunkSize int64) (string, error) {
 	hash := sha256.New()
 
 	// If file is smaller than 2*chunkSize, hash the whole file